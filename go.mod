@@ -2,7 +2,11 @@ module github.com/tosih/motronic-m21-tool
 
 go 1.25.1
 
-require github.com/pterm/pterm v0.12.81
+require (
+	github.com/diamondburned/gotk4/pkg v0.3.1
+	github.com/pterm/pterm v0.12.81
+	golang.org/x/term v0.32.0
+)
 
 require (
 	atomicgo.dev/cursor v0.2.0 // indirect
@@ -10,7 +14,6 @@ require (
 	atomicgo.dev/schedule v0.1.0 // indirect
 	github.com/KarpelesLab/weak v0.1.1 // indirect
 	github.com/containerd/console v1.0.5 // indirect
-	github.com/diamondburned/gotk4/pkg v0.3.1 // indirect
 	github.com/gookit/color v1.5.4 // indirect
 	github.com/lithammer/fuzzysearch v1.1.8 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
@@ -19,6 +22,5 @@ require (
 	go4.org/unsafe/assume-no-moving-gc v0.0.0-20231121144256-b99613f794b6 // indirect
 	golang.org/x/sync v0.15.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
-	golang.org/x/term v0.32.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 )
@@ -0,0 +1,406 @@
+package i18n
+
+// de is the German catalog
+var de = map[string]string{
+	"cli.cancelled":            "Abgebrochen.",
+	"cli.compare.header":       "ECU-Dateivergleich",
+	"cli.compare.params":       "Konfigurationsparameter",
+	"cli.compare.section":      "Vergleich: %s\n",
+	"cli.confirm.bytes":        "Diese Bytes in die Datei schreiben?",
+	"cli.confirm.change":       "Diese Änderung schreiben?",
+	"cli.confirm.change_file":  "Diese Änderung in die Datei schreiben?",
+	"cli.confirm.changes":      "Diese Änderungen in die Datei schreiben?",
+	"cli.confirm.clamped":      "%d Zellen werden an der Grenze des Datentyps begrenzt. Trotzdem fortfahren?",
+	"cli.defs.header":          "Definitionsprüfung",
+	"cli.dry_run":              "PROBELAUF - Keine Änderungen vorgenommen",
+	"cli.edit.cancelled":       "Bearbeitung abgebrochen.",
+	"cli.edit.header":          "⚠️  INTERAKTIVER BEARBEITUNGSMODUS - ÄUSSERSTE VORSICHT  ⚠️",
+	"cli.edit.risks":           "Sind Ihnen die Risiken bewusst und möchten Sie fortfahren?",
+	"cli.edit.warning":         "Änderungen an der ECU-Kalibrierung können Motorschäden, unsichere Fahrzustände, den Verlust der Garantie und rechtliche Probleme verursachen.",
+	"cli.files.header":         "Verfügbare ECU-Binärdateien",
+	"cli.import.aborted":       "Abgebrochen - Datei unverändert (mit -on-error skip nur die akzeptierten Zellen importieren)",
+	"cli.import.ask":           "Einige Zellen wurden begrenzt oder abgelehnt. Nur die akzeptierten Zellen importieren?",
+	"cli.maps.category":        "%s (%d)",
+	"cli.maps.header":          "ECU-Kennfeldleser - Motronic M2.1",
+	"cli.maps.header_ecu":      "%s · %s",
+	"cli.maps.list_header":     "Verfügbare ECU-Kennfelder",
+	"cli.no_changes":           "Keine Zellen zu ändern.",
+	"cli.outliers.header":      "Ausreißer-Zellen",
+	"cli.preset.header":        "VOREINSTELLUNGS-MODUS",
+	"cli.preset.warning":       "Voreinstellungen wenden vordefinierte Änderungen an. MIT VORSICHT VERWENDEN!",
+	"cli.quickstart.next":      "Als Nächstes ausprobieren",
+	"cli.scan.section":         "Mögliche Kennfeldpositionen",
+	"cli.suggest.header":       "Vorgeschlagene Kraftstoffkorrekturen (unverbindlich)",
+	"cli.timeline.header":      "ECU-Dateiverlauf",
+	"cli.timeline.section":     "Verlauf: %s\n",
+	"cli.web.header":           "🌐 ECU-Webansicht gestartet",
+	"cli.web.opening":          "Weboberfläche unter %s wird geöffnet\n",
+	"cli.web.removed_temp":     "Übrig gebliebene temporäre Datei %s entfernt",
+	"cli.web.shutdown_timeout": "Nach %s noch laufende Anfragen wurden abgebrochen; temporäre Dateien bleiben erhalten",
+	"cli.web.stop":             "Strg+C beendet den Server",
+	"cli.web.stopped":          "Webserver beendet",
+	"cli.web.stopping":         "Wird beendet: laufende Schreibvorgänge werden abgeschlossen...",
+	"cli.would_change":         "%d Zellen würden sich ändern\n",
+
+	"gui.about.bin_dir":              "\n\nBinärverzeichnis: %s (aus %s)",
+	"gui.about.comments":             "Motronic-M2.1-ECU-Binärdateien lesen, analysieren und bearbeiten",
+	"gui.about.no_bin_dir":           "\n\nKein Binärverzeichnis (bin_dir in settings.json oder $%s setzen)",
+	"gui.attachments.attach":         "Log anhängen...",
+	"gui.attachments.attach_failed":  "Log konnte nicht angehängt werden: %v",
+	"gui.attachments.attached":       "%s angehängt",
+	"gui.attachments.none":           "Keine Logs angehängt",
+	"gui.attachments.open":           "Öffnen",
+	"gui.attachments.open_failed":    "%s konnte nicht geöffnet werden: %v",
+	"gui.attachments.select":         "Anzuhängendes Log auswählen",
+	"gui.attachments.title":          "Anhänge - %s",
+	"gui.blame.cell":                 "Geändert durch %s",
+	"gui.blame.unattributed":         "Nicht zugeordnet: außerhalb dieses Tools geändert",
+	"gui.button.apply":               "Anwenden",
+	"gui.button.apply_changes":       "Änderungen anwenden",
+	"gui.button.cancel":              "Abbrechen",
+	"gui.button.close":               "Schließen",
+	"gui.button.save":                "Speichern",
+	"gui.button.save_changes":        "Änderungen speichern",
+	"gui.changed.title":              "Änderungen in %s",
+	"gui.checksum.fix":               "Prüfsumme speichern",
+	"gui.checksum.fix_failed":        "Prüfsumme konnte nicht gespeichert werden: %v",
+	"gui.checksum.fixed":             "Prüfsumme 0x%X bei 0x%04X gespeichert",
+	"gui.checksum.stale":             "Das Speichern hat die Prüfsumme des Images veraltet gelassen: %s",
+	"gui.checksum.stale_title":       "Die Prüfsumme des Images stimmt nicht mehr",
+	"gui.code.info":                  "%s ist eine unbestätigte Kennfelddefinition und ihre Bytes: %s. Schreiben in Programmcode kann das Steuergerät unbrauchbar machen.",
+	"gui.code.locked":                "%s ist gesperrt: unbestätigt und %s",
+	"gui.code.prompt":                "Zum Entsperren \"%s\" eingeben und die Änderung dann erneut starten:",
+	"gui.code.title":                 "Kennfeld sieht wie Programmcode aus",
+	"gui.code.unlock":                "Entsperren",
+	"gui.code.unlocked":              "%s zum Bearbeiten entsperrt; die Änderung wird im Änderungsprotokoll markiert",
+	"gui.compare.all_match":          "Alle Konfigurationsparameter stimmen mit %s überein.",
+	"gui.compare.choose":             "Mit „Dateien vergleichen“ eine zweite Datei wählen.",
+	"gui.compare.comparing":          "Vergleich mit: %s",
+	"gui.compare.header":             "Parameterunterschiede",
+	"gui.compare.implausible":        "⚠ markiert Werte außerhalb des plausiblen Bereichs des Parameters",
+	"gui.compare.params_failed":      "Fehler beim Vergleichen der Parameter: %v",
+	"gui.compare.select":             "ECU-Datei zum Vergleichen auswählen",
+	"gui.compare_identify_failed":    "Fehler beim Identifizieren der Vergleichsdatei: %v",
+	"gui.config.cannot_move":         "%s kann nicht verschoben werden",
+	"gui.config.confirm_detail":      "Parameter: %s\nNeuer Wert: %.1f %s\n\n",
+	"gui.config.current":             "Aktueller Wert: %.1f %s",
+	"gui.config.edit":                "Bearbeiten",
+	"gui.config.edit_title":          "%s bearbeiten",
+	"gui.config.error":               "Fehler",
+	"gui.config.header":              "ECU-Konfigurationsparameter",
+	"gui.config.linked":              "Verknüpft: %s (Änderungen verschieben die Gruppe gemeinsam)",
+	"gui.config.move_linked":         "%s um denselben Betrag verschieben",
+	"gui.config.out_of_range":        "Wert außerhalb des Bereichs! Muss zwischen %.1f und %.1f liegen",
+	"gui.config.range":               "Gültiger Bereich: %.1f - %.1f %s",
+	"gui.config.read_failed":         "Parameter konnte nicht gelesen werden: %v",
+	"gui.config.save_failed":         "Parameter konnte nicht gespeichert werden",
+	"gui.config.save_group_failed":   "Parameter konnten nicht gespeichert werden",
+	"gui.config.warning":             "⚠️  Änderungen an ECU-Parametern können den Motor beschädigen!",
+	"gui.confirm_modification":       "<b>ECU-Änderung bestätigen</b>\n\nDies verändert die ECU-Binärdatei.\nEine Sicherung wird automatisch erstellt.\n\n%sVorsicht beim Fortfahren!",
+	"gui.diff.badge_skipped":         "Nicht verglichen: %s",
+	"gui.diff.badge_tooltip":         "%d von %d Zellen geändert",
+	"gui.diff.counted":               "Geänderte Zellen von %d Kennfeldern gezählt",
+	"gui.diff.export":                "Vergleichsbericht exportieren",
+	"gui.diff.export_failed":         "Vergleichsbericht konnte nicht geschrieben werden: %v",
+	"gui.diff.export_select":         "Vergleichsbericht speichern",
+	"gui.diff.export_tooltip":        "Vergleich als HTML, JSON oder CSV speichern, je nach Dateiendung",
+	"gui.diff.exported":              "Vergleichsbericht nach %s geschrieben",
+	"gui.diff.failed":                "Dateien konnten nicht verglichen werden: %v",
+	"gui.diff.not_ready":             "Der Vergleich läuft noch",
+	"gui.diff.opened":                "Vergleiche %s mit %s (schreibgeschützt)",
+	"gui.diff.read_only":             "Dieses Fenster vergleicht zwei Dateien und kann sie nicht bearbeiten. Öffnen Sie eine Datei, um sie zu bearbeiten.",
+	"gui.diff.title":                 "Motronic M2.1 ECU-Werkzeug - Vergleich %s ↔ %s (schreibgeschützt)",
+	"gui.divergence.button":          "Abweichungen anzeigen",
+	"gui.divergence.count":           "%d Zelle(n) unterscheiden sich",
+	"gui.divergence.none":            "Alle Kennfelder und Parameter haben dieselben Rohwerte",
+	"gui.divergence.title":           "Abweichungen: %s gegen %s",
+	"gui.edit.info":                  "Kennfeld: %s\nPosition: Zeile %d, Spalte %d\nAktueller Wert: %.2f %s",
+	"gui.edit.range":                 "Zulässiger Bereich: %.2f - %.2f %s",
+	"gui.edit.save_failed":           "Änderung konnte nicht gespeichert werden",
+	"gui.edit.title":                 "Zellwert bearbeiten",
+	"gui.edit.updated":               "Zelle [%d,%d] auf %.2f %s gesetzt",
+	"gui.engine_warning":             "⚠️  Änderungen an ECU-Werten können den Motor beschädigen!",
+	"gui.export.done":                "Kennfeld erfolgreich nach %s exportiert",
+	"gui.export.failed":              "Export fehlgeschlagen: %v",
+	"gui.export.select":              "Exportverzeichnis auswählen",
+	"gui.find.hint":                  "value oder raw eines Kennfelds oder \"any\" mit > >= < <= == != vergleichen",
+	"gui.find.title":                 "Zellen suchen",
+	"gui.fuelcut.cannot":             "Drehzahlbegrenzer-Änderung kann nicht geplant werden",
+	"gui.fuelcut.detected":           "Kraftstoffkennfeld: %d Abschaltspalte(n) ab %.0f U/min. Ohne Verschieben der Abschaltung ruckelt der Motor an der neuen Grenze.",
+	"gui.fuelcut.done":               "Drehzahlbegrenzer auf %.0f U/min gesetzt und Abschaltung um %+d Spalte(n) verschoben",
+	"gui.fuelcut.failed":             "Drehzahlbegrenzer und Kraftstoffabschaltung konnten nicht geschrieben werden",
+	"gui.fuelcut.op":                 "%s: %d Zelle(n)",
+	"gui.fuelcut.shift":              "Kraftstoffabschaltung um Spalten verschieben:",
+	"gui.import.accept":              "Importieren",
+	"gui.import.accept_partial":      "Akzeptierte Zellen importieren",
+	"gui.import.detail":              "%d akzeptiert · %d gerundet · %d begrenzt · %d abgelehnt",
+	"gui.import.done":                "%d Zellen importiert",
+	"gui.import.failed":              "Import fehlgeschlagen",
+	"gui.import.filter":              "Kennfeld-CSV-Dateien (*.csv)",
+	"gui.import.incomplete":          " Begrenzte und abgelehnte Zellen werden nicht geschrieben; der Import übernimmt nur akzeptierte und gerundete Zellen.",
+	"gui.import.rejected":            "Abgelehnt: %s",
+	"gui.import.report":              "Importbericht",
+	"gui.import.summary":             "%d Zellen würden sich ändern.",
+	"gui.import.title":               "Kennfeld-CSV importieren",
+	"gui.invalid_value":              "Ungültiger Wert: %v",
+	"gui.layout":                     "%s: Abbild bei 0x%X (%s)",
+	"gui.linked.dropped":             "Verknüpfte Datei entfernt: %v",
+	"gui.linked.failed":              "Datei kann nicht verknüpft werden: %v",
+	"gui.linked.linked":              "Änderungen werden jetzt auch in %s geschrieben",
+	"gui.linked.select":              "Datei für gleichzeitiges Bearbeiten auswählen",
+	"gui.linked.title":               "%s ⇄ %s",
+	"gui.linked.unlinked":            "Änderungen werden nicht mehr in %s geschrieben",
+	"gui.loaded":                     "Geladen: %s",
+	"gui.loaded_ecu":                 "Geladen: %s (%s)",
+	"gui.log.all":                    "Alle",
+	"gui.log.clear":                  "Leeren",
+	"gui.log.copied":                 "Log in die Zwischenablage kopiert",
+	"gui.log.copy":                   "Kopieren",
+	"gui.log.errors":                 "Fehler",
+	"gui.log.show":                   "Anzeigen:",
+	"gui.log.title":                  "Protokoll",
+	"gui.log.warnings":               "Warnungen",
+	"gui.map.compare_read_failed":    "Fehler beim Lesen des Vergleichskennfelds: %v",
+	"gui.map.empty":                  "Keine ECU-Datei geladen",
+	"gui.map.empty_hint":             "Zum Starten „ECU-Datei öffnen“ anklicken",
+	"gui.map.load_axis":              "Last",
+	"gui.map.read_failed":            "Fehler beim Lesen des Kennfelds: %v",
+	"gui.map.unit":                   "Einheit: %s",
+	"gui.maps.filter":                "Kennfelddefinitionen (JSON, YAML)",
+	"gui.maps.load_failed":           "Kennfelddefinitionen nicht geladen: %v",
+	"gui.maps.loaded":                "%d Kennfelder aus %s hinzugefügt",
+	"gui.maps.title":                 "Kennfelddefinitionen laden",
+	"gui.menu.about":                 "Über",
+	"gui.menu.attachments":           "Anhänge...",
+	"gui.menu.changed":               "Änderungen seit gestern...",
+	"gui.menu.compare":               "Dateien vergleichen",
+	"gui.menu.define_map":            "Kennfeld definieren…",
+	"gui.menu.export":                "Als CSV exportieren...",
+	"gui.menu.find":                  "Zellen suchen...",
+	"gui.menu.import":                "CSV importieren...",
+	"gui.menu.load_maps":             "Kennfelddefinitionen laden…",
+	"gui.menu.open":                  "Datei öffnen...",
+	"gui.menu.open_linked":           "Verknüpfte Datei öffnen…",
+	"gui.menu.preferences":           "Einstellungen",
+	"gui.menu.preset":                "Voreinstellung anwenden...",
+	"gui.menu.project":               "Projekt öffnen...",
+	"gui.menu.quit":                  "Beenden",
+	"gui.menu.save_as":               "Speichern unter...",
+	"gui.menu.scale":                 "Kennfeld skalieren...",
+	"gui.menu.scanner":               "Scanner",
+	"gui.menu.snapshots":             "Schnappschüsse…",
+	"gui.menu.unlink":                "Verknüpfung aufheben",
+	"gui.mirror.stale":               "Gespiegelter Dump: nur die erste von %d Kopien des Abbilds wurde geschrieben, die Kopien unterscheiden sich jetzt (siehe Einstellungen)",
+	"gui.more":                       "… und %d weitere",
+	"gui.need_file":                  "Bitte zuerst eine ECU-Datei öffnen",
+	"gui.new_value":                  "Neuer Wert:",
+	"gui.no_files":                   "Keine ECU-Dateien in bins/ gefunden",
+	"gui.nudge.cannot":               "Anpassen nicht möglich",
+	"gui.nudge.confirm":              "<b>%s [%d,%d] anpassen?</b>\n\n%.2f → %.2f %s\n\nEine Sicherung wird automatisch erstellt.",
+	"gui.nudge.failed":               "Zelle konnte nicht angepasst werden",
+	"gui.nudge.write":                "Schreiben",
+	"gui.open.failed":                "%s kann nicht geöffnet werden: %v",
+	"gui.open.filter":                "ECU-Binärdateien (*.bin)",
+	"gui.open.not_image":             "Kein ECU-Abbild: %s (erwartet wird eine .bin-Datei)",
+	"gui.open.title":                 "ECU-Binärdatei öffnen",
+	"gui.outliers.cell":              "Ausreißer: Umgebungsmedian %.2f, geglättet %.2f",
+	"gui.outliers.found":             "%s: %d Ausreißer-Zelle(n), Schwelle %.2f %s",
+	"gui.outliers.toggle":            "Ausreißer",
+	"gui.outliers.tooltip":           "Zellen umranden, die um mehr als 10 % des Kennfeldbereichs vom Median ihrer 3x3-Umgebung abweichen",
+	"gui.overlay.loaded":             "Überlagere %s: %d Messpunkte (%d übersprungen, %d außerhalb des Rasters)",
+	"gui.overlay.no_log":             "Kein angehängtes Log gefunden; über Datei > Anhänge eines anhängen",
+	"gui.overlay.parse_failed":       "%s konnte nicht gelesen werden: %v",
+	"gui.overlay.toggle":             "Angehängtes Lambda-Log überlagern",
+	"gui.overlay.tooltip":            "Das zuletzt angehängte Log auf das Lambda-Sollwert-Kennfeld einteilen",
+	"gui.prefs.checksum":             "Prüfsumme beim Speichern:",
+	"gui.prefs.checksum.always":      "Immer speichern",
+	"gui.prefs.checksum.ask":         "Nachfragen, wenn veraltet",
+	"gui.prefs.checksum.never":       "Nie (Flash-Tool korrigiert sie)",
+	"gui.prefs.checksum_set":         "Prüfsummen-Richtlinie auf %s gesetzt",
+	"gui.prefs.confirmations":        "Bestätigungen:",
+	"gui.prefs.hint":                 "Änderungen werden unabhängig von der Einstellung automatisch gesichert.",
+	"gui.prefs.ignored":              "Gespeicherte Einstellung wird ignoriert: %v",
+	"gui.prefs.language":             "Sprache:",
+	"gui.prefs.language_set":         "Sprache auf %s gesetzt; nach einem Neustart überall wirksam",
+	"gui.prefs.language_system":      "Systemstandard ($LANG)",
+	"gui.prefs.load_failed":          "Einstellungen konnten nicht geladen werden: %v",
+	"gui.prefs.mirror_writes":        "Änderungen an gespiegelten Dumps in jede Kopie schreiben",
+	"gui.prefs.mirror_writes_hint":   "Ein Dump, der das Abbild mehrfach enthält, etwa ein 27C256-Abbild als 27C512 gelesen, behält gleiche Kopien. Sonst wird nur die erste Kopie geschrieben und das Protokoll warnt.",
+	"gui.prefs.mirror_writes_off":    "Änderungen an gespiegelten Dumps schreiben jetzt nur die erste Kopie",
+	"gui.prefs.mirror_writes_on":     "Änderungen an gespiegelten Dumps schreiben jetzt jede Kopie",
+	"gui.prefs.policy.full":          "Vollständig (jede Änderung bestätigen)",
+	"gui.prefs.policy.never":         "Nie (Experte)",
+	"gui.prefs.policy.save":          "Nur beim Speichern bestätigen",
+	"gui.prefs.policy_set":           "Bestätigungsregel auf %s gesetzt",
+	"gui.prefs.require_backup":       "Vor jedem Schreiben eine geprüfte Sicherung verlangen",
+	"gui.prefs.require_backup_hint":  "Es wird nichts geschrieben, bis eine Sicherung des aktuellen Dateiinhalts zurückgelesen und ihr SHA-256 geprüft wurde. Eine passende Sicherung von heute wird wiederverwendet.",
+	"gui.prefs.require_backup_off":   "Schreibvorgänge verlangen keine geprüfte Sicherung mehr",
+	"gui.prefs.require_backup_on":    "Schreibvorgänge verlangen jetzt eine geprüfte Sicherung",
+	"gui.prefs.save_failed":          "Einstellungen konnten nicht gespeichert werden: %v",
+	"gui.prefs.snapshots":            "Automatische Schnappschüsse anlegen",
+	"gui.prefs.snapshots_hint":       "Alle %d Minuten oder %d Änderungen, nur wenn sich die Datei geändert hat. Schnappschüsse liegen neben der Datei, die neuesten %d werden behalten; Sicherungen der Bearbeitungen sind davon unabhängig.",
+	"gui.preset.applied":             "Voreinstellung %s angewendet: %d Zellen geändert",
+	"gui.preset.cannot":              "%s kann nicht angewendet werden",
+	"gui.preset.confirm":             "<b>Voreinstellung %s anwenden?</b>\n\n%d Zellen werden geändert. Eine Sicherung wird automatisch erstellt.\n\n<tt>%s</tt>",
+	"gui.preset.error":               "Fehler: %v",
+	"gui.preset.failed":              "Voreinstellung konnte nicht angewendet werden",
+	"gui.preset.need_file":           "Vor dem Anwenden einer Voreinstellung eine ECU-Datei öffnen.",
+	"gui.preset.no_changes":          "Voreinstellung %s: keine Zellen zu ändern",
+	"gui.preset.title":               "Voreinstellung anwenden",
+	"gui.profile.fixed":              "Die Definitionen stammen aus --xdf oder --maps-mode replace, daher lässt sich das Profil nicht ändern",
+	"gui.profile.invalid":            "Profil nicht ausgewählt: %v",
+	"gui.profile.load_failed":        "Profile nicht geladen: %v",
+	"gui.profile.switched":           "Zu Profil %s gewechselt",
+	"gui.profile.tooltip":            "ECU-Profil: die Kennfeld- und Parameterdefinitionen einer Firmware-Variante",
+	"gui.project.filter":             "Projektdateien (*.project.json)",
+	"gui.project.load_failed":        "Projekt konnte nicht geladen werden: %v",
+	"gui.project.no_file":            "Projekt %s nennt keine ECU-Datei",
+	"gui.project.offsets_web_only":   "Die Kennfeld-Offsets des Projekts gelten nur in der Weboberfläche",
+	"gui.project.opened":             "Projekt %s geöffnet",
+	"gui.project.title":              "Projekt öffnen",
+	"gui.provenance.definitions":     "Definitionen: %s",
+	"gui.provenance.defs_changed":    "Die Definitionen haben sich seit dem Speichern geändert",
+	"gui.provenance.label":           "Mit diesem Tool geändert",
+	"gui.provenance.modified":        "Geändert: %s",
+	"gui.provenance.saved":           "Gespeichert von %s am %s",
+	"gui.provenance.stale":           "Die Datei wurde seitdem von einem anderen Programm geändert",
+	"gui.range.force":                "Trotzdem schreiben",
+	"gui.range.info":                 "<b>Wert außerhalb des zulässigen Bereichs</b>\n\n%s: %.2f %s liegt außerhalb von %.2f - %.2f %s.\nWerte außerhalb dieses Bereichs können den Motor beschädigen.",
+	"gui.range.refused":              "%.2f %s liegt außerhalb des zulässigen Bereichs %.2f - %.2f %s",
+	"gui.read_attachments_failed":    "Anhänge konnten nicht gelesen werden: %v",
+	"gui.read_failed":                "Datei konnte nicht gelesen werden: %v",
+	"gui.save_as.done":               "%s als %s gespeichert; weitere Änderungen gehen in die Kopie",
+	"gui.save_as.failed":             "Speichern unter fehlgeschlagen",
+	"gui.save_as.title":              "ECU-Datei speichern unter",
+	"gui.scale.cannot":               "%s kann nicht skaliert werden",
+	"gui.scale.confirm":              "<b>%s mit %.2f skalieren?</b>\n\n%d Zellen werden geändert. %s\nEine Sicherung wird automatisch erstellt.",
+	"gui.scale.failed":               "Kennfeld konnte nicht skaliert werden",
+	"gui.scale.info":                 "Jede Rohzelle von %s mit einem Faktor multiplizieren",
+	"gui.scale.need_map":             "Vor dem Skalieren eine ECU-Datei öffnen und ein Kennfeld wählen.",
+	"gui.scale.no_changes":           "%s: keine Zellen zu ändern",
+	"gui.scale.title":                "Kennfeld skalieren",
+	"gui.scan.button":                "Datei durchsuchen",
+	"gui.scan.cancel":                "Suche abbrechen",
+	"gui.scan.canceled":              "Suche bei %s mit bisher %d möglichen Kennfeldern abgebrochen; erneut suchen, um fortzufahren",
+	"gui.scan.complete":              "Suche abgeschlossen. %d mögliche Kennfelder gefunden.",
+	"gui.scan.description":           "Die ECU-Binärdatei anhand von Datenmustern nach möglichen Kennfeldern durchsuchen.",
+	"gui.scan.dim_all":               "Alle (8x8, 8x16, 16x16)",
+	"gui.scan.dim_only":              "nur %s",
+	"gui.scan.dimensions":            "Abmessungen:",
+	"gui.scan.exhaustive":            "Vollständig (jeder Offset)",
+	"gui.scan.exhaustive_complete":   "Vollständige Suche abgeschlossen. %d mögliche Kennfelder gefunden.",
+	"gui.scan.exhaustive_started":    "Vollständige Suche gestartet; „Suche abbrechen“ hält sie an und behält einen Zwischenstand",
+	"gui.scan.failed":                "Suche fehlgeschlagen: %v",
+	"gui.scan.found":                 "%d mögliche Kennfelder gefunden:\n\n",
+	"gui.scan.gap":                   "%s durchsuchen (%d Bytes)",
+	"gui.scan.gaps":                  "Unbekannte Bereiche",
+	"gui.scan.gaps_none":             "Jeder Bereich, der groß genug für ein Kennfeld ist, ist definiert",
+	"gui.scan.gaps_tooltip":          "Einen Bytebereich durchsuchen, den keine Kennfeld- oder Parameterdefinition abdeckt",
+	"gui.scan.gaps_unavailable":      "%v",
+	"gui.scan.header":                "Binärscanner - Unbekannte Kennfelder finden",
+	"gui.scan.min_variance":          "Min. Varianz:",
+	"gui.scan.none":                  "Mit den aktuellen Kriterien wurden keine möglichen Kennfelder gefunden.",
+	"gui.scan.profile":               "Profil:",
+	"gui.scan.profile_delete":        "Löschen",
+	"gui.scan.profile_delete_failed": "Suchprofil nicht gelöscht: %v",
+	"gui.scan.profile_deleted":       "Suchprofil %s gelöscht",
+	"gui.scan.profile_save":          "Speichern",
+	"gui.scan.profile_save_failed":   "Suchprofil nicht gespeichert: %v",
+	"gui.scan.profile_saved":         "Suchprofil %s gespeichert: %s",
+	"gui.scan.profile_selected":      "Suchprofil %s: %s",
+	"gui.scan.profile_tooltip":       "Ein Profil wählen, um die Sucheinstellungen auszufüllen, oder einen Namen eingeben und mit Speichern die aktuellen behalten",
+	"gui.scan.promote":               "Kennfeld aus Treffer definieren…",
+	"gui.scan.promote_none":          "Zuerst scannen und einen Treffer wählen",
+	"gui.scan.range":                 "Nur den Bereich",
+	"gui.scan.resuming":              "Vollständige Suche wird bei %s fortgesetzt",
+	"gui.scan.started":               "Datei wird durchsucht... Dies kann einen Moment dauern.",
+	"gui.session.reloaded":           "%s wurde außerhalb des Fensters geändert und neu eingelesen",
+	"gui.sidebar":                    "ECU-Kennfelder",
+	"gui.snapshot.compare":           "Vergleichen",
+	"gui.snapshot.confirm":           "<b>%s aus dem Schnappschuss vom %s wiederherstellen?</b>\n\nDer aktuelle Inhalt wird vorher gesichert.",
+	"gui.snapshot.disabled":          "Automatische Schnappschüsse aus",
+	"gui.snapshot.enabled":           "Automatische Schnappschüsse an (alle %d Minuten oder %d Änderungen)",
+	"gui.snapshot.failed":            "Schnappschuss fehlgeschlagen: %v",
+	"gui.snapshot.none":              "Noch keine Schnappschüsse",
+	"gui.snapshot.off":               "Automatische Schnappschüsse sind aus. Sie lassen sich in den Einstellungen einschalten.",
+	"gui.snapshot.restore":           "Wiederherstellen",
+	"gui.snapshot.restore_failed":    "Wiederherstellen des Schnappschusses fehlgeschlagen",
+	"gui.snapshot.restored":          "Schnappschuss von %s wiederhergestellt",
+	"gui.snapshot.title":             "Schnappschüsse von %s",
+	"gui.source.button":              "Anzeige: %s",
+	"gui.source.compare":             "Vergleich",
+	"gui.source.current":             "diese Datei",
+	"gui.source.delta":               "Differenz",
+	"gui.source.nudge_current":       "Vor dem Anpassen die Kennfeldansicht auf diese Datei zurückschalten (Tab)",
+	"gui.source.title":               "%s — %s",
+	"gui.source.tooltip":             "Zwischen dieser Datei, der Vergleichsdatei und ihrer Differenz wechseln (Tab in der Kennfeldansicht)",
+	"gui.status.ready":               "Bereit. Zum Starten eine ECU-Datei öffnen.",
+	"gui.tab.compare":                "Parameter vergleichen",
+	"gui.tab.config":                 "Konfigurationsparameter",
+	"gui.tab.map":                    "Kennfeldansicht",
+	"gui.tab.scanner":                "Scanner",
+	"gui.timeline.comparing":         "Vergleich mit Sicherung vom %s",
+	"gui.timeline.label":             "Vergleichen mit Version:",
+	"gui.timeline.missing":           "Fehlende Sicherung wird übersprungen: %s",
+	"gui.title":                      "Motronic M2.1 ECU-Werkzeug",
+	"gui.title_file_ecu":             "Motronic M2.1 ECU-Werkzeug - %s (%s)",
+	"gui.transform.button":           "Kennfeld umrechnen…",
+	"gui.transform.cannot":           "%s kann nicht umgerechnet werden",
+	"gui.transform.cols":             "Spalten:",
+	"gui.transform.confirm":          "<b>%s umrechnen?</b>\n\n%s\nEine Sicherung wird automatisch erstellt.",
+	"gui.transform.done":             "%s umgerechnet: %d Zellen geändert",
+	"gui.transform.failed":           "Kennfeld konnte nicht umgerechnet werden",
+	"gui.transform.info":             "Zellen von %s ändern (Werte in %s)",
+	"gui.transform.need_map":         "Vor dem Umrechnen eine ECU-Datei öffnen und ein Kennfeld wählen.",
+	"gui.transform.op.add":           "Addieren",
+	"gui.transform.op.mul":           "Multiplizieren mit",
+	"gui.transform.op.set":           "Setzen auf",
+	"gui.transform.preview":          "%d von %d Zellen ändern sich, Ergebnis %.2f bis %.2f %s, %d begrenzt",
+	"gui.transform.rows":             "Zeilen:",
+	"gui.transform.title":            "Kennfeld umrechnen",
+	"gui.wizard.back":                "Zurück",
+	"gui.wizard.big_endian":          "Big-endian",
+	"gui.wizard.byte_order":          "Byte-Reihenfolge",
+	"gui.wizard.calibrate":           "Faktor setzen",
+	"gui.wizard.calibrate_failed":    "Kalibrieren nicht möglich: %v",
+	"gui.wizard.cols":                "Spalten",
+	"gui.wizard.confirm_overlap":     "Trotz Überschneidung mit vorhandenen Definitionen hinzufügen",
+	"gui.wizard.create":              "Anlegen",
+	"gui.wizard.created":             "Kennfeld %s definiert",
+	"gui.wizard.data_type":           "Datentyp",
+	"gui.wizard.description":         "Beschreibung",
+	"gui.wizard.endianness":          "16-Bit-Werte werden in der gewählten Byte-Reihenfolge gelesen; die M2.1 speichert sie little-endian.",
+	"gui.wizard.failed":              "Kennfeld nicht definiert: %v",
+	"gui.wizard.little_endian":       "Little-endian (M2.1)",
+	"gui.wizard.load_failed":         "Eigene Kennfelddefinitionen nicht geladen: %v",
+	"gui.wizard.name":                "Name",
+	"gui.wizard.next":                "Weiter",
+	"gui.wizard.offset":              "Offset",
+	"gui.wizard.page.location":       "Position",
+	"gui.wizard.page.name":           "Name",
+	"gui.wizard.page.scaling":        "Skalierung",
+	"gui.wizard.page.shape":          "Größe und Datentyp",
+	"gui.wizard.raw":                 "Roh",
+	"gui.wizard.reads_as":            "entspricht",
+	"gui.wizard.rows":                "Zeilen",
+	"gui.wizard.scale":               "Faktor",
+	"gui.wizard.scale_preview":       "Erste Zelle: roh %d = %.3f %s; alle Zellen %.3f – %.3f %s",
+	"gui.wizard.step":                "Schritt %d von %d: %s",
+	"gui.wizard.title":               "Kennfeld definieren",
+	"gui.wizard.two_point":           "Aus zwei bekannten Zellen kalibrieren",
+	"gui.wizard.unit":                "Einheit",
+	"gui.wizard.untitled":            "Unbenanntes Kennfeld",
+	"gui.wizard.use_axes":            "Vorgeschlagene Achsen übernehmen",
+	"gui.wizard.value_offset":        "Wertversatz",
+	"gui.xdf.load_failed":            "XDF nicht geladen: %v",
+	"gui.xdf.loaded":                 "%d Kennfelder und %d Parameter aus %s",
+
+	"language.name": "Deutsch",
+
+	"validate.arg_range":   "%s=%g außerhalb des Bereichs [%g, %g]",
+	"validate.arg_syntax":  "ungültiges Argument %q: erwartet wird Schlüssel=Wert",
+	"validate.arg_unknown": "unbekanntes Argument %q für Voreinstellung %s",
+	"validate.arg_value":   "ungültiger Wert für %s: %v",
+	"validate.arg_whole":   "%s muss eine ganze Zahl sein",
+	"validate.link_gap":    "%s (%.0f %s) muss mindestens %.0f %s über %s (%.0f %s) liegen",
+}
@@ -0,0 +1,406 @@
+package i18n
+
+// en is the English catalog; every key used in the code must be here
+var en = map[string]string{
+	"cli.cancelled":            "Cancelled.",
+	"cli.compare.header":       "ECU File Comparison",
+	"cli.compare.params":       "Configuration Parameters",
+	"cli.compare.section":      "Comparing: %s\n",
+	"cli.confirm.bytes":        "Write these bytes to file?",
+	"cli.confirm.change":       "Write this change?",
+	"cli.confirm.change_file":  "Write this change to file?",
+	"cli.confirm.changes":      "Write these changes to file?",
+	"cli.confirm.clamped":      "%d cells will be clamped at the data type limit. Continue anyway?",
+	"cli.defs.header":          "Definition Check",
+	"cli.dry_run":              "DRY RUN - No changes made",
+	"cli.edit.cancelled":       "Edit cancelled.",
+	"cli.edit.header":          "⚠️  INTERACTIVE EDIT MODE - USE WITH EXTREME CAUTION  ⚠️",
+	"cli.edit.risks":           "Do you understand the risks and want to proceed?",
+	"cli.edit.warning":         "Modifying ECU calibration can cause engine damage, unsafe driving conditions, warranty void, and legal issues.",
+	"cli.files.header":         "Available ECU Binary Files",
+	"cli.import.aborted":       "Aborted - file left unchanged (use -on-error skip to import only the accepted cells)",
+	"cli.import.ask":           "Some cells were clamped or rejected. Import only the accepted cells?",
+	"cli.maps.category":        "%s (%d)",
+	"cli.maps.header":          "ECU Map Reader - Motronic M2.1",
+	"cli.maps.header_ecu":      "%s · %s",
+	"cli.maps.list_header":     "Available ECU Maps",
+	"cli.no_changes":           "No cells need changing.",
+	"cli.outliers.header":      "Outlier Cells",
+	"cli.preset.header":        "PRESET MODIFICATION MODE",
+	"cli.preset.warning":       "Presets apply predefined changes. USE WITH CAUTION!",
+	"cli.quickstart.next":      "Try next",
+	"cli.scan.section":         "Potential Map Locations",
+	"cli.suggest.header":       "Suggested Fuel Corrections (advisory)",
+	"cli.timeline.header":      "ECU File Timeline",
+	"cli.timeline.section":     "Timeline: %s\n",
+	"cli.web.header":           "🌐 ECU Web Viewer Started",
+	"cli.web.opening":          "Opening web interface at %s\n",
+	"cli.web.removed_temp":     "Removed leftover temporary file %s",
+	"cli.web.shutdown_timeout": "Requests still running after %s were abandoned; temporary files were left in place",
+	"cli.web.stop":             "Press Ctrl+C to stop the server",
+	"cli.web.stopped":          "Web server stopped",
+	"cli.web.stopping":         "Stopping: finishing pending writes...",
+	"cli.would_change":         "%d cells would change\n",
+
+	"gui.about.bin_dir":              "\n\nBinary directory: %s (from %s)",
+	"gui.about.comments":             "Read, analyze, and edit Motronic M2.1 ECU binary files",
+	"gui.about.no_bin_dir":           "\n\nNo binary directory (set bin_dir in settings.json or $%s)",
+	"gui.attachments.attach":         "Attach Log...",
+	"gui.attachments.attach_failed":  "Failed to attach log: %v",
+	"gui.attachments.attached":       "Attached %s",
+	"gui.attachments.none":           "No logs attached",
+	"gui.attachments.open":           "Open",
+	"gui.attachments.open_failed":    "Failed to open %s: %v",
+	"gui.attachments.select":         "Select Log to Attach",
+	"gui.attachments.title":          "Attachments - %s",
+	"gui.blame.cell":                 "Changed by %s",
+	"gui.blame.unattributed":         "Unattributed: changed outside this tool",
+	"gui.button.apply":               "Apply",
+	"gui.button.apply_changes":       "Apply Changes",
+	"gui.button.cancel":              "Cancel",
+	"gui.button.close":               "Close",
+	"gui.button.save":                "Save",
+	"gui.button.save_changes":        "Save Changes",
+	"gui.changed.title":              "Changes in %s",
+	"gui.checksum.fix":               "Store Checksum",
+	"gui.checksum.fix_failed":        "Failed to store the checksum: %v",
+	"gui.checksum.fixed":             "Stored checksum 0x%X at 0x%04X",
+	"gui.checksum.stale":             "The save left the image checksum stale: %s",
+	"gui.checksum.stale_title":       "The image checksum no longer matches",
+	"gui.code.info":                  "%s is an unconfirmed map definition and its bytes %s. Writing to program code can brick the ECU.",
+	"gui.code.locked":                "%s is locked: it is unconfirmed and %s",
+	"gui.code.prompt":                "Type \"%s\" to unlock it, then start the edit again:",
+	"gui.code.title":                 "Map looks like program code",
+	"gui.code.unlock":                "Unlock",
+	"gui.code.unlocked":              "%s unlocked for editing; the edit will be marked in the changelog",
+	"gui.compare.all_match":          "All configuration parameters match %s.",
+	"gui.compare.choose":             "Use Compare Files to choose a second file.",
+	"gui.compare.comparing":          "Comparing with: %s",
+	"gui.compare.header":             "Parameter Differences",
+	"gui.compare.implausible":        "⚠ marks values outside the parameter's plausible range",
+	"gui.compare.params_failed":      "Error comparing parameters: %v",
+	"gui.compare.select":             "Select ECU File to Compare",
+	"gui.compare_identify_failed":    "Error identifying comparison file: %v",
+	"gui.config.cannot_move":         "Cannot move %s",
+	"gui.config.confirm_detail":      "Parameter: %s\nNew Value: %.1f %s\n\n",
+	"gui.config.current":             "Current Value: %.1f %s",
+	"gui.config.edit":                "Edit",
+	"gui.config.edit_title":          "Edit %s",
+	"gui.config.error":               "Error",
+	"gui.config.header":              "ECU Configuration Parameters",
+	"gui.config.linked":              "Linked: %s (edits move the group together)",
+	"gui.config.move_linked":         "Move %s by the same amount",
+	"gui.config.out_of_range":        "Value out of range! Must be between %.1f and %.1f",
+	"gui.config.range":               "Valid Range: %.1f - %.1f %s",
+	"gui.config.read_failed":         "Failed to read parameter: %v",
+	"gui.config.save_failed":         "Failed to save parameter",
+	"gui.config.save_group_failed":   "Failed to save parameters",
+	"gui.config.warning":             "⚠️  Modifying ECU parameters can damage your engine!",
+	"gui.confirm_modification":       "<b>Confirm ECU Modification</b>\n\nThis will modify the ECU binary file.\nA backup will be created automatically.\n\n%sProceed with caution!",
+	"gui.diff.badge_skipped":         "Not compared: %s",
+	"gui.diff.badge_tooltip":         "%d of %d cells changed",
+	"gui.diff.counted":               "Counted changed cells of %d maps",
+	"gui.diff.export":                "Export Diff Report",
+	"gui.diff.export_failed":         "Failed to write diff report: %v",
+	"gui.diff.export_select":         "Save Diff Report",
+	"gui.diff.export_tooltip":        "Save the comparison as HTML, JSON or CSV, chosen by the file extension",
+	"gui.diff.exported":              "Diff report written to %s",
+	"gui.diff.failed":                "Failed to compare the files: %v",
+	"gui.diff.not_ready":             "The comparison is still running",
+	"gui.diff.opened":                "Comparing %s with %s (read-only)",
+	"gui.diff.read_only":             "This window compares two files and can't edit them. Open a file to edit it.",
+	"gui.diff.title":                 "Motronic M2.1 ECU Tool - diff %s ↔ %s (read-only)",
+	"gui.divergence.button":          "Show divergence",
+	"gui.divergence.count":           "%d cell(s) differ",
+	"gui.divergence.none":            "All maps and parameters hold the same raw values",
+	"gui.divergence.title":           "Divergence: %s vs %s",
+	"gui.edit.info":                  "Map: %s\nPosition: Row %d, Column %d\nCurrent Value: %.2f %s",
+	"gui.edit.range":                 "Allowed range: %.2f - %.2f %s",
+	"gui.edit.save_failed":           "Failed to save edit",
+	"gui.edit.title":                 "Edit Cell Value",
+	"gui.edit.updated":               "Cell [%d,%d] updated to %.2f %s",
+	"gui.engine_warning":             "⚠️  Modifying ECU values can damage your engine!",
+	"gui.export.done":                "Map exported successfully to %s",
+	"gui.export.failed":              "Export failed: %v",
+	"gui.export.select":              "Select Export Directory",
+	"gui.find.hint":                  "Compare value or raw of one map or \"any\" with > >= < <= == !=",
+	"gui.find.title":                 "Find Cells",
+	"gui.fuelcut.cannot":             "Cannot plan the rev limit change",
+	"gui.fuelcut.detected":           "Fuel map: %d fuel-cut column(s) from %.0f RPM. Moving the limit without moving the cut makes the engine buck.",
+	"gui.fuelcut.done":               "Rev limit set to %.0f RPM and fuel cut moved by %+d column(s)",
+	"gui.fuelcut.failed":             "Failed to write the rev limit and fuel cut",
+	"gui.fuelcut.op":                 "%s: %d cell(s)",
+	"gui.fuelcut.shift":              "Move fuel cut by columns:",
+	"gui.import.accept":              "Import",
+	"gui.import.accept_partial":      "Import Accepted Cells",
+	"gui.import.detail":              "%d accepted · %d snapped · %d clamped · %d rejected",
+	"gui.import.done":                "Imported %d cells",
+	"gui.import.failed":              "Import failed",
+	"gui.import.filter":              "Map CSV Files (*.csv)",
+	"gui.import.incomplete":          " Clamped and rejected cells are not written; importing keeps only the accepted and snapped cells.",
+	"gui.import.rejected":            "Rejected: %s",
+	"gui.import.report":              "Import Report",
+	"gui.import.summary":             "%d cells would change.",
+	"gui.import.title":               "Import Map CSV",
+	"gui.invalid_value":              "Invalid value: %v",
+	"gui.layout":                     "%s: image at 0x%X (%s)",
+	"gui.linked.dropped":             "Linked file dropped: %v",
+	"gui.linked.failed":              "Cannot link file: %v",
+	"gui.linked.linked":              "Edits are now also written to %s",
+	"gui.linked.select":              "Select a file to edit in lock step",
+	"gui.linked.title":               "%s ⇄ %s",
+	"gui.linked.unlinked":            "Edits are no longer written to %s",
+	"gui.loaded":                     "Loaded: %s",
+	"gui.loaded_ecu":                 "Loaded: %s (%s)",
+	"gui.log.all":                    "All",
+	"gui.log.clear":                  "Clear",
+	"gui.log.copied":                 "Log copied to clipboard",
+	"gui.log.copy":                   "Copy",
+	"gui.log.errors":                 "Errors",
+	"gui.log.show":                   "Show:",
+	"gui.log.title":                  "Log",
+	"gui.log.warnings":               "Warnings",
+	"gui.map.compare_read_failed":    "Error reading comparison map: %v",
+	"gui.map.empty":                  "No ECU file loaded",
+	"gui.map.empty_hint":             "Click 'Open ECU File' to begin",
+	"gui.map.load_axis":              "Load",
+	"gui.map.read_failed":            "Error reading map: %v",
+	"gui.map.unit":                   "Unit: %s",
+	"gui.maps.filter":                "Map definitions (JSON, YAML)",
+	"gui.maps.load_failed":           "Map definitions not loaded: %v",
+	"gui.maps.loaded":                "Added %d maps from %s",
+	"gui.maps.title":                 "Load Map Definitions",
+	"gui.menu.about":                 "About",
+	"gui.menu.attachments":           "Attachments...",
+	"gui.menu.changed":               "What Changed Since Yesterday...",
+	"gui.menu.compare":               "Compare Files",
+	"gui.menu.define_map":            "Define Map…",
+	"gui.menu.export":                "Export to CSV...",
+	"gui.menu.find":                  "Find Cells...",
+	"gui.menu.import":                "Import CSV...",
+	"gui.menu.load_maps":             "Load Map Definitions…",
+	"gui.menu.open":                  "Open File...",
+	"gui.menu.open_linked":           "Open Linked File…",
+	"gui.menu.preferences":           "Preferences",
+	"gui.menu.preset":                "Apply Preset...",
+	"gui.menu.project":               "Open Project...",
+	"gui.menu.quit":                  "Quit",
+	"gui.menu.save_as":               "Save As...",
+	"gui.menu.scale":                 "Scale Map...",
+	"gui.menu.scanner":               "Scanner",
+	"gui.menu.snapshots":             "Snapshots…",
+	"gui.menu.unlink":                "Unlink File",
+	"gui.mirror.stale":               "Mirrored dump: only the first of %d copies of the image was written, so the copies now differ (see Preferences)",
+	"gui.more":                       "… and %d more",
+	"gui.need_file":                  "Please open an ECU file first",
+	"gui.new_value":                  "New Value:",
+	"gui.no_files":                   "No ECU files found in bins/",
+	"gui.nudge.cannot":               "Cannot nudge",
+	"gui.nudge.confirm":              "<b>Nudge %s [%d,%d]?</b>\n\n%.2f → %.2f %s\n\nA backup will be created automatically.",
+	"gui.nudge.failed":               "Failed to nudge cell",
+	"gui.nudge.write":                "Write",
+	"gui.open.failed":                "Cannot open %s: %v",
+	"gui.open.filter":                "ECU Binary Files (*.bin)",
+	"gui.open.not_image":             "Not an ECU image: %s (expected a .bin file)",
+	"gui.open.title":                 "Open ECU Binary File",
+	"gui.outliers.cell":              "Outlier: neighborhood median %.2f, smoothed %.2f",
+	"gui.outliers.found":             "%s: %d outlier cell(s), threshold %.2f %s",
+	"gui.outliers.toggle":            "Outliers",
+	"gui.outliers.tooltip":           "Outline cells that deviate from the median of their 3x3 neighborhood by more than 10% of the map's range",
+	"gui.overlay.loaded":             "Overlaying %s: %d samples (%d skipped, %d outside grid)",
+	"gui.overlay.no_log":             "No attached log found; attach one via File > Attachments",
+	"gui.overlay.parse_failed":       "Failed to parse %s: %v",
+	"gui.overlay.toggle":             "Overlay attached lambda log",
+	"gui.overlay.tooltip":            "Bin the most recent attached log onto the Lambda Target Map",
+	"gui.prefs.checksum":             "Checksum on save:",
+	"gui.prefs.checksum.always":      "Always store",
+	"gui.prefs.checksum.ask":         "Ask when stale",
+	"gui.prefs.checksum.never":       "Never (flashing tool fixes it)",
+	"gui.prefs.checksum_set":         "Checksum policy set to %s",
+	"gui.prefs.confirmations":        "Confirmations:",
+	"gui.prefs.hint":                 "Edits are still backed up automatically whatever the policy.",
+	"gui.prefs.ignored":              "Ignoring saved setting: %v",
+	"gui.prefs.language":             "Language:",
+	"gui.prefs.language_set":         "Language set to %s; restart to apply it everywhere",
+	"gui.prefs.language_system":      "System default ($LANG)",
+	"gui.prefs.load_failed":          "Could not load settings: %v",
+	"gui.prefs.mirror_writes":        "Write edits of mirrored dumps to every copy",
+	"gui.prefs.mirror_writes_hint":   "A dump holding the image more than once, such as a 27C256 image read as a 27C512, keeps its copies equal. Otherwise only the first copy is written and the log warns.",
+	"gui.prefs.mirror_writes_off":    "Edits of mirrored dumps now write the first copy only",
+	"gui.prefs.mirror_writes_on":     "Edits of mirrored dumps now write every copy",
+	"gui.prefs.policy.full":          "Full (confirm every change)",
+	"gui.prefs.policy.never":         "Never (expert)",
+	"gui.prefs.policy.save":          "Confirm on save only",
+	"gui.prefs.policy_set":           "Confirmation policy set to %s",
+	"gui.prefs.require_backup":       "Require a verified backup before every write",
+	"gui.prefs.require_backup_hint":  "Nothing is written until a backup of the file's current contents has been read back and its SHA-256 checked. A matching backup from today is reused.",
+	"gui.prefs.require_backup_off":   "Writes no longer require a verified backup",
+	"gui.prefs.require_backup_on":    "Writes now require a verified backup",
+	"gui.prefs.save_failed":          "Failed to save settings: %v",
+	"gui.prefs.snapshots":            "Take automatic snapshots",
+	"gui.prefs.snapshots_hint":       "Every %d minutes or %d edits, only if the file changed. Snapshots sit next to the file and the newest %d are kept; operation backups are not affected.",
+	"gui.preset.applied":             "Preset %s applied: %d cells changed",
+	"gui.preset.cannot":              "Cannot apply %s",
+	"gui.preset.confirm":             "<b>Apply preset %s?</b>\n\n%d cells will change. A backup will be created automatically.\n\n<tt>%s</tt>",
+	"gui.preset.error":               "Error: %v",
+	"gui.preset.failed":              "Failed to apply preset",
+	"gui.preset.need_file":           "Open an ECU file before applying a preset.",
+	"gui.preset.no_changes":          "Preset %s: no cells need changing",
+	"gui.preset.title":               "Apply Preset",
+	"gui.profile.fixed":              "The definitions come from --xdf or --maps-mode replace, so the profile can't be changed",
+	"gui.profile.invalid":            "Profile not selected: %v",
+	"gui.profile.load_failed":        "Profiles not loaded: %v",
+	"gui.profile.switched":           "Switched to profile %s",
+	"gui.profile.tooltip":            "ECU profile: the map and parameter definitions of a firmware variant",
+	"gui.project.filter":             "Project Files (*.project.json)",
+	"gui.project.load_failed":        "Failed to load project: %v",
+	"gui.project.no_file":            "Project %s names no ECU file",
+	"gui.project.offsets_web_only":   "The project's map offset overrides only apply in the web UI",
+	"gui.project.opened":             "Opened project %s",
+	"gui.project.title":              "Open Project",
+	"gui.provenance.definitions":     "Definitions: %s",
+	"gui.provenance.defs_changed":    "The definitions have changed since this save",
+	"gui.provenance.label":           "Modified with this tool",
+	"gui.provenance.modified":        "Modified: %s",
+	"gui.provenance.saved":           "Saved by %s on %s",
+	"gui.provenance.stale":           "The file was changed by another program since",
+	"gui.range.force":                "Write Anyway",
+	"gui.range.info":                 "<b>Value outside the allowed range</b>\n\n%s: %.2f %s is outside %.2f - %.2f %s.\nValues outside this range can damage the engine.",
+	"gui.range.refused":              "%.2f %s is outside the allowed range %.2f - %.2f %s",
+	"gui.read_attachments_failed":    "Failed to read attachments: %v",
+	"gui.read_failed":                "Failed to read file: %v",
+	"gui.save_as.done":               "Saved %s as %s; further edits go to the copy",
+	"gui.save_as.failed":             "Save As failed",
+	"gui.save_as.title":              "Save ECU File As",
+	"gui.scale.cannot":               "Cannot scale %s",
+	"gui.scale.confirm":              "<b>Scale %s by %.2f?</b>\n\n%d cells will change. %s\nA backup will be created automatically.",
+	"gui.scale.failed":               "Failed to scale map",
+	"gui.scale.info":                 "Multiply every raw cell of %s by a factor",
+	"gui.scale.need_map":             "Open an ECU file and select a map before scaling.",
+	"gui.scale.no_changes":           "%s: no cells need changing",
+	"gui.scale.title":                "Scale Map",
+	"gui.scan.button":                "Scan File",
+	"gui.scan.cancel":                "Cancel Scan",
+	"gui.scan.canceled":              "Scan canceled at %s with %d potential maps so far; scan again to continue",
+	"gui.scan.complete":              "Scan complete. Found %d potential maps.",
+	"gui.scan.description":           "Scan the ECU binary file for potential map locations based on data patterns.",
+	"gui.scan.dim_all":               "All (8x8, 8x16, 16x16)",
+	"gui.scan.dim_only":              "%s only",
+	"gui.scan.dimensions":            "Dimensions:",
+	"gui.scan.exhaustive":            "Exhaustive (every offset)",
+	"gui.scan.exhaustive_complete":   "Exhaustive scan complete. Found %d potential maps.",
+	"gui.scan.exhaustive_started":    "Exhaustive scan started; press Cancel Scan to stop and keep a checkpoint",
+	"gui.scan.failed":                "Scan failed: %v",
+	"gui.scan.found":                 "Found %d potential maps:\n\n",
+	"gui.scan.gap":                   "Scan %s (%d bytes)",
+	"gui.scan.gaps":                  "Unknown regions",
+	"gui.scan.gaps_none":             "Every region large enough for a map is defined",
+	"gui.scan.gaps_tooltip":          "Scan a byte range no map or parameter definition covers",
+	"gui.scan.gaps_unavailable":      "%v",
+	"gui.scan.header":                "Binary Scanner - Find Unknown Maps",
+	"gui.scan.min_variance":          "Min Variance:",
+	"gui.scan.none":                  "No potential maps found with the current criteria.",
+	"gui.scan.profile":               "Profile:",
+	"gui.scan.profile_delete":        "Delete",
+	"gui.scan.profile_delete_failed": "Scan profile not deleted: %v",
+	"gui.scan.profile_deleted":       "Deleted scan profile %s",
+	"gui.scan.profile_save":          "Save",
+	"gui.scan.profile_save_failed":   "Scan profile not saved: %v",
+	"gui.scan.profile_saved":         "Saved scan profile %s: %s",
+	"gui.scan.profile_selected":      "Scan profile %s: %s",
+	"gui.scan.profile_tooltip":       "Pick a profile to fill in the scan settings, or type a name and press Save to keep the current ones",
+	"gui.scan.promote":               "Define Map from Hit…",
+	"gui.scan.promote_none":          "Run a scan and pick a hit first",
+	"gui.scan.range":                 "Only the range",
+	"gui.scan.resuming":              "Resuming exhaustive scan at %s",
+	"gui.scan.started":               "Scanning file... This may take a moment.",
+	"gui.session.reloaded":           "%s was changed outside the window and has been read again",
+	"gui.sidebar":                    "ECU Maps",
+	"gui.snapshot.compare":           "Compare",
+	"gui.snapshot.confirm":           "<b>Restore %s from the snapshot of %s?</b>\n\nThe current contents are backed up first.",
+	"gui.snapshot.disabled":          "Automatic snapshots off",
+	"gui.snapshot.enabled":           "Automatic snapshots on (every %d minutes or %d edits)",
+	"gui.snapshot.failed":            "Snapshot failed: %v",
+	"gui.snapshot.none":              "No snapshots yet",
+	"gui.snapshot.off":               "Automatic snapshots are off. Turn them on in Preferences.",
+	"gui.snapshot.restore":           "Restore",
+	"gui.snapshot.restore_failed":    "Restoring the snapshot failed",
+	"gui.snapshot.restored":          "Restored the snapshot of %s",
+	"gui.snapshot.title":             "Snapshots of %s",
+	"gui.source.button":              "Showing: %s",
+	"gui.source.compare":             "comparison",
+	"gui.source.current":             "this file",
+	"gui.source.delta":               "difference",
+	"gui.source.nudge_current":       "Switch the map view back to this file (Tab) before nudging",
+	"gui.source.title":               "%s — %s",
+	"gui.source.tooltip":             "Cycle between this file, the comparison file and their difference (Tab in the map view)",
+	"gui.status.ready":               "Ready. Open an ECU file to begin.",
+	"gui.tab.compare":                "Compare Parameters",
+	"gui.tab.config":                 "Config Parameters",
+	"gui.tab.map":                    "Map View",
+	"gui.tab.scanner":                "Scanner",
+	"gui.timeline.comparing":         "Comparing with backup from %s",
+	"gui.timeline.label":             "Compare with version:",
+	"gui.timeline.missing":           "Skipping missing backup: %s",
+	"gui.title":                      "Motronic M2.1 ECU Tool",
+	"gui.title_file_ecu":             "Motronic M2.1 ECU Tool - %s (%s)",
+	"gui.transform.button":           "Transform Map…",
+	"gui.transform.cannot":           "Cannot transform %s",
+	"gui.transform.cols":             "Columns:",
+	"gui.transform.confirm":          "<b>Transform %s?</b>\n\n%s\nA backup will be created automatically.",
+	"gui.transform.done":             "%s transformed: %d cells changed",
+	"gui.transform.failed":           "Failed to transform map",
+	"gui.transform.info":             "Change the cells of %s (values in %s)",
+	"gui.transform.need_map":         "Open an ECU file and select a map before transforming.",
+	"gui.transform.op.add":           "Add",
+	"gui.transform.op.mul":           "Multiply by",
+	"gui.transform.op.set":           "Set to",
+	"gui.transform.preview":          "%d of %d cells change, result %.2f to %.2f %s, %d clamped",
+	"gui.transform.rows":             "Rows:",
+	"gui.transform.title":            "Transform Map",
+	"gui.wizard.back":                "Back",
+	"gui.wizard.big_endian":          "Big-endian",
+	"gui.wizard.byte_order":          "Byte order",
+	"gui.wizard.calibrate":           "Set scale",
+	"gui.wizard.calibrate_failed":    "Cannot calibrate: %v",
+	"gui.wizard.cols":                "Columns",
+	"gui.wizard.confirm_overlap":     "Add it although it overlaps existing definitions",
+	"gui.wizard.create":              "Create",
+	"gui.wizard.created":             "Defined map %s",
+	"gui.wizard.data_type":           "Data type",
+	"gui.wizard.description":         "Description",
+	"gui.wizard.endianness":          "16-bit values are read in the chosen byte order; the M2.1 stores them little-endian.",
+	"gui.wizard.failed":              "Map not defined: %v",
+	"gui.wizard.little_endian":       "Little-endian (M2.1)",
+	"gui.wizard.load_failed":         "User map definitions not loaded: %v",
+	"gui.wizard.name":                "Name",
+	"gui.wizard.next":                "Next",
+	"gui.wizard.offset":              "Offset",
+	"gui.wizard.page.location":       "Location",
+	"gui.wizard.page.name":           "Name",
+	"gui.wizard.page.scaling":        "Scaling",
+	"gui.wizard.page.shape":          "Size and data type",
+	"gui.wizard.raw":                 "Raw",
+	"gui.wizard.reads_as":            "reads as",
+	"gui.wizard.rows":                "Rows",
+	"gui.wizard.scale":               "Scale",
+	"gui.wizard.scale_preview":       "First cell: raw %d = %.3f %s; all cells %.3f – %.3f %s",
+	"gui.wizard.step":                "Step %d of %d: %s",
+	"gui.wizard.title":               "Define Map",
+	"gui.wizard.two_point":           "Calibrate from two known cells",
+	"gui.wizard.unit":                "Unit",
+	"gui.wizard.untitled":            "Untitled map",
+	"gui.wizard.use_axes":            "Use the suggested axes",
+	"gui.wizard.value_offset":        "Value offset",
+	"gui.xdf.load_failed":            "XDF not loaded: %v",
+	"gui.xdf.loaded":                 "%d maps and %d parameters from %s",
+
+	"language.name": "English",
+
+	"validate.arg_range":   "%s=%g out of range [%g, %g]",
+	"validate.arg_syntax":  "invalid argument %q: expected key=value",
+	"validate.arg_unknown": "unknown argument %q for preset %s",
+	"validate.arg_value":   "invalid value for %s: %v",
+	"validate.arg_whole":   "%s must be a whole number",
+	"validate.link_gap":    "%s (%.0f %s) must be at least %.0f %s above %s (%.0f %s)",
+}
@@ -0,0 +1,113 @@
+// Package i18n translates user-facing strings of the GUI and CLI. Messages
+// are looked up by key in a catalog for the active locale, falling back to
+// English and then to the key itself, so a missing translation never hides
+// a message.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// English is the fallback locale every key must exist in
+const English = "en"
+
+// catalogs holds the messages of every supported locale by key
+var catalogs = map[string]map[string]string{
+	English: en,
+	"de":    de,
+}
+
+var active = English
+
+// Locales returns the supported locales, English first
+func Locales() []string {
+	locales := []string{English}
+	for locale := range catalogs {
+		if locale != English {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales[1:])
+	return locales
+}
+
+// Name returns a locale's name in its own language, e.g. "Deutsch"
+func Name(locale string) string {
+	if name, ok := catalogs[locale]["language.name"]; ok {
+		return name
+	}
+	return locale
+}
+
+// Normalize turns a POSIX locale such as "de_DE.UTF-8" into a supported
+// catalog name, or "" if there is no catalog for it
+func Normalize(locale string) string {
+	locale = strings.ToLower(locale)
+	if i := strings.IndexAny(locale, "_.@-"); i >= 0 {
+		locale = locale[:i]
+	}
+	if _, ok := catalogs[locale]; ok {
+		return locale
+	}
+	return ""
+}
+
+// Detect picks the locale from the saved setting if it is set, otherwise
+// from LC_ALL, LC_MESSAGES and LANG, defaulting to English
+func Detect(setting string) string {
+	candidates := []string{setting, os.Getenv("LC_ALL"), os.Getenv("LC_MESSAGES"), os.Getenv("LANG")}
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		if locale := Normalize(c); locale != "" {
+			return locale
+		}
+		// An explicit but unsupported choice still means "not the next one"
+		return English
+	}
+	return English
+}
+
+// SetLocale activates a locale; unsupported locales select English
+func SetLocale(locale string) {
+	active = Normalize(locale)
+	if active == "" {
+		active = English
+	}
+}
+
+// Locale returns the active locale
+func Locale() string {
+	return active
+}
+
+// T returns the message for key in the active locale, formatted with args
+// like fmt.Sprintf when any are given
+func T(key string, args ...interface{}) string {
+	msg, ok := catalogs[active][key]
+	if !ok {
+		if msg, ok = en[key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Missing returns the English keys a locale has no translation for, sorted
+func Missing(locale string) []string {
+	var missing []string
+	for key := range en {
+		if _, ok := catalogs[locale][key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
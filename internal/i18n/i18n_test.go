@@ -0,0 +1,148 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, locale := range Locales() {
+		if missing := Missing(locale); len(missing) > 0 {
+			t.Errorf("%s has no translation for %d key(s): %s", locale, len(missing), strings.Join(missing, ", "))
+		}
+		for key := range catalogs[locale] {
+			if _, ok := en[key]; !ok {
+				t.Errorf("%s translates %s, which English doesn't have", locale, key)
+			}
+		}
+	}
+}
+
+// keyPattern matches string literals that look like catalog keys. A
+// trailing dot marks a prefix completed at run time, such as
+// "gui.wizard.page."+wizardPages[page].
+var keyPattern = regexp.MustCompile(`^(cli|gui|language|validate)\.[a-z0-9_.]+$`)
+
+// TestReferencedKeysExist fails when the source refers to a key the
+// English catalog doesn't have. Keys passed through variables are found
+// because their literals match keyPattern wherever they are written.
+// Prefixes must be joined to an element of a package-level string slice
+// literal, whose elements are checked as the suffixes.
+func TestReferencedKeysExist(t *testing.T) {
+	root := filepath.Join("..", "..")
+	catalogDir := filepath.Join(root, "internal", "i18n")
+	fset := token.NewFileSet()
+	checked := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") || filepath.Dir(path) == catalogDir {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		slices := stringSlices(file)
+		ast.Inspect(file, func(n ast.Node) bool {
+			if bin, ok := n.(*ast.BinaryExpr); ok && bin.Op == token.ADD {
+				if prefix, ok := stringLit(bin.X); ok && strings.HasSuffix(prefix, ".") && keyPattern.MatchString(prefix) {
+					checked += checkPrefix(t, fset.Position(bin.Pos()), prefix, bin.Y, slices)
+					return false
+				}
+			}
+			if key, ok := stringLit(n); ok && keyPattern.MatchString(key) && !strings.HasSuffix(key, ".go") {
+				checked++
+				if strings.HasSuffix(key, ".") {
+					t.Errorf("%s: key prefix %q is not joined to a slice element", fset.Position(n.Pos()), key)
+				} else if _, ok := en[key]; !ok {
+					t.Errorf("%s: %q is not in the English catalog", fset.Position(n.Pos()), key)
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked < len(en)/2 {
+		t.Errorf("only %d key references found; is the source walk broken?", checked)
+	}
+}
+
+// checkPrefix checks prefix joined to each element of the slice that
+// suffix indexes and returns how many keys it checked
+func checkPrefix(t *testing.T, pos token.Position, prefix string, suffix ast.Expr, slices map[string][]string) int {
+	t.Helper()
+	index, ok := suffix.(*ast.IndexExpr)
+	var elems []string
+	if ok {
+		if ident, ok := index.X.(*ast.Ident); ok {
+			elems = slices[ident.Name]
+		}
+	}
+	if len(elems) == 0 {
+		t.Errorf("%s: key prefix %q is joined to something other than a string slice literal", pos, prefix)
+		return 0
+	}
+	for _, elem := range elems {
+		if _, ok := en[prefix+elem]; !ok {
+			t.Errorf("%s: %q is not in the English catalog", pos, prefix+elem)
+		}
+	}
+	return len(elems)
+}
+
+// stringSlices returns the package-level variables of file that are
+// string slice literals, by name
+func stringSlices(file *ast.File) map[string][]string {
+	slices := make(map[string][]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, value := range vs.Values {
+				lit, ok := value.(*ast.CompositeLit)
+				if !ok || i >= len(vs.Names) {
+					continue
+				}
+				var elems []string
+				for _, elt := range lit.Elts {
+					if s, ok := stringLit(elt); ok {
+						elems = append(elems, s)
+					}
+				}
+				slices[vs.Names[i].Name] = elems
+			}
+		}
+	}
+	return slices
+}
+
+// stringLit returns the value of a string literal node
+func stringLit(n ast.Node) (string, bool) {
+	lit, ok := n.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
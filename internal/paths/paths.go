@@ -0,0 +1,87 @@
+// Package paths resolves where the tool stores persistent state such as
+// settings, caches and metadata. All features that write state outside the
+// ECU binary itself must resolve their locations through this package.
+//
+// By default the platform's user config and cache directories are used
+// (XDG on Linux, ~/Library on macOS, %AppData% on Windows). A single
+// directory override, set with the -config flag or the MOTRONIC_CONFIG_DIR
+// environment variable, keeps all state in one place for portable use.
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appName = "motronic-m21-tool"
+
+// EnvConfigDir is the environment variable that overrides the state directory
+const EnvConfigDir = "MOTRONIC_CONFIG_DIR"
+
+var override string
+
+// SetOverride stores all state under dir instead of the platform defaults
+func SetOverride(dir string) {
+	override = dir
+}
+
+// overrideDir returns the active override directory, if any
+func overrideDir() string {
+	if override != "" {
+		return override
+	}
+	return os.Getenv(EnvConfigDir)
+}
+
+// ConfigDir returns the directory for settings and other persistent state
+func ConfigDir() (string, error) {
+	if dir := overrideDir(); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appName), nil
+}
+
+// CacheDir returns the directory for data that can be regenerated
+func CacheDir() (string, error) {
+	if dir := overrideDir(); dir != "" {
+		return filepath.Join(dir, "cache"), nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appName), nil
+}
+
+// ConfigFile returns the path of a named file in the config directory,
+// creating the directory if needed
+func ConfigFile(name string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// CacheSubdir returns a named subdirectory of the cache directory,
+// creating it if needed
+func CacheSubdir(name string) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
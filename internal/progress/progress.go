@@ -0,0 +1,98 @@
+// Package progress provides a shared progress bar for long-running batch
+// operations such as folder exports and exhaustive scans.
+package progress
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Quiet suppresses all progress rendering, e.g. for machine-readable output
+var Quiet bool
+
+// Reporter renders a progress bar with item count, current item and ETA.
+// Step may be called concurrently from worker goroutines; updates are
+// serialized through a channel and rendered by a single goroutine.
+type Reporter struct {
+	title   string
+	total   int
+	started time.Time
+	bar     *pterm.ProgressbarPrinter
+	updates chan string
+	done    chan struct{}
+}
+
+// Start creates a Reporter for total items and begins rendering it. When
+// output is not a terminal or Quiet is set, the Reporter is silent.
+func Start(title string, total int) *Reporter {
+	r := &Reporter{
+		title:   title,
+		total:   total,
+		started: time.Now(),
+		updates: make(chan string, 64),
+		done:    make(chan struct{}),
+	}
+
+	if !Quiet && isTerminal() && total > 0 {
+		r.bar, _ = pterm.DefaultProgressbar.
+			WithTotal(total).
+			WithTitle(title).
+			WithShowCount().
+			WithRemoveWhenDone().
+			Start()
+	}
+
+	go r.run()
+	return r
+}
+
+// Step marks one item as processed; detail names the current file or offset
+func (r *Reporter) Step(detail string) {
+	r.updates <- detail
+}
+
+// Stop finishes rendering and waits for all pending updates to be drawn
+func (r *Reporter) Stop() {
+	close(r.updates)
+	<-r.done
+}
+
+func (r *Reporter) run() {
+	defer close(r.done)
+
+	current := 0
+	for detail := range r.updates {
+		current++
+		if r.bar == nil {
+			continue
+		}
+		r.bar.UpdateTitle(fmt.Sprintf("%s | %s | ETA %s", r.title, detail, r.eta(current)))
+		r.bar.Increment()
+	}
+
+	if r.bar != nil {
+		r.bar.Stop()
+	}
+}
+
+// eta estimates the remaining time from the average duration per item
+func (r *Reporter) eta(current int) string {
+	if current == 0 || current >= r.total {
+		return "0s"
+	}
+	perItem := time.Since(r.started) / time.Duration(current)
+	remaining := perItem * time.Duration(r.total-current)
+	return remaining.Round(time.Second).String()
+}
+
+// isTerminal reports whether stdout is attached to a terminal
+func isTerminal() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
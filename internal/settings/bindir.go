@@ -0,0 +1,44 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// EnvBinDir is the environment variable naming the default directory of
+// ECU binaries
+const EnvBinDir = "ECU_READER_BINS"
+
+// localBinDir is used when nothing else is configured and it exists in the
+// working directory
+const localBinDir = "bins"
+
+// DefaultBinDir resolves the directory holding ECU binaries, used by the
+// CLI file listing, the GUI file chooser and the web server's folder mode.
+// The first of these wins: flagDir, the bin_dir setting, $ECU_READER_BINS,
+// ./bins if it exists. It returns the absolute directory and where it came
+// from, or two empty strings when none applies.
+func DefaultBinDir(flagDir string) (dir, source string) {
+	// A missing or unreadable settings file just skips that source
+	saved, _ := Load()
+
+	switch {
+	case flagDir != "":
+		dir, source = flagDir, "-bins flag"
+	case saved.BinDir != "":
+		dir, source = saved.BinDir, "settings file"
+	case os.Getenv(EnvBinDir) != "":
+		dir, source = os.Getenv(EnvBinDir), "$"+EnvBinDir
+	default:
+		info, err := os.Stat(localBinDir)
+		if err != nil || !info.IsDir() {
+			return "", ""
+		}
+		dir, source = localBinDir, "./"+localBinDir
+	}
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return dir, source
+}
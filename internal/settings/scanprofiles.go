@@ -0,0 +1,54 @@
+package settings
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
+)
+
+// AllScanProfiles returns the built-in scan profiles followed by the
+// saved ones
+func (s *Settings) AllScanProfiles() []scanner.Profile {
+	return slices.Concat(scanner.BuiltinProfiles, s.ScanProfiles)
+}
+
+// ScanProfile returns the built-in or saved scan profile called name
+func (s *Settings) ScanProfile(name string) (scanner.Profile, error) {
+	return scanner.FindProfile(s.ScanProfiles, name)
+}
+
+// SetScanProfile adds p to the saved profiles, replacing one of the same
+// name. Built-in profiles can't be replaced. The settings still have to be
+// saved.
+func (s *Settings) SetScanProfile(p scanner.Profile) error {
+	if err := p.Check(); err != nil {
+		return err
+	}
+	if scanner.IsBuiltinProfile(p.Name) {
+		return fmt.Errorf("%s is a built-in scan profile; save under another name", p.Name)
+	}
+	for i := range s.ScanProfiles {
+		if s.ScanProfiles[i].Name == p.Name {
+			s.ScanProfiles[i] = p
+			return nil
+		}
+	}
+	s.ScanProfiles = append(s.ScanProfiles, p)
+	return nil
+}
+
+// DeleteScanProfile removes a saved profile. The settings still have to
+// be saved.
+func (s *Settings) DeleteScanProfile(name string) error {
+	if scanner.IsBuiltinProfile(name) {
+		return fmt.Errorf("%s is a built-in scan profile and can't be deleted", name)
+	}
+	i := slices.IndexFunc(s.ScanProfiles, func(p scanner.Profile) bool { return p.Name == name })
+	if i < 0 {
+		return reader.NewError(reader.ErrNotFound, "no saved scan profile %q", name)
+	}
+	s.ScanProfiles = slices.Delete(s.ScanProfiles, i, i+1)
+	return nil
+}
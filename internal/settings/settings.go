@@ -0,0 +1,86 @@
+// Package settings loads and saves user preferences shared by the CLI,
+// GUI and web interface. The file lives in the config directory resolved
+// by the paths package.
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/tosih/motronic-m21-tool/internal/paths"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
+)
+
+// fileName is the settings file inside the config directory
+const fileName = "settings.json"
+
+// Settings holds user preferences. Zero values mean "use the default".
+type Settings struct {
+	// ConfirmPolicy is full, confirm-on-save-only or never
+	ConfirmPolicy string `json:"confirm_policy,omitempty"`
+	// BinDir is the default directory of ECU binaries (see DefaultBinDir)
+	BinDir string `json:"bin_dir,omitempty"`
+	// Locale is the language of the GUI and CLI, e.g. "de"; empty follows
+	// $LANG
+	Locale string `json:"locale,omitempty"`
+	// Snapshots turns on the GUI's automatic snapshots of the open file
+	Snapshots bool `json:"snapshots,omitempty"`
+	// SnapshotMinutes and SnapshotEdits override when a snapshot is due
+	// (see editor.Snapshotter)
+	SnapshotMinutes int `json:"snapshot_minutes,omitempty"`
+	SnapshotEdits   int `json:"snapshot_edits,omitempty"`
+	// ChecksumOnSave is ask, always or never (see editor.ChecksumPolicy)
+	ChecksumOnSave string `json:"checksum_on_save,omitempty"`
+	// ChecksumSpec is the image checksum in -checksum-spec form; empty
+	// means the profile has none
+	ChecksumSpec string `json:"checksum_spec,omitempty"`
+	// RequireBackup turns on the strict backup mode of -require-backup
+	// (see reader.RequireBackup)
+	RequireBackup bool `json:"require_backup,omitempty"`
+	// MirrorWrites writes edits of a mirrored dump to every copy of the
+	// image (see editor.MirrorWrites)
+	MirrorWrites bool `json:"mirror_writes,omitempty"`
+	// ScanProfiles are the saved scanner parameters; the built-in
+	// profiles are not stored
+	ScanProfiles []scanner.Profile `json:"scan_profiles,omitempty"`
+}
+
+// Load reads the settings file, returning empty settings if it doesn't
+// exist yet
+func Load() (*Settings, error) {
+	s := &Settings{}
+
+	path, err := paths.ConfigFile(fileName)
+	if err != nil {
+		return s, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return &Settings{}, err
+	}
+	return s, nil
+}
+
+// Save writes the settings file
+func (s *Settings) Save() error {
+	path, err := paths.ConfigFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
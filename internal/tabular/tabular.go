@@ -0,0 +1,99 @@
+// Package tabular renders the results of tabular commands (-list, -scan,
+// the -compare summary) from one Table as a terminal table, RFC 4180 CSV
+// or JSON, so every format carries the same columns. Column names are part
+// of the CSV and JSON output; rename them only with a release note.
+package tabular
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Format is an output format for a Table
+type Format string
+
+const (
+	// FormatTable renders a pterm table for the terminal
+	FormatTable Format = "table"
+	// FormatCSV writes RFC 4180 CSV with a header row and CRLF line ends
+	FormatCSV Format = "csv"
+	// FormatJSON writes an array of objects keyed by column name
+	FormatJSON Format = "json"
+)
+
+// ParseFormat validates a -format flag value
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatTable, FormatCSV, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid format %q: expected table, csv or json", s)
+}
+
+// Table is a header row and rows of plain (uncolored) cells
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// New creates an empty table with the given columns
+func New(columns ...string) *Table {
+	return &Table{Columns: columns}
+}
+
+// Add appends a row; it must have one cell per column
+func (t *Table) Add(cells ...string) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// Render prints the table to the terminal
+func (t *Table) Render() {
+	data := pterm.TableData{t.Columns}
+	data = append(data, t.Rows...)
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
+
+// WriteCSV writes the header and rows as CSV. Cells containing commas,
+// quotes or line breaks are quoted; text such as unit symbols is written
+// as UTF-8 unchanged.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	cw.Write(t.Columns)
+	cw.WriteAll(t.Rows)
+	return cw.Error()
+}
+
+// WriteJSON writes one object per row, keyed by column name
+func (t *Table) WriteJSON(w io.Writer) error {
+	objects := make([]map[string]string, len(t.Rows))
+	for i, row := range t.Rows {
+		objects[i] = make(map[string]string, len(t.Columns))
+		for j, column := range t.Columns {
+			if j < len(row) {
+				objects[i][column] = row[j]
+			}
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(objects)
+}
+
+// Write writes the table in a machine-readable format; FormatTable
+// renders it to the terminal and ignores w
+func (t *Table) Write(w io.Writer, f Format) error {
+	switch f {
+	case FormatCSV:
+		return t.WriteCSV(w)
+	case FormatJSON:
+		return t.WriteJSON(w)
+	}
+	t.Render()
+	return nil
+}
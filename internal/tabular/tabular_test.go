@@ -0,0 +1,77 @@
+package tabular
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+// sample holds the cells that need care in CSV: a comma, a quote, a line
+// break and unit symbols outside ASCII
+func sample() *Table {
+	t := New("Name", "Unit", "Description")
+	t.Add("Lambda Target", "λ", "target λ, by load")
+	t.Add("Ignition Timing", "°BTDC", `the "main" map`)
+	t.Add("Notes", "", "two\nlines")
+	return t
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sample().WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "Name,Unit,Description\r\n" +
+		"Lambda Target,λ,\"target λ, by load\"\r\n" +
+		"Ignition Timing,°BTDC,\"the \"\"main\"\" map\"\r\n" +
+		"Notes,,\"two\r\nlines\"\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("CSV:\n%q\nwant\n%q", got, want)
+	}
+
+	// A CSV reader gets the cells back as they were, apart from the CRLF
+	// the writer puts in quoted line breaks
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := sample()
+	if !slices.Equal(records[0], table.Columns) {
+		t.Errorf("header %q, want %q", records[0], table.Columns)
+	}
+	for i, row := range table.Rows {
+		if !slices.Equal(records[i+1], row) {
+			t.Errorf("row %d reads back as %q, want %q", i, records[i+1], row)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sample().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"Unit": "λ"`)) {
+		t.Errorf("JSON escapes the unit symbol:\n%s", buf.String())
+	}
+	var objects []map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &objects); err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 3 || objects[0]["Description"] != "target λ, by load" || objects[1]["Unit"] != "°BTDC" {
+		t.Errorf("JSON objects %v", objects)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{"csv": FormatCSV, "CSV": FormatCSV, "json": FormatJSON, "table": FormatTable} {
+		if f, err := ParseFormat(s); f != want || err != nil {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", s, f, err, want)
+		}
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("ParseFormat accepted xlsx")
+	}
+}
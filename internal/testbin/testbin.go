@@ -0,0 +1,111 @@
+// Package testbin generates a synthetic Motronic M2.1 image for demos and
+// fixtures. Every defined map holds a smooth, plausible-looking table,
+// every configuration parameter is in range and the ID block carries
+// recognizable (but fictitious) part numbers, so each feature of the tool
+// has something sensible to show without a real EPROM dump.
+package testbin
+
+import (
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// Identification strings written to the ID block. The part number's
+// 999 prefix marks the image as synthetic.
+const (
+	PartNumber  = "999.618.124.00"
+	BoschNumber = "0 261 200 173"
+	SWNumber    = "1 267 357 006"
+)
+
+// valueRange is the engineering value range a map is filled with; the
+// value rises with columns (RPM) and with rows (load) unless falling is set
+type valueRange struct {
+	min, max float64
+	falling  bool
+}
+
+// mapRanges gives the confirmed maps realistic values. Candidates without
+// an entry are filled with a gentle raw gradient.
+var mapRanges = map[string]valueRange{
+	"Main Fuel Map":       {min: 1.5, max: 8.5},
+	"Ignition Timing Map": {min: 8, max: 34, falling: true},
+	"Lambda Target Map":   {min: 0.86, max: 1.0, falling: true},
+}
+
+// Image returns a synthetic image of the M2.1 image size
+func Image() []byte {
+	data := make([]byte, models.M21IDProfile.ImageSize)
+	for i := range data {
+		data[i] = 0xFF // erased EPROM
+	}
+
+	for _, cfg := range models.MapConfigs {
+		fillMap(data, cfg)
+		if cfg.XAxis != nil {
+			fillAxis(data, *cfg.XAxis)
+		}
+	}
+	writeAxes(data, models.MapConfigs[0])
+
+	for _, p := range models.ConfigParams {
+		raw, _ := p.ToRaw((p.MinValue + p.MaxValue) / 2)
+		p.EncodeRaw(data[p.Offset:], raw)
+	}
+
+	at := models.M21IDProfile.Regions[0].Start + 0x10
+	for _, s := range []string{PartNumber, BoschNumber, SWNumber} {
+		copy(data[at:], s)
+		data[at+int64(len(s))] = 0
+		at += int64(len(s)) + 0x10
+	}
+	return data
+}
+
+// fillMap writes a smooth table into the map's cells
+func fillMap(data []byte, cfg models.MapConfig) {
+	size := models.DataTypeSize(cfg.DataType)
+	for row := 0; row < cfg.Rows; row++ {
+		for col := 0; col < cfg.Cols; col++ {
+			x := float64(col) / float64(max(cfg.Cols-1, 1))
+			y := float64(row) / float64(max(cfg.Rows-1, 1))
+
+			var raw int64
+			if r, ok := mapRanges[cfg.Name]; ok {
+				t := 0.6*x + 0.4*y
+				if r.falling {
+					t = 0.4*x + 0.6*(1-y)
+				}
+				raw, _ = cfg.ToRaw(r.min + (r.max-r.min)*t)
+			} else {
+				raw = int64(60 + 80*x + 40*y)
+			}
+			offset := cfg.Offset + int64((row*cfg.Cols+col)*size)
+			cfg.EncodeRaw(data[offset:], raw)
+		}
+	}
+}
+
+// fillAxis writes evenly rising breakpoints into a defined axis. The raw
+// step is a multiple of 4, so quarter-unit scales give whole labels.
+func fillAxis(data []byte, axis models.AxisConfig) {
+	size := models.DataTypeSize(axis.DataType)
+	step := max(160/max(axis.Count-1, 1)&^3, 1)
+	for i := 0; i < axis.Count; i++ {
+		raw := int64(40 + i*step)
+		models.EncodeRawOrder(data[axis.Offset+int64(i*size):], axis.DataType, raw, axis.Endianness)
+	}
+}
+
+// writeAxes stores an RPM vector and a load vector just before the map, in
+// the layout the scanner's axis suggestions look for
+func writeAxes(data []byte, cfg models.MapConfig) {
+	xAt := cfg.Offset - int64(cfg.Rows+cfg.Cols)
+	for col := 0; col < cfg.Cols; col++ {
+		// RPM / 40, 600 to 6600 RPM
+		data[xAt+int64(col)] = byte(15 + col*150/max(cfg.Cols-1, 1))
+	}
+	yAt := cfg.Offset - int64(cfg.Rows)
+	for row := 0; row < cfg.Rows; row++ {
+		data[yAt+int64(row)] = byte(20 + row*220/max(cfg.Rows-1, 1))
+	}
+}
@@ -0,0 +1,322 @@
+// Package usage holds the CLI help topics and their runnable examples. The
+// examples are stored as argument lists rather than text, so Check can
+// verify every flag they use still exists and the help never drifts from
+// the real flag names.
+package usage
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Example is one runnable command line
+type Example struct {
+	Args []string
+	Note string
+}
+
+// Topic groups the flags of one kind of task with examples of combining
+// them
+type Topic struct {
+	Name    string
+	Summary string
+	Flags   []string
+	// Examples use "sample.bin", "tuned.bin" and "bins" as placeholders
+	Examples []Example
+}
+
+// Topics lists the help topics in the order they are shown
+var Topics = []Topic{
+	{
+		Name:    "view",
+		Summary: "Show maps, parameters and identification of a binary",
+		Flags:   []string{"file", "map", "display", "v", "query", "list", "profile", "profiles", "xdf", "maps", "maps-mode", "bins", "format", "byte-order", "base-offset", "json"},
+		Examples: []Example{
+			{Args: []string{"info", "sample.bin"}, Note: "one-screen summary; exits 1 if anything looks wrong"},
+			{Args: []string{"-file", "sample.bin"}, Note: "every map as a heatmap"},
+			{Args: []string{"-file", "sample.bin", "-map", "lambda", "-display", "values"}, Note: "one map as numbers"},
+			{Args: []string{"-file", "sample.bin", "-map", "curves"}, Note: "one-row curves as values over a sparkline"},
+			{Args: []string{"-file", "sample.bin", "-map", "corrections"}, Note: "every map of a category; -list groups maps by category"},
+			{Args: []string{"-file", "sample.bin", "-query", "ignition > 30"}, Note: "find cells by predicate"},
+			{Args: []string{"-maps", "my964.yaml", "-list"}, Note: "add your own map definitions; -list shows where each came from"},
+			{Args: []string{"-xdf", "964.xdf", "-file", "sample.bin"}, Note: "use the tables and constants of a TunerPro XDF"},
+			{Args: []string{"-file", "sample.bin", "-profile", "auto"}, Note: "pick the firmware profile by size and signature bytes"},
+			{Args: []string{"-file", "dump.bin", "-base-offset", "0x8000"}, Note: "a dump whose image doesn't start the file, when detection misses it"},
+		},
+	},
+	{
+		Name:    "scan",
+		Summary: "Look for undefined maps in a binary",
+		Flags:   []string{"file", "scan", "scan-range", "exhaustive", "scan-stride", "min-variance", "scan-sizes", "resume", "scan-profile", "save-scan-profile", "delete-scan-profile", "scan-profiles", "promote", "promote-name", "format", "o"},
+		Examples: []Example{
+			{Args: []string{"-file", "sample.bin", "-scan"}, Note: "quick scan every 0x40 bytes"},
+			{Args: []string{"-file", "sample.bin", "-scan", "-exhaustive", "-resume"}, Note: "every offset, continuing after Ctrl+C"},
+			{Args: []string{"-file", "sample.bin", "-scan", "-scan-range", "0x6000:0x7FFF"}, Note: "only the data area, skipping the code"},
+			{Args: []string{"-scan-stride", "2", "-scan-range", "0x6000:0x7FFF", "-min-variance", "20", "-save-scan-profile", "calarea"}, Note: "save scanner settings under a name"},
+			{Args: []string{"-file", "sample.bin", "-scan", "-scan-profile", "calarea", "-format", "csv", "-o", "hits.csv"}, Note: "scan with a saved profile; the CSV records it"},
+			{Args: []string{"-scan-profiles"}, Note: "list built-in and saved profiles"},
+			{Args: []string{"-file", "sample.bin", "-scan", "-promote", "0x6800", "-promote-name", "Warmup trim"}, Note: "add a hit to your map definitions"},
+		},
+	},
+	{
+		Name:    "edit",
+		Summary: "Change maps and parameters, with backups and dry runs",
+		Flags:   []string{"file", "edit", "nudge", "scale-region", "preset", "args", "dry-run", "safe-copy", "yes", "no-backup", "require-backup", "force", "base-offset", "mirror-writes", "fuel-cut", "also-edit", "force-mismatch", "outliers", "outlier-threshold"},
+		Examples: []Example{
+			{Args: []string{"-file", "sample.bin", "-nudge", "ignition:3,7:+1", "-dry-run"}, Note: "preview a one-cell change"},
+			{Args: []string{"-file", "sample.bin", "-scale-region", "fuel:mul:1.05:4-7,0-15", "-dry-run"}, Note: "preview +5% fuel in the upper load rows"},
+			{Args: []string{"-file", "sample.bin", "-outliers", "-map", "ignition"}, Note: "single-cell spikes with smoothed suggestions"},
+			{Args: []string{"-file", "sample.bin", "-fuel-cut", "-1", "-dry-run"}, Note: "preview cutting fuel one column earlier"},
+			{Args: []string{"-file", "sample.bin", "-preset", "lambda-openloop", "-args", "row=5,value=0.88", "-dry-run"}, Note: "preview a preset"},
+			{Args: []string{"-file", "sample.bin", "-edit", "-safe-copy"}, Note: "edit a copy, keeping the original"},
+			{Args: []string{"-file", "sample.bin", "-also-edit", "tuned.bin", "-nudge", "ignition:3,7:+1"}, Note: "edit a ROM pair in lock step"},
+			{Args: []string{"-file", "sample.bin", "-nudge", "ignition:3,7:+1", "-require-backup"}, Note: "write only after a verified backup"},
+			{Args: []string{"-file", "sample.bin", "-nudge", "ignition:3,7:+20", "-force"}, Note: "write past the map's allowed range"},
+		},
+	},
+	{
+		Name:    "compare",
+		Summary: "Diff two binaries cell by cell",
+		Flags:   []string{"file", "compare", "compare-raw", "blame", "since", "map", "tolerance", "strict", "report", "map-hashes", "format", "o"},
+		Examples: []Example{
+			{Args: []string{"-file", "sample.bin", "-compare", "tuned.bin"}, Note: "show changed maps"},
+			{Args: []string{"-file", "sample.bin", "-compare", "tuned.bin", "-strict", "-report", "diff.html"}, Note: "every raw change as an HTML report"},
+			{Args: []string{"-file", "sample.bin", "-compare", "tuned.bin", "-format", "csv", "-o", "summary.csv"}, Note: "per-map change summary for a spreadsheet"},
+			{Args: []string{"-file", "sample.bin", "-compare", "tuned.bin", "-compare-raw"}, Note: "raw bytes only, when the files were saved with different definitions"},
+			{Args: []string{"-file", "tuned.bin", "-compare", "customer.bin", "-blame", "-report", "blame.html"}, Note: "which logged edit made each change"},
+			{Args: []string{"-since", "yesterday", "changed", "sample.bin"}, Note: "what changed since a backup; exits 0 if anything did, 1 if not"},
+			{Args: []string{"-map-hashes", "-bins", "bins"}, Note: "per-map content hashes of a folder, for scripts"},
+		},
+	},
+	{
+		Name:    "transfer",
+		Summary: "Export and import maps as CSV, extract or inject raw bytes",
+		Flags:   []string{"file", "export", "export-lossless", "export-offsets", "export-symbols", "export-xdf", "import", "on-error", "force", "import-winols", "winols-delta", "json", "extract", "extract-map", "o", "inject", "at"},
+		Examples: []Example{
+			{Args: []string{"-file", "sample.bin", "-export", "out", "-export-lossless"}, Note: "CSV files that re-import byte-identical"},
+			{Args: []string{"-file", "sample.bin", "-import", "out", "-dry-run"}, Note: "preview an import"},
+			{Args: []string{"-file", "sample.bin", "-import-winols", "maps.csv", "-winols-delta", "-0x8000", "-dry-run"}, Note: "preview map definitions from a WinOLS map list"},
+			{Args: []string{"-file", "sample.bin", "-extract-map", "Main Fuel Map", "-o", "fuel.bin"}, Note: "raw bytes of one map"},
+			{Args: []string{"-file", "sample.bin", "-export-symbols", "m21.sym"}, Note: "labels for Ghidra or IDA (.csv for Ghidra CSV)"},
+			{Args: []string{"-file", "sample.bin", "-export-xdf", "m21.xdf"}, Note: "the definitions in use, for TunerPro"},
+		},
+	},
+	{
+		Name:    "logs",
+		Summary: "Attach, overlay and learn from wideband logs",
+		Flags:   []string{"file", "attach-log", "note", "attachments", "export-archive", "embed-logs", "overlay-log", "log-columns", "min-samples", "suggest-fuel", "log", "authority", "report"},
+		Examples: []Example{
+			{Args: []string{"-file", "sample.bin", "-export-archive", "tune.zip", "-embed-logs"}, Note: "the binary with its attached logs"},
+			{Args: []string{"-file", "sample.bin", "-overlay-log", "run.csv", "-report", "overlay.html"}, Note: "measured lambda per cell"},
+			{Args: []string{"-file", "sample.bin", "-suggest-fuel", "-log", "run.csv", "-dry-run"}, Note: "preview fuel corrections"},
+		},
+	},
+	{
+		Name:    "web",
+		Summary: "Browse a directory of binaries in the browser",
+		Flags:   []string{"web", "port", "bins", "project"},
+		Examples: []Example{
+			{Args: []string{"-web", "-bins", "bins"}, Note: "serve http://localhost:8080"},
+			{Args: []string{"-web", "-project", "bins"}, Note: "reopen a saved view"},
+		},
+	},
+	{
+		Name:    "checksum",
+		Summary: "Verify and fix the image checksum",
+		Flags:   []string{"file", "checksum", "fix-checksum", "checksum-spec", "checksum-on-save", "dry-run", "yes"},
+		Examples: []Example{
+			{Args: []string{"-file", "sample.bin", "-checksum", "-checksum-spec", "sum16:0x0000-0x7FFD@0x7FFE"}, Note: "check a 16-bit byte sum stored in the last two bytes"},
+			{Args: []string{"-file", "sample.bin", "-fix-checksum", "-checksum-spec", "sum8-complement:0x0000-0x7FFF@0x7FFF", "-dry-run"}, Note: "preview fixing a two's-complement byte"},
+			{Args: []string{"-file", "sample.bin", "-checksum", "-checksum-spec", "sum16-multi:0x0000-0x5FFF,0x6000-0x7FFD@0x7FFE"}, Note: "one sum over code and data regions"},
+			{Args: []string{"-file", "sample.bin", "-nudge", "ignition:3,7:+1", "-checksum-spec", "sum16:0x0000-0x7FFD@0x7FFE", "-checksum-on-save", "always"}, Note: "store the new checksum with the edit"},
+		},
+	},
+	{
+		Name:    "ci",
+		Summary: "Check every binary in a directory for CI pipelines",
+		Flags:   []string{"bins", "checksum-spec", "check-defs"},
+		Examples: []Example{
+			{Args: []string{"ci", "-out", "results.xml", "bins"}, Note: "JUnit results (.json for JSON); exits 1 if any check failed"},
+			{Args: []string{"-check-defs"}, Note: "overlapping definitions and zero scales"},
+		},
+	},
+	{
+		Name:    "quickstart",
+		Summary: "Create a directory with a synthetic binary to try things on",
+		Examples: []Example{
+			{Args: []string{"quickstart", "ecu-demo"}, Note: "writes ecu-demo/sample.bin"},
+		},
+	},
+}
+
+// Program returns how to invoke the CLI in examples: the binary name, or
+// "go run main.go" when run through go run
+func Program() string {
+	name := filepath.Base(os.Args[0])
+	if strings.Contains(os.Args[0], "go-build") {
+		return "go run main.go"
+	}
+	return name
+}
+
+// FindTopic returns the topic with the given name
+func FindTopic(name string) (Topic, bool) {
+	for _, t := range Topics {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Topic{}, false
+}
+
+// Command formats an example as a shell command line, quoting arguments
+// that need it
+func (e Example) Command(program string) string {
+	parts := []string{program}
+	for _, arg := range e.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'<>|&;$*?") {
+			arg = "\"" + strings.ReplaceAll(arg, "\"", "\\\"") + "\""
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// placeholders are the example file names In replaces
+var placeholders = map[string]bool{"sample.bin": true, "tuned.bin": true, "bins": true}
+
+// In returns the example with its placeholder files located in dir
+func (e Example) In(dir string) Example {
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		switch {
+		case arg == "bins":
+			args[i] = dir
+		case placeholders[arg]:
+			args[i] = filepath.Join(dir, arg)
+		default:
+			args[i] = arg
+		}
+	}
+	return Example{Args: args, Note: e.Note}
+}
+
+// NextSteps returns the examples quickstart suggests trying first: the
+// first example of the view, compare and web topics
+func NextSteps() []Example {
+	var steps []Example
+	for _, name := range []string{"view", "compare", "web"} {
+		if t, ok := FindTopic(name); ok && len(t.Examples) > 0 {
+			steps = append(steps, t.Examples[0])
+		}
+	}
+	return steps
+}
+
+// commandFlags are the subcommands with flags of their own
+var commandFlags = map[string]bool{"ci": true}
+
+// Check returns the flags named by topics or used in examples that fs does
+// not define, sorted. Flags after a subcommand with its own are left to it.
+func Check(fs *flag.FlagSet) []string {
+	unknown := map[string]bool{}
+	for _, t := range Topics {
+		for _, name := range t.Flags {
+			if fs.Lookup(name) == nil {
+				unknown[name] = true
+			}
+		}
+		for _, e := range t.Examples {
+			for _, arg := range e.Args {
+				// Subcommands parse the flags after them
+				if commandFlags[arg] {
+					break
+				}
+				// Negative numbers such as -1 or -0x8000 are values
+				name, ok := strings.CutPrefix(arg, "-")
+				if ok && name != "" && (name[0] < '0' || name[0] > '9') && fs.Lookup(name) == nil {
+					unknown[name] = true
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(unknown))
+	for name := range unknown {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrintOverview lists the topics with one example each, for -h
+func PrintOverview(fs *flag.FlagSet) {
+	program := Program()
+	pterm.DefaultSection.Println("Usage")
+	pterm.Printf("  %s [flags]\n", program)
+	pterm.Printf("  %s help <topic>    flags and examples for one task\n", program)
+	pterm.Printf("  %s [-json] info <file.bin>    summary with a verdict\n", program)
+	pterm.Printf("  %s [-since 36h] changed <file.bin>    what changed since a backup\n", program)
+	pterm.Printf("  %s ci [-out results.xml] [dir]    check every binary of a directory\n", program)
+	pterm.Printf("  %s quickstart [dir]\n\n", program)
+
+	tableData := pterm.TableData{{"Topic", "Summary", "Example"}}
+	for _, t := range Topics {
+		example := ""
+		if len(t.Examples) > 0 {
+			example = t.Examples[0].Command(program)
+		}
+		tableData = append(tableData, []string{t.Name, t.Summary, example})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	pterm.DefaultSection.Println("All flags")
+	fs.PrintDefaults()
+	warnStale(fs)
+}
+
+// PrintTopic prints a topic's flags with their help text and its examples
+func PrintTopic(fs *flag.FlagSet, name string) error {
+	t, ok := FindTopic(name)
+	if !ok {
+		names := make([]string, len(Topics))
+		for i, t := range Topics {
+			names[i] = t.Name
+		}
+		return fmt.Errorf("unknown help topic %q (topics: %s)", name, strings.Join(names, ", "))
+	}
+
+	pterm.DefaultSection.Printf("%s: %s\n", t.Name, t.Summary)
+	for _, name := range t.Flags {
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		line := fmt.Sprintf("  -%-16s %s", f.Name, f.Usage)
+		if f.DefValue != "" && f.DefValue != "false" {
+			line += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		pterm.Println(line)
+	}
+
+	pterm.Println()
+	program := Program()
+	for _, e := range t.Examples {
+		pterm.Println(pterm.Gray("# " + e.Note))
+		pterm.Println("  " + e.Command(program))
+	}
+	warnStale(fs)
+	return nil
+}
+
+// warnStale reports examples that use flags no longer defined
+func warnStale(fs *flag.FlagSet) {
+	if unknown := Check(fs); len(unknown) > 0 {
+		pterm.Warning.Printf("Help examples use undefined flags: -%s\n", strings.Join(unknown, ", -"))
+	}
+}
@@ -0,0 +1,6 @@
+// Package version holds the release version shown by the CLI -version flag
+// and the GUI about dialog
+package version
+
+// Version is the current release
+const Version = "1.0.0"
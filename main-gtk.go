@@ -1,20 +1,65 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 	"github.com/tosih/motronic-m21-tool/pkg/gui"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
 )
 
 func main() {
+	// --diff a.bin b.bin opens a read-only comparison of the two files;
+	// --xdf FILE, --maps FILE and --maps-mode append|replace load
+	// definitions like the CLI's -xdf and -maps; --profile NAME selects the
+	// definitions of a firmware variant like -profile
+	var diffFiles []string
+	args := os.Args[:1]
+	for rest := os.Args[1:]; len(rest) > 0; rest = rest[1:] {
+		switch rest[0] {
+		case "--diff":
+			if len(rest) != 3 {
+				fmt.Fprintf(os.Stderr, "usage: %s [--profile NAME] [--xdf FILE] [--maps FILE [--maps-mode MODE]] --diff FILE1 FILE2\n", os.Args[0])
+				os.Exit(2)
+			}
+			diffFiles = rest[1:3]
+			rest = rest[2:]
+		case "--xdf", "--maps", "--maps-mode", "--profile":
+			if len(rest) < 2 {
+				fmt.Fprintf(os.Stderr, "%s needs a value\n", rest[0])
+				os.Exit(2)
+			}
+			switch rest[0] {
+			case "--xdf":
+				gui.XDFFile = rest[1]
+			case "--maps":
+				gui.MapsFile = rest[1]
+			case "--profile":
+				gui.Profile = rest[1]
+			default:
+				gui.MapsMode = rest[1]
+			}
+			rest = rest[1:]
+		default:
+			args = append(args, rest[0])
+		}
+	}
+
 	app := gtk.NewApplication("com.github.tosih.motronic-m21-tool", gio.ApplicationFlagsNone)
 	app.ConnectActivate(func() {
+		if diffFiles != nil {
+			gui.NewDiffWindow(app, diffFiles[0], diffFiles[1])
+			return
+		}
 		gui.NewMainWindow(app)
 	})
 
-	if code := app.Run(os.Args); code > 0 {
+	code := app.Run(args)
+	// Parsed maps not yet written to the cache
+	reader.FlushCache()
+	if code > 0 {
 		os.Exit(code)
 	}
 }
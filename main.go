@@ -1,52 +1,356 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/paths"
+	"github.com/tosih/motronic-m21-tool/internal/progress"
+	"github.com/tosih/motronic-m21-tool/internal/settings"
+	"github.com/tosih/motronic-m21-tool/internal/tabular"
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/internal/usage"
+	"github.com/tosih/motronic-m21-tool/internal/version"
+	"github.com/tosih/motronic-m21-tool/pkg/checksum"
+	"github.com/tosih/motronic-m21-tool/pkg/ci"
 	"github.com/tosih/motronic-m21-tool/pkg/compare"
+	"github.com/tosih/motronic-m21-tool/pkg/datalog"
 	"github.com/tosih/motronic-m21-tool/pkg/editor"
 	"github.com/tosih/motronic-m21-tool/pkg/export"
+	"github.com/tosih/motronic-m21-tool/pkg/info"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/query"
 	"github.com/tosih/motronic-m21-tool/pkg/reader"
 	"github.com/tosih/motronic-m21-tool/pkg/renderer"
 	"github.com/tosih/motronic-m21-tool/pkg/scanner"
 	"github.com/tosih/motronic-m21-tool/pkg/web"
+	"golang.org/x/term"
 )
 
 func main() {
 	filename := flag.String("file", "", "ECU binary file to read")
-	mapType := flag.String("map", "all", "Map type to display: fuel, spark, lambda, boost, coldstart, or all")
+	mapType := flag.String("map", "all", "Map type to display: fuel, spark, lambda, boost, coldstart, curves, all, or a category (corrections, experimental, other)")
 	verbose := flag.Bool("v", false, "Verbose output showing raw values")
 	scan := flag.Bool("scan", false, "Scan file for potential map locations")
+	exhaustive := flag.Bool("exhaustive", false, "With -scan, try every offset instead of every 0x40 bytes")
+	resume := flag.Bool("resume", false, "With -scan, continue an interrupted scan from its checkpoint")
+	scanRange := flag.String("scan-range", "", "With -scan, only look for maps inside this byte range, e.g. 0x6000:0x7FFF (end inclusive)")
+	scanStride := flag.Int("scan-stride", scanner.Stride, "With -scan, the offset step (-exhaustive is a stride of 1)")
+	minVariance := flag.Float64("min-variance", scanner.DefaultMinVariance, "With -scan, drop hits whose value range (max - min) is smaller")
+	scanSizes := flag.String("scan-sizes", "", "With -scan, only report these map shapes, e.g. 8x16,16x16 (default: all)")
+	scanProfileName := flag.String("scan-profile", "", "With -scan, start from a saved or built-in scan profile (quick, exhaustive); scan flags given as well override it")
+	saveScanProfile := flag.String("save-scan-profile", "", "Save the scan flags given (on top of -scan-profile) as a named scan profile in the settings")
+	deleteScanProfile := flag.String("delete-scan-profile", "", "Delete a saved scan profile")
+	listScanProfiles := flag.Bool("scan-profiles", false, "List the built-in and saved scan profiles")
+	promote := flag.String("promote", "", "With -scan, add the hit at this offset (e.g. 0x6800, or 0x6800:8x16 for one shape) to your map definitions")
+	promoteName := flag.String("promote-name", "", "Name of the map -promote adds (default: \"Scan hit 0x<offset>\")")
+	profileName := flag.String("profile", "", "Definitions of a firmware variant: a profile name, or auto to detect it from -file (default: "+models.DefaultProfile+")")
+	listProfiles := flag.Bool("profiles", false, "List the ECU profiles, built-in and from the profiles directory of the config directory")
 	displayMode := flag.String("display", "heatmap", "Display mode: heatmap, symbols, or values")
 	edit := flag.Bool("edit", false, "Enter interactive edit mode")
-	preset := flag.String("preset", "", "Apply preset modification: revlimit, boost, etc.")
+	preset := flag.String("preset", "", "Apply preset modification: revlimit, fuel-enrich, lambda-openloop, boost")
+	nudge := flag.String("nudge", "", "Nudge one cell by whole steps of the map's nudge step, e.g. \"ignition:3,7:+1\"")
+	scaleRegion := flag.String("scale-region", "", "Add to, multiply or set the cells of a map or region, e.g. \"fuel:mul:1.05\" or \"ignition:add:-2:4-7,0-15\" (map:op:value[:rows,cols])")
+	fuelCut := flag.String("fuel-cut", "", "Show the fuel-cut columns at the top of the fuel map, or move the cut by a signed number of columns, e.g. \"+1\" (0 only shows them)")
+	queryExpr := flag.String("query", "", "List cells matching a predicate, e.g. \"ignition > 35\" or \"any.raw >= 90%\"")
+	presetArgs := flag.String("args", "", "Arguments for parameterized presets, e.g. \"row=5,value=0.88\"")
+	dryRun := flag.Bool("dry-run", false, "Show what an edit or preset would change without writing")
+	safeCopy := flag.Bool("safe-copy", false, "Write edits to a new copy in the current directory, leaving the original untouched")
+	alsoEdit := flag.String("also-edit", "", "Apply every edit to this second binary as well, in lock step (staged cells must hold the same raw value in both)")
+	forceMismatch := flag.Bool("force-mismatch", false, "With -also-edit, write cells whose current value differs between the two files")
+	forceRange := flag.Bool("force", false, "Write map cells outside the map's allowed range (e.g. ignition -10 to 45 deg); such writes are marked in the changelog")
 	exportPath := flag.String("export", "", "Export maps to CSV files in specified directory")
-	importFile := flag.String("import", "", "Import map from CSV file")
+	exportXDF := flag.String("export-xdf", "", "Write the active map and parameter definitions to a TunerPro .xdf file")
+	exportSymbols := flag.String("export-symbols", "", "Write disassembler labels for every map and parameter to a .sym file, or Ghidra CSV if the name ends in .csv")
+	exportLossless := flag.Bool("export-lossless", false, "Embed raw cell values in CSV exports so re-importing is byte-identical")
+	exportOffsets := flag.Bool("export-offsets", false, "Add a grid of absolute per-cell file offsets to CSV exports")
+	importFile := flag.String("import", "", "Import maps from a CSV file, comma-separated files, or a directory of CSVs")
+	importWinOLS := flag.String("import-winols", "", "Add the maps of a WinOLS map list export (name, address, rows, columns, factor, offset) to your map definitions")
+	winOLSDelta := flag.String("winols-delta", "0", "Added to every -import-winols address, e.g. -0x8000 for a list made against a 64KB dump")
+	onError := flag.String("on-error", "abort", "Import policy when cells are clamped or rejected: abort, skip (write the accepted cells), or ask")
+	jsonOut := flag.Bool("json", false, "Print the -import report, -map-hashes or info summary as JSON on stdout (other output goes to stderr)")
+	assumeYes := flag.Bool("yes", false, "Write without confirmation prompts (the edit-mode risk acknowledgement is still shown)")
+	extractRange := flag.String("extract", "", "Extract a raw byte range (inclusive), e.g. 0x6000:0x7FFF (use with -o)")
+	extractMap := flag.String("extract-map", "", "Extract the raw bytes of a map by name (use with -o)")
+	outFile := flag.String("o", "", "Output file for -extract and -extract-map, or for -format csv/json (default stdout)")
+	formatFlag := flag.String("format", "table", "Output of -list, -scan and the -compare summary: table, csv or json (csv/json go to stdout or -o, other output to stderr)")
+	injectFile := flag.String("inject", "", "Write the bytes of a file into the ECU file (use with -at)")
+	injectAt := flag.String("at", "", "Offset for -inject, e.g. 0x6000")
+	attachLog := flag.String("attach-log", "", "Associate a datalog or dyno CSV with the ECU file (see -note)")
+	note := flag.String("note", "", "Short note stored with -attach-log")
+	attachments := flag.Bool("attachments", false, "List logs attached to the ECU file")
+	exportArchive := flag.String("export-archive", "", "Write the ECU file, its metadata and a list of its attached logs to a .zip tune archive")
+	embedLogs := flag.Bool("embed-logs", false, "Store the attached logs themselves in the -export-archive archive")
+	overlayLog := flag.String("overlay-log", "", "Bin a wideband CSV log onto the Lambda Target Map and show measured lambda per cell")
+	logColumns := flag.String("log-columns", "", "Log column mapping for -overlay-log, e.g. \"rpm=RPM,load=MAP,afr=AFR1\" (default: detect from header)")
+	minSamples := flag.Int("min-samples", datalog.DefaultMinSamples, "Samples needed before an overlay cell counts as reliable")
+	reportFile := flag.String("report", "", "Write the -overlay-log or -compare result to a .csv or .html report (-compare also .json)")
+	suggestFuel := flag.Bool("suggest-fuel", false, "Suggest fuel map corrections from logged vs target lambda (use with -log)")
+	logFile := flag.String("log", "", "Wideband CSV log for -suggest-fuel")
+	authority := flag.Float64("authority", 0.08, "Largest relative fuel correction -suggest-fuel may suggest per cell")
+	outliers := flag.Bool("outliers", false, "List cells that deviate from the median of their 3x3 neighborhood (use with -map) and offer to stage smoothed values")
+	outlierThreshold := flag.Float64("outlier-threshold", 0, "Deviation in engineering units that makes a cell an -outliers hit (default: 10% of the map's value range)")
 	compareFile := flag.String("compare", "", "Compare current file with another ECU file")
+	compareRaw := flag.Bool("compare-raw", false, "With -compare, diff raw stored values, ignoring map scales (e.g. files saved with different definitions)")
+	blame := flag.Bool("blame", false, "With -compare, attribute each changed cell to the changelog entry that wrote it")
+	tolerance := flag.Float64("tolerance", -1, "Absolute compare tolerance per cell (default: half a raw step of each map)")
+	strict := flag.Bool("strict", false, "Compare without tolerance, reporting every raw difference")
 	list := flag.Bool("list", false, "List all available maps")
+	xdfFile := flag.String("xdf", "", "Use the tables and constants of a TunerPro XDF file instead of the built-in definitions")
+	mapsFile := flag.String("maps", "", "Load map definitions from a JSON or YAML file (entries as in user_maps.json)")
+	mapsMode := flag.String("maps-mode", editor.MapsAppend, "How -maps combines with the built-in map definitions: append, or replace (fuel, ignition and lambda must stay first)")
 	webMode := flag.Bool("web", false, "Launch web interface for interactive visualization")
 	port := flag.Int("port", 8080, "Port for web server (default: 8080)")
+	timelineFile := flag.String("timeline", "", "Show how maps changed across all backups of the given file")
+	timelineCSV := flag.String("timeline-csv", "", "Write per-cell timeline values to CSV (use with -timeline)")
+	configDir := flag.String("config", "", "Directory for all persisted state (settings, caches) instead of the user config dir")
+	noCache := flag.Bool("no-cache", false, "Disable the on-disk cache of parsed map data")
+	noBackup := flag.Bool("no-backup", false, "Write without the timestamped backup, for scripts that keep their own copies (a failed backup otherwise stops the write)")
+	mirrorWrites := flag.Bool("mirror-writes", false, "Write edits of a mirrored dump (the image repeated, e.g. a 27C256 read as a 27C512) to every copy instead of the first only (also the mirror_writes setting)")
+	requireBackup := flag.Bool("require-backup", false, "Refuse every write until a backup of the file's current contents has been read back and its hash checked (also the require_backup setting)")
+	maxFileSize := flag.String("max-file-size", "4MB", "Largest file accepted as an ECU image (e.g. 512K, 4MB)")
+	baseOffset := flag.String("base-offset", "", "File offset of the calibration image in a dump, e.g. 0x8000 or 512 (default: detected from a reader header or the identification strings)")
+	mapHashes := flag.Bool("map-hashes", false, "Print a content hash of every map of -file, or of every binary in the binary directory (-json for JSON)")
+	byteOrder := flag.String("byte-order", "", "Default byte order of 16-bit parameters without their own: little (M2.1) or big")
+	since := flag.String("since", "today", "Window of the changed command: today, yesterday, a duration such as 36h, or a date such as 2006-01-02")
+	checksumCheck := flag.Bool("checksum", false, "Verify the image checksum of -file and show the algorithm, regions and store location used")
+	fixChecksum := flag.Bool("fix-checksum", false, "Store the computed image checksum in -file (with -dry-run to only show it)")
+	checksumSpec := flag.String("checksum-spec", "", "Checksum of the profile as algorithm:start-end@store, e.g. sum16:0x0000-0x7FFD@0x7FFE (algorithms: sum16, sum8-complement, sum16-multi with comma-separated regions)")
+	checksumOnSave := flag.String("checksum-on-save", "", "Whether saving an edit also stores the image checksum: ask (default), always or never (overrides the checksum_on_save setting)")
+	checkDefs := flag.Bool("check-defs", false, "Validate map and parameter definitions for overlapping byte ranges and invalid scales")
+	binsFlag := flag.String("bins", "", "Directory of ECU binaries (default: bin_dir setting, $ECU_READER_BINS, or ./bins)")
+	projectPath := flag.String("project", "", "Open the files saved in a project file (or a directory's ecu-reader.project.json) from the web UI")
+	showVersion := flag.Bool("version", false, "Show the version and the active config and binary directories")
 
+	flag.Usage = func() { usage.PrintOverview(flag.CommandLine) }
 	flag.Parse()
 
+	reader.NoCache = *noCache
+	defer reader.FlushCache()
+	reader.NoBackup = *noBackup
+	editor.ForceRange = *forceRange
+	limit, err := reader.ParseSize(*maxFileSize)
+	if err != nil {
+		pterm.Error.Printf("Invalid -max-file-size: %v\n", err)
+		os.Exit(1)
+	}
+	reader.MaxFileSize = limit
+	if *baseOffset != "" {
+		base, err := strconv.ParseInt(*baseOffset, 0, 64)
+		if err != nil || base < 0 {
+			pterm.Error.Printf("Invalid -base-offset %q: want a file offset such as 0x8000 or 512\n", *baseOffset)
+			os.Exit(1)
+		}
+		reader.BaseOffsetOverride = base
+	}
+	if *byteOrder != "" {
+		order := models.Endianness(strings.ToLower(*byteOrder))
+		if err := models.CheckEndianness(order); err != nil {
+			pterm.Error.Printf("Invalid -byte-order: %v\n", err)
+			os.Exit(1)
+		}
+		models.M21IDProfile.Endianness = order
+	}
+	if *checksumSpec != "" {
+		if err := checksum.UseSpec(*checksumSpec); err != nil {
+			pterm.Error.Printf("Invalid -checksum-spec: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *configDir != "" {
+		paths.SetOverride(*configDir)
+	}
+	applyLocale()
+	applyConfirmPolicy(*assumeYes)
+	applyBackupPolicy(*requireBackup)
+	applyMirrorWrites(*mirrorWrites)
+	format, err := tabular.ParseFormat(*formatFlag)
+	if err != nil {
+		pterm.Error.Println(err)
+		os.Exit(1)
+	}
+	// Machine-readable tables own stdout
+	if format != tabular.FormatTable && (*list || *scan || *compareFile != "") {
+		logToStderr()
+		progress.Quiet = true
+	}
+	// JSON reports own stdout too
+	if *jsonOut {
+		progress.Quiet = true
+	}
+	if err := editor.ApplyProfiles(); err != nil {
+		pterm.Warning.Printf("Profiles not loaded:\n%v\n", err)
+	}
+	if *profileName != "" && !applyProfile(*profileName, *filename) {
+		os.Exit(1)
+	}
+	if *xdfFile != "" && !applyXDF(*xdfFile) {
+		os.Exit(1)
+	}
+	if *mapsFile != "" {
+		if err := editor.ApplyMapDefinitions(*mapsFile, *mapsMode); err != nil {
+			pterm.Error.Printf("Map definitions not loaded:\n%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := editor.ApplyUserMaps(); err != nil {
+		pterm.Warning.Printf("User map definitions not loaded: %v\n", err)
+	}
+	prompt := editor.PtermPrompter{}
+	applyChecksumPolicy(*checksumOnSave, prompt)
+
+	// Commands given as arguments instead of flags
+	switch flag.Arg(0) {
+	case "help":
+		if flag.Arg(1) == "" {
+			flag.Usage()
+			return
+		}
+		if err := usage.PrintTopic(flag.CommandLine, flag.Arg(1)); err != nil {
+			pterm.Error.Println(err)
+			os.Exit(1)
+		}
+		return
+	case "quickstart":
+		if !runQuickstart(flag.Arg(1)) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// A saved project fills in -file and -compare unless they are given
+	if *projectPath != "" && !applyProject(*projectPath, filename, compareFile) {
+		os.Exit(1)
+	}
+
+	// Bare filenames are looked up in the binary directory
+	binDir, binSource := settings.DefaultBinDir(*binsFlag)
+	*filename = resolveBinFile(*filename, binDir)
+	*compareFile = resolveBinFile(*compareFile, binDir)
+	*timelineFile = resolveBinFile(*timelineFile, binDir)
+
+	// A dump with a reader header or the image in an upper bank moves the
+	// definitions, so every command addresses the image of -file
+	if *filename != "" {
+		applyLayout(*filename)
+	}
+
+	if *showVersion {
+		printVersion(binDir, binSource)
+		return
+	}
+
+	// What changed since a backup: exit 0 if something changed, 1 if not
+	// and 2 on errors, for shell prompts and checklists
+	if flag.Arg(0) == "changed" {
+		name := *filename
+		if flag.Arg(1) != "" {
+			name = resolveBinFile(flag.Arg(1), binDir)
+		}
+		os.Exit(runChanged(name, *since))
+	}
+
+	// One-screen summary of a binary
+	if flag.Arg(0) == "info" {
+		name := *filename
+		if flag.Arg(1) != "" {
+			name = resolveBinFile(flag.Arg(1), binDir)
+		}
+		if !runInfo(name, *jsonOut) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *listProfiles {
+		printProfiles()
+		return
+	}
+
+	// Saved scan profiles
+	scanParams := scanFlags{
+		name:        *scanProfileName,
+		stride:      *scanStride,
+		exhaustive:  *exhaustive,
+		scanRange:   *scanRange,
+		minVariance: *minVariance,
+		sizes:       *scanSizes,
+	}
+	if *listScanProfiles || *saveScanProfile != "" || *deleteScanProfile != "" {
+		if !runScanProfiles(scanParams, *listScanProfiles, *saveScanProfile, *deleteScanProfile) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// List available maps
 	if *list {
+		if format != tabular.FormatTable {
+			if !writeTable(renderer.MapListTable(), format, *outFile) {
+				os.Exit(1)
+			}
+			return
+		}
 		renderer.ListAvailableMaps()
 		return
 	}
 
+	// Headless checks of a directory of binaries: exit 0 if every file
+	// passed, 1 on a failed check and 2 if the checks couldn't run
+	if flag.Arg(0) == "ci" {
+		os.Exit(ci.Command(flag.Args()[1:], binDir))
+	}
+
+	// Validate definitions
+	if *checkDefs {
+		if !checkDefinitions() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Backup timeline
+	if *timelineFile != "" {
+		compare.ShowTimeline(*timelineFile, *mapType, *timelineCSV, reader.ReadMapCached)
+		return
+	}
+
+	// Map content hashes for scripts
+	if *mapHashes {
+		files := []string{*filename}
+		if *filename == "" {
+			files = findBinFiles(binDir)
+		}
+		if !printMapHashes(files, *jsonOut) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Web interface mode
 	if *webMode {
 		var server *web.Server
 		fileOrDir := *filename
 
-		// If no file specified, use bins/ directory
+		// If no file specified, serve the binary directory
 		if fileOrDir == "" {
-			fileOrDir = "bins"
+			if binDir == "" {
+				pterm.Error.Println("No binary directory found; pass -file or -bins, or create ./bins")
+				os.Exit(1)
+			}
+			fileOrDir = binDir
 		}
 
 		if *compareFile != "" {
@@ -54,24 +358,36 @@ func main() {
 		} else {
 			server = web.NewServer(fileOrDir, *port)
 		}
-		if err := server.Start(); err != nil {
+		// Nobody answers the terminal while serving; responses report a
+		// stale checksum and the page offers to fix it
+		editor.AskChecksum = nil
+		editor.ConfirmCodeEdit = nil
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := server.Start(ctx)
+		stop()
+		if err != nil {
 			pterm.Error.Printf("Web server error: %v\n", err)
 			os.Exit(1)
 		}
 		return
 	}
 
-	// If no file specified, scan bins/ directory and list available files
+	// If no file specified, list the files in the binary directory
 	if *filename == "" {
-		binFiles := findBinFiles("bins")
+		binFiles := findBinFiles(binDir)
+		if binDir == "" {
+			pterm.Error.Println("No binary directory found")
+			pterm.Info.Println("Please specify a file with -file flag, pass -bins <dir>, or place .bin files in the bins/ directory")
+			os.Exit(1)
+		}
 		if len(binFiles) == 0 {
-			pterm.Error.Println("No .bin files found in bins/ directory")
-			pterm.Info.Println("Please specify a file with -file flag or place .bin files in the bins/ directory")
+			pterm.Error.Printf("No .bin files found in %s\n", binDir)
+			pterm.Info.Println("Please specify a file with -file flag, pass -bins <dir>, or place .bin files in the bins/ directory")
 			os.Exit(1)
 		}
 
 		// Show available bin files
-		pterm.DefaultHeader.WithFullWidth().Println("Available ECU Binary Files")
+		pterm.DefaultHeader.WithFullWidth().Println(i18n.T("cli.files.header"))
 		pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData{
 			{"#", "Filename", "Size", "Path"},
 		}).Render()
@@ -89,68 +405,1512 @@ func main() {
 		}
 		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
 
-		pterm.Info.Printf("\nFound %d .bin file(s) in bins/ directory\n", len(binFiles))
+		pterm.Info.Printf("\nFound %d .bin file(s) in %s\n", len(binFiles), binDir)
 		pterm.Info.Println("Use -file <path> to analyze a specific file")
 		pterm.Info.Println("Use -web to launch web interface with all files")
 		return
 	}
 
+	// Parse -nudge and -scale-region before any copy or prompt
+	var nudgeSpec editor.NudgeSpec
+	if *nudge != "" {
+		spec, err := editor.ParseNudge(*nudge)
+		if err != nil {
+			pterm.Error.Println(err)
+			os.Exit(1)
+		}
+		nudgeSpec = spec
+	}
+	fuelCutShift := 0
+	if *fuelCut != "" {
+		shift, err := strconv.Atoi(*fuelCut)
+		if err != nil {
+			pterm.Error.Printf("Invalid -fuel-cut %q: expected a signed number of columns\n", *fuelCut)
+			os.Exit(1)
+		}
+		fuelCutShift = shift
+	}
+	var transformSpec editor.TransformSpec
+	if *scaleRegion != "" {
+		spec, err := editor.ParseTransform(*scaleRegion)
+		if err != nil {
+			pterm.Error.Println(err)
+			os.Exit(1)
+		}
+		transformSpec = spec
+	}
+
+	// Edit a second binary in lock step
+	if *alsoEdit != "" {
+		if *safeCopy || *injectFile != "" {
+			pterm.Error.Println("-also-edit cannot be combined with -safe-copy or -inject")
+			os.Exit(1)
+		}
+		if !linkFile(*filename, *alsoEdit, *forceMismatch, *verbose) {
+			os.Exit(1)
+		}
+	}
+
+	// Check write access before any prompt, or redirect edits to a copy
+	writes := !*dryRun && (*edit || *preset != "" || *nudge != "" || fuelCutShift != 0 || *scaleRegion != "" || *importFile != "" || *injectFile != "")
+	if writes || (*safeCopy && (*suggestFuel || *outliers)) {
+		target, ok := prepareWriteTarget(*filename, *safeCopy)
+		if !ok {
+			os.Exit(1)
+		}
+		*filename = target
+	}
+
+	// Export the definitions for TunerPro
+	if *exportXDF != "" {
+		if !writeXDF(*filename, *exportXDF) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Export disassembler symbols
+	if *exportSymbols != "" {
+		n, err := export.ExportSymbols(*filename, *exportSymbols)
+		if err != nil {
+			pterm.Error.Printf("Symbol export failed: %v\n", err)
+			os.Exit(1)
+		}
+		pterm.Success.Printf("Wrote %d symbols to %s\n", n, *exportSymbols)
+		return
+	}
+
 	// Export maps to CSV
 	if *exportPath != "" {
-		export.ExportMapsToCSV(*filename, *exportPath, *mapType, reader.ReadMap)
+		opts := export.Options{Lossless: *exportLossless, Offsets: *exportOffsets}
+		export.ExportMapsToCSV(*filename, *exportPath, *mapType, opts, reader.ReadMapCached)
+		return
+	}
+
+	// Attach a log to the binary
+	if *attachLog != "" {
+		a, err := editor.AttachLog(*filename, *attachLog, *note)
+		if err != nil {
+			pterm.Error.Printf("Attach failed: %v\n", err)
+			os.Exit(1)
+		}
+		pterm.Success.Printf("Attached %s (sha256 %s) to %s\n", a.Path, a.SHA256[:16], *filename)
+		return
+	}
+
+	// List attached logs
+	if *attachments {
+		if !listAttachments(*filename) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Package the binary and its logs
+	if *exportArchive != "" {
+		manifest, err := editor.WriteTuneArchive(*filename, *exportArchive, *embedLogs)
+		if err != nil {
+			pterm.Error.Printf("Archive failed: %v\n", err)
+			os.Exit(1)
+		}
+		for _, log := range manifest.Logs {
+			if *embedLogs && log.Embedded == "" {
+				pterm.Warning.Printf("%s is %s and was only listed\n", log.Path, log.Status)
+			}
+		}
+		pterm.Success.Printf("Wrote %s with %d attached log(s)\n", *exportArchive, len(manifest.Logs))
+		return
+	}
+
+	// Overlay a datalog on the lambda map
+	if *overlayLog != "" {
+		if !overlayLambdaLog(*filename, *overlayLog, *logColumns, *minSamples, *reportFile) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Suggest fuel corrections from a datalog
+	if *suggestFuel {
+		if !suggestFuelCorrections(prompt, *filename, *logFile, *logColumns, *minSamples, *authority) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Find single-cell spikes
+	if *outliers {
+		if !findOutliers(prompt, *filename, *mapType, *outlierThreshold, *dryRun) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Search cells across maps
+	if *queryExpr != "" {
+		if !runQuery(*filename, *queryExpr) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Extract raw bytes for external tools
+	if *extractRange != "" || *extractMap != "" {
+		if !extractBytes(*filename, *extractRange, *extractMap, *outFile) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Verify or fix the image checksum
+	if *checksumCheck || *fixChecksum {
+		if !runChecksum(prompt, *filename, *fixChecksum, *dryRun) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Inject raw bytes
+	if *injectFile != "" {
+		if !injectBytes(prompt, *filename, *injectFile, *injectAt) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Import map definitions from a WinOLS map list
+	if *importWinOLS != "" {
+		delta, err := strconv.ParseInt(strings.TrimSpace(*winOLSDelta), 0, 64)
+		if err != nil {
+			pterm.Error.Printf("invalid -winols-delta %q: expected a signed decimal or 0x number\n", *winOLSDelta)
+			os.Exit(1)
+		}
+		if editor.NeedsConfirm(editor.ConfirmSave) && !*dryRun && !stdinIsTerminal() {
+			pterm.Error.Println("Confirmation needs an interactive terminal; pass -yes to write without asking")
+			os.Exit(1)
+		}
+		if !export.ImportWinOLSList(prompt, *filename, *importWinOLS, delta, *dryRun) {
+			os.Exit(1)
+		}
 		return
 	}
 
 	// Import map from CSV
 	if *importFile != "" {
-		export.ImportMapFromCSV(*filename, *importFile)
+		policy, err := editor.ParseFailurePolicy(*onError)
+		if err != nil {
+			pterm.Error.Println(err)
+			os.Exit(1)
+		}
+		if policy == editor.PolicyAsk && !stdinIsTerminal() {
+			pterm.Error.Println("-on-error ask needs an interactive terminal; use abort or skip")
+			os.Exit(1)
+		}
+		if editor.NeedsConfirm(editor.ConfirmSave) && !*dryRun && !stdinIsTerminal() {
+			pterm.Error.Println("Confirmation needs an interactive terminal; pass -yes to write without asking")
+			os.Exit(1)
+		}
+		if *jsonOut {
+			logToStderr()
+		}
+		export.ImportMapFromCSV(prompt, *filename, *importFile, policy, *dryRun, *jsonOut)
 		return
 	}
 
 	// Compare two files
 	if *compareFile != "" {
-		compare.CompareFiles(*filename, *compareFile, *mapType, reader.ReadMap)
+		tol := *tolerance
+		if *strict {
+			tol = 0
+		}
+		result := compare.CompareFiles(*filename, *compareFile, *mapType, tol, *compareRaw, reader.ReadMapCached)
+		if result == nil {
+			os.Exit(1)
+		}
+		if *blame {
+			if err := result.Blame(); err != nil {
+				pterm.Error.Printf("Failed to read the changelogs: %v\n", err)
+				os.Exit(1)
+			}
+			compare.PrintBlame(result)
+		}
+		if format != tabular.FormatTable && !writeTable(compare.SummaryTable(result), format, *outFile) {
+			os.Exit(1)
+		}
+		writeCSV := func(w io.Writer) error { return compare.WriteCSV(w, result) }
+		if strings.EqualFold(filepath.Ext(*reportFile), ".json") {
+			writeCSV = func(w io.Writer) error { return compare.WriteJSON(w, result) }
+		}
+		if *reportFile != "" && !writeReport(*reportFile, writeCSV,
+			func(w io.Writer) error { return compare.WriteHTML(w, result) }) {
+			os.Exit(1)
+		}
 		return
 	}
 
 	// File scanning mode
 	if *scan {
-		scanner.ScanForMaps(*filename)
+		profile, err := scanParams.profile()
+		if err != nil {
+			pterm.Error.Println(err)
+			os.Exit(1)
+		}
+		// Promoting an overlapping hit asks first
+		if *promote != "" && editor.NeedsConfirm(editor.ConfirmSave) && !stdinIsTerminal() {
+			pterm.Error.Println("Confirmation needs an interactive terminal; pass -yes to write without asking")
+			os.Exit(1)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		results, ok := scanner.ScanForMaps(ctx, *filename, profile, *resume)
+		stop()
+		// Results found before an interruption are still written
+		if format != tabular.FormatTable && !writeTable(scanner.ResultsTable(results, profile), format, *outFile) {
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		if *promote != "" && !promoteScanHit(prompt, *filename, results, *promote, *promoteName) {
+			os.Exit(1)
+		}
 		return
 	}
 
 	// Interactive edit mode
 	if *edit {
-		editor.InteractiveEdit(*filename, false)
+		editor.InteractiveEdit(prompt, *filename, *dryRun)
 		return
 	}
 
 	// Apply preset modifications
 	if *preset != "" {
-		editor.ApplyPreset(*filename, *preset, false)
+		editor.ApplyPreset(prompt, *filename, *preset, *presetArgs, *dryRun)
+		return
+	}
+
+	// Show or move the fuel-cut columns
+	if *fuelCut != "" {
+		if fuelCutShift != 0 && editor.NeedsConfirm(editor.ConfirmSave) && !*dryRun && !stdinIsTerminal() {
+			pterm.Error.Println("Confirmation needs an interactive terminal; pass -yes to write without asking")
+			os.Exit(1)
+		}
+		if !editor.AdjustFuelCut(prompt, *filename, fuelCutShift, false, *dryRun) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Nudge a single cell
+	if *nudge != "" {
+		if editor.NeedsConfirm(editor.ConfirmSave) && !*dryRun && !stdinIsTerminal() {
+			pterm.Error.Println("Confirmation needs an interactive terminal; pass -yes to write without asking")
+			os.Exit(1)
+		}
+		if !editor.ApplyNudge(prompt, *filename, nudgeSpec, *dryRun) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Transform a map or region
+	if *scaleRegion != "" {
+		if editor.NeedsConfirm(editor.ConfirmSave) && !*dryRun && !stdinIsTerminal() {
+			pterm.Error.Println("Confirmation needs an interactive terminal; pass -yes to write without asking")
+			os.Exit(1)
+		}
+		if !editor.ApplyTransform(prompt, *filename, transformSpec, *dryRun) {
+			os.Exit(1)
+		}
 		return
 	}
 
 	// Normal display mode
-	renderer.DisplayMaps(*filename, *mapType, *verbose, *displayMode, reader.ReadMap)
+	id, _ := reader.IdentifyBinary(*filename)
+	renderer.DisplayMaps(*filename, *mapType, *verbose, *displayMode, id, reader.ReadMapCached)
 }
 
-// findBinFiles scans a directory for .bin files
-func findBinFiles(dir string) []string {
-	var binFiles []string
+// runQuery lists every cell matching a predicate with its value, raw value
+// and file offset
+func runQuery(filename, expr string) bool {
+	p, err := query.Parse(expr)
+	if err != nil {
+		pterm.Error.Printf("Invalid query: %v\n", err)
+		return false
+	}
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read file: %v\n", err)
+		return false
+	}
 
-	files, err := os.ReadDir(dir)
+	matches := query.Find(data, p)
+	if len(matches) == 0 {
+		pterm.Info.Printf("No cells match %s\n", p)
+		return true
+	}
+	tableData := pterm.TableData{{"Map", "Row", "Col", "Value", "Raw", "Offset"}}
+	for _, m := range matches {
+		tableData = append(tableData, []string{
+			m.Map, strconv.Itoa(m.Row), strconv.Itoa(m.Col),
+			pterm.Sprintf("%.2f %s", m.Value, m.Unit),
+			strconv.FormatInt(m.Raw, 10),
+			pterm.Sprintf("0x%04X", m.Offset),
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	pterm.Info.Println(query.Describe(p, matches))
+	return true
+}
+
+// applyProject loads a project file, or the default one of a directory,
+// and uses its files where -file and -compare were not given
+func applyProject(path string, filename, compareFile *string) bool {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path, _ = editor.ProjectPath(path, "")
+	}
+	p, err := editor.LoadProject(path)
 	if err != nil {
-		return binFiles
+		pterm.Error.Printf("Failed to load project: %v\n", err)
+		return false
+	}
+	if p == nil {
+		pterm.Error.Printf("No project file at %s\n", path)
+		return false
 	}
 
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".bin") {
-			binFiles = append(binFiles, filepath.Join(dir, file.Name()))
+	if *filename == "" {
+		*filename = p.File
+	}
+	if *compareFile == "" {
+		*compareFile = p.CompareFile
+	}
+	if len(p.Offsets) > 0 {
+		pterm.Warning.Println("The project's map offset overrides only apply in the web UI")
+	}
+	pterm.Info.Printf("Project %s (saved %s)\n", path, p.Saved.Format("2006-01-02 15:04"))
+	return true
+}
+
+// applyXDF replaces the definitions with those of a TunerPro XDF file and
+// reports what it left out
+func applyXDF(path string) bool {
+	x, err := export.ApplyXDF(path)
+	if err != nil {
+		pterm.Error.Printf("XDF not loaded: %v\n", err)
+		return false
+	}
+	for _, line := range x.Warnings() {
+		pterm.Warning.Println(line)
+	}
+	pterm.Info.Printf("%d maps and %d parameters from %s\n", len(x.Maps), len(models.ConfigParams), filepath.Base(path))
+	return true
+}
+
+// applyProfile activates the profile of -profile. auto reads filename to
+// detect it.
+func applyProfile(name, filename string) bool {
+	var data []byte
+	if strings.EqualFold(name, "auto") && filename != "" {
+		var err error
+		if data, err = reader.ReadBinary(filename); err != nil {
+			pterm.Error.Printf("Failed to read %s: %v\n", filename, err)
+			return false
+		}
+	}
+	p, err := editor.SelectProfile(name, data)
+	if err != nil {
+		pterm.Error.Printf("Invalid -profile: %v\n", err)
+		return false
+	}
+	if strings.EqualFold(name, "auto") {
+		pterm.Info.Printf("Detected profile %s (%s)\n", p.Name, p.Description)
+	}
+	return true
+}
+
+// printProfiles lists the ECU profiles, marking the active one
+func printProfiles() {
+	data := pterm.TableData{{"Profile", "Description", "Sizes", "Maps", "Parameters", "Signatures", "Source", ""}}
+	for _, p := range models.Profiles {
+		sizes := make([]string, len(p.ExpectedSizes))
+		for i, size := range p.ExpectedSizes {
+			sizes[i] = fmt.Sprintf("%d KB", size/1024)
+		}
+		source, active := p.Source, ""
+		if source == "" {
+			source = "built-in"
 		}
+		if p.Name == models.ActiveProfile {
+			active = "active"
+		}
+		data = append(data, []string{p.Name, p.Description, strings.Join(sizes, ", "),
+			strconv.Itoa(len(p.MapConfigs)), strconv.Itoa(len(p.ConfigParams)), strconv.Itoa(len(p.Signatures)), source, active})
 	}
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+	dir, _ := paths.ConfigFile(editor.ProfilesDir)
+	pterm.Info.Printf("Add profiles as JSON files in %s\n", dir)
+}
 
-	return binFiles
+// writeXDF exports the active definitions as an XDF for filename, warning
+// about inverse tables, which -xdf reads back as formulas without an
+// inverse
+func writeXDF(filename, out string) bool {
+	id, err := reader.IdentifyBinary(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to identify %s: %v\n", filename, err)
+		return false
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		pterm.Error.Printf("XDF export failed: %v\n", err)
+		return false
+	}
+	// BASEOFFSET carries the image's place in filename
+	err = export.WriteXDF(f, models.ImageMapConfigs(), models.ImageConfigParams(), id)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		pterm.Error.Printf("XDF export failed: %v\n", err)
+		return false
+	}
+	var inverse []string
+	for _, cfg := range models.MapConfigs {
+		if cfg.Formula == "" && cfg.Conversion == models.ConversionInverse {
+			inverse = append(inverse, cfg.Name)
+		}
+	}
+	if len(inverse) > 0 {
+		pterm.Warning.Printf("Inverse tables are exported as equations that -xdf reads back as read-only formulas: %s\n", strings.Join(inverse, ", "))
+	}
+	pterm.Success.Printf("Wrote %d tables and %d constants to %s\n", len(models.MapConfigs), len(models.ConfigParams), out)
+	return true
+}
+
+// logToStderr sends pterm messages to stderr so stdout carries only JSON
+func logToStderr() {
+	pterm.SetDefaultOutput(os.Stderr)
+	for _, p := range []*pterm.PrefixPrinter{&pterm.Info, &pterm.Success, &pterm.Warning, &pterm.Error} {
+		p.Writer = os.Stderr
+	}
+}
+
+// runChanged prints what changed in filename since the -since window
+// began and returns the exit code of the changed command
+func runChanged(filename, since string) int {
+	if filename == "" {
+		pterm.Error.Println("changed needs a binary: changed <file.bin>")
+		return 2
+	}
+	start, err := compare.ParseSince(since, time.Now())
+	if err != nil {
+		pterm.Error.Println(err)
+		return 2
+	}
+	report, err := compare.ChangesSince(filename, start, reader.ReadMapCached)
+	if err != nil {
+		pterm.Error.Println(err)
+		return 2
+	}
+	report.Print()
+	if report.Changed() {
+		return 0
+	}
+	return 1
+}
+
+// runInfo prints the summary of a binary, as JSON on stdout if asJSON is
+// set. It returns false if the file cannot be read or has issues.
+func runInfo(filename string, asJSON bool) bool {
+	if filename == "" {
+		pterm.Error.Println("info needs a binary: info <file.bin>")
+		return false
+	}
+	if asJSON {
+		logToStderr()
+	}
+	summary, err := info.Build(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	if asJSON {
+		if err := info.WriteJSON(os.Stdout, summary); err != nil {
+			pterm.Error.Println(err)
+			return false
+		}
+	} else {
+		info.Print(summary)
+	}
+	return summary.OK()
+}
+
+// applyLayout moves the map and parameter definitions to the image found
+// in filename. Unreadable files are left to the command to report
+func applyLayout(filename string) {
+	layout, err := reader.FileLayout(filename)
+	if err != nil {
+		return
+	}
+	if layout.Reason != "" {
+		pterm.Info.Printf("%s: image at 0x%X (%s)\n", filepath.Base(filename), layout.BaseOffset, layout.Reason)
+	}
+	models.UseBaseOffset(layout.BaseOffset)
+}
+
+// resolveBinFile returns name unchanged if it exists or includes a
+// directory, otherwise the same name inside binDir when that exists
+func resolveBinFile(name, binDir string) string {
+	if name == "" || binDir == "" || filepath.Base(name) != name {
+		return name
+	}
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	candidate := filepath.Join(binDir, name)
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+	return name
+}
+
+// printVersion shows the version and which directories are active, so users
+// can check what a launch from the current directory will use
+func printVersion(binDir, binSource string) {
+	pterm.Printf("motronic-m21-tool %s\n", version.Version)
+	if dir, err := paths.ConfigDir(); err == nil {
+		pterm.Printf("Config directory: %s\n", dir)
+	}
+	if binDir == "" {
+		pterm.Printf("Binary directory: none (set -bins, bin_dir in settings.json, or $%s)\n", settings.EnvBinDir)
+		return
+	}
+	pterm.Printf("Binary directory: %s (from %s)\n", binDir, binSource)
+}
+
+// runQuickstart creates a demo directory with a synthetic binary, a tuned
+// copy of it and a project file comparing the two, then prints commands to
+// try. Existing files are never overwritten.
+func runQuickstart(dir string) bool {
+	if dir == "" {
+		dir = "ecu-demo"
+	}
+	sample := filepath.Join(dir, "sample.bin")
+	tuned := filepath.Join(dir, "tuned.bin")
+	for _, path := range []string{sample, tuned} {
+		if _, err := os.Stat(path); err == nil {
+			pterm.Error.Printf("%s already exists; choose another directory\n", path)
+			return false
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		pterm.Error.Printf("Failed to create %s: %v\n", dir, err)
+		return false
+	}
+
+	// The tuned copy has the open-loop lambda preset applied, so compare
+	// has something to show
+	data := testbin.Image()
+	tunedData := append([]byte(nil), data...)
+	if p, ok := editor.FindPreset("lambda-openloop"); ok {
+		args, _ := p.ParseArgs("")
+		changes, err := p.Plan(tunedData, args)
+		if err != nil {
+			pterm.Error.Printf("Failed to build the tuned sample: %v\n", err)
+			return false
+		}
+		for _, c := range changes {
+			c.Apply(tunedData)
+		}
+	}
+
+	for path, contents := range map[string][]byte{sample: data, tuned: tunedData} {
+		if err := os.WriteFile(path, contents, 0644); err != nil {
+			pterm.Error.Printf("Failed to write %s: %v\n", path, err)
+			return false
+		}
+	}
+	projectFile, err := editor.ProjectPath(dir, "")
+	if err == nil {
+		_, err = editor.SaveProject(projectFile, &editor.Project{File: sample, CompareFile: tuned, Map: "Lambda Target Map"})
+	}
+	if err != nil {
+		pterm.Error.Printf("Failed to write the project file: %v\n", err)
+		return false
+	}
+
+	pterm.Success.Printf("Created %s\n", dir)
+	pterm.Printf("  %-32s synthetic M2.1 image with every defined map filled in\n", sample)
+	pterm.Printf("  %-32s the same image with the lambda-openloop preset applied\n", tuned)
+	pterm.Printf("  %-32s web view of the two files compared\n", projectFile)
+	pterm.Println()
+	pterm.DefaultSection.Println(i18n.T("cli.quickstart.next"))
+	program := usage.Program()
+	for _, e := range usage.NextSteps() {
+		pterm.Println(pterm.Gray("# " + e.Note))
+		pterm.Println("  " + e.In(dir).Command(program))
+	}
+	pterm.Info.Printf("More examples: %s help <topic>\n", program)
+	return true
+}
+
+// applyLocale selects the language of messages from the saved settings or
+// $LANG. A settings file that fails to load is reported by
+// applyConfirmPolicy.
+func applyLocale() {
+	s, _ := settings.Load()
+	i18n.SetLocale(i18n.Detect(s.Locale))
+}
+
+// applyConfirmPolicy sets the confirmation policy from -yes or, without it,
+// from the saved settings
+func applyConfirmPolicy(assumeYes bool) {
+	if assumeYes {
+		editor.Confirmation = editor.ConfirmNever
+		return
+	}
+
+	s, err := settings.Load()
+	if err != nil {
+		pterm.Warning.Printf("Could not load settings: %v\n", err)
+	}
+	if s.ConfirmPolicy == "" {
+		return
+	}
+	policy, err := editor.ParseConfirmPolicy(s.ConfirmPolicy)
+	if err != nil {
+		pterm.Warning.Printf("Ignoring saved setting: %v\n", err)
+		return
+	}
+	editor.Confirmation = policy
+}
+
+// applyBackupPolicy turns on the strict backup mode from -require-backup
+// or the require_backup setting. It can't be combined with -no-backup,
+// which the setting then refuses too.
+func applyBackupPolicy(requireBackup bool) {
+	s, _ := settings.Load()
+	reader.RequireBackup = requireBackup || s.RequireBackup
+	if reader.RequireBackup && reader.NoBackup {
+		if requireBackup {
+			pterm.Error.Println("-require-backup and -no-backup can't be combined")
+		} else {
+			pterm.Error.Println("-no-backup is refused while the require_backup setting is on")
+		}
+		os.Exit(1)
+	}
+}
+
+// applyMirrorWrites makes saves to a mirrored dump write every copy of the
+// image, from -mirror-writes or the mirror_writes setting
+func applyMirrorWrites(mirrorWrites bool) {
+	s, _ := settings.Load()
+	editor.MirrorWrites = mirrorWrites || s.MirrorWrites
+}
+
+// applyChecksumPolicy sets whether saves store the image checksum, from
+// the -checksum-on-save flag or else the checksum_on_save setting, and
+// takes the profile's checksum from the checksum_spec setting unless
+// -checksum-spec gave one. Under the ask policy a save with a stale
+// checksum asks on the terminal; -yes stores it without asking, and
+// without a terminal the checksum is left stale with a warning.
+func applyChecksumPolicy(flagValue string, prompt editor.Prompter) {
+	s, err := settings.Load()
+	if err != nil {
+		pterm.Warning.Printf("Could not load settings: %v\n", err)
+	}
+	if s.ChecksumSpec != "" && models.M21IDProfile.Checksum == nil {
+		if err := checksum.UseSpec(s.ChecksumSpec); err != nil {
+			pterm.Warning.Printf("Ignoring saved checksum_spec: %v\n", err)
+		}
+	}
+
+	switch {
+	case flagValue != "":
+		policy, err := editor.ParseChecksumPolicy(strings.ToLower(flagValue))
+		if err != nil {
+			pterm.Error.Printf("Invalid -checksum-on-save: %v\n", err)
+			os.Exit(1)
+		}
+		editor.ChecksumOnSave = policy
+	case s.ChecksumOnSave != "":
+		policy, err := editor.ParseChecksumPolicy(s.ChecksumOnSave)
+		if err != nil {
+			pterm.Warning.Printf("Ignoring saved setting: %v\n", err)
+			break
+		}
+		editor.ChecksumOnSave = policy
+	}
+
+	editor.PlanChecksum = checksum.SessionStatus
+	editor.AskChecksum = func(filename string, status editor.ChecksumStatus) bool {
+		if !editor.NeedsConfirm(editor.ConfirmSave) {
+			return true
+		}
+		pterm.Warning.Printf("The edit leaves the image checksum stale: %s\n", status)
+		if !stdinIsTerminal() {
+			return false
+		}
+		return prompt.Confirm("Store the computed checksum too?")
+	}
+	// -yes doesn't cover this one: the phrase has to be typed
+	editor.ConfirmCodeEdit = func(filename, mapName string, check scanner.CodeCheck) bool {
+		pterm.Warning.Printf("%s is an unconfirmed map and %s. Writing to program code can brick the ECU.\n", mapName, check)
+		if !stdinIsTerminal() {
+			return false
+		}
+		answer, err := prompt.Input(fmt.Sprintf("Type %q to edit it anyway", editor.CodeConfirmPhrase), nil)
+		return err == nil && strings.TrimSpace(answer) == editor.CodeConfirmPhrase
+	}
+}
+
+// overlayLambdaLog bins a wideband log onto the Lambda Target Map, prints
+// the overlay and optionally writes a CSV or HTML report
+func overlayLambdaLog(filename, logPath, columns string, minSamples int, reportPath string) bool {
+	cols, err := datalog.ParseColumns(columns)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	samples, skipped, err := datalog.ParseFile(logPath, cols)
+	if err != nil {
+		pterm.Error.Printf("Failed to parse log: %v\n", err)
+		return false
+	}
+
+	target, err := reader.ReadMap(filename, models.MapConfigs[2])
+	if err != nil {
+		pterm.Error.Printf("Failed to read lambda map: %v\n", err)
+		return false
+	}
+
+	overlay := datalog.Bin(target.Config, samples, minSamples)
+	pterm.Info.Printf("%d samples, %d unparseable rows skipped, %d outside the map grid\n", len(samples), skipped, overlay.Outside)
+	renderer.RenderLogOverlay(target, overlay)
+
+	if reportPath == "" {
+		return true
+	}
+	return writeReport(reportPath,
+		func(w io.Writer) error { return datalog.WriteCSV(w, target, overlay) },
+		func(w io.Writer) error { return datalog.WriteHTML(w, target, overlay) })
+}
+
+// writeReport creates reportPath and writes it as HTML if it ends in .html,
+// otherwise as CSV
+func writeReport(reportPath string, writeCSV, writeHTML func(io.Writer) error) bool {
+	f, err := os.Create(reportPath)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	if strings.EqualFold(filepath.Ext(reportPath), ".html") {
+		err = writeHTML(f)
+	} else {
+		err = writeCSV(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		pterm.Error.Printf("Failed to write report: %v\n", err)
+		return false
+	}
+	pterm.Success.Printf("Report written to %s\n", reportPath)
+	return true
+}
+
+// promoteScanHit adds the scan hit at spec (see scanner.FindResult) to the
+// user's map definitions. Exact duplicates of existing definitions are
+// refused; partial overlaps are listed and need confirmation.
+func promoteScanHit(prompt editor.Prompter, filename string, results []scanner.ScanResult, spec, name string) bool {
+	hit, err := scanner.FindResult(results, spec)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read %s: %v\n", filename, err)
+		return false
+	}
+	if name == "" {
+		name = fmt.Sprintf("Scan hit 0x%04X", hit.Offset)
+	}
+
+	cfg := hit.Candidate(name)
+	for _, a := range []struct {
+		label string
+		guess scanner.AxisGuess
+	}{{"X", hit.Axes.X}, {"Y", hit.Axes.Y}} {
+		pterm.Info.Printf("Suggested %s axis: %s in %s, confidence %s (%s)\n",
+			a.label, a.guess.Kind, a.guess.Unit, a.guess.Confidence, a.guess.Note)
+	}
+	if (cfg.XAxis != nil || cfg.YAxis != nil) && !prompt.Confirm("Use the suggested axes?") {
+		cfg.XAxis, cfg.YAxis = nil, nil
+		cfg.Description, _, _ = strings.Cut(cfg.Description, ";")
+	}
+	check, err := editor.PromoteMap(prompt, cfg, info.Size())
+	for _, o := range check.Overlaps {
+		pterm.Warning.Println(o.String())
+	}
+	if err != nil {
+		pterm.Error.Printf("Failed to add %s: %v\n", name, err)
+		return false
+	}
+	pterm.Success.Printf("Added %s at 0x%04X (%dx%d %s) to %s, unconfirmed and read raw until you calibrate it\n",
+		name, cfg.Offset, cfg.Rows, cfg.Cols, cfg.DataType, editor.UserMapsFile)
+	return true
+}
+
+// scanFlags are the scanner parameters given on the command line
+type scanFlags struct {
+	name        string
+	stride      int
+	exhaustive  bool
+	scanRange   string
+	minVariance float64
+	sizes       string
+}
+
+// profile returns the parameters of a scan: the -scan-profile profile, or
+// the defaults, with the scan flags given explicitly on top
+func (f scanFlags) profile() (scanner.Profile, error) {
+	var p scanner.Profile
+	if f.name != "" {
+		s, err := settings.Load()
+		if err != nil {
+			pterm.Warning.Printf("Could not load settings: %v\n", err)
+		}
+		if p, err = s.ScanProfile(f.name); err != nil {
+			return p, err
+		}
+	}
+
+	var err error
+	flag.Visit(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch fl.Name {
+		case "scan-stride":
+			if f.stride < 1 {
+				err = fmt.Errorf("invalid -scan-stride %d: expected 1 or more", f.stride)
+			}
+			p.Stride = f.stride
+		case "exhaustive":
+			if f.exhaustive {
+				p.Stride = 1
+			}
+		case "scan-range":
+			p.Range, err = scanner.ParseRange(f.scanRange)
+		case "min-variance":
+			if f.minVariance < 0 {
+				err = fmt.Errorf("invalid -min-variance %g: expected 0 or more", f.minVariance)
+			}
+			p.MinVariance = f.minVariance
+		case "scan-sizes":
+			p.Sizes, err = scanner.ParseSizes(f.sizes)
+		}
+	})
+	return p, err
+}
+
+// runScanProfiles lists the scan profiles, saves the scan flags as the
+// profile save or deletes the saved profile del
+func runScanProfiles(f scanFlags, list bool, save, del string) bool {
+	s, err := settings.Load()
+	if err != nil {
+		pterm.Error.Printf("Could not load settings: %v\n", err)
+		return false
+	}
+
+	switch {
+	case save != "":
+		p, err := f.profile()
+		if err == nil {
+			p.Name = save
+			err = s.SetScanProfile(p)
+		}
+		if err == nil {
+			err = s.Save()
+		}
+		if err != nil {
+			pterm.Error.Printf("Scan profile not saved: %v\n", err)
+			return false
+		}
+		pterm.Success.Printf("Saved scan profile %s: %s\n", p.Name, p)
+	case del != "":
+		err := s.DeleteScanProfile(del)
+		if err == nil {
+			err = s.Save()
+		}
+		if err != nil {
+			pterm.Error.Printf("Scan profile not deleted: %v\n", err)
+			return false
+		}
+		pterm.Success.Printf("Deleted scan profile %s\n", del)
+	}
+
+	if list {
+		data := pterm.TableData{{"Profile", "Parameters", ""}}
+		for _, p := range s.AllScanProfiles() {
+			builtin := ""
+			if scanner.IsBuiltinProfile(p.Name) {
+				builtin = "built-in"
+			}
+			data = append(data, []string{p.Name, p.String(), builtin})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+	}
+	return true
+}
+
+// writeTable writes t as CSV or JSON to outPath, or to stdout when it is
+// empty
+func writeTable(t *tabular.Table, format tabular.Format, outPath string) bool {
+	if outPath == "" {
+		if err := t.Write(os.Stdout, format); err != nil {
+			pterm.Error.Printf("Failed to write output: %v\n", err)
+			return false
+		}
+		return true
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	err = t.Write(f, format)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		pterm.Error.Printf("Failed to write %s: %v\n", outPath, err)
+		return false
+	}
+	pterm.Success.Printf("Wrote %d row(s) to %s\n", len(t.Rows), outPath)
+	return true
+}
+
+// suggestFuelCorrections shows advisory fuel map changes from a wideband
+// log and lets the user stage them into a reviewed edit session. The
+// suggestion is never written without an explicit yes, whatever the
+// confirmation policy.
+func suggestFuelCorrections(prompt editor.Prompter, filename, logPath, columns string, minSamples int, authority float64) bool {
+	if logPath == "" {
+		pterm.Error.Println("-log is required with -suggest-fuel")
+		return false
+	}
+	if authority <= 0 || authority > 0.5 {
+		pterm.Error.Println("-authority must be between 0 and 0.5")
+		return false
+	}
+
+	cols, err := datalog.ParseColumns(columns)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	samples, _, err := datalog.ParseFile(logPath, cols)
+	if err != nil {
+		pterm.Error.Printf("Failed to parse log: %v\n", err)
+		return false
+	}
+
+	target, err := reader.ReadMap(filename, models.MapConfigs[2])
+	if err != nil {
+		pterm.Error.Printf("Failed to read lambda map: %v\n", err)
+		return false
+	}
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+
+	overlay := datalog.Bin(target.Config, samples, minSamples)
+	suggestion, err := editor.SuggestFuel(data, models.MapConfigs[0], target, overlay, authority)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+
+	pterm.DefaultHeader.WithFullWidth().Println(i18n.T("cli.suggest.header"))
+	pterm.Info.Printf("%d samples, authority ±%.0f%%, at least %d samples per cell\n", len(samples), authority*100, minSamples)
+	if len(suggestion.Changes) > 0 {
+		editor.SortChanges(suggestion.Changes)
+		editor.PrintChanges(suggestion.Changes)
+	}
+	pterm.Info.Printf("%d cells would change\n", len(suggestion.Changes))
+	if len(suggestion.Uncovered) > 0 {
+		pterm.Warning.Printf("%d cells without enough log coverage left untouched:\n", len(suggestion.Uncovered))
+		for _, c := range suggestion.Uncovered {
+			pterm.Printf("%s ", c)
+		}
+		pterm.Println()
+	}
+
+	if len(suggestion.Changes) == 0 {
+		return true
+	}
+	if !stdinIsTerminal() {
+		pterm.Info.Println("Run interactively to stage the suggestion into an edit session")
+		return true
+	}
+	if !prompt.Confirm("Stage these changes into an edit session for review?") {
+		return true
+	}
+
+	session, err := editor.NewSession(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	session.Add(editor.Operation{Name: "suggest-fuel", Plan: func([]byte) ([]editor.CellChange, error) {
+		return suggestion.Changes, nil
+	}})
+	session.Confirm = func(r *editor.Report) bool {
+		r.PrintTable()
+		return prompt.Confirm("Write the reviewed fuel corrections to file?")
+	}
+
+	report, err := session.Commit()
+	report.PrintSummary()
+	if err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return false
+	}
+	return true
+}
+
+// findOutliers lists the outlier cells of the selected maps with their
+// smoothed values and lets the user stage the smoothing into a reviewed
+// edit session
+func findOutliers(prompt editor.Prompter, filename, mapType string, threshold float64, dryRun bool) bool {
+	configs := models.MapConfigs
+	if mapType != "all" {
+		cfg, err := editor.MatchMap(mapType)
+		if err != nil {
+			pterm.Error.Println(err)
+			return false
+		}
+		configs = []models.MapConfig{cfg}
+	}
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+
+	pterm.DefaultHeader.WithFullWidth().Println(i18n.T("cli.outliers.header"))
+	var ops []editor.Operation
+	total := 0
+	for _, cfg := range configs {
+		m, err := reader.ReadMapFromBytes(data, cfg)
+		if err != nil {
+			pterm.Error.Printf("%s: %v\n", cfg.Name, err)
+			continue
+		}
+		limit := editor.OutlierThreshold(m, threshold)
+		found := editor.FindOutliers(m, limit)
+		if len(found) == 0 {
+			pterm.Success.Printf("%s: no cell deviates more than %.2f %s from its neighbors\n", cfg.Name, limit, cfg.Unit)
+			continue
+		}
+
+		pterm.DefaultSection.Printf("%s (threshold %.2f %s)\n", cfg.Name, limit, cfg.Unit)
+		tableData := pterm.TableData{{"Row", "Col", "Value", "Median", "Deviation", "Smoothed"}}
+		for _, o := range found {
+			tableData = append(tableData, []string{
+				strconv.Itoa(o.Row), strconv.Itoa(o.Col),
+				pterm.Sprintf("%.2f", o.Value), pterm.Sprintf("%.2f", o.Median),
+				pterm.Sprintf("%+.2f", o.Deviation()), pterm.Sprintf("%.2f", o.Smoothed),
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		total += len(found)
+
+		changes, err := editor.PlanOutlierSmoothing(data, cfg, found)
+		if err != nil {
+			pterm.Error.Println(err)
+			return false
+		}
+		if len(changes) > 0 {
+			ops = append(ops, editor.Operation{Name: "smooth " + cfg.Name, Plan: func([]byte) ([]editor.CellChange, error) {
+				return changes, nil
+			}})
+		}
+	}
+	pterm.Info.Printf("%d outlier cell(s) in %d map(s)\n", total, len(configs))
+
+	if len(ops) == 0 || dryRun {
+		return true
+	}
+	if !stdinIsTerminal() {
+		pterm.Info.Println("Run interactively to stage the smoothed values into an edit session")
+		return true
+	}
+	if !prompt.Confirm("Stage the smoothed values into an edit session for review?") {
+		return true
+	}
+
+	session, err := editor.NewSession(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	for _, op := range ops {
+		session.Add(op)
+	}
+	session.Confirm = func(r *editor.Report) bool {
+		r.PrintTable()
+		return prompt.Confirm("Write the smoothed values to file?")
+	}
+
+	report, err := session.Commit()
+	report.PrintSummary()
+	if err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return false
+	}
+	return true
+}
+
+// linkFile sets up lock-step editing of filename and linked and reports
+// where the pair already diverges, per map or in full with verbose
+func linkFile(filename, linked string, force, verbose bool) bool {
+	editor.LinkedFile = linked
+	editor.ForceMismatch = force
+	if err := editor.CheckLinked(filename); err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	if err := reader.CheckWritable(linked); err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return false
+	}
+	mismatches, err := editor.DivergenceFiles(filename, linked)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	if len(mismatches) == 0 {
+		pterm.Info.Printf("Editing %s and %s in lock step; all maps and parameters match\n", filename, linked)
+		return true
+	}
+	pterm.Warning.Printf("Editing %s and %s in lock step; they already differ in %d cell(s):\n", filename, linked, len(mismatches))
+	if verbose {
+		editor.MismatchTable(mismatches, filename, linked).Render()
+	} else {
+		editor.MismatchSummary(mismatches).Render()
+	}
+	return true
+}
+
+// prepareWriteTarget checks that filename can be modified before any prompt
+// is shown. With -safe-copy, edits go to a fresh copy in the current
+// directory instead and its path is returned.
+func prepareWriteTarget(filename string, safeCopy bool) (string, bool) {
+	if safeCopy {
+		dest, err := editor.SafeCopy(filename, ".")
+		if err != nil {
+			pterm.Error.Printf("Failed to create safe copy: %v\n", err)
+			return "", false
+		}
+		pterm.Info.Printf("Editing copy %s; %s is left untouched\n", dest, filename)
+		return dest, true
+	}
+	if err := reader.CheckWritable(filename); err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return "", false
+	}
+	return filename, true
+}
+
+// listAttachments prints the logs attached to an ECU file and whether
+// each is still present and unchanged
+func listAttachments(filename string) bool {
+	sidecar, err := editor.LoadSidecar(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	if len(sidecar.Attachments) == 0 {
+		pterm.Info.Printf("No logs attached to %s\n", filename)
+		return true
+	}
+
+	binHash, _ := reader.HashFile(filename)
+	tableData := pterm.TableData{{"File", "Size", "SHA-256", "Added", "Revision", "Status", "Note"}}
+	for _, a := range sidecar.Attachments {
+		revision := "older"
+		if a.BinSHA256 == binHash {
+			revision = "current"
+		}
+		tableData = append(tableData, []string{
+			a.Path,
+			formatFileSize(a.Size),
+			a.SHA256[:16],
+			a.Added.Format("2006-01-02 15:04"),
+			revision,
+			a.Status(),
+			a.Note,
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	return true
+}
+
+// extractBytes writes a byte range or a map's bytes to outFile
+func extractBytes(filename, rangeStr, mapName, outFile string) bool {
+	if outFile == "" {
+		pterm.Error.Println("-o is required with -extract and -extract-map")
+		return false
+	}
+
+	var region models.Region
+	if mapName != "" {
+		cfg, ok := models.FindMapConfig(mapName)
+		if !ok {
+			pterm.Error.Printf("Unknown map: %s\n", mapName)
+			return false
+		}
+		region = cfg.Region()
+	} else {
+		var err error
+		region, err = editor.ParseRange(rangeStr)
+		if err != nil {
+			pterm.Error.Println(err)
+			return false
+		}
+	}
+
+	if err := editor.ExtractRegion(filename, region, outFile); err != nil {
+		pterm.Error.Printf("Extract failed: %v\n", err)
+		return false
+	}
+	pterm.Success.Printf("Wrote %d bytes (0x%04X-0x%04X) to %s\n", region.End-region.Start, region.Start, region.End-1, outFile)
+	return true
+}
+
+// injectBytes writes the contents of src into the ECU file at the given offset
+func injectBytes(prompt editor.Prompter, filename, src, at string) bool {
+	if at == "" {
+		pterm.Error.Println("-at is required with -inject")
+		return false
+	}
+	offset, err := editor.ParseOffset(at)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	pterm.Warning.Printf("Writing %d bytes from %s at 0x%04X in %s\n", info.Size(), src, offset, filename)
+	if editor.NeedsConfirm(editor.ConfirmSave) && !stdinIsTerminal() {
+		pterm.Error.Println("Confirmation needs an interactive terminal; pass -yes to write without asking")
+		return false
+	}
+	if !editor.Confirm(prompt, editor.ConfirmSave, i18n.T("cli.confirm.bytes")) {
+		pterm.Info.Println("Cancelled")
+		return true
+	}
+
+	backup, err := editor.InjectRegion(filename, src, offset)
+	editor.PrintBackup(backup)
+	if err != nil {
+		pterm.Error.Printf("Inject failed: %s\n", reader.DescribeWriteError(err))
+		return false
+	}
+	pterm.Success.Printf("Injected %d bytes at 0x%04X\n", info.Size(), offset)
+	if models.M21IDProfile.Checksum != nil {
+		pterm.Warning.Println("The image checksum may no longer match; check it with -checksum")
+	} else {
+		pterm.Warning.Println("No checksum is defined for Motronic M2.1 images yet; verify the checksum with your flashing tool or pass -checksum-spec")
+	}
+	return true
+}
+
+// runChecksum verifies the image checksum of filename through the active
+// profile and, with fix set, stores the computed value in an edit session.
+// It returns false if the checksum cannot be computed, or does not match
+// and is not fixed.
+func runChecksum(prompt editor.Prompter, filename string, fix, dryRun bool) bool {
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	profile := models.M21IDProfile
+	base := reader.IdentifyData(data, profile).BaseOffset
+	changes, result, err := checksum.PlanFix(data, base, profile)
+	if err != nil {
+		pterm.Error.Println(err)
+		if profile.Checksum == nil {
+			pterm.Info.Println("Pass -checksum-spec algorithm:start-end@store to check a known scheme")
+		}
+		return false
+	}
+	pterm.DefaultSection.Println("Checksum")
+	result.Print()
+	if !fix || len(changes) == 0 {
+		return result.OK()
+	}
+	if dryRun {
+		pterm.Info.Printf("Dry run: would store 0x%X at 0x%04X\n", result.Computed, changes[0].Offset)
+		return true
+	}
+	if editor.NeedsConfirm(editor.ConfirmSave) && !stdinIsTerminal() {
+		pterm.Error.Println("Confirmation needs an interactive terminal; pass -yes to write without asking")
+		return false
+	}
+	if !editor.Confirm(prompt, editor.ConfirmSave, "Store the computed checksum?") {
+		pterm.Info.Println("Cancelled")
+		return false
+	}
+
+	session, err := editor.NewSession(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	defer session.Close()
+	session.Add(editor.Operation{Name: "fix checksum " + result.Algorithm.Name, Plan: func(data []byte) ([]editor.CellChange, error) {
+		changes, _, err := checksum.PlanFix(data, base, profile)
+		return changes, err
+	}})
+	report, err := session.Commit()
+	report.PrintSummary()
+	if err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return false
+	}
+	pterm.Success.Printf("Stored checksum 0x%X at 0x%04X\n", result.Computed, changes[0].Offset)
+	return true
+}
+
+// stdinIsTerminal reports whether interactive prompts can be answered
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// findBinFiles scans a directory for .bin files
+func findBinFiles(dir string) []string {
+	var binFiles []string
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return binFiles
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".bin") {
+			binFiles = append(binFiles, filepath.Join(dir, file.Name()))
+		}
+	}
+
+	return binFiles
+}
+
+// MapHash is one line of -map-hashes output
+type MapHash struct {
+	File  string `json:"file"`
+	Map   string `json:"map"`
+	Hash  string `json:"hash,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// printMapHashes prints the content hash of every map of every file as
+// tab-separated file, map and hash lines, or as JSON. Maps that can't be
+// hashed show "-" and the error. It returns false if no file was given or
+// any hash failed.
+func printMapHashes(files []string, asJSON bool) bool {
+	if len(files) == 0 {
+		pterm.Error.Println("No files to hash; pass -file or -bins")
+		return false
+	}
+
+	var hashes []MapHash
+	ok := true
+	bar := progress.Start("Hashing maps", len(files))
+	for _, file := range files {
+		id, idErr := reader.IdentifyBinary(file)
+		for _, cfg := range models.ImageMapConfigs() {
+			h := MapHash{File: file, Map: cfg.Name}
+			err := idErr
+			if err == nil {
+				h.Hash, err = reader.MapHashCached(file, cfg, id.BaseOffset)
+			}
+			if err != nil {
+				h.Error = err.Error()
+				ok = false
+			}
+			hashes = append(hashes, h)
+		}
+		bar.Step(filepath.Base(file))
+	}
+	bar.Stop()
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(hashes) == nil && ok
+	}
+	for _, h := range hashes {
+		if h.Error != "" {
+			fmt.Printf("%s\t%s\t-\t%s\n", h.File, h.Map, h.Error)
+			continue
+		}
+		fmt.Printf("%s\t%s\t%s\n", h.File, h.Map, h.Hash)
+	}
+	return ok
+}
+
+// checkDefinitions reports overlapping map and parameter definitions.
+// Exact duplicates are errors; partial overlaps are reported as warnings.
+func checkDefinitions() bool {
+	pterm.DefaultHeader.WithFullWidth().Println(i18n.T("cli.defs.header"))
+
+	// A zero scale makes a definition unreadable, so it always fails
+	check := models.CheckDefinitions(models.MapConfigs, models.ConfigParams)
+	scaleErrs, overlaps := check.Errors, check.Overlaps
+	for _, err := range scaleErrs {
+		pterm.Error.Println(err)
+	}
+
+	regions := append(models.DefinitionRegions(models.MapConfigs, models.ConfigParams), models.AxisRegions(models.MapConfigs)...)
+
+	if len(overlaps) == 0 {
+		if len(scaleErrs) > 0 {
+			pterm.Info.Printf("%d definitions checked, %d invalid scale(s)\n", len(regions), len(scaleErrs))
+			return false
+		}
+		pterm.Success.Printf("%d definitions checked, no overlapping byte ranges\n", len(regions))
+		return true
+	}
+
+	duplicates := check.Duplicates()
+	for _, o := range overlaps {
+		if o.Exact {
+			pterm.Error.Println(o.String())
+		} else {
+			pterm.Warning.Println(o.String())
+		}
+	}
+	for _, m := range models.MapConfigs {
+		if m.OverlapNote != "" {
+			pterm.Info.Printf("%s was added with a confirmed overlap: %s\n", m.Name, m.OverlapNote)
+		}
+	}
+
+	pterm.Info.Printf("%d definitions checked, %d overlap(s), %d duplicate(s), %d invalid scale(s)\n",
+		len(regions), len(overlaps), duplicates, len(scaleErrs))
+	return check.OK()
 }
 
 // formatFileSize formats a file size in bytes to a human-readable string
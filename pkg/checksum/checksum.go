@@ -0,0 +1,301 @@
+// Package checksum verifies and fixes image checksums. Algorithms are
+// looked up by name in Algorithms; which one a binary uses, over which
+// regions and where the result is stored, comes from its profile's
+// models.ChecksumConfig, so supporting another ECU means adding a profile
+// entry (and an algorithm only if none fits).
+package checksum
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// Algorithm computes a checksum over the bytes of a profile's regions
+type Algorithm struct {
+	Name        string
+	Description string
+	// DataType is how the checksum is stored, "uint8" or "uint16"
+	DataType string
+	// MultiRegion allows more than one region
+	MultiRegion bool
+	// Compute returns the checksum of the regions' bytes, in order. The
+	// stored checksum's own bytes read as zero.
+	Compute func(regions [][]byte) int64
+}
+
+// Algorithms is the registry of checksum algorithms profiles can name
+var Algorithms = []Algorithm{
+	{
+		Name:        "sum16",
+		Description: "16-bit sum of every byte in one region",
+		DataType:    "uint16",
+		Compute:     func(regions [][]byte) int64 { return byteSum(regions) & 0xFFFF },
+	},
+	{
+		Name:        "sum8-complement",
+		Description: "byte that makes the 8-bit sum of one region zero (two's complement)",
+		DataType:    "uint8",
+		Compute:     func(regions [][]byte) int64 { return -byteSum(regions) & 0xFF },
+	},
+	{
+		Name:        "sum16-multi",
+		Description: "16-bit sum of every byte in several regions, stored once",
+		DataType:    "uint16",
+		MultiRegion: true,
+		Compute:     func(regions [][]byte) int64 { return byteSum(regions) & 0xFFFF },
+	},
+}
+
+// byteSum adds up every byte of regions
+func byteSum(regions [][]byte) int64 {
+	var sum int64
+	for _, region := range regions {
+		for _, b := range region {
+			sum += int64(b)
+		}
+	}
+	return sum
+}
+
+// FindAlgorithm returns the registered algorithm called name
+func FindAlgorithm(name string) (Algorithm, bool) {
+	for _, a := range Algorithms {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Algorithm{}, false
+}
+
+// algorithmNames lists the registered algorithms for error messages
+func algorithmNames() string {
+	names := make([]string, len(Algorithms))
+	for i, a := range Algorithms {
+		names[i] = a.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Check validates a checksum configuration against an image size
+func Check(cfg models.ChecksumConfig, imageSize int64) error {
+	a, ok := FindAlgorithm(cfg.Algorithm)
+	if !ok {
+		return reader.NewError(reader.ErrInvalidDefinition, "unknown checksum algorithm %q (known: %s)", cfg.Algorithm, algorithmNames())
+	}
+	if len(cfg.Regions) == 0 {
+		return reader.NewError(reader.ErrInvalidDefinition, "checksum %s has no regions", a.Name)
+	}
+	if len(cfg.Regions) > 1 && !a.MultiRegion {
+		return reader.NewError(reader.ErrInvalidDefinition, "checksum %s covers one region, got %d", a.Name, len(cfg.Regions))
+	}
+	for _, r := range cfg.Regions {
+		if r.Start < 0 || r.End <= r.Start || r.End > imageSize {
+			return reader.NewError(reader.ErrOutOfRange, "checksum region 0x%04X-0x%04X is outside the 0x%X-byte image", r.Start, r.End-1, imageSize)
+		}
+	}
+	if cfg.Store < 0 || cfg.Store+int64(models.DataTypeSize(a.DataType)) > imageSize {
+		return reader.NewError(reader.ErrOutOfRange, "checksum store 0x%04X is outside the 0x%X-byte image", cfg.Store, imageSize)
+	}
+	return nil
+}
+
+// Result is what Verify found. Offsets are relative to the image, which
+// starts at Base in the file.
+type Result struct {
+	Profile   string
+	Algorithm Algorithm
+	Regions   []models.ChecksumRegion
+	Store     int64
+	Base      int64
+	Order     models.Endianness
+	Stored    int64
+	Computed  int64
+}
+
+// OK reports whether the stored checksum matches
+func (r Result) OK() bool { return r.Stored == r.Computed }
+
+// Verify computes the checksum of the image at base in data as configured
+// by profile and reads the stored one. It fails if the profile has no
+// checksum or its configuration doesn't fit the image.
+func Verify(data []byte, base int64, profile models.IDProfile) (Result, error) {
+	cfg := profile.Checksum
+	if cfg == nil {
+		return Result{}, reader.NewError(reader.ErrNotFound, "no checksum algorithm defined for %s", profile.Name)
+	}
+	if err := Check(*cfg, profile.ImageSize); err != nil {
+		return Result{}, err
+	}
+	if base < 0 || base+profile.ImageSize > int64(len(data)) {
+		return Result{}, reader.NewError(reader.ErrOutOfRange, "image at 0x%X does not fit the %d-byte file", base, len(data))
+	}
+
+	a, _ := FindAlgorithm(cfg.Algorithm)
+	image := data[base : base+profile.ImageSize]
+	store := cfg.Store
+	width := int64(models.DataTypeSize(a.DataType))
+
+	regions := make([][]byte, len(cfg.Regions))
+	for i, r := range cfg.Regions {
+		region := append([]byte(nil), image[r.Start:r.End]...)
+		// The stored checksum is not part of what it sums
+		for off := max(store, r.Start); off < min(store+width, r.End); off++ {
+			region[off-r.Start] = 0
+		}
+		regions[i] = region
+	}
+
+	return Result{
+		Profile:   profile.Name,
+		Algorithm: a,
+		Regions:   cfg.Regions,
+		Store:     store,
+		Base:      base,
+		Order:     profile.Endianness,
+		Stored:    models.DecodeRawOrder(image[store:], a.DataType, profile.Endianness),
+		Computed:  a.Compute(regions),
+	}, nil
+}
+
+// PlanFix returns the change that stores the computed checksum, or none
+// if it already matches
+func PlanFix(data []byte, base int64, profile models.IDProfile) ([]editor.CellChange, Result, error) {
+	result, err := Verify(data, base, profile)
+	if err != nil || result.OK() {
+		return nil, result, err
+	}
+	return []editor.CellChange{result.Fix()}, result, nil
+}
+
+// Fix returns the change that stores the computed checksum
+func (r Result) Fix() editor.CellChange {
+	return editor.CellChange{
+		Map:        editor.ChecksumChange,
+		Offset:     r.Base + r.Store,
+		DataType:   r.Algorithm.DataType,
+		Endianness: r.Order,
+		OldRaw:     r.Stored,
+		NewRaw:     r.Computed,
+		OldValue:   float64(r.Stored),
+		NewValue:   float64(r.Computed),
+	}
+}
+
+// SessionStatus implements editor.PlanChecksum for models.M21IDProfile:
+// the checksum of data at its identified base offset, or nil when the
+// profile has no checksum
+func SessionStatus(data []byte) (*editor.ChecksumStatus, error) {
+	profile := models.M21IDProfile
+	if profile.Checksum == nil {
+		return nil, nil
+	}
+	base := reader.IdentifyData(data, profile).BaseOffset
+	result, err := Verify(data, base, profile)
+	if err != nil {
+		return nil, err
+	}
+	return &editor.ChecksumStatus{
+		Algorithm: result.Algorithm.Name,
+		Stored:    result.Stored,
+		Computed:  result.Computed,
+		Fix:       result.Fix(),
+	}, nil
+}
+
+// ParseSpec parses a -checksum-spec value, "algorithm:start-end@store",
+// with several comma-separated regions for multi-region algorithms, e.g.
+// "sum16:0x0000-0x7FFD@0x7FFE". Region ends are inclusive like
+// -scan-range; offsets are relative to the image.
+func ParseSpec(spec string) (*models.ChecksumConfig, error) {
+	name, rest, ok := strings.Cut(spec, ":")
+	ranges, store, ok2 := strings.Cut(rest, "@")
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("invalid checksum spec %q: expected algorithm:start-end@store", spec)
+	}
+	cfg := &models.ChecksumConfig{Algorithm: strings.TrimSpace(name)}
+	var err error
+	if cfg.Store, err = parseOffset(store); err != nil {
+		return nil, fmt.Errorf("invalid checksum store in %q: %w", spec, err)
+	}
+	for _, part := range strings.Split(ranges, ",") {
+		from, to, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid checksum region %q: expected start-end", part)
+		}
+		start, err := parseOffset(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum region %q: %w", part, err)
+		}
+		end, err := parseOffset(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum region %q: %w", part, err)
+		}
+		cfg.Regions = append(cfg.Regions, models.ChecksumRegion{Start: start, End: end + 1})
+	}
+	if _, ok := FindAlgorithm(cfg.Algorithm); !ok {
+		return nil, fmt.Errorf("unknown checksum algorithm %q (known: %s)", cfg.Algorithm, algorithmNames())
+	}
+	return cfg, nil
+}
+
+// UseSpec parses a checksum spec, checks it against the M2.1 image size
+// and makes it the profile's checksum
+func UseSpec(spec string) error {
+	cfg, err := ParseSpec(spec)
+	if err == nil {
+		err = Check(*cfg, models.M21IDProfile.ImageSize)
+	}
+	if err != nil {
+		return err
+	}
+	models.M21IDProfile.Checksum = cfg
+	return nil
+}
+
+// parseOffset parses a decimal or 0x-prefixed offset
+func parseOffset(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(s), 0, 64)
+}
+
+// FormatRegions lists regions as inclusive ranges, as ParseSpec reads them
+func FormatRegions(regions []models.ChecksumRegion) string {
+	parts := make([]string, len(regions))
+	for i, r := range regions {
+		parts[i] = fmt.Sprintf("0x%04X-0x%04X", r.Start, r.End-1)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Print shows the algorithm, regions and store used and whether the
+// stored checksum matches, so the setup can be checked against the ECU's
+// documentation
+func (r Result) Print() {
+	digits := 2 * models.DataTypeSize(r.Algorithm.DataType)
+	order := r.Order
+	if order == "" {
+		order = models.LittleEndian
+	}
+	tableData := pterm.TableData{
+		{"Profile", r.Profile},
+		{"Algorithm", fmt.Sprintf("%s (%s)", r.Algorithm.Name, r.Algorithm.Description)},
+		{"Regions", FormatRegions(r.Regions)},
+		{"Stored at", fmt.Sprintf("0x%04X, %s %s", r.Store, r.Algorithm.DataType, order)},
+		{"Stored", fmt.Sprintf("0x%0*X", digits, r.Stored)},
+		{"Computed", fmt.Sprintf("0x%0*X", digits, r.Computed)},
+	}
+	if r.Base != 0 {
+		tableData = append(tableData, []string{"Image at", fmt.Sprintf("0x%X (offsets above are relative to it)", r.Base)})
+	}
+	pterm.DefaultTable.WithData(tableData).Render()
+	if r.OK() {
+		pterm.Success.Println("Checksum matches")
+	} else {
+		pterm.Error.Println("Checksum does not match; -fix-checksum stores the computed value")
+	}
+}
@@ -0,0 +1,213 @@
+package checksum
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want *models.ChecksumConfig
+		err  string
+	}{
+		{spec: "sum16:0x0000-0x7FFD@0x7FFE",
+			want: &models.ChecksumConfig{Algorithm: "sum16", Regions: []models.ChecksumRegion{{Start: 0, End: 0x7FFE}}, Store: 0x7FFE}},
+		{spec: "sum8-complement:0-255@256",
+			want: &models.ChecksumConfig{Algorithm: "sum8-complement", Regions: []models.ChecksumRegion{{Start: 0, End: 256}}, Store: 256}},
+		{spec: " sum16-multi : 0x0000-0x0FFF, 0x2000-0x2FFF @ 0x7FFE",
+			want: &models.ChecksumConfig{Algorithm: "sum16-multi", Regions: []models.ChecksumRegion{{Start: 0, End: 0x1000}, {Start: 0x2000, End: 0x3000}}, Store: 0x7FFE}},
+		{spec: "sum16", err: "expected algorithm:start-end@store"},
+		{spec: "sum16:0x0000-0x7FFD", err: "expected algorithm:start-end@store"},
+		{spec: "sum16:0x0000-0x7FFD@top", err: "invalid checksum store"},
+		{spec: "sum16:0x7FFD@0x7FFE", err: "expected start-end"},
+		{spec: "sum16:start-0x7FFD@0x7FFE", err: `invalid checksum region "start-0x7FFD"`},
+		{spec: "sum16:0x0000-0x7FFD,0x10-@0x7FFE", err: `invalid checksum region "0x10-"`},
+		{spec: "crc32:0x0000-0x7FFD@0x7FFE", err: `unknown checksum algorithm "crc32" (known: sum16, sum8-complement, sum16-multi)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSpec(tt.spec)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ParseSpec = %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSpec = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	const size = 0x100
+	region := func(start, end int64) []models.ChecksumRegion {
+		return []models.ChecksumRegion{{Start: start, End: end}}
+	}
+	tests := []struct {
+		name string
+		cfg  models.ChecksumConfig
+		err  error
+	}{
+		{name: "sum16", cfg: models.ChecksumConfig{Algorithm: "sum16", Regions: region(0, 0xFE), Store: 0xFE}},
+		{name: "sum8 in the last byte", cfg: models.ChecksumConfig{Algorithm: "sum8-complement", Regions: region(0, size), Store: 0xFF}},
+		{name: "multi", cfg: models.ChecksumConfig{Algorithm: "sum16-multi",
+			Regions: []models.ChecksumRegion{{Start: 0, End: 0x40}, {Start: 0x80, End: 0xC0}}, Store: 0}},
+		{name: "unknown algorithm", cfg: models.ChecksumConfig{Algorithm: "crc32", Regions: region(0, 0x10)}, err: reader.ErrInvalidDefinition},
+		{name: "no regions", cfg: models.ChecksumConfig{Algorithm: "sum16"}, err: reader.ErrInvalidDefinition},
+		{name: "several regions for one", cfg: models.ChecksumConfig{Algorithm: "sum16",
+			Regions: []models.ChecksumRegion{{Start: 0, End: 0x40}, {Start: 0x80, End: 0xC0}}}, err: reader.ErrInvalidDefinition},
+		{name: "region past the image", cfg: models.ChecksumConfig{Algorithm: "sum16", Regions: region(0, size+1)}, err: reader.ErrOutOfRange},
+		{name: "empty region", cfg: models.ChecksumConfig{Algorithm: "sum16", Regions: region(0x10, 0x10)}, err: reader.ErrOutOfRange},
+		{name: "negative region", cfg: models.ChecksumConfig{Algorithm: "sum16", Regions: region(-1, 0x10)}, err: reader.ErrOutOfRange},
+		{name: "store across the end", cfg: models.ChecksumConfig{Algorithm: "sum16", Regions: region(0, 0x10), Store: 0xFF}, err: reader.ErrOutOfRange},
+		{name: "negative store", cfg: models.ChecksumConfig{Algorithm: "sum8-complement", Regions: region(0, 0x10), Store: -1}, err: reader.ErrOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.cfg, size)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Check = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// testImage returns 0x100 bytes counting up from 1 (the last wraps to 0),
+// behind a base-byte header of 0xAA
+func testImage(base int) []byte {
+	data := make([]byte, base+0x100)
+	for i := range base {
+		data[i] = 0xAA
+	}
+	for i := range 0x100 {
+		data[base+i] = byte(i + 1)
+	}
+	return data
+}
+
+func TestVerifyAndFix(t *testing.T) {
+	// Bytes 1..0x10 add up to 0x88, bytes 0x21..0x30 to 0x288
+	tests := []struct {
+		name     string
+		cfg      models.ChecksumConfig
+		order    models.Endianness
+		base     int64
+		stored   int64
+		computed int64
+	}{
+		{name: "sum16", cfg: models.ChecksumConfig{Algorithm: "sum16", Regions: []models.ChecksumRegion{{Start: 0, End: 0x10}}, Store: 0xFE},
+			stored: 0x00FF, computed: 0x88},
+		{name: "sum16 big-endian behind a header", cfg: models.ChecksumConfig{Algorithm: "sum16", Regions: []models.ChecksumRegion{{Start: 0, End: 0x10}}, Store: 0x40},
+			order: models.BigEndian, base: 16, stored: 0x4142, computed: 0x88},
+		// The stored bytes inside the region read as zero
+		{name: "sum16 storing inside its region", cfg: models.ChecksumConfig{Algorithm: "sum16", Regions: []models.ChecksumRegion{{Start: 0, End: 0x12}}, Store: 0x10},
+			stored: 0x1211, computed: 0x88},
+		{name: "sum8-complement", cfg: models.ChecksumConfig{Algorithm: "sum8-complement", Regions: []models.ChecksumRegion{{Start: 0, End: 0x11}}, Store: 0x10},
+			stored: 0x11, computed: 0x78},
+		{name: "sum16-multi", cfg: models.ChecksumConfig{Algorithm: "sum16-multi",
+			Regions: []models.ChecksumRegion{{Start: 0, End: 0x10}, {Start: 0x20, End: 0x30}}, Store: 0x80},
+			stored: 0x8281, computed: 0x88 + 0x288},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testImage(int(tt.base))
+			profile := models.IDProfile{Name: "test", ImageSize: 0x100, Endianness: tt.order, Checksum: &tt.cfg}
+			result, err := Verify(data, tt.base, profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Stored != tt.stored || result.Computed != tt.computed || result.OK() {
+				t.Fatalf("stored 0x%X, computed 0x%X; want 0x%X and 0x%X", result.Stored, result.Computed, tt.stored, tt.computed)
+			}
+
+			changes, _, err := PlanFix(data, tt.base, profile)
+			if err != nil || len(changes) != 1 {
+				t.Fatalf("PlanFix = %v, %v", changes, err)
+			}
+			changes[0].Apply(data)
+			if result, err = Verify(data, tt.base, profile); err != nil || !result.OK() {
+				t.Fatalf("after the fix: %+v, %v", result, err)
+			}
+			if changes, _, _ := PlanFix(data, tt.base, profile); changes != nil {
+				t.Errorf("a matching checksum plans %v", changes)
+			}
+			if tt.cfg.Algorithm == "sum8-complement" {
+				var sum byte
+				for _, b := range data[tt.base : tt.base+0x11] {
+					sum += b
+				}
+				if sum != 0 {
+					t.Errorf("region sums to 0x%02X with its checksum, want 0", sum)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	cfg := &models.ChecksumConfig{Algorithm: "sum16", Regions: []models.ChecksumRegion{{Start: 0, End: 0x10}}, Store: 0xFE}
+	tests := []struct {
+		name    string
+		profile models.IDProfile
+		base    int64
+		err     error
+	}{
+		{name: "no checksum", profile: models.IDProfile{Name: "test", ImageSize: 0x100}, err: reader.ErrNotFound},
+		{name: "invalid configuration", profile: models.IDProfile{Name: "test", ImageSize: 0x10, Checksum: cfg}, err: reader.ErrOutOfRange},
+		{name: "image past the file", profile: models.IDProfile{Name: "test", ImageSize: 0x100, Checksum: cfg}, base: 1, err: reader.ErrOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(testImage(0), tt.base, tt.profile); !errors.Is(err, tt.err) {
+				t.Errorf("Verify = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// UseSpec installs a valid spec as the M2.1 checksum and leaves it alone
+// otherwise
+func TestUseSpec(t *testing.T) {
+	saved := models.M21IDProfile.Checksum
+	t.Cleanup(func() { models.M21IDProfile.Checksum = saved })
+	models.M21IDProfile.Checksum = nil
+
+	for _, spec := range []string{"sum16:0x0000-0xFFFF@0x7FFE", "sum16:0x0000-0x7FFD@0x7FFF", "sum16:0x0000@0x7FFE"} {
+		if err := UseSpec(spec); err == nil {
+			t.Errorf("UseSpec(%q) succeeded", spec)
+		}
+	}
+	if models.M21IDProfile.Checksum != nil {
+		t.Fatalf("an invalid spec set %+v", models.M21IDProfile.Checksum)
+	}
+	if err := UseSpec("sum16:0x0000-0x7FFD@0x7FFE"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := models.M21IDProfile.Checksum; cfg == nil || cfg.Algorithm != "sum16" || cfg.Store != 0x7FFE {
+		t.Errorf("UseSpec set %+v", cfg)
+	}
+}
+
+// FormatRegions prints regions the way ParseSpec reads them back
+func TestFormatRegions(t *testing.T) {
+	regions := []models.ChecksumRegion{{Start: 0, End: 0x1000}, {Start: 0x2000, End: 0x3000}}
+	text := FormatRegions(regions)
+	if text != "0x0000-0x0FFF, 0x2000-0x2FFF" {
+		t.Errorf("FormatRegions = %q", text)
+	}
+	cfg, err := ParseSpec("sum16-multi:" + text + "@0")
+	if err != nil || !reflect.DeepEqual(cfg.Regions, regions) {
+		t.Errorf("ParseSpec read %v back, %v", cfg, err)
+	}
+}
@@ -0,0 +1,324 @@
+// Package ci verifies every ECU binary in a directory for use in
+// continuous integration on tune repositories. Each check reuses the
+// reader, identification, validation and sidecar code the rest of the
+// tool uses.
+package ci
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/progress"
+	"github.com/tosih/motronic-m21-tool/pkg/checksum"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// Check outcomes
+const (
+	Pass = "pass"
+	Fail = "fail"
+	// Skip marks a check with nothing to verify, e.g. no sidecar file
+	Skip = "skip"
+)
+
+// CheckResult is the outcome of one check on one file
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// FileResult holds every check of one file
+type FileResult struct {
+	File   string        `json:"file"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Passed reports whether no check failed
+func (f FileResult) Passed() bool {
+	for _, c := range f.Checks {
+		if c.Status == Fail {
+			return false
+		}
+	}
+	return true
+}
+
+// Result holds the results of a run, sorted by file
+type Result struct {
+	Dir   string       `json:"dir"`
+	Files []FileResult `json:"files"`
+}
+
+// Passed reports whether every file passed
+func (r *Result) Passed() bool {
+	for _, f := range r.Files {
+		if !f.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// checkNames lists the checks in the order they are reported
+var checkNames = []string{"size", "identity", "checksum", "maps", "validation", "sidecar"}
+
+// Run checks every .bin file in dir concurrently
+func Run(dir string) (*Result, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".bin") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	result := &Result{Dir: dir, Files: make([]FileResult, len(files))}
+	bar := progress.Start("Checking files", len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.NumCPU(), len(files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result.Files[i] = CheckFile(files[i])
+				bar.Step(filepath.Base(files[i]))
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	bar.Stop()
+
+	sort.Slice(result.Files, func(i, j int) bool { return result.Files[i].File < result.Files[j].File })
+	return result, nil
+}
+
+// CheckFile runs every check on one file. Later checks that need the
+// contents are failed too if the file can't be read.
+func CheckFile(filename string) FileResult {
+	fr := FileResult{File: filename}
+	add := func(name, status, format string, args ...interface{}) {
+		fr.Checks = append(fr.Checks, CheckResult{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	}
+
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		for _, name := range checkNames {
+			add(name, Fail, "%v", err)
+		}
+		return fr
+	}
+
+	// Size: one image, or a dump of whole images after a reader header
+	imageSize := models.M21IDProfile.ImageSize
+	layout := reader.DetectLayout(data)
+	if images := int64(len(data)) - layout.Header; images <= 0 || images%imageSize != 0 {
+		add("size", Fail, "%s is not a multiple of the %s image size", reader.FormatSize(int64(len(data))), reader.FormatSize(imageSize))
+	} else {
+		detail := reader.FormatSize(int64(len(data)))
+		if layout.Header > 0 {
+			detail += fmt.Sprintf(" with a %d-byte header", layout.Header)
+		}
+		if layout.Mirrored() {
+			detail += fmt.Sprintf(", mirrored: the image repeated %d times", layout.Copies)
+		}
+		add("size", Pass, "%s", detail)
+	}
+
+	id := reader.IdentifyData(data, models.M21IDProfile)
+	if label := id.Label(); label != "" {
+		add("identity", Pass, "%s", label)
+	} else {
+		add("identity", Fail, "no part, hardware or software number recognized")
+	}
+
+	// Without a checksum in the profile (none is documented for M2.1 yet),
+	// report it rather than passing silently
+	if models.M21IDProfile.Checksum == nil {
+		add("checksum", Skip, "no checksum algorithm defined for %s", models.M21IDProfile.Name)
+	} else if result, err := checksum.Verify(data, id.BaseOffset, models.M21IDProfile); err != nil {
+		add("checksum", Fail, "%v", err)
+	} else if result.OK() {
+		add("checksum", Pass, "%s over %s matches (0x%X)", result.Algorithm.Name, checksum.FormatRegions(result.Regions), result.Stored)
+	} else {
+		add("checksum", Fail, "%s over %s: stored 0x%X, computed 0x%X", result.Algorithm.Name, checksum.FormatRegions(result.Regions), result.Stored, result.Computed)
+	}
+
+	var mapErrs []string
+	for _, cfg := range models.MapConfigs {
+		if _, err := reader.ReadMapFromBytes(data, layout.LocateMap(cfg)); err != nil {
+			mapErrs = append(mapErrs, err.Error())
+		}
+	}
+	if len(mapErrs) > 0 {
+		add("maps", Fail, "%s", strings.Join(mapErrs, "; "))
+	} else {
+		add("maps", Pass, "%d maps readable", len(models.MapConfigs))
+	}
+
+	fr.Checks = append(fr.Checks, validate(data, layout), checkSidecar(filename, id.SHA256))
+	return fr
+}
+
+// validate checks the configuration parameters, in the image of a file
+// with the given layout, against their allowed ranges and links
+func validate(data []byte, layout reader.Layout) CheckResult {
+	config := reader.ReadParamsFromBytes(data, layout.LocateParams(models.ConfigParams))
+	var problems []string
+	for _, p := range models.ConfigParams {
+		value, ok := config.Values[p.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s unreadable", p.Name))
+			continue
+		}
+		if value < p.MinValue || value > p.MaxValue {
+			problems = append(problems, fmt.Sprintf("%s %.2f outside %.2f-%.2f %s", p.Name, value, p.MinValue, p.MaxValue, p.Unit))
+		}
+	}
+	if err := models.CheckLinks(config.Values); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return CheckResult{Name: "validation", Status: Fail, Message: strings.Join(problems, "; ")}
+	}
+	return CheckResult{Name: "validation", Status: Pass, Message: fmt.Sprintf("%d parameters in range", len(models.ConfigParams))}
+}
+
+// checkSidecar compares the file hash with the hash recorded by the most
+// recent attachment in its sidecar metadata
+func checkSidecar(filename, hash string) CheckResult {
+	result := func(status, format string, args ...interface{}) CheckResult {
+		return CheckResult{Name: "sidecar", Status: status, Message: fmt.Sprintf(format, args...)}
+	}
+	if _, err := os.Stat(editor.SidecarPath(filename)); err != nil {
+		return result(Skip, "no sidecar metadata")
+	}
+	sidecar, err := editor.LoadSidecar(filename)
+	if err != nil {
+		return result(Fail, "%v", err)
+	}
+	if len(sidecar.Attachments) == 0 {
+		return result(Skip, "no hash recorded")
+	}
+	latest := sidecar.Attachments[0]
+	for _, a := range sidecar.Attachments[1:] {
+		if a.Added.After(latest.Added) {
+			latest = a
+		}
+	}
+	if latest.BinSHA256 != hash {
+		return result(Fail, "file changed since %s was attached", filepath.Base(latest.Path))
+	}
+	return result(Pass, "matches %s", filepath.Base(latest.Path))
+}
+
+// Print renders one row per file with the status of each check, then the
+// messages of failed checks
+func (r *Result) Print() {
+	header := append([]string{"File"}, checkNames...)
+	tableData := pterm.TableData{append(header, "Result")}
+	var failures []string
+	for _, f := range r.Files {
+		row := []string{filepath.Base(f.File)}
+		for _, c := range f.Checks {
+			row = append(row, c.Status)
+			if c.Status == Fail {
+				failures = append(failures, fmt.Sprintf("%s %s: %s", filepath.Base(f.File), c.Name, c.Message))
+			}
+		}
+		if f.Passed() {
+			row = append(row, pterm.Green("PASS"))
+		} else {
+			row = append(row, pterm.Red("FAIL"))
+		}
+		tableData = append(tableData, row)
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	for _, msg := range failures {
+		pterm.Error.Println(msg)
+	}
+}
+
+// WriteJSON writes the result as indented JSON
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// JUnit XML elements, one test suite per file and one test case per check
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the result as JUnit XML for CI test reporters
+func (r *Result) WriteJUnit(w io.Writer) error {
+	var doc junitSuites
+	for _, f := range r.Files {
+		suite := junitSuite{Name: filepath.Base(f.File), Tests: len(f.Checks)}
+		for _, c := range f.Checks {
+			tc := junitCase{Name: c.Name, ClassName: suite.Name}
+			switch c.Status {
+			case Fail:
+				tc.Failure = &junitMessage{Message: c.Message}
+				suite.Failures++
+			case Skip:
+				tc.Skipped = &junitMessage{Message: c.Message}
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
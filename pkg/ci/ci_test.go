@@ -0,0 +1,126 @@
+package ci
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+)
+
+// binDir writes the synthetic image as good.bin to a new directory, and a
+// truncated one as bad.bin if bad is set
+func binDir(t *testing.T, bad bool) string {
+	t.Helper()
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.bin"), testbin.Image(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if bad {
+		if err := os.WriteFile(filepath.Join(dir, "bad.bin"), testbin.Image()[:0x1000], 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCommandExitCode(t *testing.T) {
+	passing, failing := binDir(t, false), binDir(t, true)
+	empty := t.TempDir()
+	tests := []struct {
+		name       string
+		args       []string
+		defaultDir string
+		want       int
+	}{
+		{name: "all pass", args: []string{passing}, want: ExitPassed},
+		{name: "default directory", defaultDir: passing, want: ExitPassed},
+		{name: "a file fails", args: []string{failing}, want: ExitFailed},
+		{name: "no binaries", args: []string{empty}, want: ExitError},
+		{name: "missing directory", args: []string{filepath.Join(empty, "missing")}, want: ExitError},
+		{name: "no directory", want: ExitError},
+		{name: "two directories", args: []string{passing, failing}, want: ExitError},
+		{name: "unknown flag", args: []string{"-report", "x.xml", passing}, want: ExitError},
+		{name: "unwritable result", args: []string{"-out", filepath.Join(empty, "missing", "r.json"), passing}, want: ExitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Command(tt.args, tt.defaultDir); got != tt.want {
+				t.Errorf("Command(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandJSON(t *testing.T) {
+	dir := binDir(t, true)
+	out := filepath.Join(t.TempDir(), "results.json")
+	if code := Command([]string{"-out", out, dir}, ""); code != ExitFailed {
+		t.Fatalf("exit code %d, want %d", code, ExitFailed)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Dir != dir || len(result.Files) != 2 {
+		t.Fatalf("result of %s with %d files, want %s with 2", result.Dir, len(result.Files), dir)
+	}
+	// Sorted by file: bad.bin first
+	bad, good := result.Files[0], result.Files[1]
+	if filepath.Base(bad.File) != "bad.bin" || bad.Passed() {
+		t.Errorf("%s passed: %+v", bad.File, bad.Checks)
+	}
+	if filepath.Base(good.File) != "good.bin" || !good.Passed() {
+		t.Errorf("%s failed: %+v", good.File, good.Checks)
+	}
+	for _, f := range result.Files {
+		if len(f.Checks) != len(checkNames) {
+			t.Errorf("%s has %d checks, want %d", f.File, len(f.Checks), len(checkNames))
+		}
+	}
+}
+
+func TestCommandJUnit(t *testing.T) {
+	dir := binDir(t, true)
+	out := filepath.Join(t.TempDir(), "results.xml")
+	if code := Command([]string{"-out", out, dir}, ""); code != ExitFailed {
+		t.Fatalf("exit code %d, want %d", code, ExitFailed)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc junitSuites
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Suites) != 2 {
+		t.Fatalf("%d test suites, want 2", len(doc.Suites))
+	}
+	bad, good := doc.Suites[0], doc.Suites[1]
+	if bad.Name != "bad.bin" || bad.Failures == 0 || bad.Tests != len(checkNames) {
+		t.Errorf("bad.bin suite %s: %d tests, %d failures", bad.Name, bad.Tests, bad.Failures)
+	}
+	if good.Name != "good.bin" || good.Failures != 0 {
+		t.Errorf("good.bin suite %s: %d failures", good.Name, good.Failures)
+	}
+	// Checks with nothing to verify are skipped, not passed
+	for _, c := range good.Cases {
+		if c.Name == "checksum" && c.Skipped == nil {
+			t.Error("checksum without an algorithm isn't skipped")
+		}
+		if c.Failure != nil {
+			t.Errorf("%s failed: %s", c.Name, c.Failure.Message)
+		}
+	}
+	if good.Skipped == 0 {
+		t.Error("good.bin suite counts no skipped checks")
+	}
+}
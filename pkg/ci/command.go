@@ -0,0 +1,95 @@
+package ci
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Exit codes of Command
+const (
+	ExitPassed = 0
+	ExitFailed = 1
+	// ExitError means the checks couldn't run: bad arguments, no binaries
+	// or a result file that couldn't be written
+	ExitError = 2
+)
+
+// Command runs the `ci [-out file] [dir]` subcommand with args, the
+// arguments after "ci". It checks every binary in dir, or in defaultDir
+// if none is given, prints one row per file and writes the result to
+// -out, as JUnit XML for a .xml name and as JSON otherwise. It returns
+// the exit code.
+func Command(args []string, defaultDir string) int {
+	fs := flag.NewFlagSet("ci", flag.ContinueOnError)
+	out := fs.String("out", "", "Write the result to a .xml (JUnit) or .json file")
+	fs.Usage = func() {
+		pterm.Println("Usage: ci [-out results.xml|results.json] [dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitPassed
+		}
+		return ExitError
+	}
+	if fs.NArg() > 1 {
+		pterm.Error.Printf("ci checks one directory, got %s\n", strings.Join(fs.Args(), " "))
+		return ExitError
+	}
+	dir := fs.Arg(0)
+	if dir == "" {
+		dir = defaultDir
+	}
+	if dir == "" {
+		pterm.Error.Println("No binary directory found; pass a directory or -bins")
+		return ExitError
+	}
+
+	result, err := Run(dir)
+	if err != nil {
+		pterm.Error.Println(err)
+		return ExitError
+	}
+	if len(result.Files) == 0 {
+		pterm.Error.Printf("No .bin files found in %s\n", dir)
+		return ExitError
+	}
+	result.Print()
+	if *out != "" {
+		if err := result.WriteFile(*out); err != nil {
+			pterm.Error.Printf("Failed to write results: %v\n", err)
+			return ExitError
+		}
+		pterm.Success.Printf("Results written to %s\n", *out)
+	}
+
+	if !result.Passed() {
+		pterm.Error.Printf("CI checks failed in %s\n", dir)
+		return ExitFailed
+	}
+	pterm.Success.Printf("All %d files passed\n", len(result.Files))
+	return ExitPassed
+}
+
+// WriteFile writes the result to path, as JUnit XML for a .xml name and
+// as JSON otherwise
+func (r *Result) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := r.WriteJSON
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		write = r.WriteJUnit
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
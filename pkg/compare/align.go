@@ -0,0 +1,137 @@
+package compare
+
+import (
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// Alignment describes how the maps of two compared files line up. Map
+// offsets are translated by each file's base offset from identification,
+// so a 32KB image can be compared against a 64KB dump holding it in its
+// upper bank. A mirrored dump is compared as its first copy.
+type Alignment struct {
+	// Size1 and Size2 are the file sizes, up to the end of the first copy
+	// of a mirrored dump (reader.Layout.Extent)
+	Size1, Size2 int64
+	Base1, Base2 int64
+	// Copies1 and Copies2 are how often a mirrored dump holds the image,
+	// 1 for other files
+	Copies1, Copies2 int
+	// Defs1 and Defs2 are the definitions fingerprints each file was last
+	// saved with (see models.Provenance), or the active ones for files
+	// without provenance
+	Defs1, Defs2 string
+}
+
+// Align identifies both files and returns their sizes and base offsets
+func Align(file1, file2 string) (*Alignment, error) {
+	id1, err := reader.IdentifyBinary(file1)
+	if err != nil {
+		return nil, err
+	}
+	id2, err := reader.IdentifyBinary(file2)
+	if err != nil {
+		return nil, err
+	}
+	layout1, err := reader.FileLayout(file1)
+	if err != nil {
+		return nil, err
+	}
+	layout2, err := reader.FileLayout(file2)
+	if err != nil {
+		return nil, err
+	}
+	return &Alignment{
+		Size1: layout1.Extent(id1.Size), Size2: layout2.Extent(id2.Size),
+		Base1: id1.BaseOffset, Base2: id2.BaseOffset,
+		Copies1: layout1.Copies, Copies2: layout2.Copies,
+		Defs1: savedDefinitions(id1), Defs2: savedDefinitions(id2),
+	}, nil
+}
+
+// savedDefinitions returns the definitions fingerprint a file was last
+// saved with, or the active one if the tool never saved it
+func savedDefinitions(id *models.BinaryIdentity) string {
+	if id.Provenance != nil && id.Provenance.Definitions != "" {
+		return id.Provenance.Definitions
+	}
+	return models.DefinitionsFingerprint()
+}
+
+// DefinitionsDiffer reports whether the files were saved with different
+// map definitions, e.g. before and after a scale was revised. Scaled
+// values then differ by the revision, not only by real edits.
+func (a *Alignment) DefinitionsDiffer() bool {
+	return a.Defs1 != a.Defs2
+}
+
+// Shifts returns how far the active definitions, which address the image
+// at models.BaseOffset, move to address the image of each file
+func (a *Alignment) Shifts() (int64, int64) {
+	return a.Base1 - models.BaseOffset, a.Base2 - models.BaseOffset
+}
+
+// Locate returns the map definition translated to each file's base offset
+func (a *Alignment) Locate(cfg models.MapConfig) (models.MapConfig, models.MapConfig) {
+	shift1, shift2 := a.Shifts()
+	return cfg.Relocate(shift1), cfg.Relocate(shift2)
+}
+
+// Identical reports whether the map's content hashes are equal in both
+// files. Errors count as not identical, leaving the caller to do a full
+// diff and report them.
+func (a *Alignment) Identical(file1, file2 string, cfg models.MapConfig) bool {
+	// Hashes take offsets into the image, so a tune hashes the same in
+	// any layout
+	image := cfg.Relocate(-models.BaseOffset)
+	h1, err := reader.MapHashCached(file1, image, a.Base1)
+	if err != nil {
+		return false
+	}
+	h2, err := reader.MapHashCached(file2, image, a.Base2)
+	return err == nil && h1 == h2
+}
+
+// SkipReason returns why a map cannot be compared, such as "out of range
+// in file2", or "" if both files hold the whole map
+func (a *Alignment) SkipReason(cfg models.MapConfig) string {
+	shift1, shift2 := a.Shifts()
+	out1 := shift1+cfg.Offset+cfg.ByteSize() > a.Size1
+	out2 := shift2+cfg.Offset+cfg.ByteSize() > a.Size2
+	switch {
+	case out1 && out2:
+		return "out of range in both files"
+	case out1:
+		return "out of range in file1"
+	case out2:
+		return "out of range in file2"
+	}
+	return ""
+}
+
+// Print reports mirrored dumps, differing file sizes and any offset
+// translation
+func (a *Alignment) Print() {
+	for i, copies := range []int{a.Copies1, a.Copies2} {
+		if copies > 1 {
+			pterm.Info.Printf("file%d: mirrored dump, the image repeated %d times; the first copy is compared\n", i+1, copies)
+		}
+	}
+	if a.Size1 != a.Size2 {
+		pterm.Warning.Printf("Files differ in length: %s vs %s\n", reader.FormatSize(a.Size1), reader.FormatSize(a.Size2))
+	}
+	for i, base := range []int64{a.Base1, a.Base2} {
+		if base != 0 {
+			pterm.Info.Printf("file%d: image found at 0x%X, map offsets translated\n", i+1, base)
+		}
+	}
+}
+
+// PrintDefinitions warns when the files were saved with different map
+// definitions, suggesting a raw compare. Raw compares don't need it.
+func (a *Alignment) PrintDefinitions() {
+	if a.DefinitionsDiffer() {
+		pterm.Warning.Printf("file1 was saved with definitions %s and file2 with %s; revised scales show up as changes, -compare-raw compares raw values instead\n", a.Defs1, a.Defs2)
+	}
+}
@@ -0,0 +1,225 @@
+package compare
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// Blame is the changelog entry that wrote a changed cell's current value.
+// A cell is unattributed when the latest logged write to it left a
+// different value, or no write was logged at all: its bytes were changed
+// outside this tool.
+type Blame struct {
+	Unattributed bool `json:"unattributed,omitempty"`
+	// File is the file whose changelog has the entry: a compared file,
+	// or the original it was saved from
+	File   string    `json:"file,omitempty"`
+	Time   time.Time `json:"time,omitzero"`
+	Action string    `json:"action,omitempty"`
+	// Detail is the entry's note: the operation or preset name of an
+	// edit, the source of an inject or the snapshot of a restore
+	Detail string `json:"detail,omitempty"`
+}
+
+// String describes the entry as "2006-01-02 15:04 detail (file)", or
+// says the cell is unattributed
+func (b *Blame) String() string {
+	if b.Unattributed {
+		return "unattributed"
+	}
+	detail := b.Detail
+	if detail == "" {
+		detail = b.Action
+	}
+	return fmt.Sprintf("%s %s (%s)", b.Time.Format("2006-01-02 15:04"), detail, filepath.Base(b.File))
+}
+
+// Blamer attributes the changed cells of two compared files to the
+// changelog entries of either file
+type Blamer struct {
+	files [2]blameFile
+}
+
+// blameFile is a file's current contents and the writes logged for it,
+// oldest first
+type blameFile struct {
+	data    []byte
+	entries []blameEntry
+}
+
+// blameEntry is a changelog entry and the file whose changelog has it
+type blameEntry struct {
+	editor.ChangelogEntry
+	file string
+}
+
+// NewBlamer reads both files and their changelogs. A file saved with
+// Save As continues the history of its original, when the original's
+// changelog is next to it.
+func NewBlamer(file1, file2 string) (*Blamer, error) {
+	b := &Blamer{}
+	for i, name := range []string{file1, file2} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := blameEntries(name, time.Time{}, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		b.files[i] = blameFile{data: data, entries: entries}
+	}
+	return b, nil
+}
+
+// blameEntries returns the changelog of filename up to until (all of it
+// if until is zero). A save-as entry is followed by the original's
+// entries up to the copy, which replace the copy's own earlier ones; the
+// save-as stays as the start of the history.
+func blameEntries(filename string, until time.Time, seen map[string]bool) ([]blameEntry, error) {
+	seen[filepath.Clean(filename)] = true
+	entries, err := editor.ReadChangelog(filename)
+	if err != nil {
+		return nil, err
+	}
+	var history []blameEntry
+	for _, e := range entries {
+		if !until.IsZero() && e.Time.After(until) {
+			break
+		}
+		if e.Action != "save-as" {
+			history = append(history, blameEntry{e, filename})
+			continue
+		}
+		history = []blameEntry{{e, filename}}
+		original := filepath.Join(filepath.Dir(filename), e.Detail)
+		if seen[filepath.Clean(original)] {
+			continue
+		}
+		earlier, err := blameEntries(original, e.Time, seen)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, earlier...)
+	}
+	return history, nil
+}
+
+// Cell attributes a changed cell at offset1 in the first file and offset2
+// in the second. When both files' changelogs explain the cell, the later
+// entry wins.
+func (b *Blamer) Cell(offset1, offset2 int64) *Blame {
+	b1, b2 := b.files[0].blame(offset1), b.files[1].blame(offset2)
+	switch {
+	case b1.Unattributed:
+		return b2
+	case b2.Unattributed:
+		return b1
+	case b2.Time.After(b1.Time):
+		return b2
+	}
+	return b1
+}
+
+// blame finds the latest logged write to the cell at offset. An edit
+// explains the cell only if the value it wrote is still there; an inject
+// or restore whose bytes cover it is taken as its source, since the
+// written bytes are not logged.
+func (f *blameFile) blame(offset int64) *Blame {
+	for _, e := range slices.Backward(f.entries) {
+		switch e.Action {
+		case "edit":
+			for _, c := range slices.Backward(e.Changes) {
+				if c.Offset != offset {
+					continue
+				}
+				end := offset + int64(models.DataTypeSize(c.DataType))
+				if end > int64(len(f.data)) || models.DecodeRawOrder(f.data[offset:], c.DataType, c.Endianness) != c.NewRaw {
+					return &Blame{Unattributed: true}
+				}
+				return e.attribute()
+			}
+		case "inject", "restore":
+			if offset >= e.Offset && offset < e.Offset+e.Length {
+				return e.attribute()
+			}
+		case "save-as":
+			// The copy's history starts here and no earlier entry was
+			// found for the cell
+			return &Blame{Unattributed: true}
+		}
+	}
+	return &Blame{Unattributed: true}
+}
+
+// attribute names the entry as the source of a cell
+func (e blameEntry) attribute() *Blame {
+	return &Blame{File: e.file, Time: e.Time, Action: e.Action, Detail: e.Detail}
+}
+
+// Blame attributes every changed cell of the result to the changelog
+// entry that wrote its current value, see Blamer
+func (r *Result) Blame() error {
+	b, err := NewBlamer(r.File1, r.File2)
+	if err != nil {
+		return err
+	}
+	for i := range r.Maps {
+		for j := range r.Maps[i].Cells {
+			c := &r.Maps[i].Cells[j]
+			c.Blame = b.Cell(c.Offset1, c.Offset2)
+		}
+	}
+	r.Blamed = true
+	return nil
+}
+
+// Unattributed counts the changed cells no changelog entry explains
+func (r *Result) Unattributed() (unattributed, total int) {
+	for _, m := range r.Maps {
+		for _, c := range m.Cells {
+			total++
+			if c.Blame != nil && c.Blame.Unattributed {
+				unattributed++
+			}
+		}
+	}
+	return unattributed, total
+}
+
+// PrintBlame lists the changed cells of every map with the changelog
+// entry that wrote them, after CompareFiles has printed the comparison
+func PrintBlame(r *Result) {
+	pterm.Println()
+	pterm.DefaultSection.Println("Changelog attribution")
+	for _, m := range r.Maps {
+		if len(m.Cells) == 0 {
+			continue
+		}
+		pterm.Println(pterm.Bold.Sprint(m.Name))
+		for _, c := range m.Cells {
+			line := fmt.Sprintf("  [%d,%d] %.2f → %.2f (%+.2f %s)  ", c.Row, c.Col, c.Value1, c.Value2, c.Delta(), m.Unit)
+			if c.Blame.Unattributed {
+				pterm.Println(line + pterm.FgYellow.Sprint("unattributed: changed outside this tool"))
+				continue
+			}
+			pterm.Println(line + c.Blame.String())
+		}
+	}
+	unattributed, total := r.Unattributed()
+	switch {
+	case total == 0:
+		pterm.Info.Println("No changed cells to attribute")
+	case unattributed > 0:
+		pterm.Warning.Printf("%d of %d changed cell(s) match no changelog entry\n", unattributed, total)
+	default:
+		pterm.Success.Printf("All %d changed cell(s) match a changelog entry\n", total)
+	}
+}
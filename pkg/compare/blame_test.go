@@ -0,0 +1,159 @@
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+)
+
+// at is the time of the n-th logged write in the blame tests
+func at(n int) time.Time {
+	return time.Date(2026, 3, 1, 12, n, 0, 0, time.UTC)
+}
+
+// edit is a changelog edit entry writing each raw value to its uint8 cell
+func edit(n int, detail string, writes ...[2]int64) editor.ChangelogEntry {
+	e := editor.ChangelogEntry{Time: at(n), Action: "edit", Detail: detail}
+	for _, w := range writes {
+		e.Changes = append(e.Changes, editor.CellChange{Offset: w[0], DataType: "uint8", NewRaw: w[1]})
+	}
+	return e
+}
+
+// writeLogged writes data to name with the given changelog
+func writeLogged(t *testing.T, name string, data []byte, entries ...editor.ChangelogEntry) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := editor.AppendChangelog(name, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// A cell written several times is attributed to the latest write, and
+// only if that write's value is still there: an earlier write that left
+// the same value doesn't explain a cell a later write changed again
+func TestBlameOverwriteChains(t *testing.T) {
+	dir := t.TempDir()
+	stock, tuned := filepath.Join(dir, "stock.bin"), filepath.Join(dir, "tuned.bin")
+	writeLogged(t, stock, make([]byte, 16))
+
+	data := make([]byte, 16)
+	data[0] = 40 // written three times, the last value kept
+	data[1] = 20 // written twice, then put back to the first value by hand
+	data[2] = 30 // written twice within one entry
+	data[3] = 7  // edited, then covered by an inject
+	data[4] = 9  // injected, then edited
+	data[5] = 5  // never logged
+	data[6] = 60 // edited, then overwritten by hand
+	writeLogged(t, tuned, data,
+		edit(1, "first", [2]int64{0, 20}, [2]int64{1, 20}, [2]int64{6, 60}),
+		edit(2, "second", [2]int64{0, 30}, [2]int64{1, 30}),
+		edit(3, "third", [2]int64{0, 40}),
+		edit(4, "smooth", [2]int64{2, 10}, [2]int64{2, 30}),
+		edit(5, "before inject", [2]int64{3, 1}),
+		editor.ChangelogEntry{Time: at(6), Action: "inject", Detail: "donor.bin", Offset: 3, Length: 2},
+		edit(7, "after inject", [2]int64{4, 9}),
+	)
+	// The hand edit of cell 6 is only known by its value
+	data[6] = 61
+	if err := os.WriteFile(tuned, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewBlamer(stock, tuned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		offset int64
+		want   string // the entry's detail, or "" for unattributed
+		when   int
+	}{
+		{0, "third", 3},
+		{1, "", 0},
+		{2, "smooth", 4},
+		{3, "donor.bin", 6},
+		{4, "after inject", 7},
+		{5, "", 0},
+		{6, "", 0},
+	}
+	for _, tt := range tests {
+		got := b.Cell(tt.offset, tt.offset)
+		if tt.want == "" {
+			if !got.Unattributed {
+				t.Errorf("cell %d blamed on %s, want unattributed", tt.offset, got)
+			}
+			continue
+		}
+		if got.Unattributed || got.Detail != tt.want || !got.Time.Equal(at(tt.when)) || got.File != tuned {
+			t.Errorf("cell %d blamed on %+v, want %q at %v", tt.offset, got, tt.want, at(tt.when))
+		}
+	}
+}
+
+// When both files' changelogs explain a cell the later entry wins, and a
+// copy made with Save As inherits the original's writes only up to the
+// copy
+func TestBlameAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	original, copied := filepath.Join(dir, "original.bin"), filepath.Join(dir, "copy.bin")
+
+	data1 := make([]byte, 8)
+	data1[0], data1[1], data1[2] = 10, 20, 50
+	writeLogged(t, original, data1,
+		edit(1, "original early", [2]int64{0, 10}, [2]int64{2, 50}),
+		edit(4, "original late", [2]int64{1, 20}),
+		edit(6, "original after the copy", [2]int64{2, 50}),
+	)
+	data2 := make([]byte, 8)
+	data2[0], data2[1], data2[2] = 10, 30, 50
+	// The copy's own edit before the save-as is replaced by the
+	// original's history
+	writeLogged(t, copied, data2,
+		edit(3, "copy before save-as", [2]int64{3, 0}),
+		editor.ChangelogEntry{Time: at(5), Action: "save-as", Detail: "original.bin"},
+		edit(7, "copy", [2]int64{1, 30}),
+	)
+
+	b, err := NewBlamer(original, copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		offset int64
+		want   string
+		file   string
+	}{
+		// Both histories have the early edit; the copy's is the
+		// original's own entry
+		{0, "original early", original},
+		// The copy's later edit beats the original's
+		{1, "copy", copied},
+		// The original's write after the copy is later than anything the
+		// copy logged for the cell
+		{2, "original after the copy", original},
+	}
+	for _, tt := range tests {
+		got := b.Cell(tt.offset, tt.offset)
+		if got.Unattributed || got.Detail != tt.want || got.File != tt.file {
+			t.Errorf("cell %d blamed on %+v, want %q in %s", tt.offset, got, tt.want, filepath.Base(tt.file))
+		}
+	}
+
+	// The copy alone doesn't see the original's write after the save-as
+	copyOnly := b.files[1].blame(2)
+	if copyOnly.Detail != "original early" {
+		t.Errorf("the copy blames cell 2 on %+v, want the edit before the save-as", copyOnly)
+	}
+	// nor its own edit from before the save-as
+	if got := b.files[1].blame(3); !got.Unattributed {
+		t.Errorf("the copy blames cell 3 on %+v, want unattributed", got)
+	}
+}
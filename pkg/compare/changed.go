@@ -0,0 +1,170 @@
+package compare
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/tabular"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// ChangedCellLimit is how many changed cells ChangeReport lists per map
+const ChangedCellLimit = 8
+
+// ParseSince resolves a -since value relative to now: "today" (local
+// midnight), "yesterday", a duration back from now such as "36h", or a
+// date such as "2026-10-14"
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -since %q: expected today, yesterday, a duration such as 36h or a date such as 2006-01-02", s)
+}
+
+// ChangeReport is what changed in a file since a point in time
+type ChangeReport struct {
+	File  string
+	Since time.Time
+	// Baseline is the backup the file was compared with, nil if no backup
+	// was taken since Since
+	Baseline *editor.Backup
+	// Result is the comparison of Baseline (file1) with File (file2), nil
+	// without a baseline
+	Result *Result
+}
+
+// ChangesSince compares filename with the first backup taken at or after
+// since. A backup holds the file as it was before the edit that made it,
+// so the first one in the window is the file as it stood at since. No
+// backup in the window means no edit was saved through this tool since
+// then; writes made with -no-backup are not seen.
+func ChangesSince(filename string, since time.Time, readMap func(string, models.MapConfig) (*models.ECUMap, error)) (*ChangeReport, error) {
+	backups, err := editor.ListBackups(filename)
+	if err != nil {
+		return nil, err
+	}
+	report := &ChangeReport{File: filename, Since: since}
+	for i := range backups {
+		if !backups[i].Time.Before(since) {
+			report.Baseline = &backups[i]
+			break
+		}
+	}
+	if report.Baseline == nil {
+		return report, nil
+	}
+	report.Result, err = Diff(report.Baseline.Path, filename, "all", -1, false, readMap)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ChangedMaps returns the summaries of the maps with changed cells
+func (r *ChangeReport) ChangedMaps() []MapSummary {
+	if r.Result == nil {
+		return nil
+	}
+	var changed []MapSummary
+	for _, m := range r.Result.Maps {
+		if m.Changed > 0 {
+			changed = append(changed, m)
+		}
+	}
+	return changed
+}
+
+// ChangedParams returns the parameters whose values changed, leaving out
+// ones neither file can hold
+func (r *ChangeReport) ChangedParams() []ParamDiff {
+	if r.Result == nil {
+		return nil
+	}
+	var changed []ParamDiff
+	for _, d := range r.Result.Params {
+		if d.Err != "out of range in both files" {
+			changed = append(changed, d)
+		}
+	}
+	return changed
+}
+
+// Changed reports whether any map or parameter changed
+func (r *ChangeReport) Changed() bool {
+	return len(r.ChangedMaps()) > 0 || len(r.ChangedParams()) > 0
+}
+
+// MapTable returns the changed maps in the columns of SummaryTable
+func (r *ChangeReport) MapTable() *tabular.Table {
+	return SummaryTable(&Result{Maps: r.ChangedMaps()})
+}
+
+// Heading describes what the report compares
+func (r *ChangeReport) Heading() string {
+	name := filepath.Base(r.File)
+	if r.Baseline == nil {
+		return fmt.Sprintf("%s: no backup since %s, so nothing was edited", name, r.Since.Format("2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("%s since %s, against backup %s", name, r.Since.Format("2006-01-02 15:04"), filepath.Base(r.Baseline.Path))
+}
+
+// Verdict is the closing line, e.g. "2 map(s) and 1 parameter(s) changed"
+func (r *ChangeReport) Verdict() string {
+	if !r.Changed() {
+		return "Nothing changed"
+	}
+	return fmt.Sprintf("%d map(s) and %d parameter(s) changed", len(r.ChangedMaps()), len(r.ChangedParams()))
+}
+
+// CellLines lists up to ChangedCellLimit changed cells of m as
+// "[row,col] old → new (delta)", with a count of the rest
+func CellLines(m MapSummary) []string {
+	var lines []string
+	for i, c := range m.Cells {
+		if i == ChangedCellLimit {
+			lines = append(lines, fmt.Sprintf("… and %d more", len(m.Cells)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("[%d,%d] %.2f → %.2f (%+.2f %s)", c.Row, c.Col, c.Value1, c.Value2, c.Delta(), m.Unit))
+	}
+	return lines
+}
+
+// Print shows the changed maps with their changed cells and the changed
+// parameters, in the tables of the compare command
+func (r *ChangeReport) Print() {
+	pterm.DefaultSection.Println(r.Heading())
+	if !r.Changed() {
+		pterm.Success.Println(r.Verdict())
+		return
+	}
+	if maps := r.ChangedMaps(); len(maps) > 0 {
+		r.MapTable().Render()
+		for _, m := range maps {
+			pterm.Println(pterm.Bold.Sprint(m.Name))
+			for _, line := range CellLines(m) {
+				pterm.Println("  " + line)
+			}
+		}
+	}
+	if params := r.ChangedParams(); len(params) > 0 {
+		pterm.Println()
+		PrintParamDiffs(params)
+	}
+	pterm.Println()
+	pterm.Info.Println(r.Verdict())
+}
@@ -1,47 +1,259 @@
 package compare
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/progress"
 	"github.com/tosih/motronic-m21-tool/pkg/models"
 )
 
-// CompareFiles compares maps between two ECU files
-func CompareFiles(file1, file2, mapType string, readMap func(string, models.MapConfig) (*models.ECUMap, error)) {
-	pterm.DefaultHeader.WithFullWidth().Println("ECU File Comparison")
-
-	var selectedConfigs []models.MapConfig
-	if mapType == "all" {
-		selectedConfigs = models.MapConfigs
-	} else {
-		for _, cfg := range models.MapConfigs {
-			if strings.Contains(strings.ToLower(cfg.Name), strings.ToLower(mapType)) {
-				selectedConfigs = append(selectedConfigs, cfg)
+// DefaultTolerance returns half of one raw step of the map in engineering
+// units, which absorbs rounding from export/import round trips
+func DefaultTolerance(cfg models.MapConfig) float64 {
+	if cfg.Formula != "" {
+		// A formula's steps vary; use half of the smallest one
+		lo, hi := models.RawRange(cfg.DataType)
+		smallest := math.Inf(1)
+		for raw := lo; raw < hi; raw++ {
+			if step := math.Abs(cfg.ToReal(raw+1) - cfg.ToReal(raw)); step > 0 {
+				smallest = min(smallest, step)
 			}
 		}
+		if math.IsInf(smallest, 1) {
+			return 0
+		}
+		return smallest / 2
+	}
+	if cfg.Conversion == models.ConversionInverse {
+		// Inverse steps shrink as raw grows; use half of the smallest one
+		_, hi := models.RawRange(cfg.DataType)
+		return math.Abs(cfg.Scale) / (2 * float64(hi) * float64(hi-1))
+	}
+	return math.Abs(cfg.Scale) / 2
+}
+
+// ToleranceFor resolves the tolerance to use for a map: a negative value
+// selects DefaultTolerance, zero compares strictly
+func ToleranceFor(cfg models.MapConfig, tolerance float64) float64 {
+	if tolerance < 0 {
+		return DefaultTolerance(cfg)
+	}
+	return tolerance
+}
+
+// DiffMaps returns data2 - data1 cell by cell. Differences whose magnitude
+// is within tolerance are reported as zero.
+func DiffMaps(data1, data2 [][]float64, tolerance float64) [][]float64 {
+	return compareMapData(data1, data2, tolerance)
+}
+
+// MapSummary is the outcome of comparing one map
+type MapSummary struct {
+	Name        string     `json:"name"`
+	Unit        string     `json:"unit"`
+	Skipped     string     `json:"skipped,omitempty"` // reason the map was not compared, "" if it was
+	Changed     int        `json:"changed"`
+	Total       int        `json:"total"`
+	AvgChange   float64    `json:"avg_change"`
+	MaxIncrease float64    `json:"max_increase"`
+	MaxDecrease float64    `json:"max_decrease"`
+	Cells       []CellDiff `json:"cells,omitempty"`
+	// Scales color both files and the difference map; nil if the map was
+	// skipped or its hashes matched
+	Scales *Scales `json:"scales,omitempty"`
+}
+
+// CellDiff is one changed cell. Offset1 and Offset2 are the absolute file
+// offsets of the cell in each file after base offset translation, so the
+// change can be checked in a hex editor; the cell spans Width bytes from
+// there. Blame is set by Result.Blame.
+type CellDiff struct {
+	Row     int     `json:"row"`
+	Col     int     `json:"col"`
+	Value1  float64 `json:"value1"`
+	Value2  float64 `json:"value2"`
+	Raw1    int64   `json:"raw1"`
+	Raw2    int64   `json:"raw2"`
+	Offset1 int64   `json:"offset1"`
+	Offset2 int64   `json:"offset2"`
+	Width   int     `json:"width"`
+	Blame   *Blame  `json:"blame,omitempty"`
+}
+
+// Delta returns Value2 - Value1
+func (c CellDiff) Delta() float64 { return c.Value2 - c.Value1 }
+
+// Result collects what CompareFiles found, for writing reports
+type Result struct {
+	File1 string `json:"file1"`
+	File2 string `json:"file2"`
+	// Raw is set when maps were compared as raw values (see RawConfig)
+	Raw bool `json:"raw,omitempty"`
+	// DefinitionsDiffer is set when the files were saved with different
+	// map definitions (see Alignment.DefinitionsDiffer)
+	DefinitionsDiffer bool `json:"definitions_differ,omitempty"`
+	// Blamed is set when the changed cells were attributed to changelog
+	// entries (see Result.Blame)
+	Blamed bool         `json:"blamed,omitempty"`
+	Maps   []MapSummary `json:"maps"`
+	Params []ParamDiff  `json:"params,omitempty"`
+}
+
+// CompareFiles compares maps between two ECU files. Cell differences within
+// tolerance count as unchanged; see ToleranceFor. With raw set, maps are
+// compared as unscaled raw values and the tolerance is in raw steps. When
+// all maps are compared, configuration parameters are compared too. It
+// returns nil if the files could not be identified.
+func CompareFiles(file1, file2, mapType string, tolerance float64, raw bool, readMap func(string, models.MapConfig) (*models.ECUMap, error)) *Result {
+	pterm.DefaultHeader.WithFullWidth().Println(i18n.T("cli.compare.header"))
+
+	align, err := Align(file1, file2)
+	if err != nil {
+		pterm.Error.Printf("Failed to identify files: %v\n", err)
+		return nil
+	}
+	align.Print()
+	if raw {
+		pterm.Info.Println("Comparing raw values: map scales and offsets are ignored")
+	} else {
+		align.PrintDefinitions()
 	}
 
-	for _, cfg := range selectedConfigs {
+	result := &Result{File1: file1, File2: file2, Raw: raw, DefinitionsDiffer: align.DefinitionsDiffer()}
+	// Compare every map before printing, so the progress bar doesn't
+	// interleave with the map sections
+	configs := selectConfigs(mapType)
+	comparisons := make([]mapComparison, len(configs))
+	bar := progress.Start("Comparing maps", len(configs))
+	for i, cfg := range configs {
+		comparisons[i] = compareMap(align, file1, file2, cfg, tolerance, raw, readMap)
+		bar.Step(cfg.Name)
+	}
+	bar.Stop()
+
+	var skipped []string
+	for i, cfg := range configs {
 		pterm.Println()
-		pterm.DefaultSection.Printf("Comparing: %s\n", cfg.Name)
+		pterm.DefaultSection.Print(i18n.T("cli.compare.section", cfg.Name))
 
-		map1, err1 := readMap(file1, cfg)
-		map2, err2 := readMap(file2, cfg)
+		c := comparisons[i]
+		result.Maps = append(result.Maps, c.summary)
+		switch {
+		case c.err != nil:
+			pterm.Error.Printf("Failed to read one or both maps: %v\n", c.err)
+			skipped = append(skipped, cfg.Name)
+		case c.summary.Skipped != "":
+			pterm.Warning.Printf("Skipped: %s\n", c.summary.Skipped)
+			skipped = append(skipped, cfg.Name)
+		case c.identical:
+			// Equal content hashes mean equal bytes; the cells were not decoded
+			pterm.Success.Println("Identical (map content hashes match)")
+		default:
+			displayComparison(c.map1, c.map2, c.diff, c.summary, c.cfg, c.tolerance)
+		}
+	}
 
-		if err1 != nil || err2 != nil {
-			pterm.Error.Println("Failed to read one or both maps")
-			continue
+	if mapType == "all" {
+		pterm.Println()
+		pterm.DefaultSection.Print(i18n.T("cli.compare.section", i18n.T("cli.compare.params")))
+		params, err := CompareParams(file1, file2, align)
+		if err != nil {
+			pterm.Error.Printf("Failed to compare parameters: %v\n", err)
+		} else {
+			PrintParamDiffs(params)
+			result.Params = params
 		}
+	}
+
+	if len(skipped) > 0 {
+		pterm.Println()
+		pterm.Warning.Printf("%d map(s) not compared: %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+	return result
+}
+
+// mapComparison is one map compared by compareMap
+type mapComparison struct {
+	summary MapSummary
+	// identical is set when the map's content hashes matched and the
+	// cells were not decoded
+	identical  bool
+	err        error
+	cfg        models.MapConfig
+	map1, map2 *models.ECUMap
+	diff       [][]float64
+	tolerance  float64
+}
 
-		// Calculate differences
-		differences := compareMapData(map1.Data, map2.Data)
-		displayComparison(map1, map2, differences, cfg)
+// compareMap compares one map of two aligned files without printing.
+// Maps outside the shorter file and unreadable maps get Skipped set in
+// their summary; unreadable ones also get err.
+func compareMap(align *Alignment, file1, file2 string, cfg models.MapConfig, tolerance float64, raw bool, readMap func(string, models.MapConfig) (*models.ECUMap, error)) mapComparison {
+	c := mapComparison{summary: MapSummary{Name: cfg.Name, Unit: cfg.Unit}}
+	if reason := align.SkipReason(cfg); reason != "" {
+		c.summary.Skipped = reason
+		return c
 	}
+	if align.Identical(file1, file2, cfg) {
+		c.identical = true
+		c.summary.Total = cfg.Rows * cfg.Cols
+		return c
+	}
+
+	if raw {
+		cfg = RawConfig(cfg)
+	}
+	cfg1, cfg2 := align.Locate(cfg)
+	map1, err1 := readMap(file1, cfg1)
+	map2, err2 := readMap(file2, cfg2)
+	if err1 != nil || err2 != nil {
+		c.err = errors.Join(err1, err2)
+		c.summary.Skipped = c.err.Error()
+		return c
+	}
+
+	// Calculate differences
+	c.cfg, c.map1, c.map2 = cfg, map1, map2
+	c.tolerance = ToleranceFor(cfg, tolerance)
+	c.diff = compareMapData(map1.Data, map2.Data, c.tolerance)
+	c.summary = summarize(c.diff, cfg)
+	c.summary.Cells = cellDiffs(map1, map2, c.diff, cfg1, cfg2)
+	scales := SharedScales(cfg, map1.Data, map2.Data, c.diff)
+	c.summary.Scales = &scales
+	return c
+}
+
+// Diff compares two files like CompareFiles without printing anything.
+// Parameters are compared when all maps are.
+func Diff(file1, file2, mapType string, tolerance float64, raw bool, readMap func(string, models.MapConfig) (*models.ECUMap, error)) (*Result, error) {
+	align, err := Align(file1, file2)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{File1: file1, File2: file2, Raw: raw, DefinitionsDiffer: align.DefinitionsDiffer()}
+	for _, cfg := range selectConfigs(mapType) {
+		result.Maps = append(result.Maps, compareMap(align, file1, file2, cfg, tolerance, raw, readMap).summary)
+	}
+	if mapType == "all" {
+		if result.Params, err = CompareParams(file1, file2, align); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// selectConfigs returns all maps, or the maps whose name contains mapType
+// or whose category it names
+func selectConfigs(mapType string) []models.MapConfig {
+	return models.SelectMaps(mapType)
 }
 
-func compareMapData(data1, data2 [][]float64) [][]float64 {
+func compareMapData(data1, data2 [][]float64, tolerance float64) [][]float64 {
 	rows := len(data1)
 	cols := len(data1[0])
 	diff := make([][]float64, rows)
@@ -49,81 +261,124 @@ func compareMapData(data1, data2 [][]float64) [][]float64 {
 	for i := 0; i < rows; i++ {
 		diff[i] = make([]float64, cols)
 		for j := 0; j < cols; j++ {
-			diff[i][j] = data2[i][j] - data1[i][j]
+			d := data2[i][j] - data1[i][j]
+			if math.Abs(d) <= tolerance {
+				d = 0
+			}
+			diff[i][j] = d
 		}
 	}
 
 	return diff
 }
 
-func displayComparison(map1, map2 *models.ECUMap, diff [][]float64, cfg models.MapConfig) {
-	// Show statistics
-	var totalDiff, maxDiff, minDiff float64
-	changedCells := 0
-
+// summarize counts the changed cells of a difference grid and their
+// average and extreme changes
+func summarize(diff [][]float64, cfg models.MapConfig) MapSummary {
+	s := MapSummary{Name: cfg.Name, Unit: cfg.Unit, Total: cfg.Rows * cfg.Cols}
+	var totalDiff float64
 	for i := 0; i < cfg.Rows; i++ {
 		for j := 0; j < cfg.Cols; j++ {
 			d := diff[i][j]
 			if d != 0 {
-				changedCells++
+				s.Changed++
 				totalDiff += d
-				if d > maxDiff {
-					maxDiff = d
-				}
-				if d < minDiff {
-					minDiff = d
-				}
+				s.MaxIncrease = math.Max(s.MaxIncrease, d)
+				s.MaxDecrease = math.Min(s.MaxDecrease, d)
+			}
+		}
+	}
+	if s.Changed > 0 {
+		s.AvgChange = totalDiff / float64(s.Changed)
+	}
+	return s
+}
+
+// cellDiffs lists the changed cells of a difference grid with their raw
+// values and absolute offsets, using the base-translated definitions the
+// maps were read with
+func cellDiffs(map1, map2 *models.ECUMap, diff [][]float64, cfg1, cfg2 models.MapConfig) []CellDiff {
+	width := models.DataTypeSize(cfg1.DataType)
+	var cells []CellDiff
+	for i := 0; i < cfg1.Rows; i++ {
+		for j := 0; j < cfg1.Cols; j++ {
+			if diff[i][j] == 0 {
+				continue
 			}
+			cell := int64((i*cfg1.Cols + j) * width)
+			raw1, _ := cfg1.ToRaw(map1.Data[i][j])
+			raw2, _ := cfg2.ToRaw(map2.Data[i][j])
+			cells = append(cells, CellDiff{
+				Row: i, Col: j,
+				Value1: map1.Data[i][j], Value2: map2.Data[i][j],
+				Raw1: raw1, Raw2: raw2,
+				Offset1: cfg1.Offset + cell, Offset2: cfg2.Offset + cell,
+				Width: width,
+			})
 		}
 	}
+	return cells
+}
 
-	avgDiff := totalDiff / float64(changedCells)
+func displayComparison(map1, map2 *models.ECUMap, diff [][]float64, s MapSummary, cfg models.MapConfig, tolerance float64) {
+	// Show statistics
+	changedCells, avgDiff, maxDiff, minDiff := s.Changed, s.AvgChange, s.MaxIncrease, s.MaxDecrease
 
+	if tolerance > 0 {
+		pterm.Info.Printf("Tolerance: ±%.3f %s\n", tolerance, cfg.Unit)
+	} else {
+		pterm.Info.Println("Tolerance: none (strict)")
+	}
 	pterm.Info.Printf("Changed cells: %d / %d (%.1f%%)\n",
 		changedCells, cfg.Rows*cfg.Cols,
 		float64(changedCells)/float64(cfg.Rows*cfg.Cols)*100)
 	pterm.Info.Printf("Average change: %.2f %s\n", avgDiff, cfg.Unit)
 	pterm.Info.Printf("Max increase: %.2f %s\n", maxDiff, cfg.Unit)
 	pterm.Info.Printf("Max decrease: %.2f %s\n", minDiff, cfg.Unit)
+	if cfg.HighlightBelow != nil {
+		pterm.Info.Printf("Cells below %.1f %s: %d → %d\n", *cfg.HighlightBelow, cfg.Unit,
+			countBelowThreshold(map1.Data, cfg), countBelowThreshold(map2.Data, cfg))
+	}
 
 	// Visualize differences
 	pterm.Println("\nDifference Map (File2 - File1):")
-	visualizeDifferences(diff, cfg)
+	visualizeDifferences(diff, map1, s.Scales.Delta)
 }
 
-func visualizeDifferences(diff [][]float64, cfg models.MapConfig) {
-	var result strings.Builder
-
-	// Find max absolute difference for scaling
-	maxAbs := 0.0
-	for i := 0; i < cfg.Rows; i++ {
-		for j := 0; j < cfg.Cols; j++ {
-			abs := diff[i][j]
-			if abs < 0 {
-				abs = -abs
-			}
-			if abs > maxAbs {
-				maxAbs = abs
+// countBelowThreshold counts the cells carrying the map's highlight marker
+func countBelowThreshold(data [][]float64, cfg models.MapConfig) int {
+	n := 0
+	for _, row := range data {
+		for _, v := range row {
+			if cfg.BelowThreshold(v) {
+				n++
 			}
 		}
 	}
+	return n
+}
+
+// visualizeDifferences draws diff on the symmetric delta scale of Scales,
+// labelled with the axes of the first file's map
+func visualizeDifferences(diff [][]float64, map1 *models.ECUMap, scale models.HeatScale) {
+	var result strings.Builder
+	cfg := map1.Config
+	rowLabels := map1.RowLabels()
 
 	// RPM header
-	rpmStep := 8000 / cfg.Cols
-	result.WriteString("    RPM → |")
-	for j := 0; j < cfg.Cols; j++ {
-		result.WriteString(fmt.Sprintf("%-6d", j*rpmStep))
+	result.WriteString(fmt.Sprintf("%7s → |", cfg.XAxisName()))
+	for _, rpm := range map1.ColumnLabels() {
+		result.WriteString(fmt.Sprintf("%-6s", rpm))
 	}
 	result.WriteString("\n")
-	result.WriteString("  Load%  |" + strings.Repeat("-", cfg.Cols*6) + "\n")
+	result.WriteString(fmt.Sprintf("  %-7s|", cfg.RowCaption()) + strings.Repeat("-", cfg.Cols*6) + "\n")
 
 	// Data rows
-	loadStep := 100 / cfg.Rows
 	for i := 0; i < cfg.Rows; i++ {
-		result.WriteString(fmt.Sprintf("   %3d ↓ |", i*loadStep))
+		result.WriteString(fmt.Sprintf("  %4s ↓ |", rowLabels[i]))
 		for j := 0; j < cfg.Cols; j++ {
 			val := diff[i][j]
-			symbol := getDiffSymbol(val, maxAbs)
+			symbol := getDiffSymbol(val, scale)
 			result.WriteString(symbol)
 		}
 		result.WriteString("\n")
@@ -140,12 +395,14 @@ func visualizeDifferences(diff [][]float64, cfg models.MapConfig) {
 	pterm.DefaultBox.Println(result.String())
 }
 
-func getDiffSymbol(val, maxAbs float64) string {
+// getDiffSymbol picks the symbol for a difference from its position on a
+// symmetric scale, from -1 (largest decrease) to 1 (largest increase)
+func getDiffSymbol(val float64, scale models.HeatScale) string {
 	if val == 0 {
 		return pterm.FgGray.Sprint("·· ")
 	}
 
-	normalized := val / maxAbs
+	normalized := 2*scale.Normalize(val) - 1
 
 	if normalized < -0.5 {
 		return pterm.FgBlue.Sprint("▼▼ ")
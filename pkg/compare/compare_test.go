@@ -0,0 +1,105 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// The offsets reported for the changed cells are exactly the bytes that
+// differ between the files, in each file's own layout: the second file
+// has a 512-byte reader header, so its offsets are 512 further on
+func TestCellOffsets(t *testing.T) {
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	saved := models.MapConfigs
+	t.Cleanup(func() { models.MapConfigs = saved })
+	wide := models.MapConfig{Name: "Wide Trim", Offset: 0x5000, Rows: 2, Cols: 8, DataType: "uint16", Scale: 0.01, MaxValue: 655.35, Unit: "%"}
+	models.MapConfigs = append(append([]models.MapConfig(nil), saved...), wide)
+
+	fuel := saved[0]
+	image := testbin.Image()
+	changed := bytes.Clone(image)
+	changed[fuel.Offset+2*int64(fuel.Cols)+3]++
+	changed[fuel.Offset+int64(fuel.Rows*fuel.Cols)-1]--
+	changed[wide.Offset+2*5] ^= 0x01    // high byte of cell [0,5]
+	changed[wide.Offset+2*12+1] ^= 0x80 // low byte of cell [1,4]
+
+	const header = 512
+	dir := t.TempDir()
+	file1, file2 := filepath.Join(dir, "stock.bin"), filepath.Join(dir, "tuned.bin")
+	if err := os.WriteFile(file1, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file2, append(make([]byte, header), changed...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Diff(file1, file2, "all", 0, false, reader.ReadMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data1, _ := os.ReadFile(file1)
+	data2, _ := os.ReadFile(file2)
+	covered := make(map[int64]bool)
+	for _, m := range result.Maps {
+		for _, c := range m.Cells {
+			if c.Offset2 != c.Offset1+header {
+				t.Errorf("%s [%d,%d] at 0x%04X and 0x%04X, want %d bytes apart", m.Name, c.Row, c.Col, c.Offset1, c.Offset2, header)
+			}
+			b1 := data1[c.Offset1 : c.Offset1+int64(c.Width)]
+			b2 := data2[c.Offset2 : c.Offset2+int64(c.Width)]
+			if bytes.Equal(b1, b2) {
+				t.Errorf("%s [%d,%d]: the bytes at 0x%04X and 0x%04X are equal", m.Name, c.Row, c.Col, c.Offset1, c.Offset2)
+			}
+			dataType := fuel.DataType
+			if m.Name == wide.Name {
+				dataType = wide.DataType
+			}
+			if raw := models.DecodeRaw(b2, dataType); raw != c.Raw2 {
+				t.Errorf("%s [%d,%d]: raw %d at 0x%04X, reported %d", m.Name, c.Row, c.Col, raw, c.Offset2, c.Raw2)
+			}
+			for i := range int64(c.Width) {
+				covered[c.Offset1+i] = true
+			}
+		}
+	}
+	for i := range image {
+		if differs := image[i] != changed[i]; differs && !covered[int64(i)] {
+			t.Errorf("byte 0x%04X differs but no cell covers it", i)
+		}
+	}
+
+	// The CSV cell section prints the same offsets
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	cr := csv.NewReader(&buf)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var printed int
+	for _, rec := range records {
+		if len(rec) != 12 || rec[0] == "Map" {
+			continue
+		}
+		off1, err1 := strconv.ParseInt(rec[9], 0, 64)
+		off2, err2 := strconv.ParseInt(rec[10], 0, 64)
+		if err1 != nil || err2 != nil || !covered[off1] || off2 != off1+header {
+			t.Errorf("CSV row %v", rec)
+		}
+		printed++
+	}
+	if printed != 4 {
+		t.Errorf("the CSV lists %d changed cells, want 4", printed)
+	}
+}
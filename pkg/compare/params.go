@@ -0,0 +1,132 @@
+package compare
+
+import (
+	"fmt"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/tabular"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// ParamDiff is a configuration parameter whose stored value differs
+// between two files
+type ParamDiff struct {
+	Param  models.ConfigParam `json:"param"`
+	Value1 float64            `json:"value1"`
+	Value2 float64            `json:"value2"`
+	Raw1   int64              `json:"raw1"`
+	Raw2   int64              `json:"raw2"`
+	// Implausible1/2 flag values outside the parameter's MinValue-MaxValue
+	// range, which usually means a wrong offset or a corrupted image
+	Implausible1 bool `json:"implausible1,omitempty"`
+	Implausible2 bool `json:"implausible2,omitempty"`
+	// Err is set when the parameter lies outside one of the files
+	Err string `json:"error,omitempty"`
+}
+
+// Delta returns Value2 - Value1
+func (d ParamDiff) Delta() float64 { return d.Value2 - d.Value1 }
+
+// Implausible reports whether either value is implausible
+func (d ParamDiff) Implausible() bool { return d.Implausible1 || d.Implausible2 }
+
+// CompareParams reads every configuration parameter from both files at
+// their aligned offsets and returns the ones whose raw values differ or
+// that could not be read from one of the files
+func CompareParams(file1, file2 string, align *Alignment) ([]ParamDiff, error) {
+	data1, err := reader.ReadBinary(file1)
+	if err != nil {
+		return nil, err
+	}
+	data2, err := reader.ReadBinary(file2)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []ParamDiff
+	for _, param := range models.ConfigParams {
+		p1, p2 := param, param
+		shift1, shift2 := align.Shifts()
+		p1.Offset += shift1
+		p2.Offset += shift2
+
+		d := ParamDiff{Param: param}
+		v1, err1 := reader.ReadConfigParamFromBytes(data1, p1)
+		v2, err2 := reader.ReadConfigParamFromBytes(data2, p2)
+		switch {
+		case err1 != nil && err2 != nil:
+			d.Err = "out of range in both files"
+		case err1 != nil:
+			d.Err = "out of range in file1"
+		case err2 != nil:
+			d.Err = "out of range in file2"
+		}
+		if d.Err != "" {
+			diffs = append(diffs, d)
+			continue
+		}
+
+		d.Raw1 = param.DecodeRaw(data1[p1.Offset:])
+		d.Raw2 = param.DecodeRaw(data2[p2.Offset:])
+		if d.Raw1 == d.Raw2 {
+			continue
+		}
+		d.Value1, d.Value2 = v1, v2
+		d.Implausible1 = !plausible(param, v1)
+		d.Implausible2 = !plausible(param, v2)
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// plausible reports whether a value lies in the parameter's allowed range
+func plausible(param models.ConfigParam, value float64) bool {
+	return value >= param.MinValue && value <= param.MaxValue
+}
+
+// FormatValue renders a parameter value with its raw byte(s), marking
+// implausible values
+func (d ParamDiff) FormatValue(file int) string {
+	value, raw, implausible := d.Value1, d.Raw1, d.Implausible1
+	if file == 2 {
+		value, raw, implausible = d.Value2, d.Raw2, d.Implausible2
+	}
+	s := fmt.Sprintf("%.1f %s (raw %d)", value, d.Param.Unit, raw)
+	if implausible {
+		s += " ⚠"
+	}
+	return s
+}
+
+// ParamTable returns one row per differing parameter, as PrintParamDiffs
+// shows them
+func ParamTable(diffs []ParamDiff) *tabular.Table {
+	t := tabular.New("Parameter", "Offset", "File1", "File2", "Change")
+	for _, d := range diffs {
+		offset := fmt.Sprintf("0x%04X", d.Param.Offset)
+		if d.Err != "" {
+			t.Add(d.Param.Name, offset, "-", "-", d.Err)
+			continue
+		}
+		t.Add(d.Param.Name, offset, d.FormatValue(1), d.FormatValue(2),
+			fmt.Sprintf("%+.1f %s", d.Delta(), d.Param.Unit))
+	}
+	return t
+}
+
+// PrintParamDiffs prints a table of the differing parameters
+func PrintParamDiffs(diffs []ParamDiff) {
+	if len(diffs) == 0 {
+		pterm.Success.Println("All configuration parameters match")
+		return
+	}
+
+	ParamTable(diffs).Render()
+	for _, d := range diffs {
+		if d.Implausible() {
+			pterm.Warning.Println("⚠ marks values outside the parameter's plausible range")
+			break
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package compare
+
+import "github.com/tosih/motronic-m21-tool/pkg/models"
+
+// RawUnit is the unit of maps read with RawConfig
+const RawUnit = "raw"
+
+// RawConfig returns cfg reading the stored raw values unscaled: scale 1,
+// no offset, linear conversion and no formula. Diffing with it ignores differences in
+// Scale and Offset2 between definition versions, so only bytes that
+// really changed count. Color scale and highlight threshold are dropped
+// since they are in engineering units.
+func RawConfig(cfg models.MapConfig) models.MapConfig {
+	cfg.Scale = 1
+	cfg.Offset2 = 0
+	cfg.Conversion = models.ConversionLinear
+	cfg.Formula, cfg.InverseFormula = "", ""
+	cfg.Unit = RawUnit
+	cfg.ColorScale = models.ColorScale{}
+	cfg.HighlightBelow = nil
+	cfg.NudgeStep = 0
+	return cfg
+}
@@ -0,0 +1,149 @@
+package compare
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/internal/tabular"
+)
+
+// SummaryTable returns one row per compared map with its changed-cell
+// summary, the first section of WriteCSV and the -format output of
+// -compare
+func SummaryTable(r *Result) *tabular.Table {
+	t := tabular.New("Map", "Unit", "Changed", "Cells", "Average", "Max Increase", "Max Decrease", "Skipped")
+	for _, m := range r.Maps {
+		if m.Skipped != "" {
+			t.Add(m.Name, m.Unit, "", "", "", "", "", m.Skipped)
+			continue
+		}
+		t.Add(
+			m.Name, m.Unit,
+			strconv.Itoa(m.Changed), strconv.Itoa(m.Total),
+			fmt.Sprintf("%.3f", m.AvgChange),
+			fmt.Sprintf("%.3f", m.MaxIncrease),
+			fmt.Sprintf("%.3f", m.MaxDecrease),
+			"",
+		)
+	}
+	return t
+}
+
+// WriteCSV writes the comparison as three sections: one row per map with
+// its changed-cell summary, one row per differing parameter, then one row
+// per changed cell with its absolute offset in each file
+func WriteCSV(w io.Writer, r *Result) error {
+	cw := csv.NewWriter(w)
+	summary := SummaryTable(r)
+	cw.Write(summary.Columns)
+	cw.WriteAll(summary.Rows)
+	cw.Write(nil)
+	cw.Write([]string{"Parameter", "Offset", "Unit", "Value1", "Raw1", "Value2", "Raw2", "Implausible", "Error"})
+	for _, d := range r.Params {
+		implausible := ""
+		switch {
+		case d.Implausible1 && d.Implausible2:
+			implausible = "both"
+		case d.Implausible1:
+			implausible = "file1"
+		case d.Implausible2:
+			implausible = "file2"
+		}
+		row := []string{d.Param.Name, fmt.Sprintf("0x%04X", d.Param.Offset), d.Param.Unit, "", "", "", "", implausible, d.Err}
+		if d.Err == "" {
+			row[3] = fmt.Sprintf("%.2f", d.Value1)
+			row[4] = strconv.FormatInt(d.Raw1, 10)
+			row[5] = fmt.Sprintf("%.2f", d.Value2)
+			row[6] = strconv.FormatInt(d.Raw2, 10)
+		}
+		cw.Write(row)
+	}
+
+	cw.Write(nil)
+	header := []string{"Map", "Row", "Col", "Unit", "Value1", "Value2", "Change", "Raw1", "Raw2", "Offset1", "Offset2", "Bytes"}
+	if r.Blamed {
+		header = append(header, "Changed At", "Changed By", "Changelog Of")
+	}
+	cw.Write(header)
+	for _, m := range r.Maps {
+		for _, c := range m.Cells {
+			row := []string{
+				m.Name, strconv.Itoa(c.Row), strconv.Itoa(c.Col), m.Unit,
+				fmt.Sprintf("%.3f", c.Value1), fmt.Sprintf("%.3f", c.Value2), fmt.Sprintf("%+.3f", c.Delta()),
+				strconv.FormatInt(c.Raw1, 10), strconv.FormatInt(c.Raw2, 10),
+				fmt.Sprintf("0x%04X", c.Offset1), fmt.Sprintf("0x%04X", c.Offset2),
+				strconv.Itoa(c.Width),
+			}
+			if b := c.Blame; b != nil && b.Unattributed {
+				row = append(row, "", "unattributed", "")
+			} else if b != nil {
+				row = append(row, b.Time.Format(time.RFC3339), b.Detail, filepath.Base(b.File))
+			}
+			cw.Write(row)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the comparison, including every changed cell with its
+// absolute offsets, as indented JSON
+func WriteJSON(w io.Writer, r *Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.File1}} vs {{.File2}}</title>
+<style>
+body { font-family: sans-serif; background: #1e1e1e; color: #e0e0e0; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #444; padding: 4px 8px; font-size: 13px; }
+td.num { text-align: right; }
+td.skipped { color: #888; font-style: italic; }
+td.implausible { background: #5a2020; }
+td.unattributed { color: #e0b040; }
+</style></head><body>
+<h1>{{.File1}} vs {{.File2}}{{if .Raw}} (raw values){{end}}</h1>
+{{if .Raw}}<p>Maps were compared as raw stored values; scales and offsets of the definitions were ignored.</p>
+{{else if .DefinitionsDiffer}}<p>The files were saved with different map definitions, so revised scales show up as changes. Compare with -compare-raw to see only changed bytes.</p>
+{{end}}<h2>Maps</h2>
+<table>
+<tr><th>Map</th><th>Changed cells</th><th>Average</th><th>Max increase</th><th>Max decrease</th></tr>
+{{range .Maps}}<tr><td>{{.Name}}</td>{{if .Skipped}}<td class="skipped" colspan="4">Skipped: {{.Skipped}}</td>{{else}}<td class="num">{{.Changed}} / {{.Total}}</td><td class="num">{{printf "%.2f" .AvgChange}} {{.Unit}}</td><td class="num">{{printf "%.2f" .MaxIncrease}} {{.Unit}}</td><td class="num">{{printf "%.2f" .MaxDecrease}} {{.Unit}}</td>{{end}}</tr>
+{{end}}</table>
+<h2>Configuration parameters</h2>
+{{if .Params}}<table>
+<tr><th>Parameter</th><th>Offset</th><th>{{.File1}}</th><th>{{.File2}}</th><th>Change</th></tr>
+{{range .Params}}<tr><td>{{.Param.Name}}</td><td>0x{{printf "%04X" .Param.Offset}}</td>{{if .Err}}<td class="skipped" colspan="3">{{.Err}}</td>{{else}}<td class="num{{if .Implausible1}} implausible{{end}}">{{.FormatValue 1}}</td><td class="num{{if .Implausible2}} implausible{{end}}">{{.FormatValue 2}}</td><td class="num">{{printf "%+.1f" .Delta}} {{.Param.Unit}}</td>{{end}}</tr>
+{{end}}</table>
+<p>Red cells are outside the parameter's plausible range.</p>
+{{else}}<p>All configuration parameters match.</p>{{end}}
+{{range .Maps}}{{if .Cells}}<h2>{{.Name}}: changed cells</h2>
+<table>
+<tr><th>Cell</th><th>{{$.File1}}</th><th>{{$.File2}}</th><th>Change</th><th>Offset in {{$.File1}}</th><th>Offset in {{$.File2}}</th>{{if $.Blamed}}<th>Changed by</th>{{end}}</tr>
+{{$unit := .Unit}}{{range .Cells}}<tr><td>[{{.Row}},{{.Col}}]</td><td class="num">{{printf "%.2f" .Value1}} (raw {{.Raw1}})</td><td class="num">{{printf "%.2f" .Value2}} (raw {{.Raw2}})</td><td class="num">{{printf "%+.2f" .Delta}} {{$unit}}</td><td class="num">0x{{printf "%04X" .Offset1}}</td><td class="num">0x{{printf "%04X" .Offset2}}</td>{{with .Blame}}<td{{if .Unattributed}} class="unattributed"{{end}}>{{if .Unattributed}}Unattributed: changed outside this tool{{else}}{{.String}}{{end}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{end}}</body></html>
+`))
+
+// WriteHTML writes a standalone HTML report of the map summaries and the
+// differing parameters, with the changelog entry of each changed cell
+// when the result was blamed
+func WriteHTML(w io.Writer, r *Result) error {
+	data := struct {
+		File1, File2                   string
+		Raw, DefinitionsDiffer, Blamed bool
+		Maps                           []MapSummary
+		Params                         []ParamDiff
+	}{filepath.Base(r.File1), filepath.Base(r.File2), r.Raw, r.DefinitionsDiffer, r.Blamed, r.Maps, r.Params}
+	return reportTemplate.Execute(w, data)
+}
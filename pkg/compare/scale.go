@@ -0,0 +1,24 @@
+package compare
+
+import "github.com/tosih/motronic-m21-tool/pkg/models"
+
+// Scales are the color scales every view of one compared map uses, so the
+// CLI, GUI and web page color the same values alike
+type Scales struct {
+	// Shared is the map's color scale resolved over the cells of both
+	// files; a per-file scale would give equal values different colors
+	Shared models.HeatScale `json:"shared"`
+	// Delta is symmetric around zero over the differences
+	Delta models.HeatScale `json:"delta"`
+}
+
+// SharedScales resolves cfg's color scale over data1 and data2 together
+// and a symmetric scale over diff
+func SharedScales(cfg models.MapConfig, data1, data2, diff [][]float64) Scales {
+	both := make([][]float64, 0, len(data1)+len(data2))
+	both = append(append(both, data1...), data2...)
+	return Scales{
+		Shared: cfg.HeatScale(both),
+		Delta:  models.SymmetricScale(diff),
+	}
+}
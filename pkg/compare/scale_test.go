@@ -0,0 +1,93 @@
+package compare
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// When file B is file A plus a constant, both sides are colored on one
+// scale from A's minimum to B's maximum, so B's colors are A's shifted by
+// the constant's share of the range, and every difference is the hot end
+// of the delta scale with zero in the middle
+func TestSharedScalesConstantOffset(t *testing.T) {
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	// The fuel map's robust scale clips the ends; min/max keeps every
+	// cell inside the gradient
+	saved := models.MapConfigs
+	t.Cleanup(func() { models.MapConfigs = saved })
+	models.MapConfigs = append([]models.MapConfig(nil), saved...)
+	models.MapConfigs[0].ColorScale = models.ColorScale{Mode: models.ScaleMinMax}
+	fuel := models.MapConfigs[0]
+	const step = 10 // raw steps added to every cell
+	a := testbin.Image()
+	b := append([]byte(nil), a...)
+	for i := fuel.Offset; i < fuel.Offset+fuel.ByteSize(); i++ {
+		if int(b[i])+step > 0xFF {
+			t.Fatalf("raw 0x%02X at 0x%04X leaves no room for the constant", b[i], i)
+		}
+		b[i] += step
+	}
+	dir := t.TempDir()
+	fileA, fileB := filepath.Join(dir, "a.bin"), filepath.Join(dir, "b.bin")
+	os.WriteFile(fileA, a, 0644)
+	os.WriteFile(fileB, b, 0644)
+
+	result, err := Diff(fileA, fileB, fuel.Name, 0, false, reader.ReadMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := result.Maps[0]
+	if summary.Scales == nil || summary.Changed != fuel.Rows*fuel.Cols {
+		t.Fatalf("summary %+v", summary)
+	}
+	shared, delta := summary.Scales.Shared, summary.Scales.Delta
+	mapA, _ := reader.ReadMap(fileA, fuel)
+	mapB, _ := reader.ReadMap(fileB, fuel)
+	constant := float64(step) * fuel.Scale
+	shift := constant / (shared.Max() - shared.Min())
+
+	const eps = 1e-9
+	for i := range fuel.Rows {
+		for j := range fuel.Cols {
+			na, nb := shared.Normalize(mapA.Data[i][j]), shared.Normalize(mapB.Data[i][j])
+			if math.Abs(nb-na-shift) > eps {
+				t.Errorf("[%d,%d] is colored at %.4f in A and %.4f in B, want B %.4f further", i, j, na, nb, shift)
+			}
+			if nd := delta.Normalize(mapB.Data[i][j] - mapA.Data[i][j]); math.Abs(nd-1) > eps {
+				t.Errorf("[%d,%d] difference is colored at %.4f, want 1", i, j, nd)
+			}
+		}
+	}
+	if shared.Min() != min2(mapA.Data) || shared.Max() != max2(mapB.Data) {
+		t.Errorf("shared scale %v, want A's minimum to B's maximum", shared.Bounds)
+	}
+	if delta.Normalize(0) != 0.5 {
+		t.Errorf("no change is colored at %g, want the middle", delta.Normalize(0))
+	}
+}
+
+func min2(data [][]float64) float64 {
+	m := math.Inf(1)
+	for _, row := range data {
+		for _, v := range row {
+			m = math.Min(m, v)
+		}
+	}
+	return m
+}
+
+func max2(data [][]float64) float64 {
+	m := math.Inf(-1)
+	for _, row := range data {
+		for _, v := range row {
+			m = math.Max(m, v)
+		}
+	}
+	return m
+}
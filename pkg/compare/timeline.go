@@ -0,0 +1,176 @@
+package compare
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/stats"
+)
+
+// TimelineVersion is one revision of a file in a backup timeline
+type TimelineVersion struct {
+	Label string
+	Path  string
+}
+
+// BuildTimeline returns all backups of filename in chronological order,
+// followed by the current file itself
+func BuildTimeline(filename string) ([]TimelineVersion, error) {
+	backups, err := editor.ListBackups(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]TimelineVersion, 0, len(backups)+1)
+	for _, b := range backups {
+		versions = append(versions, TimelineVersion{
+			Label: b.Time.Format("2006-01-02 15:04:05"),
+			Path:  b.Path,
+		})
+	}
+	versions = append(versions, TimelineVersion{Label: "current", Path: filename})
+
+	return versions, nil
+}
+
+// ShowTimeline prints how the selected maps changed across the backup
+// series of filename. If csvPath is set, per-cell values are also written
+// to a CSV file with one column per version.
+func ShowTimeline(filename, mapType, csvPath string, readMap func(string, models.MapConfig) (*models.ECUMap, error)) {
+	pterm.DefaultHeader.WithFullWidth().Println(i18n.T("cli.timeline.header"))
+
+	versions, err := BuildTimeline(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to list backups: %v\n", err)
+		return
+	}
+	if len(versions) == 1 {
+		pterm.Warning.Printf("No backups found for %s\n", filename)
+	}
+
+	// Skip backups that can't be read or don't match the current file size
+	currentInfo, err := os.Stat(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read %s: %v\n", filename, err)
+		return
+	}
+	var valid []TimelineVersion
+	for _, v := range versions {
+		info, err := os.Stat(v.Path)
+		if err != nil {
+			pterm.Warning.Printf("Skipping %s: %v\n", v.Path, err)
+			continue
+		}
+		if info.Size() != currentInfo.Size() {
+			pterm.Warning.Printf("Skipping %s: size %d differs from current file (%d)\n", v.Path, info.Size(), currentInfo.Size())
+			continue
+		}
+		valid = append(valid, v)
+	}
+
+	var csvRows [][]string
+	if csvPath != "" {
+		header := []string{"Map", "Row", "Col"}
+		for _, v := range valid {
+			header = append(header, v.Label)
+		}
+		csvRows = append(csvRows, header)
+	}
+
+	for _, cfg := range selectConfigs(mapType) {
+		pterm.Println()
+		pterm.DefaultSection.Print(i18n.T("cli.timeline.section", cfg.Name))
+
+		var maps []*models.ECUMap
+		tableData := pterm.TableData{{"Version", "Min", "Max", "Mean", "Changed Cells"}}
+		for _, v := range valid {
+			m, err := readMap(v.Path, cfg)
+			if err != nil {
+				pterm.Warning.Printf("Skipping %s: %v\n", v.Path, err)
+				maps = append(maps, nil)
+				continue
+			}
+
+			changed := "-"
+			if prev := lastMap(maps); prev != nil {
+				changed = fmt.Sprintf("%d", countChanged(prev.Data, m.Data))
+			}
+			maps = append(maps, m)
+
+			s := stats.OfMap(m.Data)
+			tableData = append(tableData, []string{
+				v.Label,
+				fmt.Sprintf("%.2f %s", s.Min, cfg.Unit),
+				fmt.Sprintf("%.2f %s", s.Max, cfg.Unit),
+				fmt.Sprintf("%.2f %s", s.Mean, cfg.Unit),
+				changed,
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+		if csvPath != "" {
+			for i := 0; i < cfg.Rows; i++ {
+				for j := 0; j < cfg.Cols; j++ {
+					row := []string{cfg.Name, fmt.Sprintf("%d", i), fmt.Sprintf("%d", j)}
+					for _, m := range maps {
+						if m == nil {
+							row = append(row, "")
+						} else {
+							row = append(row, fmt.Sprintf("%.2f", m.Data[i][j]))
+						}
+					}
+					csvRows = append(csvRows, row)
+				}
+			}
+		}
+	}
+
+	if csvPath != "" {
+		if err := writeCSV(csvPath, csvRows); err != nil {
+			pterm.Error.Printf("Failed to write timeline CSV: %v\n", err)
+			return
+		}
+		pterm.Success.Printf("Per-cell timeline written to %s\n", csvPath)
+	}
+}
+
+// lastMap returns the most recent successfully read map
+func lastMap(maps []*models.ECUMap) *models.ECUMap {
+	for i := len(maps) - 1; i >= 0; i-- {
+		if maps[i] != nil {
+			return maps[i]
+		}
+	}
+	return nil
+}
+
+func countChanged(data1, data2 [][]float64) int {
+	changed := 0
+	for i := range data1 {
+		for j := range data1[i] {
+			if data1[i][j] != data2[i][j] {
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+func writeCSV(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return file.Close()
+}
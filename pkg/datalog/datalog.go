@@ -0,0 +1,172 @@
+// Package datalog parses CSV datalogs (e.g. wideband logs) and bins their
+// samples onto map grids for comparison with the calibration.
+package datalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// StoichAFR converts measured AFR to lambda for gasoline
+const StoichAFR = 14.7
+
+// Columns maps log header names to the values the parser needs. Exactly
+// one of Lambda or AFR is used; AFR is converted with StoichAFR.
+type Columns struct {
+	RPM    string
+	Load   string
+	Lambda string
+	AFR    string
+}
+
+// Sample is one log row
+type Sample struct {
+	RPM    float64
+	Load   float64
+	Lambda float64
+}
+
+// ParseColumns parses "rpm=Engine Speed,load=MAP,lambda=Lambda1" overrides.
+// Unset columns are detected from the header.
+func ParseColumns(s string) (Columns, error) {
+	var c Columns
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid column mapping %q: expected key=header", pair)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "rpm":
+			c.RPM = value
+		case "load", "map":
+			c.Load = value
+		case "lambda":
+			c.Lambda = value
+		case "afr":
+			c.AFR = value
+		default:
+			return c, fmt.Errorf("unknown column %q: expected rpm, load, lambda or afr", key)
+		}
+	}
+	return c, nil
+}
+
+// detect fills unset columns from header names
+func (c Columns) detect(header []string) Columns {
+	find := func(keys ...string) string {
+		for _, h := range header {
+			name := strings.ToLower(strings.TrimSpace(h))
+			for _, k := range keys {
+				if strings.Contains(name, k) {
+					return h
+				}
+			}
+		}
+		return ""
+	}
+
+	if c.RPM == "" {
+		c.RPM = find("rpm", "engine speed")
+	}
+	if c.Load == "" {
+		c.Load = find("load", "map", "kpa")
+	}
+	if c.Lambda == "" && c.AFR == "" {
+		c.Lambda = find("lambda")
+		if c.Lambda == "" {
+			c.AFR = find("afr")
+		}
+	}
+	return c
+}
+
+// Parse reads samples from a CSV log with a header row. Rows with missing
+// or non-numeric values are skipped and counted.
+func Parse(r io.Reader, cols Columns) ([]Sample, int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading header: %w", err)
+	}
+	cols = cols.detect(header)
+
+	index := func(name string) int {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	rpmIdx, loadIdx := index(cols.RPM), index(cols.Load)
+	valueIdx, isAFR := index(cols.Lambda), false
+	if cols.Lambda == "" {
+		valueIdx, isAFR = index(cols.AFR), true
+	}
+	if rpmIdx < 0 || loadIdx < 0 || valueIdx < 0 {
+		return nil, 0, errors.New("log needs RPM, load and lambda/AFR columns; map them with rpm=,load=,lambda= or afr=")
+	}
+
+	var samples []Sample
+	skipped := 0
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return samples, skipped, err
+		}
+
+		values := make([]float64, 3)
+		ok := true
+		for k, idx := range []int{rpmIdx, loadIdx, valueIdx} {
+			if idx >= len(record) {
+				ok = false
+				break
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(record[idx]), 64)
+			if err != nil {
+				ok = false
+				break
+			}
+			values[k] = v
+		}
+		if !ok {
+			skipped++
+			continue
+		}
+
+		lambda := values[2]
+		if isAFR {
+			lambda /= StoichAFR
+		}
+		samples = append(samples, Sample{RPM: values[0], Load: values[1], Lambda: lambda})
+	}
+
+	return samples, skipped, nil
+}
+
+// ParseFile reads samples from a CSV log file
+func ParseFile(path string, cols Columns) ([]Sample, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	return Parse(f, cols)
+}
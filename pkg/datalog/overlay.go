@@ -0,0 +1,68 @@
+package datalog
+
+import (
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// Axis ranges of the map grids. Samples are binned on the evenly spaced
+// RPM and load axes the map views label when a map has no breakpoints:
+// column j starts at j*MaxRPM/cols, and a sample goes to the row whose
+// load is nearest (see models.MapConfig.LoadAt). Breakpoints read from
+// the binary (models.AxisConfig) are not used for binning yet.
+const (
+	MaxRPM  = models.MaxRPM
+	MaxLoad = models.MaxLoad
+)
+
+// DefaultMinSamples is the sample count below which a cell is marked as
+// low-confidence
+const DefaultMinSamples = 5
+
+// Overlay holds measured lambda binned onto a map grid
+type Overlay struct {
+	Rows       int
+	Cols       int
+	Mean       [][]float64
+	Count      [][]int
+	MinSamples int
+	Outside    int // samples beyond the grid's axes
+}
+
+// Bin averages samples into the cells of the map's grid
+func Bin(cfg models.MapConfig, samples []Sample, minSamples int) *Overlay {
+	o := &Overlay{Rows: cfg.Rows, Cols: cfg.Cols, MinSamples: minSamples}
+	o.Mean = make([][]float64, cfg.Rows)
+	o.Count = make([][]int, cfg.Rows)
+	for i := range o.Mean {
+		o.Mean[i] = make([]float64, cfg.Cols)
+		o.Count[i] = make([]int, cfg.Cols)
+	}
+
+	for _, s := range samples {
+		row, col, ok := CellFor(cfg, s.RPM, s.Load)
+		if !ok {
+			o.Outside++
+			continue
+		}
+		o.Count[row][col]++
+		o.Mean[row][col] += (s.Lambda - o.Mean[row][col]) / float64(o.Count[row][col])
+	}
+
+	return o
+}
+
+// CellFor returns the map cell covering an RPM and load operating point
+func CellFor(cfg models.MapConfig, rpm, load float64) (row, col int, ok bool) {
+	if rpm < 0 || rpm >= MaxRPM || load < 0 || load > MaxLoad {
+		return 0, 0, false
+	}
+	col = int(rpm / (MaxRPM / float64(cfg.Cols)))
+	row, ok = cfg.LoadRow(load)
+	return row, col, ok
+}
+
+// LowConfidence reports whether a cell has too few samples for its mean
+// to be trusted
+func (o *Overlay) LowConfidence(row, col int) bool {
+	return o.Count[row][col] < o.MinSamples
+}
@@ -0,0 +1,116 @@
+package datalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// WriteCSV writes one row per cell with the target, measured mean, sample
+// count and confidence
+func WriteCSV(w io.Writer, target *models.ECUMap, o *Overlay) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Row", "Col", "RPM", "Load", "Target", "Measured", "Samples", "Confidence"})
+
+	for i := 0; i < o.Rows; i++ {
+		for j := 0; j < o.Cols; j++ {
+			measured := ""
+			confidence := "none"
+			if o.Count[i][j] > 0 {
+				measured = fmt.Sprintf("%.3f", o.Mean[i][j])
+				confidence = "ok"
+				if o.LowConfidence(i, j) {
+					confidence = "low"
+				}
+			}
+			cw.Write([]string{
+				strconv.Itoa(i),
+				strconv.Itoa(j),
+				strconv.Itoa(int(float64(j) * MaxRPM / float64(o.Cols))),
+				strconv.Itoa(int(math.Round(target.Config.LoadAt(i)))),
+				fmt.Sprintf("%.3f", target.Data[i][j]),
+				measured,
+				strconv.Itoa(o.Count[i][j]),
+				confidence,
+			})
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Name}} - log overlay</title>
+<style>
+body { font-family: sans-serif; background: #1e1e1e; color: #e0e0e0; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #444; padding: 4px 6px; text-align: center; font-size: 12px; }
+td.low { color: #888; font-style: italic; }
+td.rich { background: #203a5a; }
+td.lean { background: #5a2020; }
+small { display: block; color: #aaa; }
+</style></head><body>
+<h1>{{.Name}}</h1>
+<p>Target / measured mean lambda and sample count per cell. Cells with fewer than {{.MinSamples}} samples are low-confidence (grey, ~). {{.Outside}} samples fell outside the grid.</p>
+<table>
+<tr><th>Load \ RPM</th>{{range .RPM}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr><th>{{.Load}}</th>{{range .Cells}}<td class="{{.Class}}">{{.Target}}<small>{{.Measured}}</small></td>{{end}}</tr>
+{{end}}</table>
+</body></html>
+`))
+
+type reportCell struct {
+	Target   string
+	Measured string
+	Class    string
+}
+
+type reportRow struct {
+	Load  string
+	Cells []reportCell
+}
+
+// WriteHTML writes a standalone HTML grid of target and measured values
+func WriteHTML(w io.Writer, target *models.ECUMap, o *Overlay) error {
+	data := struct {
+		Name       string
+		MinSamples int
+		Outside    int
+		RPM        []int
+		Rows       []reportRow
+	}{Name: target.Config.Name, MinSamples: o.MinSamples, Outside: o.Outside}
+
+	for j := 0; j < o.Cols; j++ {
+		data.RPM = append(data.RPM, int(float64(j)*MaxRPM/float64(o.Cols)))
+	}
+	for i := 0; i < o.Rows; i++ {
+		row := reportRow{Load: target.Config.LoadLabel(i)}
+		for j := 0; j < o.Cols; j++ {
+			cell := reportCell{Target: fmt.Sprintf("%.2f", target.Data[i][j])}
+			switch {
+			case o.Count[i][j] == 0:
+				cell.Measured = "–"
+			case o.LowConfidence(i, j):
+				cell.Measured = fmt.Sprintf("~%.2f (%d)", o.Mean[i][j], o.Count[i][j])
+				cell.Class = "low"
+			default:
+				cell.Measured = fmt.Sprintf("%.2f (%d)", o.Mean[i][j], o.Count[i][j])
+				if o.Mean[i][j] < target.Data[i][j] {
+					cell.Class = "rich"
+				} else if o.Mean[i][j] > target.Data[i][j] {
+					cell.Class = "lean"
+				}
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	return reportTemplate.Execute(w, data)
+}
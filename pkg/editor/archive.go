@@ -0,0 +1,126 @@
+package editor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// ArchiveManifestName is the manifest of a tune archive
+const ArchiveManifestName = "manifest.json"
+
+// ArchivedLog is an attachment as listed in a tune archive
+type ArchivedLog struct {
+	Attachment
+	// Status is the attachment's Status when the archive was written
+	Status string `json:"status"`
+	// Embedded is the log's name in the archive, empty if it is only listed
+	Embedded string `json:"embedded,omitempty"`
+}
+
+// ArchiveManifest describes a tune archive: the ECU file it holds and the
+// logs attached to it
+type ArchiveManifest struct {
+	File    string        `json:"file"`
+	SHA256  string        `json:"sha256"`
+	Created time.Time     `json:"created"`
+	Logs    []ArchivedLog `json:"logs,omitempty"`
+}
+
+// WriteTuneArchive writes a zip archive holding the ECU file, its sidecar
+// and a manifest listing the logs attached to it. With embedLogs the logs
+// are stored under logs/ as well; a log that is missing or changed since
+// it was attached is only listed, with its status.
+func WriteTuneArchive(filename, archivePath string, embedLogs bool) (*ArchiveManifest, error) {
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		return nil, err
+	}
+	sidecar, err := LoadSidecar(filename)
+	if err != nil {
+		return nil, err
+	}
+	sidecarData, err := os.ReadFile(SidecarPath(filename))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	manifest := &ArchiveManifest{File: filepath.Base(filename), SHA256: hashData(data), Created: time.Now()}
+	files := map[string][]byte{manifest.File: data}
+	if sidecarData != nil {
+		files[filepath.Base(SidecarPath(filename))] = sidecarData
+	}
+	for _, a := range sidecar.Attachments {
+		log := ArchivedLog{Attachment: a, Status: a.Status()}
+		if embedLogs && log.Status == "ok" {
+			content, err := os.ReadFile(a.Path)
+			if err != nil {
+				return nil, err
+			}
+			// The log may have changed since Status hashed it
+			if hashData(content) != a.SHA256 {
+				log.Status = "modified"
+			} else {
+				log.Embedded = archiveLogName(files, filepath.Base(a.Path))
+				files[log.Embedded] = content
+			}
+		}
+		manifest.Logs = append(manifest.Logs, log)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[ArchiveManifestName] = append(manifestData, '\n')
+	order := []string{manifest.File, filepath.Base(SidecarPath(filename)), ArchiveManifestName}
+	for _, log := range manifest.Logs {
+		order = append(order, log.Embedded)
+	}
+	return manifest, writeZip(archivePath, files, order)
+}
+
+// archiveLogName returns logs/name, numbered if the archive already holds
+// a log of that name
+func archiveLogName(files map[string][]byte, name string) string {
+	candidate := path.Join("logs", name)
+	for i := 2; ; i++ {
+		if _, taken := files[candidate]; !taken {
+			return candidate
+		}
+		candidate = path.Join("logs", fmt.Sprintf("%d-%s", i, name))
+	}
+}
+
+// writeZip writes the files named in order that are in files to a new
+// zip archive, replacing it only once it is complete
+func writeZip(archivePath string, files map[string][]byte, order []string) error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range order {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(archivePath, buf.Bytes())
+}
@@ -0,0 +1,185 @@
+package editor
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeLog writes a log file named name in its own directory
+func writeLog(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAttachLog(t *testing.T) {
+	file, data := writeImage(t)
+	log := writeLog(t, "run3.csv", "rpm,afr\n3000,12.5\n")
+
+	a, err := AttachLog(file, log, "3rd gear pull")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Path != log || a.Size != 18 || a.SHA256 != hashData([]byte("rpm,afr\n3000,12.5\n")) || a.BinSHA256 != hashData(data) {
+		t.Errorf("attached %+v", a)
+	}
+
+	// Attaching it again to the same revision updates the note
+	if _, err := AttachLog(file, log, "4th gear"); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSidecar(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Attachments) != 1 || s.Attachments[0].Note != "4th gear" {
+		t.Fatalf("attachments after re-attaching: %+v", s.Attachments)
+	}
+
+	// Another revision of the binary gets its own entry
+	data[0] ^= 0xFF
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AttachLog(file, log, ""); err != nil {
+		t.Fatal(err)
+	}
+	s, _ = LoadSidecar(file)
+	if len(s.Attachments) != 2 || s.Attachments[1].BinSHA256 != hashData(data) {
+		t.Fatalf("attachments after a new revision: %+v", s.Attachments)
+	}
+
+	if _, err := AttachLog(file, filepath.Dir(log), ""); err == nil {
+		t.Error("a directory was attached")
+	}
+}
+
+func TestAttachmentStatus(t *testing.T) {
+	file, _ := writeImage(t)
+	log := writeLog(t, "run.csv", "a")
+	a, err := AttachLog(file, log, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Status(); got != "ok" {
+		t.Errorf("status %s, want ok", got)
+	}
+	os.WriteFile(log, []byte("b"), 0644)
+	if got := a.Status(); got != "modified" {
+		t.Errorf("status of a changed log %s, want modified", got)
+	}
+	os.Remove(log)
+	if got := a.Status(); got != "missing" {
+		t.Errorf("status of a deleted log %s, want missing", got)
+	}
+}
+
+func TestWriteTuneArchive(t *testing.T) {
+	file, data := writeImage(t)
+	logs := []string{
+		writeLog(t, "run.csv", "first"),
+		writeLog(t, "run.csv", "second"),
+		writeLog(t, "gone.csv", "deleted"),
+		writeLog(t, "dyno.csv", "changed"),
+	}
+	for _, log := range logs {
+		if _, err := AttachLog(file, log, filepath.Base(filepath.Dir(log))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Remove(logs[2])
+	os.WriteFile(logs[3], []byte("edited"), 0644)
+
+	t.Run("listed", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "tune.zip")
+		if _, err := WriteTuneArchive(file, archive, false); err != nil {
+			t.Fatal(err)
+		}
+		files := readZip(t, archive)
+		if names := sortedKeys(files); !slices.Equal(names, []string{"ecu.bin", "ecu.bin.meta.json", "manifest.json"}) {
+			t.Errorf("archive holds %v", names)
+		}
+	})
+
+	t.Run("embedded", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "tune.zip")
+		manifest, err := WriteTuneArchive(file, archive, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := readZip(t, archive)
+		want := map[string]string{"logs/run.csv": "first", "logs/2-run.csv": "second"}
+		if names := sortedKeys(files); !slices.Equal(names, []string{"ecu.bin", "ecu.bin.meta.json", "logs/2-run.csv", "logs/run.csv", "manifest.json"}) {
+			t.Fatalf("archive holds %v", names)
+		}
+		for name, content := range want {
+			if string(files[name]) != content {
+				t.Errorf("%s holds %q, want %q", name, files[name], content)
+			}
+		}
+		if string(files["ecu.bin"]) != string(data) {
+			t.Error("the archived binary differs from the file")
+		}
+
+		var stored ArchiveManifest
+		if err := json.Unmarshal(files[ArchiveManifestName], &stored); err != nil {
+			t.Fatal(err)
+		}
+		if stored.File != "ecu.bin" || stored.SHA256 != hashData(data) || len(stored.Logs) != len(logs) {
+			t.Fatalf("manifest %+v", stored)
+		}
+		wantLogs := []struct{ status, embedded string }{
+			{"ok", "logs/run.csv"}, {"ok", "logs/2-run.csv"}, {"missing", ""}, {"modified", ""},
+		}
+		for i, w := range wantLogs {
+			got := stored.Logs[i]
+			if got.Status != w.status || got.Embedded != w.embedded || got.Path != logs[i] {
+				t.Errorf("log %d listed as %s, %q, %q; want %s, %q", i, got.Path, got.Status, got.Embedded, w.status, w.embedded)
+			}
+		}
+		if manifest.Logs[0].Note == "" {
+			t.Error("the manifest lost the notes")
+		}
+	})
+}
+
+// readZip returns the contents of every file in a zip archive by name
+func readZip(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
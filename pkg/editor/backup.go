@@ -0,0 +1,86 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is the timestamp suffix used in backup, snapshot and
+// safe copy filenames
+const backupTimeFormat = "20060102_150405"
+
+// backupNameFormat is the timestamp of new backup names, to the
+// microsecond so backups made within one second get their own names.
+// backupTimeFormat parses both, since Go accepts a fractional second
+// after the seconds field, so older backups are still listed.
+const backupNameFormat = "20060102_150405.000000"
+
+// Backup describes a timestamped backup of an ECU file
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// ListBackups returns all backups of filename, oldest first. Files whose
+// suffix is not a valid backup timestamp are ignored.
+func ListBackups(filename string) ([]Backup, error) {
+	dir := filepath.Dir(filename)
+	prefix := filepath.Base(filename) + ".backup_"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimPrefix(name, prefix), time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(dir, name), Time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.Before(backups[j].Time)
+	})
+
+	return backups, nil
+}
+
+// SafeCopy copies an ECU file into dir as "<name>_edit_<timestamp><ext>"
+// so edits can proceed when the original is read-only or locked. The copy
+// is always writable, whatever the permissions of the original.
+func SafeCopy(filename, dir string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext) + "_edit_" + time.Now().Format(backupTimeFormat) + ext
+	dest := filepath.Join(dir, name)
+
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(dest)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(dest)
+		return "", err
+	}
+	return dest, nil
+}
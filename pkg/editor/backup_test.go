@@ -0,0 +1,143 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+func TestBackupsWithinOneSecond(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ecu.bin")
+	if err := os.WriteFile(file, []byte{0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each backup keeps its own contents, however quickly they follow
+	var names []string
+	for i := range 20 {
+		name, err := CreateBackupFrom(file, []byte{byte(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	for i, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, []byte{byte(i)}) {
+			t.Errorf("backup %d (%s) holds %v, want [%d]", i, filepath.Base(name), data, i)
+		}
+	}
+
+	backups, err := ListBackups(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != len(names) {
+		t.Fatalf("listed %d backups, want %d", len(backups), len(names))
+	}
+	for i, b := range backups {
+		if b.Path != names[i] {
+			t.Errorf("backup %d listed as %s, want %s in creation order", i, filepath.Base(b.Path), filepath.Base(names[i]))
+		}
+	}
+}
+
+func TestBackupNeverOverwrites(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ecu.bin")
+	at := time.Date(2026, 3, 1, 12, 30, 45, 123456000, time.Local)
+	taken := file + ".backup_20260301_123045.123456"
+	if err := os.WriteFile(taken, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, name, err := createBackupFile(file, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if want := file + ".backup_20260301_123045.123457"; name != want {
+		t.Errorf("backup named %s, want %s", filepath.Base(name), filepath.Base(want))
+	}
+	if data, _ := os.ReadFile(taken); string(data) != "keep" {
+		t.Errorf("the existing backup was overwritten with %q", data)
+	}
+
+	// Backups named before names carried microseconds are still listed
+	legacy := file + ".backup_20260301_123044"
+	if err := os.WriteFile(legacy, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := ListBackups(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for _, b := range backups {
+		listed = append(listed, filepath.Base(b.Path))
+	}
+	want := []string{filepath.Base(legacy), filepath.Base(taken), filepath.Base(name)}
+	if fmt.Sprint(listed) != fmt.Sprint(want) {
+		t.Errorf("listed %v, want %v", listed, want)
+	}
+}
+
+// A batch of 50 edits in one session makes one backup, from the session's
+// snapshot, and writes the file once
+func BenchmarkBatchCommit(b *testing.B) {
+	cfg := models.MapConfigs[0]
+	saved := AfterWrite
+	b.Cleanup(func() { AfterWrite = saved })
+	for b.Loop() {
+		b.StopTimer()
+		file, data := writeImage(b)
+		s, err := NewSession(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := range 50 {
+			row, col := i/cfg.Cols, i%cfg.Cols
+			offset := cfg.Offset + int64(i)
+			old := int64(data[offset])
+			s.Add(Operation{Name: fmt.Sprintf("cell %d", i), Plan: func([]byte) ([]CellChange, error) {
+				return []CellChange{{Map: cfg.Name, Row: row, Col: col, Offset: offset, DataType: cfg.DataType,
+					OldRaw: old, NewRaw: old ^ 1, OldValue: cfg.ToReal(old), NewValue: cfg.ToReal(old ^ 1)}}, nil
+			}})
+		}
+		writes := 0
+		AfterWrite = func(string, []byte) { writes++ }
+		b.StartTimer()
+
+		report, err := s.Commit()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		backups, err := ListBackups(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(backups) != 1 || report.Backup == "" {
+			b.Fatalf("%d backups (%q), want 1", len(backups), report.Backup)
+		}
+		if backup, err := os.ReadFile(report.Backup); err != nil || !bytes.Equal(backup, data) {
+			b.Fatalf("backup doesn't hold the original file: %v", err)
+		}
+		if writes != 1 {
+			b.Fatalf("file written %d times, want once", writes)
+		}
+		if changed := report.Results; len(changed) != 50 {
+			b.Fatalf("%d operations applied, want 50", len(changed))
+		}
+		s.Close()
+		b.StartTimer()
+	}
+}
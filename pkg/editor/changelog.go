@@ -0,0 +1,97 @@
+package editor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// ChangelogEntry records one operation performed on an ECU file
+type ChangelogEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"` // extract, inject, edit, restore, save-as
+	Detail string    `json:"detail,omitempty"`
+	Backup string    `json:"backup,omitempty"`
+	// BackupSHA256 is the hash the backup was verified against under
+	// -require-backup
+	BackupSHA256 string `json:"backup_sha256,omitempty"`
+	// NoBackup records a write made with -no-backup
+	NoBackup bool `json:"no_backup,omitempty"`
+	// Linked names the other file of a lock-step edit (-also-edit)
+	Linked string `json:"linked,omitempty"`
+	// ChecksumFixed records that the write stored the image checksum, as
+	// the change named ChecksumChange; ChecksumStale that it left a
+	// mismatched one (see ChecksumOnSave)
+	ChecksumFixed bool `json:"checksum_fixed,omitempty"`
+	ChecksumStale bool `json:"checksum_stale,omitempty"`
+	// Mirrored is the number of copies of a mirrored dump the write kept
+	// equal; MirrorStale records that it wrote only the first (see
+	// MirrorWrites)
+	Mirrored    int  `json:"mirrored,omitempty"`
+	MirrorStale bool `json:"mirror_stale,omitempty"`
+	// CodeWarning names the unconfirmed maps written although their bytes
+	// looked like program code (see CodeWarning)
+	CodeWarning []string     `json:"code_warning,omitempty"`
+	Offset      int64        `json:"offset"`
+	Length      int64        `json:"length"`
+	Changes     []CellChange `json:"changes,omitempty"`
+}
+
+// ChangelogPath returns the changelog file kept next to an ECU file
+func ChangelogPath(filename string) string {
+	return filename + ".changelog.jsonl"
+}
+
+// AppendChangelog adds an entry to the file's changelog, one JSON object
+// per line
+func AppendChangelog(filename string, entry ChangelogEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(ChangelogPath(filename), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadChangelog returns the file's changelog entries, oldest first. A
+// missing changelog is not an error.
+func ReadChangelog(filename string) ([]ChangelogEntry, error) {
+	f, err := os.Open(ChangelogPath(filename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []ChangelogEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e ChangelogEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
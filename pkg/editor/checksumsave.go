@@ -0,0 +1,156 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// ChecksumPolicy decides whether saving an edit also stores the image
+// checksum. It only matters when the active profile has a checksum (see
+// models.ChecksumConfig).
+type ChecksumPolicy string
+
+const (
+	// ChecksumAsk reports a stale checksum on save and asks whether to fix
+	// it (the default)
+	ChecksumAsk ChecksumPolicy = "ask"
+	// ChecksumAlways stores the computed checksum with every save
+	ChecksumAlways ChecksumPolicy = "always"
+	// ChecksumNever leaves the checksum bytes alone, for flashing tools
+	// that recalculate it themselves
+	ChecksumNever ChecksumPolicy = "never"
+)
+
+// ChecksumPolicies lists the policies in the order settings show them
+var ChecksumPolicies = []ChecksumPolicy{ChecksumAsk, ChecksumAlways, ChecksumNever}
+
+// ChecksumOnSave is the active checksum policy
+var ChecksumOnSave = ChecksumAsk
+
+// ChecksumChange is the CellChange.Map name of a change storing the image
+// checksum, so changelogs and diffs can tell those bytes from map edits
+const ChecksumChange = "Checksum"
+
+// ParseChecksumPolicy validates a checksum policy name
+func ParseChecksumPolicy(s string) (ChecksumPolicy, error) {
+	for _, p := range ChecksumPolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid checksum policy %q: expected ask, always or never", s)
+}
+
+// ChecksumStatus is the image checksum of the contents about to be saved
+type ChecksumStatus struct {
+	Algorithm string
+	Stored    int64
+	Computed  int64
+	// Fix is the change that stores Computed
+	Fix CellChange
+}
+
+// OK reports whether the stored checksum matches
+func (c ChecksumStatus) OK() bool { return c.Stored == c.Computed }
+
+// String describes the stored and computed checksums in hex
+func (c ChecksumStatus) String() string {
+	digits := 2 * models.DataTypeSize(c.Fix.DataType)
+	return fmt.Sprintf("%s checksum at 0x%04X: stored 0x%0*X, computed 0x%0*X",
+		c.Algorithm, c.Fix.Offset, digits, c.Stored, digits, c.Computed)
+}
+
+// PlanChecksum returns the checksum status of an image's contents, or nil
+// when the active profile has no checksum. pkg/checksum implements it; it
+// is a hook because that package builds on this one.
+var PlanChecksum func(data []byte) (*ChecksumStatus, error)
+
+// AskChecksum is consulted under ChecksumAsk when a save would leave the
+// checksum stale. It returns true to store the computed checksum with the
+// save. When it is nil or returns false, the save goes ahead, the stale
+// checksum is recorded in the report and changelog, and FixChecksum can
+// store it later.
+var AskChecksum func(filename string, status ChecksumStatus) bool
+
+// checksumActive reports whether saves have to consider the checksum
+func checksumActive() bool {
+	return ChecksumOnSave != ChecksumNever && PlanChecksum != nil
+}
+
+// planChecksum applies the checksum policy to a working copy about to be
+// saved as filename. It records the status in the report and returns the
+// change fixing the checksum, or nil to leave it.
+func planChecksum(filename string, work []byte, report *Report) (*CellChange, error) {
+	if !checksumActive() {
+		return nil, nil
+	}
+	status, err := PlanChecksum(work)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	report.Checksum = status
+	if status.OK() {
+		return nil, nil
+	}
+	if ChecksumOnSave == ChecksumAlways || (AskChecksum != nil && AskChecksum(filename, *status)) {
+		report.ChecksumFixed = true
+		fix := status.Fix
+		return &fix, nil
+	}
+	report.ChecksumStale = true
+	return nil, nil
+}
+
+// CheckChecksum returns the checksum status of a file, or nil when the
+// active profile has no checksum
+func CheckChecksum(filename string) (*ChecksumStatus, error) {
+	if PlanChecksum == nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return PlanChecksum(data)
+}
+
+// FixChecksum stores the computed checksum in a session of its own, for a
+// checksum an earlier save left stale. It writes nothing if the checksum
+// already matches.
+func FixChecksum(filename string) (*Report, error) {
+	if PlanChecksum == nil {
+		return nil, reader.NewError(reader.ErrNotFound, "no checksum algorithm is configured")
+	}
+	s, err := NewSession(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	s.Add(Operation{Name: "fix checksum", Plan: func(data []byte) ([]CellChange, error) {
+		status, err := PlanChecksum(data)
+		if err != nil {
+			return nil, err
+		}
+		if status == nil {
+			return nil, reader.NewError(reader.ErrNotFound, "no checksum algorithm is configured")
+		}
+		if status.OK() {
+			return nil, nil
+		}
+		return []CellChange{status.Fix}, nil
+	}})
+	return s.Commit()
+}
+
+// hasChecksumChange reports whether changes store the checksum
+func hasChecksumChange(changes []CellChange) bool {
+	for _, c := range changes {
+		if c.Map == ChecksumChange {
+			return true
+		}
+	}
+	return false
+}
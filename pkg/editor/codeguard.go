@@ -0,0 +1,105 @@
+package editor
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
+)
+
+// CodeConfirmPhrase is what the user has to type to write to an
+// unconfirmed map whose bytes look like program code
+const CodeConfirmPhrase = "yes, edit anyway"
+
+// ConfirmCodeEdit asks whether to write to an unconfirmed map of filename
+// whose bytes look like program code (see CodeWarning), and returns true
+// only if the user typed CodeConfirmPhrase. The CLI sets it to a prompt.
+// When it is nil, as in the web server and the GUI, which ask before the
+// edit starts and call AcknowledgeCode, such writes are refused.
+var ConfirmCodeEdit func(filename, mapName string, check scanner.CodeCheck) bool
+
+// acknowledgedCode holds the maps the user agreed to edit in this run, by
+// file
+var acknowledgedCode = struct {
+	sync.Mutex
+	maps map[string][]string
+}{maps: make(map[string][]string)}
+
+// AcknowledgeCode records that the user agreed to edit mapName of filename
+// despite its code warning, so the next write to it goes ahead
+func AcknowledgeCode(filename, mapName string) {
+	acknowledgedCode.Lock()
+	defer acknowledgedCode.Unlock()
+	if !slices.Contains(acknowledgedCode.maps[filename], mapName) {
+		acknowledgedCode.maps[filename] = append(acknowledgedCode.maps[filename], mapName)
+	}
+}
+
+// acknowledgedNow reports whether the user agreed to edit mapName of
+// filename in this run
+func acknowledgedNow(filename, mapName string) bool {
+	acknowledgedCode.Lock()
+	defer acknowledgedCode.Unlock()
+	return slices.Contains(acknowledgedCode.maps[filename], mapName)
+}
+
+// acknowledgedBefore reports whether the changelog of filename records an
+// edit of mapName made despite its code warning
+func acknowledgedBefore(filename, mapName string) bool {
+	entries, _ := ReadChangelog(filename)
+	return slices.ContainsFunc(entries, func(e ChangelogEntry) bool {
+		return slices.Contains(e.CodeWarning, mapName)
+	})
+}
+
+// likelyCode runs scanner.CheckCode on an unconfirmed map. Confirmed maps
+// are never checked.
+func likelyCode(data []byte, cfg models.MapConfig) (scanner.CodeCheck, bool) {
+	if !cfg.Unconfirmed {
+		return scanner.CodeCheck{}, false
+	}
+	check, err := scanner.CheckCode(data, cfg)
+	return check, err == nil && check.LikelyCode
+}
+
+// CodeWarning runs scanner.CheckCode on cfg in data, the contents of
+// filename, and returns its verdict and true if the map is unconfirmed,
+// looks like program code and the user hasn't agreed to edit it yet
+func CodeWarning(filename string, data []byte, cfg models.MapConfig) (scanner.CodeCheck, bool) {
+	check, likely := likelyCode(data, cfg)
+	if !likely || acknowledgedNow(filename, cfg.Name) || acknowledgedBefore(filename, cfg.Name) {
+		return check, false
+	}
+	return check, true
+}
+
+// confirmCodeEdits checks every map changes write to, in data, the
+// contents of filename before the edit. An unconfirmed map that looks like
+// program code needs the user's agreement, given earlier in this run
+// (AcknowledgeCode) or now through ConfirmCodeEdit, unless the changelog
+// records an earlier edit made despite the warning. It returns the maps
+// to mark in the changelog, or an ErrLikelyCode error for the first one
+// refused.
+func confirmCodeEdits(filename string, data []byte, changes []CellChange) ([]string, error) {
+	var agreed []string
+	for _, name := range changedNames(changes) {
+		i := slices.IndexFunc(models.MapConfigs, func(cfg models.MapConfig) bool { return cfg.Name == name })
+		if i < 0 {
+			continue // a parameter or the checksum
+		}
+		check, likely := likelyCode(data, models.MapConfigs[i])
+		if !likely || acknowledgedBefore(filename, name) {
+			continue
+		}
+		if !acknowledgedNow(filename, name) {
+			if ConfirmCodeEdit == nil || !ConfirmCodeEdit(filename, name, check) {
+				return nil, reader.NewError(reader.ErrLikelyCode, "%s is unconfirmed and %s; not written", name, check)
+			}
+			AcknowledgeCode(filename, name)
+		}
+		agreed = append(agreed, name)
+	}
+	return agreed, nil
+}
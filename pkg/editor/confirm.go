@@ -0,0 +1,67 @@
+package editor
+
+import "fmt"
+
+// ConfirmPolicy controls which confirmation prompts are shown before
+// modifying a binary. The initial risk acknowledgement of interactive edit
+// mode is never skipped.
+type ConfirmPolicy string
+
+const (
+	// ConfirmFull shows every confirmation (the default)
+	ConfirmFull ConfirmPolicy = "full"
+	// ConfirmOnSaveOnly skips the extra confirmation after an edit or
+	// preset dialog was accepted, keeping only the prompt before writing
+	ConfirmOnSaveOnly ConfirmPolicy = "confirm-on-save-only"
+	// ConfirmNever writes without asking, for scripted use
+	ConfirmNever ConfirmPolicy = "never"
+)
+
+// ConfirmPolicies lists the policies in order of decreasing caution
+var ConfirmPolicies = []ConfirmPolicy{ConfirmFull, ConfirmOnSaveOnly, ConfirmNever}
+
+// Confirmation is the active confirmation policy
+var Confirmation = ConfirmFull
+
+// ConfirmKind classifies a confirmation prompt
+type ConfirmKind int
+
+const (
+	// ConfirmReview is a second confirmation after the user already
+	// accepted an edit, e.g. "Save changes?" after an edit dialog
+	ConfirmReview ConfirmKind = iota
+	// ConfirmSave is the only gate before a write
+	ConfirmSave
+)
+
+// ParseConfirmPolicy validates a confirmation policy name
+func ParseConfirmPolicy(s string) (ConfirmPolicy, error) {
+	for _, p := range ConfirmPolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid confirmation policy %q: expected full, confirm-on-save-only or never", s)
+}
+
+// NeedsConfirm reports whether the active policy asks for this kind of
+// confirmation
+func NeedsConfirm(kind ConfirmKind) bool {
+	switch Confirmation {
+	case ConfirmNever:
+		return false
+	case ConfirmOnSaveOnly:
+		return kind == ConfirmSave
+	default:
+		return true
+	}
+}
+
+// Confirm asks a yes/no question through the prompter, or returns true
+// without asking when the active policy skips this kind of confirmation
+func Confirm(p Prompter, kind ConfirmKind, question string) bool {
+	if !NeedsConfirm(kind) {
+		return true
+	}
+	return p.Confirm(question)
+}
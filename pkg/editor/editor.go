@@ -1,47 +1,201 @@
 package editor
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
 	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
 )
 
-// CreateBackup creates a timestamped backup of the file
+// CreateBackup creates a timestamped backup of the file, streaming it so
+// large images are never held in memory whole. With reader.NoBackup set it
+// makes none and returns "". With reader.RequireBackup set it reads the
+// file whole and makes a verified backup, see CreateBackupFrom.
 func CreateBackup(filename string) (string, error) {
-	data, err := os.ReadFile(filename)
+	if reader.NoBackup {
+		return "", nil
+	}
+	if reader.RequireBackup {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return "", err
+		}
+		return verifiedBackup(filename, data)
+	}
+	src, err := os.Open(filename)
 	if err != nil {
 		return "", err
 	}
+	defer src.Close()
 
-	timestamp := time.Now().Format("20060102_150405")
-	backupName := filename + ".backup_" + timestamp
-	err = os.WriteFile(backupName, data, 0644)
+	dst, backupName, err := createBackupFile(filename, time.Now())
 	if err != nil {
 		return "", err
 	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(backupName)
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(backupName)
+		return "", err
+	}
 
 	return backupName, nil
 }
 
+// CreateBackupFrom backs up a file from data, the file's contents already
+// read by the caller, saving a second read of the file. data must match
+// what is on disk, e.g. a session snapshot checked with checkUnchanged. A
+// nil data falls back to CreateBackup. With reader.RequireBackup set the
+// backup is verified before it is returned.
+func CreateBackupFrom(filename string, data []byte) (string, error) {
+	if data == nil {
+		return CreateBackup(filename)
+	}
+	if reader.NoBackup {
+		return "", nil
+	}
+	if reader.RequireBackup {
+		return verifiedBackup(filename, data)
+	}
+	return writeBackup(filename, data)
+}
+
+// verifiedBackup returns a backup of filename holding exactly data, for
+// reader.RequireBackup. A backup made earlier the same day with the same
+// SHA-256 is reused; otherwise a new one is written and read back, and an
+// ErrBackupUnverified error stops the write if it doesn't match.
+func verifiedBackup(filename string, data []byte) (string, error) {
+	hash := hashData(data)
+	if backup := sameDayBackup(filename, hash); backup != "" {
+		return backup, nil
+	}
+	backupName, err := writeBackup(filename, data)
+	if err != nil {
+		return "", reader.NewError(reader.ErrBackupUnverified, "backup of %s could not be written: %v", filename, err)
+	}
+	if backupWritten != nil {
+		backupWritten(backupName)
+	}
+	if err := reader.VerifyBackup(backupName, hash); err != nil {
+		return "", err
+	}
+	return backupName, nil
+}
+
+// backupWritten, if set, is called with the path of each backup
+// verifiedBackup wrote, before it is read back. Tests use it to damage
+// the copy the way a failing disk would.
+var backupWritten func(path string)
+
+// sameDayBackup returns the newest backup of filename made today whose
+// SHA-256 is hash, or "". Only regular files count.
+func sameDayBackup(filename, hash string) string {
+	backups, err := ListBackups(filename)
+	if err != nil {
+		return ""
+	}
+	y, m, d := time.Now().Date()
+	for _, b := range slices.Backward(backups) {
+		if by, bm, bd := b.Time.Date(); by != y || bm != m || bd != d {
+			break
+		}
+		if info, err := os.Stat(b.Path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if h, err := reader.HashFile(b.Path); err == nil && h == hash {
+			return b.Path
+		}
+	}
+	return ""
+}
+
+// backupHash returns the SHA-256 recorded for a backup of data: set only
+// when reader.RequireBackup verified it
+func backupHash(backup string, data []byte) string {
+	if !reader.RequireBackup || backup == "" {
+		return ""
+	}
+	return hashData(data)
+}
+
+// maxBackupNames bounds how many taken names createBackupFile skips
+const maxBackupNames = 1000
+
+// createBackupFile creates a new, empty backup file of filename named
+// for time t and returns it open for writing. It never replaces an
+// existing file: while the name is taken, by a backup made in the same
+// microsecond or anything else, the timestamp moves on by a microsecond.
+func createBackupFile(filename string, t time.Time) (*os.File, string, error) {
+	for range maxBackupNames {
+		name := filename + ".backup_" + t.Format(backupNameFormat)
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, name, err
+		}
+		t = t.Add(time.Microsecond)
+	}
+	return nil, "", fmt.Errorf("no free backup name for %s", filename)
+}
+
+// writeBackup writes data to a new backup file of filename and returns
+// its path. A backup that can't be written completely is removed.
+func writeBackup(filename string, data []byte) (string, error) {
+	f, name, err := createBackupFile(filename, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(name)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
+// PrintBackup reports the backup made before a write, or that -no-backup
+// skipped it. A backup verified by -require-backup is shown with its
+// SHA-256.
+func PrintBackup(backup string) {
+	switch {
+	case backup != "" && reader.RequireBackup:
+		hash, _ := reader.HashFile(backup)
+		pterm.Success.Printf("Verified backup: %s (SHA-256 %s)\n", backup, hash)
+	case backup != "":
+		pterm.Success.Printf("Backup created: %s\n", backup)
+	case reader.NoBackup:
+		pterm.Warning.Println("No backup made (-no-backup)")
+	}
+}
+
 // InteractiveEdit provides an interactive menu for editing ECU maps
-func InteractiveEdit(filename string, dryRun bool) {
+func InteractiveEdit(prompt Prompter, filename string, dryRun bool) {
 	pterm.DefaultHeader.WithFullWidth().
 		WithBackgroundStyle(pterm.NewStyle(pterm.BgRed)).
 		WithTextStyle(pterm.NewStyle(pterm.FgBlack)).
-		Println("⚠️  INTERACTIVE EDIT MODE - USE WITH EXTREME CAUTION  ⚠️")
+		Println(i18n.T("cli.edit.header"))
 
-	pterm.Warning.Println("Modifying ECU calibration can cause engine damage, unsafe driving conditions, warranty void, and legal issues.")
+	pterm.Warning.Println(i18n.T("cli.edit.warning"))
 
-	result, _ := pterm.DefaultInteractiveConfirm.Show("Do you understand the risks and want to proceed?")
-	if !result {
-		pterm.Info.Println("Edit cancelled.")
+	// The risk acknowledgement is never skipped by the confirmation policy
+	if !prompt.Confirm(i18n.T("cli.edit.risks")) {
+		pterm.Info.Println(i18n.T("cli.edit.cancelled"))
 		return
 	}
 
@@ -50,125 +204,161 @@ func InteractiveEdit(filename string, dryRun bool) {
 		"Edit Fuel Map Cell",
 		"Edit Ignition Map Cell",
 		"Scale Entire Map",
+		"Adjust Fuel-Cut Columns",
 		"Exit",
 	}
 
-	selectedOption, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(options).
-		Show("Select what to edit:")
-
-	switch selectedOption {
+	switch prompt.Select("Select what to edit:", options) {
 	case "Edit Rev Limiter":
-		EditRevLimiter(filename, dryRun)
+		EditRevLimiter(prompt, filename, dryRun)
 	case "Edit Fuel Map Cell":
-		EditMapCell(filename, models.MapConfigs[0])
+		EditMapCell(prompt, filename, models.MapConfigs[0])
 	case "Edit Ignition Map Cell":
-		EditMapCell(filename, models.MapConfigs[1])
+		EditMapCell(prompt, filename, models.MapConfigs[1])
 	case "Scale Entire Map":
-		ScaleMap(filename, dryRun)
+		ScaleMap(prompt, filename, dryRun)
+	case "Adjust Fuel-Cut Columns":
+		AdjustFuelCut(prompt, filename, 0, true, dryRun)
 	case "Exit":
 		pterm.Info.Println("Exiting edit mode.")
 		return
 	}
 }
 
-// EditRevLimiter allows editing the rev limiter value
-func EditRevLimiter(filename string, dryRun bool) {
+// RevLimiterParam is the parameter "the rev limit" refers to; parameters
+// linked to it, such as a hard cut, are moved along with it
+const RevLimiterParam = "Rev Limiter"
+
+// EditRevLimiter allows editing the rev limiter value. Since M2.1 cuts
+// fuel with zeroed top columns of the fuel map, moving the limit without
+// moving the cut makes the engine buck, so the fuel-cut columns are shown
+// and offered in the same session.
+func EditRevLimiter(prompt Prompter, filename string, dryRun bool) {
 	pterm.Info.Println("Rev Limiter Editor")
 	pterm.Warning.Println("Setting too high can cause catastrophic engine damage!")
 
-	currentValue, _ := pterm.DefaultInteractiveTextInput.Show("Enter new RPM limit (e.g., 6500)")
-
-	rpm := 0
-	fmt.Sscanf(currentValue, "%d", &rpm)
-
-	if rpm < 3000 || rpm > 7500 {
-		pterm.Error.Println("Invalid RPM range. Must be between 3000-7500.")
+	rpmStr, err := prompt.Input("Enter new RPM limit (e.g., 6500)", intRange(3000, 7500, "Invalid RPM range. Must be between 3000-7500."))
+	if err != nil {
+		pterm.Error.Println(err)
 		return
 	}
+	rpm, _ := strconv.Atoi(rpmStr)
 
-	if dryRun {
-		pterm.Warning.Println("DRY RUN - No changes made")
-		return
+	if !dryRun {
+		if err := reader.CheckWritable(filename); err != nil {
+			pterm.Error.Println(reader.DescribeWriteError(err))
+			return
+		}
 	}
 
-	result, _ := pterm.DefaultInteractiveConfirm.Show("Write this change to file?")
-	if !result {
-		pterm.Info.Println("Cancelled.")
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		pterm.Error.Println(err)
 		return
 	}
-
-	backup, err := CreateBackup(filename)
+	m, err := reader.ReadMapFromBytes(data, fuelMap())
 	if err != nil {
-		pterm.Error.Printf("Failed to create backup: %v\n", err)
+		pterm.Error.Println(err)
 		return
 	}
-	pterm.Success.Printf("Backup created: %s\n", backup)
-
-	data, _ := os.ReadFile(filename)
-	scaled := uint8(rpm / 50)
-	if len(data) > 0x7000 {
-		data[0x7000] = scaled
-	}
-
-	err = os.WriteFile(filename, data, 0644)
+	shift, err := askFuelCutShift(prompt, data, SuggestFuelCutShift(FindFuelCut(m), float64(rpm)))
 	if err != nil {
-		pterm.Error.Printf("Failed to write: %v\n", err)
+		pterm.Error.Println(err)
 		return
 	}
 
-	pterm.Success.Println("Rev limiter updated successfully!")
+	// Linked parameters such as a hard cut move along with the limit
+	ops := []Operation{RevLimitOperation(float64(rpm))}
+	if shift != 0 {
+		ops = append(ops, FuelCutOperation(shift))
+	}
+	commitOperations(prompt, filename, dryRun, ops...)
 }
 
 // EditMapCell allows editing a specific cell in a map (CLI version)
-func EditMapCell(filename string, cfg models.MapConfig) {
+func EditMapCell(prompt Prompter, filename string, cfg models.MapConfig) {
 	pterm.Info.Printf("Editing %s (%dx%d)\n", cfg.Name, cfg.Rows, cfg.Cols)
 
-	rowStr, _ := pterm.DefaultInteractiveTextInput.Show(fmt.Sprintf("Enter row (0-%d)", cfg.Rows-1))
-	colStr, _ := pterm.DefaultInteractiveTextInput.Show(fmt.Sprintf("Enter column (0-%d)", cfg.Cols-1))
-
+	rowStr, err := prompt.Input(fmt.Sprintf("Enter row (0-%d)", cfg.Rows-1), intRange(0, cfg.Rows-1, "Invalid cell coordinates"))
+	if err != nil {
+		pterm.Error.Println(err)
+		return
+	}
+	colStr, err := prompt.Input(fmt.Sprintf("Enter column (0-%d)", cfg.Cols-1), intRange(0, cfg.Cols-1, "Invalid cell coordinates"))
+	if err != nil {
+		pterm.Error.Println(err)
+		return
+	}
 	row, _ := strconv.Atoi(rowStr)
 	col, _ := strconv.Atoi(colStr)
 
-	if row < 0 || row >= cfg.Rows || col < 0 || col >= cfg.Cols {
-		pterm.Error.Println("Invalid cell coordinates")
+	if err := reader.CheckWritable(filename); err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
 		return
 	}
 
-	f, _ := os.Open(filename)
-	cellOffset := cfg.Offset + int64(row*cfg.Cols+col)
-	f.Seek(cellOffset, io.SeekStart)
-	var currentRaw uint8
-	binary.Read(f, binary.LittleEndian, &currentRaw)
-	f.Close()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read file: %v\n", err)
+		return
+	}
+	size := models.DataTypeSize(cfg.DataType)
+	cellOffset := cfg.Offset + int64((row*cfg.Cols+col)*size)
+	if cellOffset+int64(size) > int64(len(data)) {
+		pterm.Error.Println("Cell offset out of bounds")
+		return
+	}
+	currentRaw := cfg.DecodeRaw(data[cellOffset:])
 
-	currentValue := float64(currentRaw)*cfg.Scale + cfg.Offset2
-	pterm.Info.Printf("Current value at [%d,%d]: %.2f %s (raw: 0x%02X)\n", row, col, currentValue, cfg.Unit, currentRaw)
+	currentValue := cfg.ToReal(currentRaw)
+	pterm.Info.Printf("Current value at [%d,%d]: %.2f %s (raw: %d)\n", row, col, currentValue, cfg.Unit, currentRaw)
 
-	newValueStr, _ := pterm.DefaultInteractiveTextInput.Show("Enter new value")
+	newValueStr, err := prompt.Input("Enter new value", isFloat)
+	if err != nil {
+		pterm.Error.Println(err)
+		return
+	}
 	newValue, _ := strconv.ParseFloat(newValueStr, 64)
 
-	newRaw := uint8((newValue - cfg.Offset2) / cfg.Scale)
-	pterm.Info.Printf("New value: %.2f %s (raw: 0x%02X)\n", newValue, cfg.Unit, newRaw)
+	newRaw, clamped := cfg.ToRaw(newValue)
+	if clamped {
+		pterm.Warning.Printf("%.2f is outside the representable range, clamped to %.2f\n", newValue, cfg.ToReal(newRaw))
+	}
+	pterm.Info.Printf("New value: %.2f %s (raw: %d)\n", cfg.ToReal(newRaw), cfg.Unit, newRaw)
+	if err := CheckCellRange(cfg, cfg.ToReal(newRaw)); err != nil && newRaw != currentRaw {
+		if !ForceRange {
+			pterm.Error.Println(err)
+			pterm.Info.Println("Use -force to write it anyway")
+			return
+		}
+		pterm.Warning.Printf("-force: %v\n", err)
+	}
 
-	result, _ := pterm.DefaultInteractiveConfirm.Show("Write this change?")
-	if !result {
-		pterm.Info.Println("Cancelled.")
+	if !Confirm(prompt, ConfirmSave, i18n.T("cli.confirm.change")) {
+		pterm.Info.Println(i18n.T("cli.cancelled"))
 		return
 	}
 
-	backup, _ := CreateBackup(filename)
-	pterm.Success.Printf("Backup created: %s\n", backup)
-
-	data, _ := os.ReadFile(filename)
-	data[cellOffset] = newRaw
-	os.WriteFile(filename, data, 0644)
-
+	// The session backs up, logs and replaces the file atomically, and
+	// handles the checksum, a linked file and mirrored copies
+	change := cellChange(cfg.Name, row, col, cellOffset, cfg.DataType, cfg.ByteOrder(), currentRaw, newRaw, cfg.ToReal)
+	report, err := applyChanges(filename, fmt.Sprintf("Edit %s [%d,%d]", cfg.Name, row, col), []CellChange{change})
+	PrintBackup(report.Backup)
+	report.PrintChecksum()
+	report.PrintMirror()
+	if err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return
+	}
+	if LinkedFile != "" {
+		pterm.Success.Printf("Cell updated in %s and %s\n", filename, LinkedFile)
+		return
+	}
 	pterm.Success.Println("Cell updated successfully!")
 }
 
 // ScaleMap scales an entire map by a multiplier
-func ScaleMap(filename string, dryRun bool) {
+func ScaleMap(prompt Prompter, filename string, dryRun bool) {
 	pterm.Info.Println("Scale an entire map by a multiplier")
 	pterm.Warning.Println("This modifies ALL cells in the selected map!")
 
@@ -178,21 +368,17 @@ func ScaleMap(filename string, dryRun bool) {
 	}
 	mapNames = append(mapNames, "Cancel")
 
-	selectedOption, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(mapNames).
-		Show("Select map to scale:")
-
-	if selectedOption == "Cancel" {
+	selectedOption := prompt.Select("Select map to scale:", mapNames)
+	if selectedOption == "" || selectedOption == "Cancel" {
 		return
 	}
 
-	multiplierStr, _ := pterm.DefaultInteractiveTextInput.Show("Enter multiplier (e.g., 1.1 for +10%, 0.9 for -10%)")
-	multiplier, _ := strconv.ParseFloat(multiplierStr, 64)
-
-	if multiplier < 0.5 || multiplier > 2.0 {
-		pterm.Error.Println("Multiplier out of safe range (0.5-2.0)")
+	multiplierStr, err := prompt.Input("Enter multiplier (e.g., 1.1 for +10%, 0.9 for -10%)", floatRange(0.5, 2.0, "Multiplier out of safe range (0.5-2.0)"))
+	if err != nil {
+		pterm.Error.Println(err)
 		return
 	}
+	multiplier, _ := strconv.ParseFloat(multiplierStr, 64)
 
 	// Find selected config
 	var selectedCfg models.MapConfig
@@ -204,158 +390,183 @@ func ScaleMap(filename string, dryRun bool) {
 	}
 
 	pterm.Info.Printf("Will multiply all values in %s by %.2f\n", selectedCfg.Name, multiplier)
-
-	if dryRun {
-		pterm.Warning.Println("DRY RUN - No changes made")
-		return
-	}
-
-	result, _ := pterm.DefaultInteractiveConfirm.Show("Apply this scaling?")
-	if !result {
-		pterm.Info.Println("Cancelled.")
-		return
-	}
-
-	backup, _ := CreateBackup(filename)
-	pterm.Success.Printf("Backup created: %s\n", backup)
-
-	data, _ := os.ReadFile(filename)
-	for i := 0; i < selectedCfg.Rows*selectedCfg.Cols; i++ {
-		cellOffset := int(selectedCfg.Offset) + i
-		oldVal := data[cellOffset]
-		newVal := uint8(float64(oldVal) * multiplier)
-		data[cellOffset] = newVal
-	}
-
-	os.WriteFile(filename, data, 0644)
-	pterm.Success.Println("Map scaled successfully!")
+	applyScale(prompt, filename, selectedCfg, multiplier, dryRun, "Apply this scaling?")
 }
 
-// ApplyPreset applies a predefined modification preset
-func ApplyPreset(filename, presetName string, dryRun bool) {
+// ApplyPreset applies a predefined modification preset. args holds
+// "key=value" arguments for parameterized presets from the registry.
+func ApplyPreset(prompt Prompter, filename, presetName, args string, dryRun bool) {
 	pterm.DefaultHeader.WithFullWidth().
 		WithBackgroundStyle(pterm.NewStyle(pterm.BgYellow)).
 		WithTextStyle(pterm.NewStyle(pterm.FgBlack)).
-		Println("PRESET MODIFICATION MODE")
+		Println(i18n.T("cli.preset.header"))
 
-	pterm.Warning.Println("Presets apply predefined changes. USE WITH CAUTION!")
+	pterm.Warning.Println(i18n.T("cli.preset.warning"))
 
 	switch presetName {
 	case "revlimit":
-		EditRevLimiter(filename, dryRun)
+		EditRevLimiter(prompt, filename, dryRun)
 	case "fuel-enrich":
-		applyFuelEnrichPreset(filename, dryRun)
+		applyFuelEnrichPreset(prompt, filename, dryRun)
 	default:
+		if p, ok := FindPreset(presetName); ok {
+			applyRegisteredPreset(prompt, filename, p, args, dryRun)
+			return
+		}
+		names := []string{"revlimit", "fuel-enrich"}
+		for _, p := range Presets {
+			names = append(names, p.Name)
+		}
 		pterm.Error.Printf("Unknown preset: %s\n", presetName)
-		pterm.Info.Println("Available presets: revlimit, fuel-enrich")
+		pterm.Info.Printf("Available presets: %s\n", strings.Join(names, ", "))
 	}
 }
 
-func applyFuelEnrichPreset(filename string, dryRun bool) {
-	pterm.Info.Println("Fuel Enrichment Preset: +5% across entire fuel map")
+func applyRegisteredPreset(prompt Prompter, filename string, p Preset, argStr string, dryRun bool) {
+	pterm.Info.Printf("%s: %s\n", p.Name, p.Description)
 
-	if dryRun {
-		pterm.Warning.Println("DRY RUN - Would increase fuel by 5%")
+	args, err := p.ParseArgs(argStr)
+	if err != nil {
+		pterm.Error.Printf("Invalid preset arguments: %v\n", err)
+		for _, param := range p.Params {
+			pterm.Info.Printf("  %s (%g-%g, default %g): %s\n", param.Name, param.Min, param.Max, param.Default, param.Description)
+		}
 		return
 	}
 
-	result, _ := pterm.DefaultInteractiveConfirm.Show("Apply +5% fuel enrichment?")
-	if !result {
-		pterm.Info.Println("Cancelled.")
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read file: %v\n", err)
 		return
 	}
 
-	backup, _ := CreateBackup(filename)
-	pterm.Success.Printf("Backup created: %s\n", backup)
+	changes, err := p.Plan(data, args)
+	if err != nil {
+		pterm.Error.Printf("Preset failed: %v\n", err)
+		return
+	}
+	if len(changes) == 0 {
+		pterm.Info.Println("No cells need changing.")
+		return
+	}
 
-	cfg := models.MapConfigs[0] // Main fuel map
-	data, _ := os.ReadFile(filename)
+	SortChanges(changes)
+	PrintChanges(changes)
+	pterm.Info.Print(i18n.T("cli.would_change", len(changes)))
 
-	for i := 0; i < cfg.Rows*cfg.Cols; i++ {
-		cellOffset := int(cfg.Offset) + i
-		oldVal := data[cellOffset]
-		newVal := uint8(float64(oldVal) * 1.05)
-		data[cellOffset] = newVal
+	if dryRun {
+		pterm.Warning.Println(i18n.T("cli.dry_run"))
+		return
 	}
 
-	os.WriteFile(filename, data, 0644)
-	pterm.Success.Println("Fuel enrichment applied!")
-}
+	if err := reader.CheckWritable(filename); err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return
+	}
 
-// WriteConfigParam writes a configuration parameter value to the ECU file
-func WriteConfigParam(filename string, param models.ConfigParam, value float64) error {
-	// Convert real value to raw
-	var rawValue interface{}
-	switch param.DataType {
-	case "uint8":
-		rawValue = uint8((value - param.Offset2) / param.Scale)
-	case "uint16":
-		rawValue = uint16((value - param.Offset2) / param.Scale)
-	case "int8":
-		rawValue = int8((value - param.Offset2) / param.Scale)
-	case "int16":
-		rawValue = int16((value - param.Offset2) / param.Scale)
-	default:
-		return fmt.Errorf("unsupported data type: %s", param.DataType)
+	if !Confirm(prompt, ConfirmSave, i18n.T("cli.confirm.changes")) {
+		pterm.Info.Println(i18n.T("cli.cancelled"))
+		return
 	}
 
-	// Read file
-	data, err := os.ReadFile(filename)
+	report, err := applyChanges(filename, "Preset "+p.Name, changes)
+	PrintBackup(report.Backup)
 	if err != nil {
-		return err
+		pterm.Error.Printf("Failed to write: %s\n", reader.DescribeWriteError(err))
+		return
 	}
+	pterm.Success.Printf("Preset %s applied!\n", p.Name)
+	report.PrintChecksum()
+	report.PrintMirror()
+}
 
-	// Check bounds
-	if int(param.Offset) >= len(data) {
-		return fmt.Errorf("offset 0x%X out of bounds", param.Offset)
-	}
+func applyFuelEnrichPreset(prompt Prompter, filename string, dryRun bool) {
+	pterm.Info.Println("Fuel Enrichment Preset: +5% across entire fuel map")
+	applyScale(prompt, filename, models.MapConfigs[0], 1.05, dryRun, "Apply +5% fuel enrichment?")
+}
 
-	// Write value
-	switch v := rawValue.(type) {
-	case uint8:
-		data[param.Offset] = v
-	case uint16:
-		binary.LittleEndian.PutUint16(data[param.Offset:], v)
-	case int8:
-		data[param.Offset] = byte(v)
-	case int16:
-		binary.LittleEndian.PutUint16(data[param.Offset:], uint16(v))
+// SetConfigParam writes one configuration parameter through a session,
+// checking its range, data type and links, so the file gets a backup and
+// changelog entry and is either fully written or left untouched. Every
+// parameter write goes through it.
+func SetConfigParam(filename, name string, value float64) (*Report, error) {
+	param, ok := models.FindConfigParam(name)
+	if !ok {
+		return nil, reader.NewError(reader.ErrNotFound, "parameter not found: %s", name)
+	}
+	if value < param.MinValue || value > param.MaxValue {
+		return nil, reader.NewError(reader.ErrValueOutOfBounds, "value %.2f out of range [%.2f, %.2f]", value, param.MinValue, param.MaxValue)
+	}
+	if _, clamped := param.ToRaw(value); clamped {
+		return nil, reader.NewError(reader.ErrValueOutOfBounds, "value %.2f cannot be represented as %s", value, param.DataType)
 	}
 
-	// Write back to file
-	return os.WriteFile(filename, data, 0644)
+	s, err := NewSession(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	s.Add(Operation{
+		Name: fmt.Sprintf("Set %s to %.2f", name, value),
+		Plan: func(data []byte) ([]CellChange, error) {
+			// The web UI writes files other than the one the
+			// definitions were moved to
+			return PlanConfigParam(data, reader.DetectLayout(data).LocateParam(param), value)
+		},
+	})
+	return s.Commit()
 }
 
-// EditMapCellDirect edits a specific map cell without prompts (for GUI use)
-func EditMapCellDirect(filename string, cfg models.MapConfig, row, col int, newValue float64) error {
-	if row < 0 || row >= cfg.Rows || col < 0 || col >= cfg.Cols {
-		return fmt.Errorf("invalid cell coordinates: [%d,%d]", row, col)
+// PlanConfigParam plans setting a configuration parameter in data to
+// value, checking that it fits the data type, the file and the parameter's
+// links. The range check of SetConfigParam is left to the caller.
+func PlanConfigParam(data []byte, param models.ConfigParam, value float64) ([]CellChange, error) {
+	raw, clamped := param.ToRaw(value)
+	if clamped {
+		return nil, reader.NewError(reader.ErrValueOutOfBounds, "value %.2f cannot be represented as %s", value, param.DataType)
 	}
+	if err := reader.CheckBounds(fmt.Sprintf("parameter %q", param.Name), param.Offset, int64(models.DataTypeSize(param.DataType)), int64(len(data))); err != nil {
+		return nil, err
+	}
+	if err := reader.CheckLinkedValue(data, param, value); err != nil {
+		return nil, err
+	}
+	change := cellChange(param.Name, 0, 0, param.Offset, param.DataType, param.ByteOrder(), param.DecodeRaw(data[param.Offset:]), raw, param.ToReal)
+	return []CellChange{change}, nil
+}
 
-	// Read file
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return err
+// PlanCellEdit plans setting one cell of a map in data to newValue
+func PlanCellEdit(data []byte, cfg models.MapConfig, row, col int, newValue float64) ([]CellChange, error) {
+	if row < 0 || row >= cfg.Rows || col < 0 || col >= cfg.Cols {
+		return nil, reader.NewError(reader.ErrOutOfRange, "invalid cell coordinates: [%d,%d]", row, col)
+	}
+	if !models.KnownDataType(cfg.DataType) {
+		return nil, reader.NewError(reader.ErrUnsupportedDataType, "%s: unknown data type %q", cfg.Name, cfg.DataType)
 	}
 
 	// Calculate offset
-	cellOffset := cfg.Offset + int64(row*cfg.Cols+col)
-	if int(cellOffset) >= len(data) {
-		return fmt.Errorf("cell offset out of bounds")
+	size := models.DataTypeSize(cfg.DataType)
+	cellOffset := cfg.Offset + int64((row*cfg.Cols+col)*size)
+	if int(cellOffset)+size > len(data) {
+		return nil, reader.NewError(reader.ErrOutOfRange, "cell offset out of bounds")
 	}
 
 	// Convert value to raw
-	var newRaw uint8
-	if cfg.DataType == "uint8" {
-		newRaw = uint8((newValue - cfg.Offset2) / cfg.Scale)
-		data[cellOffset] = newRaw
-	} else if cfg.DataType == "uint16" {
-		newRaw16 := uint16((newValue - cfg.Offset2) / cfg.Scale)
-		binary.LittleEndian.PutUint16(data[cellOffset:], newRaw16)
+	newRaw, clamped := cfg.ToRaw(newValue)
+	if clamped {
+		return nil, reader.NewError(reader.ErrValueOutOfBounds, "value %.2f cannot be represented in %s", newValue, cfg.Name)
 	}
+	oldRaw := cfg.DecodeRaw(data[cellOffset:])
+	return []CellChange{cellChange(cfg.Name, row, col, cellOffset, cfg.DataType, cfg.ByteOrder(), oldRaw, newRaw, cfg.ToReal)}, nil
+}
 
-	// Write back
-	return os.WriteFile(filename, data, 0644)
+// cellChange describes a single-cell write, for the planners above and
+// the session commit of InteractiveEdit
+func cellChange(name string, row, col int, offset int64, dataType string, order models.Endianness, oldRaw, newRaw int64, toReal func(int64) float64) CellChange {
+	return CellChange{
+		Map: name, Row: row, Col: col, Offset: offset, DataType: dataType, Endianness: order,
+		OldRaw: oldRaw, NewRaw: newRaw, OldValue: toReal(oldRaw), NewValue: toReal(newRaw),
+	}
 }
 
 // ExportMapToCSV exports a map to a CSV file
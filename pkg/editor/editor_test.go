@@ -0,0 +1,645 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// TestInteractiveEdit drives whole interactive edits through a scripted
+// prompter and checks the bytes they leave in the file
+func TestInteractiveEdit(t *testing.T) {
+	fuel := models.MapConfigs[0]
+	rev := models.ConfigParams[0]
+
+	tests := []struct {
+		name    string
+		answers []string
+		// want changes the original image into the expected one
+		want func(data []byte)
+	}{
+		{
+			name:    "fuel cell",
+			answers: []string{"y", "Edit Fuel Map Cell", "2", "3", "5.0", "y"},
+			want: func(data []byte) {
+				raw, _ := fuel.ToRaw(5)
+				data[fuel.Offset+2*16+3] = byte(raw)
+			},
+		},
+		{
+			name:    "scale map",
+			answers: []string{"y", "Scale Entire Map", "Main Fuel Map (0x6700)", "1.1", "y"},
+			want: func(data []byte) {
+				// The fuel map has no value offset, so raw values scale too
+				for i := range fuel.ByteSize() {
+					data[fuel.Offset+i] = byte(math.Round(float64(data[fuel.Offset+i]) * 1.1))
+				}
+			},
+		},
+		{
+			name:    "rev limiter",
+			answers: []string{"y", "Edit Rev Limiter", "7000", "0", "y"},
+			want: func(data []byte) {
+				raw, _ := rev.ToRaw(7000)
+				data[rev.Offset] = byte(raw)
+			},
+		},
+		{name: "risks declined", answers: []string{"n"}},
+		{name: "exit", answers: []string{"y", "Exit"}},
+		{name: "change declined", answers: []string{"y", "Edit Fuel Map Cell", "2", "3", "5.0", "n"}},
+		{name: "row out of range", answers: []string{"y", "Edit Fuel Map Cell", "8"}},
+		{name: "multiplier out of range", answers: []string{"y", "Scale Entire Map", "Main Fuel Map (0x6700)", "3"}},
+		{name: "rev limit out of range", answers: []string{"y", "Edit Rev Limiter", "9000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, before := writeImage(t)
+			prompt := &ScriptedPrompter{Answers: tt.answers}
+			InteractiveEdit(prompt, file, false)
+
+			if prompt.next != len(tt.answers) {
+				t.Errorf("the edit used %d of %d answers", prompt.next, len(tt.answers))
+			}
+			want := bytes.Clone(before)
+			if tt.want != nil {
+				tt.want(want)
+			}
+			after, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i := firstDifference(after, want); i >= 0 {
+				t.Errorf("byte 0x%04X is 0x%02X, want 0x%02X", i, after[i], want[i])
+			}
+			if backups, _ := ListBackups(file); (len(backups) == 1) != (tt.want != nil) {
+				t.Errorf("%d backups after the edit", len(backups))
+			}
+		})
+	}
+}
+
+// A dry run asks every question but leaves the file alone
+func TestInteractiveEditDryRun(t *testing.T) {
+	file, before := writeImage(t)
+	answers := []string{"y", "Scale Entire Map", "Main Fuel Map (0x6700)", "1.1"}
+	prompt := &ScriptedPrompter{Answers: answers}
+	InteractiveEdit(prompt, file, true)
+	if prompt.next != len(answers) {
+		t.Errorf("the dry run used %d of %d answers", prompt.next, len(answers))
+	}
+	if after, _ := os.ReadFile(file); !bytes.Equal(after, before) {
+		t.Error("a dry run changed the file")
+	}
+}
+
+// Every write path refuses a read-only file up front, before a backup or
+// a prompt, and leaves it as it was
+func TestReadOnlyFile(t *testing.T) {
+	file, before := writeImage(t)
+	readOnly(t, file)
+
+	_, err := SetConfigParam(file, RevLimiterParam, 7000)
+	if !errors.Is(err, reader.ErrReadOnly) {
+		t.Errorf("SetConfigParam = %v, want a read-only error", err)
+	}
+
+	s, err := NewSession(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add(change("first", 0, 2))
+	if _, err := s.Commit(); !errors.Is(err, reader.ErrReadOnly) {
+		t.Errorf("Commit = %v, want a read-only error", err)
+	}
+
+	// The edit stops after the coordinates, before asking for a value
+	prompt := &ScriptedPrompter{Answers: []string{"y", "Edit Fuel Map Cell", "2", "3", "5.0", "y"}}
+	InteractiveEdit(prompt, file, false)
+	if prompt.next != 4 {
+		t.Errorf("the edit of a read-only file used %d answers, want 4", prompt.next)
+	}
+
+	if after, _ := os.ReadFile(file); !bytes.Equal(after, before) {
+		t.Error("a read-only file was changed")
+	}
+	if backups, _ := ListBackups(file); len(backups) != 0 {
+		t.Errorf("%d backups of a read-only file", len(backups))
+	}
+}
+
+// A single-cell edit is committed by a session: the file is replaced with
+// the new cell, and the backup and the change are in the changelog, so
+// -blame can attribute the cell
+func TestEditMapCellSession(t *testing.T) {
+	file, before := writeImage(t)
+	prompt := &ScriptedPrompter{Answers: []string{"y", "Edit Fuel Map Cell", "2", "3", "5.0", "y"}}
+	InteractiveEdit(prompt, file, false)
+
+	cfg := models.MapConfigs[0]
+	offset := cfg.Offset + int64(2*cfg.Cols+3)
+	raw, _ := cfg.ToRaw(5.0)
+	after, _ := os.ReadFile(file)
+	if i := firstDifference(after, before); i != int(offset) || after[offset] != byte(raw) {
+		t.Fatalf("first difference at 0x%04X, want raw %d at 0x%04X", i, raw, offset)
+	}
+	if i := firstDifference(after[offset+1:], before[offset+1:]); i >= 0 {
+		t.Errorf("the edit changed a second byte at 0x%04X", offset+1+int64(i))
+	}
+
+	backups, _ := ListBackups(file)
+	entries, err := ReadChangelog(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || len(entries) != 1 {
+		t.Fatalf("%d backups and %d changelog entries, want one each", len(backups), len(entries))
+	}
+	e := entries[0]
+	if e.Action != "edit" || e.Detail != "Edit Main Fuel Map [2,3]" || e.Backup == "" ||
+		len(e.Changes) != 1 || e.Changes[0].Offset != offset || e.Changes[0].NewRaw != raw {
+		t.Errorf("changelog entry %+v, want the cell edit with its backup", e)
+	}
+}
+
+// readOnly makes path read-only, skipping the test where file modes don't
+// stop writes: on Windows, and for root
+func readOnly(t *testing.T, path string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("read-only files are tested with chmod on Unix")
+	}
+	if err := os.Chmod(path, 0444); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(path, 0644) })
+	if f, err := os.OpenFile(path, os.O_RDWR, 0); err == nil {
+		f.Close()
+		t.Skip("file modes are not enforced for this user")
+	}
+}
+
+// firstDifference returns the first offset where a and b differ, or -1
+func firstDifference(a, b []byte) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return min(len(a), len(b))
+	}
+	return -1
+}
+
+func TestSetConfigParamErrors(t *testing.T) {
+	file, before := writeImage(t)
+	if _, err := SetConfigParam(file, "Boost Limit", 1); !errors.Is(err, reader.ErrNotFound) {
+		t.Errorf("unknown parameter: %v, want ErrNotFound", err)
+	}
+	if _, err := SetConfigParam(file, RevLimiterParam, 9000); !errors.Is(err, reader.ErrValueOutOfBounds) {
+		t.Errorf("rev limit of 9000: %v, want ErrValueOutOfBounds", err)
+	}
+	if after, _ := os.ReadFile(file); !bytes.Equal(after, before) {
+		t.Error("a refused parameter write changed the file")
+	}
+}
+
+// Setting every cell and parameter of the image to the value it is shown
+// with leaves every byte as it was
+func TestReenteredValuesAreNoOp(t *testing.T) {
+	data := testbin.Image()
+	after := bytes.Clone(data)
+	shown := func(v float64) float64 {
+		f, _ := strconv.ParseFloat(fmt.Sprintf("%.2f", v), 64)
+		return f
+	}
+	for _, cfg := range models.MapConfigs {
+		m, err := reader.ReadMapFromBytes(data, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for row := range cfg.Rows {
+			for col := range cfg.Cols {
+				changes, err := PlanCellEdit(data, cfg, row, col, shown(m.Data[row][col]))
+				if err != nil {
+					t.Fatalf("%s [%d,%d]: %v", cfg.Name, row, col, err)
+				}
+				for _, c := range changes {
+					c.Apply(after)
+				}
+			}
+		}
+	}
+	config := reader.ReadConfigParamsFromBytes(data)
+	for _, p := range models.ConfigParams {
+		value, ok := config.Values[p.Name]
+		if !ok {
+			continue
+		}
+		changes, err := PlanConfigParam(data, p, shown(value))
+		if err != nil {
+			t.Fatalf("%s: %v", p.Name, err)
+		}
+		for _, c := range changes {
+			c.Apply(after)
+		}
+	}
+	if i := firstDifference(after, data); i >= 0 {
+		t.Errorf("re-entering the shown values changed byte 0x%04X from 0x%02X to 0x%02X", i, data[i], after[i])
+	}
+}
+
+// A map with a negative scale reads, colors and edits in engineering
+// values: raw and value run in opposite directions, but the highest value
+// is still the hot end of the heatmap and nudging up raises the value
+func TestNegativeScale(t *testing.T) {
+	cfg := models.MapConfig{Name: "Inverted", Offset: 0x5000, Rows: 2, Cols: 4, DataType: "uint8", Scale: -0.5, Offset2: 100, Unit: "%"}
+	data := testbin.Image()
+	copy(data[cfg.Offset:], []byte{0, 20, 40, 60, 80, 100, 120, 200})
+
+	m, err := reader.ReadMapFromBytes(data, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]float64{{100, 90, 80, 70}, {60, 50, 40, 0}}
+	for i := range want {
+		for j := range want[i] {
+			if m.Data[i][j] != want[i][j] {
+				t.Errorf("cell [%d,%d] reads %g, want %g", i, j, m.Data[i][j], want[i][j])
+			}
+		}
+	}
+
+	scale := cfg.HeatScale(m.Data)
+	if scale.Min() != 0 || scale.Max() != 100 || scale.Normalize(m.Data[0][0]) != 1 || scale.Normalize(m.Data[1][3]) != 0 {
+		t.Errorf("heat scale %v puts raw 0 at %g and raw 200 at %g", scale.Bounds, scale.Normalize(m.Data[0][0]), scale.Normalize(m.Data[1][3]))
+	}
+
+	changes, err := PlanCellEdit(data, cfg, 0, 1, 95)
+	if err != nil || len(changes) != 1 || changes[0].NewRaw != 10 {
+		t.Errorf("setting [0,1] to 95: %+v, %v; want raw 10", changes, err)
+	}
+	changes, err = PlanNudge(data, cfg, 0, 1, 1)
+	if err != nil || len(changes) != 1 || changes[0].NewRaw != 19 || changes[0].NewValue != 90.5 {
+		t.Errorf("nudging [0,1] up: %+v, %v; want raw 19 (90.5)", changes, err)
+	}
+	// 100 is raw 0, so a value above it can't be stored
+	if _, err := PlanCellEdit(data, cfg, 0, 0, 101); !errors.Is(err, reader.ErrValueOutOfBounds) {
+		t.Errorf("setting [0,0] to 101: %v, want ErrValueOutOfBounds", err)
+	}
+	if _, err := PlanNudge(data, cfg, 0, 0, 1); !errors.Is(err, reader.ErrValueOutOfBounds) {
+		t.Errorf("nudging the largest value up: %v, want ErrValueOutOfBounds", err)
+	}
+}
+
+// A map and a parameter stored as 256/x are written through the editor and
+// read back by the reader within one raw step (LSB) of the value entered,
+// with and without an inverse formula
+func TestFormulaEdit(t *testing.T) {
+	for _, inverse := range []string{"256/x", ""} {
+		cfg := models.MapConfig{Name: "Time Constant", Offset: 0x5000, Rows: 2, Cols: 4, DataType: "uint8", Formula: "256/x", InverseFormula: inverse, MaxValue: 256, Unit: "ms"}
+		param := models.ConfigParam{Name: "Idle Constant", Offset: 0x5010, DataType: "uint16", Formula: "65536/x", MaxValue: 65536, Unit: "ms"}
+		if inverse != "" {
+			param.InverseFormula = "65536/x"
+		}
+		data := testbin.Image()
+		copy(data[cfg.Offset:], []byte{1, 2, 4, 8, 16, 32, 64, 0})
+
+		m, err := reader.ReadMapFromBytes(data, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]float64{{256, 128, 64, 32}, {16, 8, 4, 0}}
+		for i := range want {
+			for j := range want[i] {
+				if m.Data[i][j] != want[i][j] {
+					t.Errorf("inverse %q: cell [%d,%d] reads %g, want %g", inverse, i, j, m.Data[i][j], want[i][j])
+				}
+			}
+		}
+
+		for _, value := range []float64{100, 12.3, 3.7, 1.01} {
+			changes, err := PlanCellEdit(data, cfg, 0, 1, value)
+			if err != nil || len(changes) != 1 {
+				t.Fatalf("inverse %q: setting [0,1] to %g: %v", inverse, value, err)
+			}
+			changes[0].Apply(data)
+			m, _ := reader.ReadMapFromBytes(data, cfg)
+			raw := changes[0].NewRaw
+			if got := m.Data[0][1]; math.Abs(got-value) > cfg.ToReal(raw-1)-cfg.ToReal(raw) || got != changes[0].NewValue {
+				t.Errorf("inverse %q: %g writes raw %d, which reads %g (planned %g), more than one LSB off", inverse, value, raw, got, changes[0].NewValue)
+			}
+
+			changes, err = PlanConfigParam(data, param, value*100)
+			if err != nil || len(changes) != 1 {
+				t.Fatalf("inverse %q: setting the parameter to %g: %v", inverse, value*100, err)
+			}
+			changes[0].Apply(data)
+			got := reader.ReadParamsFromBytes(data, []models.ConfigParam{param}).Values[param.Name]
+			raw = changes[0].NewRaw
+			if math.Abs(got-value*100) > param.ToReal(raw-1)-param.ToReal(raw) {
+				t.Errorf("inverse %q: parameter %g writes raw %d, which reads %g, more than one LSB off", inverse, value*100, raw, got)
+			}
+		}
+		// 256 is the largest value, read from raw 1; 1000 is more than
+		// one step beyond it
+		if _, err := PlanCellEdit(data, cfg, 0, 0, 1000); !errors.Is(err, reader.ErrValueOutOfBounds) {
+			t.Errorf("inverse %q: setting 1000: %v, want ErrValueOutOfBounds", inverse, err)
+		}
+	}
+}
+
+// When no backup can be made next to the file, every write path stops
+// before touching it. -no-backup skips the backup, so a cell edit then
+// writes without one.
+func TestUnwritableBackupDir(t *testing.T) {
+	patch := filepath.Join(t.TempDir(), "patch.bin")
+	if err := os.WriteFile(patch, []byte{1, 2, 3, 4}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		write func(file string) error
+	}{
+		{"cell edit", func(file string) error {
+			prompt := &ScriptedPrompter{Answers: []string{"y", "Edit Fuel Map Cell", "2", "3", "5.0", "y"}}
+			InteractiveEdit(prompt, file, false)
+			return nil
+		}},
+		{"parameter", func(file string) error {
+			_, err := SetConfigParam(file, RevLimiterParam, 7000)
+			return err
+		}},
+		{"session", func(file string) error {
+			s, err := NewSession(file)
+			if err != nil {
+				return err
+			}
+			s.Add(change("first", 0, 2))
+			_, err = s.Commit()
+			return err
+		}},
+		{"inject", func(file string) error {
+			_, err := InjectRegion(file, patch, 0x5000)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, before := writeImage(t)
+			readOnlyDir(t, filepath.Dir(file))
+			if err := tt.write(file); tt.name != "cell edit" && (err == nil || !strings.Contains(err.Error(), "backup")) {
+				t.Errorf("write = %v, want a backup error", err)
+			}
+			if after, _ := os.ReadFile(file); !bytes.Equal(after, before) {
+				t.Error("the file was written without a backup")
+			}
+		})
+	}
+
+	t.Run("no backup", func(t *testing.T) {
+		file, before := writeImage(t)
+		reader.NoBackup = true
+		t.Cleanup(func() { reader.NoBackup = false })
+		tests[0].write(file)
+		if after, _ := os.ReadFile(file); bytes.Equal(after, before) {
+			t.Error("-no-backup didn't let the cell edit through")
+		}
+		if backups, _ := ListBackups(file); len(backups) != 0 {
+			t.Errorf("-no-backup made %d backups", len(backups))
+		}
+	})
+}
+
+// readOnlyDir makes dir read-only, skipping the test where file modes
+// don't stop writes (see readOnly)
+func readOnlyDir(t *testing.T, dir string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("read-only directories are tested with chmod on Unix")
+	}
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+	if f, err := os.CreateTemp(dir, "probe"); err == nil {
+		f.Close()
+		os.Remove(f.Name())
+		t.Skip("file modes are not enforced for this user")
+	}
+}
+
+// Under -require-backup a backup that reads back damaged stops every write
+// path: the file and its changelog stay as they were and the damaged copy
+// is removed. An intact backup lets the write through and the changelog
+// records its hash.
+func TestCorruptedBackup(t *testing.T) {
+	reader.RequireBackup = true
+	t.Cleanup(func() { reader.RequireBackup = false })
+	patch := filepath.Join(t.TempDir(), "patch.bin")
+	if err := os.WriteFile(patch, []byte{1, 2, 3, 4}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		write func(file string, data []byte) error
+	}{
+		{"cell edit", func(file string, data []byte) error {
+			changes, err := PlanCellEdit(data, models.MapConfigs[0], 2, 3, 5.0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ApplyChanges(file, changes)
+			return err
+		}},
+		{"parameter", func(file string, _ []byte) error {
+			_, err := SetConfigParam(file, RevLimiterParam, 7000)
+			return err
+		}},
+		{"session", func(file string, _ []byte) error {
+			s, err := NewSession(file)
+			if err != nil {
+				return err
+			}
+			defer s.Close()
+			s.Add(change("first", 0, 2))
+			_, err = s.Commit()
+			return err
+		}},
+		{"inject", func(file string, _ []byte) error {
+			_, err := InjectRegion(file, patch, 0x5000)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, before := writeImage(t)
+			backupWritten = func(path string) {
+				f, err := os.OpenFile(path, os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.WriteAt([]byte{^before[0x100]}, 0x100)
+				f.Close()
+			}
+			t.Cleanup(func() { backupWritten = nil })
+
+			if err := tt.write(file, before); !errors.Is(err, reader.ErrBackupUnverified) {
+				t.Errorf("write = %v, want ErrBackupUnverified", err)
+			}
+			if after, _ := os.ReadFile(file); !bytes.Equal(after, before) {
+				t.Error("the file was written after a damaged backup")
+			}
+			if backups, _ := ListBackups(file); len(backups) != 0 {
+				t.Errorf("the damaged backup %s was kept", backups[0].Path)
+			}
+			if entries, _ := ReadChangelog(file); len(entries) != 0 {
+				t.Errorf("the changelog has %d entries, want none", len(entries))
+			}
+
+			backupWritten = nil
+			if err := tt.write(file, before); err != nil {
+				t.Fatalf("write with an intact backup = %v", err)
+			}
+			if after, _ := os.ReadFile(file); bytes.Equal(after, before) {
+				t.Error("the write didn't go through with an intact backup")
+			}
+			entries, _ := ReadChangelog(file)
+			if len(entries) != 1 || entries[0].BackupSHA256 != hashData(before) || entries[0].Backup == "" {
+				t.Fatalf("changelog %+v, want one entry with the verified backup", entries)
+			}
+			if saved, _ := os.ReadFile(entries[0].Backup); !bytes.Equal(saved, before) {
+				t.Error("the verified backup doesn't hold the original contents")
+			}
+		})
+	}
+}
+
+// A big-endian uint16 parameter in the last word of the image is written
+// high byte first and reads back as written, whether the order is its own
+// or the profile's; one byte further on it lies past the end
+func TestBigEndianParamAtEnd(t *testing.T) {
+	savedParams, savedProfile := models.ConfigParams, models.M21IDProfile
+	t.Cleanup(func() { models.ConfigParams, models.M21IDProfile = savedParams, savedProfile })
+
+	tests := []struct {
+		name    string
+		own     models.Endianness
+		profile models.Endianness
+	}{
+		{"own order", models.BigEndian, models.LittleEndian},
+		{"profile order", "", models.BigEndian},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, before := writeImage(t)
+			end := int64(len(before))
+			word := models.ConfigParam{Name: "Idle Target", Offset: end - 2, DataType: "uint16", Scale: 0.5,
+				Unit: "rpm", MinValue: 0, MaxValue: 30000, Endianness: tt.own}
+			models.ConfigParams = append(append([]models.ConfigParam(nil), savedParams...), word)
+			models.M21IDProfile.Endianness = tt.profile
+
+			if _, err := SetConfigParam(file, word.Name, 0x1234*0.5); err != nil {
+				t.Fatal(err)
+			}
+			after, _ := os.ReadFile(file)
+			if got := after[end-2:]; got[0] != 0x12 || got[1] != 0x34 {
+				t.Errorf("stored % X, want 12 34", got)
+			}
+			if i := firstDifference(after[:end-2], before[:end-2]); i >= 0 {
+				t.Errorf("byte 0x%04X changed too", i)
+			}
+			if value, err := reader.ReadConfigParam(file, word); value != 0x1234*0.5 || err != nil {
+				t.Errorf("reads back as %g, %v", value, err)
+			}
+
+			word.Offset++
+			if _, err := reader.ReadConfigParamFromBytes(after, word); !errors.Is(err, reader.ErrOutOfRange) {
+				t.Errorf("reading one byte past the end: %v, want ErrOutOfRange", err)
+			}
+			if _, err := PlanConfigParam(after, word, 1); !errors.Is(err, reader.ErrOutOfRange) {
+				t.Errorf("writing one byte past the end: %v, want ErrOutOfRange", err)
+			}
+		})
+	}
+}
+
+// Signed maps read their negative cells back, and edits and scaling
+// write them as two's complement, for int8 and little- and big-endian
+// int16
+func TestSignedMapRoundTrip(t *testing.T) {
+	tests := []struct {
+		cfg models.MapConfig
+		// cells holds the stored bytes of the raw values -128, -1, 0, 127
+		cells []byte
+	}{
+		{models.MapConfig{Name: "Retard", Offset: 0x5000, Rows: 2, Cols: 2, DataType: "int8", Scale: 0.5, Unit: "deg"},
+			[]byte{0x80, 0xFF, 0x00, 0x7F}},
+		{models.MapConfig{Name: "Trim", Offset: 0x5100, Rows: 2, Cols: 2, DataType: "int16", Scale: 0.5, Unit: "%"},
+			[]byte{0x80, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x7F, 0x00}},
+		{models.MapConfig{Name: "Trim BE", Offset: 0x5200, Rows: 2, Cols: 2, DataType: "int16", Scale: 0.5, Unit: "%", Endianness: models.BigEndian},
+			[]byte{0xFF, 0x80, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x7F}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cfg.Name, func(t *testing.T) {
+			cfg := tt.cfg
+			data := testbin.Image()
+			copy(data[cfg.Offset:], tt.cells)
+
+			m, err := reader.ReadMapFromBytes(data, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := [][]float64{{-64, -0.5}, {0, 63.5}}; !slices.EqualFunc(m.Data, want, slices.Equal) {
+				t.Errorf("reads %v, want %v", m.Data, want)
+			}
+
+			// Every cell set to a negative value reads back as it
+			for _, value := range []float64{-64, -0.5, -10, 63.5} {
+				written := bytes.Clone(data)
+				changes, err := PlanCellEdit(written, cfg, 1, 1, value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				changes[0].Apply(written)
+				back, _ := reader.ReadMapFromBytes(written, cfg)
+				if back.Data[1][1] != value {
+					t.Errorf("%g written to [1,1] reads back as %g", value, back.Data[1][1])
+				}
+			}
+			if _, err := PlanCellEdit(data, cfg, 0, 0, -64.5); cfg.DataType == "int8" && !errors.Is(err, reader.ErrValueOutOfBounds) {
+				t.Errorf("-64.5 in an int8 cell, below raw -128: %v, want ErrValueOutOfBounds", err)
+			}
+
+			// Doubling keeps the signs and clamps at the type's limits
+			changes, err := PlanScale(data, cfg, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scaled := bytes.Clone(data)
+			for _, c := range changes {
+				c.Apply(scaled)
+			}
+			back, _ := reader.ReadMapFromBytes(scaled, cfg)
+			want := [][]float64{{-128, -1}, {0, 127}}
+			if cfg.DataType == "int8" {
+				want = [][]float64{{-64, -1}, {0, 63.5}}
+			}
+			if !slices.EqualFunc(back.Data, want, slices.Equal) {
+				t.Errorf("doubled reads %v, want %v", back.Data, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/datalog"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// FuelCutShare is the share of the fuel map's largest value at or below
+// which a cell counts as cut. M2.1 cuts fuel above the rev limit with
+// zeroed cells in the top RPM columns of the fuel map.
+const FuelCutShare = 0.02
+
+// FuelCut is the run of fuel-cut columns at the high-RPM end of the fuel
+// map
+type FuelCut struct {
+	// Start is the first cut column; it equals Cols when nothing is cut
+	Start int
+	Cols  int
+}
+
+// Columns returns how many columns are cut
+func (c FuelCut) Columns() int { return c.Cols - c.Start }
+
+// String describes the cut for prompts and logs
+func (c FuelCut) String() string {
+	if c.Columns() == 0 {
+		return "no fuel-cut columns"
+	}
+	return fmt.Sprintf("%d fuel-cut column(s) from %.0f RPM (column %d)", c.Columns(), ColumnRPM(c.Start, c.Cols), c.Start)
+}
+
+// fuelMap is the map whose top columns implement the fuel cut
+func fuelMap() models.MapConfig {
+	return models.MapConfigs[0] // Main Fuel Map
+}
+
+// ColumnRPM returns the RPM at which a fuel map column starts, on the axis
+// the map views label (datalog.MaxRPM spread over the columns)
+func ColumnRPM(col, cols int) float64 {
+	return float64(col) * datalog.MaxRPM / float64(cols)
+}
+
+// FindFuelCut returns the columns at the high-RPM end of m in which every
+// cell is zero or near zero (see FuelCutShare)
+func FindFuelCut(m *models.ECUMap) FuelCut {
+	cut := FuelCut{Start: m.Config.Cols, Cols: m.Config.Cols}
+	peak := 0.0
+	for _, row := range m.Data {
+		for _, value := range row {
+			peak = math.Max(peak, math.Abs(value))
+		}
+	}
+	limit := peak * FuelCutShare
+	for col := m.Config.Cols - 1; col > 0; col-- {
+		for _, row := range m.Data {
+			if math.Abs(row[col]) > limit {
+				return cut
+			}
+		}
+		cut.Start = col
+	}
+	return cut
+}
+
+// SuggestFuelCutShift returns how many columns to move the cut so that it
+// starts in the first column beginning above limit RPM: the column holding
+// the limit keeps fueling, the ones above it are cut
+func SuggestFuelCutShift(cut FuelCut, limit float64) int {
+	step := datalog.MaxRPM / float64(cut.Cols)
+	target := min(int(limit/step)+1, cut.Cols)
+	return target - cut.Start
+}
+
+// PlanFuelCutShift returns the changes that move the fuel cut boundary by
+// shift columns. A positive shift un-cuts columns by copying the raw values
+// of the last fueled column into them; a negative shift cuts more columns,
+// setting them to the raw values of the first cut column, or to zero if
+// nothing is cut yet. At least one column must stay fueled.
+func PlanFuelCutShift(data []byte, shift int) ([]CellChange, error) {
+	cfg := fuelMap()
+	m, err := reader.ReadMapFromBytes(data, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cut := FindFuelCut(m)
+	start := cut.Start + shift
+	if start < 1 || start > cfg.Cols {
+		return nil, reader.NewError(reader.ErrOutOfRange, "cannot move the fuel cut by %+d columns: it starts at column %d of %d", shift, cut.Start, cfg.Cols)
+	}
+
+	size := models.DataTypeSize(cfg.DataType)
+	offset := func(row, col int) int64 { return cfg.Offset + int64((row*cfg.Cols+col)*size) }
+	zero, _ := cfg.ToRaw(0)
+
+	var changes []CellChange
+	for row := 0; row < cfg.Rows; row++ {
+		for col := min(start, cut.Start); col < max(start, cut.Start); col++ {
+			var newRaw int64
+			switch {
+			case shift > 0:
+				newRaw = cfg.DecodeRaw(data[offset(row, cut.Start-1):])
+			case cut.Start < cfg.Cols:
+				newRaw = cfg.DecodeRaw(data[offset(row, cut.Start):])
+			default:
+				newRaw = zero
+			}
+			oldRaw := cfg.DecodeRaw(data[offset(row, col):])
+			if newRaw == oldRaw {
+				continue
+			}
+			changes = append(changes, cellChange(cfg.Name, row, col, offset(row, col), cfg.DataType, cfg.ByteOrder(), oldRaw, newRaw, cfg.ToReal))
+		}
+	}
+	return changes, nil
+}
+
+// FuelCutOperation is the session operation that moves the fuel cut by
+// shift columns
+func FuelCutOperation(shift int) Operation {
+	return Operation{
+		Name: fmt.Sprintf("Move fuel cut by %+d column(s)", shift),
+		Plan: func(data []byte) ([]CellChange, error) {
+			return PlanFuelCutShift(data, shift)
+		},
+	}
+}
+
+// RevLimitOperation is the session operation that moves the rev limiter,
+// and the parameters linked to it, to rpm
+func RevLimitOperation(rpm float64) Operation {
+	return Operation{
+		Name: fmt.Sprintf("Set rev limit to %.0f RPM", rpm),
+		Plan: func(data []byte) ([]CellChange, error) {
+			return PlanLinkedMove(data, RevLimiterParam, rpm)
+		},
+	}
+}
+
+// ShowFuelCut prints the detected fuel cut of the file's fuel map and
+// returns it
+func ShowFuelCut(data []byte) (FuelCut, error) {
+	m, err := reader.ReadMapFromBytes(data, fuelMap())
+	if err != nil {
+		return FuelCut{}, err
+	}
+	cut := FindFuelCut(m)
+	pterm.Info.Printf("%s: %s\n", m.Config.Name, cut)
+	if cut.Columns() > 0 {
+		tableData := pterm.TableData{{"Column", "RPM", "Max " + m.Config.Unit}}
+		for col := cut.Start; col < cut.Cols; col++ {
+			peak := 0.0
+			for _, row := range m.Data {
+				peak = math.Max(peak, row[col])
+			}
+			tableData = append(tableData, []string{strconv.Itoa(col), fmt.Sprintf("%.0f", ColumnRPM(col, cut.Cols)), fmt.Sprintf("%.2f", peak)})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	}
+	return cut, nil
+}
+
+// askFuelCutShift shows the fuel cut and asks how far to move it, offering
+// suggested as the default. It returns 0 to leave the cut alone.
+func askFuelCutShift(prompt Prompter, data []byte, suggested int) (int, error) {
+	cut, err := ShowFuelCut(data)
+	if err != nil {
+		return 0, err
+	}
+	low, high := 1-cut.Start, cut.Cols-cut.Start
+	text := fmt.Sprintf("Move the fuel cut by how many columns (%+d to %+d, suggested %+d, 0 leaves it)", low, high, suggested)
+	answer, err := prompt.Input(text, intRange(low, high, "Shift out of range"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(answer)
+}
+
+// AdjustFuelCut shows the fuel-cut columns and moves the cut boundary by
+// shift columns in a reviewed session. With ask set, the shift is asked
+// for instead.
+func AdjustFuelCut(prompt Prompter, filename string, shift int, ask, dryRun bool) bool {
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	if ask {
+		if shift, err = askFuelCutShift(prompt, data, 0); err != nil {
+			pterm.Error.Println(err)
+			return false
+		}
+	} else if _, err := ShowFuelCut(data); err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	if shift == 0 {
+		return true
+	}
+	return commitOperations(prompt, filename, dryRun, FuelCutOperation(shift))
+}
+
+// commitOperations plans ops in one session and, unless dryRun, writes them
+// after the user reviewed the report
+func commitOperations(prompt Prompter, filename string, dryRun bool, ops ...Operation) bool {
+	s, err := NewSession(filename)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	s.DryRun = dryRun
+	for _, op := range ops {
+		s.Add(op)
+	}
+	s.Confirm = func(r *Report) bool {
+		r.PrintTable()
+		return Confirm(prompt, ConfirmSave, i18n.T("cli.confirm.changes"))
+	}
+	report, err := s.Commit()
+	if dryRun {
+		report.PrintTable()
+		pterm.Warning.Println(i18n.T("cli.dry_run"))
+	} else {
+		report.PrintSummary()
+	}
+	if err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// DefaultHistoryLimit is the number of points CellHistory returns when no
+// limit is given
+const DefaultHistoryLimit = 20
+
+// maxHistoryBackups bounds how many of the newest backups are loaded
+const maxHistoryBackups = 50
+
+// HistoryPoint is the value of a cell or parameter at one point in time
+type HistoryPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// historySource is everything recorded about one file, loaded once and
+// reused until the file or its changelog changes
+type historySource struct {
+	stamp   string
+	entries []ChangelogEntry
+	backups []historySnapshot
+	current historySnapshot
+}
+
+type historySnapshot struct {
+	time time.Time
+	data []byte
+}
+
+var (
+	historyMu    sync.Mutex
+	historyCache = map[string]*historySource{}
+)
+
+// CellHistory returns the recorded values of one map cell, oldest first:
+// its value in each backup, the before/after values of changelog edits
+// and the current value. Consecutive equal values are merged and only the
+// last limit points are kept (DefaultHistoryLimit if limit <= 0).
+func CellHistory(filename, mapName string, row, col, limit int) ([]HistoryPoint, error) {
+	cfg, ok := models.FindMapConfig(mapName)
+	if !ok {
+		return nil, reader.NewError(reader.ErrNotFound, "unknown map: %s", mapName)
+	}
+	if row < 0 || row >= cfg.Rows || col < 0 || col >= cfg.Cols {
+		return nil, reader.NewError(reader.ErrOutOfRange, "invalid cell coordinates: [%d,%d]", row, col)
+	}
+	offset := cfg.Offset + int64((row*cfg.Cols+col)*models.DataTypeSize(cfg.DataType))
+	return ValueHistory(filename, offset, cfg.DataType, cfg.ByteOrder(), cfg.ToReal, limit)
+}
+
+// ValueHistory returns the recorded values of the raw value at offset,
+// stored in the given byte order and converted with toReal. See
+// CellHistory.
+func ValueHistory(filename string, offset int64, dataType string, order models.Endianness, toReal func(int64) float64, limit int) ([]HistoryPoint, error) {
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	src, err := loadHistorySource(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// order breaks ties between points recorded at the same instant: an
+	// old value precedes the new value written after it
+	type event struct {
+		HistoryPoint
+		order int
+	}
+	var events []event
+
+	size := int64(models.DataTypeSize(dataType))
+	valueIn := func(data []byte) (float64, bool) {
+		if offset+size > int64(len(data)) {
+			return 0, false
+		}
+		return toReal(models.DecodeRawOrder(data[offset:], dataType, order)), true
+	}
+
+	for _, b := range src.backups {
+		if v, ok := valueIn(b.data); ok {
+			events = append(events, event{HistoryPoint{b.time, v}, 0})
+		}
+	}
+	for _, e := range src.entries {
+		for _, c := range e.Changes {
+			if c.Offset != offset || c.DataType != dataType {
+				continue
+			}
+			events = append(events,
+				event{HistoryPoint{e.Time, toReal(c.OldRaw)}, 0},
+				event{HistoryPoint{e.Time, toReal(c.NewRaw)}, 1})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Time.Equal(events[j].Time) {
+			return events[i].Time.Before(events[j].Time)
+		}
+		return events[i].order < events[j].order
+	})
+
+	// The current file is the latest state even though its changelog entry
+	// is stamped just after the write
+	if v, ok := valueIn(src.current.data); ok {
+		t := src.current.time
+		if n := len(events); n > 0 && events[n-1].Time.After(t) {
+			t = events[n-1].Time
+		}
+		events = append(events, event{HistoryPoint{t, v}, 0})
+	}
+
+	var points []HistoryPoint
+	for _, e := range events {
+		if n := len(points); n > 0 && points[n-1].Value == e.Value {
+			continue
+		}
+		points = append(points, e.HistoryPoint)
+	}
+	if len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+	return points, nil
+}
+
+// loadHistorySource returns the cached changelog and backups of filename,
+// reloading them when the file or its changelog has changed
+func loadHistorySource(filename string) (*historySource, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	stamp := fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+	if logInfo, err := os.Stat(ChangelogPath(filename)); err == nil {
+		stamp += fmt.Sprintf("/%d/%d", logInfo.ModTime().UnixNano(), logInfo.Size())
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if src, ok := historyCache[filename]; ok && src.stamp == stamp {
+		return src, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ReadChangelog(filename)
+	if err != nil {
+		return nil, err
+	}
+	backups, err := ListBackups(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) > maxHistoryBackups {
+		backups = backups[len(backups)-maxHistoryBackups:]
+	}
+
+	src := &historySource{
+		stamp:   stamp,
+		entries: entries,
+		current: historySnapshot{time: info.ModTime(), data: data},
+	}
+	for _, b := range backups {
+		// Unreadable backups are left out of the history
+		if data, err := os.ReadFile(b.Path); err == nil {
+			src.backups = append(src.backups, historySnapshot{time: b.Time, data: data})
+		}
+	}
+	historyCache[filename] = src
+	return src, nil
+}
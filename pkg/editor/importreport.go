@@ -0,0 +1,146 @@
+package editor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pterm/pterm"
+)
+
+// ImportOutcome classifies one imported cell
+type ImportOutcome string
+
+const (
+	// OutcomeAccepted cells are written exactly as given
+	OutcomeAccepted ImportOutcome = "accepted"
+	// OutcomeSnapped cells are written as the nearest representable value
+	OutcomeSnapped ImportOutcome = "snapped"
+	// OutcomeClamped cells lie outside the data type range and are not
+	// written, since the clamped value is rarely what was meant
+	OutcomeClamped ImportOutcome = "clamped"
+	// OutcomeRejected cells could not be read and are not written
+	OutcomeRejected ImportOutcome = "rejected"
+)
+
+// ImportCell is a cell that was not accepted verbatim
+type ImportCell struct {
+	Row     int           `json:"row"`
+	Col     int           `json:"col"`
+	Value   string        `json:"value"`
+	Outcome ImportOutcome `json:"outcome"`
+	Reason  string        `json:"reason"`
+}
+
+// ImportOperation is the outcome of importing one file or map. Only cells
+// that were not accepted verbatim are listed; the counters cover all cells.
+type ImportOperation struct {
+	Name     string `json:"name"`
+	Map      string `json:"map,omitempty"`
+	Accepted int    `json:"accepted"`
+	Snapped  int    `json:"snapped"`
+	Clamped  int    `json:"clamped"`
+	Rejected int    `json:"rejected"`
+	// Err rejects the whole operation, e.g. an unknown map or a grid of
+	// the wrong size
+	Err   string       `json:"error,omitempty"`
+	Cells []ImportCell `json:"cells,omitempty"`
+	// Changes writes the accepted and snapped cells that differ from the file
+	Changes []CellChange `json:"-"`
+}
+
+// Record counts a cell outcome, keeping the details of any cell that was
+// not accepted verbatim
+func (op *ImportOperation) Record(cell ImportCell) {
+	switch cell.Outcome {
+	case OutcomeAccepted:
+		op.Accepted++
+		return
+	case OutcomeSnapped:
+		op.Snapped++
+	case OutcomeClamped:
+		op.Clamped++
+	case OutcomeRejected:
+		op.Rejected++
+	}
+	op.Cells = append(op.Cells, cell)
+}
+
+// Reject marks the whole operation as rejected
+func (op *ImportOperation) Reject(format string, args ...interface{}) {
+	op.Err = fmt.Sprintf(format, args...)
+	op.Changes = nil
+}
+
+// Dropped reports whether any part of the operation will not be written
+func (op *ImportOperation) Dropped() bool {
+	return op.Err != "" || op.Clamped > 0 || op.Rejected > 0
+}
+
+// ImportReport is the shared, cell-level outcome of an import, whatever
+// its source format
+type ImportReport struct {
+	Operations []*ImportOperation `json:"operations"`
+	Written    bool               `json:"written"`
+	Backup     string             `json:"backup,omitempty"`
+}
+
+// Add appends an operation and returns it for recording
+func (r *ImportReport) Add(name string) *ImportOperation {
+	op := &ImportOperation{Name: name}
+	r.Operations = append(r.Operations, op)
+	return op
+}
+
+// Complete reports whether every operation and cell will be written
+func (r *ImportReport) Complete() bool {
+	for _, op := range r.Operations {
+		if op.Dropped() {
+			return false
+		}
+	}
+	return true
+}
+
+// AcceptedChanges returns the writes of the accepted subset
+func (r *ImportReport) AcceptedChanges() []CellChange {
+	var changes []CellChange
+	for _, op := range r.Operations {
+		changes = append(changes, op.Changes...)
+	}
+	return changes
+}
+
+// Print renders one row per operation, then one row per cell that was
+// snapped, clamped or rejected
+func (r *ImportReport) Print() {
+	tableData := pterm.TableData{{"Import", "Map", "Accepted", "Snapped", "Clamped", "Rejected", "Error"}}
+	var cells pterm.TableData
+	for _, op := range r.Operations {
+		tableData = append(tableData, []string{
+			op.Name, op.Map,
+			strconv.Itoa(op.Accepted), strconv.Itoa(op.Snapped),
+			strconv.Itoa(op.Clamped), strconv.Itoa(op.Rejected),
+			op.Err,
+		})
+		for _, c := range op.Cells {
+			cells = append(cells, []string{
+				op.Name, fmt.Sprintf("[%d,%d]", c.Row, c.Col), c.Value, string(c.Outcome), c.Reason,
+			})
+		}
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if len(cells) > 0 {
+		cells = append(pterm.TableData{{"Import", "Cell", "Value", "Outcome", "Reason"}}, cells...)
+		pterm.DefaultTable.WithHasHeader().WithData(cells).Render()
+	}
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *ImportReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
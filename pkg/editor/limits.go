@@ -0,0 +1,85 @@
+package editor
+
+import (
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// ForceRange lets every edit write map cells outside the map's
+// MinValue-MaxValue (-force). Forced changes are marked in the changelog.
+var ForceRange bool
+
+// CheckCellRange returns an error stating the allowed range when value
+// is outside the limits of cfg
+func CheckCellRange(cfg models.MapConfig, value float64) error {
+	if cfg.InRange(value) {
+		return nil
+	}
+	return reader.NewError(reader.ErrValueOutOfBounds, "%.2f %s is outside the allowed range of %s, %s", value, cfg.Unit, cfg.Name, cfg.RangeLabel())
+}
+
+// checkRanges refuses changes that move a map cell outside its limits,
+// unless force or ForceRange is set, in which case they are marked
+// Forced. A change leaving the raw value as it was always passes.
+func checkRanges(changes []CellChange, force bool) error {
+	for i := range changes {
+		c := &changes[i]
+		cfg, ok := cellMap(*c)
+		if !ok || c.NewRaw == c.OldRaw || cfg.InRange(c.NewValue) {
+			continue
+		}
+		if force || ForceRange {
+			c.Forced = true
+			continue
+		}
+		return reader.NewError(reader.ErrValueOutOfBounds, "cell [%d,%d] of %s would be %.2f %s, outside the allowed range %s; -force writes it anyway",
+			c.Row, c.Col, c.Map, c.NewValue, cfg.Unit, cfg.RangeLabel())
+	}
+	return nil
+}
+
+// cellMap finds the active definition of the map a change writes to, by
+// name and offset, so parameter and axis changes are not mistaken for
+// cells
+func cellMap(c CellChange) (models.MapConfig, bool) {
+	for _, cfg := range models.MapConfigs {
+		if cfg.Name != c.Map || !cfg.HasLimits() {
+			continue
+		}
+		size := int64(models.DataTypeSize(cfg.DataType))
+		if c.Offset >= cfg.Offset && c.Offset < cfg.Offset+int64(cfg.Rows*cfg.Cols)*size {
+			return cfg, true
+		}
+	}
+	return models.MapConfig{}, false
+}
+
+// OutOfRange returns the cells the changes would move outside the limits
+// of cfg
+func OutOfRange(cfg models.MapConfig, changes []CellChange) []CellPos {
+	var cells []CellPos
+	for _, c := range changes {
+		if c.Map == cfg.Name && c.NewRaw != c.OldRaw && !cfg.InRange(c.NewValue) {
+			cells = append(cells, CellPos{c.Row, c.Col})
+		}
+	}
+	return cells
+}
+
+// refuseOutOfRange reports the cells of a planned CLI edit that would
+// leave the map's limits and returns true when the edit must stop there.
+// With ForceRange they are only listed.
+func refuseOutOfRange(cfg models.MapConfig, changes []CellChange) bool {
+	cells := OutOfRange(cfg, changes)
+	if len(cells) == 0 {
+		return false
+	}
+	if ForceRange {
+		pterm.Warning.Printf("-force: writing %d cell(s) outside the allowed range %s: %s\n", len(cells), cfg.RangeLabel(), joinCells(cells))
+		return false
+	}
+	pterm.Error.Printf("%d cell(s) would leave the allowed range of %s, %s: %s\n", len(cells), cfg.Name, cfg.RangeLabel(), joinCells(cells))
+	pterm.Info.Println("Use -force to write them anyway")
+	return true
+}
@@ -0,0 +1,61 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// PlanLinkedMove returns the changes that set a parameter to value and
+// shift every parameter linked to it by the same amount, so a soft/hard
+// cut pair moves together and keeps its gap
+func PlanLinkedMove(data []byte, name string, value float64) ([]CellChange, error) {
+	param, ok := models.FindConfigParam(name)
+	if !ok {
+		return nil, reader.NewError(reader.ErrNotFound, "parameter not found: %s", name)
+	}
+	current, err := reader.ReadConfigParamFromBytes(data, param)
+	if err != nil {
+		return nil, err
+	}
+	delta := value - current
+
+	values := reader.ReadConfigParamsFromBytes(data).Values
+	var changes []CellChange
+	for _, p := range models.LinkedGroup(param.Name) {
+		old, err := reader.ReadConfigParamFromBytes(data, p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name, err)
+		}
+		target := old + delta
+		if target < p.MinValue || target > p.MaxValue {
+			return nil, reader.NewError(reader.ErrValueOutOfBounds, "%s would move to %.2f, outside [%.2f, %.2f]", p.Name, target, p.MinValue, p.MaxValue)
+		}
+		raw, clamped := p.ToRaw(target)
+		if clamped {
+			return nil, reader.NewError(reader.ErrValueOutOfBounds, "value %.2f cannot be represented as %s", target, p.DataType)
+		}
+		values[p.Name] = p.ToReal(raw)
+
+		oldRaw := p.DecodeRaw(data[p.Offset:])
+		if raw == oldRaw {
+			continue
+		}
+		changes = append(changes, CellChange{
+			Map:        p.Name,
+			Offset:     p.Offset,
+			DataType:   p.DataType,
+			Endianness: p.ByteOrder(),
+			OldRaw:     oldRaw,
+			NewRaw:     raw,
+			OldValue:   old,
+			NewValue:   p.ToReal(raw),
+		})
+	}
+
+	if err := models.CheckLinks(values); err != nil {
+		return nil, reader.NewError(reader.ErrValueOutOfBounds, "%v", err)
+	}
+	return changes, nil
+}
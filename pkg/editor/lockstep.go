@@ -0,0 +1,164 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/tosih/motronic-m21-tool/internal/tabular"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// LinkedFile, when set, is a second binary that every session also writes
+// (-also-edit), for left/right or early/late ROM pairs that must stay in
+// sync. Each staged cell must hold the same raw value in both files unless
+// ForceMismatch is set; both files are backed up and logged separately.
+var LinkedFile string
+
+// ForceMismatch lets a linked session write cells whose current raw value
+// differs between the two files (-force-mismatch). The new raw value is
+// the one planned against the primary file.
+var ForceMismatch bool
+
+// Mismatch is a cell or parameter whose raw value differs between two
+// files
+type Mismatch struct {
+	Map    string
+	Row    int
+	Col    int
+	Offset int64
+	Raw1   int64
+	Raw2   int64
+}
+
+// FindMismatches returns the staged changes whose cell in linked does not
+// hold the raw value the change was planned from
+func FindMismatches(changes []CellChange, linked []byte) []Mismatch {
+	var mismatches []Mismatch
+	for _, c := range changes {
+		if c.Offset+int64(models.DataTypeSize(c.DataType)) > int64(len(linked)) {
+			continue // checkBounds already rejected it for the primary
+		}
+		if raw := models.DecodeRawOrder(linked[c.Offset:], c.DataType, c.Endianness); raw != c.OldRaw {
+			mismatches = append(mismatches, Mismatch{
+				Map: c.Map, Row: c.Row, Col: c.Col, Offset: c.Offset,
+				Raw1: c.OldRaw, Raw2: raw,
+			})
+		}
+	}
+	return mismatches
+}
+
+// Divergence returns every map cell and parameter whose raw value differs
+// between two images, in definition order. Definitions lying outside
+// either image are left out.
+func Divergence(data1, data2 []byte) []Mismatch {
+	size := int64(min(len(data1), len(data2)))
+	var mismatches []Mismatch
+	for _, cfg := range models.MapConfigs {
+		if cfg.Offset+cfg.ByteSize() > size {
+			continue
+		}
+		step := models.DataTypeSize(cfg.DataType)
+		for row := 0; row < cfg.Rows; row++ {
+			for col := 0; col < cfg.Cols; col++ {
+				offset := cfg.Offset + int64((row*cfg.Cols+col)*step)
+				raw1 := cfg.DecodeRaw(data1[offset:])
+				raw2 := cfg.DecodeRaw(data2[offset:])
+				if raw1 != raw2 {
+					mismatches = append(mismatches, Mismatch{Map: cfg.Name, Row: row, Col: col, Offset: offset, Raw1: raw1, Raw2: raw2})
+				}
+			}
+		}
+	}
+	for _, p := range models.ConfigParams {
+		if p.Offset+int64(models.DataTypeSize(p.DataType)) > size {
+			continue
+		}
+		raw1 := p.DecodeRaw(data1[p.Offset:])
+		raw2 := p.DecodeRaw(data2[p.Offset:])
+		if raw1 != raw2 {
+			mismatches = append(mismatches, Mismatch{Map: p.Name, Offset: p.Offset, Raw1: raw1, Raw2: raw2})
+		}
+	}
+	return mismatches
+}
+
+// DivergenceFiles reads two images and returns their Divergence
+func DivergenceFiles(file1, file2 string) ([]Mismatch, error) {
+	data1, err := os.ReadFile(file1)
+	if err != nil {
+		return nil, err
+	}
+	data2, err := os.ReadFile(file2)
+	if err != nil {
+		return nil, err
+	}
+	return Divergence(data1, data2), nil
+}
+
+// MismatchTable lists mismatches with the raw value in each file, the
+// columns headed by the two file names. The CLI renders it and the GUI
+// shows the same rows.
+func MismatchTable(mismatches []Mismatch, file1, file2 string) *tabular.Table {
+	t := tabular.New("Map", "Row", "Col", "Offset", filepath.Base(file1), filepath.Base(file2))
+	for _, m := range mismatches {
+		t.Add(m.Map, strconv.Itoa(m.Row), strconv.Itoa(m.Col), fmt.Sprintf("0x%04X", m.Offset),
+			strconv.FormatInt(m.Raw1, 10), strconv.FormatInt(m.Raw2, 10))
+	}
+	return t
+}
+
+// CheckLinked verifies that filename can be edited in lock step with
+// LinkedFile: the two must be different files of the same size
+func CheckLinked(filename string) error {
+	if LinkedFile == "" {
+		return nil
+	}
+	if sameFile(filename, LinkedFile) {
+		return fmt.Errorf("the linked file %s is the file being edited", LinkedFile)
+	}
+	info1, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	info2, err := os.Stat(LinkedFile)
+	if err != nil {
+		return err
+	}
+	if info1.Size() != info2.Size() {
+		return reader.NewError(reader.ErrOutOfRange, "%s is %d bytes but the linked file %s is %d bytes",
+			filepath.Base(filename), info1.Size(), filepath.Base(LinkedFile), info2.Size())
+	}
+	return nil
+}
+
+// sameFile reports whether two paths name the same file
+func sameFile(a, b string) bool {
+	info1, err1 := os.Stat(a)
+	info2, err2 := os.Stat(b)
+	if err1 != nil || err2 != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(info1, info2)
+}
+
+// MismatchSummary counts mismatches per map or parameter, in the order
+// they first appear, with the offset of the first differing cell
+func MismatchSummary(mismatches []Mismatch) *tabular.Table {
+	t := tabular.New("Map", "Cells", "First offset")
+	counts := map[string]int{}
+	var order []Mismatch
+	for _, m := range mismatches {
+		if counts[m.Map] == 0 {
+			order = append(order, m)
+		}
+		counts[m.Map]++
+	}
+	for _, m := range order {
+		t.Add(m.Map, strconv.Itoa(counts[m.Map]), fmt.Sprintf("0x%04X", m.Offset))
+	}
+	return t
+}
@@ -0,0 +1,399 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// How ApplyMapDefinitions combines a definitions file with the built-in
+// maps
+const (
+	MapsAppend  = "append"
+	MapsReplace = "replace"
+)
+
+// MapDefinition is one entry of a map definitions file and the line it
+// starts on
+type MapDefinition struct {
+	UserMap
+	Line int
+}
+
+// ApplyMapDefinitions loads the map definitions file of -maps and, by
+// mode, appends its maps to models.MapConfigs or replaces the built-in
+// ones with them. The file is used whole or not at all: any invalid or
+// overlapping entry rejects it, and the error lists every problem by line.
+func ApplyMapDefinitions(path, mode string) error {
+	if mode != MapsAppend && mode != MapsReplace {
+		return fmt.Errorf("unknown mode %q (want %s or %s)", mode, MapsAppend, MapsReplace)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	defs, err := ParseMapDefinitions(path, data)
+	if err != nil {
+		return err
+	}
+
+	var base []models.MapConfig
+	if mode == MapsAppend {
+		base = models.MapConfigs
+	}
+	maps, err := CheckMapDefinitions(path, defs, base)
+	if err != nil {
+		return err
+	}
+	if mode == MapsAppend {
+		models.MapConfigs = append(models.MapConfigs, maps...)
+		return nil
+	}
+	// Fuel, ignition and lambda are looked up by position
+	if len(maps) < models.FixedMaps {
+		return fmt.Errorf("%s: replacing the built-in maps needs at least %d definitions, fuel, ignition and lambda first; found %d",
+			path, models.FixedMaps, len(maps))
+	}
+	models.MapConfigs = maps
+	return nil
+}
+
+// CheckMapDefinitions validates defs in order against base and the
+// entries before them with models.CheckNewMap, for images up to
+// reader.MaxFileSize. Unlike the map wizard it refuses partial overlaps
+// too: a definitions file describes separate tables. It returns the maps
+// of defs, marked with the file as their Source and moved to
+// models.BaseOffset like the active ones.
+func CheckMapDefinitions(path string, defs []MapDefinition, base []models.MapConfig) ([]models.MapConfig, error) {
+	maps := append([]models.MapConfig(nil), base...)
+	source := filepath.Base(path)
+	var errs []error
+	for _, def := range defs {
+		cfg := models.Located(def.Config())
+		cfg.Source = source
+		check := models.CheckNewMap(cfg, maps, models.ConfigParams, reader.MaxFileSize)
+		for _, err := range check.Errors {
+			errs = append(errs, fmt.Errorf("%s:%d: %w", path, def.Line, err))
+		}
+		for _, o := range check.Overlaps {
+			errs = append(errs, fmt.Errorf("%s:%d: %s", path, def.Line, o))
+		}
+		// A rejected entry would only repeat its problems in later ones
+		if len(check.Errors) == 0 && len(check.Overlaps) == 0 {
+			maps = append(maps, cfg)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return maps[len(base):], nil
+}
+
+// ParseMapDefinitions reads the entries of a map definitions file: a JSON
+// array in the format of UserMapsFile or, for names ending in .yaml or
+// .yml, the same entries as a YAML list. Errors name the line they were
+// found on.
+func ParseMapDefinitions(path string, data []byte) ([]MapDefinition, error) {
+	var defs []MapDefinition
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		defs, err = parseYAMLDefinitions(path, data)
+	default:
+		defs, err = parseJSONDefinitions(path, data)
+	}
+	if err == nil && len(defs) == 0 {
+		err = fmt.Errorf("%s: no map definitions", path)
+	}
+	return defs, err
+}
+
+// parseJSONDefinitions decodes a JSON array of UserMap one entry at a
+// time, to know the line of each
+func parseJSONDefinitions(path string, data []byte) ([]MapDefinition, error) {
+	// Syntax errors first: only a whole-document decode reports their
+	// offset in the file
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineAt(data, syntax.Offset-1), err)
+		}
+		return nil, fmt.Errorf("%s:%d: expected a list of map definitions", path, lineAt(data, skipSpace(data, 0)))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.Token() // [
+	var defs []MapDefinition
+	var errs []error
+	for dec.More() {
+		var entry json.RawMessage
+		dec.Decode(&entry)
+		start := dec.InputOffset() - int64(len(entry))
+
+		var u UserMap
+		entryDec := json.NewDecoder(bytes.NewReader(entry))
+		entryDec.DisallowUnknownFields()
+		if err := entryDec.Decode(&u); err != nil {
+			line := lineAt(data, start)
+			var typ *json.UnmarshalTypeError
+			if errors.As(err, &typ) {
+				line = lineAt(data, start+typ.Offset-1)
+				err = fmt.Errorf("%s: cannot use %s as %s", typ.Field, typ.Value, typ.Type)
+			}
+			errs = append(errs, fmt.Errorf("%s:%d: %w", path, line, err))
+			continue
+		}
+		defs = append(defs, MapDefinition{UserMap: u, Line: lineAt(data, start)})
+	}
+	return defs, errors.Join(errs...)
+}
+
+// skipSpace returns the offset of the first non-space byte from off
+func skipSpace(data []byte, off int64) int64 {
+	for off < int64(len(data)) && strings.IndexByte(" \t\r\n", data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+// lineAt returns the 1-based line of byte offset off
+func lineAt(data []byte, off int64) int {
+	off = min(off, int64(len(data)))
+	return 1 + bytes.Count(data[:off], []byte{'\n'})
+}
+
+// yamlEntry is one list item of a YAML definitions file. Nested mappings
+// (the axes) are map[string]any; lines holds the line of every key, the
+// nested ones as "x_axis.count".
+type yamlEntry struct {
+	line   int
+	fields map[string]any
+	lines  map[string]int
+}
+
+// parseYAMLDefinitions reads the YAML form of a definitions file: a list of
+// mappings with one "key: value" per line and the axes as nested mappings.
+// It is a small subset of YAML, enough for definitions; anchors, flow
+// collections and multi-line strings are not supported.
+func parseYAMLDefinitions(path string, data []byte) ([]MapDefinition, error) {
+	fail := func(line int, format string, args ...interface{}) error {
+		return fmt.Errorf("%s:%d: %s", path, line, fmt.Sprintf(format, args...))
+	}
+
+	var entries []*yamlEntry
+	var entry *yamlEntry
+	var nested map[string]any // the mapping open under a key without value
+	var nestedKey string
+	listIndent, keyIndent, nestedIndent := -1, -1, -1
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := i + 1
+		text := strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		content := strings.TrimLeft(text, " ")
+		if content == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fail(line, "indent with spaces, not tabs")
+		}
+		indent := len(text) - len(content)
+
+		if content == "-" || strings.HasPrefix(content, "- ") {
+			if listIndent < 0 {
+				listIndent = indent
+			}
+			if indent != listIndent {
+				return nil, fail(line, "list item indented differently from the first one")
+			}
+			entry = &yamlEntry{line: line, fields: map[string]any{}, lines: map[string]int{}}
+			entries = append(entries, entry)
+			nested = nil
+			rest := strings.TrimLeft(content[1:], " ")
+			if rest == "" {
+				keyIndent = -1
+				continue
+			}
+			keyIndent = indent + len(content) - len(rest)
+			content, indent = rest, keyIndent
+		}
+		if entry == nil {
+			return nil, fail(line, "expected a list of map definitions, each starting with \"- \"")
+		}
+
+		key, value, ok := strings.Cut(content, ":")
+		if !ok || (value != "" && value[0] != ' ') {
+			return nil, fail(line, "expected \"key: value\"")
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if keyIndent < 0 {
+			keyIndent = indent
+		}
+
+		switch {
+		case indent == keyIndent:
+			nested = nil
+			if _, dup := entry.fields[key]; dup {
+				return nil, fail(line, "%s is given twice", key)
+			}
+			entry.lines[key] = line
+			if value == "" {
+				nested, nestedKey, nestedIndent = map[string]any{}, key, -1
+				entry.fields[key] = nested
+				continue
+			}
+			scalar, err := parseYAMLScalar(value)
+			if err != nil {
+				return nil, fail(line, "%s: %v", key, err)
+			}
+			entry.fields[key] = scalar
+		case indent > keyIndent && nested != nil:
+			if nestedIndent < 0 {
+				nestedIndent = indent
+			}
+			if indent != nestedIndent {
+				return nil, fail(line, "%s is indented differently from the keys above it", key)
+			}
+			if value == "" {
+				return nil, fail(line, "%s: only one level of nesting is supported", key)
+			}
+			if _, dup := nested[key]; dup {
+				return nil, fail(line, "%s.%s is given twice", nestedKey, key)
+			}
+			scalar, err := parseYAMLScalar(value)
+			if err != nil {
+				return nil, fail(line, "%s: %v", key, err)
+			}
+			nested[key] = scalar
+			entry.lines[nestedKey+"."+key] = line
+		default:
+			return nil, fail(line, "%s is indented differently from the keys above it", key)
+		}
+	}
+
+	defs := make([]MapDefinition, 0, len(entries))
+	var errs []error
+	for _, e := range entries {
+		u, line, err := e.userMap()
+		if err != nil {
+			errs = append(errs, fail(line, "%v", err))
+			continue
+		}
+		defs = append(defs, MapDefinition{UserMap: u, Line: e.line})
+	}
+	return defs, errors.Join(errs...)
+}
+
+// userMap converts the entry to a UserMap through JSON, which checks the
+// value types. On error it also returns the line of the offending key.
+func (e *yamlEntry) userMap() (UserMap, int, error) {
+	fields, axisFields := jsonFields(reflect.TypeOf(UserMap{})), jsonFields(reflect.TypeOf(UserAxis{}))
+	for key, value := range e.fields {
+		if !fields[key] {
+			return UserMap{}, e.lines[key], fmt.Errorf("unknown field %q", key)
+		}
+		axis, ok := value.(map[string]any)
+		if key != "x_axis" && key != "y_axis" {
+			if ok {
+				return UserMap{}, e.lines[key], fmt.Errorf("%s takes a value, not a mapping", key)
+			}
+			continue
+		}
+		if !ok {
+			return UserMap{}, e.lines[key], fmt.Errorf("%s needs offset, count, data_type and scale on the lines below it", key)
+		}
+		for k := range axis {
+			if !axisFields[k] {
+				return UserMap{}, e.lines[key+"."+k], fmt.Errorf("unknown field %q", key+"."+k)
+			}
+		}
+	}
+
+	data, err := json.Marshal(e.fields)
+	if err != nil {
+		return UserMap{}, e.line, err
+	}
+	var u UserMap
+	if err := json.Unmarshal(data, &u); err != nil {
+		var typ *json.UnmarshalTypeError
+		if errors.As(err, &typ) && e.lines[typ.Field] != 0 {
+			return UserMap{}, e.lines[typ.Field], fmt.Errorf("%s: cannot use %s as %s", typ.Field, typ.Value, typ.Type)
+		}
+		return UserMap{}, e.line, err
+	}
+	return u, 0, nil
+}
+
+// jsonFields returns the JSON keys of the fields of struct type t
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	return fields
+}
+
+// parseYAMLScalar returns a YAML value as a string, bool, int64 (decimal or
+// 0x hex, as offsets are usually written) or float64. null and ~ are nil.
+func parseYAMLScalar(value string) (any, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad quoted string %s", value)
+		}
+		return s, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return nil, fmt.Errorf("bad quoted string %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case value == "true" || value == "false":
+		return value == "true", nil
+	case value == "null" || value == "~":
+		return nil, nil
+	case strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") ||
+		strings.HasPrefix(value, "&") || strings.HasPrefix(value, "*") ||
+		value == "|" || value == ">":
+		return nil, fmt.Errorf("%s is not supported in map definitions", value)
+	}
+	if n, err := strconv.ParseInt(value, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f, nil
+	}
+	return value, nil
+}
+
+// stripYAMLComment removes a # comment that is not inside quotes
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || line[i-1] == ' '):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
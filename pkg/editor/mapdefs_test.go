@@ -0,0 +1,153 @@
+package editor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+const yamlDefinitions = `---
+# Tables found on a modified image
+- name: "Boost # target"   # the hash in quotes is kept
+  offset: 0x5000
+  rows: 8
+  cols: 8
+  data_type: uint8
+  scale: 0.01
+  value_offset: -1
+  unit: 'driver''s bar'
+  invert_y: true
+  x_axis:
+    offset: 0x4FF0
+    count: 8
+    data_type: uint8
+    scale: 40
+    value_offset: 0
+    unit: RPM
+  y_axis:
+      offset: 20464   # deeper indent is fine if the keys agree
+      count: 8
+      data_type: uint16
+      scale: 0.5
+      value_offset: 0
+      endianness: big
+
+-
+  name: Idle Target
+  offset: 20736
+  rows: 1
+  cols: 4
+  data_type: int16
+  scale: 2.5
+  value_offset: 0
+  unit: "°KW"
+  description: ~
+`
+
+func TestParseYAMLDefinitions(t *testing.T) {
+	defs, err := ParseMapDefinitions("maps.yaml", []byte(yamlDefinitions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MapDefinition{
+		{Line: 3, UserMap: UserMap{
+			Name: "Boost # target", Offset: 0x5000, Rows: 8, Cols: 8, DataType: "uint8", Scale: 0.01, ValueOffset: -1,
+			Unit: "driver's bar", InvertY: true,
+			XAxis: &UserAxis{Offset: 0x4FF0, Count: 8, DataType: "uint8", Scale: 40, Unit: "RPM"},
+			YAxis: &UserAxis{Offset: 0x4FF0, Count: 8, DataType: "uint16", Scale: 0.5, Endianness: models.BigEndian},
+		}},
+		{Line: 27, UserMap: UserMap{
+			Name: "Idle Target", Offset: 0x5100, Rows: 1, Cols: 4, DataType: "int16", Scale: 2.5, Unit: "°KW",
+		}},
+	}
+	if !reflect.DeepEqual(defs, want) {
+		t.Errorf("parsed\n%+v\nwant\n%+v", defs, want)
+	}
+}
+
+func TestParseYAMLDefinitionsErrors(t *testing.T) {
+	const entry = "- name: A\n  offset: 0x5000\n  rows: 1\n  cols: 1\n  data_type: uint8\n  scale: 1\n  value_offset: 0\n"
+	tests := []struct {
+		name, yaml, want string
+	}{
+		{"no list", "name: A\n", `maps.yaml:1: expected a list of map definitions`},
+		{"tab indent", "- name: A\n\toffset: 1\n", "maps.yaml:2: indent with spaces, not tabs"},
+		{"no colon", "- name: A\n  offset 1\n", `maps.yaml:2: expected "key: value"`},
+		{"no space after colon", "- name: A\n  offset:1\n", `maps.yaml:2: expected "key: value"`},
+		{"list indent", entry + "  - name: B\n", "maps.yaml:8: list item indented differently"},
+		{"key indent", "- name: A\n   offset: 1\n", "maps.yaml:2: offset is indented differently"},
+		{"duplicate key", "- name: A\n  name: B\n", "maps.yaml:2: name is given twice"},
+		{"nested indent", "- x_axis:\n    offset: 1\n      count: 8\n", "maps.yaml:3: count is indented differently"},
+		{"nested twice", "- x_axis:\n    count: 1\n    count: 2\n", "maps.yaml:3: x_axis.count is given twice"},
+		{"two levels", "- x_axis:\n    range:\n      min: 1\n", "maps.yaml:2: range: only one level of nesting"},
+		{"flow list", "- name: A\n  rows: [1, 2]\n", "maps.yaml:2: rows: [1, 2] is not supported"},
+		{"anchor", "- name: &a A\n", "maps.yaml:1: name: &a A is not supported"},
+		{"block string", "- description: |\n", "maps.yaml:1: description: | is not supported"},
+		{"bad quote", "- name: \"A\n", `maps.yaml:1: name: bad quoted string "A`},
+		{"unknown field", entry + "  colour: red\n", `maps.yaml:8: unknown field "colour"`},
+		{"unknown axis field", entry + "  x_axis:\n    colour: red\n", `maps.yaml:9: unknown field "x_axis.colour"`},
+		{"axis without mapping", entry + "  x_axis: 0x4000\n", "maps.yaml:8: x_axis needs offset, count"},
+		{"mapping for a value", "- name:\n    first: A\n", "maps.yaml:1: name takes a value, not a mapping"},
+		{"wrong type", "- name: A\n  rows: eight\n", "maps.yaml:2: rows: cannot use string as int"},
+		{"empty", "# nothing here\n---\n", "maps.yaml: no map definitions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMapDefinitions("maps.yaml", []byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// Every entry's problem is reported, not only the first one's
+func TestParseYAMLDefinitionsAllErrors(t *testing.T) {
+	yaml := "- name: A\n  rows: x\n- name: B\n  cols: y\n"
+	_, err := ParseMapDefinitions("maps.yml", []byte(yaml))
+	if err == nil {
+		t.Fatal("no error")
+	}
+	for _, want := range []string{"maps.yml:2: rows", "maps.yml:4: cols"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+}
+
+// A YAML file and the JSON file of the same maps read the same
+func TestYAMLMatchesJSON(t *testing.T) {
+	json := `[
+  {"name": "Idle Target", "offset": 20736, "rows": 1, "cols": 4, "data_type": "int16",
+   "scale": 2.5, "value_offset": 0, "unit": "°KW",
+   "x_axis": {"offset": 20720, "count": 4, "data_type": "uint8", "scale": 40, "value_offset": 0}}
+]`
+	yaml := `- name: Idle Target
+  offset: 0x5100
+  rows: 1
+  cols: 4
+  data_type: int16
+  scale: 2.5
+  value_offset: 0
+  unit: °KW
+  x_axis:
+    offset: 0x50F0
+    count: 4
+    data_type: uint8
+    scale: 40
+    value_offset: 0
+`
+	fromJSON, err := ParseMapDefinitions("maps.json", []byte(json))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := ParseMapDefinitions("maps.yaml", []byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON[0].UserMap, fromYAML[0].UserMap) {
+		t.Errorf("JSON read as %+v, YAML as %+v", fromJSON[0].UserMap, fromYAML[0].UserMap)
+	}
+}
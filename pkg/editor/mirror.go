@@ -0,0 +1,52 @@
+package editor
+
+import (
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// MirrorWrites makes a save to a mirrored dump, one holding the image
+// more than once (see reader.Layout), write every change to each copy
+// (-mirror-writes or the mirror_writes setting). Without it only the
+// first copy is written and the report says the copies now differ.
+var MirrorWrites bool
+
+// planMirror records in the report whether the snapshot is a mirrored
+// dump and, under MirrorWrites, returns its layout for mirrorChanges.
+// Otherwise the save leaves the other copies stale.
+func planMirror(snapshot []byte, report *Report) *reader.Layout {
+	layout := reader.DetectLayout(snapshot)
+	if !layout.Mirrored() {
+		return nil
+	}
+	report.Copies = layout.Copies
+	if !MirrorWrites {
+		report.MirrorStale = true
+		return nil
+	}
+	return &layout
+}
+
+// mirrorChanges repeats changes made to the first copy of a mirrored dump
+// in the other copies of work
+func mirrorChanges(work []byte, layout reader.Layout, changes []CellChange) {
+	for _, c := range changes {
+		for _, offset := range layout.MirrorOffsets(c.Offset, int64(models.DataTypeSize(c.DataType))) {
+			c.Offset = offset
+			c.Apply(work)
+		}
+	}
+}
+
+// PrintMirror reports a mirrored dump whose copies the save kept equal, or
+// loudly that only the first one was written
+func (r *Report) PrintMirror() {
+	switch {
+	case r.Copies < 2:
+	case r.MirrorStale:
+		pterm.Warning.Printf("Mirrored dump: only the first of %d copies of the image was written, so the copies now differ; -mirror-writes writes all of them\n", r.Copies)
+	default:
+		pterm.Success.Printf("Mirrored dump: written to all %d copies of the image\n", r.Copies)
+	}
+}
@@ -0,0 +1,83 @@
+package editor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// writeMirrored writes the synthetic image twice over, a 27C256 image read
+// as a 27C512, to a temporary file named name
+func writeMirrored(t *testing.T, name string) (string, []byte) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	data := bytes.Repeat(testbin.Image(), 2)
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file, data
+}
+
+func TestMirrorWrites(t *testing.T) {
+	t.Cleanup(func() { MirrorWrites, LinkedFile = false, "" })
+	for _, mirror := range []bool{true, false} {
+		name := "first copy only"
+		if mirror {
+			name = "every copy"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+			MirrorWrites = mirror
+			file, before := writeMirrored(t, "ecu.bin")
+			linked, _ := writeMirrored(t, "also.bin")
+			LinkedFile = linked
+			size := int64(len(before) / 2)
+
+			s, err := NewSession(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Add(change("first", 0, 2))
+			s.Add(change("second", 3, 3))
+			report, err := s.Commit()
+			if err != nil || !report.Written {
+				t.Fatalf("Commit = %v, written %v", err, report.Written)
+			}
+			if report.Copies != 2 || report.MirrorStale == mirror {
+				t.Errorf("report of %d copies, stale %v", report.Copies, report.MirrorStale)
+			}
+
+			want := bytes.Clone(before)
+			fuelCell(before, 0, 2).Apply(want)
+			fuelCell(before, 3, 3).Apply(want)
+			if mirror {
+				copy(want[size:], want[:size])
+			}
+			for _, f := range []string{file, linked} {
+				after, err := os.ReadFile(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(after, want) {
+					t.Errorf("%s: first copy changed %v, second copy changed %v", filepath.Base(f),
+						!bytes.Equal(after[:size], before[:size]), !bytes.Equal(after[size:], before[size:]))
+				}
+				if got := reader.DetectLayout(after).Mirrored(); got != mirror {
+					t.Errorf("%s mirrored %v after the save", filepath.Base(f), got)
+				}
+			}
+
+			entries, err := ReadChangelog(file)
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("changelog %v, %v", entries, err)
+			}
+			if e := entries[0]; e.MirrorStale == mirror || (e.Mirrored == 2) != mirror {
+				t.Errorf("changelog records mirrored %d, stale %v", e.Mirrored, e.MirrorStale)
+			}
+		})
+	}
+}
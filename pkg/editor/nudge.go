@@ -0,0 +1,169 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// NudgeSpec moves one cell by a number of nudge steps, as given to -nudge
+type NudgeSpec struct {
+	Map   models.MapConfig
+	Row   int
+	Col   int
+	Steps int
+}
+
+// ParseNudge parses "map:row,col:steps", e.g. "ignition:3,7:+1". The map
+// is a full map name or a part of exactly one map name.
+func ParseNudge(s string) (NudgeSpec, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return NudgeSpec{}, fmt.Errorf("invalid nudge %q: expected map:row,col:steps", s)
+	}
+
+	cfg, err := MatchMap(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return NudgeSpec{}, err
+	}
+
+	cell := strings.Split(parts[1], ",")
+	if len(cell) != 2 {
+		return NudgeSpec{}, fmt.Errorf("invalid cell %q: expected row,col", parts[1])
+	}
+	row, err1 := strconv.Atoi(strings.TrimSpace(cell[0]))
+	col, err2 := strconv.Atoi(strings.TrimSpace(cell[1]))
+	if err1 != nil || err2 != nil {
+		return NudgeSpec{}, fmt.Errorf("invalid cell %q: expected row,col", parts[1])
+	}
+	if row < 0 || row >= cfg.Rows || col < 0 || col >= cfg.Cols {
+		return NudgeSpec{}, reader.NewError(reader.ErrOutOfRange, "invalid cell coordinates: [%d,%d]", row, col)
+	}
+
+	steps, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(parts[2]), "+"))
+	if err != nil || steps == 0 {
+		return NudgeSpec{}, fmt.Errorf("invalid steps %q: expected a non-zero whole number such as +1 or -2", parts[2])
+	}
+	return NudgeSpec{Map: cfg, Row: row, Col: col, Steps: steps}, nil
+}
+
+// MatchMap finds a map by exact name, else by a whole word of exactly one
+// name ("fuel" is the Main Fuel Map), else by a part of exactly one name
+func MatchMap(name string) (models.MapConfig, error) {
+	if cfg, ok := models.FindMapConfig(name); ok {
+		return cfg, nil
+	}
+
+	var byWord, byPart []models.MapConfig
+	for _, cfg := range models.MapConfigs {
+		if !strings.Contains(strings.ToLower(cfg.Name), strings.ToLower(name)) {
+			continue
+		}
+		byPart = append(byPart, cfg)
+		for _, word := range strings.Fields(cfg.Name) {
+			if strings.EqualFold(word, name) {
+				byWord = append(byWord, cfg)
+				break
+			}
+		}
+	}
+
+	matches := byPart
+	if len(byWord) == 1 {
+		matches = byWord
+	}
+	switch len(matches) {
+	case 0:
+		return models.MapConfig{}, reader.NewError(reader.ErrNotFound, "unknown map: %s", name)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, cfg := range matches {
+		names[i] = cfg.Name
+	}
+	return models.MapConfig{}, fmt.Errorf("%q matches several maps: %s", name, strings.Join(names, ", "))
+}
+
+// PlanNudge returns the change that moves one cell by steps nudges (see
+// MapConfig.Nudge). Nudging past the data type limit is an error.
+func PlanNudge(data []byte, cfg models.MapConfig, row, col, steps int) ([]CellChange, error) {
+	if row < 0 || row >= cfg.Rows || col < 0 || col >= cfg.Cols {
+		return nil, reader.NewError(reader.ErrOutOfRange, "invalid cell coordinates: [%d,%d]", row, col)
+	}
+	offset := cfg.Offset + int64((row*cfg.Cols+col)*models.DataTypeSize(cfg.DataType))
+	if offset+int64(models.DataTypeSize(cfg.DataType)) > int64(len(data)) {
+		return nil, reader.NewError(reader.ErrOutOfRange, "%s at 0x%04X lies outside the file", cfg.Name, cfg.Offset)
+	}
+
+	oldRaw := cfg.DecodeRaw(data[offset:])
+	newRaw, clamped := cfg.Nudge(oldRaw, steps)
+	if newRaw == oldRaw {
+		if clamped {
+			return nil, reader.NewError(reader.ErrValueOutOfBounds, "%s [%d,%d] is already at its limit (%.2f %s)", cfg.Name, row, col, cfg.ToReal(oldRaw), cfg.Unit)
+		}
+		return nil, nil
+	}
+	return []CellChange{{
+		Map:        cfg.Name,
+		Row:        row,
+		Col:        col,
+		Offset:     offset,
+		DataType:   cfg.DataType,
+		Endianness: cfg.ByteOrder(),
+		OldRaw:     oldRaw,
+		NewRaw:     newRaw,
+		OldValue:   cfg.ToReal(oldRaw),
+		NewValue:   cfg.ToReal(newRaw),
+	}}, nil
+}
+
+// ApplyNudge plans, confirms and writes a one-shot -nudge
+func ApplyNudge(prompt Prompter, filename string, spec NudgeSpec, dryRun bool) bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read file: %v\n", err)
+		return false
+	}
+	changes, err := PlanNudge(data, spec.Map, spec.Row, spec.Col, spec.Steps)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+	pterm.Info.Printf("Nudging %s [%d,%d] by %+d × %s\n", spec.Map.Name, spec.Row, spec.Col, spec.Steps, spec.Map.StepLabel())
+	if len(changes) == 0 {
+		pterm.Info.Println("No cells need changing.")
+		return true
+	}
+	PrintChanges(changes)
+
+	if dryRun {
+		pterm.Warning.Println(i18n.T("cli.dry_run"))
+		return true
+	}
+	if err := reader.CheckWritable(filename); err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return false
+	}
+	if !Confirm(prompt, ConfirmSave, i18n.T("cli.confirm.change")) {
+		pterm.Info.Println(i18n.T("cli.cancelled"))
+		return true
+	}
+
+	report, err := applyChanges(filename, fmt.Sprintf("Nudge %s [%d,%d]", spec.Map.Name, spec.Row, spec.Col), changes)
+	PrintBackup(report.Backup)
+	if err != nil {
+		pterm.Error.Printf("Failed to write: %s\n", reader.DescribeWriteError(err))
+		return false
+	}
+	pterm.Success.Printf("%s [%d,%d] set to %.2f %s\n", spec.Map.Name, spec.Row, spec.Col, changes[0].NewValue, spec.Map.Unit)
+	report.PrintChecksum()
+	report.PrintMirror()
+	return true
+}
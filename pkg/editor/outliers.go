@@ -0,0 +1,125 @@
+package editor
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// DefaultOutlierShare is the share of a map's value range a cell may
+// deviate from its neighborhood median before it counts as an outlier,
+// when no threshold is given
+const DefaultOutlierShare = 0.10
+
+// Neighborhood returns the values of the cells at most radius rows and
+// columns away from [row,col], the cell itself included. At the edges only
+// cells inside the map are used, so a corner has a 2x2 neighborhood for
+// radius 1 rather than a padded or wrapped one.
+func Neighborhood(data [][]float64, row, col, radius int) []float64 {
+	var values []float64
+	for i := max(row-radius, 0); i <= min(row+radius, len(data)-1); i++ {
+		for j := max(col-radius, 0); j <= min(col+radius, len(data[i])-1); j++ {
+			values = append(values, data[i][j])
+		}
+	}
+	return values
+}
+
+// Median returns the median of values, averaging the middle two of an
+// even count. values is sorted in place.
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
+// Outlier is a cell that deviates from the median of its 3x3 neighborhood
+type Outlier struct {
+	Row    int
+	Col    int
+	Value  float64
+	Median float64
+	// Smoothed is the median snapped to the nearest value the map can store
+	Smoothed float64
+}
+
+// Deviation returns how far the cell lies above (positive) or below the
+// neighborhood median
+func (o Outlier) Deviation() float64 { return o.Value - o.Median }
+
+// OutlierThreshold returns threshold if it is positive, otherwise
+// DefaultOutlierShare of the range of m's values
+func OutlierThreshold(m *models.ECUMap, threshold float64) float64 {
+	if threshold > 0 {
+		return threshold
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, row := range m.Data {
+		for _, value := range row {
+			lo, hi = math.Min(lo, value), math.Max(hi, value)
+		}
+	}
+	return (hi - lo) * DefaultOutlierShare
+}
+
+// FindOutliers returns the cells of m deviating from the median of their
+// 3x3 neighborhood (see Neighborhood) by more than threshold engineering
+// units, in row-major order. A flat map has none.
+func FindOutliers(m *models.ECUMap, threshold float64) []Outlier {
+	var outliers []Outlier
+	for i, row := range m.Data {
+		for j, value := range row {
+			median := Median(Neighborhood(m.Data, i, j, 1))
+			if math.Abs(value-median) <= threshold {
+				continue
+			}
+			raw, _ := m.Config.ToRaw(median)
+			outliers = append(outliers, Outlier{
+				Row: i, Col: j, Value: value, Median: median,
+				Smoothed: m.Config.ToReal(raw),
+			})
+		}
+	}
+	return outliers
+}
+
+// PlanOutlierSmoothing returns the changes that set each outlier to its
+// smoothed value. Outliers whose smoothed value stores as the same raw
+// value are left out.
+func PlanOutlierSmoothing(data []byte, cfg models.MapConfig, outliers []Outlier) ([]CellChange, error) {
+	if cfg.Offset+cfg.ByteSize() > int64(len(data)) {
+		return nil, reader.NewError(reader.ErrOutOfRange, "%s at 0x%04X lies outside the file", cfg.Name, cfg.Offset)
+	}
+
+	size := models.DataTypeSize(cfg.DataType)
+	var changes []CellChange
+	for _, o := range outliers {
+		offset := cfg.Offset + int64((o.Row*cfg.Cols+o.Col)*size)
+		oldRaw := cfg.DecodeRaw(data[offset:])
+		newRaw, _ := cfg.ToRaw(o.Smoothed)
+		if newRaw == oldRaw {
+			continue
+		}
+		changes = append(changes, CellChange{
+			Map:        cfg.Name,
+			Row:        o.Row,
+			Col:        o.Col,
+			Offset:     offset,
+			DataType:   cfg.DataType,
+			Endianness: cfg.ByteOrder(),
+			OldRaw:     oldRaw,
+			NewRaw:     newRaw,
+			OldValue:   cfg.ToReal(oldRaw),
+			NewValue:   cfg.ToReal(newRaw),
+		})
+	}
+	return changes, nil
+}
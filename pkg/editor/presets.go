@@ -0,0 +1,299 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// PresetParam describes one argument accepted by a parameterized preset
+type PresetParam struct {
+	Name        string
+	Description string
+	Default     float64
+	Min         float64
+	Max         float64
+	Integer     bool
+}
+
+// CellChange is a single cell modification planned by a preset
+type CellChange struct {
+	Map      string
+	Row      int
+	Col      int
+	Offset   int64
+	DataType string
+	// Endianness is the byte order of multi-byte values, the map's or
+	// parameter's ByteOrder; empty is little-endian
+	Endianness models.Endianness `json:",omitempty"`
+	OldRaw     int64
+	NewRaw     int64
+	OldValue   float64
+	NewValue   float64
+	// Forced marks a cell written outside its map's limits with -force
+	// or the GUI's "write anyway"
+	Forced bool `json:",omitempty"`
+}
+
+// Apply writes the change's new raw value into data
+func (c CellChange) Apply(data []byte) {
+	models.EncodeRawOrder(data[c.Offset:], c.DataType, c.NewRaw, c.Endianness)
+}
+
+// Preset is a parameterized modification. Plan computes the cell changes
+// for the given file contents without modifying anything, so callers can
+// show a dry-run diff before writing.
+type Preset struct {
+	Name        string
+	Description string
+	Params      []PresetParam
+	Plan        func(data []byte, args map[string]float64) ([]CellChange, error)
+}
+
+// Presets is the registry of parameterized presets available to the CLI
+// (-preset name -args "k=v,...") and the GUI preset dialog
+var Presets = []Preset{
+	{
+		Name:        "lambda-openloop",
+		Description: "Set Lambda Target Map cells at or above a load row to a fixed open-loop value",
+		Params: []PresetParam{
+			{Name: "row", Description: "First load row of the open-loop region", Default: 5, Min: 0, Max: 7, Integer: true},
+			{Name: "value", Description: "Open-loop lambda target", Default: 0.88, Min: 0.80, Max: 1.05},
+		},
+		Plan: planLambdaOpenLoop,
+	},
+	{
+		Name:        "boost",
+		Description: "Raise the boost map's targets by delta, never above ceiling, leaving the first rows and columns stock",
+		Params: []PresetParam{
+			{Name: "delta", Description: "Amount added to each target, in the boost map's unit (bar)", Default: 0.1, Min: 0.01, Max: 0.5},
+			{Name: "ceiling", Description: "Highest target any raised cell may reach", Default: 1.0, Min: 0, Max: 2.5},
+			{Name: "cols", Description: "Number of leading RPM columns left stock", Default: 2, Min: 0, Max: 16, Integer: true},
+			{Name: "rows", Description: "Number of leading load rows left stock", Default: 0, Min: 0, Max: 16, Integer: true},
+		},
+		Plan: planBoost,
+	},
+}
+
+// FindPreset returns the registered preset with the given name
+func FindPreset(name string) (Preset, bool) {
+	for _, p := range Presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// ParseArgs parses "key=value,key=value" preset arguments, filling in
+// defaults and validating each value against the preset's parameter schema
+func (p Preset) ParseArgs(argStr string) (map[string]float64, error) {
+	args := make(map[string]float64)
+	for _, param := range p.Params {
+		args[param.Name] = param.Default
+	}
+
+	for _, pair := range strings.Split(argStr, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, valueStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, errors.New(i18n.T("validate.arg_syntax", pair))
+		}
+		key = strings.TrimSpace(key)
+		if _, known := args[key]; !known {
+			return nil, errors.New(i18n.T("validate.arg_unknown", key, p.Name))
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+		if err != nil {
+			return nil, errors.New(i18n.T("validate.arg_value", key, err))
+		}
+		args[key] = value
+	}
+
+	return args, p.ValidateArgs(args)
+}
+
+// ValidateArgs checks every argument against its parameter range
+func (p Preset) ValidateArgs(args map[string]float64) error {
+	for _, param := range p.Params {
+		value := args[param.Name]
+		if value < param.Min || value > param.Max {
+			return reader.NewError(reader.ErrValueOutOfBounds, i18n.T("validate.arg_range"), param.Name, value, param.Min, param.Max)
+		}
+		if param.Integer && value != math.Trunc(value) {
+			return errors.New(i18n.T("validate.arg_whole", param.Name))
+		}
+	}
+	return nil
+}
+
+// ApplyChanges backs up the file and writes the planned cell changes.
+// It returns the backup path.
+func ApplyChanges(filename string, changes []CellChange) (string, error) {
+	report, err := applyChanges(filename, "changes", changes)
+	return report.Backup, err
+}
+
+// applyChanges writes the planned cell changes in a session as the
+// operation name, which the changelog entry records, and returns its
+// report, which is never nil
+func applyChanges(filename, name string, changes []CellChange) (*Report, error) {
+	session, err := NewSession(filename)
+	if err != nil {
+		return &Report{}, err
+	}
+	defer session.Close()
+	session.Add(Operation{Name: name, Plan: func([]byte) ([]CellChange, error) {
+		return changes, nil
+	}})
+	return session.Commit()
+}
+
+// SortChanges orders changes by map, row and column for display
+func SortChanges(changes []CellChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Map != changes[j].Map {
+			return changes[i].Map < changes[j].Map
+		}
+		if changes[i].Row != changes[j].Row {
+			return changes[i].Row < changes[j].Row
+		}
+		return changes[i].Col < changes[j].Col
+	})
+}
+
+func planLambdaOpenLoop(data []byte, args map[string]float64) ([]CellChange, error) {
+	cfg := models.MapConfigs[2] // Lambda Target Map
+	startRow := int(args["row"])
+	target := args["value"]
+
+	if startRow >= cfg.Rows {
+		return nil, reader.NewError(reader.ErrOutOfRange, "row %d out of range (map has %d rows)", startRow, cfg.Rows)
+	}
+	if cfg.Offset+cfg.ByteSize() > int64(len(data)) {
+		return nil, reader.NewError(reader.ErrOutOfRange, "%s extends past end of file", cfg.Name)
+	}
+
+	newRaw, _ := cfg.ToRaw(target)
+
+	var changes []CellChange
+	for row := startRow; row < cfg.Rows; row++ {
+		for col := 0; col < cfg.Cols; col++ {
+			offset := cfg.Offset + int64((row*cfg.Cols+col)*models.DataTypeSize(cfg.DataType))
+			oldRaw := cfg.DecodeRaw(data[offset:])
+			if oldRaw == newRaw {
+				continue
+			}
+			changes = append(changes, CellChange{
+				Map:        cfg.Name,
+				Row:        row,
+				Col:        col,
+				Offset:     offset,
+				DataType:   cfg.DataType,
+				Endianness: cfg.ByteOrder(),
+				OldRaw:     oldRaw,
+				NewRaw:     newRaw,
+				OldValue:   cfg.ToReal(oldRaw),
+				NewValue:   cfg.ToReal(newRaw),
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// planBoost raises the cells of the map designated as the boost map by
+// delta, clamping each to the ceiling and the data type range. Cells
+// already above the ceiling are left alone rather than lowered.
+func planBoost(data []byte, args map[string]float64) ([]CellChange, error) {
+	cfg, ok := models.MapByRole(models.RoleBoost)
+	if !ok {
+		return nil, fmt.Errorf("no boost map is designated in the map definitions")
+	}
+	if cfg.Unconfirmed {
+		return nil, fmt.Errorf("%s is designated as the boost map but is unconfirmed; refusing to modify it", cfg.Name)
+	}
+	if cfg.Offset+cfg.ByteSize() > int64(len(data)) {
+		return nil, reader.NewError(reader.ErrOutOfRange, "%s extends past end of file", cfg.Name)
+	}
+
+	delta, ceiling := args["delta"], args["ceiling"]
+	stockCols, stockRows := int(args["cols"]), int(args["rows"])
+
+	var changes []CellChange
+	for row := stockRows; row < cfg.Rows; row++ {
+		for col := stockCols; col < cfg.Cols; col++ {
+			offset := cfg.Offset + int64((row*cfg.Cols+col)*models.DataTypeSize(cfg.DataType))
+			oldRaw := cfg.DecodeRaw(data[offset:])
+			oldValue := cfg.ToReal(oldRaw)
+			if oldValue >= ceiling {
+				continue
+			}
+			newRaw, _ := cfg.ToRaw(math.Min(oldValue+delta, ceiling))
+			newRaw, ok := rawAtMost(cfg, newRaw, ceiling)
+			if !ok || newRaw == oldRaw || cfg.ToReal(newRaw) < oldValue {
+				continue
+			}
+			changes = append(changes, CellChange{
+				Map:        cfg.Name,
+				Row:        row,
+				Col:        col,
+				Offset:     offset,
+				DataType:   cfg.DataType,
+				Endianness: cfg.ByteOrder(),
+				OldRaw:     oldRaw,
+				NewRaw:     newRaw,
+				OldValue:   oldValue,
+				NewValue:   cfg.ToReal(newRaw),
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// rawAtMost steps a raw value rounded to the nearest representable value
+// back to limit or below if rounding overshot it. It reports false if no
+// neighbouring raw value is within the limit.
+func rawAtMost(cfg models.MapConfig, raw int64, limit float64) (int64, bool) {
+	const epsilon = 1e-9
+	lo, hi := models.RawRange(cfg.DataType)
+	for _, r := range []int64{raw, raw - 1, raw + 1} {
+		if r >= lo && r <= hi && cfg.ToReal(r) <= limit+epsilon {
+			return r, true
+		}
+	}
+	return raw, false
+}
+
+// PrintChanges renders planned changes as an old/new table with the
+// relative change of each cell
+func PrintChanges(changes []CellChange) {
+	tableData := pterm.TableData{{"Map", "Row", "Col", "Old", "New", "Change"}}
+	for _, c := range changes {
+		change := "-"
+		if c.OldValue != 0 {
+			change = fmt.Sprintf("%+.1f%%", (c.NewValue/c.OldValue-1)*100)
+		}
+		tableData = append(tableData, []string{
+			c.Map,
+			strconv.Itoa(c.Row),
+			strconv.Itoa(c.Col),
+			fmt.Sprintf("%.3f", c.OldValue),
+			fmt.Sprintf("%.3f", c.NewValue),
+			change,
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
@@ -0,0 +1,224 @@
+package editor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/internal/paths"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// ProfilesDir is the directory in the config directory holding profile
+// files, one JSON file per firmware variant
+const ProfilesDir = "profiles"
+
+// ProfileFile is the stored form of a models.Profile. Maps are entries as
+// in UserMapsFile and replace the built-in ones, so they must keep their
+// order: fuel, ignition and lambda first. The cold start map is found by
+// its role.
+type ProfileFile struct {
+	Name          string          `json:"name"`
+	Description   string          `json:"description,omitempty"`
+	ExpectedSizes []int64         `json:"expected_sizes,omitempty"`
+	Signatures    []UserSignature `json:"signatures,omitempty"`
+	Maps          []UserMap       `json:"maps"`
+	Params        []UserParam     `json:"params,omitempty"`
+}
+
+// UserSignature is the stored form of a models.Signature, with the bytes
+// in hex
+type UserSignature struct {
+	Offset int64  `json:"offset"`
+	Bytes  string `json:"bytes"`
+}
+
+// UserParam is the stored form of a configuration parameter.
+// 16-bit values are little-endian unless Endianness is "big".
+type UserParam struct {
+	Name        string            `json:"name"`
+	Offset      int64             `json:"offset"`
+	DataType    string            `json:"data_type"`
+	Scale       float64           `json:"scale"`
+	ValueOffset float64           `json:"value_offset"`
+	Unit        string            `json:"unit"`
+	Description string            `json:"description,omitempty"`
+	MinValue    float64           `json:"min_value"`
+	MaxValue    float64           `json:"max_value"`
+	Endianness  models.Endianness `json:"endianness,omitempty"`
+	// Formula and InverseFormula are as in UserMap
+	Formula        string `json:"formula,omitempty"`
+	InverseFormula string `json:"inverse_formula,omitempty"`
+}
+
+// Config returns the parameter definition of u
+func (u UserParam) Config() models.ConfigParam {
+	return models.ConfigParam{
+		Name:           u.Name,
+		Offset:         u.Offset,
+		DataType:       u.DataType,
+		Scale:          u.Scale,
+		Offset2:        u.ValueOffset,
+		Unit:           u.Unit,
+		Description:    u.Description,
+		MinValue:       u.MinValue,
+		MaxValue:       u.MaxValue,
+		Endianness:     u.Endianness,
+		Formula:        u.Formula,
+		InverseFormula: u.InverseFormula,
+	}
+}
+
+// Profile converts the file read from path to a profile, refusing
+// definitions -check-defs would reject
+func (f ProfileFile) Profile(path string) (models.Profile, error) {
+	source := filepath.Base(path)
+	p := models.Profile{
+		Name:          f.Name,
+		Description:   f.Description,
+		ExpectedSizes: f.ExpectedSizes,
+		Source:        source,
+	}
+	var errs []error
+	if strings.TrimSpace(f.Name) == "" {
+		errs = append(errs, errors.New("no name"))
+	}
+	// Fuel, ignition and lambda are looked up by position
+	if len(f.Maps) < models.FixedMaps {
+		errs = append(errs, fmt.Errorf("needs at least %d maps, fuel, ignition and lambda first; found %d", models.FixedMaps, len(f.Maps)))
+	}
+	for i, s := range f.Signatures {
+		b, err := hex.DecodeString(strings.ReplaceAll(s.Bytes, " ", ""))
+		if err != nil || len(b) == 0 {
+			errs = append(errs, fmt.Errorf("signature %d: bytes %q are not hex", i+1, s.Bytes))
+			continue
+		}
+		p.Signatures = append(p.Signatures, models.Signature{Offset: s.Offset, Bytes: b})
+	}
+	for _, u := range f.Maps {
+		cfg := u.Config()
+		cfg.Source = source
+		p.MapConfigs = append(p.MapConfigs, cfg)
+	}
+	for _, u := range f.Params {
+		if u.MaxValue <= u.MinValue {
+			errs = append(errs, fmt.Errorf("parameter %q: max_value must be above min_value", u.Name))
+		}
+		p.ConfigParams = append(p.ConfigParams, u.Config())
+	}
+	check := models.CheckDefinitions(p.MapConfigs, p.ConfigParams)
+	errs = append(errs, check.Errors...)
+	if check.Duplicates() > 0 {
+		for _, o := range check.Overlaps {
+			if o.Exact {
+				errs = append(errs, errors.New(o.String()))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return models.Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// LoadProfiles reads the profile files of ProfilesDir in name order. A
+// missing directory has none. Files that can't be used are skipped and
+// their problems returned together.
+func LoadProfiles() ([]models.Profile, error) {
+	dir, err := paths.ConfigFile(ProfilesDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []models.Profile
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var f ProfileFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		p, err := f.Profile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, errors.Join(errs...)
+}
+
+// ApplyProfiles adds the profile files to models.Profiles. A profile
+// named like one already there is refused.
+func ApplyProfiles() error {
+	profiles, err := LoadProfiles()
+	errs := []error{err}
+	for _, p := range profiles {
+		if existing, ok := models.FindProfile(p.Name); ok {
+			errs = append(errs, fmt.Errorf("%s: profile %q is already defined%s", p.Source, p.Name, profileSource(existing)))
+			continue
+		}
+		models.Profiles = append(models.Profiles, p)
+	}
+	return errors.Join(errs...)
+}
+
+// profileSource says where a profile came from, for messages
+func profileSource(p models.Profile) string {
+	if p.Source == "" {
+		return " (built in)"
+	}
+	return " by " + p.Source
+}
+
+// SelectProfile activates the profile named name, or for "auto" the one
+// models.DetectProfile picks for data, the contents of the file to edit
+func SelectProfile(name string, data []byte) (models.Profile, error) {
+	if !strings.EqualFold(name, "auto") {
+		p, ok := models.FindProfile(name)
+		if !ok {
+			return models.Profile{}, fmt.Errorf("unknown profile %q (known: %s)", name, strings.Join(ProfileNames(), ", "))
+		}
+		models.UseProfile(p)
+		return p, nil
+	}
+	if data == nil {
+		return models.Profile{}, errors.New("auto needs a file to detect the profile from")
+	}
+	p, ok := models.DetectProfile(data)
+	if !ok {
+		return models.Profile{}, fmt.Errorf("no single profile matches a %d-byte file", len(data))
+	}
+	models.UseProfile(p)
+	return p, nil
+}
+
+// ProfileNames returns the names of models.Profiles in order
+func ProfileNames() []string {
+	names := make([]string, len(models.Profiles))
+	for i, p := range models.Profiles {
+		names[i] = p.Name
+	}
+	return names
+}
@@ -0,0 +1,136 @@
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ProjectFileName is the project file kept in a binary directory. Named
+// slots are stored next to it as ecu-reader.<slot>.project.json.
+const ProjectFileName = "ecu-reader.project.json"
+
+// slotPattern keeps slot names usable as part of a file name
+var slotPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ColorRange is a manual heatmap range; nil ends use the map's own scale
+type ColorRange struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// Project is a saved view configuration shared by the web UI, GUI and CLI:
+// which files are viewed or compared and how they are displayed. File
+// paths inside the project directory are stored relative to it so the
+// directory can be moved or checked into a repository.
+type Project struct {
+	Saved       time.Time `json:"saved"`
+	File        string    `json:"file,omitempty"`
+	CompareFile string    `json:"compare_file,omitempty"`
+	// Map is the focused map's name
+	Map        string `json:"map,omitempty"`
+	ShowValues *bool  `json:"show_values,omitempty"`
+	// ColorRanges and Offsets are keyed by map name. Offsets override a
+	// map's definition offset when reading it.
+	ColorRanges map[string]ColorRange `json:"color_ranges,omitempty"`
+	Offsets     map[string]int64      `json:"offsets,omitempty"`
+}
+
+// ProjectPath returns the project file of dir, or of a named slot in it
+func ProjectPath(dir, slot string) (string, error) {
+	if slot == "" {
+		return filepath.Join(dir, ProjectFileName), nil
+	}
+	if !slotPattern.MatchString(slot) {
+		return "", fmt.Errorf("invalid state slot %q (use letters, digits, - and _)", slot)
+	}
+	return filepath.Join(dir, "ecu-reader."+slot+".project.json"), nil
+}
+
+// LoadProject reads a project file, resolving its file paths against the
+// project's directory into absolute paths. A missing file returns nil without error.
+func LoadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Project{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := absPath(filepath.Dir(path))
+	p.File = resolveProjectPath(dir, p.File)
+	p.CompareFile = resolveProjectPath(dir, p.CompareFile)
+	return p, nil
+}
+
+// SaveProject writes the project, replacing whatever is there: concurrent
+// writers are last-write-wins. Relative file paths are taken relative to
+// the working directory, like every other path on the command line, and
+// stored relative to the project's directory when they lie inside it. The previous state is kept as a timestamped
+// backup next to the file, and the backup path is returned ("" if there
+// was no previous state).
+func SaveProject(path string, p *Project) (string, error) {
+	dir := absPath(filepath.Dir(path))
+	p.Saved = time.Now()
+	out := *p
+	out.File = relativeProjectPath(dir, p.File)
+	out.CompareFile = relativeProjectPath(dir, p.CompareFile)
+
+	data, err := json.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	backup := ""
+	if _, err := os.Stat(path); err == nil {
+		if backup, err = CreateBackup(path); err != nil {
+			return "", err
+		}
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return backup, err
+	}
+	return backup, nil
+}
+
+// relativeProjectPath stores files inside dir, an absolute path, relative
+// to it
+func relativeProjectPath(dir, path string) string {
+	if path == "" {
+		return path
+	}
+	path = absPath(path)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+// absPath returns path made absolute, or path itself if the working
+// directory is unknown
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// resolveProjectPath is the inverse of relativeProjectPath
+func resolveProjectPath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
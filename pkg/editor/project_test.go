@@ -0,0 +1,68 @@
+package editor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveProjectRelativePaths(t *testing.T) {
+	root := t.TempDir()
+	other := t.TempDir()
+	t.Chdir(root)
+
+	// Paths as quickstart and the command line give them: relative to
+	// the working directory, not to the project
+	projectFile, err := ProjectPath("demo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("demo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(other, "stock.bin")
+	if _, err := SaveProject(projectFile, &Project{File: filepath.Join("demo", "sample.bin"), CompareFile: outside}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(projectFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored Project
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.File != "sample.bin" {
+		t.Errorf("stored file %q, want sample.bin relative to the project", stored.File)
+	}
+	if stored.CompareFile != outside {
+		t.Errorf("stored compare file %q, want %q", stored.CompareFile, outside)
+	}
+
+	// Loading from elsewhere finds the same files
+	t.Chdir(other)
+	p, err := LoadProject(filepath.Join(root, projectFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "demo", "sample.bin"); p.File != want {
+		t.Errorf("loaded file %q, want %q", p.File, want)
+	}
+	if p.CompareFile != outside {
+		t.Errorf("loaded compare file %q, want %q", p.CompareFile, outside)
+	}
+
+	// Saving what was loaded keeps the stored paths
+	if _, err := SaveProject(filepath.Join(root, projectFile), p); err != nil {
+		t.Fatal(err)
+	}
+	again, err := LoadProject(filepath.Join(root, projectFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.File != p.File || again.CompareFile != p.CompareFile {
+		t.Errorf("resaved project has %q, %q, want %q, %q", again.File, again.CompareFile, p.File, p.CompareFile)
+	}
+}
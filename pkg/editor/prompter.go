@@ -0,0 +1,134 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// Prompter asks the user questions during interactive flows, so the same
+// flows can run on the terminal, from another frontend or from a script
+type Prompter interface {
+	// Confirm asks a yes/no question
+	Confirm(question string) bool
+	// Select asks the user to pick one of options and returns it
+	Select(prompt string, options []string) string
+	// Input asks for text until validate (if non-nil) accepts it.
+	// Implementations that cannot ask again return the validation error.
+	Input(prompt string, validate func(string) error) (string, error)
+}
+
+// PtermPrompter prompts on the terminal with pterm's interactive widgets
+type PtermPrompter struct{}
+
+// Confirm implements Prompter
+func (PtermPrompter) Confirm(question string) bool {
+	ok, _ := pterm.DefaultInteractiveConfirm.Show(question)
+	return ok
+}
+
+// Select implements Prompter
+func (PtermPrompter) Select(prompt string, options []string) string {
+	selected, _ := pterm.DefaultInteractiveSelect.WithOptions(options).Show(prompt)
+	return selected
+}
+
+// Input implements Prompter, asking again after each invalid answer
+func (PtermPrompter) Input(prompt string, validate func(string) error) (string, error) {
+	for {
+		answer, err := pterm.DefaultInteractiveTextInput.Show(prompt)
+		if err != nil {
+			return "", err
+		}
+		answer = strings.TrimSpace(answer)
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			pterm.Error.Println(err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// ScriptedPrompter answers prompts from a fixed list, in order. Confirm
+// accepts "y" or "yes"; Select requires the answer to be one of the
+// options. Running out of answers declines or fails.
+type ScriptedPrompter struct {
+	Answers []string
+	next    int
+}
+
+func (s *ScriptedPrompter) answer() (string, bool) {
+	if s.next >= len(s.Answers) {
+		return "", false
+	}
+	a := s.Answers[s.next]
+	s.next++
+	return a, true
+}
+
+// Confirm implements Prompter
+func (s *ScriptedPrompter) Confirm(question string) bool {
+	a, ok := s.answer()
+	a = strings.ToLower(strings.TrimSpace(a))
+	return ok && (a == "y" || a == "yes")
+}
+
+// Select implements Prompter
+func (s *ScriptedPrompter) Select(prompt string, options []string) string {
+	a, ok := s.answer()
+	if !ok || !slices.Contains(options, a) {
+		return ""
+	}
+	return a
+}
+
+// Input implements Prompter
+func (s *ScriptedPrompter) Input(prompt string, validate func(string) error) (string, error) {
+	a, ok := s.answer()
+	if !ok {
+		return "", fmt.Errorf("no scripted answer for %q", prompt)
+	}
+	if validate != nil {
+		if err := validate(a); err != nil {
+			return "", err
+		}
+	}
+	return a, nil
+}
+
+// intRange returns an Input validator accepting whole numbers in [lo, hi]
+func intRange(lo, hi int, msg string) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < lo || n > hi {
+			return errors.New(msg)
+		}
+		return nil
+	}
+}
+
+// floatRange returns an Input validator accepting numbers in [lo, hi]
+func floatRange(lo, hi float64, msg string) func(string) error {
+	return func(s string) error {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < lo || f > hi {
+			return errors.New(msg)
+		}
+		return nil
+	}
+}
+
+// isFloat is an Input validator accepting any number
+func isFloat(s string) error {
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	return nil
+}
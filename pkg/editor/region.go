@@ -0,0 +1,131 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// ParseRange parses "start:end" with an inclusive end, e.g. "0x6000:0x7FFF".
+// Numbers may be decimal or 0x-prefixed hex.
+func ParseRange(s string) (models.Region, error) {
+	startStr, endStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return models.Region{}, fmt.Errorf("invalid range %q: expected start:end", s)
+	}
+
+	start, err := ParseOffset(startStr)
+	if err != nil {
+		return models.Region{}, err
+	}
+	end, err := ParseOffset(endStr)
+	if err != nil {
+		return models.Region{}, err
+	}
+	if end < start {
+		return models.Region{}, fmt.Errorf("invalid range %q: end is before start", s)
+	}
+
+	return models.Region{Name: s, Kind: "range", Start: start, End: end + 1}, nil
+}
+
+// ParseOffset parses a decimal or 0x-prefixed hex file offset
+func ParseOffset(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 0, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid offset %q", s)
+	}
+	return n, nil
+}
+
+// ExtractRegion writes the bytes of region from filename to out. Ranges
+// crossing the end of the file are refused.
+func ExtractRegion(filename string, region models.Region, out string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if region.End > int64(len(data)) {
+		return reader.NewError(reader.ErrOutOfRange, "range 0x%04X-0x%04X crosses end of file (size 0x%X)", region.Start, region.End-1, len(data))
+	}
+
+	if err := os.WriteFile(out, data[region.Start:region.End], 0644); err != nil {
+		return err
+	}
+
+	return AppendChangelog(filename, ChangelogEntry{
+		Action: "extract",
+		Detail: fmt.Sprintf("%s -> %s", region.Name, out),
+		Offset: region.Start,
+		Length: region.End - region.Start,
+	})
+}
+
+// definitionsIn returns the names of the maps and parameters whose bytes
+// intersect [start, end)
+func definitionsIn(start, end int64) []string {
+	var names []string
+	for _, r := range models.DefinitionRegions(models.MapConfigs, models.ConfigParams) {
+		if r.Start < end && start < r.End {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// InjectRegion writes the contents of src into filename at offset, after
+// backing up the file. Data that would run past the end of the file is
+// refused; the file never grows.
+func InjectRegion(filename, src string, offset int64) (string, error) {
+	patch, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+	if len(patch) == 0 {
+		return "", fmt.Errorf("%s is empty", src)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	end := offset + int64(len(patch))
+	if end > int64(len(data)) {
+		return "", reader.NewError(reader.ErrOutOfRange, "%d bytes at 0x%04X cross end of file (size 0x%X)", len(patch), offset, len(data))
+	}
+
+	if err := reader.CheckWritable(filename); err != nil {
+		return "", err
+	}
+
+	backup, err := CreateBackupFrom(filename, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	parent, backupSHA := hashData(data), backupHash(backup, data)
+	copy(data[offset:end], patch)
+	if err := writeFileAtomic(filename, data); err != nil {
+		return backup, err
+	}
+	notifyWritten(filename, data)
+	RecordProvenance(filename, parent, definitionsIn(offset, end))
+
+	err = AppendChangelog(filename, ChangelogEntry{
+		Action:       "inject",
+		Detail:       src,
+		Backup:       backup,
+		BackupSHA256: backupSHA,
+		NoBackup:     backup == "",
+		Offset:       offset,
+		Length:       int64(len(patch)),
+	})
+	if err != nil {
+		return backup, fmt.Errorf("bytes injected but changelog not updated: %w", err)
+	}
+	return backup, nil
+}
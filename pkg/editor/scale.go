@@ -0,0 +1,200 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// ceilingMargin is the fraction of the data type maximum above which a
+// cell counts as close to the ceiling
+const ceilingMargin = 0.9
+
+// CellPos identifies a map cell
+type CellPos struct {
+	Row int
+	Col int
+}
+
+// String formats the cell as [row,col]
+func (p CellPos) String() string {
+	return fmt.Sprintf("[%d,%d]", p.Row, p.Col)
+}
+
+// ScaleAnalysis reports the clamp risk of multiplying a map's raw values
+// by a factor
+type ScaleAnalysis struct {
+	Map    string
+	Factor float64
+	Cells  int
+	// NearCeiling holds cells already within 10% of the data type maximum
+	NearCeiling []CellPos
+	// Clamped holds cells whose scaled raw value would not fit the data
+	// type and would be clamped
+	Clamped []CellPos
+}
+
+// AnalyzeScale computes the clamp risk of scaling every raw cell of m by
+// factor, without modifying anything
+func AnalyzeScale(m *models.ECUMap, factor float64) ScaleAnalysis {
+	a := ScaleAnalysis{Map: m.Config.Name, Factor: factor}
+	lo, hi := models.RawRange(m.Config.DataType)
+
+	for i, row := range m.Data {
+		for j, value := range row {
+			a.Cells++
+			raw, _ := m.Config.ToRaw(value)
+			if float64(raw) >= ceilingMargin*float64(hi) {
+				a.NearCeiling = append(a.NearCeiling, CellPos{i, j})
+			}
+			scaled := math.Round(float64(raw) * factor)
+			if scaled > float64(hi) || scaled < float64(lo) {
+				a.Clamped = append(a.Clamped, CellPos{i, j})
+			}
+		}
+	}
+
+	return a
+}
+
+// Summary describes the analysis in one line
+func (a ScaleAnalysis) Summary() string {
+	return fmt.Sprintf("%d of %d cells within 10%% of the data type ceiling, %d would clamp at ×%.2f",
+		len(a.NearCeiling), a.Cells, len(a.Clamped), a.Factor)
+}
+
+// Print reports the analysis with the coordinates of affected cells
+func (a ScaleAnalysis) Print() {
+	if len(a.NearCeiling) == 0 && len(a.Clamped) == 0 {
+		pterm.Info.Printf("%s: no cells near the data type ceiling\n", a.Map)
+		return
+	}
+
+	pterm.Warning.Printf("%s: %s\n", a.Map, a.Summary())
+	if len(a.NearCeiling) > 0 {
+		pterm.Info.Printf("Near ceiling: %s\n", joinCells(a.NearCeiling))
+	}
+	if len(a.Clamped) > 0 {
+		pterm.Warning.Printf("Would clamp: %s\n", joinCells(a.Clamped))
+	}
+}
+
+func joinCells(cells []CellPos) string {
+	parts := make([]string, len(cells))
+	for i, c := range cells {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// PlanScale computes the changes that multiply every raw cell of a map by
+// factor, clamping to the data type range
+func PlanScale(data []byte, cfg models.MapConfig, factor float64) ([]CellChange, error) {
+	if !models.KnownDataType(cfg.DataType) {
+		return nil, reader.NewError(reader.ErrUnsupportedDataType, "%s: unknown data type %q", cfg.Name, cfg.DataType)
+	}
+	if cfg.Offset+cfg.ByteSize() > int64(len(data)) {
+		return nil, reader.NewError(reader.ErrOutOfRange, "%s at 0x%04X lies outside the file", cfg.Name, cfg.Offset)
+	}
+
+	lo, hi := models.RawRange(cfg.DataType)
+	size := models.DataTypeSize(cfg.DataType)
+
+	var changes []CellChange
+	for i := 0; i < cfg.Rows; i++ {
+		for j := 0; j < cfg.Cols; j++ {
+			offset := cfg.Offset + int64((i*cfg.Cols+j)*size)
+			oldRaw := cfg.DecodeRaw(data[offset:])
+			newRaw := int64(math.Round(float64(oldRaw) * factor))
+			newRaw = max(lo, min(hi, newRaw))
+			if newRaw == oldRaw {
+				continue
+			}
+			changes = append(changes, CellChange{
+				Map:        cfg.Name,
+				Row:        i,
+				Col:        j,
+				Offset:     offset,
+				DataType:   cfg.DataType,
+				Endianness: cfg.ByteOrder(),
+				OldRaw:     oldRaw,
+				NewRaw:     newRaw,
+				OldValue:   cfg.ToReal(oldRaw),
+				NewValue:   cfg.ToReal(newRaw),
+			})
+		}
+	}
+	return changes, nil
+}
+
+// applyScale analyzes, confirms and writes a map scaling. Clamp risk is
+// shown in dry-run output and must be acknowledged before writing.
+func applyScale(prompt Prompter, filename string, cfg models.MapConfig, factor float64, dryRun bool, question string) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read file: %v\n", err)
+		return
+	}
+
+	changes, err := PlanScale(data, cfg, factor)
+	if err != nil {
+		pterm.Error.Println(err)
+		return
+	}
+
+	m := &models.ECUMap{Config: cfg, Data: make([][]float64, cfg.Rows)}
+	size := models.DataTypeSize(cfg.DataType)
+	for i := range m.Data {
+		m.Data[i] = make([]float64, cfg.Cols)
+		for j := range m.Data[i] {
+			m.Data[i][j] = cfg.ToReal(cfg.DecodeRaw(data[cfg.Offset+int64((i*cfg.Cols+j)*size):]))
+		}
+	}
+	analysis := AnalyzeScale(m, factor)
+	analysis.Print()
+	pterm.Info.Print(i18n.T("cli.would_change", len(changes)))
+	if refuseOutOfRange(cfg, changes) {
+		return
+	}
+
+	if dryRun {
+		pterm.Warning.Println(i18n.T("cli.dry_run"))
+		return
+	}
+	if len(changes) == 0 {
+		pterm.Info.Println(i18n.T("cli.no_changes"))
+		return
+	}
+
+	if err := reader.CheckWritable(filename); err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return
+	}
+
+	if len(analysis.Clamped) > 0 {
+		if !Confirm(prompt, ConfirmSave, i18n.T("cli.confirm.clamped", len(analysis.Clamped))) {
+			pterm.Info.Println(i18n.T("cli.cancelled"))
+			return
+		}
+	}
+	if !Confirm(prompt, ConfirmSave, question) {
+		pterm.Info.Println(i18n.T("cli.cancelled"))
+		return
+	}
+
+	report, err := applyChanges(filename, fmt.Sprintf("Scale %s by %.2f", cfg.Name, factor), changes)
+	PrintBackup(report.Backup)
+	if err != nil {
+		pterm.Error.Printf("Failed to write: %s\n", reader.DescribeWriteError(err))
+		return
+	}
+	pterm.Success.Println("Map scaled successfully!")
+	report.PrintChecksum()
+	report.PrintMirror()
+}
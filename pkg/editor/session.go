@@ -0,0 +1,646 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// FailurePolicy decides what a session commit does when an operation fails
+type FailurePolicy string
+
+const (
+	// PolicyAbort discards every operation and leaves the file untouched
+	PolicyAbort FailurePolicy = "abort"
+	// PolicySkip applies the remaining operations and reports the failures
+	PolicySkip FailurePolicy = "skip"
+	// PolicyAsk asks the user whether to skip or abort on each failure
+	PolicyAsk FailurePolicy = "ask"
+)
+
+// ParseFailurePolicy validates an -on-error flag value
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch p := FailurePolicy(s); p {
+	case PolicyAbort, PolicySkip, PolicyAsk:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid failure policy %q: expected abort, skip or ask", s)
+}
+
+// Operation is one named modification in a session. Plan is called with
+// the working copy as modified by the operations before it.
+type Operation struct {
+	Name string
+	Plan func(data []byte) ([]CellChange, error)
+	// Force writes cells outside their map's limits, as ForceRange does
+	// for every operation
+	Force bool
+}
+
+// OperationResult records the outcome of one operation
+type OperationResult struct {
+	Name    string
+	Changes int
+	Err     error
+	Skipped bool
+}
+
+// Report is the outcome of a session commit
+type Report struct {
+	Results []OperationResult
+	Backup  string
+	// BackupSHA256 and LinkedBackupSHA256 are the hashes the backups were
+	// verified against under -require-backup
+	BackupSHA256       string
+	LinkedBackupSHA256 string
+	// BackupSkipped is set when -no-backup wrote the file without one
+	BackupSkipped bool
+	Written       bool
+	Aborted       bool
+	// Linked is the file written in lock step, if any, with its backup
+	Linked       string
+	LinkedBackup string
+	// Mismatches are staged cells whose raw value differs in the linked
+	// file; they abort the commit unless ForceMismatch is set
+	Mismatches []Mismatch
+	// Checksum is the image checksum of the edited contents before any
+	// fix, nil when none is configured or the policy is ChecksumNever.
+	// ChecksumFixed is set when the save stored the computed checksum and
+	// ChecksumStale when it left a mismatched one.
+	Checksum      *ChecksumStatus
+	ChecksumFixed bool
+	ChecksumStale bool
+	// Copies is how often a mirrored dump holds the image, 0 for other
+	// files. MirrorStale is set when the save wrote only the first copy
+	// (see MirrorWrites).
+	Copies      int
+	MirrorStale bool
+}
+
+// Session batches operations against a snapshot of a file and writes them
+// in one step, so the file is never left half-applied. A session can be
+// kept open across commits, as the GUI does for the file it shows: each
+// commit plans against what the previous one wrote, and Image serves
+// reads from memory.
+type Session struct {
+	Policy FailurePolicy
+	// Ask is consulted under PolicyAsk; it returns true to skip the failed
+	// operation and false to abort the whole session
+	Ask func(op Operation, err error) bool
+	// Confirm, if set, is shown the planned report before writing and
+	// returns false to cancel
+	Confirm func(r *Report) bool
+	// DryRun plans every operation without writing
+	DryRun bool
+
+	filename string
+	snapshot []byte
+	// image wraps snapshot for reading maps and parameters
+	image *reader.ECUFile
+	ops   []Operation
+
+	linked         string
+	linkedSnapshot []byte
+
+	// written lists the files Commit replaced; committed is set once every
+	// target was written
+	written   []string
+	committed bool
+}
+
+// NewSession snapshots the file so operations can be planned against it.
+// With LinkedFile set, the linked file is snapshotted as well and written
+// in lock step.
+func NewSession(filename string) (*Session, error) {
+	s := &Session{Policy: PolicyAbort, filename: filename}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload snapshots the file, and the linked file, again, for a session
+// kept open while something else changed them. Queued operations are
+// kept.
+func (s *Session) Reload() error {
+	info, err := os.Stat(s.filename)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.filename)
+	if err != nil {
+		return err
+	}
+	s.snapshot = data
+	s.image = reader.NewECUFile(s.filename, data, info.ModTime())
+	s.linked, s.linkedSnapshot = "", nil
+	return s.syncLinked()
+}
+
+// syncLinked snapshots LinkedFile if it changed since the last snapshot,
+// so a session kept open follows the link the user sets
+func (s *Session) syncLinked() error {
+	if LinkedFile == s.linked {
+		return nil
+	}
+	s.linked, s.linkedSnapshot = "", nil
+	if LinkedFile == "" {
+		return nil
+	}
+	if err := CheckLinked(s.filename); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(LinkedFile)
+	if err != nil {
+		return err
+	}
+	s.linked, s.linkedSnapshot = LinkedFile, data
+	return nil
+}
+
+// Filename returns the file the session edits
+func (s *Session) Filename() string { return s.filename }
+
+// Image returns the contents the next commit plans against: the file as
+// snapshotted, or as the last commit wrote it. Its Stale method tells
+// whether something else has written the file since.
+func (s *Session) Image() *reader.ECUFile { return s.image }
+
+// Add queues an operation for the next commit
+func (s *Session) Add(op Operation) {
+	s.ops = append(s.ops, op)
+}
+
+// Preview plans the queued operations without writing, like a commit with
+// DryRun set, and returns the report
+func (s *Session) Preview() (*Report, error) {
+	dryRun := s.DryRun
+	s.DryRun = true
+	defer func() { s.DryRun = dryRun }()
+	return s.Commit()
+}
+
+// Commit plans and applies every queued operation to a working copy, then
+// backs up and replaces the file. If the session aborts, nothing is
+// written and the file stays byte-identical to the snapshot. A linked
+// file gets the same raw values; if its write fails the primary file is
+// restored, so the pair is never left half-written. The queued operations
+// are used up either way; after a write the written contents are the new
+// snapshot.
+func (s *Session) Commit() (*Report, error) {
+	defer func() { s.ops = nil }()
+	s.written, s.committed = nil, false
+	if err := s.syncLinked(); err != nil {
+		return &Report{}, err
+	}
+	report := &Report{Linked: s.linked}
+	work := bytes.Clone(s.snapshot)
+	var applied []CellChange
+	var names []string
+
+	for _, op := range s.ops {
+		result := OperationResult{Name: op.Name}
+
+		changes, err := op.Plan(work)
+		if err == nil {
+			err = checkBounds(work, changes)
+		}
+		if err == nil {
+			err = checkRanges(changes, op.Force)
+		}
+		if err != nil {
+			result.Err = err
+			if !s.skipFailure(op, err) {
+				report.Results = append(report.Results, result)
+				report.Aborted = true
+				return report, fmt.Errorf("%s: %w", op.Name, err)
+			}
+			result.Skipped = true
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		for _, c := range changes {
+			c.Apply(work)
+		}
+		result.Changes = len(changes)
+		applied = append(applied, changes...)
+		names = append(names, op.Name)
+		report.Results = append(report.Results, result)
+	}
+
+	if s.linked != "" {
+		report.Mismatches = FindMismatches(applied, s.linkedSnapshot)
+		if len(report.Mismatches) > 0 && !ForceMismatch {
+			report.Aborted = true
+			return report, reader.NewError(reader.ErrLinkedMismatch,
+				"%d staged cell(s) hold different values in %s; -force-mismatch writes them anyway",
+				len(report.Mismatches), filepath.Base(s.linked))
+		}
+	}
+
+	if len(applied) == 0 || s.DryRun {
+		return report, nil
+	}
+	mirror := planMirror(s.snapshot, report)
+	for _, filename := range s.targets() {
+		if err := reader.CheckWritable(filename); err != nil {
+			return report, err
+		}
+	}
+	codeMaps, err := confirmCodeEdits(s.filename, s.snapshot, applied)
+	if err != nil {
+		report.Aborted = true
+		return report, err
+	}
+	if s.Confirm != nil && !s.Confirm(report) {
+		return report, nil
+	}
+
+	if err := checkUnchanged(s.filename, s.snapshot); err != nil {
+		report.Aborted = true
+		return report, err
+	}
+	changes := applied
+	fix, err := planChecksum(s.filename, work, report)
+	if err != nil {
+		report.Aborted = true
+		return report, fmt.Errorf("checksum: %w", err)
+	}
+	if fix != nil {
+		fix.Apply(work)
+		changes = append(slices.Clone(applied), *fix)
+	}
+	if mirror != nil {
+		mirrorChanges(work, *mirror, changes)
+	}
+	var linkedWork []byte
+	linkedChanges, linkedStale := applied, false
+	if s.linked != "" {
+		if err := checkUnchanged(s.linked, s.linkedSnapshot); err != nil {
+			report.Aborted = true
+			return report, err
+		}
+		linkedWork = bytes.Clone(s.linkedSnapshot)
+		for _, c := range applied {
+			c.Apply(linkedWork)
+		}
+		// The linked file's checksum covers its own bytes; it follows the
+		// decision made for the primary file
+		if checksumActive() {
+			status, err := PlanChecksum(linkedWork)
+			if err != nil {
+				report.Aborted = true
+				return report, fmt.Errorf("checksum of %s: %w", s.linked, err)
+			}
+			if status != nil && !status.OK() {
+				if report.ChecksumFixed {
+					status.Fix.Apply(linkedWork)
+					linkedChanges = append(slices.Clone(applied), status.Fix)
+				} else {
+					linkedStale = true
+				}
+			}
+		}
+		if layout := reader.DetectLayout(s.linkedSnapshot); MirrorWrites && layout.Mirrored() {
+			mirrorChanges(linkedWork, layout, linkedChanges)
+		}
+	}
+
+	report.Backup, err = CreateBackupFrom(s.filename, s.snapshot)
+	if err != nil {
+		return report, fmt.Errorf("failed to create backup: %w", err)
+	}
+	report.BackupSkipped = report.Backup == ""
+	report.BackupSHA256 = backupHash(report.Backup, s.snapshot)
+	if s.linked != "" {
+		report.LinkedBackup, err = CreateBackupFrom(s.linked, s.linkedSnapshot)
+		if err != nil {
+			return report, fmt.Errorf("failed to create backup of %s: %w", s.linked, err)
+		}
+		report.LinkedBackupSHA256 = backupHash(report.LinkedBackup, s.linkedSnapshot)
+	}
+
+	if err := writeFileAtomic(s.filename, work); err != nil {
+		return report, err
+	}
+	s.written = append(s.written, s.filename)
+	notifyWritten(s.filename, work)
+	if s.linked != "" {
+		if err := writeFileAtomic(s.linked, linkedWork); err != nil {
+			if restoreErr := writeFileAtomic(s.filename, s.snapshot); restoreErr != nil {
+				return report, fmt.Errorf("%w; restoring %s also failed, use the backup: %v", err, s.filename, restoreErr)
+			}
+			s.written = nil
+			notifyWritten(s.filename, s.snapshot)
+			return report, fmt.Errorf("%w; %s was restored", err, s.filename)
+		}
+		s.written = append(s.written, s.linked)
+		notifyWritten(s.linked, linkedWork)
+	}
+	s.committed = true
+	report.Written = true
+	before, linkedBefore := s.snapshot, s.linkedSnapshot
+	s.snapshot, s.linkedSnapshot = work, linkedWork
+	s.image = reader.NewECUFile(s.filename, work, modTime(s.filename))
+
+	entry := ChangelogEntry{Detail: strings.Join(names, ", "), CodeWarning: codeMaps, MirrorStale: report.MirrorStale}
+	if mirror != nil {
+		entry.Mirrored = mirror.Copies
+	}
+	primary := entry
+	primary.Backup, primary.Linked, primary.ChecksumStale, primary.Changes = report.Backup, s.linked, report.ChecksumStale, changes
+	if err := s.record(s.filename, before, primary); err != nil {
+		return report, err
+	}
+	if s.linked != "" {
+		linked := entry
+		linked.Backup, linked.Linked, linked.ChecksumStale, linked.Changes = report.LinkedBackup, s.filename, linkedStale, linkedChanges
+		if err := s.record(s.linked, linkedBefore, linked); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// Close ends the session so its files are either fully committed or
+// untouched. Operations that were never committed are dropped. If a commit
+// stopped after replacing some but not all targets, those are restored
+// from their snapshots. Close is safe to call more than once and after a
+// successful Commit, where it does nothing.
+func (s *Session) Close() error {
+	s.ops = nil
+	if s.committed {
+		return nil
+	}
+	var errs []error
+	for _, filename := range s.written {
+		snapshot := s.snapshot
+		if filename == s.linked {
+			snapshot = s.linkedSnapshot
+		}
+		if err := writeFileAtomic(filename, snapshot); err != nil {
+			errs = append(errs, fmt.Errorf("restoring %s failed, use the backup: %w", filename, err))
+			continue
+		}
+		notifyWritten(filename, snapshot)
+	}
+	s.written = nil
+	return errors.Join(errs...)
+}
+
+// SaveAs writes the session's contents to filename and makes it the file
+// later commits edit, leaving the original as it is. The copy's provenance
+// names the original contents as its parent, and its changelog starts
+// with an entry naming the original.
+func (s *Session) SaveAs(filename string) error {
+	for _, target := range s.targets() {
+		if filepath.Clean(filename) == filepath.Clean(target) {
+			return fmt.Errorf("%s is already being edited", filepath.Base(filename))
+		}
+	}
+	// Under -require-backup a file being overwritten is backed up too
+	if _, err := os.Stat(filename); err == nil && reader.RequireBackup {
+		if _, err := CreateBackup(filename); err != nil {
+			return fmt.Errorf("failed to create backup of %s: %w", filepath.Base(filename), err)
+		}
+	}
+	if err := writeFileAtomic(filename, s.snapshot); err != nil {
+		return err
+	}
+	notifyWritten(filename, s.snapshot)
+	original := s.filename
+	s.filename = filename
+	s.image = reader.NewECUFile(filename, s.snapshot, modTime(filename))
+
+	RecordProvenance(filename, hashData(s.snapshot), nil)
+	return AppendChangelog(filename, ChangelogEntry{
+		Action: "save-as",
+		Detail: filepath.Base(original),
+		Length: int64(len(s.snapshot)),
+	})
+}
+
+// modTime returns the modification time of filename, or the zero time if
+// it can't be read, which makes the session's image stale
+func modTime(filename string) time.Time {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// RemoveStaleTemps deletes the temporary files replaceFile leaves next to
+// filename when the process dies between creating and renaming them. Call
+// it only while no session is writing filename.
+func RemoveStaleTemps(filename string) []string {
+	dir, prefix := filepath.Dir(filename), filepath.Base(filename)+".tmp"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var removed []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		tmp := filepath.Join(dir, e.Name())
+		if os.Remove(tmp) == nil {
+			removed = append(removed, tmp)
+		}
+	}
+	return removed
+}
+
+// targets returns the files the session writes
+func (s *Session) targets() []string {
+	if s.linked != "" {
+		return []string{s.filename, s.linked}
+	}
+	return []string{s.filename}
+}
+
+// record logs a written file's provenance and its changelog entry, an
+// edit of the snapshot contents described by entry: its detail, backup,
+// the other file of a lock-step edit, the changes, and the checksum,
+// mirror and code warning outcomes
+func (s *Session) record(filename string, snapshot []byte, entry ChangelogEntry) error {
+	RecordProvenance(filename, hashData(snapshot), changedNames(entry.Changes))
+	entry.Action = "edit"
+	entry.BackupSHA256 = backupHash(entry.Backup, snapshot)
+	entry.NoBackup = entry.Backup == ""
+	entry.ChecksumFixed = hasChecksumChange(entry.Changes)
+	err := AppendChangelog(filename, entry)
+	if err != nil {
+		return fmt.Errorf("changes written but changelog of %s not updated: %w", filename, err)
+	}
+	return nil
+}
+
+// checkUnchanged fails if the file no longer matches its snapshot
+func checkUnchanged(filename string, snapshot []byte) error {
+	current, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, snapshot) {
+		return fmt.Errorf("%s changed on disk since the session started", filename)
+	}
+	return nil
+}
+
+// changedNames returns the distinct map and parameter names of changes in
+// the order they first appear
+func changedNames(changes []CellChange) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, c := range changes {
+		if !seen[c.Map] {
+			seen[c.Map] = true
+			names = append(names, c.Map)
+		}
+	}
+	return names
+}
+
+// skipFailure applies the failure policy to a failed operation
+func (s *Session) skipFailure(op Operation, err error) bool {
+	switch s.Policy {
+	case PolicySkip:
+		return true
+	case PolicyAsk:
+		return s.Ask != nil && s.Ask(op, err)
+	default:
+		return false
+	}
+}
+
+// Print renders the report as a table followed by a summary line
+func (r *Report) Print() {
+	r.PrintTable()
+	r.PrintSummary()
+}
+
+// PrintTable renders one row per operation
+func (r *Report) PrintTable() {
+	tableData := pterm.TableData{{"Operation", "Status", "Cells", "Detail"}}
+	for _, res := range r.Results {
+		status, detail := "applied", ""
+		switch {
+		case res.Skipped:
+			status, detail = "skipped", res.Err.Error()
+		case res.Err != nil:
+			status, detail = "failed", res.Err.Error()
+		case res.Changes == 0:
+			status = "unchanged"
+		}
+		tableData = append(tableData, []string{res.Name, status, strconv.Itoa(res.Changes), detail})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	if len(r.Mismatches) > 0 {
+		pterm.Warning.Printf("%d staged cell(s) differ in the linked file %s:\n", len(r.Mismatches), r.Linked)
+		MismatchTable(r.Mismatches, "this file", r.Linked).Render()
+	}
+}
+
+// PrintSummary prints whether the session was written, aborted or left
+// without changes
+func (r *Report) PrintSummary() {
+	skipped := 0
+	for _, res := range r.Results {
+		if res.Skipped {
+			skipped++
+		}
+	}
+
+	switch {
+	case r.Aborted:
+		pterm.Error.Println("Aborted - file left unchanged")
+	case r.Written:
+		PrintBackup(r.Backup)
+		r.PrintChecksum()
+		r.PrintMirror()
+		if r.Linked != "" {
+			PrintBackup(r.LinkedBackup)
+			pterm.Success.Printf("Applied %d operations, skipped %d, to both files (linked %s)\n", len(r.Results)-skipped, skipped, r.Linked)
+			return
+		}
+		pterm.Success.Printf("Applied %d operations, skipped %d\n", len(r.Results)-skipped, skipped)
+	default:
+		pterm.Info.Println("No changes written")
+	}
+}
+
+// PrintChecksum reports a checksum the save fixed or left stale
+func (r *Report) PrintChecksum() {
+	switch {
+	case r.Checksum == nil:
+	case r.ChecksumFixed:
+		pterm.Success.Printf("Checksum updated (%s)\n", r.Checksum)
+	case r.ChecksumStale:
+		pterm.Warning.Printf("Checksum left stale (%s); -fix-checksum stores it\n", r.Checksum)
+	}
+}
+
+// checkBounds verifies every change has a known data type and lies inside
+// the file
+func checkBounds(data []byte, changes []CellChange) error {
+	for _, c := range changes {
+		if !models.KnownDataType(c.DataType) {
+			return reader.NewError(reader.ErrUnsupportedDataType, "%s: unknown data type %q", c.Map, c.DataType)
+		}
+		if c.Offset < 0 || c.Offset+int64(models.DataTypeSize(c.DataType)) > int64(len(data)) {
+			return reader.NewError(reader.ErrOutOfRange, "cell [%d,%d] of %s at 0x%X is out of bounds", c.Row, c.Col, c.Map, c.Offset)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file via a temporary file and rename, so a
+// failed write never leaves a truncated binary behind
+func writeFileAtomic(filename string, data []byte) error {
+	if err := replaceFile(filename, data); err != nil {
+		var pathErr *fs.PathError
+		var linkErr *os.LinkError
+		switch {
+		case errors.As(err, &pathErr):
+			err = pathErr.Err
+		case errors.As(err, &linkErr):
+			err = linkErr.Err
+		}
+		return &reader.WriteError{Path: filename, Err: err}
+	}
+	return nil
+}
+
+func replaceFile(filename string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if info, err := os.Stat(filename); err == nil {
+		os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	return os.Rename(tmp.Name(), filename)
+}
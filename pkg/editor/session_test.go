@@ -0,0 +1,152 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// fuelCell returns a change of one Main Fuel Map cell of data to value
+func fuelCell(data []byte, col int, value float64) CellChange {
+	cfg := models.MapConfigs[0]
+	offset := cfg.Offset + int64(col)
+	raw, _ := cfg.ToRaw(value)
+	return CellChange{Map: cfg.Name, Col: col, Offset: offset, DataType: cfg.DataType,
+		OldRaw: int64(data[offset]), NewRaw: raw, OldValue: cfg.ToReal(int64(data[offset])), NewValue: cfg.ToReal(raw)}
+}
+
+// change is an operation writing one fuel cell
+func change(name string, col int, value float64) Operation {
+	return Operation{Name: name, Plan: func(data []byte) ([]CellChange, error) {
+		return []CellChange{fuelCell(data, col, value)}, nil
+	}}
+}
+
+// writeImage writes the synthetic image to a temporary file and points
+// the config directory at a temporary one
+func writeImage(t testing.TB) (string, []byte) {
+	t.Helper()
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	file := filepath.Join(t.TempDir(), "ecu.bin")
+	data := testbin.Image()
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file, data
+}
+
+func TestCommitFailurePolicy(t *testing.T) {
+	missing := Operation{Name: "missing map", Plan: func([]byte) ([]CellChange, error) {
+		return nil, reader.NewError(reader.ErrNotFound, "no map named Boost")
+	}}
+	ops := []Operation{
+		change("first", 0, 2),
+		change("out of range", 1, 10.2),
+		missing,
+		change("last", 2, 3),
+	}
+
+	tests := []struct {
+		name    string
+		policy  FailurePolicy
+		ask     []bool
+		written bool
+		skipped []string
+	}{
+		{name: "abort", policy: PolicyAbort},
+		{name: "skip", policy: PolicySkip, written: true, skipped: []string{"out of range", "missing map"}},
+		{name: "ask and skip", policy: PolicyAsk, ask: []bool{true, true}, written: true, skipped: []string{"out of range", "missing map"}},
+		{name: "ask and abort", policy: PolicyAsk, ask: []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, before := writeImage(t)
+			s, err := NewSession(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Policy = tt.policy
+			s.Ask = func(Operation, error) bool {
+				answer := tt.ask[0]
+				tt.ask = tt.ask[1:]
+				return answer
+			}
+			for _, op := range ops {
+				s.Add(op)
+			}
+			report, err := s.Commit()
+
+			after, readErr := os.ReadFile(file)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			backups, _ := ListBackups(file)
+			if !tt.written {
+				if err == nil || !report.Aborted {
+					t.Fatalf("Commit = %v, aborted %v; want an aborted commit", err, report.Aborted)
+				}
+				if !bytes.Equal(after, before) {
+					t.Error("an aborted commit changed the file")
+				}
+				if len(backups) != 0 {
+					t.Errorf("an aborted commit made %d backup(s)", len(backups))
+				}
+				return
+			}
+
+			if err != nil || !report.Written {
+				t.Fatalf("Commit = %v, written %v", err, report.Written)
+			}
+			want := bytes.Clone(before)
+			fuelCell(before, 0, 2).Apply(want)
+			fuelCell(before, 2, 3).Apply(want)
+			if !bytes.Equal(after, want) {
+				t.Error("the file doesn't hold exactly the changes of the operations that succeeded")
+			}
+			var skipped []string
+			for _, r := range report.Results {
+				if r.Skipped {
+					if r.Err == nil {
+						t.Errorf("%s skipped without an error", r.Name)
+					}
+					skipped = append(skipped, r.Name)
+				}
+			}
+			if len(skipped) != len(tt.skipped) || skipped[0] != tt.skipped[0] || skipped[1] != tt.skipped[1] {
+				t.Errorf("skipped %v, want %v", skipped, tt.skipped)
+			}
+			if !errors.Is(report.Results[1].Err, reader.ErrValueOutOfBounds) {
+				t.Errorf("out-of-range operation failed with %v", report.Results[1].Err)
+			}
+			if len(backups) != 1 {
+				t.Fatalf("%d backups, want 1", len(backups))
+			}
+			if data, _ := os.ReadFile(backups[0].Path); !bytes.Equal(data, before) {
+				t.Error("the backup doesn't hold the file as it was before the commit")
+			}
+		})
+	}
+}
+
+// A commit refused at the confirmation leaves the file as it was
+func TestCommitDeclined(t *testing.T) {
+	file, before := writeImage(t)
+	s, err := NewSession(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Confirm = func(*Report) bool { return false }
+	s.Add(change("first", 0, 2))
+	if report, err := s.Commit(); err != nil || report.Written {
+		t.Fatalf("Commit = %v, written %v", err, report.Written)
+	}
+	if after, _ := os.ReadFile(file); !bytes.Equal(after, before) {
+		t.Error("a declined commit changed the file")
+	}
+}
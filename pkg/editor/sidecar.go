@@ -0,0 +1,168 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/internal/version"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// Attachment associates an external file, such as a datalog or dyno run,
+// with a revision of an ECU file
+type Attachment struct {
+	Path      string    `json:"path"`
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	Note      string    `json:"note,omitempty"`
+	Added     time.Time `json:"added"`
+	BinSHA256 string    `json:"bin_sha256"` // hash of the ECU file when attached
+}
+
+// Sidecar holds metadata kept next to an ECU file
+type Sidecar struct {
+	Attachments []Attachment       `json:"attachments,omitempty"`
+	Provenance  *models.Provenance `json:"provenance,omitempty"`
+	// Snapshots labels the automatic snapshots (see ListSnapshots)
+	Snapshots []SnapshotInfo `json:"snapshots,omitempty"`
+}
+
+// SidecarPath returns the metadata file kept next to an ECU file
+func SidecarPath(filename string) string {
+	return reader.SidecarPath(filename)
+}
+
+// LoadSidecar reads the ECU file's metadata, returning empty metadata if
+// none has been saved yet
+func LoadSidecar(filename string) (*Sidecar, error) {
+	s := &Sidecar{}
+	data, err := os.ReadFile(SidecarPath(filename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return &Sidecar{}, fmt.Errorf("%s: %w", SidecarPath(filename), err)
+	}
+	return s, nil
+}
+
+// Save writes the metadata next to the ECU file
+func (s *Sidecar) Save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(SidecarPath(filename), append(data, '\n'))
+}
+
+// AttachLog records an external log in the ECU file's metadata, with its
+// hash and the hash of the ECU file revision it belongs to. Attaching the
+// same log again to the same revision updates the note.
+func AttachLog(filename, logPath, note string) (Attachment, error) {
+	abs, err := filepath.Abs(logPath)
+	if err != nil {
+		return Attachment{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if info.IsDir() {
+		return Attachment{}, fmt.Errorf("%s is a directory", logPath)
+	}
+
+	logHash, err := reader.HashFile(abs)
+	if err != nil {
+		return Attachment{}, err
+	}
+	binHash, err := reader.HashFile(filename)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	s, err := LoadSidecar(filename)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	a := Attachment{
+		Path:      abs,
+		SHA256:    logHash,
+		Size:      info.Size(),
+		Note:      note,
+		Added:     time.Now(),
+		BinSHA256: binHash,
+	}
+
+	replaced := false
+	for i, existing := range s.Attachments {
+		if existing.SHA256 == a.SHA256 && existing.BinSHA256 == a.BinSHA256 {
+			s.Attachments[i] = a
+			replaced = true
+		}
+	}
+	if !replaced {
+		s.Attachments = append(s.Attachments, a)
+	}
+
+	return a, s.Save(filename)
+}
+
+// RecordProvenance replaces the provenance in the sidecar of a file that
+// was just saved with the tool version, the active definitions and the
+// names of the maps and parameters the save changed. parentHash is the
+// file's hash before the save. It is best effort: every write path calls
+// it after a successful save, and a sidecar that can't be read or written
+// never fails the save.
+func RecordProvenance(filename, parentHash string, modified []string) {
+	hash, err := reader.HashFile(filename)
+	if err != nil {
+		return
+	}
+	s, err := LoadSidecar(filename)
+	if err != nil {
+		return // don't overwrite a sidecar we could not parse
+	}
+	s.Provenance = &models.Provenance{
+		ToolVersion:  version.Version,
+		Definitions:  models.DefinitionsFingerprint(),
+		Profile:      models.M21IDProfile.Name,
+		Modified:     modified,
+		ParentSHA256: parentHash,
+		SHA256:       hash,
+		Saved:        time.Now(),
+	}
+	s.Save(filename)
+}
+
+// hashData returns the hex SHA-256 of file contents, as reader.HashFile
+// would for the same bytes on disk
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Status reports whether the attached file is still present and unchanged:
+// "ok", "missing" or "modified"
+func (a Attachment) Status() string {
+	hash, err := reader.HashFile(a.Path)
+	switch {
+	case err != nil:
+		return "missing"
+	case hash != a.SHA256:
+		return "modified"
+	default:
+		return "ok"
+	}
+}
@@ -0,0 +1,255 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot defaults: a snapshot is due after DefaultSnapshotInterval or
+// DefaultSnapshotEdits edits, whichever comes first, and the newest
+// DefaultSnapshotKeep are kept
+const (
+	DefaultSnapshotInterval = 15 * time.Minute
+	DefaultSnapshotEdits    = 25
+	DefaultSnapshotKeep     = 20
+)
+
+// snapshotInfix separates the file name from the timestamp of a snapshot.
+// It differs from ".backup_", so snapshots never appear among the
+// operation backups, the timeline, or their retention.
+const snapshotInfix = ".snapshot_"
+
+// AfterWrite, if set, is called with the complete new contents after every
+// successful write of an ECU file by this package. The GUI's Snapshotter
+// uses it to keep the latest contents without re-reading the file.
+var AfterWrite func(filename string, data []byte)
+
+// notifyWritten calls AfterWrite
+func notifyWritten(filename string, data []byte) {
+	if AfterWrite != nil {
+		AfterWrite(filename, data)
+	}
+}
+
+// Snapshot is an automatic, labeled copy of a file taken during a long
+// editing session
+type Snapshot struct {
+	Path  string
+	Time  time.Time
+	Label string
+}
+
+// SnapshotInfo is the label of a snapshot, kept in the file's sidecar
+type SnapshotInfo struct {
+	// File is the snapshot's base name
+	File  string `json:"file"`
+	Label string `json:"label"`
+}
+
+// ListSnapshots returns the snapshots of filename, oldest first, with
+// their labels from the sidecar
+func ListSnapshots(filename string) ([]Snapshot, error) {
+	dir := filepath.Dir(filename)
+	prefix := filepath.Base(filename) + snapshotInfix
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{}
+	if s, err := LoadSidecar(filename); err == nil {
+		for _, info := range s.Snapshots {
+			labels[info.File] = info.Label
+		}
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimPrefix(name, prefix), time.Local)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Path: filepath.Join(dir, name), Time: t, Label: labels[name]})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+	return snapshots, nil
+}
+
+// SaveSnapshot writes data as a snapshot of filename with the given label
+// and prunes all but the newest keep snapshots
+func SaveSnapshot(filename string, data []byte, label string, keep int) (Snapshot, error) {
+	now := time.Now()
+	path := filename + snapshotInfix + now.Format(backupTimeFormat)
+	if err := writeFileAtomic(path, data); err != nil {
+		return Snapshot{}, err
+	}
+
+	// A second snapshot within the same second replaces the first
+	s, err := LoadSidecar(filename)
+	if err == nil {
+		info := SnapshotInfo{File: filepath.Base(path), Label: label}
+		s.Snapshots = append(slices.DeleteFunc(s.Snapshots, func(old SnapshotInfo) bool {
+			return old.File == info.File
+		}), info)
+		err = s.Save(filename)
+	}
+	snapshot := Snapshot{Path: path, Time: now, Label: label}
+	if err != nil {
+		return snapshot, fmt.Errorf("snapshot saved but not labeled: %w", err)
+	}
+	return snapshot, PruneSnapshots(filename, keep)
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots of filename and
+// their labels. Operation backups are never touched.
+func PruneSnapshots(filename string, keep int) error {
+	snapshots, err := ListSnapshots(filename)
+	if err != nil || len(snapshots) <= keep {
+		return err
+	}
+	removed := map[string]bool{}
+	for _, snapshot := range snapshots[:len(snapshots)-keep] {
+		if err := os.Remove(snapshot.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		removed[filepath.Base(snapshot.Path)] = true
+	}
+
+	s, err := LoadSidecar(filename)
+	if err != nil {
+		return err
+	}
+	kept := s.Snapshots[:0]
+	for _, info := range s.Snapshots {
+		if !removed[info.File] {
+			kept = append(kept, info)
+		}
+	}
+	s.Snapshots = kept
+	return s.Save(filename)
+}
+
+// RestoreSnapshot replaces filename with a snapshot, backing it up first
+// and logging the restore in its changelog. It returns the backup path.
+func RestoreSnapshot(filename string, snapshot Snapshot) (string, error) {
+	data, err := os.ReadFile(snapshot.Path)
+	if err != nil {
+		return "", err
+	}
+	current, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	backup, err := CreateBackupFrom(filename, current)
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup: %w", err)
+	}
+	if err := writeFileAtomic(filename, data); err != nil {
+		return backup, err
+	}
+	notifyWritten(filename, data)
+	RecordProvenance(filename, hashData(current), nil)
+
+	detail := filepath.Base(snapshot.Path)
+	if snapshot.Label != "" {
+		detail += " (" + snapshot.Label + ")"
+	}
+	err = AppendChangelog(filename, ChangelogEntry{
+		Action:       "restore",
+		Detail:       detail,
+		Backup:       backup,
+		BackupSHA256: backupHash(backup, current),
+		NoBackup:     backup == "",
+		Length:       int64(len(data)),
+	})
+	if err != nil {
+		return backup, fmt.Errorf("snapshot restored but changelog not updated: %w", err)
+	}
+	return backup, nil
+}
+
+// Snapshotter takes a snapshot of the tracked file when Interval has
+// passed or Edits writes were made since the last one, and only if the
+// file was written since. It keeps the contents passed to Written, so
+// taking a snapshot never re-reads the file.
+type Snapshotter struct {
+	Interval time.Duration
+	Edits    int
+	Keep     int
+
+	mu       sync.Mutex
+	filename string
+	buffer   []byte
+	edits    int
+	last     time.Time
+}
+
+// NewSnapshotter creates a snapshotter with the default interval, edit
+// count and retention
+func NewSnapshotter() *Snapshotter {
+	return &Snapshotter{Interval: DefaultSnapshotInterval, Edits: DefaultSnapshotEdits, Keep: DefaultSnapshotKeep}
+}
+
+// Track starts a new series for filename, forgetting unsnapshotted edits
+// of the previous file
+func (s *Snapshotter) Track(filename string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filename, s.buffer, s.edits, s.last = filename, nil, 0, time.Now()
+}
+
+// Written records a write of the tracked file; writes of other files are
+// ignored. It reports whether enough edits have accumulated for a
+// snapshot.
+func (s *Snapshotter) Written(filename string, data []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if filename != s.filename {
+		return false
+	}
+	s.buffer = data
+	s.edits++
+	return s.Edits > 0 && s.edits >= s.Edits
+}
+
+// Due reports whether a snapshot should be taken now: the file was written
+// since the last one and either the interval has passed or the edit count
+// is reached
+func (s *Snapshotter) Due(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.edits == 0 {
+		return false
+	}
+	return (s.Interval > 0 && now.Sub(s.last) >= s.Interval) || (s.Edits > 0 && s.edits >= s.Edits)
+}
+
+// Take saves the latest contents as a snapshot labeled with the time and
+// the number of edits it covers. It does nothing and returns false if
+// nothing was written since the last snapshot.
+func (s *Snapshotter) Take() (Snapshot, bool, error) {
+	s.mu.Lock()
+	filename, data, edits := s.filename, s.buffer, s.edits
+	if edits == 0 || data == nil {
+		s.mu.Unlock()
+		return Snapshot{}, false, nil
+	}
+	s.edits, s.last = 0, time.Now()
+	s.mu.Unlock()
+
+	label := fmt.Sprintf("auto %s, %d edit(s)", time.Now().Format("15:04"), edits)
+	snapshot, err := SaveSnapshot(filename, data, label, s.Keep)
+	return snapshot, true, err
+}
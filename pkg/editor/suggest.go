@@ -0,0 +1,71 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/tosih/motronic-m21-tool/pkg/datalog"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// FuelSuggestion is an advisory fuel map correction derived from logged
+// lambda. It is never applied automatically.
+type FuelSuggestion struct {
+	Changes []CellChange
+	// Uncovered cells have too few samples and are left untouched
+	Uncovered []CellPos
+	Authority float64
+}
+
+// SuggestFuel computes per-cell fuel multipliers from the measured and
+// target lambda of each cell. The multiplier is measured/target, so a
+// lean cell (measured above target) gets more fuel, and is limited to
+// 1±authority. fuel and target must share the overlay's grid.
+func SuggestFuel(data []byte, fuel models.MapConfig, target *models.ECUMap, o *datalog.Overlay, authority float64) (*FuelSuggestion, error) {
+	if o.Rows != fuel.Rows || o.Cols != fuel.Cols || target.Config.Rows != fuel.Rows || target.Config.Cols != fuel.Cols {
+		return nil, fmt.Errorf("%s (%dx%d) and %s (%dx%d) do not share a grid",
+			fuel.Name, fuel.Rows, fuel.Cols, target.Config.Name, target.Config.Rows, target.Config.Cols)
+	}
+	if fuel.Offset+fuel.ByteSize() > int64(len(data)) {
+		return nil, reader.NewError(reader.ErrOutOfRange, "%s at 0x%04X lies outside the file", fuel.Name, fuel.Offset)
+	}
+
+	s := &FuelSuggestion{Authority: authority}
+	size := models.DataTypeSize(fuel.DataType)
+
+	for i := 0; i < fuel.Rows; i++ {
+		for j := 0; j < fuel.Cols; j++ {
+			if o.LowConfidence(i, j) || target.Data[i][j] <= 0 {
+				s.Uncovered = append(s.Uncovered, CellPos{i, j})
+				continue
+			}
+
+			factor := o.Mean[i][j] / target.Data[i][j]
+			factor = math.Max(1-authority, math.Min(1+authority, factor))
+
+			offset := fuel.Offset + int64((i*fuel.Cols+j)*size)
+			oldRaw := fuel.DecodeRaw(data[offset:])
+			oldValue := fuel.ToReal(oldRaw)
+			newRaw, _ := fuel.ToRaw(oldValue * factor)
+			if newRaw == oldRaw {
+				continue
+			}
+
+			s.Changes = append(s.Changes, CellChange{
+				Map:        fuel.Name,
+				Row:        i,
+				Col:        j,
+				Offset:     offset,
+				DataType:   fuel.DataType,
+				Endianness: fuel.ByteOrder(),
+				OldRaw:     oldRaw,
+				NewRaw:     newRaw,
+				OldValue:   oldValue,
+				NewValue:   fuel.ToReal(newRaw),
+			})
+		}
+	}
+
+	return s, nil
+}
@@ -0,0 +1,287 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// TransformOp is an operation applied to every cell of a region, in
+// engineering units
+type TransformOp string
+
+const (
+	// TransformAdd adds the value to each cell
+	TransformAdd TransformOp = "add"
+	// TransformMultiply multiplies each cell by the value
+	TransformMultiply TransformOp = "mul"
+	// TransformSet sets each cell to the value
+	TransformSet TransformOp = "set"
+)
+
+// TransformOps lists the operations in the order the GUI offers them
+var TransformOps = []TransformOp{TransformAdd, TransformMultiply, TransformSet}
+
+// ParseTransformOp validates an operation name; "multiply" is accepted for
+// "mul"
+func ParseTransformOp(s string) (TransformOp, error) {
+	switch op := TransformOp(strings.ToLower(strings.TrimSpace(s))); op {
+	case TransformAdd, TransformMultiply, TransformSet:
+		return op, nil
+	case "multiply":
+		return TransformMultiply, nil
+	}
+	return "", fmt.Errorf("invalid operation %q: expected add, mul or set", s)
+}
+
+// Apply returns the result of the operation on one value
+func (op TransformOp) Apply(value, operand float64) float64 {
+	switch op {
+	case TransformAdd:
+		return value + operand
+	case TransformMultiply:
+		return value * operand
+	}
+	return operand
+}
+
+// CellRegion is a rectangle of map cells with inclusive bounds
+type CellRegion struct {
+	Row0 int `json:"row0"`
+	Col0 int `json:"col0"`
+	Row1 int `json:"row1"`
+	Col1 int `json:"col1"`
+}
+
+// WholeMap returns the region covering every cell of a map
+func WholeMap(cfg models.MapConfig) CellRegion {
+	return CellRegion{Row1: cfg.Rows - 1, Col1: cfg.Cols - 1}
+}
+
+// ParseCellRegion parses "rows,cols" where each part is one index or an
+// inclusive "first-last" range, e.g. "2-5,0-15" or "3,7"
+func ParseCellRegion(s string) (CellRegion, error) {
+	rows, cols, ok := strings.Cut(s, ",")
+	if !ok {
+		return CellRegion{}, fmt.Errorf("invalid region %q: expected rows,cols such as 2-5,0-15", s)
+	}
+	r0, r1, err := parseIndexRange(rows)
+	if err != nil {
+		return CellRegion{}, fmt.Errorf("invalid region %q: %w", s, err)
+	}
+	c0, c1, err := parseIndexRange(cols)
+	if err != nil {
+		return CellRegion{}, fmt.Errorf("invalid region %q: %w", s, err)
+	}
+	return CellRegion{Row0: r0, Col0: c0, Row1: r1, Col1: c1}, nil
+}
+
+func parseIndexRange(s string) (int, int, error) {
+	first, last, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	a, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not an index", first)
+	}
+	if !isRange {
+		return a, a, nil
+	}
+	b, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not an index", last)
+	}
+	return a, b, nil
+}
+
+// Check reports a region that is empty or leaves the map
+func (r CellRegion) Check(cfg models.MapConfig) error {
+	if r.Row0 < 0 || r.Col0 < 0 || r.Row1 >= cfg.Rows || r.Col1 >= cfg.Cols || r.Row0 > r.Row1 || r.Col0 > r.Col1 {
+		return reader.NewError(reader.ErrOutOfRange, "region %s does not fit %s (%dx%d)", r, cfg.Name, cfg.Rows, cfg.Cols)
+	}
+	return nil
+}
+
+// Cells returns the number of cells in the region
+func (r CellRegion) Cells() int {
+	return (r.Row1 - r.Row0 + 1) * (r.Col1 - r.Col0 + 1)
+}
+
+// String formats the region as [row0,col0]-[row1,col1]
+func (r CellRegion) String() string {
+	return fmt.Sprintf("[%d,%d]-[%d,%d]", r.Row0, r.Col0, r.Row1, r.Col1)
+}
+
+// TransformResult is the planned outcome of a transform: the changes to
+// write and what the region looks like afterwards
+type TransformResult struct {
+	Changes []CellChange
+	Cells   int
+	// Min and Max are the region's values after the transform
+	Min float64
+	Max float64
+	// Clamped holds cells whose result does not fit the data type and is
+	// clamped to its limit
+	Clamped []CellPos
+}
+
+// Summary describes the result in one line
+func (t TransformResult) Summary() string {
+	return fmt.Sprintf("%d of %d cells change, result %.2f to %.2f, %d clamped",
+		len(t.Changes), t.Cells, t.Min, t.Max, len(t.Clamped))
+}
+
+// TransformRegion computes the changes that apply op with operand to every
+// cell of region, in engineering units. Results outside the data type are
+// clamped and listed. Nothing is modified, so the result can be shown as
+// a preview; the CLI -scale-region flag, the GUI Transform Map dialog and
+// the web transform endpoint all plan through it.
+func TransformRegion(data []byte, cfg models.MapConfig, region CellRegion, op TransformOp, operand float64) (TransformResult, error) {
+	if err := region.Check(cfg); err != nil {
+		return TransformResult{}, err
+	}
+	if math.IsNaN(operand) || math.IsInf(operand, 0) {
+		return TransformResult{}, reader.NewError(reader.ErrValueOutOfBounds, "invalid operand %v", operand)
+	}
+	if cfg.Offset+cfg.ByteSize() > int64(len(data)) {
+		return TransformResult{}, reader.NewError(reader.ErrOutOfRange, "%s at 0x%04X lies outside the file", cfg.Name, cfg.Offset)
+	}
+
+	size := models.DataTypeSize(cfg.DataType)
+	result := TransformResult{Cells: region.Cells(), Min: math.Inf(1), Max: math.Inf(-1)}
+	for i := region.Row0; i <= region.Row1; i++ {
+		for j := region.Col0; j <= region.Col1; j++ {
+			offset := cfg.Offset + int64((i*cfg.Cols+j)*size)
+			oldRaw := cfg.DecodeRaw(data[offset:])
+			newRaw, clamped := cfg.ToRaw(op.Apply(cfg.ToReal(oldRaw), operand))
+			if clamped {
+				result.Clamped = append(result.Clamped, CellPos{i, j})
+			}
+
+			newValue := cfg.ToReal(newRaw)
+			result.Min = math.Min(result.Min, newValue)
+			result.Max = math.Max(result.Max, newValue)
+			if newRaw == oldRaw {
+				continue
+			}
+			result.Changes = append(result.Changes, CellChange{
+				Map:        cfg.Name,
+				Row:        i,
+				Col:        j,
+				Offset:     offset,
+				DataType:   cfg.DataType,
+				Endianness: cfg.ByteOrder(),
+				OldRaw:     oldRaw,
+				NewRaw:     newRaw,
+				OldValue:   cfg.ToReal(oldRaw),
+				NewValue:   newValue,
+			})
+		}
+	}
+	return result, nil
+}
+
+// TransformSpec is a transform of one map region, as given to -scale-region
+type TransformSpec struct {
+	Map     models.MapConfig
+	Op      TransformOp
+	Operand float64
+	Region  CellRegion
+}
+
+// ParseTransform parses "map:op:value[:rows,cols]", e.g. "fuel:mul:1.05"
+// or "ignition:add:-2:4-7,0-15". Without a region the whole map is used.
+func ParseTransform(s string) (TransformSpec, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return TransformSpec{}, fmt.Errorf("invalid transform %q: expected map:op:value[:rows,cols]", s)
+	}
+
+	cfg, err := MatchMap(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return TransformSpec{}, err
+	}
+	op, err := ParseTransformOp(parts[1])
+	if err != nil {
+		return TransformSpec{}, err
+	}
+	operand, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+	if err != nil {
+		return TransformSpec{}, fmt.Errorf("invalid value %q: expected a number", parts[2])
+	}
+
+	region := WholeMap(cfg)
+	if len(parts) == 4 {
+		if region, err = ParseCellRegion(parts[3]); err != nil {
+			return TransformSpec{}, err
+		}
+		if err := region.Check(cfg); err != nil {
+			return TransformSpec{}, err
+		}
+	}
+	return TransformSpec{Map: cfg, Op: op, Operand: operand, Region: region}, nil
+}
+
+// ApplyTransform plans, confirms and writes a one-shot -scale-region.
+// Clamped cells must be acknowledged before writing.
+func ApplyTransform(prompt Prompter, filename string, spec TransformSpec, dryRun bool) bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read file: %v\n", err)
+		return false
+	}
+	result, err := TransformRegion(data, spec.Map, spec.Region, spec.Op, spec.Operand)
+	if err != nil {
+		pterm.Error.Println(err)
+		return false
+	}
+
+	pterm.Info.Printf("%s %s: %s %g\n", spec.Map.Name, spec.Region, spec.Op, spec.Operand)
+	if len(result.Changes) == 0 {
+		pterm.Info.Println(i18n.T("cli.no_changes"))
+		return true
+	}
+	PrintChanges(result.Changes)
+	pterm.Info.Println(result.Summary())
+	if len(result.Clamped) > 0 {
+		pterm.Warning.Printf("Would clamp: %s\n", joinCells(result.Clamped))
+	}
+	if refuseOutOfRange(spec.Map, result.Changes) {
+		return false
+	}
+
+	if dryRun {
+		pterm.Warning.Println(i18n.T("cli.dry_run"))
+		return true
+	}
+	if err := reader.CheckWritable(filename); err != nil {
+		pterm.Error.Println(reader.DescribeWriteError(err))
+		return false
+	}
+	if len(result.Clamped) > 0 {
+		if !Confirm(prompt, ConfirmSave, i18n.T("cli.confirm.clamped", len(result.Clamped))) {
+			pterm.Info.Println(i18n.T("cli.cancelled"))
+			return true
+		}
+	}
+	if !Confirm(prompt, ConfirmSave, i18n.T("cli.confirm.changes")) {
+		pterm.Info.Println(i18n.T("cli.cancelled"))
+		return true
+	}
+
+	report, err := applyChanges(filename, "Transform "+spec.Map.Name, result.Changes)
+	PrintBackup(report.Backup)
+	if err != nil {
+		pterm.Error.Printf("Failed to write: %s\n", reader.DescribeWriteError(err))
+		return false
+	}
+	pterm.Success.Printf("%s: %d cells changed\n", spec.Map.Name, len(result.Changes))
+	report.PrintChecksum()
+	report.PrintMirror()
+	return true
+}
@@ -0,0 +1,252 @@
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/tosih/motronic-m21-tool/internal/paths"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// UserMapsFile is the file in the config directory holding maps defined
+// by hand
+const UserMapsFile = "user_maps.json"
+
+// UserMap is a map definition created by the user, as stored in
+// UserMapsFile. 16-bit data is little-endian unless Endianness is "big".
+type UserMap struct {
+	Name        string  `json:"name"`
+	Offset      int64   `json:"offset"`
+	Rows        int     `json:"rows"`
+	Cols        int     `json:"cols"`
+	DataType    string  `json:"data_type"`
+	Scale       float64 `json:"scale"`
+	ValueOffset float64 `json:"value_offset"`
+	Unit        string  `json:"unit"`
+	Description string  `json:"description,omitempty"`
+	InvertY     bool    `json:"invert_y,omitempty"`
+	// Category is one of models.Categories; empty lists the map under Other
+	Category string `json:"category,omitempty"`
+	// MinValue and MaxValue limit edits to the cells; both zero for none
+	MinValue float64 `json:"min_value,omitempty"`
+	MaxValue float64 `json:"max_value,omitempty"`
+	// Endianness is "little" (the default) or "big"
+	Endianness models.Endianness `json:"endianness,omitempty"`
+	// Formula and InverseFormula replace scale and value_offset for tables
+	// that aren't linear, e.g. "256/x" (see models.Formula)
+	Formula        string `json:"formula,omitempty"`
+	InverseFormula string `json:"inverse_formula,omitempty"`
+	// OverlapNote records partial overlaps confirmed when the map was added
+	OverlapNote string `json:"overlap_note,omitempty"`
+	// Unconfirmed marks a promoted scan hit whose location isn't verified
+	Unconfirmed bool `json:"unconfirmed,omitempty"`
+	// Role designates the map for a feature, e.g. "coldstart" (see
+	// models.MapByRole)
+	Role string `json:"role,omitempty"`
+	// XAxis and YAxis locate the RPM and load breakpoints in the binary
+	XAxis *UserAxis `json:"x_axis,omitempty"`
+	YAxis *UserAxis `json:"y_axis,omitempty"`
+}
+
+// UserAxis is the stored form of a map axis (models.AxisConfig)
+type UserAxis struct {
+	Offset      int64   `json:"offset"`
+	Count       int     `json:"count"`
+	DataType    string  `json:"data_type"`
+	Scale       float64 `json:"scale"`
+	ValueOffset float64 `json:"value_offset"`
+	Unit        string  `json:"unit,omitempty"`
+	// Endianness is empty to follow the map
+	Endianness models.Endianness `json:"endianness,omitempty"`
+	// Name captions the axis, e.g. "Temp"
+	Name string `json:"name,omitempty"`
+	// Labels are fixed breakpoints for an axis not located in the binary
+	Labels []float64 `json:"labels,omitempty"`
+}
+
+// config returns the axis definition of a, nil for no axis
+func (a *UserAxis) config() *models.AxisConfig {
+	if a == nil {
+		return nil
+	}
+	return &models.AxisConfig{
+		Offset:     a.Offset,
+		Count:      a.Count,
+		DataType:   a.DataType,
+		Scale:      a.Scale,
+		Offset2:    a.ValueOffset,
+		Unit:       a.Unit,
+		Endianness: a.Endianness,
+		Name:       a.Name,
+		Labels:     a.Labels,
+	}
+}
+
+// newUserAxis returns the stored form of an axis definition
+func newUserAxis(axis *models.AxisConfig) *UserAxis {
+	if axis == nil {
+		return nil
+	}
+	return &UserAxis{
+		Offset:      axis.Offset,
+		Count:       axis.Count,
+		DataType:    axis.DataType,
+		Scale:       axis.Scale,
+		ValueOffset: axis.Offset2,
+		Unit:        axis.Unit,
+		Endianness:  axis.Endianness,
+		Name:        axis.Name,
+		Labels:      axis.Labels,
+	}
+}
+
+// Config returns the map definition of u
+func (u UserMap) Config() models.MapConfig {
+	return models.MapConfig{
+		Name:           u.Name,
+		Offset:         u.Offset,
+		Rows:           u.Rows,
+		Cols:           u.Cols,
+		DataType:       u.DataType,
+		Scale:          u.Scale,
+		Offset2:        u.ValueOffset,
+		Unit:           u.Unit,
+		Description:    u.Description,
+		InvertY:        u.InvertY,
+		Category:       u.Category,
+		MinValue:       u.MinValue,
+		MaxValue:       u.MaxValue,
+		Endianness:     u.Endianness,
+		Formula:        u.Formula,
+		InverseFormula: u.InverseFormula,
+		OverlapNote:    u.OverlapNote,
+		Unconfirmed:    u.Unconfirmed,
+		Role:           u.Role,
+		XAxis:          u.XAxis.config(),
+		YAxis:          u.YAxis.config(),
+	}
+}
+
+// NewUserMap returns the stored form of a map definition
+func NewUserMap(cfg models.MapConfig) UserMap {
+	return UserMap{
+		Name:           cfg.Name,
+		Offset:         cfg.Offset,
+		Rows:           cfg.Rows,
+		Cols:           cfg.Cols,
+		DataType:       cfg.DataType,
+		Scale:          cfg.Scale,
+		ValueOffset:    cfg.Offset2,
+		Unit:           cfg.Unit,
+		Description:    cfg.Description,
+		InvertY:        cfg.InvertY,
+		Category:       cfg.Category,
+		MinValue:       cfg.MinValue,
+		MaxValue:       cfg.MaxValue,
+		Endianness:     cfg.Endianness,
+		Formula:        cfg.Formula,
+		InverseFormula: cfg.InverseFormula,
+		OverlapNote:    cfg.OverlapNote,
+		Unconfirmed:    cfg.Unconfirmed,
+		Role:           cfg.Role,
+		XAxis:          newUserAxis(cfg.XAxis),
+		YAxis:          newUserAxis(cfg.YAxis),
+	}
+}
+
+// LoadUserMaps reads the user's map definitions. A missing file returns
+// none without error.
+func LoadUserMaps() ([]UserMap, error) {
+	path, err := paths.ConfigFile(UserMapsFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var maps []UserMap
+	if err := json.Unmarshal(data, &maps); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return maps, nil
+}
+
+// ApplyUserMaps appends the user's map definitions to models.MapConfigs,
+// so every view, edit and -check-defs sees them
+func ApplyUserMaps() error {
+	maps, err := LoadUserMaps()
+	if err != nil {
+		return err
+	}
+	for _, u := range maps {
+		cfg := models.Located(u.Config())
+		cfg.Source = UserMapsFile
+		models.MapConfigs = append(models.MapConfigs, cfg)
+	}
+	return nil
+}
+
+// AddUserMap validates cfg against the active definitions and a file of
+// size bytes with models.CheckNewMap, saves it to the user's definitions
+// and appends it to models.MapConfigs. It refuses anything -check-defs
+// would reject, and partial overlaps unless cfg.OverlapNote shows they
+// were confirmed (see models.OverlapNote); both are ErrOverlap errors.
+func AddUserMap(cfg models.MapConfig, size int64) (models.DefinitionCheck, error) {
+	check := models.CheckNewMap(cfg, models.MapConfigs, models.ConfigParams, size)
+	if err := errors.Join(check.Errors...); err != nil {
+		return check, err
+	}
+	for _, o := range check.Overlaps {
+		if o.Exact {
+			return check, reader.NewError(reader.ErrOverlap, "%s", o)
+		}
+	}
+	if len(check.Overlaps) > 0 && cfg.OverlapNote == "" {
+		return check, reader.NewError(reader.ErrOverlap, "%s; confirm the overlap to add the map anyway", check.Overlaps[0])
+	}
+
+	maps, err := LoadUserMaps()
+	if err != nil {
+		return check, err
+	}
+	// Saved offsets are into the image, like those of the built-in maps
+	maps = append(maps, NewUserMap(cfg.Relocate(-models.BaseOffset)))
+	data, err := json.MarshalIndent(maps, "", "  ")
+	if err != nil {
+		return check, err
+	}
+	path, err := paths.ConfigFile(UserMapsFile)
+	if err != nil {
+		return check, err
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return check, err
+	}
+	cfg.Source = UserMapsFile
+	models.MapConfigs = append(models.MapConfigs, cfg)
+	return check, nil
+}
+
+// PromoteMap adds a map found by the scanner to the user's definitions
+// with AddUserMap. Partial overlaps with existing definitions need the
+// user's confirmation, which is recorded in the map's OverlapNote;
+// declining them returns an ErrOverlap error.
+func PromoteMap(p Prompter, cfg models.MapConfig, size int64) (models.DefinitionCheck, error) {
+	check := models.CheckNewMap(cfg, models.MapConfigs, models.ConfigParams, size)
+	if len(check.Errors) == 0 && check.Duplicates() == 0 && len(check.Overlaps) > 0 {
+		question := fmt.Sprintf("%s %s; add it anyway?", cfg.Name, models.OverlapNote(check.Overlaps))
+		if !Confirm(p, ConfirmSave, question) {
+			return check, reader.NewError(reader.ErrOverlap, "%s was not added: the overlap was not confirmed", cfg.Name)
+		}
+		cfg.OverlapNote = models.OverlapNote(check.Overlaps)
+	}
+	return AddUserMap(cfg, size)
+}
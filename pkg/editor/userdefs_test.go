@@ -0,0 +1,85 @@
+package editor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// withDefinitions replaces the active definitions and the user config
+// directory for one test
+func withDefinitions(t *testing.T, maps []models.MapConfig) {
+	t.Helper()
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	savedMaps, savedParams := models.MapConfigs, models.ConfigParams
+	t.Cleanup(func() { models.MapConfigs, models.ConfigParams = savedMaps, savedParams })
+	models.MapConfigs, models.ConfigParams = maps, nil
+}
+
+func TestPromoteMap(t *testing.T) {
+	existing := models.MapConfig{Name: "Ignition", Offset: 0x100, Rows: 2, Cols: 4, DataType: "uint8", Scale: 1}
+	hit := func(offset int64) models.MapConfig {
+		return models.MapConfig{Name: "Hit", Offset: offset, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1, Unit: "raw", Unconfirmed: true}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     models.MapConfig
+		answers []string
+		added   bool
+		note    bool
+	}{
+		{name: "no overlap", cfg: hit(0x200), added: true},
+		{name: "overlap confirmed", cfg: hit(0x106), answers: []string{"y"}, added: true, note: true},
+		{name: "overlap declined", cfg: hit(0x106), answers: []string{"n"}},
+		{name: "duplicate", cfg: models.MapConfig{Name: "Hit", Offset: 0x100, Rows: 2, Cols: 4, DataType: "uint8", Scale: 1}, answers: []string{"y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withDefinitions(t, []models.MapConfig{existing})
+			_, err := PromoteMap(&ScriptedPrompter{Answers: tt.answers}, tt.cfg, 0x1000)
+			if tt.added != (err == nil) {
+				t.Fatalf("PromoteMap error %v, want added %v", err, tt.added)
+			}
+			if !tt.added {
+				if !errors.Is(err, reader.ErrOverlap) {
+					t.Errorf("error %v, want an ErrOverlap error", err)
+				}
+				if len(models.MapConfigs) != 1 {
+					t.Errorf("%d maps active, want only the existing one", len(models.MapConfigs))
+				}
+				return
+			}
+
+			saved, err := LoadUserMaps()
+			if err != nil || len(saved) != 1 {
+				t.Fatalf("LoadUserMaps = %d maps, %v; want the promoted map", len(saved), err)
+			}
+			note := models.MapConfigs[len(models.MapConfigs)-1].OverlapNote
+			if tt.note != (note != "") || saved[0].OverlapNote != note {
+				t.Errorf("active note %q, saved note %q, want a note: %v", note, saved[0].OverlapNote, tt.note)
+			}
+			if !saved[0].Config().Unconfirmed {
+				t.Error("the promoted map was saved as confirmed")
+			}
+			if tt.note && !strings.Contains(note, `map "Ignition"`) {
+				t.Errorf("note %q does not name the overlapped map", note)
+			}
+		})
+	}
+}
+
+func TestAddUserMapNeedsConfirmedOverlap(t *testing.T) {
+	withDefinitions(t, []models.MapConfig{{Name: "Ignition", Offset: 0x100, Rows: 2, Cols: 4, DataType: "uint8", Scale: 1}})
+	cfg := models.MapConfig{Name: "Hit", Offset: 0x106, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1}
+	if _, err := AddUserMap(cfg, 0x1000); err == nil {
+		t.Fatal("AddUserMap accepted an unconfirmed overlap")
+	}
+	cfg.OverlapNote = "overlaps the end of Ignition"
+	if _, err := AddUserMap(cfg, 0x1000); err != nil {
+		t.Fatalf("AddUserMap with an overlap note: %v", err)
+	}
+}
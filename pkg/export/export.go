@@ -1,59 +1,98 @@
 package export
 
 import (
+	"bytes"
 	"encoding/csv"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/progress"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
 	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
 )
 
+// Prefixes of the section headers marking the optional raw and offset
+// grids. The scaled value grid is headed by the map's GridHeader, e.g.
+// "Load\\RPM" or "Temp\\RPM".
+const (
+	rawPrefix     = "Raw\\"
+	offsetsPrefix = "Offset\\"
+)
+
+// Options selects the optional sections written by ExportMapsToCSV
+type Options struct {
+	// Lossless adds the original raw cell values so that importing the
+	// file back reproduces the binary byte for byte
+	Lossless bool
+	// Offsets adds the absolute file offset of every cell
+	Offsets bool
+}
+
+// fileNameReplacer turns a map name into a safe CSV file name
+var fileNameReplacer = strings.NewReplacer(" ", "_", "/", "-", "\\", "-")
+
+// CSVFileName returns the CSV file name used when exporting a map
+func CSVFileName(cfg models.MapConfig) string {
+	return fileNameReplacer.Replace(strings.ToLower(cfg.Name)) + ".csv"
+}
+
 // ExportMapsToCSV exports selected maps to CSV files
-func ExportMapsToCSV(filename, exportPath, mapType string, readMap func(string, models.MapConfig) (*models.ECUMap, error)) {
+func ExportMapsToCSV(filename, exportPath, mapType string, opts Options, readMap func(string, models.MapConfig) (*models.ECUMap, error)) {
 	// Create export directory if it doesn't exist
 	if err := os.MkdirAll(exportPath, 0755); err != nil {
 		pterm.Error.Printf("Failed to create export directory: %v\n", err)
 		return
 	}
 
-	var selectedConfigs []models.MapConfig
-	if mapType == "all" {
-		selectedConfigs = models.MapConfigs
-	} else {
-		// Select specific map
-		for _, cfg := range models.MapConfigs {
-			if strings.Contains(strings.ToLower(cfg.Name), strings.ToLower(mapType)) {
-				selectedConfigs = append(selectedConfigs, cfg)
-			}
-		}
-	}
+	selectedConfigs := models.SelectMaps(mapType)
 
-	spinner, _ := pterm.DefaultSpinner.Start("Exporting maps to CSV...")
+	bar := progress.Start("Exporting maps to CSV", len(selectedConfigs))
+	var failures []string
 
 	for _, cfg := range selectedConfigs {
 		ecuMap, err := readMap(filename, cfg)
 		if err != nil {
-			spinner.Warning(fmt.Sprintf("Failed to read %s", cfg.Name))
+			failures = append(failures, fmt.Sprintf("Failed to read %s", cfg.Name))
+			bar.Step(cfg.Name)
 			continue
 		}
 
+		var raw [][]int64
+		if opts.Lossless {
+			raw, err = reader.ReadRawMap(filename, cfg)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("Failed to read raw values of %s", cfg.Name))
+				bar.Step(cfg.Name)
+				continue
+			}
+		}
+
 		// Create CSV filename
-		csvFilename := filepath.Join(exportPath,
-			strings.ReplaceAll(strings.ToLower(cfg.Name), " ", "_")+".csv")
+		csvFilename := filepath.Join(exportPath, CSVFileName(cfg))
 
-		if err := exportMapToCSV(ecuMap, csvFilename); err != nil {
-			spinner.Warning(fmt.Sprintf("Failed to export %s", cfg.Name))
-			continue
+		if err := exportMapToCSV(ecuMap, raw, opts.Offsets, csvFilename); err != nil {
+			failures = append(failures, fmt.Sprintf("Failed to export %s", cfg.Name))
 		}
+		bar.Step(filepath.Base(csvFilename))
 	}
 
-	spinner.Success(fmt.Sprintf("Maps exported to %s", exportPath))
+	bar.Stop()
+
+	for _, failure := range failures {
+		pterm.Warning.Println(failure)
+	}
+	pterm.Success.Printf("Maps exported to %s\n", exportPath)
 }
 
-func exportMapToCSV(m *models.ECUMap, filename string) error {
+func exportMapToCSV(m *models.ECUMap, raw [][]int64, offsets bool, filename string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -66,64 +105,428 @@ func exportMapToCSV(m *models.ECUMap, filename string) error {
 	// Write metadata as comments
 	writer.Write([]string{fmt.Sprintf("# %s", m.Config.Name)})
 	writer.Write([]string{fmt.Sprintf("# Offset: 0x%04X", m.Config.Offset)})
+	writer.Write([]string{fmt.Sprintf("# End: 0x%04X (exclusive)", m.Config.Offset+m.Config.ByteSize())})
 	writer.Write([]string{fmt.Sprintf("# Size: %dx%d", m.Config.Rows, m.Config.Cols)})
 	writer.Write([]string{fmt.Sprintf("# Unit: %s", m.Config.Unit)})
+	for _, warning := range m.AxisWarnings() {
+		writer.Write([]string{fmt.Sprintf("# Warning: %s", warning)})
+	}
 	writer.Write([]string{""})
 
-	// Write RPM header (column indices)
-	rpmStep := 8000 / m.Config.Cols
-	header := []string{"Load\\RPM"}
-	for j := 0; j < m.Config.Cols; j++ {
-		header = append(header, fmt.Sprintf("%d", j*rpmStep))
-	}
+	// Write the column header: the map's breakpoints, or synthetic labels
+	header := append([]string{m.Config.GridHeader()}, m.ColumnLabels()...)
 	writer.Write(header)
+	rowLabels := m.RowLabels()
 
-	// Write data rows with load percentages
-	loadStep := 100 / m.Config.Rows
+	// Write data rows labeled with the row axis
 	for i := 0; i < m.Config.Rows; i++ {
-		row := []string{fmt.Sprintf("%d%%", i*loadStep)}
+		row := []string{rowLabels[i]}
 		for j := 0; j < m.Config.Cols; j++ {
 			row = append(row, fmt.Sprintf("%.2f", m.Data[i][j]))
 		}
 		writer.Write(row)
 	}
 
-	return nil
+	// Write the raw hex grid used for lossless import
+	if raw != nil {
+		digits := 2 * models.DataTypeSize(m.Config.DataType)
+		writer.Write([]string{""})
+		header[0] = rawPrefix + m.Config.XAxisName()
+		writer.Write(header)
+		for i := 0; i < m.Config.Rows; i++ {
+			row := []string{rowLabels[i]}
+			for j := 0; j < m.Config.Cols; j++ {
+				row = append(row, fmt.Sprintf("%0*X", digits, raw[i][j]))
+			}
+			writer.Write(row)
+		}
+	}
+
+	// Write the absolute file offset of each cell, without a 0x prefix so
+	// values can be pasted straight into a disassembler or hex viewer
+	if offsets {
+		size := models.DataTypeSize(m.Config.DataType)
+		writer.Write([]string{""})
+		header[0] = offsetsPrefix + m.Config.XAxisName()
+		writer.Write(header)
+		for i := 0; i < m.Config.Rows; i++ {
+			row := []string{rowLabels[i]}
+			for j := 0; j < m.Config.Cols; j++ {
+				row = append(row, fmt.Sprintf("%04X", m.Config.Offset+int64((i*m.Config.Cols+j)*size)))
+			}
+			writer.Write(row)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// MapCSV is a map parsed from an exported CSV file
+type MapCSV struct {
+	Name   string
+	Offset int64
+	// Header is the corner cell of the value grid, e.g. "Temp\\RPM"
+	Header string
+	// RowLabels are the row axis labels of the value grid
+	RowLabels []string
+	Values    [][]string
+	Raw       [][]string
+}
+
+// ParseMapCSV parses a CSV file written by the exporter
+func ParseMapCSV(r io.Reader) (*MapCSV, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MapCSV{Offset: -1}
+	var section *[][]string
+	for _, record := range records {
+		if len(record) == 0 || strings.TrimSpace(strings.Join(record, "")) == "" {
+			section = nil
+			continue
+		}
+
+		first := strings.TrimSpace(record[0])
+		switch {
+		case strings.HasPrefix(first, "# Offset:"):
+			offset, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(first, "# Offset:")), 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid offset line %q", first)
+			}
+			m.Offset = offset
+		case strings.HasPrefix(first, "#"):
+			if m.Name == "" {
+				m.Name = strings.TrimSpace(strings.TrimPrefix(first, "#"))
+			}
+		case strings.HasPrefix(first, rawPrefix):
+			section = &m.Raw
+		case strings.HasPrefix(first, offsetsPrefix):
+			section = nil
+		case section == nil && strings.Contains(first, "\\") && m.Values == nil:
+			m.Header = first
+			section = &m.Values
+		case section != nil:
+			if section == &m.Values {
+				m.RowLabels = append(m.RowLabels, first)
+			}
+			*section = append(*section, record[1:])
+		}
+	}
+
+	if m.Values == nil {
+		return nil, fmt.Errorf("couldn't find data header")
+	}
+	return m, nil
+}
+
+// Config returns the built-in map definition the CSV was exported from,
+// matched by name and falling back to offset
+func (m *MapCSV) Config() (models.MapConfig, error) {
+	for _, cfg := range models.MapConfigs {
+		if strings.EqualFold(cfg.Name, m.Name) {
+			return cfg, nil
+		}
+	}
+	for _, cfg := range models.MapConfigs {
+		if cfg.Offset == m.Offset {
+			return cfg, nil
+		}
+	}
+	return models.MapConfig{}, fmt.Errorf("no map definition matches %q at 0x%04X", m.Name, m.Offset)
 }
 
-// ImportMapFromCSV imports a map from a CSV file
-func ImportMapFromCSV(ecuFilename, csvFilename string) {
-	pterm.Info.Printf("Importing map from %s\n", csvFilename)
+// snapTolerance is half the 0.01 resolution values are exported with;
+// values further than this from a representable value are snapped
+const snapTolerance = 0.005 + 1e-9
 
-	// Read CSV file
-	file, err := os.Open(csvFilename)
+// PlanImport classifies every cell of the CSV against the binary data and
+// records the writes for the accepted and snapped cells in op. Raw values
+// are preferred when present, unless the scaled value in the same cell was
+// edited after export. Cells outside the map's limits are rejected unless
+// editor.ForceRange is set.
+func PlanImport(data []byte, op *editor.ImportOperation, m *MapCSV) {
+	cfg, err := m.Config()
 	if err != nil {
-		pterm.Error.Printf("Failed to open CSV file: %v\n", err)
+		op.Reject("%v", err)
+		return
+	}
+	op.Map = cfg.Name
+
+	if err := checkGrid(m.Values, cfg); err != nil {
+		op.Reject("values: %v", err)
+		return
+	}
+	if m.Raw != nil {
+		if err := checkGrid(m.Raw, cfg); err != nil {
+			op.Reject("raw values: %v", err)
+			return
+		}
+	}
+	if cfg.Offset+cfg.ByteSize() > int64(len(data)) {
+		op.Reject("%s extends past end of file", cfg.Name)
 		return
 	}
+
+	size := models.DataTypeSize(cfg.DataType)
+	lo, hi := models.RawRange(cfg.DataType)
+	minReal, maxReal := cfg.ToReal(lo), cfg.ToReal(hi)
+	if minReal > maxReal {
+		minReal, maxReal = maxReal, minReal
+	}
+
+	for i := 0; i < cfg.Rows; i++ {
+		for j := 0; j < cfg.Cols; j++ {
+			text := strings.TrimSpace(m.Values[i][j])
+			cell := editor.ImportCell{Row: i, Col: j, Value: text, Outcome: editor.OutcomeAccepted}
+
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				cell.Outcome, cell.Reason = editor.OutcomeRejected, "not a number"
+				op.Record(cell)
+				continue
+			}
+
+			var newRaw int64
+			haveRaw := false
+			if m.Raw != nil {
+				raw, err := strconv.ParseInt(strings.TrimSpace(m.Raw[i][j]), 16, 64)
+				if err != nil {
+					cell.Outcome, cell.Reason = editor.OutcomeRejected, fmt.Sprintf("invalid raw value %q", m.Raw[i][j])
+					op.Record(cell)
+					continue
+				}
+				// Hex is written unsigned; reinterpret it in the map's type
+				var buf [2]byte
+				cfg.EncodeRaw(buf[:], raw)
+				raw = cfg.DecodeRaw(buf[:])
+
+				// Use the raw value only if the scaled value is untouched
+				if fmt.Sprintf("%.2f", cfg.ToReal(raw)) == text {
+					newRaw, haveRaw = raw, true
+				}
+			}
+			if !haveRaw {
+				var clamped bool
+				newRaw, clamped = cfg.ToRaw(value)
+				if clamped {
+					cell.Outcome = editor.OutcomeClamped
+					cell.Reason = fmt.Sprintf("outside the %s range %.2f to %.2f %s", cfg.DataType, minReal, maxReal, cfg.Unit)
+					op.Record(cell)
+					continue
+				}
+				if math.Abs(cfg.ToReal(newRaw)-value) > snapTolerance {
+					cell.Outcome = editor.OutcomeSnapped
+					cell.Reason = fmt.Sprintf("written as %.2f %s", cfg.ToReal(newRaw), cfg.Unit)
+				}
+			}
+
+			offset := cfg.Offset + int64((i*cfg.Cols+j)*size)
+			oldRaw := cfg.DecodeRaw(data[offset:])
+			if oldRaw != newRaw && !cfg.InRange(cfg.ToReal(newRaw)) && !editor.ForceRange {
+				cell.Outcome = editor.OutcomeRejected
+				cell.Reason = fmt.Sprintf("%.2f is outside the allowed range %s", cfg.ToReal(newRaw), cfg.RangeLabel())
+				op.Record(cell)
+				continue
+			}
+			op.Record(cell)
+			if oldRaw == newRaw {
+				continue
+			}
+
+			op.Changes = append(op.Changes, editor.CellChange{
+				Map:        cfg.Name,
+				Row:        i,
+				Col:        j,
+				Offset:     offset,
+				DataType:   cfg.DataType,
+				Endianness: cfg.ByteOrder(),
+				OldRaw:     oldRaw,
+				NewRaw:     newRaw,
+				OldValue:   cfg.ToReal(oldRaw),
+				NewValue:   cfg.ToReal(newRaw),
+			})
+		}
+	}
+}
+
+// PlanImportFiles classifies every cell of each CSV file against data, as
+// modified by the files before it. The CLI and GUI share it so both
+// report and apply imports the same way.
+func PlanImportFiles(data []byte, csvFiles []string) *editor.ImportReport {
+	return planImportFiles(data, csvFiles, func(string) {})
+}
+
+// planImportFiles does the work of PlanImportFiles, calling step after
+// each file
+func planImportFiles(data []byte, csvFiles []string, step func(string)) *editor.ImportReport {
+	report := &editor.ImportReport{}
+	work := bytes.Clone(data)
+	for _, csvFile := range csvFiles {
+		op := report.Add(filepath.Base(csvFile))
+		if m, err := parseMapCSVFile(csvFile); err != nil {
+			op.Reject("invalid CSV format: %v", err)
+		} else {
+			PlanImport(work, op, m)
+			for _, c := range op.Changes {
+				c.Apply(work)
+			}
+		}
+		step(filepath.Base(csvFile))
+	}
+	return report
+}
+
+// parseMapCSVFile opens and parses one exported CSV file
+func parseMapCSVFile(path string) (*MapCSV, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
+	return ParseMapCSV(file)
+}
+
+// checkGrid verifies a parsed grid matches the map dimensions
+func checkGrid(grid [][]string, cfg models.MapConfig) error {
+	if len(grid) != cfg.Rows {
+		return fmt.Errorf("expected %d rows, found %d", cfg.Rows, len(grid))
+	}
+	for i, row := range grid {
+		if len(row) != cfg.Cols {
+			return fmt.Errorf("row %d: expected %d columns, found %d", i, cfg.Cols, len(row))
+		}
+	}
+	return nil
+}
+
+// ImportMapFromCSV imports maps from a CSV file, a comma-separated list
+// of files, or every .csv file in a directory. Every cell is classified
+// first and the report printed, or written to stdout as JSON when jsonOut
+// is set. If anything was clamped or rejected, policy decides whether the
+// accepted subset is written. All maps are applied in one editor session.
+func ImportMapFromCSV(prompt editor.Prompter, ecuFilename, csvPath string, policy editor.FailurePolicy, dryRun, jsonOut bool) {
+	csvFiles, err := ImportFiles(csvPath)
+	if err != nil {
+		pterm.Error.Printf("Failed to list CSV files: %v\n", err)
+		return
+	}
+	if len(csvFiles) == 0 {
+		pterm.Error.Printf("No CSV files found in %s\n", csvPath)
+		return
+	}
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	data, err := os.ReadFile(ecuFilename)
 	if err != nil {
-		pterm.Error.Printf("Failed to read CSV file: %v\n", err)
+		pterm.Error.Printf("Failed to read ECU file: %v\n", err)
 		return
 	}
 
-	// Parse CSV and find data start
-	dataStart := 0
-	for i, record := range records {
-		if len(record) > 0 && strings.HasPrefix(record[0], "Load\\RPM") {
-			dataStart = i + 1
-			break
+	pterm.Info.Printf("Importing %d CSV file(s) into %s\n", len(csvFiles), ecuFilename)
+	bar := progress.Start("Checking CSV files", len(csvFiles))
+	report := planImportFiles(data, csvFiles, bar.Step)
+	bar.Stop()
+	if jsonOut {
+		defer report.WriteJSON(os.Stdout)
+	} else {
+		report.Print()
+	}
+
+	if !report.Complete() {
+		switch policy {
+		case editor.PolicyAbort:
+			pterm.Error.Println(i18n.T("cli.import.aborted"))
+			return
+		case editor.PolicyAsk:
+			if !prompt.Confirm(i18n.T("cli.import.ask")) {
+				pterm.Info.Println(i18n.T("cli.cancelled"))
+				return
+			}
 		}
 	}
 
-	if dataStart == 0 {
-		pterm.Error.Println("Invalid CSV format: couldn't find data header")
+	changes := report.AcceptedChanges()
+	pterm.Info.Print(i18n.T("cli.would_change", len(changes)))
+	if dryRun {
+		pterm.Warning.Println(i18n.T("cli.dry_run"))
+		return
+	}
+	if len(changes) == 0 {
+		pterm.Info.Println(i18n.T("cli.no_changes"))
 		return
 	}
 
-	// TODO: Implement full CSV import with map identification
-	pterm.Warning.Println("CSV import is under development")
+	result, err := ApplyImport(ecuFilename, report, func(*editor.Report) bool {
+		return editor.Confirm(prompt, editor.ConfirmSave, i18n.T("cli.confirm.changes"))
+	})
+	if err != nil {
+		pterm.Error.Printf("Import failed: %s\n", reader.DescribeWriteError(err))
+	}
+	if result != nil {
+		result.PrintSummary()
+	}
+}
+
+// ApplyImport writes the accepted subset of a planned import in one editor
+// session, one operation per imported file, and records the outcome in the
+// report. confirm may be nil.
+func ApplyImport(ecuFilename string, report *editor.ImportReport, confirm func(*editor.Report) bool) (*editor.Report, error) {
+	session, err := editor.NewSession(ecuFilename)
+	if err != nil {
+		return nil, err
+	}
+	session.Confirm = confirm
+	for _, op := range ImportOperations(report) {
+		session.Add(op)
+	}
+
+	result, err := session.Commit()
+	report.Backup, report.Written = result.Backup, result.Written
+	return result, err
+}
+
+// ImportOperations returns the session operations writing the accepted
+// subset of a planned import, one per imported file
+func ImportOperations(report *editor.ImportReport) []editor.Operation {
+	var ops []editor.Operation
+	for _, op := range report.Operations {
+		if op.Err != "" || len(op.Changes) == 0 {
+			continue
+		}
+		op := op
+		ops = append(ops, editor.Operation{
+			Name: op.Name,
+			Plan: func([]byte) ([]editor.CellChange, error) { return op.Changes, nil },
+		})
+	}
+	return ops
+}
+
+// ImportFiles expands an -import argument into CSV file paths
+func ImportFiles(csvPath string) ([]string, error) {
+	var files []string
+	for _, p := range strings.Split(csvPath, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(p, "*.csv"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
 }
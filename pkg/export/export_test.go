@@ -0,0 +1,161 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// A lossless export of every built-in map, imported into an image whose
+// map bytes were all changed, restores the original byte for byte. A map
+// too fine for the two decimals of the values is added, so the raw grid
+// is what makes it exact.
+func TestLosslessRoundTrip(t *testing.T) {
+	saved := models.MapConfigs
+	t.Cleanup(func() { models.MapConfigs = saved })
+	fine := models.MapConfig{Name: "Fine Trim", Offset: 0x5000, Rows: 2, Cols: 8, DataType: "uint16", Scale: 0.0001, Unit: "%"}
+	models.MapConfigs = append(append([]models.MapConfig(nil), saved...), fine)
+
+	dir := t.TempDir()
+	original := testbin.Image()
+	for i := range fine.ByteSize() {
+		original[fine.Offset+i] = byte(i * 37)
+	}
+	ecu := filepath.Join(dir, "ecu.bin")
+	if err := os.WriteFile(ecu, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+	csvDir := filepath.Join(dir, "csv")
+	ExportMapsToCSV(ecu, csvDir, "all", Options{Lossless: true}, reader.ReadMap)
+
+	files, err := ImportFiles(csvDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(models.MapConfigs) {
+		t.Fatalf("exported %d files, want one per map (%d)", len(files), len(models.MapConfigs))
+	}
+
+	changed := bytes.Clone(original)
+	for _, cfg := range models.MapConfigs {
+		for i := cfg.Offset; i < cfg.Offset+cfg.ByteSize(); i++ {
+			changed[i] ^= 0x01
+		}
+	}
+	report := PlanImportFiles(changed, files)
+	for _, op := range report.Operations {
+		if op.Err != "" {
+			t.Errorf("%s: %s", op.Name, op.Err)
+		}
+		for _, c := range op.Changes {
+			c.Apply(changed)
+		}
+	}
+	if !report.Complete() {
+		t.Error("the import clamped or rejected cells")
+	}
+	if i := firstDifference(changed, original); i >= 0 {
+		t.Errorf("reimported image differs from the original at 0x%04X: 0x%02X, want 0x%02X", i, changed[i], original[i])
+	}
+}
+
+// firstDifference returns the first offset where a and b differ, or -1
+func firstDifference(a, b []byte) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return min(len(a), len(b))
+	}
+	return -1
+}
+
+// The exported CSV labels its rows with the load axis the other views
+// use, high load last, or the fixed labels of its row axis (see
+// models.TestLoadAxisGolden), under the map's GridHeader
+func TestExportLoadAxis(t *testing.T) {
+	dir := t.TempDir()
+	ecu := filepath.Join(dir, "ecu.bin")
+	if err := os.WriteFile(ecu, testbin.Image(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ExportMapsToCSV(ecu, dir, "all", Options{}, reader.ReadMap)
+
+	for _, cfg := range models.MapConfigs {
+		f, err := os.Open(filepath.Join(dir, CSVFileName(cfg)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cr := csv.NewReader(f)
+		cr.FieldsPerRecord = -1
+		records, err := cr.ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var labels []string
+		for i, rec := range records {
+			if rec[0] == cfg.GridHeader() {
+				for _, row := range records[i+1 : i+1+cfg.Rows] {
+					labels = append(labels, row[0])
+				}
+				break
+			}
+		}
+		if want := cfg.LoadLabels(); !slices.Equal(labels, want) {
+			t.Errorf("%s rows are labeled %v, want %v", cfg.Name, labels, want)
+		}
+	}
+}
+
+// The cold start map exports under a Temp\RPM header with its temperature
+// row labels, and the file reads back with both intact and imports
+// without changes
+func TestExportColdStartRoundTrip(t *testing.T) {
+	cfg, ok := models.MapByRole(models.RoleColdStart)
+	if !ok {
+		t.Fatal("no built-in cold start map")
+	}
+	dir := t.TempDir()
+	original := testbin.Image()
+	ecu := filepath.Join(dir, "ecu.bin")
+	if err := os.WriteFile(ecu, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ExportMapsToCSV(ecu, dir, "all", Options{Lossless: true}, reader.ReadMap)
+
+	f, err := os.Open(filepath.Join(dir, CSVFileName(cfg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := ParseMapCSV(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Header != "Temp\\RPM" {
+		t.Errorf("header %q, want Temp\\RPM", m.Header)
+	}
+	if want := []string{"-30", "-15", "0", "15", "30", "50", "70", "90"}; !slices.Equal(m.RowLabels, want) {
+		t.Errorf("rows are labeled %v, want %v", m.RowLabels, want)
+	}
+	if len(m.Raw) != cfg.Rows {
+		t.Errorf("%d raw rows, want %d", len(m.Raw), cfg.Rows)
+	}
+
+	op := &editor.ImportOperation{}
+	PlanImport(original, op, m)
+	if op.Err != "" || op.Map != cfg.Name || op.Accepted != cfg.Rows*cfg.Cols || len(op.Changes) != 0 {
+		t.Errorf("reimport into %s accepted %d cells with %d changes: %q", op.Map, op.Accepted, len(op.Changes), op.Err)
+	}
+}
@@ -0,0 +1,149 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// Symbol labels one map or parameter for a disassembler
+type Symbol struct {
+	// Label is Name made into an identifier, e.g. Main_Fuel_Map
+	Label   string
+	Name    string
+	Address int64
+	Length  int64
+	// Comment gives the layout: rows, columns and cell size for maps,
+	// data type and unit for parameters
+	Comment string
+}
+
+// Symbols returns a symbol for every map, map axis and parameter of the
+// active definitions, at their offsets plus base, in address order
+func Symbols(base int64) []Symbol {
+	var symbols []Symbol
+	for _, cfg := range models.MapConfigs {
+		size := models.DataTypeSize(cfg.DataType)
+		symbols = append(symbols, Symbol{
+			Label:   SymbolLabel(cfg.Name),
+			Name:    cfg.Name,
+			Address: base + cfg.Offset,
+			Length:  cfg.ByteSize(),
+			Comment: fmt.Sprintf("%dx%d %s cells, %d byte(s) each, %s", cfg.Rows, cfg.Cols, cfg.DataType, size, cfg.Unit),
+		})
+		for _, axis := range []struct {
+			name string
+			cfg  *models.AxisConfig
+		}{{"X axis", cfg.XAxis}, {"Y axis", cfg.YAxis}} {
+			if axis.cfg == nil || axis.cfg.Labels != nil {
+				continue
+			}
+			name := cfg.Name + " " + axis.name
+			symbols = append(symbols, Symbol{
+				Label:   SymbolLabel(name),
+				Name:    name,
+				Address: base + axis.cfg.Offset,
+				Length:  axis.cfg.ByteSize(),
+				Comment: fmt.Sprintf("%d %s breakpoints, %s", axis.cfg.Count, axis.cfg.DataType, axis.cfg.Unit),
+			})
+		}
+	}
+	for _, p := range models.ConfigParams {
+		symbols = append(symbols, Symbol{
+			Label:   SymbolLabel(p.Name),
+			Name:    p.Name,
+			Address: base + p.Offset,
+			Length:  int64(models.DataTypeSize(p.DataType)),
+			Comment: fmt.Sprintf("%s parameter, %s", p.DataType, p.Unit),
+		})
+	}
+	sort.SliceStable(symbols, func(i, j int) bool { return symbols[i].Address < symbols[j].Address })
+	return symbols
+}
+
+// SymbolLabel turns a definition name into an identifier disassemblers
+// accept: letters, digits and underscores, not starting with a digit
+func SymbolLabel(name string) string {
+	label := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, name)
+	label = strings.Trim(label, "_")
+	for strings.Contains(label, "__") {
+		label = strings.ReplaceAll(label, "__", "_")
+	}
+	if label == "" || unicode.IsDigit(rune(label[0])) {
+		label = "_" + label
+	}
+	return label
+}
+
+// WriteSymbols writes one "Label = 0xADDR" line per symbol, each preceded
+// by a ";" comment with its name, length and layout
+func WriteSymbols(w io.Writer, symbols []Symbol, header []string) error {
+	for _, line := range header {
+		if _, err := fmt.Fprintf(w, "; %s\n", line); err != nil {
+			return err
+		}
+	}
+	for _, s := range symbols {
+		if _, err := fmt.Fprintf(w, "\n; %s: %d bytes, %s\n%s = 0x%04X\n", s.Name, s.Length, s.Comment, s.Label, s.Address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSymbolsCSV writes the symbols as CSV with Name, Address, Length,
+// Type and Comment columns for Ghidra's CSV label importers. Type is
+// always "data".
+func WriteSymbolsCSV(w io.Writer, symbols []Symbol) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Name", "Address", "Length", "Type", "Comment"})
+	for _, s := range symbols {
+		cw.Write([]string{s.Label, fmt.Sprintf("0x%04X", s.Address), strconv.FormatInt(s.Length, 10), "data", s.Name + ": " + s.Comment})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportSymbols writes the symbols of filename to outPath, as CSV if it
+// ends in .csv and in the .sym format otherwise. Addresses include the
+// base offset detected by identification, so they match the file as
+// loaded into a disassembler. It returns the number of symbols written.
+func ExportSymbols(filename, outPath string) (int, error) {
+	id, err := reader.IdentifyBinary(filename)
+	if err != nil {
+		return 0, err
+	}
+	// The active definitions may have been moved to the image already
+	symbols := Symbols(id.BaseOffset - models.BaseOffset)
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return 0, err
+	}
+	if strings.EqualFold(filepath.Ext(outPath), ".csv") {
+		err = WriteSymbolsCSV(f, symbols)
+	} else {
+		err = WriteSymbols(f, symbols, []string{
+			fmt.Sprintf("%s calibration symbols for %s", models.M21IDProfile.Name, filepath.Base(filename)),
+			fmt.Sprintf("Base offset 0x%04X, definitions %s", id.BaseOffset, models.DefinitionsFingerprint()),
+		})
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return len(symbols), err
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestSymbols compares the symbols of the built-in definitions at base
+// offset 0 with the golden files
+func TestSymbols(t *testing.T) {
+	symbols := Symbols(0)
+	tests := []struct {
+		golden string
+		write  func(*bytes.Buffer) error
+	}{
+		{"symbols.sym", func(b *bytes.Buffer) error {
+			return WriteSymbols(b, symbols, []string{"Motronic M2.1 calibration symbols"})
+		}},
+		{"symbols.csv", func(b *bytes.Buffer) error { return WriteSymbolsCSV(b, symbols) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s differs from the golden file; run go test -update if the definitions changed on purpose\ngot:\n%s", tt.golden, buf.String())
+			}
+		})
+	}
+}
+
+// The base offset moves every address and nothing else
+func TestSymbolsBaseOffset(t *testing.T) {
+	at0, at8000 := Symbols(0), Symbols(0x8000)
+	for i := range at0 {
+		moved := at0[i]
+		moved.Address += 0x8000
+		if at8000[i] != moved {
+			t.Errorf("symbol %d at base 0x8000 is %+v, want %+v", i, at8000[i], moved)
+		}
+	}
+}
+
+func TestSymbolLabel(t *testing.T) {
+	tests := map[string]string{
+		"Main Fuel Map":          "Main_Fuel_Map",
+		"Warm-up (λ) enrichment": "Warm_up_enrichment",
+		"2nd Ignition Map":       "_2nd_Ignition_Map",
+		"---":                    "_",
+	}
+	for name, want := range tests {
+		if got := SymbolLabel(name); got != want {
+			t.Errorf("SymbolLabel(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// WinOLSEntry is one line of a WinOLS map list with the map definition
+// it describes, or the reason it couldn't be read
+type WinOLSEntry struct {
+	Line   int
+	Config models.MapConfig
+	Err    error
+}
+
+// winOLSColumns are the columns of a WinOLS map list, in the order of a
+// list exported without a header row
+var winOLSColumns = []string{"name", "address", "rows", "cols", "factor", "offset", "type", "unit"}
+
+// winOLSHeaders maps header names of English and German WinOLS exports,
+// lowercased with everything but letters removed, to winOLSColumns
+var winOLSHeaders = map[string]string{
+	"name": "name", "map": "name", "mapname": "name", "bezeichnung": "name",
+	"address": "address", "addr": "address", "adresse": "address", "start": "address", "startaddress": "address", "startadresse": "address",
+	"rows": "rows", "zeilen": "rows", "rowsy": "rows", "y": "rows",
+	"columns": "cols", "cols": "cols", "spalten": "cols", "columnsx": "cols", "x": "cols",
+	"factor": "factor", "faktor": "factor", "scale": "factor",
+	"datatype": "type", "type": "type", "datenorganisation": "type", "organization": "type", "dataorganization": "type",
+	"offset": "offset", "unit": "unit", "einheit": "unit",
+}
+
+// ParseWinOLSList reads a map list exported from WinOLS: one map per line
+// with name, address, rows, columns, factor and offset, optionally
+// followed by data type and unit. The delimiter (tab, semicolon or comma)
+// is taken from the first line, which may be a header naming the columns
+// in any order. Addresses are hex, as WinOLS shows them, and delta is
+// added to each to move it to this tool's file offsets. Lines that can't
+// be read are returned with Err set; the error return is for reading r.
+func ParseWinOLSList(r io.Reader, delta int64) ([]WinOLSEntry, error) {
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body := strings.TrimPrefix(string(text), "\ufeff")
+
+	cr := csv.NewReader(strings.NewReader(body))
+	cr.Comma = winOLSDelimiter(body)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.Comment = '#'
+	// A tab counts as leading space, so trimming would shift the fields
+	// after an empty one in tab-separated lists
+	cr.TrimLeadingSpace = cr.Comma != '\t'
+
+	var entries []WinOLSEntry
+	columns := winOLSColumns
+	first := true
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			entries = append(entries, WinOLSEntry{Line: line, Err: err})
+			continue
+		}
+		if first {
+			first = false
+			if header, ok := winOLSHeader(record); ok {
+				columns = header
+				continue
+			}
+		}
+		fields := make(map[string]string)
+		for i, value := range record {
+			if i < len(columns) && columns[i] != "" {
+				fields[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+		cfg, err := winOLSConfig(fields, cr.Comma, delta)
+		entries = append(entries, WinOLSEntry{Line: line, Config: cfg, Err: err})
+	}
+	return entries, nil
+}
+
+// winOLSDelimiter picks the delimiter of the first non-empty line: tab,
+// else semicolon (German exports, whose factors use a decimal comma),
+// else comma
+func winOLSDelimiter(body string) rune {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		switch {
+		case strings.Contains(line, "\t"):
+			return '\t'
+		case strings.Contains(line, ";"):
+			return ';'
+		}
+		break
+	}
+	return ','
+}
+
+// winOLSHeader returns the columns named by a header record. A record is
+// a header if it names both the name and the address column.
+func winOLSHeader(record []string) ([]string, bool) {
+	columns := make([]string, len(record))
+	found := make(map[string]bool)
+	for i, field := range record {
+		key := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' {
+				return r
+			}
+			return -1
+		}, strings.ToLower(field))
+		columns[i] = winOLSHeaders[key]
+		found[columns[i]] = true
+	}
+	return columns, found["name"] && found["address"]
+}
+
+// winOLSConfig builds the map definition of one line's fields
+func winOLSConfig(fields map[string]string, comma rune, delta int64) (models.MapConfig, error) {
+	cfg := models.MapConfig{Name: fields["name"], Unit: fields["unit"], Scale: 1}
+	if cfg.Name == "" {
+		return cfg, errors.New("no map name")
+	}
+
+	address, err := parseWinOLSAddress(fields["address"])
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Offset = address + delta
+	if cfg.Offset < 0 {
+		return cfg, fmt.Errorf("address 0x%X with delta %d is before the start of the file", address, delta)
+	}
+
+	if cfg.Rows, err = parseWinOLSSize("rows", fields["rows"]); err != nil {
+		return cfg, err
+	}
+	if cfg.Cols, err = parseWinOLSSize("columns", fields["cols"]); err != nil {
+		return cfg, err
+	}
+	if s := fields["factor"]; s != "" {
+		if cfg.Scale, err = parseWinOLSNumber(s, comma); err != nil {
+			return cfg, fmt.Errorf("invalid factor %q", s)
+		}
+	}
+	if s := fields["offset"]; s != "" {
+		if cfg.Offset2, err = parseWinOLSNumber(s, comma); err != nil {
+			return cfg, fmt.Errorf("invalid offset %q", s)
+		}
+	}
+	if cfg.DataType, cfg.Endianness, err = parseWinOLSType(fields["type"]); err != nil {
+		return cfg, err
+	}
+	if err := models.CheckScale(cfg.Scale); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// parseWinOLSAddress parses a hex address with an optional 0x or $ prefix
+// or h suffix
+func parseWinOLSAddress(s string) (int64, error) {
+	hex := strings.ToLower(strings.TrimSpace(s))
+	hex = strings.TrimPrefix(strings.TrimPrefix(hex, "0x"), "$")
+	hex = strings.TrimSuffix(hex, "h")
+	n, err := strconv.ParseInt(hex, 16, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return n, nil
+}
+
+// parseWinOLSSize parses a row or column count; empty means 1, as WinOLS
+// leaves the rows of a curve blank
+func parseWinOLSSize(what, s string) (int, error) {
+	if s == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q", what, s)
+	}
+	return n, nil
+}
+
+// parseWinOLSNumber parses a factor or offset. Lists not separated by
+// commas may use a decimal comma.
+func parseWinOLSNumber(s string, comma rune) (float64, error) {
+	if comma != ',' {
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseWinOLSType maps a WinOLS data organization such as "8 Bit",
+// "16 Bit (HiLo)" or "16 Bit signed (LoHi)", or a data type of this tool,
+// to a data type and byte order. Empty is 8 bit unsigned.
+func parseWinOLSType(s string) (string, models.Endianness, error) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	if t == "" {
+		return "uint8", "", nil
+	}
+	if models.KnownDataType(t) {
+		return t, "", nil
+	}
+
+	var dataType string
+	switch {
+	case strings.Contains(t, "32") || strings.Contains(t, "float"):
+		return "", "", fmt.Errorf("unsupported data type %q: only 8 and 16 bit maps are supported", s)
+	case strings.Contains(t, "16") || strings.Contains(t, "word"):
+		dataType = "uint16"
+	case strings.Contains(t, "8") || strings.Contains(t, "byte"):
+		dataType = "uint8"
+	default:
+		return "", "", fmt.Errorf("unknown data type %q", s)
+	}
+	signed := (strings.Contains(t, "signed") && !strings.Contains(t, "unsigned")) ||
+		(strings.Contains(t, "vorzeichen") && !strings.Contains(t, "ohne"))
+	if signed {
+		dataType = strings.TrimPrefix(dataType, "u")
+	}
+
+	var order models.Endianness
+	switch {
+	case strings.Contains(t, "hilo") || strings.Contains(t, "big") || strings.Contains(t, "motorola"):
+		order = models.BigEndian
+	case strings.Contains(t, "lohi") || strings.Contains(t, "little") || strings.Contains(t, "intel"):
+		order = models.LittleEndian
+	}
+	return dataType, order, nil
+}
+
+// ImportWinOLSList previews the maps of a WinOLS map list against the
+// active definitions and a file the size of ecuFilename, with an error or
+// warning per line, and adds the valid ones to the user definitions.
+// Nothing is saved with dryRun. It returns false if the list or file
+// can't be read or a map can't be saved.
+func ImportWinOLSList(prompt editor.Prompter, ecuFilename, listPath string, delta int64, dryRun bool) bool {
+	info, err := os.Stat(ecuFilename)
+	if err != nil {
+		pterm.Error.Printf("Failed to read ECU file: %v\n", err)
+		return false
+	}
+	f, err := os.Open(listPath)
+	if err != nil {
+		pterm.Error.Printf("Failed to read map list: %v\n", err)
+		return false
+	}
+	entries, err := ParseWinOLSList(f, delta)
+	f.Close()
+	if err != nil {
+		pterm.Error.Printf("Failed to read map list: %v\n", err)
+		return false
+	}
+
+	// Later lines are checked against the maps of earlier ones, so a list
+	// can't import the same map twice
+	maps := models.MapConfigs
+	var accepted []models.MapConfig
+	tableData := pterm.TableData{{"Line", "Name", "Offset", "Size", "Type", "Factor", "Value offset", "Status"}}
+	overlapping := 0
+	for _, e := range entries {
+		status := "ok"
+		var check models.DefinitionCheck
+		if e.Err == nil {
+			e.Config.Description = "Imported from " + filepath.Base(listPath)
+			check = models.CheckNewMap(e.Config, maps, models.ConfigParams, info.Size())
+			e.Err = errors.Join(check.Errors...)
+			for _, o := range check.Overlaps {
+				if o.Exact && e.Err == nil {
+					e.Err = errors.New(o.String())
+				}
+				status = "overlaps " + o.B.Name
+			}
+		}
+		if e.Err != nil {
+			pterm.Warning.Printf("Line %d: %v\n", e.Line, e.Err)
+			tableData = append(tableData, []string{strconv.Itoa(e.Line), e.Config.Name, "", "", "", "", "", pterm.Red("error")})
+			continue
+		}
+
+		// Confirming the import confirms its partial overlaps, which are
+		// recorded with the map like a promoted scan hit's
+		cfg := e.Config
+		if len(check.Overlaps) > 0 {
+			cfg.OverlapNote = models.OverlapNote(check.Overlaps)
+			overlapping++
+		}
+		accepted = append(accepted, cfg)
+		maps = append(maps[:len(maps):len(maps)], cfg)
+		dataType := cfg.DataType
+		if cfg.Endianness != "" {
+			dataType += " " + string(cfg.Endianness)
+		}
+		tableData = append(tableData, []string{
+			strconv.Itoa(e.Line), cfg.Name, fmt.Sprintf("0x%04X", cfg.Offset),
+			fmt.Sprintf("%dx%d", cfg.Rows, cfg.Cols), dataType,
+			fmt.Sprintf("%g", cfg.Scale), fmt.Sprintf("%g", cfg.Offset2), status,
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	pterm.Info.Printf("%d of %d map(s) can be imported\n", len(accepted), len(entries))
+
+	if dryRun {
+		pterm.Warning.Println(i18n.T("cli.dry_run"))
+		return true
+	}
+	if len(accepted) == 0 {
+		return true
+	}
+	question := fmt.Sprintf("Add %d map(s) to your map definitions?", len(accepted))
+	if overlapping > 0 {
+		question = fmt.Sprintf("Add %d map(s), %d of them overlapping existing definitions, to your map definitions?", len(accepted), overlapping)
+	}
+	if !editor.Confirm(prompt, editor.ConfirmSave, question) {
+		pterm.Info.Println(i18n.T("cli.cancelled"))
+		return true
+	}
+
+	for _, cfg := range accepted {
+		if _, err := editor.AddUserMap(cfg, info.Size()); err != nil {
+			pterm.Error.Printf("Failed to add %s: %v\n", cfg.Name, err)
+			return false
+		}
+	}
+	pterm.Success.Printf("Added %d map(s) to %s\n", len(accepted), editor.UserMapsFile)
+	return true
+}
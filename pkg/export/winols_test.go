@@ -0,0 +1,150 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// winOLSWant is what one line of a sample list should parse to; err is a
+// substring of the line's error, or empty for a valid line
+type winOLSWant struct {
+	line       int
+	name       string
+	offset     int64
+	rows, cols int
+	dataType   string
+	order      models.Endianness
+	scale      float64
+	offset2    float64
+	unit       string
+	err        string
+}
+
+func TestParseWinOLSList(t *testing.T) {
+	tests := []struct {
+		file  string
+		delta int64
+		want  []winOLSWant
+	}{
+		{"winols_en.csv", -0x10000, []winOLSWant{
+			{line: 2, name: "Boost Target", offset: 0x5000, rows: 8, cols: 8, dataType: "uint8", scale: 0.01, unit: "bar"},
+			{line: 3, name: "Idle Speed", offset: 0x5100, rows: 1, cols: 8, dataType: "uint16", order: models.BigEndian, scale: 10, unit: "RPM"},
+			{line: 4, name: "Knock Retard", offset: 0x5200, rows: 4, cols: 4, dataType: "int8", scale: -0.75, unit: "deg"},
+			{line: 5, err: `invalid address "zz"`},
+			{line: 6, err: "unsupported data type"},
+			{line: 7, name: "Fuel, cold start", offset: 0x5400, rows: 2, cols: 4, dataType: "uint8", scale: 0.1, unit: "ms"},
+		}},
+		{"winols_de.txt", 0, []winOLSWant{
+			{line: 3, name: "Zündwinkel Leerlauf", offset: 0x5400, rows: 8, cols: 8, dataType: "uint8", scale: 0.75, offset2: -24, unit: "°KW"},
+			{line: 4, name: "Kennlinie Drossel", offset: 0x5500, rows: 1, cols: 16, dataType: "uint16", order: models.LittleEndian, scale: 0.5, unit: "%"},
+			{line: 5, name: "Lambda Korrektur", offset: 0x5600, rows: 4, cols: 4, dataType: "int16", order: models.BigEndian, scale: 1.5},
+			{line: 6, err: `invalid rows "0"`},
+		}},
+		{"winols_noheader.tsv", 0, []winOLSWant{
+			{line: 1, name: "Fuel Cutoff", offset: 0x5800, rows: 1, cols: 1, dataType: "uint8", scale: 1},
+			{line: 2, name: "Startup Enrichment", offset: 0x5810, rows: 2, cols: 8, dataType: "uint8", scale: 0.1, unit: "ms"},
+			{line: 3, err: "invalid address"},
+			{line: 4, err: "no map name"},
+			{line: 5, err: "scale 0 is invalid"},
+			{line: 6, name: "Main Fuel Copy", offset: 0x6700, rows: 8, cols: 16, dataType: "uint8", scale: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			entries, err := ParseWinOLSList(f, tt.delta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("%d entries, want %d: %+v", len(entries), len(tt.want), entries)
+			}
+			for i, want := range tt.want {
+				checkWinOLSEntry(t, entries[i], want)
+			}
+		})
+	}
+}
+
+func checkWinOLSEntry(t *testing.T, e WinOLSEntry, want winOLSWant) {
+	t.Helper()
+	if e.Line != want.line {
+		t.Errorf("entry on line %d, want line %d", e.Line, want.line)
+	}
+	if want.err != "" {
+		if e.Err == nil || !strings.Contains(e.Err.Error(), want.err) {
+			t.Errorf("line %d: error %v, want one containing %q", e.Line, e.Err, want.err)
+		}
+		return
+	}
+	if e.Err != nil {
+		t.Errorf("line %d: %v", e.Line, e.Err)
+		return
+	}
+	c := e.Config
+	got := winOLSWant{line: e.Line, name: c.Name, offset: c.Offset, rows: c.Rows, cols: c.Cols, dataType: c.DataType,
+		order: c.Endianness, scale: c.Scale, offset2: c.Offset2, unit: c.Unit}
+	if got != want {
+		t.Errorf("line %d:\n got %+v\nwant %+v", e.Line, got, want)
+	}
+}
+
+func TestParseWinOLSListDelta(t *testing.T) {
+	list := "Low,0x100,1,1\nHigh,0x8100,1,1\n"
+	entries, err := ParseWinOLSList(strings.NewReader(list), -0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2", len(entries))
+	}
+	if entries[0].Err == nil || !strings.Contains(entries[0].Err.Error(), "before the start of the file") {
+		t.Errorf("a delta moving a map before the file gave %v", entries[0].Err)
+	}
+	if entries[1].Err != nil || entries[1].Config.Offset != 0x7F00 {
+		t.Errorf("0x8100 with delta -0x200 read as 0x%X, %v, want 0x7F00", entries[1].Config.Offset, entries[1].Err)
+	}
+}
+
+func TestImportWinOLSList(t *testing.T) {
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	ecu := filepath.Join(t.TempDir(), "ecu.bin")
+	if err := os.WriteFile(ecu, testbin.Image(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list := filepath.Join("testdata", "winols_noheader.tsv")
+
+	if !ImportWinOLSList(&editor.ScriptedPrompter{}, ecu, list, 0, true) {
+		t.Fatal("dry run failed")
+	}
+	if maps, _ := editor.LoadUserMaps(); len(maps) != 0 {
+		t.Fatalf("a dry run saved %d map(s)", len(maps))
+	}
+
+	if !ImportWinOLSList(&editor.ScriptedPrompter{Answers: []string{"y"}}, ecu, list, 0, false) {
+		t.Fatal("import failed")
+	}
+	maps, err := editor.LoadUserMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The lines with errors and the exact copy of the main fuel map are
+	// left out
+	var names []string
+	for _, m := range maps {
+		names = append(names, m.Config().Name)
+	}
+	if want := "Fuel Cutoff, Startup Enrichment"; strings.Join(names, ", ") != want {
+		t.Errorf("imported %v, want %s", names, want)
+	}
+}
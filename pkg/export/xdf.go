@@ -0,0 +1,779 @@
+package export
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// XDF holds the definitions read from a TunerPro XDF file
+type XDF struct {
+	Maps   []models.MapConfig
+	Params []models.ConfigParam
+	// BaseOffset is the BASEOFFSET added to every address, negative for
+	// one subtracted
+	BaseOffset int64
+	// Unsupported names the tables and constants left out because their
+	// equation is neither linear in X nor a formula (see models.Formula)
+	Unsupported []string
+	// AxesDropped names the tables kept without an axis whose equation,
+	// link or layout couldn't be used
+	AxesDropped []string
+	// Skipped says which other entries were left out and why
+	Skipped []error
+	// Builtin names the built-in maps ApplyXDF kept at their positions
+	// because no table of the file sits at their offset with their size
+	Builtin []string
+}
+
+// xdfFormat is the part of an XDF document the import reads
+type xdfFormat struct {
+	XMLName xml.Name `xml:"XDFFORMAT"`
+	Header  struct {
+		BaseOffset struct {
+			Offset   string `xml:"offset,attr"`
+			Subtract string `xml:"subtract,attr"`
+		} `xml:"BASEOFFSET"`
+		Defaults struct {
+			DataSize string `xml:"datasizeinbits,attr"`
+		} `xml:"DEFAULTS"`
+		Categories []struct {
+			Index string `xml:"index,attr"`
+			Name  string `xml:"name,attr"`
+		} `xml:"CATEGORY"`
+	} `xml:"XDFHEADER"`
+	Tables    []xdfTable    `xml:"XDFTABLE"`
+	Constants []xdfConstant `xml:"XDFCONSTANT"`
+}
+
+type xdfTable struct {
+	UniqueID    string `xml:"uniqueid,attr"`
+	Comment     string `xml:",comment"`
+	Title       string `xml:"title"`
+	Description string `xml:"description"`
+	Categories  []struct {
+		Category string `xml:"category,attr"`
+	} `xml:"CATEGORYMEM"`
+	Axes []xdfAxis `xml:"XDFAXIS"`
+}
+
+type xdfAxis struct {
+	ID         string    `xml:"id,attr"`
+	Data       *xdfData  `xml:"EMBEDDEDDATA"`
+	Units      string    `xml:"units"`
+	IndexCount string    `xml:"indexcount"`
+	Math       []xdfMath `xml:"MATH"`
+	EmbedInfo  *struct {
+		Type      string `xml:"type,attr"`
+		LinkObjID string `xml:"linkobjid,attr"`
+	} `xml:"embedinfo"`
+}
+
+type xdfConstant struct {
+	UniqueID    string    `xml:"uniqueid,attr"`
+	Comment     string    `xml:",comment"`
+	Title       string    `xml:"title"`
+	Description string    `xml:"description"`
+	Data        *xdfData  `xml:"EMBEDDEDDATA"`
+	Units       string    `xml:"units"`
+	Math        []xdfMath `xml:"MATH"`
+	RangeLow    string    `xml:"rangelow"`
+	RangeHigh   string    `xml:"rangehigh"`
+}
+
+// xdfData is an EMBEDDEDDATA element: where and how values are stored
+type xdfData struct {
+	TypeFlags   string `xml:"mmedtypeflags,attr"`
+	Address     string `xml:"mmedaddress,attr"`
+	ElementBits string `xml:"mmedelementsizebits,attr"`
+	Rows        string `xml:"mmedrowcount,attr"`
+	Cols        string `xml:"mmedcolcount,attr"`
+	MajorStride string `xml:"mmedmajorstridebits,attr"`
+	MinorStride string `xml:"mmedminorstridebits,attr"`
+}
+
+// xdfMath is a MATH element. Row and Col are set on per-cell overrides.
+type xdfMath struct {
+	Equation string `xml:"equation,attr"`
+	Row      string `xml:"row,attr"`
+	Col      string `xml:"col,attr"`
+	Vars     []struct {
+		ID string `xml:"id,attr"`
+	} `xml:"VAR"`
+}
+
+// EMBEDDEDDATA type flags
+const (
+	xdfSigned      = 0x01
+	xdfLSBFirst    = 0x02
+	xdfColumnMajor = 0x04
+	xdfFloat       = 0x10000
+)
+
+// errUnsupportedEquation marks an equation that isn't linear in X
+var errUnsupportedEquation = errors.New("unsupported equation")
+
+// ApplyXDF replaces the active definitions with those of a TunerPro XDF
+// file. The first models.FixedMaps maps keep their meaning, since fuel,
+// ignition and lambda are addressed by position, and so do built-in maps
+// with a Role such as the cold start map: a table at the same offset with
+// the same size takes the built-in map's place and Role, otherwise the
+// built-in map stays. The constants replace the built-in
+// parameters unless there are none. Entries that can't be used are left
+// out and listed in the result; only an unreadable file is an error.
+func ApplyXDF(path string) (*XDF, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	x, err := ParseXDF(f, filepath.Base(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// A positive BASEOFFSET is the header or bank before the image the
+	// XDF was written for; a negative one maps CPU addresses to the image
+	models.UseBaseOffset(max(x.BaseOffset, 0))
+	tables := x.Maps
+	maps := make([]models.MapConfig, 0, len(tables)+models.FixedMaps)
+	for n, builtin := range models.MapConfigs {
+		if n >= models.FixedMaps && builtin.Role == "" {
+			continue
+		}
+		cfg := builtin
+		if i := sameTable(tables, builtin); i >= 0 {
+			cfg = tables[i]
+			cfg.Role = builtin.Role
+			tables = append(tables[:i:i], tables[i+1:]...)
+		} else {
+			x.Builtin = append(x.Builtin, builtin.Name)
+		}
+		cfg.Name = uniqueMapName(maps, cfg.Name)
+		maps = append(maps, cfg)
+	}
+	for _, cfg := range tables {
+		cfg.Name = uniqueMapName(maps, cfg.Name)
+		maps = append(maps, cfg)
+	}
+	x.Maps = maps
+
+	models.MapConfigs = maps
+	// A file without constants leaves the built-in parameters
+	if len(x.Params) > 0 {
+		models.ConfigParams = x.Params
+	}
+	return x, nil
+}
+
+// sameTable returns the index of the table covering exactly the cells of
+// cfg, -1 if there is none
+func sameTable(tables []models.MapConfig, cfg models.MapConfig) int {
+	for i, t := range tables {
+		if t.Offset == cfg.Offset && t.Rows == cfg.Rows && t.Cols == cfg.Cols {
+			return i
+		}
+	}
+	return -1
+}
+
+// uniqueMapName returns name, numbered if a map of maps already has it.
+// XDF files often repeat a title in different categories.
+func uniqueMapName(maps []models.MapConfig, name string) string {
+	taken := func(n string) bool {
+		for _, m := range maps {
+			if strings.EqualFold(m.Name, n) {
+				return true
+			}
+		}
+		return false
+	}
+	unique := name
+	for i := 2; taken(unique); i++ {
+		unique = fmt.Sprintf("%s (%d)", name, i)
+	}
+	return unique
+}
+
+// ParseXDF converts the XDFTABLE and XDFCONSTANT entries of an XDF
+// document into map and parameter definitions with source as their
+// Source. Tables and constants whose equation is not linear in X, or that
+// can't be represented otherwise, are left out and listed in the result.
+func ParseXDF(r io.Reader, source string) (*XDF, error) {
+	var doc xdfFormat
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	base, err := parseXDFNumber(doc.Header.BaseOffset.Offset, 0)
+	if err != nil {
+		return nil, fmt.Errorf("BASEOFFSET: %w", err)
+	}
+	if doc.Header.BaseOffset.Subtract == "1" {
+		base = -base
+	}
+	bits, err := parseXDFNumber(doc.Header.Defaults.DataSize, 8)
+	if err != nil {
+		return nil, fmt.Errorf("DEFAULTS: %w", err)
+	}
+	p := xdfParser{base: base, defaultBits: bits, tables: make(map[string]xdfTable), categories: make(map[int64]string)}
+	for _, c := range doc.Header.Categories {
+		if i, err := parseXDFNumber(c.Index, -1); err == nil && i >= 0 {
+			p.categories[i] = c.Name
+		}
+	}
+	for _, t := range doc.Tables {
+		if t.UniqueID != "" {
+			p.tables[strings.ToLower(t.UniqueID)] = t
+		}
+	}
+
+	x := &XDF{BaseOffset: base}
+	skip := func(kind, name string, err error) {
+		if errors.Is(err, errUnsupportedEquation) {
+			x.Unsupported = append(x.Unsupported, name)
+			return
+		}
+		x.Skipped = append(x.Skipped, fmt.Errorf("%s %q: %w", kind, name, err))
+	}
+
+	for _, t := range doc.Tables {
+		name := xdfTitle(t.Title, "Table", t.UniqueID)
+		cfg, dropped, err := p.table(t)
+		if err != nil {
+			skip("table", name, err)
+			continue
+		}
+		cfg.Name = uniqueMapName(x.Maps, name)
+		cfg.Source = source
+		check := models.CheckNewMap(cfg, x.Maps, nil, reader.MaxFileSize)
+		if err := errors.Join(check.Errors...); err != nil {
+			skip("table", name, err)
+			continue
+		}
+		if check.Duplicates() > 0 {
+			skip("table", name, errors.New(exactOverlap(check.Overlaps).String()))
+			continue
+		}
+		if dropped {
+			x.AxesDropped = append(x.AxesDropped, cfg.Name)
+		}
+		x.Maps = append(x.Maps, cfg)
+	}
+
+	for _, c := range doc.Constants {
+		name := xdfTitle(c.Title, "Constant", c.UniqueID)
+		param, err := p.constant(c)
+		if err != nil {
+			skip("constant", name, err)
+			continue
+		}
+		if _, taken := findParam(x.Params, name); taken {
+			skip("constant", name, errors.New("a constant of that name comes earlier"))
+			continue
+		}
+		param.Name = name
+		x.Params = append(x.Params, param)
+	}
+	return x, nil
+}
+
+// exactOverlap returns the first overlap covering the same bytes
+func exactOverlap(overlaps []models.Overlap) models.Overlap {
+	for _, o := range overlaps {
+		if o.Exact {
+			return o
+		}
+	}
+	return models.Overlap{}
+}
+
+// Warnings describes what the import left out or couldn't take from the
+// file, one line each
+func (x *XDF) Warnings() []string {
+	var lines []string
+	if len(x.Unsupported) > 0 {
+		lines = append(lines, fmt.Sprintf("Skipped %d entries with unsupported equations: %s", len(x.Unsupported), strings.Join(x.Unsupported, ", ")))
+	}
+	for _, err := range x.Skipped {
+		lines = append(lines, fmt.Sprintf("Skipped %v", err))
+	}
+	if len(x.AxesDropped) > 0 {
+		lines = append(lines, fmt.Sprintf("Kept without an axis that could not be used: %s", strings.Join(x.AxesDropped, ", ")))
+	}
+	if len(x.Builtin) > 0 {
+		lines = append(lines, fmt.Sprintf("Built-in maps kept, no table at their offset and size: %s", strings.Join(x.Builtin, ", ")))
+	}
+	return lines
+}
+
+// xdfTitle returns the title of an entry, or a name made of its kind and
+// unique ID if it has none
+func xdfTitle(title, kind, id string) string {
+	if title = strings.TrimSpace(title); title != "" {
+		return title
+	}
+	return fmt.Sprintf("%s %s", kind, id)
+}
+
+// findParam returns the index of the parameter named name
+func findParam(params []models.ConfigParam, name string) (int, bool) {
+	for i, p := range params {
+		if strings.EqualFold(p.Name, name) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// xdfParser converts entries with the settings of the document header
+type xdfParser struct {
+	base        int64
+	defaultBits int64
+	// tables by lowercased unique ID, for axis links
+	tables map[string]xdfTable
+	// category names by header index
+	categories map[int64]string
+}
+
+// category returns the first category of a table that is one of
+// models.Categories, empty if there is none. Other category names are
+// dropped and the map is listed under Other.
+func (p xdfParser) category(t xdfTable) string {
+	for _, mem := range t.Categories {
+		i, err := parseXDFNumber(mem.Category, 0)
+		if err != nil || i < 1 {
+			continue
+		}
+		if c, ok := models.FindCategory(strings.TrimSpace(p.categories[i-1])); ok {
+			return c
+		}
+	}
+	return ""
+}
+
+// table converts an XDFTABLE. dropped reports an axis that couldn't be
+// used; the map then has none on that side.
+func (p xdfParser) table(t xdfTable) (cfg models.MapConfig, dropped bool, err error) {
+	x, y, z := xdfAxisByID(t.Axes, "x"), xdfAxisByID(t.Axes, "y"), xdfAxisByID(t.Axes, "z")
+	if z == nil || z.Data == nil {
+		return cfg, false, errors.New("no z axis data")
+	}
+	cfg.Description = strings.TrimSpace(t.Description)
+	cfg.Unit = strings.TrimSpace(z.Units)
+	cfg.Category = p.category(t)
+	// Settings of a file written by ExportXDF
+	var extras xdfMapExtras
+	if readXDFExtras(t.Comment, &extras) {
+		cfg.HighlightBelow, cfg.NudgeStep, cfg.ColorScale = extras.HighlightBelow, extras.NudgeStep, extras.ColorScale
+		cfg.Role, cfg.Unconfirmed, cfg.InvertY = extras.Role, extras.Unconfirmed, extras.InvertY
+		cfg.MinValue, cfg.MaxValue = extras.MinValue, extras.MaxValue
+		cfg.InverseFormula = extras.InverseFormula
+	}
+	if cfg.Offset, cfg.DataType, cfg.Endianness, err = p.location(z.Data); err != nil {
+		return cfg, false, err
+	}
+	if cfg.Scale, cfg.Offset2, err = xdfEquation(z.Math); err != nil {
+		if cfg.Formula = xdfFormula(z.Math, err); cfg.Formula == "" {
+			return cfg, false, err
+		}
+		cfg.Scale, cfg.Offset2 = 1, 0
+	}
+
+	rows, err := parseXDFNumber(z.Data.Rows, 0)
+	if err != nil {
+		return cfg, false, fmt.Errorf("row count: %w", err)
+	}
+	cols, err := parseXDFNumber(z.Data.Cols, 0)
+	if err != nil {
+		return cfg, false, fmt.Errorf("column count: %w", err)
+	}
+	// Older files leave the counts to the axes
+	if rows == 0 && y != nil {
+		rows, _ = parseXDFNumber(y.IndexCount, 0)
+	}
+	if cols == 0 && x != nil {
+		cols, _ = parseXDFNumber(x.IndexCount, 0)
+	}
+	cfg.Rows, cfg.Cols = int(max(rows, 1)), int(max(cols, 1))
+	if err := checkXDFStride(z.Data, cfg.Cols); err != nil {
+		return cfg, false, err
+	}
+
+	var errX, errY error
+	cfg.XAxis, errX = p.axis(x, cfg.Cols)
+	cfg.YAxis, errY = p.axis(y, cfg.Rows)
+	// Fixed labels shown as label-only axes
+	if cfg.XAxis == nil {
+		cfg.XAxis = extras.XAxis
+	}
+	if cfg.YAxis == nil {
+		cfg.YAxis = extras.YAxis
+	}
+	// Maps default to little-endian and axes follow their map, so only a
+	// different order is recorded
+	for _, a := range []*models.AxisConfig{cfg.XAxis, cfg.YAxis} {
+		if a != nil && a.Endianness == cfg.Endianness {
+			a.Endianness = ""
+		}
+	}
+	if cfg.Endianness == models.LittleEndian {
+		cfg.Endianness = ""
+	}
+	return cfg, errX != nil || errY != nil, nil
+}
+
+// axis converts the x or y axis of a table with count breakpoints. Axes
+// stored in the file, as their own EMBEDDEDDATA or as a link to another
+// table, become an AxisConfig; label-only axes are nil.
+func (p xdfParser) axis(a *xdfAxis, count int) (*models.AxisConfig, error) {
+	if a == nil {
+		return nil, nil
+	}
+	data, maths, unit := a.Data, a.Math, a.Units
+	if a.EmbedInfo != nil && a.EmbedInfo.LinkObjID != "" {
+		linked, ok := p.tables[strings.ToLower(a.EmbedInfo.LinkObjID)]
+		z := xdfAxisByID(linked.Axes, "z")
+		if !ok || z == nil {
+			return nil, fmt.Errorf("axis %s links to unknown table %s", a.ID, a.EmbedInfo.LinkObjID)
+		}
+		data, maths = z.Data, z.Math
+		if strings.TrimSpace(unit) == "" {
+			unit = z.Units
+		}
+	}
+	if data == nil || strings.TrimSpace(data.Address) == "" {
+		return nil, nil
+	}
+
+	axis := &models.AxisConfig{Count: count, Unit: strings.TrimSpace(unit)}
+	var err error
+	if axis.Offset, axis.DataType, axis.Endianness, err = p.location(data); err != nil {
+		return nil, err
+	}
+	if axis.Scale, axis.Offset2, err = xdfEquation(maths); err != nil {
+		return nil, err
+	}
+	return axis, nil
+}
+
+// constant converts an XDFCONSTANT. Without a range in the file the
+// parameter may take any value its data type can store.
+func (p xdfParser) constant(c xdfConstant) (models.ConfigParam, error) {
+	param := models.ConfigParam{
+		Description: strings.TrimSpace(c.Description),
+		Unit:        strings.TrimSpace(c.Units),
+	}
+	if c.Data == nil {
+		return param, errors.New("no data")
+	}
+	var extras xdfParamExtras
+	if readXDFExtras(c.Comment, &extras) {
+		param.LinkedTo, param.MinGap = extras.LinkedTo, extras.MinGap
+		param.InverseFormula = extras.InverseFormula
+	}
+	var err error
+	if param.Offset, param.DataType, param.Endianness, err = p.location(c.Data); err != nil {
+		return param, err
+	}
+	if param.Scale, param.Offset2, err = xdfEquation(c.Math); err != nil {
+		if param.Formula = xdfFormula(c.Math, err); param.Formula == "" {
+			return param, err
+		}
+		param.Scale, param.Offset2 = 1, 0
+	}
+	if err := param.CheckConversion(); err != nil {
+		return param, err
+	}
+	// A single byte has no byte order
+	if models.DataTypeSize(param.DataType) == 1 {
+		param.Endianness = ""
+	}
+
+	lo, hi := models.RawRange(param.DataType)
+	param.MinValue, param.MaxValue = param.ToReal(lo), param.ToReal(hi)
+	if param.MinValue > param.MaxValue {
+		param.MinValue, param.MaxValue = param.MaxValue, param.MinValue
+	}
+	if param.Formula != "" {
+		param.MinValue, param.MaxValue = models.FormulaRange(param.Formula, param.DataType)
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(c.RangeLow), 64); err == nil {
+		param.MinValue = v
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(c.RangeHigh), 64); err == nil {
+		param.MaxValue = v
+	}
+	if param.Offset+int64(models.DataTypeSize(param.DataType)) > reader.MaxFileSize {
+		return param, fmt.Errorf("address 0x%X is past the largest accepted file", param.Offset)
+	}
+	return param, nil
+}
+
+// location returns the file offset, data type and byte order of an
+// EMBEDDEDDATA element. TunerPro stores 16-bit values most significant
+// byte first unless the LSB-first flag is set.
+func (p xdfParser) location(d *xdfData) (int64, string, models.Endianness, error) {
+	address, err := parseXDFNumber(d.Address, -1)
+	if err != nil || address < 0 {
+		return 0, "", "", fmt.Errorf("invalid address %q", d.Address)
+	}
+	offset := address + p.base
+	if offset < 0 {
+		return 0, "", "", fmt.Errorf("address 0x%X with base offset %d is before the start of the file", address, p.base)
+	}
+	flags, err := parseXDFNumber(d.TypeFlags, 0)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("type flags: %w", err)
+	}
+	bits, err := parseXDFNumber(d.ElementBits, p.defaultBits)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("element size: %w", err)
+	}
+	switch {
+	case flags&xdfFloat != 0:
+		return 0, "", "", errors.New("floating point data is not supported")
+	case flags&xdfColumnMajor != 0:
+		return 0, "", "", errors.New("column-major tables are not supported")
+	}
+
+	var dataType string
+	switch bits {
+	case 8:
+		dataType = "uint8"
+	case 16:
+		dataType = "uint16"
+	default:
+		return 0, "", "", fmt.Errorf("%d-bit data is not supported", bits)
+	}
+	if flags&xdfSigned != 0 {
+		dataType = strings.TrimPrefix(dataType, "u")
+	}
+	order := models.BigEndian
+	if flags&xdfLSBFirst != 0 {
+		order = models.LittleEndian
+	}
+	return offset, dataType, order, nil
+}
+
+// checkXDFStride refuses tables whose rows or cells aren't stored back to
+// back. A stride of 0 means packed.
+func checkXDFStride(d *xdfData, cols int) error {
+	bits, _ := parseXDFNumber(d.ElementBits, 8)
+	minor, err := parseXDFNumber(d.MinorStride, 0)
+	if err != nil {
+		return fmt.Errorf("minor stride: %w", err)
+	}
+	major, err := parseXDFNumber(d.MajorStride, 0)
+	if err != nil {
+		return fmt.Errorf("major stride: %w", err)
+	}
+	if minor != 0 && minor != bits {
+		return fmt.Errorf("cells %d bits apart are not supported", minor)
+	}
+	if major != 0 && major != bits*int64(cols) {
+		return fmt.Errorf("rows %d bits apart are not supported", major)
+	}
+	return nil
+}
+
+// xdfAxisByID returns the axis with the given id (x, y or z), nil if the
+// table has none
+func xdfAxisByID(axes []xdfAxis, id string) *xdfAxis {
+	for i := range axes {
+		if strings.EqualFold(axes[i].ID, id) {
+			return &axes[i]
+		}
+	}
+	return nil
+}
+
+// parseXDFNumber parses a decimal or 0x-prefixed count, address or flag
+// set; empty is def
+func parseXDFNumber(s string, def int64) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// xdfEquation returns scale and offset of the table-wide equation of an
+// axis or constant: raw*scale + offset. No equation is the identity.
+// Per-cell overrides are ignored.
+func xdfEquation(maths []xdfMath) (float64, float64, error) {
+	for _, m := range maths {
+		if m.Row != "" || m.Col != "" {
+			continue
+		}
+		for _, v := range m.Vars {
+			if !strings.EqualFold(v.ID, "X") {
+				return 0, 0, fmt.Errorf("%w %q: variable %s", errUnsupportedEquation, m.Equation, v.ID)
+			}
+		}
+		eq := strings.TrimSpace(m.Equation)
+		if eq == "" {
+			return 1, 0, nil
+		}
+		return parseLinear(eq)
+	}
+	return 1, 0, nil
+}
+
+// xdfFormula returns the table-wide equation that xdfEquation refused
+// with err as a formula (see models.Formula), such as "256/x" for
+// "256/X", or "" if it isn't one either
+func xdfFormula(maths []xdfMath, err error) string {
+	if !errors.Is(err, errUnsupportedEquation) {
+		return ""
+	}
+	for _, m := range maths {
+		if m.Row != "" || m.Col != "" {
+			continue
+		}
+		for _, v := range m.Vars {
+			if !strings.EqualFold(v.ID, "X") {
+				return ""
+			}
+		}
+		eq := strings.ReplaceAll(strings.TrimSpace(m.Equation), "X", "x")
+		if models.CheckFormula(eq, "", "") != nil {
+			return ""
+		}
+		return eq
+	}
+	return ""
+}
+
+// linear is the value a*X + b
+type linear struct {
+	a, b float64
+}
+
+// parseLinear reads an equation in X made of numbers, + - * / and
+// parentheses, such as "X*0.05", "(X-40)*0.75" or "X/2+10", and returns it
+// as scale*X + offset. Anything not linear in X is errUnsupportedEquation.
+func parseLinear(eq string) (scale, offset float64, err error) {
+	p := &linearParser{s: strings.ReplaceAll(eq, " ", "")}
+	v, err := p.expr()
+	if err == nil && p.pos < len(p.s) {
+		err = fmt.Errorf("unexpected %q", p.s[p.pos:])
+	}
+	if err == nil && v.a == 0 {
+		err = errors.New("does not depend on X")
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w %q: %v", errUnsupportedEquation, eq, err)
+	}
+	return v.a, v.b, nil
+}
+
+// linearParser is a recursive descent parser over linear values
+type linearParser struct {
+	s   string
+	pos int
+}
+
+// expr := term {(+|-) term}
+func (p *linearParser) expr() (linear, error) {
+	v, err := p.term()
+	for err == nil && p.pos < len(p.s) && (p.s[p.pos] == '+' || p.s[p.pos] == '-') {
+		op := p.s[p.pos]
+		p.pos++
+		var t linear
+		if t, err = p.term(); err != nil {
+			break
+		}
+		if op == '-' {
+			t = linear{-t.a, -t.b}
+		}
+		v = linear{v.a + t.a, v.b + t.b}
+	}
+	return v, err
+}
+
+// term := factor {(*|/) factor}, where one side of * and the divisor of /
+// must be constant
+func (p *linearParser) term() (linear, error) {
+	v, err := p.factor()
+	for err == nil && p.pos < len(p.s) && (p.s[p.pos] == '*' || p.s[p.pos] == '/') {
+		op := p.s[p.pos]
+		p.pos++
+		var f linear
+		if f, err = p.factor(); err != nil {
+			break
+		}
+		switch {
+		case op == '/' && (f.a != 0 || f.b == 0):
+			return v, errors.New("division by X or zero")
+		case op == '/':
+			v = linear{v.a / f.b, v.b / f.b}
+		case v.a != 0 && f.a != 0:
+			return v, errors.New("X times X")
+		default:
+			v = linear{v.a*f.b + f.a*v.b, v.b * f.b}
+		}
+	}
+	return v, err
+}
+
+// factor := (+|-) factor | number | X | ( expr )
+func (p *linearParser) factor() (linear, error) {
+	if p.pos >= len(p.s) {
+		return linear{}, errors.New("unexpected end")
+	}
+	switch c := p.s[p.pos]; {
+	case c == '+' || c == '-':
+		p.pos++
+		v, err := p.factor()
+		if c == '-' {
+			v = linear{-v.a, -v.b}
+		}
+		return v, err
+	case c == 'X' || c == 'x':
+		p.pos++
+		return linear{a: 1}, nil
+	case c == '(':
+		p.pos++
+		v, err := p.expr()
+		if err != nil {
+			return v, err
+		}
+		if p.pos >= len(p.s) || p.s[p.pos] != ')' {
+			return v, errors.New("missing )")
+		}
+		p.pos++
+		return v, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+		p.pos++
+	}
+	// Exponent, as in 1e-3
+	if p.pos > start && p.pos < len(p.s) && (p.s[p.pos] == 'e' || p.s[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.s) && (p.s[p.pos] == '+' || p.s[p.pos] == '-') {
+			p.pos++
+		}
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+	}
+	n, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return linear{}, fmt.Errorf("unexpected %q", p.s[start:])
+	}
+	return linear{b: n}, nil
+}
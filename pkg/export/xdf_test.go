@@ -0,0 +1,202 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// parseFixture parses testdata/fixture.xdf
+func parseFixture(t *testing.T) *XDF {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "fixture.xdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	x, err := ParseXDF(f, "fixture.xdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+// restoreDefinitions puts the active definitions back after the test
+func restoreDefinitions(t *testing.T) {
+	maps, params, base := models.MapConfigs, models.ConfigParams, models.BaseOffset
+	t.Cleanup(func() { models.MapConfigs, models.ConfigParams, models.BaseOffset = maps, params, base })
+}
+
+// Tables take address, size, data type, byte order and equation from their
+// z axis, axes from embedded data or a linked table, and the first
+// category the tool knows
+func TestParseXDFTables(t *testing.T) {
+	x := parseFixture(t)
+	want := []models.MapConfig{
+		{
+			Name: "Fuel Base", Offset: 0x6000, Rows: 8, Cols: 16, DataType: "uint8", Scale: 0.04, Unit: "ms",
+			Description: "Injection time", Endianness: models.BigEndian, Category: "Fuel", Source: "fixture.xdf",
+			XAxis: &models.AxisConfig{Offset: 0x5F00, Count: 16, DataType: "uint8", Scale: 50, Unit: "RPM"},
+			YAxis: &models.AxisConfig{Offset: 0x5F10, Count: 8, DataType: "uint8", Scale: 0.5, Unit: "%"},
+		},
+		// Signed, LSB first and (X-40)*0.75 as scale and offset
+		{Name: "Timing Trim", Offset: 0x6100, Rows: 2, Cols: 4, DataType: "int16", Scale: 0.75, Offset2: -30, Unit: "deg", Source: "fixture.xdf"},
+		// Not linear, but a formula
+		{Name: "Injector Dead Time", Offset: 0x6200, Rows: 1, Cols: 8, DataType: "uint8", Scale: 1, Formula: "1000/x", Endianness: models.BigEndian, Source: "fixture.xdf"},
+		// Kept without the axis whose link goes nowhere
+		{Name: "Broken Link", Offset: 0x6400, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1, Endianness: models.BigEndian, Source: "fixture.xdf"},
+	}
+	if len(x.Maps) != len(want) {
+		t.Fatalf("%d tables, want %d: %+v", len(x.Maps), len(want), x.Maps)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(x.Maps[i], want[i]) {
+			t.Errorf("table %d:\n got %+v\nwant %+v", i, x.Maps[i], want[i])
+		}
+	}
+	if !slices.Equal(x.AxesDropped, []string{"Broken Link"}) {
+		t.Errorf("axes dropped from %v, want [Broken Link]", x.AxesDropped)
+	}
+}
+
+// Constants take the range of the file, or the one their data type can
+// store
+func TestParseXDFConstants(t *testing.T) {
+	x := parseFixture(t)
+	want := []models.ConfigParam{
+		{Name: "Rev Limiter", Offset: 0x7000, DataType: "uint16", Scale: 10, Unit: "RPM", Description: "Fuel cut speed", MaxValue: 8000, Endianness: models.BigEndian},
+		{Name: "Idle Speed", Offset: 0x7002, DataType: "uint8", Scale: 10, Unit: "RPM", MaxValue: 2550},
+	}
+	if !reflect.DeepEqual(x.Params, want) {
+		t.Errorf("constants:\n got %+v\nwant %+v", x.Params, want)
+	}
+}
+
+// Entries that can't be used are listed, each with the reason, and don't
+// stop the others
+func TestParseXDFSkipped(t *testing.T) {
+	x := parseFixture(t)
+	if want := []string{"Logarithmic", "Two Variables"}; !slices.Equal(x.Unsupported, want) {
+		t.Errorf("unsupported %v, want %v", x.Unsupported, want)
+	}
+	want := []string{
+		`table "Load Breakpoints": map "Load Breakpoints" duplicates axis "Fuel Base Y axis"`,
+		`table "No Address": invalid address ""`,
+		`table "Bad Address": invalid address "0x6G00"`,
+		`table "No Data": no z axis data`,
+		`table "Wide Words": 32-bit data is not supported`,
+		`constant "Unplaced": no data`,
+		`constant "Missing Address": invalid address ""`,
+		`constant "Rev Limiter": a constant of that name comes earlier`,
+	}
+	if len(x.Skipped) != len(want) {
+		t.Fatalf("skipped %v, want %d entries", x.Skipped, len(want))
+	}
+	for i, err := range x.Skipped {
+		if !strings.HasPrefix(err.Error(), want[i]) {
+			t.Errorf("skipped %q, want %q", err, want[i])
+		}
+	}
+	if n := len(x.Warnings()); n != 1+len(want)+1 {
+		t.Errorf("%d warning lines, want %d: %q", n, 1+len(want)+1, x.Warnings())
+	}
+}
+
+// A subtracted BASEOFFSET maps CPU addresses to the file; addresses before
+// it are refused
+func TestParseXDFSubtractedBase(t *testing.T) {
+	doc := `<XDFFORMAT><XDFHEADER><BASEOFFSET offset="0x8000" subtract="1"/></XDFHEADER>
+<XDFTABLE><title>Mapped</title><XDFAXIS id="z"><EMBEDDEDDATA mmedaddress="0xE000" mmedrowcount="1" mmedcolcount="4"/></XDFAXIS></XDFTABLE>
+<XDFTABLE><title>Below</title><XDFAXIS id="z"><EMBEDDEDDATA mmedaddress="0x100" mmedrowcount="1" mmedcolcount="4"/></XDFAXIS></XDFTABLE>
+</XDFFORMAT>`
+	x, err := ParseXDF(strings.NewReader(doc), "cpu.xdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.BaseOffset != -0x8000 {
+		t.Errorf("base offset %d, want %d", x.BaseOffset, -0x8000)
+	}
+	if len(x.Maps) != 1 || x.Maps[0].Offset != 0x6000 {
+		t.Errorf("tables %+v, want Mapped at 0x6000", x.Maps)
+	}
+	if len(x.Skipped) != 1 || !strings.Contains(x.Skipped[0].Error(), "before the start of the file") {
+		t.Errorf("skipped %v, want Below before the start of the file", x.Skipped)
+	}
+}
+
+// Only a document that can't be read, or whose header can't be used, is
+// an error
+func TestParseXDFErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, want string
+	}{
+		{"not XML", "TunerPro", "EOF"},
+		{"unclosed", "<XDFFORMAT><XDFTABLE>", "unexpected EOF"},
+		{"other root", "<XDF></XDF>", "expected element type <XDFFORMAT>"},
+		{"base offset", `<XDFFORMAT><XDFHEADER><BASEOFFSET offset="0x8G00"/></XDFHEADER></XDFFORMAT>`, `BASEOFFSET: invalid number "0x8G00"`},
+		{"defaults", `<XDFFORMAT><XDFHEADER><DEFAULTS datasizeinbits="byte"/></XDFHEADER></XDFFORMAT>`, `DEFAULTS: invalid number "byte"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseXDF(strings.NewReader(tt.doc), "bad.xdf")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// ApplyXDF keeps the fixed maps and those with a role where no table
+// matches them and replaces the parameters; a table at the offset and
+// size of a built-in map takes its place
+func TestApplyXDF(t *testing.T) {
+	restoreDefinitions(t)
+	builtin := slices.Clone(models.MapConfigs)
+	x, err := ApplyXDF(filepath.Join("testdata", "fixture.xdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, cfg := range models.MapConfigs {
+		names = append(names, cfg.Name)
+	}
+	want := []string{"Main Fuel Map", "Ignition Timing Map", "Lambda Target Map", "Correction Table 1", "Cold Start Enrichment",
+		"Fuel Base", "Timing Trim", "Injector Dead Time", "Broken Link"}
+	if !slices.Equal(names, want) {
+		t.Errorf("maps %v, want %v", names, want)
+	}
+	if !slices.Equal(x.Builtin, want[:5]) {
+		t.Errorf("built-in maps kept %v, want %v", x.Builtin, want[:5])
+	}
+	if len(models.ConfigParams) != 2 || models.ConfigParams[0].Name != "Rev Limiter" {
+		t.Errorf("parameters %+v, want those of the file", models.ConfigParams)
+	}
+
+	// A table in place of the fuel map takes its slot and role
+	fuel := builtin[0]
+	doc := `<XDFFORMAT><XDFTABLE><title>Fuel Base</title><XDFAXIS id="z">
+<EMBEDDEDDATA mmedaddress="0x6700" mmedrowcount="8" mmedcolcount="16"/><MATH equation="X*0.05"/></XDFAXIS></XDFTABLE></XDFFORMAT>`
+	path := filepath.Join(t.TempDir(), "fuel.xdf")
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	models.MapConfigs, models.ConfigParams = slices.Clone(builtin), nil
+	if _, err := ApplyXDF(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := models.MapConfigs[0]; got.Name != "Fuel Base" || got.Offset != fuel.Offset || got.Scale != 0.05 || got.Role != fuel.Role {
+		t.Errorf("first map %+v, want the file's table in place of %s", got, fuel.Name)
+	}
+	if models.ConfigParams != nil {
+		t.Errorf("a file without constants replaced the parameters with %+v", models.ConfigParams)
+	}
+
+	if _, err := ApplyXDF(filepath.Join(t.TempDir(), "missing.xdf")); err == nil {
+		t.Error("no error for a missing file")
+	}
+}
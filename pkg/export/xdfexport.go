@@ -0,0 +1,463 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// xdfOut is the XDF document ExportXDF writes, in the layout of TunerPro's
+// format version 1.60
+type xdfOut struct {
+	XMLName   xml.Name         `xml:"XDFFORMAT"`
+	Version   string           `xml:"version,attr"`
+	Header    xdfOutHeader     `xml:"XDFHEADER"`
+	Tables    []xdfOutTable    `xml:"XDFTABLE"`
+	Constants []xdfOutConstant `xml:"XDFCONSTANT"`
+}
+
+type xdfOutHeader struct {
+	Flags       string `xml:"flags"`
+	FileVersion string `xml:"fileversion"`
+	Title       string `xml:"deftitle"`
+	Description string `xml:"description"`
+	Author      string `xml:"author"`
+	BaseOffset  struct {
+		Offset   int64 `xml:"offset,attr"`
+		Subtract int   `xml:"subtract,attr"`
+	} `xml:"BASEOFFSET"`
+	Defaults struct {
+		DataSize  int `xml:"datasizeinbits,attr"`
+		SigDigits int `xml:"sigdigits,attr"`
+		Output    int `xml:"outputtype,attr"`
+		Signed    int `xml:"signed,attr"`
+		LSBFirst  int `xml:"lsbfirst,attr"`
+		Float     int `xml:"float,attr"`
+	} `xml:"DEFAULTS"`
+	Region struct {
+		Type  string `xml:"type,attr"`
+		Start string `xml:"startaddress,attr"`
+		Size  string `xml:"size,attr"`
+		Flags string `xml:"regionflags,attr"`
+		Name  string `xml:"name,attr"`
+		Desc  string `xml:"desc,attr"`
+	} `xml:"REGION"`
+	Categories []xdfOutCategory `xml:"CATEGORY"`
+}
+
+// xdfOutCategory is a CATEGORY of the header. Tables refer to it by its
+// index plus one.
+type xdfOutCategory struct {
+	Index string `xml:"index,attr"`
+	Name  string `xml:"name,attr"`
+}
+
+type xdfOutTable struct {
+	UniqueID    string          `xml:"uniqueid,attr"`
+	Flags       string          `xml:"flags,attr"`
+	Extras      string          `xml:",comment"`
+	Title       string          `xml:"title"`
+	Description string          `xml:"description,omitempty"`
+	Category    *xdfCategoryMem `xml:"CATEGORYMEM"`
+	Axes        []xdfOutAxis    `xml:"XDFAXIS"`
+}
+
+// xdfCategoryMem puts a table in the header CATEGORY whose index is
+// Category minus one
+type xdfCategoryMem struct {
+	Index    int `xml:"index,attr"`
+	Category int `xml:"category,attr"`
+}
+
+type xdfOutAxis struct {
+	ID         string        `xml:"id,attr"`
+	UniqueID   string        `xml:"uniqueid,attr"`
+	Data       xdfOutData    `xml:"EMBEDDEDDATA"`
+	IndexCount int           `xml:"indexcount,omitempty"`
+	Units      string        `xml:"units,omitempty"`
+	Decimals   int           `xml:"decimalpl"`
+	Min        string        `xml:"min,omitempty"`
+	Max        string        `xml:"max,omitempty"`
+	Output     int           `xml:"outputtype"`
+	Labels     []xdfOutLabel `xml:"LABEL"`
+	Math       xdfOutMath    `xml:"MATH"`
+}
+
+type xdfOutData struct {
+	TypeFlags   string `xml:"mmedtypeflags,attr,omitempty"`
+	Address     string `xml:"mmedaddress,attr,omitempty"`
+	ElementBits int    `xml:"mmedelementsizebits,attr"`
+	Rows        int    `xml:"mmedrowcount,attr,omitempty"`
+	Cols        int    `xml:"mmedcolcount,attr,omitempty"`
+	MajorStride int    `xml:"mmedmajorstridebits,attr"`
+	MinorStride int    `xml:"mmedminorstridebits,attr"`
+}
+
+type xdfOutLabel struct {
+	Index int    `xml:"index,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type xdfOutMath struct {
+	Equation string `xml:"equation,attr"`
+	Var      struct {
+		ID string `xml:"id,attr"`
+	} `xml:"VAR"`
+}
+
+type xdfOutConstant struct {
+	UniqueID    string     `xml:"uniqueid,attr"`
+	Flags       string     `xml:"flags,attr"`
+	Extras      string     `xml:",comment"`
+	Title       string     `xml:"title"`
+	Description string     `xml:"description,omitempty"`
+	Data        xdfOutData `xml:"EMBEDDEDDATA"`
+	Units       string     `xml:"units,omitempty"`
+	Decimals    int        `xml:"decimalpl"`
+	RangeHigh   string     `xml:"rangehigh"`
+	RangeLow    string     `xml:"rangelow"`
+	Output      int        `xml:"outputtype"`
+	Math        xdfOutMath `xml:"MATH"`
+}
+
+// xdfMapExtras are the map settings an XDF has no element for
+type xdfMapExtras struct {
+	HighlightBelow *float64          `json:"highlight_below,omitempty"`
+	NudgeStep      float64           `json:"nudge_step,omitempty"`
+	ColorScale     models.ColorScale `json:"color_scale,omitzero"`
+	Role           string            `json:"role,omitempty"`
+	Unconfirmed    bool              `json:"unconfirmed,omitempty"`
+	InvertY        bool              `json:"invert_y,omitempty"`
+	MinValue       float64           `json:"min_value,omitempty"`
+	MaxValue       float64           `json:"max_value,omitempty"`
+	InverseFormula string            `json:"inverse_formula,omitempty"`
+	// XAxis and YAxis are axes with fixed labels, which the XDF only
+	// shows as label-only axes
+	XAxis *models.AxisConfig `json:"x_axis,omitempty"`
+	YAxis *models.AxisConfig `json:"y_axis,omitempty"`
+}
+
+// labeledAxis returns axis if it has fixed labels, nil otherwise
+func labeledAxis(axis *models.AxisConfig) *models.AxisConfig {
+	if axis != nil && axis.Labels != nil {
+		return axis
+	}
+	return nil
+}
+
+// xdfParamExtras are the parameter settings an XDF has no element for
+type xdfParamExtras struct {
+	LinkedTo       string  `json:"linked_to,omitempty"`
+	MinGap         float64 `json:"min_gap,omitempty"`
+	InverseFormula string  `json:"inverse_formula,omitempty"`
+}
+
+// xdfExtrasPrefix starts the comment that carries the extras of a table
+// or constant. TunerPro ignores comments; ParseXDF reads them back.
+const xdfExtrasPrefix = "m21:"
+
+// xdfExtrasComment returns the comment carrying extras, "" if they are
+// all unset
+func xdfExtrasComment(extras any) string {
+	data, err := json.Marshal(extras)
+	if err != nil || string(data) == "{}" {
+		return ""
+	}
+	// "--" may not appear in a comment; it can only occur inside a string
+	return " " + xdfExtrasPrefix + " " + strings.ReplaceAll(string(data), "--", `-\u002d`) + " "
+}
+
+// readXDFExtras decodes the extras of a comment into v. It reports false
+// if the comment has none or they can't be read.
+func readXDFExtras(comment string, v any) bool {
+	i := strings.Index(comment, xdfExtrasPrefix)
+	if i < 0 {
+		return false
+	}
+	return json.NewDecoder(strings.NewReader(comment[i+len(xdfExtrasPrefix):])).Decode(v) == nil
+}
+
+// ExportXDF writes configs and params to path as a TunerPro XDF, one
+// XDFTABLE per map and one XDFCONSTANT per parameter, for an image of the
+// profile's size. WriteXDF describes a particular binary instead.
+func ExportXDF(configs []models.MapConfig, params []models.ConfigParam, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteXDF(f, configs, params, nil)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteXDF writes the XDF document of ExportXDF. id is the binary it
+// describes, or nil: its part number titles the file and its size and
+// base offset go into the header. Addresses are offsets in
+// the image, with the base offset in BASEOFFSET, so ParseXDF reads the
+// definitions back unchanged for an image at the start of its file.
+// Settings an XDF has no place for (highlight threshold, nudge step,
+// color scale, role, unconfirmed, inverted load axis, parameter links) go
+// into a comment of their table or constant. Map categories become XDF
+// categories.
+func WriteXDF(w io.Writer, configs []models.MapConfig, params []models.ConfigParam, id *models.BinaryIdentity) error {
+	doc := xdfOut{Version: "1.60", Header: xdfHeaderFor(id)}
+	uid := 0x1000
+	nextID := func() string {
+		uid++
+		return fmt.Sprintf("0x%X", uid)
+	}
+	for _, cfg := range configs {
+		doc.Tables = append(doc.Tables, xdfTableFor(cfg, nextID))
+	}
+	for _, p := range params {
+		doc.Constants = append(doc.Constants, xdfConstantFor(p, nextID()))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// xdfHeaderFor describes the binary: the profile name and part number as
+// the title, the identification as the description and its size as the
+// region TunerPro opens. Without a binary it describes an image of the
+// profile's size at the start of its file.
+func xdfHeaderFor(id *models.BinaryIdentity) xdfOutHeader {
+	if id == nil {
+		id = &models.BinaryIdentity{Size: models.M21IDProfile.ImageSize}
+	}
+	var h xdfOutHeader
+	h.Flags = "0x1"
+	h.FileVersion = "1.0"
+	h.Title = models.M21IDProfile.Name
+	switch {
+	case id.PartNumber != "":
+		h.Title += " " + id.PartNumber
+	case id.Label() != "":
+		h.Title += " " + id.Label()
+	}
+	h.Description = fmt.Sprintf("Map definitions for a %d byte image", id.Size)
+	if label := id.Label(); label != "" {
+		h.Description += " (" + label + ")"
+	}
+	h.Author = "motronic-m21-tool"
+	h.BaseOffset.Offset = id.BaseOffset
+	h.Defaults.DataSize = 8
+	h.Defaults.SigDigits = 2
+	h.Defaults.Output = 1
+	h.Defaults.LSBFirst = 1
+	h.Region.Type = "0xFFFFFFFF"
+	h.Region.Start = "0x0"
+	h.Region.Size = fmt.Sprintf("0x%X", id.Size)
+	h.Region.Flags = "0x0"
+	h.Region.Name = "Binary File"
+	h.Region.Desc = "This region describes the bin file edited by this XDF"
+	for i, name := range models.Categories {
+		h.Categories = append(h.Categories, xdfOutCategory{Index: fmt.Sprintf("0x%X", i), Name: name})
+	}
+	return h
+}
+
+// xdfTableFor converts a map. Axes stored in the file become embedded
+// axis data; the others are labelled with the RPM and load labels.
+func xdfTableFor(cfg models.MapConfig, nextID func() string) xdfOutTable {
+	t := xdfOutTable{UniqueID: nextID(), Flags: "0x0", Title: cfg.Name, Description: cfg.Description}
+	t.Extras = xdfExtrasComment(xdfMapExtras{
+		HighlightBelow: cfg.HighlightBelow,
+		NudgeStep:      cfg.NudgeStep,
+		ColorScale:     cfg.ColorScale,
+		Role:           cfg.Role,
+		Unconfirmed:    cfg.Unconfirmed,
+		InvertY:        cfg.InvertY,
+		MinValue:       cfg.MinValue,
+		MaxValue:       cfg.MaxValue,
+		InverseFormula: cfg.InverseFormula,
+		XAxis:          labeledAxis(cfg.XAxis),
+		YAxis:          labeledAxis(cfg.YAxis),
+	})
+	if i := slices.Index(models.Categories, cfg.CategoryName()); i >= 0 {
+		t.Category = &xdfCategoryMem{Index: 0, Category: i + 1}
+	}
+
+	rpm := make([]string, cfg.Cols)
+	for i := range rpm {
+		rpm[i] = cfg.RPMLabel(i)
+	}
+	load := make([]string, cfg.Rows)
+	for i := range load {
+		load[i] = strconv.Itoa(int(math.Round(cfg.LoadAt(i))))
+	}
+	t.Axes = append(t.Axes,
+		xdfAxisFor("x", nextID(), cfg.XAxis, cfg.ByteOrder(), rpm, "rpm"),
+		xdfAxisFor("y", nextID(), cfg.YAxis, cfg.ByteOrder(), load, "%"))
+
+	lo, hi := models.RawRange(cfg.DataType)
+	low, high := cfg.ToReal(lo), cfg.ToReal(hi)
+	if low > high {
+		low, high = high, low
+	}
+	if cfg.Formula != "" {
+		low, high = models.FormulaRange(cfg.Formula, cfg.DataType)
+	}
+	if cfg.HasLimits() {
+		low, high = cfg.MinValue, cfg.MaxValue
+	}
+	decimals := xdfDecimals(cfg.Scale)
+	if cfg.Formula != "" {
+		decimals = xdfFormulaDecimals
+	}
+	t.Axes = append(t.Axes, xdfOutAxis{
+		ID:       "z",
+		UniqueID: nextID(),
+		Data: xdfOutData{
+			TypeFlags:   xdfTypeFlags(cfg.DataType, cfg.ByteOrder()),
+			Address:     fmt.Sprintf("0x%X", cfg.Offset),
+			ElementBits: 8 * models.DataTypeSize(cfg.DataType),
+			Rows:        cfg.Rows,
+			Cols:        cfg.Cols,
+		},
+		Units:    cfg.Unit,
+		Decimals: decimals,
+		Min:      strconv.FormatFloat(low, 'f', decimals, 64),
+		Max:      strconv.FormatFloat(high, 'f', decimals, 64),
+		Output:   1,
+		Math:     xdfMathFor(cfg.Scale, cfg.Offset2, cfg.Conversion, cfg.Formula),
+	})
+	return t
+}
+
+// xdfAxisFor converts the x or y axis of a map: the breakpoint table if
+// the map has one, otherwise labels only, in unit, or the axis's own
+// fixed labels
+func xdfAxisFor(id, uid string, axis *models.AxisConfig, order models.Endianness, labels []string, unit string) xdfOutAxis {
+	if axis != nil && axis.Labels != nil {
+		labels, unit = make([]string, len(axis.Labels)), axis.Unit
+		for i, v := range axis.Labels {
+			labels[i] = models.AxisLabel(v)
+		}
+		axis = nil
+	}
+	a := xdfOutAxis{ID: id, UniqueID: uid, IndexCount: len(labels), Output: 1}
+	if axis == nil {
+		a.Units = unit
+		a.Data.ElementBits = 8
+		for i, label := range labels {
+			a.Labels = append(a.Labels, xdfOutLabel{Index: i, Value: label})
+		}
+		a.Math = xdfMathFor(1, 0, models.ConversionLinear, "")
+		return a
+	}
+	if axis.Endianness != "" {
+		order = axis.Endianness
+	}
+	a.Data = xdfOutData{
+		TypeFlags:   xdfTypeFlags(axis.DataType, order),
+		Address:     fmt.Sprintf("0x%X", axis.Offset),
+		ElementBits: 8 * models.DataTypeSize(axis.DataType),
+	}
+	a.IndexCount = axis.Count
+	a.Units = axis.Unit
+	a.Decimals = xdfDecimals(axis.Scale)
+	a.Math = xdfMathFor(axis.Scale, axis.Offset2, models.ConversionLinear, "")
+	return a
+}
+
+// xdfConstantFor converts a parameter, with its plausible range
+func xdfConstantFor(p models.ConfigParam, uid string) xdfOutConstant {
+	decimals := xdfDecimals(p.Scale)
+	if p.Formula != "" {
+		decimals = xdfFormulaDecimals
+	}
+	return xdfOutConstant{
+		UniqueID:    uid,
+		Flags:       "0x0",
+		Extras:      xdfExtrasComment(xdfParamExtras{LinkedTo: p.LinkedTo, MinGap: p.MinGap, InverseFormula: p.InverseFormula}),
+		Title:       p.Name,
+		Description: p.Description,
+		Data: xdfOutData{
+			TypeFlags:   xdfTypeFlags(p.DataType, p.ByteOrder()),
+			Address:     fmt.Sprintf("0x%X", p.Offset),
+			ElementBits: 8 * models.DataTypeSize(p.DataType),
+		},
+		Units:     p.Unit,
+		Decimals:  decimals,
+		RangeHigh: formatXDFNumber(p.MaxValue),
+		RangeLow:  formatXDFNumber(p.MinValue),
+		Output:    1,
+		Math:      xdfMathFor(p.Scale, p.Offset2, models.ConversionLinear, p.Formula),
+	}
+}
+
+// xdfTypeFlags returns the EMBEDDEDDATA flags of a data type stored in
+// the given byte order
+func xdfTypeFlags(dataType string, order models.Endianness) string {
+	flags := 0
+	if strings.HasPrefix(dataType, "int") {
+		flags |= xdfSigned
+	}
+	if order == models.LittleEndian {
+		flags |= xdfLSBFirst
+	}
+	return fmt.Sprintf("0x%02X", flags)
+}
+
+// xdfMathFor returns the equation of a conversion: "X*scale+offset",
+// "scale/X+offset" for inverse tables or the formula, in TunerPro's X
+func xdfMathFor(scale, offset float64, conversion, formula string) xdfOutMath {
+	eq := "X"
+	switch {
+	case formula != "":
+		m := xdfOutMath{Equation: strings.NewReplacer("x", "X").Replace(formula)}
+		m.Var.ID = "X"
+		return m
+	case conversion == models.ConversionInverse:
+		eq = formatXDFNumber(scale) + "/X"
+	case scale != 1:
+		eq = "X*" + formatXDFNumber(scale)
+	}
+	switch {
+	case offset > 0:
+		eq += "+" + formatXDFNumber(offset)
+	case offset < 0:
+		eq += "-" + formatXDFNumber(-offset)
+	}
+	m := xdfOutMath{Equation: eq}
+	m.Var.ID = "X"
+	return m
+}
+
+// formatXDFNumber writes v with as many digits as it takes to read it
+// back exactly
+func formatXDFNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// xdfFormulaDecimals is the decimal places of formula conversions, which
+// have no single raw step to derive them from
+const xdfFormulaDecimals = 2
+
+// xdfDecimals returns the decimal places TunerPro shows for a scale: enough
+// for one raw step, at most 4
+func xdfDecimals(scale float64) int {
+	if scale == 0 {
+		return 0
+	}
+	return min(max(int(math.Ceil(-math.Log10(math.Abs(scale)))), 0), 4)
+}
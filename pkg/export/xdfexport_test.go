@@ -0,0 +1,73 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// Every built-in map and parameter, exported by ExportXDF, is parsed back
+// by ParseXDF as the same definition, fixed axis labels included
+func TestExportXDFRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "m21.xdf")
+	if err := ExportXDF(models.MapConfigs, models.ConfigParams, path); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	x, err := ParseXDF(f, "m21.xdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(x.Skipped) > 0 || len(x.Unsupported) > 0 {
+		t.Fatalf("skipped %v, unsupported %v", x.Skipped, x.Unsupported)
+	}
+	if x.BaseOffset != 0 {
+		t.Errorf("base offset %d, want 0", x.BaseOffset)
+	}
+
+	if len(x.Maps) != len(models.MapConfigs) {
+		t.Fatalf("%d tables, want %d", len(x.Maps), len(models.MapConfigs))
+	}
+	for i, want := range models.MapConfigs {
+		want.Source = "m21.xdf"
+		if got := x.Maps[i]; !reflect.DeepEqual(got, want) {
+			t.Errorf("table %d:\n got %+v\nwant %+v", i, got, want)
+		}
+	}
+	if len(x.Params) != len(models.ConfigParams) {
+		t.Fatalf("%d constants, want %d", len(x.Params), len(models.ConfigParams))
+	}
+	for i, want := range models.ConfigParams {
+		if got := x.Params[i]; !reflect.DeepEqual(got, want) {
+			t.Errorf("constant %d:\n got %+v\nwant %+v", i, got, want)
+		}
+	}
+}
+
+// Without a binary the header describes an image of the profile's size at
+// the start of its file
+func TestXDFHeaderWithoutBinary(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXDF(&buf, models.MapConfigs, models.ConfigParams, nil); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<deftitle>" + models.M21IDProfile.Name + "</deftitle>",
+		`<BASEOFFSET offset="0"`,
+		`<REGION type="0xFFFFFFFF" startaddress="0x0" size="0x8000"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("header lacks %s:\n%s", want, out[:min(len(out), 800)])
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+)
+
+// showAttachmentsDialog lists the logs attached to the current file, with
+// actions to open them externally and attach another
+func (mw *MainWindow) showAttachmentsDialog() {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.attachments.title", filepath.Base(mw.CurrentFile)))
+	dialog.SetDefaultSize(550, 300)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetVExpand(true)
+	scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	listBox := gtk.NewListBox()
+	listBox.SetSelectionMode(gtk.SelectionNone)
+	scrolled.SetChild(listBox)
+	contentArea.Append(scrolled)
+
+	populate := func() {
+		for child := listBox.FirstChild(); child != nil; child = listBox.FirstChild() {
+			listBox.Remove(child)
+		}
+
+		sidecar, err := editor.LoadSidecar(mw.CurrentFile)
+		if err != nil {
+			mw.logError(i18n.T("gui.read_attachments_failed"), err)
+			return
+		}
+		if len(sidecar.Attachments) == 0 {
+			listBox.Append(gtk.NewLabel(i18n.T("gui.attachments.none")))
+			return
+		}
+
+		for _, a := range sidecar.Attachments {
+			listBox.Append(mw.createAttachmentRow(a))
+		}
+	}
+	populate()
+
+	attachButton := gtk.NewButtonWithLabel(i18n.T("gui.attachments.attach"))
+	attachButton.SetHAlign(gtk.AlignStart)
+	attachButton.ConnectClicked(func() {
+		mw.attachLogDialog(populate)
+	})
+	contentArea.Append(attachButton)
+
+	dialog.AddButton(i18n.T("gui.button.close"), int(gtk.ResponseClose))
+	dialog.ConnectResponse(func(responseID int) {
+		dialog.Destroy()
+	})
+
+	dialog.Show()
+}
+
+// createAttachmentRow creates a row for one attached log
+func (mw *MainWindow) createAttachmentRow(a editor.Attachment) *gtk.Box {
+	rowBox := gtk.NewBox(gtk.OrientationHorizontal, 15)
+	rowBox.SetMarginStart(10)
+	rowBox.SetMarginEnd(10)
+	rowBox.SetMarginTop(6)
+	rowBox.SetMarginBottom(6)
+
+	infoBox := gtk.NewBox(gtk.OrientationVertical, 3)
+	infoBox.SetHExpand(true)
+
+	nameLabel := gtk.NewLabel(filepath.Base(a.Path))
+	nameLabel.SetXAlign(0)
+	nameLabel.AddCSSClass("param-name")
+	nameLabel.SetTooltipText(a.Path)
+	infoBox.Append(nameLabel)
+
+	status := a.Status()
+	detail := fmt.Sprintf("%s · added %s · %s", status, a.Added.Format("2006-01-02 15:04"), a.SHA256[:16])
+	if a.Note != "" {
+		detail = a.Note + " · " + detail
+	}
+	detailLabel := gtk.NewLabel(detail)
+	detailLabel.SetXAlign(0)
+	detailLabel.AddCSSClass("param-description")
+	if status != "ok" {
+		detailLabel.AddCSSClass("warning-text")
+	}
+	infoBox.Append(detailLabel)
+	rowBox.Append(infoBox)
+
+	openButton := gtk.NewButtonWithLabel(i18n.T("gui.attachments.open"))
+	openButton.SetSensitive(status != "missing")
+	openButton.ConnectClicked(func() {
+		uri := gio.NewFileForPath(a.Path).URI()
+		if err := gio.AppInfoLaunchDefaultForURI(uri, nil); err != nil {
+			mw.logError(i18n.T("gui.attachments.open_failed"), a.Path, err)
+		}
+	})
+	rowBox.Append(openButton)
+
+	return rowBox
+}
+
+// attachLogDialog picks a log file and attaches it to the current file
+func (mw *MainWindow) attachLogDialog(done func()) {
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle(i18n.T("gui.attachments.select"))
+
+	ctx := context.Background()
+	dialog.Open(ctx, &mw.window.Window, func(res gio.AsyncResulter) {
+		file, err := dialog.OpenFinish(res)
+		if err != nil || file == nil {
+			return // User cancelled
+		}
+
+		a, err := editor.AttachLog(mw.CurrentFile, file.Path(), "")
+		if err != nil {
+			mw.logError(i18n.T("gui.attachments.attach_failed"), err)
+			return
+		}
+		mw.logInfo(i18n.T("gui.attachments.attached"), filepath.Base(a.Path))
+		done()
+	})
+}
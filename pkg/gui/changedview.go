@@ -0,0 +1,91 @@
+package gui
+
+import (
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/tabular"
+	"github.com/tosih/motronic-m21-tool/pkg/compare"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// showChangedDialog shows what changed in the current file since the
+// start of yesterday, the report of the CLI changed command with -since
+// yesterday, as monospaced text
+func (mw *MainWindow) showChangedDialog() {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+	since, _ := compare.ParseSince("yesterday", time.Now())
+	report, err := compare.ChangesSince(mw.CurrentFile, since, reader.ReadMap)
+	if err != nil {
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return
+	}
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.changed.title", filepath.Base(mw.CurrentFile)))
+	dialog.SetDefaultSize(700, 450)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	textView := gtk.NewTextView()
+	textView.SetEditable(false)
+	textView.SetMonospace(true)
+	textView.Buffer().SetText(changeReportText(report))
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetVExpand(true)
+	scrolled.SetChild(textView)
+	contentArea.Append(scrolled)
+
+	dialog.AddButton(i18n.T("gui.button.close"), int(gtk.ResponseClose))
+	dialog.ConnectResponse(func(responseID int) {
+		dialog.Destroy()
+	})
+	dialog.Show()
+}
+
+// changeReportText lays out a change report as plain text in the order
+// ChangeReport.Print uses
+func changeReportText(r *compare.ChangeReport) string {
+	var b strings.Builder
+	b.WriteString(r.Heading() + "\n\n")
+	if maps := r.ChangedMaps(); len(maps) > 0 {
+		writeTableText(&b, r.MapTable())
+		for _, m := range maps {
+			b.WriteString("\n" + m.Name + "\n")
+			for _, line := range compare.CellLines(m) {
+				b.WriteString("  " + line + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+	if params := r.ChangedParams(); len(params) > 0 {
+		writeTableText(&b, compare.ParamTable(params))
+		b.WriteString("\n")
+	}
+	b.WriteString(r.Verdict() + "\n")
+	return b.String()
+}
+
+// writeTableText writes a table as aligned plain-text columns
+func writeTableText(b *strings.Builder, t *tabular.Table) {
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	w.Write([]byte(strings.Join(t.Columns, "\t") + "\n"))
+	for _, row := range t.Rows {
+		w.Write([]byte(strings.Join(row, "\t") + "\n"))
+	}
+	w.Flush()
+}
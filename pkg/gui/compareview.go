@@ -0,0 +1,157 @@
+package gui
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/compare"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// buildCompareParamsView creates the tab listing configuration parameters
+// that differ between the open file and the comparison file
+func (mw *MainWindow) buildCompareParamsView() *gtk.Box {
+	box := gtk.NewBox(gtk.OrientationVertical, 10)
+	box.SetMarginStart(20)
+	box.SetMarginEnd(20)
+	box.SetMarginTop(20)
+	box.SetMarginBottom(20)
+
+	headerLabel := gtk.NewLabel(i18n.T("gui.compare.header"))
+	headerLabel.AddCSSClass("config-header")
+	headerLabel.SetXAlign(0)
+	box.Append(headerLabel)
+
+	divergenceButton := gtk.NewButtonWithLabel(i18n.T("gui.divergence.button"))
+	divergenceButton.SetHAlign(gtk.AlignStart)
+	divergenceButton.ConnectClicked(func() {
+		if mw.CurrentFile == "" || mw.CompareFile == "" {
+			mw.logWarn("%s", i18n.T("gui.compare.choose"))
+			return
+		}
+		mw.showDivergenceDialog(mw.CurrentFile, mw.CompareFile)
+	})
+	box.Append(divergenceButton)
+
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetVExpand(true)
+	scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+
+	mw.compareParamsList = gtk.NewListBox()
+	mw.compareParamsList.SetSelectionMode(gtk.SelectionNone)
+	scrolled.SetChild(mw.compareParamsList)
+	box.Append(scrolled)
+
+	mw.refreshCompareParams()
+	return box
+}
+
+// refreshCompareParams lists the parameters that differ from the
+// comparison file, or explains why there is nothing to list
+func (mw *MainWindow) refreshCompareParams() {
+	list := mw.compareParamsList
+	if list == nil {
+		return // tab not built yet; building it lists the parameters
+	}
+	for child := list.FirstChild(); child != nil; child = list.FirstChild() {
+		list.Remove(child)
+	}
+
+	if mw.CurrentFile == "" || mw.CompareFile == "" {
+		list.Append(compareParamsMessage(i18n.T("gui.compare.choose")))
+		return
+	}
+
+	align, err := compare.Align(mw.CurrentFile, mw.CompareFile)
+	if err != nil {
+		mw.logError(i18n.T("gui.compare_identify_failed"), err)
+		return
+	}
+	diffs, err := compare.CompareParams(mw.CurrentFile, mw.CompareFile, align)
+	if err != nil {
+		mw.logError(i18n.T("gui.compare.params_failed"), err)
+		return
+	}
+	if len(diffs) == 0 {
+		list.Append(compareParamsMessage(i18n.T("gui.compare.all_match", filepath.Base(mw.CompareFile))))
+		return
+	}
+
+	for _, d := range diffs {
+		list.Append(compareParamRow(d))
+	}
+}
+
+// compareParamRow shows one differing parameter with both values; values
+// outside the plausible range are marked
+func compareParamRow(d compare.ParamDiff) *gtk.Box {
+	rowBox := gtk.NewBox(gtk.OrientationHorizontal, 15)
+	rowBox.SetMarginStart(10)
+	rowBox.SetMarginEnd(10)
+	rowBox.SetMarginTop(8)
+	rowBox.SetMarginBottom(8)
+
+	nameLabel := gtk.NewLabel(fmt.Sprintf("%s (0x%04X)", d.Param.Name, d.Param.Offset))
+	nameLabel.SetXAlign(0)
+	nameLabel.SetHExpand(true)
+	nameLabel.AddCSSClass("param-name")
+	rowBox.Append(nameLabel)
+
+	if d.Err != "" {
+		errLabel := gtk.NewLabel(d.Err)
+		errLabel.AddCSSClass("param-description")
+		rowBox.Append(errLabel)
+		return rowBox
+	}
+
+	for file := 1; file <= 2; file++ {
+		valueLabel := gtk.NewLabel(d.FormatValue(file))
+		valueLabel.AddCSSClass("param-value")
+		valueLabel.SetSizeRequest(180, -1)
+		valueLabel.SetXAlign(1)
+		rowBox.Append(valueLabel)
+	}
+
+	deltaLabel := gtk.NewLabel(fmt.Sprintf("%+.1f %s", d.Delta(), d.Param.Unit))
+	deltaLabel.SetSizeRequest(100, -1)
+	deltaLabel.SetXAlign(1)
+	rowBox.Append(deltaLabel)
+	if d.Implausible() {
+		rowBox.SetTooltipText(i18n.T("gui.compare.implausible"))
+	}
+	return rowBox
+}
+
+// compareParamsMessage is a placeholder row for the parameter list
+func compareParamsMessage(text string) *gtk.Label {
+	label := gtk.NewLabel(text)
+	label.SetXAlign(0)
+	label.SetMarginStart(10)
+	label.SetMarginTop(8)
+	label.AddCSSClass("param-description")
+	return label
+}
+
+// cellBlame names the changelog entry that wrote a cell differing between
+// the open and the compared map, or says none did. It returns "" for
+// cells within the comparison tolerance.
+func (mw *MainWindow) cellBlame(row, col int) string {
+	cur, cmp := mw.CurrentMap, mw.CompareMap
+	if math.Abs(cur.Data[row][col]-cmp.Data[row][col]) <= compare.DefaultTolerance(cur.Config) {
+		return ""
+	}
+	b, err := compare.NewBlamer(mw.CurrentFile, mw.CompareFile)
+	if err != nil {
+		mw.logger.Debug("Changelog attribution unavailable", "error", err)
+		return ""
+	}
+	cell := int64((row*cur.Config.Cols + col) * models.DataTypeSize(cur.Config.DataType))
+	blame := b.Cell(cur.Config.Offset+cell, cmp.Config.Offset+cell)
+	if blame.Unattributed {
+		return i18n.T("gui.blame.unattributed")
+	}
+	return i18n.T("gui.blame.cell", blame.String())
+}
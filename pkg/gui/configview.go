@@ -2,11 +2,12 @@ package gui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
 	"github.com/tosih/motronic-m21-tool/pkg/editor"
 	"github.com/tosih/motronic-m21-tool/pkg/models"
-	"github.com/tosih/motronic-m21-tool/pkg/reader"
 )
 
 // buildConfigView creates the configuration parameters tab
@@ -18,7 +19,7 @@ func (mw *MainWindow) buildConfigView() *gtk.Box {
 	box.SetMarginBottom(20)
 
 	// Header
-	headerLabel := gtk.NewLabel("ECU Configuration Parameters")
+	headerLabel := gtk.NewLabel(i18n.T("gui.config.header"))
 	headerLabel.AddCSSClass("config-header")
 	headerLabel.SetXAlign(0)
 	box.Append(headerLabel)
@@ -33,10 +34,26 @@ func (mw *MainWindow) buildConfigView() *gtk.Box {
 	listBox.SetSelectionMode(gtk.SelectionNone)
 	scrolled.SetChild(listBox)
 
-	// Populate parameters
+	// Populate parameters, keeping linked pairs in one row
+	rendered := map[string]bool{}
 	for _, param := range models.ConfigParams {
-		row := mw.createConfigParamRow(param)
-		listBox.Append(row)
+		if rendered[param.Name] {
+			continue
+		}
+		group := models.LinkedGroup(param.Name)
+		if len(group) == 1 {
+			listBox.Append(mw.createConfigParamRow(param))
+			rendered[param.Name] = true
+			continue
+		}
+
+		groupBox := gtk.NewBox(gtk.OrientationVertical, 0)
+		for _, p := range group {
+			groupBox.Append(mw.createConfigParamRow(p))
+			rendered[p.Name] = true
+		}
+		groupBox.Append(linkedParamsLabel(group))
+		listBox.Append(groupBox)
 	}
 
 	box.Append(scrolled)
@@ -80,7 +97,7 @@ func (mw *MainWindow) createConfigParamRow(param models.ConfigParam) *gtk.Box {
 	mw.configValueLabels[param.Name] = valueLabel
 
 	// Right side - edit button
-	editButton := gtk.NewButtonWithLabel("Edit")
+	editButton := gtk.NewButtonWithLabel(i18n.T("gui.config.edit"))
 	editButton.ConnectClicked(func() {
 		mw.editConfigParam(param, valueLabel)
 	})
@@ -89,19 +106,46 @@ func (mw *MainWindow) createConfigParamRow(param models.ConfigParam) *gtk.Box {
 	return rowBox
 }
 
+// linkedParamsLabel describes the constraints within a linked group
+func linkedParamsLabel(group []models.ConfigParam) *gtk.Label {
+	var rules []string
+	for _, p := range group {
+		if p.LinkedTo != "" {
+			rules = append(rules, fmt.Sprintf("%s ≥ %s + %.0f %s", p.Name, p.LinkedTo, p.MinGap, p.Unit))
+		}
+	}
+	label := gtk.NewLabel(i18n.T("gui.config.linked", strings.Join(rules, ", ")))
+	label.SetXAlign(0)
+	label.SetWrap(true)
+	label.SetMarginStart(10)
+	label.SetMarginBottom(8)
+	label.AddCSSClass("param-description")
+	return label
+}
+
+// readConfigParam reads a parameter of the open file from the session
+func (mw *MainWindow) readConfigParam(param models.ConfigParam) (float64, error) {
+	img, err := mw.currentImage()
+	if err != nil {
+		return 0, err
+	}
+	return img.ReadConfigParam(param)
+}
+
 // refreshConfigValues refreshes all config parameter values from the file
 func (mw *MainWindow) refreshConfigValues() {
-	if mw.currentFile == "" {
+	if mw.CurrentFile == "" {
 		return
 	}
 
 	// Read all config values
 	for _, param := range models.ConfigParams {
-		value, err := reader.ReadConfigParam(mw.currentFile, param)
+		value, err := mw.readConfigParam(param)
 		if err != nil {
 			// Show error in the label
 			if label, ok := mw.configValueLabels[param.Name]; ok {
-				label.SetText("Error")
+				label.SetText(i18n.T("gui.config.error"))
+				label.SetTooltipText(err.Error())
 			}
 			continue
 		}
@@ -109,6 +153,7 @@ func (mw *MainWindow) refreshConfigValues() {
 		// Update the value label
 		if label, ok := mw.configValueLabels[param.Name]; ok {
 			label.SetText(fmt.Sprintf("%.1f %s", value, param.Unit))
+			label.SetTooltipText("")
 		}
 	}
 }
@@ -123,22 +168,25 @@ func (mw *MainWindow) findChildByName(widget gtk.Widgetter, name string) gtk.Wid
 
 // editConfigParam shows a dialog to edit a config parameter
 func (mw *MainWindow) editConfigParam(param models.ConfigParam, valueLabel *gtk.Label) {
-	if mw.currentFile == "" {
-		mw.showErrorDialog("Please open an ECU file first")
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+	if !mw.checkWritable() {
 		return
 	}
 
 	// Read current value
-	currentValue, err := reader.ReadConfigParam(mw.currentFile, param)
+	currentValue, err := mw.readConfigParam(param)
 	if err != nil {
-		mw.showErrorDialog(fmt.Sprintf("Failed to read parameter: %v", err))
+		mw.logError(i18n.T("gui.config.read_failed"), err)
 		return
 	}
 
 	dialog := gtk.NewDialog()
 	dialog.SetTransientFor(&mw.window.Window)
 	dialog.SetModal(true)
-	dialog.SetTitle(fmt.Sprintf("Edit %s", param.Name))
+	dialog.SetTitle(i18n.T("gui.config.edit_title", param.Name))
 	dialog.SetDefaultSize(450, 250)
 
 	// Content area
@@ -156,25 +204,25 @@ func (mw *MainWindow) editConfigParam(param models.ConfigParam, valueLabel *gtk.
 	contentArea.Append(infoLabel)
 
 	// Current value
-	currentLabel := gtk.NewLabel(fmt.Sprintf("Current Value: %.1f %s", currentValue, param.Unit))
+	currentLabel := gtk.NewLabel(i18n.T("gui.config.current", currentValue, param.Unit))
 	currentLabel.SetXAlign(0)
 	currentLabel.AddCSSClass("current-value")
 	contentArea.Append(currentLabel)
 
 	// Valid range
-	rangeLabel := gtk.NewLabel(fmt.Sprintf("Valid Range: %.1f - %.1f %s", param.MinValue, param.MaxValue, param.Unit))
+	rangeLabel := gtk.NewLabel(i18n.T("gui.config.range", param.MinValue, param.MaxValue, param.Unit))
 	rangeLabel.SetXAlign(0)
 	contentArea.Append(rangeLabel)
 
 	// Warning
-	warningLabel := gtk.NewLabel("⚠️  Modifying ECU parameters can damage your engine!")
+	warningLabel := gtk.NewLabel(i18n.T("gui.config.warning"))
 	warningLabel.AddCSSClass("warning-text")
 	warningLabel.SetXAlign(0)
 	contentArea.Append(warningLabel)
 
 	// Entry box
 	entryBox := gtk.NewBox(gtk.OrientationHorizontal, 10)
-	entryLabel := gtk.NewLabel("New Value:")
+	entryLabel := gtk.NewLabel(i18n.T("gui.new_value"))
 	entryBox.Append(entryLabel)
 
 	entry := gtk.NewEntry()
@@ -186,27 +234,59 @@ func (mw *MainWindow) editConfigParam(param models.ConfigParam, valueLabel *gtk.
 	entryBox.Append(unitLabel)
 	contentArea.Append(entryBox)
 
+	// Linked parameters move by the same amount unless unchecked, in which
+	// case the new value must still satisfy the link on its own
+	var moveLinked *gtk.CheckButton
+	if group := models.LinkedGroup(param.Name); len(group) > 1 {
+		var names []string
+		for _, p := range group {
+			if p.Name != param.Name {
+				names = append(names, p.Name)
+			}
+		}
+		moveLinked = gtk.NewCheckButtonWithLabel(i18n.T("gui.config.move_linked", strings.Join(names, ", ")))
+		moveLinked.SetActive(true)
+		contentArea.Append(moveLinked)
+	}
+
+	// The rev limit is offered together with the fuel-cut columns
+	var fuelCutShift *gtk.SpinButton
+	if param.Name == editor.RevLimiterParam {
+		var fuelCutRow *gtk.Box
+		if fuelCutRow, fuelCutShift = mw.buildFuelCutRow(entry); fuelCutRow != nil {
+			contentArea.Append(fuelCutRow)
+		}
+	}
+
 	// Buttons
-	dialog.AddButton("Cancel", int(gtk.ResponseCancel))
-	dialog.AddButton("Save", int(gtk.ResponseAccept))
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(i18n.T("gui.button.save"), int(gtk.ResponseAccept))
 
 	dialog.ConnectResponse(func(responseID int) {
 		if responseID == int(gtk.ResponseAccept) {
 			var newValue float64
 			if _, err := fmt.Sscanf(entry.Text(), "%f", &newValue); err != nil {
-				mw.showErrorDialog(fmt.Sprintf("Invalid value: %v", err))
+				mw.logWarn(i18n.T("gui.invalid_value"), err)
 				dialog.Destroy()
 				return
 			}
 
 			// Validate range
 			if newValue < param.MinValue || newValue > param.MaxValue {
-				mw.showErrorDialog(fmt.Sprintf("Value out of range! Must be between %.1f and %.1f", param.MinValue, param.MaxValue))
+				mw.logWarn(i18n.T("gui.config.out_of_range"), param.MinValue, param.MaxValue)
 				dialog.Destroy()
 				return
 			}
 
 			// Confirm and save
+			if fuelCutShift != nil && fuelCutShift.ValueAsInt() != 0 {
+				mw.confirmAndStageRevLimit(newValue, fuelCutShift.ValueAsInt(), dialog)
+				return
+			}
+			if moveLinked != nil && moveLinked.Active() {
+				mw.confirmAndMoveLinkedParams(param, newValue, dialog)
+				return
+			}
 			mw.confirmAndSaveConfigParam(param, newValue, valueLabel, dialog)
 		} else {
 			dialog.Destroy()
@@ -218,55 +298,86 @@ func (mw *MainWindow) editConfigParam(param models.ConfigParam, valueLabel *gtk.
 
 // confirmAndSaveConfigParam shows confirmation and saves config parameter
 func (mw *MainWindow) confirmAndSaveConfigParam(param models.ConfigParam, newValue float64, valueLabel *gtk.Label, editDialog *gtk.Dialog) {
-	confirmDialog := gtk.NewMessageDialog(
-		&mw.window.Window,
-		gtk.DialogModal,
-		gtk.MessageWarning,
-		gtk.ButtonsNone,
-	)
-
-	confirmDialog.SetMarkup(fmt.Sprintf("<b>Confirm ECU Modification</b>\n\nThis will modify the ECU binary file.\nA backup will be created automatically.\n\nParameter: %s\nNew Value: %.1f %s\n\nProceed with caution!",
-		param.Name, newValue, param.Unit))
-
-	confirmDialog.AddButton("Cancel", int(gtk.ResponseCancel))
-	confirmDialog.AddButton("Save Changes", int(gtk.ResponseAccept))
-
-	confirmDialog.ConnectResponse(func(responseID int) {
-		if responseID == int(gtk.ResponseAccept) {
+	mw.confirmThen(editor.ConfirmReview,
+		i18n.T("gui.confirm_modification", i18n.T("gui.config.confirm_detail", param.Name, newValue, param.Unit)),
+		i18n.T("gui.button.save_changes"),
+		func() {
 			mw.saveConfigParam(param, newValue, valueLabel)
 			editDialog.Destroy()
-		}
-		confirmDialog.Destroy()
-	})
-
-	confirmDialog.Show()
+		})
 }
 
 // saveConfigParam saves a config parameter to the ECU file
 func (mw *MainWindow) saveConfigParam(param models.ConfigParam, newValue float64, valueLabel *gtk.Label) {
-	// Create backup
-	_, err := editor.CreateBackup(mw.currentFile)
-	if err != nil {
-		mw.showErrorDialog(fmt.Sprintf("Failed to create backup: %v", err))
-		return
+	report, err := mw.commitOps(editor.Operation{
+		Name: fmt.Sprintf("Set %s to %.2f", param.Name, newValue),
+		Plan: func(data []byte) ([]editor.CellChange, error) {
+			return editor.PlanConfigParam(data, param, newValue)
+		},
+	})
+	if report.Backup != "" {
+		mw.logger.Info("Backup created", "path", report.Backup)
 	}
-
-	// Write new value
-	err = editor.WriteConfigParam(mw.currentFile, param, newValue)
 	if err != nil {
-		mw.showErrorDialog(fmt.Sprintf("Failed to save parameter: %v", err))
+		mw.reportEditError(i18n.T("gui.config.save_failed"), err)
 		return
 	}
 
 	// Update UI - read the actual value back from file to confirm
-	actualValue, err := reader.ReadConfigParam(mw.currentFile, param)
+	actualValue, err := mw.readConfigParam(param)
 	if err == nil {
 		valueLabel.SetText(fmt.Sprintf("%.1f %s", actualValue, param.Unit))
 	} else {
 		valueLabel.SetText(fmt.Sprintf("%.1f %s", newValue, param.Unit))
 	}
 
-	mw.statusBar.SetText(fmt.Sprintf("%s updated to %.1f %s", param.Name, newValue, param.Unit))
+	mw.refreshCompareParams()
+	mw.logInfo("%s updated to %.1f %s", param.Name, newValue, param.Unit)
+}
+
+// confirmAndMoveLinkedParams shows the planned changes to a linked group
+// and writes them in one session
+func (mw *MainWindow) confirmAndMoveLinkedParams(param models.ConfigParam, newValue float64, editDialog *gtk.Dialog) {
+	data, err := mw.currentBytes()
+	if err != nil {
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return
+	}
+	changes, err := editor.PlanLinkedMove(data, param.Name, newValue)
+	if err != nil {
+		mw.reportEditError(i18n.T("gui.config.cannot_move", param.Name), err)
+		return
+	}
+	if len(changes) == 0 {
+		mw.logInfo("%s unchanged", param.Name)
+		editDialog.Destroy()
+		return
+	}
 
-	mw.showInfoDialog("Parameter saved successfully! Backup created.")
+	var lines []string
+	for _, c := range changes {
+		lines = append(lines, fmt.Sprintf("%s: %.1f → %.1f %s", c.Map, c.OldValue, c.NewValue, param.Unit))
+	}
+	mw.confirmThen(editor.ConfirmReview,
+		i18n.T("gui.confirm_modification", strings.Join(lines, "\n")+"\n\n"),
+		i18n.T("gui.button.save_changes"),
+		func() {
+			editDialog.Destroy()
+			report, err := mw.commitOps(editor.Operation{
+				Name: fmt.Sprintf("Move %s to %.0f", param.Name, newValue),
+				Plan: func(data []byte) ([]editor.CellChange, error) {
+					return editor.PlanLinkedMove(data, param.Name, newValue)
+				},
+			})
+			if err != nil {
+				mw.reportEditError(i18n.T("gui.config.save_group_failed"), err)
+				return
+			}
+			if report.Backup != "" {
+				mw.logger.Info("Backup created", "path", report.Backup)
+			}
+			mw.refreshConfigValues()
+			mw.refreshCompareParams()
+			mw.logInfo("%s moved to %.1f %s with linked parameters", param.Name, newValue, param.Unit)
+		})
 }
@@ -0,0 +1,190 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/compare"
+)
+
+// diffMode is the state of a window opened with --diff: two files shown
+// side by side and nothing written to either. The window stays in diff
+// mode until a file is opened explicitly.
+type diffMode struct {
+	file1, file2 string
+	// result is the comparison of every map, nil until the background
+	// diff has finished
+	result       *compare.Result
+	exportButton *gtk.Button
+}
+
+// NewDiffWindow creates and displays a read-only window comparing file1
+// with file2
+func NewDiffWindow(app *gtk.Application, file1, file2 string) *MainWindow {
+	return newMainWindow(app, &diffMode{file1: file1, file2: file2})
+}
+
+// openDiff loads the two files of the diff window, locks the file
+// controls and starts counting the changed cells of every map
+func (mw *MainWindow) openDiff() {
+	d := mw.diff
+	if !mw.checkECUFile(d.file1) || !mw.checkECUFile(d.file2) {
+		return
+	}
+
+	// The dropdown only names the first file; switching needs File > Open
+	mw.availableFiles = []string{d.file1}
+	mw.updateFileDropdown()
+	mw.fileDropdown.SetSelected(0)
+	if mw.CurrentFile != d.file1 {
+		mw.loadECUFile(d.file1)
+	}
+	mw.fileDropdown.SetSensitive(false)
+	mw.compareButton.SetSensitive(false)
+
+	mw.compareWith(d.file2)
+	mw.refreshCompareParams()
+	mw.updateLinkedTitle()
+
+	d.exportButton = gtk.NewButtonWithLabel(i18n.T("gui.diff.export"))
+	d.exportButton.SetTooltipText(i18n.T("gui.diff.export_tooltip"))
+	d.exportButton.ConnectClicked(func() {
+		mw.exportDiffReport()
+	})
+	mw.headerBar.PackEnd(d.exportButton)
+
+	mw.logInfo(i18n.T("gui.diff.opened"), filepath.Base(d.file1), filepath.Base(d.file2))
+	go mw.countDiffCells(d)
+}
+
+// countDiffCells compares every map of the diff window's files and badges
+// the sidebar rows with their changed-cell counts. It runs in the
+// background: reading and comparing all maps takes a moment.
+func (mw *MainWindow) countDiffCells(d *diffMode) {
+	result, err := compare.Diff(d.file1, d.file2, "all", -1, false, mw.readMap)
+	if err == nil {
+		// The exported report names the logged edit behind each change
+		err = result.Blame()
+	}
+	runOnMain(func() {
+		if mw.diff != d {
+			return // a file was opened meanwhile
+		}
+		if err != nil {
+			mw.logError(i18n.T("gui.diff.failed"), err)
+			return
+		}
+		d.result = result
+		for i, summary := range result.Maps {
+			badge := mw.mapBadges[i]
+			if badge == nil {
+				continue
+			}
+			switch {
+			case summary.Skipped != "":
+				badge.SetText("–")
+				badge.SetTooltipText(i18n.T("gui.diff.badge_skipped", summary.Skipped))
+			default:
+				badge.SetText(fmt.Sprintf("%d", summary.Changed))
+				badge.SetTooltipText(i18n.T("gui.diff.badge_tooltip", summary.Changed, summary.Total))
+			}
+			if summary.Changed > 0 {
+				badge.AddCSSClass("changed")
+			}
+			badge.SetVisible(true)
+		}
+		mw.logInfo(i18n.T("gui.diff.counted"), len(result.Maps))
+	})
+}
+
+// leaveDiff turns a diff window into a normal one, before a file is opened
+// explicitly
+func (mw *MainWindow) leaveDiff() {
+	d := mw.diff
+	mw.diff = nil
+	if d.exportButton != nil {
+		mw.headerBar.Remove(d.exportButton)
+	}
+	for _, badge := range mw.mapBadges {
+		badge.SetVisible(false)
+		badge.RemoveCSSClass("changed")
+	}
+	mw.compareButton.SetSensitive(true)
+	mw.compareWith("")
+	mw.refreshCompareParams()
+	mw.findAvailableFiles()
+}
+
+// showReadOnlyNotice tells the user that a diff window can't edit either
+// file
+func (mw *MainWindow) showReadOnlyNotice() {
+	mw.logWarn("%s", i18n.T("gui.diff.read_only"))
+
+	dialog := gtk.NewMessageDialog(
+		&mw.window.Window,
+		gtk.DialogModal,
+		gtk.MessageInfo,
+		gtk.ButtonsOK,
+	)
+	dialog.SetMarkup(i18n.T("gui.diff.read_only"))
+	dialog.ConnectResponse(func(int) {
+		dialog.Destroy()
+	})
+	dialog.Show()
+}
+
+// exportDiffReport asks for a file name and writes the comparison of the
+// diff window as HTML, JSON or CSV, chosen by its extension
+func (mw *MainWindow) exportDiffReport() {
+	d := mw.diff
+	if d == nil || d.result == nil {
+		mw.logWarn("%s", i18n.T("gui.diff.not_ready"))
+		return
+	}
+
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle(i18n.T("gui.diff.export_select"))
+	dialog.SetInitialName("diff-report.html")
+
+	ctx := context.Background()
+	dialog.Save(ctx, &mw.window.Window, func(res gio.AsyncResulter) {
+		file, err := dialog.SaveFinish(res)
+		if err != nil || file == nil {
+			return // User cancelled
+		}
+		path := file.Path()
+		if err := writeDiffReport(path, d.result); err != nil {
+			mw.logError(i18n.T("gui.diff.export_failed"), err)
+			return
+		}
+		mw.logInfo(i18n.T("gui.diff.exported"), path)
+	})
+}
+
+// writeDiffReport writes r to path like the CLI's -report flag: .html and
+// .json files get those formats, anything else CSV
+func writeDiffReport(path string, r *compare.Result) error {
+	write := compare.WriteCSV
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html":
+		write = compare.WriteHTML
+	case ".json":
+		write = compare.WriteJSON
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -3,24 +3,26 @@ package gui
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
 	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
 )
 
 // onMapClicked handles mouse clicks on the map for editing
 func (mw *MainWindow) onMapClicked(gesture *gtk.GestureClick, nPress int, x, y float64) {
-	if mw.currentMap == nil || mw.currentFile == "" {
+	if mw.CurrentMap == nil || mw.CurrentFile == "" {
 		return
 	}
 
-	// Get widget dimensions
-	width := mw.mapDrawArea.AllocatedWidth()
-	height := mw.mapDrawArea.AllocatedHeight()
-
 	// Determine which cell was clicked
-	row, col, valid := mw.getCellAtPosition(x, y, width, height)
+	row, col, valid := mw.getCellAtPosition(x, y)
 	if !valid {
 		return
 	}
@@ -31,12 +33,15 @@ func (mw *MainWindow) onMapClicked(gesture *gtk.GestureClick, nPress int, x, y f
 
 // showCellEditDialog displays a dialog to edit a single cell value
 func (mw *MainWindow) showCellEditDialog(row, col int) {
-	currentValue := mw.currentMap.Data[row][col]
+	if !mw.checkMapEditable() {
+		return
+	}
+	currentValue := mw.CurrentMap.Data[row][col]
 
 	dialog := gtk.NewDialog()
 	dialog.SetTransientFor(&mw.window.Window)
 	dialog.SetModal(true)
-	dialog.SetTitle("Edit Cell Value")
+	dialog.SetTitle(i18n.T("gui.edit.title"))
 	dialog.SetDefaultSize(400, 200)
 
 	// Content area
@@ -48,25 +53,32 @@ func (mw *MainWindow) showCellEditDialog(row, col int) {
 	contentArea.SetMarginBottom(20)
 
 	// Info label
-	infoLabel := gtk.NewLabel(fmt.Sprintf(
-		"Map: %s\nPosition: Row %d, Column %d\nCurrent Value: %.2f %s",
-		mw.currentMap.Config.Name,
+	infoLabel := gtk.NewLabel(i18n.T(
+		"gui.edit.info",
+		mw.CurrentMap.Config.Name,
 		row, col,
 		currentValue,
-		mw.currentMap.Config.Unit,
+		mw.CurrentMap.Config.Unit,
 	))
 	infoLabel.SetXAlign(0)
 	contentArea.Append(infoLabel)
 
+	cfg := mw.CurrentMap.Config
+	if cfg.HasLimits() {
+		rangeLabel := gtk.NewLabel(i18n.T("gui.edit.range", cfg.MinValue, cfg.MaxValue, cfg.Unit))
+		rangeLabel.SetXAlign(0)
+		contentArea.Append(rangeLabel)
+	}
+
 	// Warning label
-	warningLabel := gtk.NewLabel("⚠️  Modifying ECU values can damage your engine!")
+	warningLabel := gtk.NewLabel(i18n.T("gui.engine_warning"))
 	warningLabel.AddCSSClass("warning-text")
 	warningLabel.SetXAlign(0)
 	contentArea.Append(warningLabel)
 
 	// Entry for new value
 	entryBox := gtk.NewBox(gtk.OrientationHorizontal, 10)
-	entryLabel := gtk.NewLabel("New Value:")
+	entryLabel := gtk.NewLabel(i18n.T("gui.new_value"))
 	entryLabel.SetXAlign(0)
 	entryBox.Append(entryLabel)
 
@@ -75,26 +87,34 @@ func (mw *MainWindow) showCellEditDialog(row, col int) {
 	entry.SetHExpand(true)
 	entryBox.Append(entry)
 
-	unitLabel := gtk.NewLabel(mw.currentMap.Config.Unit)
+	unitLabel := gtk.NewLabel(mw.CurrentMap.Config.Unit)
 	entryBox.Append(unitLabel)
 	contentArea.Append(entryBox)
 
 	// Buttons
-	dialog.AddButton("Cancel", int(gtk.ResponseCancel))
-	dialog.AddButton("Save", int(gtk.ResponseAccept))
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(i18n.T("gui.button.save"), int(gtk.ResponseAccept))
 
 	dialog.ConnectResponse(func(responseID int) {
 		if responseID == int(gtk.ResponseAccept) {
 			newValueStr := entry.Text()
 			var newValue float64
 			if _, err := fmt.Sscanf(newValueStr, "%f", &newValue); err != nil {
-				mw.showErrorDialog(fmt.Sprintf("Invalid value: %v", err))
+				mw.logWarn(i18n.T("gui.invalid_value"), err)
 				dialog.Destroy()
 				return
 			}
 
+			// Values outside the map's limits need an explicit override
+			if raw, _ := cfg.ToRaw(newValue); !cfg.InRange(cfg.ToReal(raw)) && !editor.ForceRange {
+				mw.showOutOfRangeDialog(cfg, cfg.ToReal(raw), func() {
+					mw.confirmAndSaveEdit(row, col, newValue, true, dialog)
+				})
+				return
+			}
+
 			// Show confirmation dialog
-			mw.confirmAndSaveEdit(row, col, newValue, dialog)
+			mw.confirmAndSaveEdit(row, col, newValue, false, dialog)
 		} else {
 			dialog.Destroy()
 		}
@@ -103,84 +123,162 @@ func (mw *MainWindow) showCellEditDialog(row, col int) {
 	dialog.Show()
 }
 
-// confirmAndSaveEdit shows a confirmation dialog before saving
-func (mw *MainWindow) confirmAndSaveEdit(row, col int, newValue float64, editDialog *gtk.Dialog) {
-	confirmDialog := gtk.NewMessageDialog(
-		&mw.window.Window,
-		gtk.DialogModal,
-		gtk.MessageWarning,
-		gtk.ButtonsNone,
-	)
-
-	confirmDialog.SetMarkup("<b>Confirm ECU Modification</b>\n\nThis will modify the ECU binary file.\nA backup will be created automatically.\n\nProceed with caution!")
-
-	confirmDialog.AddButton("Cancel", int(gtk.ResponseCancel))
-	confirmDialog.AddButton("Save Changes", int(gtk.ResponseAccept))
+// confirmAndSaveEdit shows a confirmation dialog before saving. force
+// writes a value outside the map's limits.
+func (mw *MainWindow) confirmAndSaveEdit(row, col int, newValue float64, force bool, editDialog *gtk.Dialog) {
+	mw.confirmThen(editor.ConfirmReview,
+		i18n.T("gui.confirm_modification", ""),
+		i18n.T("gui.button.save_changes"),
+		func() {
+			mw.saveCellEdit(row, col, newValue, force)
+			editDialog.Destroy()
+		})
+}
 
-	confirmDialog.ConnectResponse(func(responseID int) {
+// showOutOfRangeDialog states the allowed range of cfg that value is
+// outside of and runs force if the user writes it anyway. Cancel returns
+// to the edit dialog.
+func (mw *MainWindow) showOutOfRangeDialog(cfg models.MapConfig, value float64, force func()) {
+	mw.logWarn(i18n.T("gui.range.refused"), value, cfg.Unit, cfg.MinValue, cfg.MaxValue, cfg.Unit)
+
+	dialog := gtk.NewMessageDialog(&mw.window.Window, gtk.DialogModal, gtk.MessageWarning, gtk.ButtonsNone)
+	dialog.SetMarkup(i18n.T("gui.range.info",
+		glib.MarkupEscapeText(cfg.Name), value, glib.MarkupEscapeText(cfg.Unit),
+		cfg.MinValue, cfg.MaxValue, glib.MarkupEscapeText(cfg.Unit)))
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(i18n.T("gui.range.force"), int(gtk.ResponseAccept))
+	dialog.ConnectResponse(func(responseID int) {
+		dialog.Destroy()
 		if responseID == int(gtk.ResponseAccept) {
-			mw.saveCellEdit(row, col, newValue)
-			editDialog.Destroy()
+			force()
 		}
-		confirmDialog.Destroy()
 	})
-
-	confirmDialog.Show()
+	dialog.Show()
 }
 
-// saveCellEdit saves a cell edit to the ECU file
-func (mw *MainWindow) saveCellEdit(row, col int, newValue float64) {
-	// Create backup first
-	_, err := editor.CreateBackup(mw.currentFile)
-	if err != nil {
-		mw.showErrorDialog(fmt.Sprintf("Failed to create backup: %v", err))
-		return
+// checkWritable reports whether the current file can be modified, logging
+// why not before any edit dialog opens
+func (mw *MainWindow) checkWritable() bool {
+	if mw.diff != nil {
+		mw.showReadOnlyNotice()
+		return false
 	}
+	if err := reader.CheckWritable(mw.CurrentFile); err != nil {
+		mw.logError("%s", reader.DescribeWriteError(err))
+		return false
+	}
+	return true
+}
 
-	// Update the cell
-	err = editor.EditMapCellDirect(mw.currentFile, mw.currentMap.Config, row, col, newValue)
+// checkMapEditable is checkWritable for edits of the current map. An
+// unconfirmed map whose bytes look like program code is locked until the
+// user types editor.CodeConfirmPhrase; the edit then has to be started
+// again.
+func (mw *MainWindow) checkMapEditable() bool {
+	if !mw.checkWritable() {
+		return false
+	}
+	data, err := mw.currentBytes()
 	if err != nil {
-		mw.showErrorDialog(fmt.Sprintf("Failed to save edit: %v", err))
-		return
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return false
 	}
+	check, warn := editor.CodeWarning(mw.CurrentFile, data, mw.CurrentMap.Config)
+	if !warn {
+		return true
+	}
+	mw.showCodeWarningDialog(mw.CurrentFile, mw.CurrentMap.Config.Name, check)
+	return false
+}
 
-	// Update local data
-	mw.currentMap.Data[row][col] = newValue
+// showCodeWarningDialog asks the user to type editor.CodeConfirmPhrase to
+// unlock mapName of filename for editing
+func (mw *MainWindow) showCodeWarningDialog(filename, mapName string, check scanner.CodeCheck) {
+	mw.logWarn(i18n.T("gui.code.locked"), mapName, check)
 
-	// Redraw
-	mw.mapDrawArea.QueueDraw()
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.code.title"))
+	dialog.SetDefaultSize(450, 200)
 
-	// Update status
-	mw.statusBar.SetText(fmt.Sprintf("Cell [%d,%d] updated to %.2f %s", row, col, newValue, mw.currentMap.Config.Unit))
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
 
-	// Show success message
-	mw.showInfoDialog("Edit saved successfully! Backup created.")
-}
+	infoLabel := gtk.NewLabel(i18n.T("gui.code.info", mapName, check))
+	infoLabel.SetWrap(true)
+	infoLabel.SetXAlign(0)
+	contentArea.Append(infoLabel)
 
-// showInfoDialog displays an informational message
-func (mw *MainWindow) showInfoDialog(message string) {
-	dialog := gtk.NewMessageDialog(
-		&mw.window.Window,
-		gtk.DialogModal,
-		gtk.MessageInfo,
-		gtk.ButtonsOK,
-	)
-	dialog.SetMarkup(message)
-	dialog.ConnectResponse(func(response int) {
+	promptLabel := gtk.NewLabel(i18n.T("gui.code.prompt", editor.CodeConfirmPhrase))
+	promptLabel.AddCSSClass("warning-text")
+	promptLabel.SetXAlign(0)
+	contentArea.Append(promptLabel)
+
+	entry := gtk.NewEntry()
+	contentArea.Append(entry)
+
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(i18n.T("gui.code.unlock"), int(gtk.ResponseAccept))
+	dialog.SetResponseSensitive(int(gtk.ResponseAccept), false)
+	entry.ConnectChanged(func() {
+		dialog.SetResponseSensitive(int(gtk.ResponseAccept), strings.TrimSpace(entry.Text()) == editor.CodeConfirmPhrase)
+	})
+
+	dialog.ConnectResponse(func(responseID int) {
+		if responseID == int(gtk.ResponseAccept) && strings.TrimSpace(entry.Text()) == editor.CodeConfirmPhrase {
+			editor.AcknowledgeCode(filename, mapName)
+			mw.logInfo(i18n.T("gui.code.unlocked"), mapName)
+		}
 		dialog.Destroy()
 	})
 	dialog.Show()
 }
 
+// saveCellEdit saves a cell edit to the ECU file. force writes a value
+// outside the map's limits.
+func (mw *MainWindow) saveCellEdit(row, col int, newValue float64, force bool) {
+	cfg := mw.CurrentMap.Config
+	report, err := mw.commitOps(editor.Operation{
+		Name: fmt.Sprintf("Edit %s [%d,%d]", cfg.Name, row, col),
+		Plan: func(data []byte) ([]editor.CellChange, error) {
+			return editor.PlanCellEdit(data, cfg, row, col, newValue)
+		},
+		Force: force,
+	})
+	if report.Backup != "" {
+		mw.logger.Info("Backup created", "path", report.Backup)
+	}
+	if err != nil {
+		mw.reportEditError(i18n.T("gui.edit.save_failed"), err)
+		return
+	}
+
+	// Update local data and redraw
+	mw.setView(mw.State.WithCell(row, col, newValue))
+
+	// Update status
+	mw.logInfo(i18n.T("gui.edit.updated"), row, col, newValue, mw.CurrentMap.Config.Unit)
+}
+
 // openCompareDialog opens a dialog to select a second file for comparison
 func (mw *MainWindow) openCompareDialog() {
-	if mw.currentFile == "" {
-		mw.showErrorDialog("Please open an ECU file first")
+	if mw.diff != nil {
+		mw.showReadOnlyNotice()
+		return
+	}
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
 		return
 	}
 
 	dialog := gtk.NewFileDialog()
-	dialog.SetTitle("Select ECU File to Compare")
+	dialog.SetTitle(i18n.T("gui.compare.select"))
+	dialog.SetDefaultFilter(binFileFilter())
 
 	// Open file dialog
 	ctx := context.Background()
@@ -192,22 +290,25 @@ func (mw *MainWindow) openCompareDialog() {
 
 		if file != nil {
 			path := file.Path()
-			mw.compareFile = path
-			mw.loadCurrentMap() // Reload to load comparison map
-			mw.statusBar.SetText(fmt.Sprintf("Comparing with: %s", path))
+			if !mw.checkECUFile(path) {
+				return
+			}
+			mw.compareWith(path)
+			mw.refreshCompareParams()
+			mw.logInfo(i18n.T("gui.compare.comparing"), path)
 		}
 	})
 }
 
 // exportDialog shows a dialog for exporting maps to CSV
 func (mw *MainWindow) exportDialog() {
-	if mw.currentFile == "" {
-		mw.showErrorDialog("Please open an ECU file first")
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
 		return
 	}
 
 	dialog := gtk.NewFileDialog()
-	dialog.SetTitle("Select Export Directory")
+	dialog.SetTitle(i18n.T("gui.export.select"))
 
 	// Select folder dialog
 	ctx := context.Background()
@@ -228,11 +329,11 @@ func (mw *MainWindow) exportDialog() {
 func (mw *MainWindow) performExport(exportPath string) {
 	// For now, export just the current map
 	// You can extend this to export all maps
-	err := editor.ExportMapToCSV(mw.currentMap, exportPath, mw.currentMap.Config.Name)
+	err := editor.ExportMapToCSV(mw.CurrentMap, exportPath, mw.CurrentMap.Config.Name)
 	if err != nil {
-		mw.showErrorDialog(fmt.Sprintf("Export failed: %v", err))
+		mw.logError(i18n.T("gui.export.failed"), err)
 		return
 	}
 
-	mw.showInfoDialog(fmt.Sprintf("Map exported successfully to:\n%s", exportPath))
+	mw.logInfo(i18n.T("gui.export.done"), exportPath)
 }
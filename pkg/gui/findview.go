@@ -0,0 +1,134 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/maplayout"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/query"
+)
+
+// showFindCellsDialog searches all maps for cells matching a predicate and
+// outlines the matches on the heatmap while the dialog is open
+func (mw *MainWindow) showFindCellsDialog() {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+	data, err := mw.currentBytes()
+	if err != nil {
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return
+	}
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetTitle(i18n.T("gui.find.title"))
+	dialog.SetDefaultSize(500, 400)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	entry := gtk.NewEntry()
+	entry.SetPlaceholderText("ignition > 35, any.raw >= 90%")
+	contentArea.Append(entry)
+
+	statusLabel := gtk.NewLabel(i18n.T("gui.find.hint"))
+	statusLabel.AddCSSClass("param-description")
+	statusLabel.SetXAlign(0)
+	statusLabel.SetWrap(true)
+	contentArea.Append(statusLabel)
+
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetVExpand(true)
+	scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	listBox := gtk.NewListBox()
+	listBox.SetSelectionMode(gtk.SelectionNone)
+	listBox.SetActivateOnSingleClick(true)
+	scrolled.SetChild(listBox)
+	contentArea.Append(scrolled)
+
+	var matches []query.Match
+	entry.ConnectChanged(func() {
+		for child := listBox.FirstChild(); child != nil; child = listBox.FirstChild() {
+			listBox.Remove(child)
+		}
+		matches = nil
+
+		p, err := query.Parse(entry.Text())
+		if err != nil {
+			mw.cellQuery = nil
+			statusLabel.SetText(err.Error())
+			mw.mapDrawArea.QueueDraw()
+			return
+		}
+		mw.cellQuery = p
+		matches = query.Find(data, p)
+		statusLabel.SetText(query.Describe(p, matches))
+		for _, m := range matches {
+			label := gtk.NewLabel(fmt.Sprintf("%s [%d,%d]  %.2f %s  raw %d  0x%04X",
+				m.Map, m.Row, m.Col, m.Value, m.Unit, m.Raw, m.Offset))
+			label.SetXAlign(0)
+			listBox.Append(label)
+		}
+		mw.mapDrawArea.QueueDraw()
+	})
+
+	// Activating a match shows its map
+	listBox.ConnectRowActivated(func(row *gtk.ListBoxRow) {
+		idx := row.Index()
+		if idx < 0 || idx >= len(matches) {
+			return
+		}
+		for i, cfg := range models.MapConfigs {
+			if cfg.Name == matches[idx].Map {
+				mw.selectMapRow(i)
+				return
+			}
+		}
+	})
+
+	dialog.AddButton(i18n.T("gui.button.close"), int(gtk.ResponseClose))
+	dialog.ConnectResponse(func(responseID int) {
+		dialog.Destroy()
+	})
+	dialog.ConnectDestroy(func() {
+		mw.cellQuery = nil
+		mw.mapDrawArea.QueueDraw()
+	})
+
+	dialog.Show()
+}
+
+// drawQueryOverlay outlines the cells of the current map that match the
+// active Find Cells predicate
+func (mw *MainWindow) drawQueryOverlay(cr *cairo.Context, layout maplayout.Layout) {
+	p := mw.cellQuery
+	if p == nil || !p.Covers(mw.CurrentMap.Config.Name) {
+		return
+	}
+
+	cfg := mw.CurrentMap.Config
+	cellWidth, cellHeight := layout.CellSize()
+	cr.SetSourceRGBA(0, 0.9, 1, 0.9)
+	cr.SetLineWidth(3)
+	for row := 0; row < layout.Rows; row++ {
+		for col := 0; col < layout.Cols; col++ {
+			value := mw.CurrentMap.Data[row][col]
+			raw, _ := cfg.ToRaw(value)
+			if !p.Match(cfg, value, raw) {
+				continue
+			}
+			x, y := layout.CellOrigin(row, col)
+			cr.Rectangle(x+1.5, y+1.5, cellWidth-3, cellHeight-3)
+			cr.Stroke()
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// buildFuelCutRow adds the fuel-cut controls to the rev limiter dialog: the
+// detected cut and a spin button to move it, which follows the suggested
+// shift as the new limit is typed. It returns nil if the fuel map can't be
+// read.
+func (mw *MainWindow) buildFuelCutRow(entry *gtk.Entry) (*gtk.Box, *gtk.SpinButton) {
+	data, err := mw.currentBytes()
+	if err != nil {
+		return nil, nil
+	}
+	m, err := reader.ReadMapFromBytes(data, models.MapConfigs[0])
+	if err != nil {
+		return nil, nil
+	}
+	cut := editor.FindFuelCut(m)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 5)
+	cutLabel := gtk.NewLabel(i18n.T("gui.fuelcut.detected", cut.Columns(), editor.ColumnRPM(cut.Start, cut.Cols)))
+	cutLabel.SetXAlign(0)
+	cutLabel.SetWrap(true)
+	box.Append(cutLabel)
+
+	row := gtk.NewBox(gtk.OrientationHorizontal, 10)
+	row.Append(gtk.NewLabel(i18n.T("gui.fuelcut.shift")))
+	spin := gtk.NewSpinButtonWithRange(float64(1-cut.Start), float64(cut.Cols-cut.Start), 1)
+	spin.SetValue(0)
+	row.Append(spin)
+	box.Append(row)
+
+	suggest := func() {
+		var limit float64
+		if _, err := fmt.Sscanf(entry.Text(), "%f", &limit); err == nil {
+			spin.SetValue(float64(editor.SuggestFuelCutShift(cut, limit)))
+		}
+	}
+	entry.ConnectChanged(suggest)
+	return box, spin
+}
+
+// confirmAndStageRevLimit moves the rev limiter and the fuel cut in one
+// session, so the file never gets a new limit without the matching cut
+func (mw *MainWindow) confirmAndStageRevLimit(newValue float64, shift int, editDialog *gtk.Dialog) {
+	ops := []editor.Operation{editor.RevLimitOperation(newValue), editor.FuelCutOperation(shift)}
+
+	// Plan without writing to show what will change
+	report, err := mw.previewOps(ops...)
+	if err != nil {
+		mw.reportEditError(i18n.T("gui.fuelcut.cannot"), err)
+		return
+	}
+	var lines []string
+	for _, r := range report.Results {
+		lines = append(lines, i18n.T("gui.fuelcut.op", r.Name, r.Changes))
+	}
+
+	mw.confirmThen(editor.ConfirmReview,
+		i18n.T("gui.confirm_modification", strings.Join(lines, "\n")+"\n\n"),
+		i18n.T("gui.button.save_changes"),
+		func() {
+			editDialog.Destroy()
+			report, err := mw.commitOps(ops...)
+			if report.Backup != "" {
+				mw.logger.Info("Backup created", "path", report.Backup)
+			}
+			if err != nil {
+				mw.reportEditError(i18n.T("gui.fuelcut.failed"), err)
+				return
+			}
+			mw.refreshConfigValues()
+			mw.refreshCompareParams()
+			mw.loadCurrentMap()
+			mw.refreshTimeline()
+			mw.logInfo(i18n.T("gui.fuelcut.done"), newValue, shift)
+		})
+}
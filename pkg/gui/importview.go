@@ -0,0 +1,164 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"html"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/export"
+)
+
+// importDialog picks an exported map CSV and shows its import report
+func (mw *MainWindow) importDialog() {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+
+	filter := gtk.NewFileFilter()
+	filter.SetName(i18n.T("gui.import.filter"))
+	filter.AddSuffix("csv")
+
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle(i18n.T("gui.import.title"))
+	dialog.SetDefaultFilter(filter)
+
+	ctx := context.Background()
+	dialog.Open(ctx, &mw.window.Window, func(res gio.AsyncResulter) {
+		file, err := dialog.OpenFinish(res)
+		if err != nil || file == nil {
+			return // User cancelled
+		}
+
+		data, err := mw.currentBytes()
+		if err != nil {
+			mw.logError(i18n.T("gui.read_failed"), err)
+			return
+		}
+		mw.showImportReport(export.PlanImportFiles(data, []string{file.Path()}))
+	})
+}
+
+// showImportReport lists every operation and every snapped, clamped or
+// rejected cell of a planned import, and writes the accepted subset if the
+// user proceeds
+func (mw *MainWindow) showImportReport(report *editor.ImportReport) {
+	changes := report.AcceptedChanges()
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.import.report"))
+	dialog.SetDefaultSize(650, 400)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	summary := i18n.T("gui.import.summary", len(changes))
+	if !report.Complete() {
+		summary += i18n.T("gui.import.incomplete")
+	}
+	summaryLabel := gtk.NewLabel(summary)
+	summaryLabel.SetWrap(true)
+	summaryLabel.SetXAlign(0)
+	contentArea.Append(summaryLabel)
+
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetVExpand(true)
+	scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	listBox := gtk.NewListBox()
+	listBox.SetSelectionMode(gtk.SelectionNone)
+	scrolled.SetChild(listBox)
+	contentArea.Append(scrolled)
+
+	for _, op := range report.Operations {
+		listBox.Append(importOperationRow(op))
+		for _, cell := range op.Cells {
+			listBox.Append(importCellRow(cell))
+		}
+	}
+
+	acceptLabel := i18n.T("gui.import.accept")
+	if !report.Complete() {
+		acceptLabel = i18n.T("gui.import.accept_partial")
+	}
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(acceptLabel, int(gtk.ResponseAccept))
+	dialog.SetResponseSensitive(int(gtk.ResponseAccept), len(changes) > 0)
+
+	dialog.ConnectResponse(func(responseID int) {
+		dialog.Destroy()
+		if responseID != int(gtk.ResponseAccept) || !mw.checkWritable() {
+			return
+		}
+
+		result, err := mw.commitOps(export.ImportOperations(report)...)
+		report.Backup, report.Written = result.Backup, result.Written
+		if err != nil {
+			mw.reportEditError(i18n.T("gui.import.failed"), err)
+			return
+		}
+		mw.logger.Info("Backup created", "path", result.Backup)
+		mw.loadCurrentMap()
+		mw.logInfo(i18n.T("gui.import.done"), len(changes))
+	})
+
+	dialog.Show()
+}
+
+// importOperationRow shows the counters of one imported file
+func importOperationRow(op *editor.ImportOperation) *gtk.Box {
+	rowBox := gtk.NewBox(gtk.OrientationVertical, 3)
+	rowBox.SetMarginStart(10)
+	rowBox.SetMarginEnd(10)
+	rowBox.SetMarginTop(6)
+	rowBox.SetMarginBottom(6)
+
+	name := op.Name
+	if op.Map != "" {
+		name = fmt.Sprintf("%s → %s", op.Name, op.Map)
+	}
+	nameLabel := gtk.NewLabel(name)
+	nameLabel.SetXAlign(0)
+	nameLabel.AddCSSClass("param-name")
+	rowBox.Append(nameLabel)
+
+	detail := i18n.T("gui.import.detail",
+		op.Accepted, op.Snapped, op.Clamped, op.Rejected)
+	if op.Err != "" {
+		detail = i18n.T("gui.import.rejected", op.Err)
+	}
+	detailLabel := gtk.NewLabel(detail)
+	detailLabel.SetXAlign(0)
+	detailLabel.SetWrap(true)
+	detailLabel.AddCSSClass("param-description")
+	if op.Dropped() {
+		detailLabel.AddCSSClass("warning-text")
+	}
+	rowBox.Append(detailLabel)
+
+	return rowBox
+}
+
+// importCellRow shows one cell that was not accepted verbatim
+func importCellRow(cell editor.ImportCell) *gtk.Label {
+	label := gtk.NewLabel("")
+	label.SetMarkup(fmt.Sprintf("[%d,%d] <tt>%s</tt> <b>%s</b>: %s",
+		cell.Row, cell.Col, html.EscapeString(cell.Value), cell.Outcome, html.EscapeString(cell.Reason)))
+	label.SetXAlign(0)
+	label.SetMarginStart(30)
+	label.SetMarginEnd(10)
+	label.AddCSSClass("param-description")
+	if cell.Outcome != editor.OutcomeSnapped {
+		label.AddCSSClass("warning-text")
+	}
+	return label
+}
@@ -0,0 +1,54 @@
+package gui
+
+import (
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+)
+
+// Notebook pages, in order
+const (
+	tabMap = iota
+	tabConfig
+	tabCompare
+	tabScanner
+)
+
+// lazyTab is a notebook page whose content is built the first time the
+// page is selected. Until then the page is an empty placeholder box, so
+// sessions that never open it skip the cost of building it.
+type lazyTab struct {
+	name        string
+	placeholder *gtk.Box
+	build       func() *gtk.Box
+	built       bool
+}
+
+// appendLazyTab adds a page labelled with the catalog key label whose
+// content build creates on first selection
+func (mw *MainWindow) appendLazyTab(label string, build func() *gtk.Box) {
+	placeholder := gtk.NewBox(gtk.OrientationVertical, 0)
+	placeholder.SetHExpand(true)
+	placeholder.SetVExpand(true)
+	page := mw.notebookTabs.AppendPage(placeholder, gtk.NewLabel(i18n.T(label)))
+	if mw.lazyTabs == nil {
+		mw.lazyTabs = make(map[int]*lazyTab)
+	}
+	mw.lazyTabs[page] = &lazyTab{name: label, placeholder: placeholder, build: build}
+}
+
+// buildLazyTab builds the content of page if it is a lazy page not built
+// yet. It is connected to the notebook's switch-page signal.
+func (mw *MainWindow) buildLazyTab(page int) {
+	tab, ok := mw.lazyTabs[page]
+	if !ok || tab.built {
+		return
+	}
+	tab.built = true
+	start := time.Now()
+	content := tab.build()
+	content.SetVExpand(true)
+	tab.placeholder.Append(content)
+	mw.logger.Debug("Tab built", "tab", tab.name, "elapsed", time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,155 @@
+package gui
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+)
+
+// openLinkedFileDialog selects a second binary that every edit is also
+// written to (editor.LinkedFile), and shows where the pair already
+// diverges
+func (mw *MainWindow) openLinkedFileDialog() {
+	if mw.diff != nil {
+		mw.showReadOnlyNotice()
+		return
+	}
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle(i18n.T("gui.linked.select"))
+	dialog.SetDefaultFilter(binFileFilter())
+
+	ctx := context.Background()
+	dialog.Open(ctx, &mw.window.Window, func(res gio.AsyncResulter) {
+		file, err := dialog.OpenFinish(res)
+		if err != nil || file == nil {
+			return // User cancelled
+		}
+		path := file.Path()
+		if !mw.checkECUFile(path) {
+			return
+		}
+		editor.LinkedFile = path
+		if err := editor.CheckLinked(mw.CurrentFile); err != nil {
+			editor.LinkedFile = ""
+			mw.logError(i18n.T("gui.linked.failed"), err)
+			return
+		}
+		mw.updateLinkedTitle()
+		mw.logInfo(i18n.T("gui.linked.linked"), filepath.Base(path))
+		mw.showDivergenceDialog(mw.CurrentFile, path)
+	})
+}
+
+// unlinkFile stops writing edits to the linked file
+func (mw *MainWindow) unlinkFile() {
+	if editor.LinkedFile == "" {
+		return
+	}
+	mw.logInfo(i18n.T("gui.linked.unlinked"), filepath.Base(editor.LinkedFile))
+	editor.LinkedFile = ""
+	mw.updateLinkedTitle()
+}
+
+// checkLinkedFile drops the link when a newly opened file can't be edited
+// in lock step with the linked file
+func (mw *MainWindow) checkLinkedFile() {
+	if editor.LinkedFile == "" {
+		return
+	}
+	if err := editor.CheckLinked(mw.CurrentFile); err != nil {
+		mw.logWarn(i18n.T("gui.linked.dropped"), err)
+		editor.LinkedFile = ""
+	}
+	mw.updateLinkedTitle()
+}
+
+// updateLinkedTitle names the linked file in the window title
+func (mw *MainWindow) updateLinkedTitle() {
+	if mw.CurrentFile == "" {
+		return
+	}
+	title := i18n.T("gui.title_file_ecu", filepath.Base(mw.CurrentFile), mw.ecuVersion)
+	if mw.diff != nil {
+		title = i18n.T("gui.diff.title", filepath.Base(mw.diff.file1), filepath.Base(mw.diff.file2))
+	} else if editor.LinkedFile != "" {
+		title = i18n.T("gui.linked.title", title, filepath.Base(editor.LinkedFile))
+	}
+	mw.window.SetTitle(title)
+}
+
+// showDivergenceDialog lists the map cells and parameters whose raw value
+// differs between two files: a per-map summary and, below it, every cell,
+// the same report -also-edit prints
+func (mw *MainWindow) showDivergenceDialog(file1, file2 string) {
+	mismatches, err := editor.DivergenceFiles(file1, file2)
+	if err != nil {
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return
+	}
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.divergence.title", filepath.Base(file1), filepath.Base(file2)))
+	dialog.SetDefaultSize(600, 450)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	if len(mismatches) == 0 {
+		contentArea.Append(gtk.NewLabel(i18n.T("gui.divergence.none")))
+	} else {
+		infoLabel := gtk.NewLabel(i18n.T("gui.divergence.count", len(mismatches)))
+		infoLabel.SetXAlign(0)
+		contentArea.Append(infoLabel)
+		summary := editor.MismatchSummary(mismatches)
+		contentArea.Append(tableGrid(summary.Columns, summary.Rows))
+
+		scrolled := gtk.NewScrolledWindow()
+		scrolled.SetVExpand(true)
+		scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+		details := editor.MismatchTable(mismatches, file1, file2)
+		scrolled.SetChild(tableGrid(details.Columns, details.Rows))
+		contentArea.Append(scrolled)
+	}
+
+	dialog.AddButton(i18n.T("gui.button.close"), int(gtk.ResponseClose))
+	dialog.ConnectResponse(func(responseID int) {
+		dialog.Destroy()
+	})
+	dialog.Show()
+}
+
+// tableGrid lays out a header row and rows of cells as a grid of labels
+func tableGrid(columns []string, rows [][]string) *gtk.Grid {
+	grid := gtk.NewGrid()
+	grid.SetColumnSpacing(15)
+	grid.SetRowSpacing(4)
+	for j, column := range columns {
+		label := gtk.NewLabel(column)
+		label.AddCSSClass("param-name")
+		label.SetXAlign(0)
+		grid.Attach(label, j, 0, 1, 1)
+	}
+	for i, row := range rows {
+		for j, cell := range row {
+			label := gtk.NewLabel(cell)
+			label.SetXAlign(0)
+			grid.Attach(label, j, i+1, 1, 1)
+		}
+	}
+	return grid
+}
@@ -0,0 +1,110 @@
+package gui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/datalog"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/maplayout"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// lambdaMapIdx is the Lambda Target Map in models.MapConfigs
+const lambdaMapIdx = 2
+
+// buildOverlayToggle creates the checkbox that overlays the most recent
+// attached datalog on the lambda map
+func (mw *MainWindow) buildOverlayToggle() *gtk.CheckButton {
+	toggle := gtk.NewCheckButtonWithLabel(i18n.T("gui.overlay.toggle"))
+	toggle.SetMarginStart(10)
+	toggle.SetTooltipText(i18n.T("gui.overlay.tooltip"))
+	toggle.ConnectToggled(func() {
+		if !toggle.Active() {
+			mw.logOverlay = nil
+			mw.mapDrawArea.QueueDraw()
+			return
+		}
+		if !mw.loadLogOverlay() {
+			toggle.SetActive(false)
+		}
+	})
+	return toggle
+}
+
+// loadLogOverlay parses the newest attached log that still exists
+func (mw *MainWindow) loadLogOverlay() bool {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return false
+	}
+
+	sidecar, err := editor.LoadSidecar(mw.CurrentFile)
+	if err != nil {
+		mw.logError(i18n.T("gui.read_attachments_failed"), err)
+		return false
+	}
+
+	var logPath string
+	for i := len(sidecar.Attachments) - 1; i >= 0; i-- {
+		if a := sidecar.Attachments[i]; a.Status() != "missing" {
+			logPath = a.Path
+			break
+		}
+	}
+	if logPath == "" {
+		mw.logWarn("%s", i18n.T("gui.overlay.no_log"))
+		return false
+	}
+
+	samples, skipped, err := datalog.ParseFile(logPath, datalog.Columns{})
+	if err != nil {
+		mw.logError(i18n.T("gui.overlay.parse_failed"), filepath.Base(logPath), err)
+		return false
+	}
+
+	mw.logOverlay = datalog.Bin(models.MapConfigs[lambdaMapIdx], samples, datalog.DefaultMinSamples)
+	mw.logInfo(i18n.T("gui.overlay.loaded"),
+		filepath.Base(logPath), len(samples), skipped, mw.logOverlay.Outside)
+	mw.mapDrawArea.QueueDraw()
+	return true
+}
+
+// drawLogOverlay writes the measured mean and sample count under each
+// lambda cell value. Low-confidence cells are drawn grey and prefixed ~.
+func (mw *MainWindow) drawLogOverlay(cr *cairo.Context, layout maplayout.Layout) {
+	o := mw.logOverlay
+	if o == nil || mw.selectedMapIdx != lambdaMapIdx || o.Rows != layout.Rows || o.Cols != layout.Cols {
+		return
+	}
+
+	cellWidth, cellHeight := layout.CellSize()
+	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightNormal)
+	cr.SetFontSize(8)
+
+	for row := 0; row < o.Rows; row++ {
+		for col := 0; col < o.Cols; col++ {
+			if o.Count[row][col] == 0 {
+				continue
+			}
+
+			text := fmt.Sprintf("%.2f (%d)", o.Mean[row][col], o.Count[row][col])
+			if o.LowConfidence(row, col) {
+				text = "~" + text
+				cr.SelectFontFace("Sans", cairo.FontSlantItalic, cairo.FontWeightNormal)
+				cr.SetSourceRGB(0.4, 0.4, 0.4)
+			} else {
+				cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightBold)
+				cr.SetSourceRGB(0, 0, 0)
+			}
+
+			x, y := layout.CellOrigin(row, col)
+			extents := cr.TextExtents(text)
+			cr.MoveTo(x+(cellWidth-extents.Width)/2, y+cellHeight-4)
+			cr.ShowText(text)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// maxLogEntries bounds the rolling log so long sessions don't grow unbounded
+const maxLogEntries = 500
+
+// logLevels are the severity filter choices, in dropdown order, named by
+// catalog key
+var logLevels = []struct {
+	name  string
+	level slog.Level
+}{
+	{"gui.log.all", slog.LevelInfo},
+	{"gui.log.warnings", slog.LevelWarn},
+	{"gui.log.errors", slog.LevelError},
+}
+
+// logEntry is one line shown in the log pane
+type logEntry struct {
+	time    time.Time
+	level   slog.Level
+	message string
+}
+
+// String formats the entry as a plain text log line
+func (e logEntry) String() string {
+	return fmt.Sprintf("%s %-5s %s", e.time.Format("15:04:05"), e.level, e.message)
+}
+
+// logPane is a collapsible, autoscrolling view of recent log entries
+type logPane struct {
+	expander *gtk.Expander
+	view     *gtk.TextView
+	buffer   *gtk.TextBuffer
+	endMark  *gtk.TextMark
+	filter   *gtk.DropDown
+	entries  []logEntry
+}
+
+// buildLogPane creates the log pane and installs its slog handler as the
+// default logger, so package code using slog also reports here
+func (mw *MainWindow) buildLogPane() *gtk.Expander {
+	pane := &logPane{}
+	mw.logPane = pane
+
+	pane.view = gtk.NewTextView()
+	pane.view.SetEditable(false)
+	pane.view.SetCursorVisible(false)
+	pane.view.SetMonospace(true)
+	pane.buffer = pane.view.Buffer()
+	pane.endMark = pane.buffer.CreateMark("end", pane.buffer.EndIter(), false)
+
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetChild(pane.view)
+	scrolled.SetSizeRequest(-1, 150)
+	scrolled.SetVExpand(true)
+
+	names := make([]string, len(logLevels))
+	for i, l := range logLevels {
+		names[i] = i18n.T(l.name)
+	}
+	pane.filter = gtk.NewDropDownFromStrings(names)
+	pane.filter.NotifyProperty("selected", func() {
+		pane.render()
+	})
+
+	copyButton := gtk.NewButtonWithLabel(i18n.T("gui.log.copy"))
+	copyButton.ConnectClicked(func() {
+		pane.view.Clipboard().SetText(pane.text())
+		mw.statusBar.SetText(i18n.T("gui.log.copied"))
+	})
+
+	clearButton := gtk.NewButtonWithLabel(i18n.T("gui.log.clear"))
+	clearButton.ConnectClicked(func() {
+		pane.entries = nil
+		pane.render()
+	})
+
+	toolbar := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	toolbar.Append(gtk.NewLabel(i18n.T("gui.log.show")))
+	toolbar.Append(pane.filter)
+	toolbar.Append(copyButton)
+	toolbar.Append(clearButton)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginStart(10)
+	box.SetMarginEnd(10)
+	box.SetMarginBottom(6)
+	box.Append(toolbar)
+	box.Append(scrolled)
+
+	pane.expander = gtk.NewExpander(i18n.T("gui.log.title"))
+	pane.expander.SetChild(box)
+
+	mw.logger = slog.New(&paneHandler{pane: pane})
+	slog.SetDefault(mw.logger)
+
+	return pane.expander
+}
+
+// minLevel returns the lowest level shown by the current filter
+func (p *logPane) minLevel() slog.Level {
+	idx := int(p.filter.Selected())
+	if idx >= len(logLevels) {
+		idx = 0
+	}
+	return logLevels[idx].level
+}
+
+// add appends an entry, trims the rolling window and autoscrolls
+func (p *logPane) add(entry logEntry) {
+	p.entries = append(p.entries, entry)
+	if len(p.entries) > maxLogEntries {
+		p.entries = p.entries[len(p.entries)-maxLogEntries:]
+		p.render()
+		return
+	}
+
+	if entry.level >= p.minLevel() {
+		p.insert(entry)
+		p.view.ScrollToMark(p.endMark, 0, false, 0, 1)
+	}
+}
+
+// render rebuilds the buffer from the stored entries
+func (p *logPane) render() {
+	p.buffer.SetText("")
+	minLevel := p.minLevel()
+	for _, e := range p.entries {
+		if e.level >= minLevel {
+			p.insert(e)
+		}
+	}
+	p.view.ScrollToMark(p.endMark, 0, false, 0, 1)
+}
+
+// insert writes a colored line for the entry at the end of the buffer
+func (p *logPane) insert(e logEntry) {
+	color := "#cccccc"
+	switch {
+	case e.level >= slog.LevelError:
+		color = "#e05050"
+	case e.level >= slog.LevelWarn:
+		color = "#e0a030"
+	}
+	p.buffer.InsertMarkup(p.buffer.EndIter(), fmt.Sprintf(
+		"<span foreground=\"%s\">%s</span>\n", color, glib.MarkupEscapeText(e.String())))
+}
+
+// text returns the visible log lines as plain text
+func (p *logPane) text() string {
+	var sb strings.Builder
+	minLevel := p.minLevel()
+	for _, e := range p.entries {
+		if e.level >= minLevel {
+			sb.WriteString(e.String())
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// paneHandler is a slog.Handler that forwards records to the log pane on
+// the GTK main loop, so it is safe to log from background goroutines
+type paneHandler struct {
+	pane  *logPane
+	attrs []slog.Attr
+}
+
+// Enabled reports whether records at the given level are recorded
+func (h *paneHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+// Handle formats the record and queues it for the log pane
+func (h *paneHandler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(r.Message)
+	writeAttr := func(a slog.Attr) bool {
+		fmt.Fprintf(&sb, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+
+	entry := logEntry{time: r.Time, level: r.Level, message: sb.String()}
+	runOnMain(func() {
+		h.pane.add(entry)
+	})
+	return nil
+}
+
+// WithAttrs returns a handler that appends attrs to every record
+func (h *paneHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &paneHandler{pane: h.pane, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+// WithGroup is a no-op; grouped attributes are flattened into the line
+func (h *paneHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// logInfo records an informational message and shows it in the status bar
+func (mw *MainWindow) logInfo(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	mw.logger.Info(msg)
+	mw.statusBar.SetText(msg)
+}
+
+// logWarn records a warning and shows it in the status bar
+func (mw *MainWindow) logWarn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	mw.logger.Warn(msg)
+	mw.statusBar.SetText("⚠ " + msg)
+}
+
+// logError records an error, shows it in the status bar and expands the
+// log pane so it is not missed
+func (mw *MainWindow) logError(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	mw.logger.Error(msg)
+	mw.statusBar.SetText("✖ " + msg)
+	mw.logPane.expander.SetExpanded(true)
+}
+
+// reportEditError shows why an edit failed. Problems with the requested
+// value, such as a value out of bounds or a cell outside the map, are
+// validation messages for the status bar; failures of the file itself are
+// shown in an error dialog as well as the log.
+func (mw *MainWindow) reportEditError(action string, err error) {
+	msg := fmt.Sprintf("%s: %s", action, reader.DescribeWriteError(err))
+	if reader.IsValidation(err) {
+		mw.logWarn("%s", msg)
+		return
+	}
+	mw.logError("%s", msg)
+	mw.showErrorDialog(msg)
+}
+
+// showErrorDialog shows msg in a modal error dialog
+func (mw *MainWindow) showErrorDialog(msg string) {
+	dialog := gtk.NewMessageDialog(&mw.window.Window, gtk.DialogModal, gtk.MessageError, gtk.ButtonsOK)
+	dialog.SetMarkup(glib.MarkupEscapeText(msg))
+	dialog.ConnectResponse(func(int) { dialog.Destroy() })
+	dialog.Show()
+}
@@ -3,35 +3,84 @@ package gui
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/settings"
+	"github.com/tosih/motronic-m21-tool/internal/version"
+	"github.com/tosih/motronic-m21-tool/pkg/compare"
+	"github.com/tosih/motronic-m21-tool/pkg/datalog"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/maplayout"
+	"github.com/tosih/motronic-m21-tool/pkg/mapview"
 	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/query"
 	"github.com/tosih/motronic-m21-tool/pkg/reader"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
 )
 
 // MainWindow represents the main application window
 type MainWindow struct {
 	app            *gtk.Application
 	window         *gtk.ApplicationWindow
-	currentFile    string
-	currentMap     *models.ECUMap
+	binDir         string // default folder of ECU binaries, "" if none
+	binDirSource   string
 	selectedMapIdx int
 
+	// Open file and map, and what they are compared with. Handlers read
+	// mw.CurrentMap and friends directly, but the state is only ever
+	// replaced whole by setView on the GTK main loop, and the draw
+	// callback copies it once per frame, so it never mixes a map of one
+	// file with the comparison of another. Workers must not touch it, or
+	// any other field: they compute a result value, such as a
+	// mapview.Loaded, and hand it to the main loop with runOnMain.
+	mapview.State
+
+	// Set in a read-only diff window (see NewDiffWindow)
+	diff *diffMode
+
+	// Part number and software version of the open file, for the title
+	ecuVersion models.ECUVersion
+
+	// Cell under the pointer, the target of +/- nudges
+	hoverRow, hoverCol int
+	hoverValid         bool
+
+	// Layout of the last drawn map. Hit-testing uses it rather than the
+	// widget's allocation, which can change before the next draw.
+	drawnLayout maplayout.Layout
+
+	// Predicate of the open Find Cells dialog, outlined on the heatmap
+	cellQuery *query.Predicate
+
 	// UI Components
 	headerBar      *gtk.HeaderBar
 	mainBox        *gtk.Box
 	sidebar        *gtk.Box
 	contentArea    *gtk.Box
 	mapListView    *gtk.ListBox
+	mapBadges      map[int]*gtk.Label
+	mapRows        map[int]*gtk.ListBoxRow
+	mapSections    map[string]*mapSection
+	compareButton  *gtk.Button
 	mapDrawArea    *gtk.DrawingArea
 	statusBar      *gtk.Label
 	configTreeView *gtk.TreeView
 	notebookTabs   *gtk.Notebook
+	lazyTabs       map[int]*lazyTab
 	fileDropdown   *gtk.DropDown
+	subtitleLabel  *gtk.Label
+
+	// Hits of the last scan, which the scanner view can define maps from
+	scanResults []scanner.ScanResult
+	scanPromote *gtk.DropDown
 
 	// Config parameter tracking
 	configValueLabels map[string]*gtk.Label
@@ -39,24 +88,81 @@ type MainWindow struct {
 	// Available ECU files
 	availableFiles []string
 
-	// Comparison mode
-	compareFile string
-	compareMap  *models.ECUMap
+	// Parameters that differ from the comparison file
+	compareParamsList *gtk.ListBox
+
+	// Backup timeline
+	timeline     []compare.TimelineVersion
+	timelineBox  *gtk.Box
+	versionScale *gtk.Scale
+	versionLabel *gtk.Label
+
+	// Datalog binned onto the lambda map, nil when the overlay is off
+	logOverlay    *datalog.Overlay
+	overlayToggle *gtk.CheckButton
+
+	// Outliers of the current map, nil when the overlay is off
+	outliers      []editor.Outlier
+	outlierToggle *gtk.CheckButton
+
+	// Cycles the Source of the map view, the data drawn while comparing
+	sourceButton *gtk.Button
+
+	// Automatic snapshots of the open file, nil when turned off
+	snapshotter *editor.Snapshotter
+
+	// Editing session of the open file: every read of it and every edit
+	// goes through its in-memory image, which it keeps up to date
+	session *editor.Session
+
+	// Images of other files read by the views, such as the compared one,
+	// dropped whenever the editor writes them
+	files reader.ECUFiles
+
+	// Log pane fed by the slog default logger
+	logger  *slog.Logger
+	logPane *logPane
 }
 
 // NewMainWindow creates and displays the main application window
 func NewMainWindow(app *gtk.Application) *MainWindow {
+	return newMainWindow(app, nil)
+}
+
+// newMainWindow creates and displays a main window, a read-only diff
+// window if diff is not nil
+func newMainWindow(app *gtk.Application, diff *diffMode) *MainWindow {
+	start := time.Now()
 	mw := &MainWindow{
 		app:               app,
 		selectedMapIdx:    0,
+		diff:              diff,
 		configValueLabels: make(map[string]*gtk.Label),
+		mapBadges:         make(map[int]*gtk.Label),
+		mapRows:           make(map[int]*gtk.ListBoxRow),
+		mapSections:       make(map[string]*mapSection),
 	}
+	mw.binDir, mw.binDirSource = settings.DefaultBinDir("")
 
+	loadLocale()
+	reportDefinitions := applyStartupDefinitions()
+	userMapsErr := editor.ApplyUserMaps()
 	mw.buildUI()
 	mw.applyCSSStyles()
+	mw.loadPreferences()
+	reportDefinitions(mw)
+	if userMapsErr != nil {
+		mw.logWarn(i18n.T("gui.wizard.load_failed"), userMapsErr)
+	}
+	mw.startSnapshotTimer()
 	mw.setupActions()
-	mw.loadAvailableFiles()
+	if diff != nil {
+		mw.openDiff()
+	} else {
+		mw.loadAvailableFiles()
+	}
 	mw.window.Show()
+	mw.logger.Info("Window ready", "startup", time.Since(start).Round(time.Millisecond))
 
 	return mw
 }
@@ -65,7 +171,7 @@ func NewMainWindow(app *gtk.Application) *MainWindow {
 func (mw *MainWindow) buildUI() {
 	// Create main window
 	mw.window = gtk.NewApplicationWindow(mw.app)
-	mw.window.SetTitle("Motronic M2.1 ECU Tool")
+	mw.window.SetTitle(i18n.T("gui.title"))
 	mw.window.SetDefaultSize(1200, 800)
 
 	// Create header bar with menu
@@ -85,14 +191,26 @@ func (mw *MainWindow) buildUI() {
 		mw.onFileSelected()
 	})
 
-	mw.headerBar.SetTitleWidget(mw.fileDropdown)
+	// Subtitle under the dropdown shows the loaded binary's identification
+	mw.subtitleLabel = gtk.NewLabel("")
+	mw.subtitleLabel.AddCSSClass("subtitle")
+	mw.subtitleLabel.SetVisible(false)
+
+	titleBox := gtk.NewBox(gtk.OrientationVertical, 0)
+	titleBox.SetVAlign(gtk.AlignCenter)
+	titleBox.Append(mw.fileDropdown)
+	titleBox.Append(mw.subtitleLabel)
+	mw.headerBar.SetTitleWidget(titleBox)
 
 	// Add compare button
-	compareButton := gtk.NewButtonWithLabel("Compare Files")
-	compareButton.ConnectClicked(func() {
+	mw.compareButton = gtk.NewButtonWithLabel(i18n.T("gui.menu.compare"))
+	mw.compareButton.ConnectClicked(func() {
 		mw.openCompareDialog()
 	})
-	mw.headerBar.PackEnd(compareButton)
+	mw.headerBar.PackEnd(mw.compareButton)
+	if mw.diff == nil {
+		mw.buildProfileDropdown()
+	}
 
 	// Main content box (horizontal split)
 	mw.mainBox = gtk.NewBox(gtk.OrientationHorizontal, 0)
@@ -103,14 +221,18 @@ func (mw *MainWindow) buildUI() {
 	// Right content area with notebook tabs
 	mw.buildContentArea()
 
+	// Collapsible log pane above the status bar
+	logExpander := mw.buildLogPane()
+
 	// Status bar at bottom
-	mw.statusBar = gtk.NewLabel("Ready. Open an ECU file to begin.")
+	mw.statusBar = gtk.NewLabel(i18n.T("gui.status.ready"))
 	mw.statusBar.SetXAlign(0)
 	mw.statusBar.AddCSSClass("statusbar")
 
 	// Overall vertical layout
 	vbox := gtk.NewBox(gtk.OrientationVertical, 0)
 	vbox.Append(mw.mainBox)
+	vbox.Append(logExpander)
 	vbox.Append(mw.statusBar)
 	mw.window.SetChild(vbox)
 }
@@ -122,7 +244,7 @@ func (mw *MainWindow) buildSidebar() {
 	mw.sidebar.AddCSSClass("sidebar")
 
 	// Sidebar header
-	sidebarLabel := gtk.NewLabel("ECU Maps")
+	sidebarLabel := gtk.NewLabel(i18n.T("gui.sidebar"))
 	sidebarLabel.AddCSSClass("sidebar-header")
 	sidebarLabel.SetXAlign(0)
 	mw.sidebar.Append(sidebarLabel)
@@ -172,48 +294,105 @@ func (mw *MainWindow) buildContentArea() {
 	})
 	mw.mapDrawArea.AddController(clickGesture)
 
+	// Hovering a cell shows its value history from backups and the changelog
+	mw.mapDrawArea.SetHasTooltip(true)
+	mw.mapDrawArea.ConnectQueryTooltip(mw.queryCellTooltip)
+
+	// +/- nudge the hovered cell by the map's nudge step
+	mw.attachNudgeControllers()
+
 	mapScrolled := gtk.NewScrolledWindow()
 	mapScrolled.SetChild(mw.mapDrawArea)
-	mw.notebookTabs.AppendPage(mapScrolled, gtk.NewLabel("Map View"))
+	mapScrolled.SetVExpand(true)
+
+	mapViewBox := gtk.NewBox(gtk.OrientationVertical, 0)
+	mapViewBox.Append(mw.buildTimelineBar())
+
+	// Map view toolbar
+	mapToolbar := gtk.NewBox(gtk.OrientationHorizontal, 10)
+	mapToolbar.SetMarginEnd(10)
+	mw.overlayToggle = mw.buildOverlayToggle()
+	mw.overlayToggle.SetHExpand(true)
+	mapToolbar.Append(mw.overlayToggle)
+	mapToolbar.Append(mw.buildOutlierToggle())
+	mapToolbar.Append(mw.buildSourceButton())
+	transformButton := gtk.NewButtonWithLabel(i18n.T("gui.transform.button"))
+	transformButton.ConnectClicked(mw.showTransformDialog)
+	mapToolbar.Append(transformButton)
+	mapViewBox.Append(mapToolbar)
+	mapViewBox.Append(mapScrolled)
+	mw.notebookTabs.AppendPage(mapViewBox, gtk.NewLabel(i18n.T("gui.tab.map")))
+
+	// The other tabs are built when first selected (see lazyTab)
+	mw.notebookTabs.ConnectSwitchPage(func(_ gtk.Widgetter, page uint) {
+		mw.buildLazyTab(int(page))
+	})
+
+	// Tab 2: Configuration Parameters, filled in from the open file
+	mw.appendLazyTab("gui.tab.config", func() *gtk.Box {
+		box := mw.buildConfigView()
+		mw.refreshConfigValues()
+		return box
+	})
 
-	// Tab 2: Configuration Parameters
-	configBox := mw.buildConfigView()
-	mw.notebookTabs.AppendPage(configBox, gtk.NewLabel("Config Parameters"))
+	// Tab 3: Parameters that differ from the comparison file
+	mw.appendLazyTab("gui.tab.compare", mw.buildCompareParamsView)
 
-	// Tab 3: Scanner
-	scannerBox := mw.buildScannerView()
-	mw.notebookTabs.AppendPage(scannerBox, gtk.NewLabel("Scanner"))
+	// Tab 4: Scanner
+	mw.appendLazyTab("gui.tab.scanner", mw.buildScannerView)
 
 	mw.contentArea.Append(mw.notebookTabs)
 	mw.mainBox.Append(mw.contentArea)
 }
 
-// populateMapList fills the sidebar with available maps
+// populateMapList fills the sidebar with available maps, under a
+// collapsible header per category
 func (mw *MainWindow) populateMapList() {
 	for i, mapConfig := range models.MapConfigs {
-		row := gtk.NewListBoxRow()
-
-		box := gtk.NewBox(gtk.OrientationVertical, 2)
-		box.SetMarginStart(10)
-		box.SetMarginEnd(10)
-		box.SetMarginTop(5)
-		box.SetMarginBottom(5)
-
-		nameLabel := gtk.NewLabel(mapConfig.Name)
-		nameLabel.SetXAlign(0)
-		nameLabel.AddCSSClass("map-name")
+		mw.appendMapRow(i, mapConfig)
+	}
+}
 
-		detailLabel := gtk.NewLabel(fmt.Sprintf("%dx%d - %s", mapConfig.Rows, mapConfig.Cols, mapConfig.Unit))
-		detailLabel.SetXAlign(0)
-		detailLabel.AddCSSClass("map-detail")
+// appendMapRow adds the sidebar row of map index i to the section of its
+// category
+func (mw *MainWindow) appendMapRow(i int, mapConfig models.MapConfig) {
+	row := gtk.NewListBoxRow()
+
+	box := gtk.NewBox(gtk.OrientationVertical, 2)
+	box.SetMarginStart(10)
+	box.SetMarginEnd(10)
+	box.SetMarginTop(5)
+	box.SetMarginBottom(5)
+
+	nameLabel := gtk.NewLabel(mapConfig.Name)
+	nameLabel.SetXAlign(0)
+	nameLabel.SetHExpand(true)
+	nameLabel.AddCSSClass("map-name")
+
+	// Changed-cell count of a diff window, hidden otherwise
+	badge := gtk.NewLabel("")
+	badge.AddCSSClass("map-badge")
+	badge.SetVisible(false)
+	mw.mapBadges[i] = badge
+
+	nameBox := gtk.NewBox(gtk.OrientationHorizontal, 5)
+	nameBox.Append(nameLabel)
+	nameBox.Append(badge)
+
+	detail := fmt.Sprintf("%dx%d - %s", mapConfig.Rows, mapConfig.Cols, mapConfig.Unit)
+	if mapConfig.Source != "" {
+		detail += fmt.Sprintf(" (%s)", mapConfig.Source)
+	}
+	detailLabel := gtk.NewLabel(detail)
+	detailLabel.SetXAlign(0)
+	detailLabel.AddCSSClass("map-detail")
 
-		box.Append(nameLabel)
-		box.Append(detailLabel)
+	box.Append(nameBox)
+	box.Append(detailLabel)
 
-		row.SetChild(box)
-		row.SetName(fmt.Sprintf("%d", i))
-		mw.mapListView.Append(row)
-	}
+	row.SetChild(box)
+	row.SetName(fmt.Sprintf("%d", i))
+	mw.addToSection(i, mapConfig, row)
 }
 
 // createMenuButton creates the application menu
@@ -225,20 +404,34 @@ func (mw *MainWindow) createMenuButton() *gtk.MenuButton {
 
 	// File menu section
 	fileSection := gio.NewMenu()
-	fileSection.Append("Open File...", "app.open")
-	fileSection.Append("Export to CSV...", "app.export")
-	fileSection.Append("Quit", "app.quit")
+	fileSection.Append(i18n.T("gui.menu.open"), "app.open")
+	fileSection.Append(i18n.T("gui.menu.save_as"), "app.save-as")
+	fileSection.Append(i18n.T("gui.menu.open_linked"), "app.open-linked")
+	fileSection.Append(i18n.T("gui.menu.unlink"), "app.unlink")
+	fileSection.Append(i18n.T("gui.menu.project"), "app.project")
+	fileSection.Append(i18n.T("gui.menu.export"), "app.export")
+	fileSection.Append(i18n.T("gui.menu.import"), "app.import")
+	fileSection.Append(i18n.T("gui.menu.attachments"), "app.attachments")
+	fileSection.Append(i18n.T("gui.menu.snapshots"), "app.snapshots")
+	fileSection.Append(i18n.T("gui.menu.preferences"), "app.preferences")
+	fileSection.Append(i18n.T("gui.menu.quit"), "app.quit")
 	menu.AppendSection("", fileSection)
 
 	// Tools menu section
 	toolsSection := gio.NewMenu()
-	toolsSection.Append("Scanner", "app.scanner")
-	toolsSection.Append("Compare Files", "app.compare")
+	toolsSection.Append(i18n.T("gui.menu.scanner"), "app.scanner")
+	toolsSection.Append(i18n.T("gui.menu.compare"), "app.compare")
+	toolsSection.Append(i18n.T("gui.menu.changed"), "app.changed")
+	toolsSection.Append(i18n.T("gui.menu.find"), "app.find")
+	toolsSection.Append(i18n.T("gui.menu.preset"), "app.preset")
+	toolsSection.Append(i18n.T("gui.menu.scale"), "app.scale")
+	toolsSection.Append(i18n.T("gui.menu.define_map"), "app.define-map")
+	toolsSection.Append(i18n.T("gui.menu.load_maps"), "app.load-maps")
 	menu.AppendSection("", toolsSection)
 
 	// Help menu section
 	helpSection := gio.NewMenu()
-	helpSection.Append("About", "app.about")
+	helpSection.Append(i18n.T("gui.menu.about"), "app.about")
 	menu.AppendSection("", helpSection)
 
 	menuButton.SetMenuModel(menu)
@@ -254,6 +447,32 @@ func (mw *MainWindow) setupActions() {
 	})
 	mw.app.AddAction(openAction)
 
+	// Save As action
+	saveAsAction := gio.NewSimpleAction("save-as", nil)
+	saveAsAction.ConnectActivate(func(param *glib.Variant) {
+		mw.saveAsDialog()
+	})
+	mw.app.AddAction(saveAsAction)
+
+	// Linked file actions
+	openLinkedAction := gio.NewSimpleAction("open-linked", nil)
+	openLinkedAction.ConnectActivate(func(param *glib.Variant) {
+		mw.openLinkedFileDialog()
+	})
+	mw.app.AddAction(openLinkedAction)
+	unlinkAction := gio.NewSimpleAction("unlink", nil)
+	unlinkAction.ConnectActivate(func(param *glib.Variant) {
+		mw.unlinkFile()
+	})
+	mw.app.AddAction(unlinkAction)
+
+	// Open project action
+	projectAction := gio.NewSimpleAction("project", nil)
+	projectAction.ConnectActivate(func(param *glib.Variant) {
+		mw.openProjectDialog()
+	})
+	mw.app.AddAction(projectAction)
+
 	// Export action
 	exportAction := gio.NewSimpleAction("export", nil)
 	exportAction.ConnectActivate(func(param *glib.Variant) {
@@ -261,6 +480,27 @@ func (mw *MainWindow) setupActions() {
 	})
 	mw.app.AddAction(exportAction)
 
+	// Import action
+	importAction := gio.NewSimpleAction("import", nil)
+	importAction.ConnectActivate(func(param *glib.Variant) {
+		mw.importDialog()
+	})
+	mw.app.AddAction(importAction)
+
+	// Attachments action
+	attachmentsAction := gio.NewSimpleAction("attachments", nil)
+	attachmentsAction.ConnectActivate(func(param *glib.Variant) {
+		mw.showAttachmentsDialog()
+	})
+	mw.app.AddAction(attachmentsAction)
+
+	// Snapshots action
+	snapshotsAction := gio.NewSimpleAction("snapshots", nil)
+	snapshotsAction.ConnectActivate(func(param *glib.Variant) {
+		mw.showSnapshotsDialog()
+	})
+	mw.app.AddAction(snapshotsAction)
+
 	// Compare action
 	compareAction := gio.NewSimpleAction("compare", nil)
 	compareAction.ConnectActivate(func(param *glib.Variant) {
@@ -268,10 +508,59 @@ func (mw *MainWindow) setupActions() {
 	})
 	mw.app.AddAction(compareAction)
 
+	// What changed since yesterday action
+	changedAction := gio.NewSimpleAction("changed", nil)
+	changedAction.ConnectActivate(func(param *glib.Variant) {
+		mw.showChangedDialog()
+	})
+	mw.app.AddAction(changedAction)
+
+	// Find cells action
+	findAction := gio.NewSimpleAction("find", nil)
+	findAction.ConnectActivate(func(param *glib.Variant) {
+		mw.showFindCellsDialog()
+	})
+	mw.app.AddAction(findAction)
+
+	// Preset action
+	presetAction := gio.NewSimpleAction("preset", nil)
+	presetAction.ConnectActivate(func(param *glib.Variant) {
+		mw.showPresetDialog()
+	})
+	mw.app.AddAction(presetAction)
+
+	// Scale action
+	scaleAction := gio.NewSimpleAction("scale", nil)
+	scaleAction.ConnectActivate(func(param *glib.Variant) {
+		mw.showScaleDialog()
+	})
+	mw.app.AddAction(scaleAction)
+
+	// Map definition wizard action
+	defineMapAction := gio.NewSimpleAction("define-map", nil)
+	defineMapAction.ConnectActivate(func(param *glib.Variant) {
+		mw.showMapWizard(nil, "")
+	})
+	mw.app.AddAction(defineMapAction)
+
+	// Map definitions file action
+	loadMapsAction := gio.NewSimpleAction("load-maps", nil)
+	loadMapsAction.ConnectActivate(func(param *glib.Variant) {
+		mw.loadMapDefinitionsDialog()
+	})
+	mw.app.AddAction(loadMapsAction)
+
+	// Preferences action
+	preferencesAction := gio.NewSimpleAction("preferences", nil)
+	preferencesAction.ConnectActivate(func(param *glib.Variant) {
+		mw.showPreferencesDialog()
+	})
+	mw.app.AddAction(preferencesAction)
+
 	// Scanner action
 	scannerAction := gio.NewSimpleAction("scanner", nil)
 	scannerAction.ConnectActivate(func(param *glib.Variant) {
-		mw.notebookTabs.SetCurrentPage(2) // Switch to scanner tab
+		mw.notebookTabs.SetCurrentPage(tabScanner)
 	})
 	mw.app.AddAction(scannerAction)
 
@@ -293,12 +582,12 @@ func (mw *MainWindow) setupActions() {
 // openFileDialog shows file chooser for opening ECU files
 func (mw *MainWindow) openFileDialog() {
 	dialog := gtk.NewFileDialog()
-	dialog.SetTitle("Open ECU Binary File")
+	dialog.SetTitle(i18n.T("gui.open.title"))
+	dialog.SetDefaultFilter(binFileFilter())
 
-	// Set default folder if it exists
-	if _, err := os.Stat("bins"); err == nil {
-		binFolder := gio.NewFileForPath("bins")
-		dialog.SetInitialFolder(binFolder)
+	// Start in the binary directory if one is configured
+	if mw.binDir != "" {
+		dialog.SetInitialFolder(gio.NewFileForPath(mw.binDir))
 	}
 
 	// Open file dialog
@@ -310,62 +599,108 @@ func (mw *MainWindow) openFileDialog() {
 		}
 
 		if file != nil {
+			// Opening a file is how a diff window becomes editable
+			if mw.diff != nil {
+				mw.leaveDiff()
+			}
 			path := file.Path()
 			mw.loadECUFile(path)
 		}
 	})
 }
 
+// binFileFilter restricts file dialogs to ECU images
+func binFileFilter() *gtk.FileFilter {
+	filter := gtk.NewFileFilter()
+	filter.SetName(i18n.T("gui.open.filter"))
+	filter.AddSuffix("bin")
+	return filter
+}
+
+// checkECUFile logs an error and returns false if the file is not a .bin
+// image or exceeds the size limit
+func (mw *MainWindow) checkECUFile(filename string) bool {
+	if !strings.EqualFold(filepath.Ext(filename), ".bin") {
+		mw.logError(i18n.T("gui.open.not_image"), filepath.Base(filename))
+		return false
+	}
+	if err := reader.CheckFileSize(filename); err != nil {
+		mw.logError(i18n.T("gui.open.failed"), filepath.Base(filename), err)
+		return false
+	}
+	return true
+}
+
 // loadECUFile loads an ECU binary file
 func (mw *MainWindow) loadECUFile(filename string) {
-	mw.currentFile = filename
-
-	// Update window title
-	mw.window.SetTitle(fmt.Sprintf("Motronic M2.1 ECU Tool - %s", filepath.Base(filename)))
+	if !mw.checkECUFile(filename) {
+		return
+	}
+	if err := mw.openSession(filename); err != nil {
+		mw.logError(i18n.T("gui.open.failed"), filepath.Base(filename), err)
+		return
+	}
+	mw.applyLayout(filename)
+	// Load the currently selected map along with the file, so the view
+	// never shows the previous file's map under the new name
+	mw.loadView(filename, mw.CompareFile)
+	mw.refreshOutliers()
+	if mw.snapshotter != nil {
+		mw.snapshotter.Track(filename)
+	}
 
-	// Load the currently selected map
-	mw.loadCurrentMap()
+	// Update window title, dropping a link the new file can't keep
+	mw.ecuVersion, _ = reader.IdentifyECU(filename)
+	mw.checkLinkedFile()
+
+	// Show part/Bosch/software numbers as the subtitle
+	label, tooltip := "", ""
+	if id, err := reader.IdentifyBinary(filename); err == nil {
+		label = id.Label()
+		tooltip = provenanceTooltip(id)
+		if label == "" && id.Provenance != nil {
+			label = i18n.T("gui.provenance.label")
+		}
+	}
+	mw.subtitleLabel.SetText(label)
+	mw.subtitleLabel.SetTooltipText(tooltip)
+	mw.subtitleLabel.SetVisible(label != "")
 
 	// Refresh config parameter values
 	mw.refreshConfigValues()
+	mw.refreshCompareParams()
+
+	// Refresh backup timeline
+	mw.refreshTimeline()
+
+	// The log overlay belongs to the previous file
+	mw.overlayToggle.SetActive(false)
 
 	// Update status
-	mw.statusBar.SetText(fmt.Sprintf("Loaded: %s", filename))
+	mw.logInfo(i18n.T("gui.loaded_ecu"), filename, mw.ecuVersion)
 }
 
-// loadCurrentMap loads the currently selected map from the file
-func (mw *MainWindow) loadCurrentMap() {
-	if mw.currentFile == "" {
+// applyLayout moves the definitions to the image of filename, for dumps
+// with a reader header or the image in an upper bank
+func (mw *MainWindow) applyLayout(filename string) {
+	layout, err := reader.FileLayout(filename)
+	if err != nil {
 		return
 	}
-
-	if mw.selectedMapIdx >= len(models.MapConfigs) {
-		return
+	if layout.Reason != "" {
+		mw.logInfo(i18n.T("gui.layout"), filepath.Base(filename), layout.BaseOffset, layout.Reason)
 	}
+	models.UseBaseOffset(layout.BaseOffset)
+}
 
-	mapConfig := models.MapConfigs[mw.selectedMapIdx]
-
-	// Read the map
-	ecuMap, err := reader.ReadMap(mw.currentFile, mapConfig)
-	if err != nil {
-		mw.showErrorDialog(fmt.Sprintf("Error reading map: %v", err))
+// loadCurrentMap reloads the currently selected map from the open file
+// and the comparison file
+func (mw *MainWindow) loadCurrentMap() {
+	if mw.CurrentFile == "" {
 		return
 	}
-
-	mw.currentMap = ecuMap
-
-	// If in comparison mode, load comparison map too
-	if mw.compareFile != "" {
-		compareMap, err := reader.ReadMap(mw.compareFile, mapConfig)
-		if err != nil {
-			mw.showErrorDialog(fmt.Sprintf("Error reading comparison map: %v", err))
-			return
-		}
-		mw.compareMap = compareMap
-	}
-
-	// Redraw
-	mw.mapDrawArea.QueueDraw()
+	mw.loadView(mw.CurrentFile, mw.CompareFile)
+	mw.refreshOutliers()
 }
 
 // onMapSelected handles map selection from sidebar
@@ -382,43 +717,45 @@ func (mw *MainWindow) onMapSelected(row *gtk.ListBoxRow) {
 	mw.loadCurrentMap()
 }
 
-// showErrorDialog displays an error message
-func (mw *MainWindow) showErrorDialog(message string) {
-	dialog := gtk.NewMessageDialog(
-		&mw.window.Window,
-		gtk.DialogModal,
-		gtk.MessageError,
-		gtk.ButtonsOK,
-	)
-	dialog.SetMarkup(fmt.Sprintf("<b>Error</b>\n\n%s", message))
-	dialog.ConnectResponse(func(response int) {
-		dialog.Destroy()
-	})
-	dialog.Show()
-}
-
 // showAboutDialog displays the about dialog
 func (mw *MainWindow) showAboutDialog() {
 	about := gtk.NewAboutDialog()
 	about.SetTransientFor(&mw.window.Window)
-	about.SetProgramName("Motronic M2.1 ECU Tool")
-	about.SetVersion("1.0.0")
-	about.SetComments("Read, analyze, and edit Motronic M2.1 ECU binary files")
+	about.SetProgramName(i18n.T("gui.title"))
+	about.SetVersion(version.Version)
+	comments := i18n.T("gui.about.comments")
+	if mw.binDir != "" {
+		comments += i18n.T("gui.about.bin_dir", mw.binDir, mw.binDirSource)
+	} else {
+		comments += i18n.T("gui.about.no_bin_dir", settings.EnvBinDir)
+	}
+	about.SetComments(comments)
 	about.SetWebsite("https://github.com/tosih/motronic-m21-tool")
 	about.SetAuthors([]string{"Motronic M2.1 Tool Contributors"})
 	about.SetLicense("MIT License")
 	about.Show()
 }
 
-// loadAvailableFiles scans the bins directory for .bin files
+// loadAvailableFiles lists the binary directory's .bin files in the file
+// dropdown and opens the first one
 func (mw *MainWindow) loadAvailableFiles() {
+	mw.findAvailableFiles()
+
+	// Auto-load the first file if available
+	if len(mw.availableFiles) > 0 {
+		mw.fileDropdown.SetSelected(0)
+	}
+}
+
+// findAvailableFiles scans the binary directory for .bin files and lists
+// them in the file dropdown
+func (mw *MainWindow) findAvailableFiles() {
 	mw.availableFiles = []string{}
 
-	// Check if bins directory exists
-	binsDir := "bins"
+	binsDir := mw.binDir
 	entries, err := os.ReadDir(binsDir)
 	if err != nil {
-		// No bins directory, show empty dropdown
+		// No binary directory, show empty dropdown
 		mw.updateFileDropdown()
 		return
 	}
@@ -437,18 +774,13 @@ func (mw *MainWindow) loadAvailableFiles() {
 
 	// Update the dropdown with the files
 	mw.updateFileDropdown()
-
-	// Auto-load the first file if available
-	if len(mw.availableFiles) > 0 {
-		mw.fileDropdown.SetSelected(0)
-	}
 }
 
 // updateFileDropdown updates the dropdown with available files
 func (mw *MainWindow) updateFileDropdown() {
 	if len(mw.availableFiles) == 0 {
 		// Create a model with just a placeholder
-		model := gtk.NewStringList([]string{"No ECU files found in bins/"})
+		model := gtk.NewStringList([]string{i18n.T("gui.no_files")})
 		mw.fileDropdown.SetModel(model)
 		mw.fileDropdown.SetSensitive(false)
 		return
@@ -473,7 +805,7 @@ func (mw *MainWindow) onFileSelected() {
 	}
 
 	filename := mw.availableFiles[selected]
-	if filename != mw.currentFile {
+	if filename != mw.CurrentFile {
 		mw.loadECUFile(filename)
 	}
 }
@@ -0,0 +1,107 @@
+package gui
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/export"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// XDFFile, MapsFile and MapsMode are the --xdf, --maps and --maps-mode
+// options, applied when the main window opens like the CLI's -xdf and -maps.
+// Profile is the --profile option, then the profile chosen in the header
+// bar; the profile's definitions are applied first.
+var (
+	XDFFile  string
+	MapsFile string
+	MapsMode = editor.MapsAppend
+	Profile  string
+)
+
+// loadProfiles adds the profile files to models.Profiles once, however
+// many windows are opened
+var loadProfiles = sync.OnceValue(editor.ApplyProfiles)
+
+// applyStartupDefinitions loads the definitions files given on the command
+// line. The log doesn't exist yet, so it returns a function reporting the
+// outcome once the window is built.
+func applyStartupDefinitions() (report func(mw *MainWindow)) {
+	var x *export.XDF
+	var xdfErr, mapsErr, profileErr error
+	profilesErr := loadProfiles()
+	if Profile != "" {
+		_, profileErr = editor.SelectProfile(Profile, nil)
+	}
+	if XDFFile != "" {
+		x, xdfErr = export.ApplyXDF(XDFFile)
+	}
+	if MapsFile != "" {
+		mapsErr = editor.ApplyMapDefinitions(MapsFile, MapsMode)
+	}
+	params := len(models.ConfigParams)
+
+	return func(mw *MainWindow) {
+		if profilesErr != nil {
+			mw.logWarn(i18n.T("gui.profile.load_failed"), profilesErr)
+		}
+		if profileErr != nil {
+			mw.logError(i18n.T("gui.profile.invalid"), profileErr)
+		}
+		switch {
+		case xdfErr != nil:
+			mw.logError(i18n.T("gui.xdf.load_failed"), xdfErr)
+		case x != nil:
+			for _, line := range x.Warnings() {
+				mw.logWarn("%s", line)
+			}
+			mw.logInfo(i18n.T("gui.xdf.loaded"), len(x.Maps), params, filepath.Base(XDFFile))
+		}
+		if mapsErr != nil {
+			mw.logError(i18n.T("gui.maps.load_failed"), mapsErr)
+		}
+	}
+}
+
+// loadMapDefinitionsDialog picks a map definitions file and adds its maps.
+// Replacing the built-in maps needs --maps-mode replace at startup, since
+// open views address maps by position.
+func (mw *MainWindow) loadMapDefinitionsDialog() {
+	filter := gtk.NewFileFilter()
+	filter.SetName(i18n.T("gui.maps.filter"))
+	filter.AddSuffix("json")
+	filter.AddSuffix("yaml")
+	filter.AddSuffix("yml")
+
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle(i18n.T("gui.maps.title"))
+	dialog.SetDefaultFilter(filter)
+
+	ctx := context.Background()
+	dialog.Open(ctx, &mw.window.Window, func(res gio.AsyncResulter) {
+		file, err := dialog.OpenFinish(res)
+		if err != nil || file == nil {
+			return // User cancelled
+		}
+		mw.loadMapDefinitions(file.Path())
+	})
+}
+
+// loadMapDefinitions appends the maps of a definitions file to the
+// sidebar. A file with any invalid entry adds none.
+func (mw *MainWindow) loadMapDefinitions(path string) {
+	first := len(models.MapConfigs)
+	if err := editor.ApplyMapDefinitions(path, editor.MapsAppend); err != nil {
+		mw.logError(i18n.T("gui.maps.load_failed"), err)
+		return
+	}
+	for i := first; i < len(models.MapConfigs); i++ {
+		mw.appendMapRow(i, models.MapConfigs[i])
+	}
+	mw.logInfo(i18n.T("gui.maps.loaded"), len(models.MapConfigs)-first, filepath.Base(path))
+}
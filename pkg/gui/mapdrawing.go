@@ -6,6 +6,12 @@ import (
 
 	"github.com/diamondburned/gotk4/pkg/cairo"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/compare"
+	"github.com/tosih/motronic-m21-tool/pkg/maplayout"
+	"github.com/tosih/motronic-m21-tool/pkg/mapview"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/renderer"
 )
 
 // isDarkMode checks if the current theme is dark
@@ -25,12 +31,28 @@ func (mw *MainWindow) getThemeColors() (textR, textG, textB, bgR, bgG, bgB float
 	return 0.1, 0.1, 0.1, 0.95, 0.95, 0.95
 }
 
-// drawMapFunc is the drawing callback for the map visualization
+// drawMapFunc is the drawing callback for the map visualization. It draws
+// the data selected by the map source, which is the current map unless a
+// comparison is being cycled through, from one snapshot of the view state.
 func (mw *MainWindow) drawMapFunc(area *gtk.DrawingArea, cr *cairo.Context, width, height int) {
-	if mw.currentMap == nil {
+	v := mw.State
+	if v.CurrentMap == nil {
+		mw.drawnLayout = maplayout.Layout{}
 		mw.drawEmptyState(cr, width, height)
 		return
 	}
+	mw.drawMap(cr, width, height, v)
+}
+
+// drawMap draws the map v shows as a heatmap, with its axes, legend and
+// overlays
+func (mw *MainWindow) drawMap(cr *cairo.Context, width, height int, v mapview.State) {
+	title := v.CurrentMap.Config.Name
+	if v.CompareMap != nil {
+		title = i18n.T("gui.source.title", title, i18n.T(mapSourceLabels[v.Source]))
+	}
+	m := v.Displayed()
+	scale := v.Scale(m)
 
 	// Get theme colors
 	textR, textG, textB, bgR, bgG, bgB := mw.getThemeColors()
@@ -40,45 +62,105 @@ func (mw *MainWindow) drawMapFunc(area *gtk.DrawingArea, cr *cairo.Context, widt
 	cr.Paint()
 
 	// Calculate cell dimensions
-	rows := mw.currentMap.Config.Rows
-	cols := mw.currentMap.Config.Cols
+	rows := m.Config.Rows
+	cols := m.Config.Cols
 
-	marginLeft := 80.0
-	marginRight := 100.0
-	marginTop := 60.0
-	marginBottom := 80.0
-
-	availableWidth := float64(width) - marginLeft - marginRight
-	availableHeight := float64(height) - marginTop - marginBottom
+	layout := maplayout.New(width, height, rows, cols)
+	mw.drawnLayout = layout
+	if !layout.Valid() {
+		return
+	}
 
-	cellWidth := availableWidth / float64(cols)
-	cellHeight := availableHeight / float64(rows)
+	marginLeft := layout.MarginLeft
+	marginTop := layout.MarginTop
+	availableWidth := layout.GridWidth()
+	availableHeight := layout.GridHeight()
+	cellWidth, _ := layout.CellSize()
 
 	// Draw title
 	cr.SetSourceRGB(textR, textG, textB)
 	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightBold)
 	cr.SetFontSize(16)
 	cr.MoveTo(marginLeft, 30)
-	cr.ShowText(mw.currentMap.Config.Name)
+	cr.ShowText(title)
 
 	// Draw unit
 	cr.SetFontSize(12)
 	cr.MoveTo(marginLeft, 48)
-	cr.ShowText(fmt.Sprintf("Unit: %s", mw.currentMap.Config.Unit))
+	cr.ShowText(i18n.T("gui.map.unit", m.Config.Unit))
 
-	// Find min/max for color scaling
-	minVal, maxVal := mw.findMinMax(mw.currentMap.Data)
+	if m.Config.IsCurve() {
+		mw.drawCurve(cr, layout, m, scale)
+	} else {
+		mw.drawCells(cr, layout, m, scale)
+	}
 
-	// Draw cells
-	for row := 0; row < rows; row++ {
-		for col := 0; col < cols; col++ {
-			x := marginLeft + float64(col)*cellWidth
-			y := marginTop + float64(row)*cellHeight
+	// Draw RPM axis (horizontal)
+	cr.SetSourceRGB(textR, textG, textB)
+	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightBold)
+	cr.SetFontSize(11)
 
-			value := mw.currentMap.Data[row][col]
+	// Breakpoints label the cell they belong to; the synthetic axis labels
+	// the cell edges from 0 to MaxRPM
+	ticks := cols + 1
+	if len(m.XAxis) == cols {
+		ticks = cols
+	}
+	for col := 0; col < ticks; col++ {
+		x := marginLeft + float64(col)*cellWidth
+		text := fmt.Sprintf("%d", int(float64(col)/float64(cols)*models.MaxRPM))
+		if len(m.XAxis) == cols {
+			x += cellWidth / 2
+			text = models.AxisLabel(m.XAxis[col])
+		}
+
+		extents := cr.TextExtents(text)
+		cr.MoveTo(x-extents.Width/2, marginTop+availableHeight+20)
+		cr.ShowText(text)
+
+		// Draw tick mark
+		cr.MoveTo(x, marginTop+availableHeight)
+		cr.LineTo(x, marginTop+availableHeight+5)
+		cr.Stroke()
+	}
+
+	// Column axis caption
+	cr.SetFontSize(12)
+	text := m.Config.XAxisName()
+	extents := cr.TextExtents(text)
+	cr.MoveTo(marginLeft+availableWidth/2-extents.Width/2, float64(height)-20)
+	cr.ShowText(text)
+
+	if !m.Config.IsCurve() {
+		mw.drawLoadAxis(cr, layout, m)
+	}
+
+	// Draw color legend
+	legendX, legendY, legendWidth, legendHeight := layout.LegendRect()
+	mw.drawColorLegend(cr, legendX, legendY, legendWidth, legendHeight, scale, m.Config.HighlightBelow)
+
+	mw.drawLogOverlay(cr, layout)
+	mw.drawQueryOverlay(cr, layout)
+	mw.drawOutlierOverlay(cr, layout)
+
+	// If in comparison mode, draw differences
+	if v.CompareMap != nil {
+		mw.drawComparisonOverlay(cr, layout, v)
+	}
+}
+
+// drawCells draws every cell of a grid map as a heatmap square with its
+// value
+func (mw *MainWindow) drawCells(cr *cairo.Context, layout maplayout.Layout, m *models.ECUMap, scale models.HeatScale) {
+	cellWidth, cellHeight := layout.CellSize()
+	for row := 0; row < layout.Rows; row++ {
+		for col := 0; col < layout.Cols; col++ {
+			x, y := layout.CellOrigin(row, col)
+
+			value := m.Data[row][col]
 
 			// Determine color based on value (heatmap)
-			r, g, b := mw.valueToColor(value, minVal, maxVal)
+			r, g, b := maplayout.HeatColor(scale.Normalize(value))
 
 			// Fill cell
 			cr.Rectangle(x, y, cellWidth, cellHeight)
@@ -114,42 +196,125 @@ func (mw *MainWindow) drawMapFunc(area *gtk.DrawingArea, cr *cairo.Context, widt
 
 			cr.MoveTo(textX, textY)
 			cr.ShowText(text)
+
+			// Dot in the corner of cells below the map's highlight threshold
+			if m.Config.BelowThreshold(value) {
+				cr.Arc(x+cellWidth-6, y+6, 3, 0, 2*math.Pi)
+				cr.Fill()
+			}
 		}
 	}
+}
+
+// drawCurve draws a one-row map as a line across its columns, against a
+// value axis from the curve's smallest to its largest value. Each column
+// still spans the plot's full height, so clicking anywhere above a point
+// edits it; faint column borders show those bounds.
+func (mw *MainWindow) drawCurve(cr *cairo.Context, layout maplayout.Layout, m *models.ECUMap, scale models.HeatScale) {
+	textR, textG, textB, _, _, _ := mw.getThemeColors()
+	cellWidth, _ := layout.CellSize()
+	top, height := layout.MarginTop, layout.GridHeight()
+	values := m.Data[0]
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
 
-	// Draw RPM axis (horizontal)
+	// Room for the value text above the highest point
+	const pad = 24.0
+	valueY := func(v float64) float64 {
+		if hi == lo {
+			return top + height/2
+		}
+		return top + height - pad - (v-lo)/(hi-lo)*(height-2*pad)
+	}
+	pointAt := func(col int) (float64, float64) {
+		x, _ := layout.CellOrigin(0, col)
+		return x + cellWidth/2, valueY(values[col])
+	}
+
+	// Column borders and frame
+	cr.SetSourceRGBA(textR, textG, textB, 0.15)
+	cr.SetLineWidth(1)
+	for col := 1; col < layout.Cols; col++ {
+		x, _ := layout.CellOrigin(0, col)
+		cr.MoveTo(x, top)
+		cr.LineTo(x, top+height)
+	}
+	cr.Stroke()
 	cr.SetSourceRGB(textR, textG, textB)
-	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightBold)
-	cr.SetFontSize(11)
+	cr.Rectangle(layout.MarginLeft, top, layout.GridWidth(), height)
+	cr.Stroke()
 
-	for col := 0; col <= cols; col++ {
-		x := marginLeft + float64(col)*cellWidth
-		rpm := int(float64(col) / float64(cols) * 8000)
+	cr.SetSourceRGB(0.4, 0.5, 0.9)
+	cr.SetLineWidth(2)
+	for col := range values {
+		px, py := pointAt(col)
+		if col == 0 {
+			cr.MoveTo(px, py)
+		} else {
+			cr.LineTo(px, py)
+		}
+	}
+	cr.Stroke()
+
+	// Points colored like heatmap cells, each with its value
+	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightNormal)
+	cr.SetFontSize(10)
+	for col, value := range values {
+		px, py := pointAt(col)
+		r, g, b := maplayout.HeatColor(scale.Normalize(value))
+		cr.SetSourceRGB(r, g, b)
+		cr.Arc(px, py, 5, 0, 2*math.Pi)
+		cr.Fill()
 
-		text := fmt.Sprintf("%d", rpm)
+		cr.SetSourceRGB(textR, textG, textB)
+		text := fmt.Sprintf("%.2f", value)
 		extents := cr.TextExtents(text)
-		cr.MoveTo(x-extents.Width/2, marginTop+availableHeight+20)
+		cr.MoveTo(px-extents.Width/2, py-10)
 		cr.ShowText(text)
 
-		// Draw tick mark
-		cr.MoveTo(x, marginTop+availableHeight)
-		cr.LineTo(x, marginTop+availableHeight+5)
-		cr.Stroke()
+		// Dot below points under the map's highlight threshold
+		if m.Config.BelowThreshold(value) {
+			cr.Arc(px, py+12, 3, 0, 2*math.Pi)
+			cr.Fill()
+		}
 	}
 
-	// RPM label
+	// Value axis: the curve's range, captioned with the unit
+	for _, v := range []float64{lo, hi} {
+		y := valueY(v)
+		text := fmt.Sprintf("%.2f", v)
+		extents := cr.TextExtents(text)
+		cr.MoveTo(layout.MarginLeft-extents.Width-10, y+extents.Height/2)
+		cr.ShowText(text)
+
+		cr.MoveTo(layout.MarginLeft-5, y)
+		cr.LineTo(layout.MarginLeft, y)
+		cr.Stroke()
+	}
+	cr.Save()
+	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightBold)
 	cr.SetFontSize(12)
-	text := "RPM"
-	extents := cr.TextExtents(text)
-	cr.MoveTo(marginLeft+availableWidth/2-extents.Width/2, float64(height)-20)
-	cr.ShowText(text)
+	cr.Translate(20, top+height/2)
+	cr.Rotate(-math.Pi / 2)
+	extents := cr.TextExtents(m.Config.Unit)
+	cr.MoveTo(-extents.Width/2, 0)
+	cr.ShowText(m.Config.Unit)
+	cr.Restore()
+}
 
-	// Draw Load axis (vertical)
-	for row := 0; row <= rows; row++ {
-		y := marginTop + float64(row)*cellHeight
-		load := int(100 - float64(row)/float64(rows)*100)
+// drawLoadAxis labels the rows of a grid map, one label per row like the
+// CLI and CSV
+func (mw *MainWindow) drawLoadAxis(cr *cairo.Context, layout maplayout.Layout, m *models.ECUMap) {
+	marginLeft, marginTop := layout.MarginLeft, layout.MarginTop
+	_, cellHeight := layout.CellSize()
+	rowLabels := m.RowLabels()
+	for row := 0; row < layout.Rows; row++ {
+		y := marginTop + (float64(row)+0.5)*cellHeight
 
-		text := fmt.Sprintf("%d%%", load)
+		text := rowLabels[row]
 		extents := cr.TextExtents(text)
 		cr.MoveTo(marginLeft-extents.Width-10, y+extents.Height/2)
 		cr.ShowText(text)
@@ -160,23 +325,18 @@ func (mw *MainWindow) drawMapFunc(area *gtk.DrawingArea, cr *cairo.Context, widt
 		cr.Stroke()
 	}
 
-	// Load label (rotated)
+	// Row axis caption (rotated): the map's own axis, or load
 	cr.Save()
-	cr.Translate(20, marginTop+availableHeight/2)
+	cr.Translate(20, marginTop+layout.GridHeight()/2)
 	cr.Rotate(-math.Pi / 2)
-	text = "Load"
-	extents = cr.TextExtents(text)
+	text := i18n.T("gui.map.load_axis")
+	if m.Config.YAxis != nil {
+		text = m.Config.YAxisName()
+	}
+	extents := cr.TextExtents(text)
 	cr.MoveTo(-extents.Width/2, 0)
 	cr.ShowText(text)
 	cr.Restore()
-
-	// Draw color legend
-	mw.drawColorLegend(cr, float64(width)-marginRight+20, marginTop, 60, availableHeight, minVal, maxVal)
-
-	// If in comparison mode, draw differences
-	if mw.compareMap != nil {
-		mw.drawComparisonOverlay(cr, marginLeft, marginTop, cellWidth, cellHeight, rows, cols)
-	}
 }
 
 // drawEmptyState draws a message when no file is loaded
@@ -191,71 +351,22 @@ func (mw *MainWindow) drawEmptyState(cr *cairo.Context, width, height int) {
 	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightNormal)
 	cr.SetFontSize(20)
 
-	text := "No ECU file loaded"
+	text := i18n.T("gui.map.empty")
 	extents := cr.TextExtents(text)
 	cr.MoveTo(float64(width)/2-extents.Width/2, float64(height)/2)
 	cr.ShowText(text)
 
 	cr.SetFontSize(14)
-	text = "Click 'Open ECU File' to begin"
+	text = i18n.T("gui.map.empty_hint")
 	extents = cr.TextExtents(text)
 	cr.MoveTo(float64(width)/2-extents.Width/2, float64(height)/2+30)
 	cr.ShowText(text)
 }
 
-// valueToColor converts a value to RGB color for heatmap visualization
-func (mw *MainWindow) valueToColor(value, minVal, maxVal float64) (float64, float64, float64) {
-	// Normalize value to 0-1 range
-	normalized := (value - minVal) / (maxVal - minVal)
-	if math.IsNaN(normalized) {
-		normalized = 0.5
-	}
-
-	// Use a blue -> cyan -> green -> yellow -> red gradient
-	if normalized < 0.25 {
-		// Blue to Cyan
-		t := normalized / 0.25
-		return 0, t, 1
-	} else if normalized < 0.5 {
-		// Cyan to Green
-		t := (normalized - 0.25) / 0.25
-		return 0, 1, 1 - t
-	} else if normalized < 0.75 {
-		// Green to Yellow
-		t := (normalized - 0.5) / 0.25
-		return t, 1, 0
-	} else {
-		// Yellow to Red
-		t := (normalized - 0.75) / 0.25
-		return 1, 1 - t, 0
-	}
-}
-
-// findMinMax finds the minimum and maximum values in the map data
-func (mw *MainWindow) findMinMax(data [][]float64) (float64, float64) {
-	if len(data) == 0 || len(data[0]) == 0 {
-		return 0, 1
-	}
-
-	minVal := data[0][0]
-	maxVal := data[0][0]
-
-	for _, row := range data {
-		for _, val := range row {
-			if val < minVal {
-				minVal = val
-			}
-			if val > maxVal {
-				maxVal = val
-			}
-		}
-	}
-
-	return minVal, maxVal
-}
-
-// drawColorLegend draws a color legend on the right side
-func (mw *MainWindow) drawColorLegend(cr *cairo.Context, x, y, width, height, minVal, maxVal float64) {
+// drawColorLegend draws a color legend on the right side, labelled at the
+// CLI heatmap band boundaries and captioned with the scaling mode. A
+// non-nil threshold is marked with a line and dot.
+func (mw *MainWindow) drawColorLegend(cr *cairo.Context, x, y, width, height float64, scale models.HeatScale, threshold *float64) {
 	textR, textG, textB, _, _, _ := mw.getThemeColors()
 
 	// Draw gradient bar
@@ -263,8 +374,7 @@ func (mw *MainWindow) drawColorLegend(cr *cairo.Context, x, y, width, height, mi
 	stepHeight := height / float64(numSteps)
 
 	for i := 0; i < numSteps; i++ {
-		value := minVal + (maxVal-minVal)*float64(numSteps-i)/float64(numSteps)
-		r, g, b := mw.valueToColor(value, minVal, maxVal)
+		r, g, b := maplayout.HeatColor(float64(numSteps-i) / float64(numSteps))
 
 		cr.Rectangle(x, y+float64(i)*stepHeight, width, stepHeight)
 		cr.SetSourceRGB(r, g, b)
@@ -282,11 +392,11 @@ func (mw *MainWindow) drawColorLegend(cr *cairo.Context, x, y, width, height, mi
 	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightNormal)
 	cr.SetFontSize(10)
 
-	for i := 0; i <= 4; i++ {
-		labelY := y + float64(i)*height/4
-		value := maxVal - (maxVal-minVal)*float64(i)/4
+	bounds := scale.BandBoundaries(renderer.HeatmapBands)
+	for i, value := range bounds {
+		labelY := y + height - float64(i)*height/renderer.HeatmapBands
 
-		text := fmt.Sprintf("%.1f", value)
+		text := scale.Format(value)
 		extents := cr.TextExtents(text)
 		cr.MoveTo(x+width+5, labelY+extents.Height/2)
 		cr.ShowText(text)
@@ -296,22 +406,41 @@ func (mw *MainWindow) drawColorLegend(cr *cairo.Context, x, y, width, height, mi
 		cr.LineTo(x+width+4, labelY)
 		cr.Stroke()
 	}
+
+	// Scaling mode above the bar, so screenshots show how colors were
+	// assigned
+	text := scale.Label()
+	extents := cr.TextExtents(text)
+	cr.MoveTo(x+width+15-extents.Width, y-10)
+	cr.ShowText(text)
+
+	if threshold != nil && !scale.Flat() && *threshold > scale.Min() && *threshold <= scale.Max() {
+		thresholdY := y + height - scale.Normalize(*threshold)*height
+		cr.MoveTo(x, thresholdY)
+		cr.LineTo(x+width, thresholdY)
+		cr.SetLineWidth(2)
+		cr.Stroke()
+		cr.Arc(x-6, thresholdY, 3, 0, 2*math.Pi)
+		cr.Fill()
+	}
 }
 
 // drawComparisonOverlay draws comparison indicators when comparing two files
-func (mw *MainWindow) drawComparisonOverlay(cr *cairo.Context, marginLeft, marginTop, cellWidth, cellHeight float64, rows, cols int) {
-	if mw.compareMap == nil {
+func (mw *MainWindow) drawComparisonOverlay(cr *cairo.Context, layout maplayout.Layout, v mapview.State) {
+	if v.CompareMap == nil {
 		return
 	}
 
-	for row := 0; row < rows; row++ {
-		for col := 0; col < cols; col++ {
-			originalValue := mw.currentMap.Data[row][col]
-			compareValue := mw.compareMap.Data[row][col]
+	tolerance := compare.DefaultTolerance(v.CurrentMap.Config)
+	cellWidth, _ := layout.CellSize()
 
-			if math.Abs(originalValue-compareValue) > 0.01 {
-				x := marginLeft + float64(col)*cellWidth
-				y := marginTop + float64(row)*cellHeight
+	for row := 0; row < layout.Rows; row++ {
+		for col := 0; col < layout.Cols; col++ {
+			originalValue := v.CurrentMap.Data[row][col]
+			compareValue := v.CompareMap.Data[row][col]
+
+			if math.Abs(originalValue-compareValue) > tolerance {
+				x, y := layout.CellOrigin(row, col)
 
 				// Draw a small indicator in the corner
 				diff := compareValue - originalValue
@@ -334,37 +463,19 @@ func (mw *MainWindow) drawComparisonOverlay(cr *cairo.Context, marginLeft, margi
 	}
 }
 
-// getCellAtPosition returns the row and column for a given mouse position
-func (mw *MainWindow) getCellAtPosition(x, y float64, width, height int) (row, col int, valid bool) {
-	if mw.currentMap == nil {
-		return 0, 0, false
-	}
-
-	rows := mw.currentMap.Config.Rows
-	cols := mw.currentMap.Config.Cols
-
-	marginLeft := 80.0
-	marginRight := 100.0
-	marginTop := 60.0
-	marginBottom := 80.0
-
-	availableWidth := float64(width) - marginLeft - marginRight
-	availableHeight := float64(height) - marginTop - marginBottom
-
-	cellWidth := availableWidth / float64(cols)
-	cellHeight := availableHeight / float64(rows)
-
-	// Check if click is within map area
-	if x < marginLeft || x > marginLeft+availableWidth || y < marginTop || y > marginTop+availableHeight {
+// getCellAtPosition returns the row and column under a pointer position,
+// using the layout the map was last drawn with so the cell matches what is
+// on screen. Before the current map's first draw it falls back to the
+// widget's allocated size.
+func (mw *MainWindow) getCellAtPosition(x, y float64) (row, col int, valid bool) {
+	if mw.CurrentMap == nil {
 		return 0, 0, false
 	}
 
-	col = int((x - marginLeft) / cellWidth)
-	row = int((y - marginTop) / cellHeight)
-
-	if row >= 0 && row < rows && col >= 0 && col < cols {
-		return row, col, true
+	layout := mw.drawnLayout
+	if layout.Rows != mw.CurrentMap.Config.Rows || layout.Cols != mw.CurrentMap.Config.Cols {
+		layout = maplayout.New(mw.mapDrawArea.AllocatedWidth(), mw.mapDrawArea.AllocatedHeight(),
+			mw.CurrentMap.Config.Rows, mw.CurrentMap.Config.Cols)
 	}
-
-	return 0, 0, false
+	return layout.CellAt(x, y)
 }
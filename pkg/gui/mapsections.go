@@ -0,0 +1,110 @@
+package gui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// mapSection is the collapsible group of sidebar rows of one category
+type mapSection struct {
+	name      string
+	header    *gtk.ListBoxRow
+	arrow     *gtk.Label
+	count     *gtk.Label
+	rows      []*gtk.ListBoxRow
+	collapsed bool
+}
+
+// categoryOrder is the position of a category among the sidebar sections
+func categoryOrder(name string) int {
+	if i := slices.Index(models.Categories, name); i >= 0 {
+		return i
+	}
+	return len(models.Categories)
+}
+
+// mapSection returns the section of category name, adding its header
+// before the first section that follows it
+func (mw *MainWindow) mapSection(name string) *mapSection {
+	if s, ok := mw.mapSections[name]; ok {
+		return s
+	}
+	s := &mapSection{name: name}
+
+	s.arrow = gtk.NewLabel("▾")
+	title := gtk.NewLabel(name)
+	title.SetXAlign(0)
+	title.SetHExpand(true)
+	s.count = gtk.NewLabel("")
+	s.count.AddCSSClass("map-detail")
+	box := gtk.NewBox(gtk.OrientationHorizontal, 5)
+	box.Append(s.arrow)
+	box.Append(title)
+	box.Append(s.count)
+
+	button := gtk.NewButton()
+	button.SetChild(box)
+	button.AddCSSClass("flat")
+	button.AddCSSClass("category-header")
+	button.ConnectClicked(func() {
+		mw.setSectionCollapsed(s, !s.collapsed)
+	})
+
+	s.header = gtk.NewListBoxRow()
+	s.header.SetChild(button)
+	s.header.SetSelectable(false)
+	s.header.SetActivatable(false)
+
+	position := -1
+	for _, other := range mw.mapSections {
+		if categoryOrder(other.name) > categoryOrder(name) && (position < 0 || other.header.Index() < position) {
+			position = other.header.Index()
+		}
+	}
+	mw.mapListView.Insert(s.header, position)
+	mw.mapSections[name] = s
+	return s
+}
+
+// addToSection inserts the row of map index i below the last row of the
+// section of the map's category
+func (mw *MainWindow) addToSection(i int, cfg models.MapConfig, row *gtk.ListBoxRow) {
+	s := mw.mapSection(cfg.CategoryName())
+	last := s.header
+	if len(s.rows) > 0 {
+		last = s.rows[len(s.rows)-1]
+	}
+	mw.mapListView.Insert(row, last.Index()+1)
+	row.SetVisible(!s.collapsed)
+	s.rows = append(s.rows, row)
+	s.count.SetText(fmt.Sprintf("%d", len(s.rows)))
+	mw.mapRows[i] = row
+}
+
+// setSectionCollapsed hides or shows the map rows of a section
+func (mw *MainWindow) setSectionCollapsed(s *mapSection, collapsed bool) {
+	s.collapsed = collapsed
+	s.arrow.SetText("▾")
+	if collapsed {
+		s.arrow.SetText("▸")
+	}
+	for _, row := range s.rows {
+		row.SetVisible(!collapsed)
+	}
+}
+
+// selectMapRow selects the sidebar row of map index i, expanding its
+// section if it is collapsed
+func (mw *MainWindow) selectMapRow(i int) {
+	row, ok := mw.mapRows[i]
+	if !ok {
+		return
+	}
+	if s, ok := mw.mapSections[models.MapConfigs[i].CategoryName()]; ok && s.collapsed {
+		mw.setSectionCollapsed(s, false)
+	}
+	mw.mapListView.SelectRow(row)
+}
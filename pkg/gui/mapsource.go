@@ -0,0 +1,42 @@
+package gui
+
+import (
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+)
+
+// mapSourceLabels are the catalog keys naming each mapview.Source, in order
+var mapSourceLabels = []string{
+	"gui.source.current",
+	"gui.source.compare",
+	"gui.source.delta",
+}
+
+// buildSourceButton creates the toolbar button that cycles the map view
+// between the current file, the comparison file and their difference. It
+// is only sensitive while a comparison map is loaded.
+func (mw *MainWindow) buildSourceButton() *gtk.Button {
+	button := gtk.NewButton()
+	button.SetTooltipText(i18n.T("gui.source.tooltip"))
+	button.ConnectClicked(func() { mw.cycleMapSource(1) })
+	mw.sourceButton = button
+	mw.updateSourceButton()
+	return button
+}
+
+// cycleMapSource moves the map view by steps through current, comparison
+// and delta and redraws it. Nothing is reloaded, so the hovered cell and
+// any overlays stay put.
+func (mw *MainWindow) cycleMapSource(steps int) {
+	if mw.CompareMap == nil {
+		return
+	}
+	mw.setView(mw.State.Cycled(steps))
+}
+
+// updateSourceButton labels the source button with the data shown and
+// disables it when there is nothing to compare with
+func (mw *MainWindow) updateSourceButton() {
+	mw.sourceButton.SetLabel(i18n.T("gui.source.button", i18n.T(mapSourceLabels[mw.Source])))
+	mw.sourceButton.SetSensitive(mw.CompareMap != nil)
+}
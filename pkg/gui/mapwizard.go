@@ -0,0 +1,429 @@
+package gui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/maplayout"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// wizardPages are the stack page names of the map wizard, in order
+var wizardPages = []string{"location", "shape", "scaling", "name"}
+
+// wizardByteOrders are the byte order choices, little-endian first as
+// the empty default so definitions only carry a big-endian order
+var wizardByteOrders = []models.Endianness{"", models.BigEndian}
+
+// wizardHexRows is how many 16-byte lines the offset preview shows
+const wizardHexRows = 8
+
+// mapWizard holds the inputs of the map definition wizard
+type mapWizard struct {
+	data []byte
+
+	offset             *gtk.SpinButton
+	rows, cols         *gtk.SpinButton
+	dataType           *gtk.DropDown
+	byteOrder          *gtk.DropDown
+	scale, valueOffset *gtk.SpinButton
+	unit, name, desc   *gtk.Entry
+
+	// Confirms partial overlaps with existing definitions, which are
+	// then recorded in the map's OverlapNote
+	confirmOverlap *gtk.CheckButton
+
+	// Keeps the axes suggested for a promoted scan hit; unchecked, the
+	// map is defined without axes
+	useAxes *gtk.CheckButton
+
+	// Category, flags and suggested axes of a promoted scan hit, kept as
+	// they were
+	prefill models.MapConfig
+}
+
+// candidate returns the definition the inputs describe
+func (wz *mapWizard) candidate() models.MapConfig {
+	cfg := models.MapConfig{
+		Category:    wz.prefill.Category,
+		Unconfirmed: wz.prefill.Unconfirmed,
+		Name:        strings.TrimSpace(wz.name.Text()),
+		Offset:      int64(wz.offset.ValueAsInt()),
+		Rows:        wz.rows.ValueAsInt(),
+		Cols:        wz.cols.ValueAsInt(),
+		DataType:    models.DataTypes[wz.dataType.Selected()],
+		Endianness:  wizardByteOrders[wz.byteOrder.Selected()],
+		Scale:       wz.scale.Value(),
+		Offset2:     wz.valueOffset.Value(),
+		Unit:        strings.TrimSpace(wz.unit.Text()),
+		Description: strings.TrimSpace(wz.desc.Text()),
+	}
+	if wz.useAxes != nil && wz.useAxes.Active() {
+		cfg.XAxis, cfg.YAxis = wz.prefill.XAxis, wz.prefill.YAxis
+	}
+	return cfg
+}
+
+// raws returns the raw values of the candidate's cells that lie inside
+// the file, row by row
+func (wz *mapWizard) raws() [][]int64 {
+	cfg := wz.candidate()
+	size := models.DataTypeSize(cfg.DataType)
+	cells := make([][]int64, cfg.Rows)
+	for i := range cells {
+		for j := 0; j < cfg.Cols; j++ {
+			at := cfg.Offset + int64((i*cfg.Cols+j)*size)
+			if at+int64(size) > int64(len(wz.data)) {
+				return cells
+			}
+			cells[i] = append(cells[i], cfg.DecodeRaw(wz.data[at:]))
+		}
+	}
+	return cells
+}
+
+// hexPreview formats the bytes at the offset as a hex dump
+func (wz *mapWizard) hexPreview() string {
+	var b strings.Builder
+	start := wz.offset.ValueAsInt() &^ 0xF
+	for line := 0; line < wizardHexRows; line++ {
+		at := start + line*16
+		if at >= len(wz.data) {
+			break
+		}
+		fmt.Fprintf(&b, "%04X ", at)
+		for i := at; i < min(at+16, len(wz.data)); i++ {
+			fmt.Fprintf(&b, " %02X", wz.data[i])
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// showMapWizard defines a new map by hand in four steps: offset with a hex
+// preview, shape and data type with a heatmap preview, scaling with a
+// two-point calibration helper, and name. The definition is checked with
+// models.CheckNewMap, saved to the user's definitions and selected.
+// Partial overlaps must be confirmed first. A non-nil prefill, such as a
+// scan hit's candidate, sets the initial inputs; its axes are offered on
+// the scaling page with axisNote, which says how confident the
+// suggestion is.
+func (mw *MainWindow) showMapWizard(prefill *models.MapConfig, axisNote string) {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+	data, err := mw.currentBytes()
+	if err != nil {
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return
+	}
+	wz := &mapWizard{data: data}
+	if prefill != nil {
+		wz.prefill = *prefill
+	}
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.wizard.title"))
+	dialog.SetDefaultSize(560, 480)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	stepLabel := gtk.NewLabel("")
+	stepLabel.AddCSSClass("param-name")
+	stepLabel.SetXAlign(0)
+	contentArea.Append(stepLabel)
+
+	stack := gtk.NewStack()
+	stack.SetVExpand(true)
+	contentArea.Append(stack)
+
+	statusLabel := gtk.NewLabel("")
+	statusLabel.SetXAlign(0)
+	statusLabel.SetWrap(true)
+	contentArea.Append(statusLabel)
+
+	newPage := func() *gtk.Box {
+		return gtk.NewBox(gtk.OrientationVertical, 8)
+	}
+	newRow := func(label string, widget gtk.Widgetter) *gtk.Box {
+		row := gtk.NewBox(gtk.OrientationHorizontal, 10)
+		l := gtk.NewLabel(label)
+		l.SetXAlign(0)
+		l.SetSizeRequest(120, -1)
+		row.Append(l)
+		row.Append(widget)
+		return row
+	}
+
+	// Step 1: offset
+	location := newPage()
+	wz.offset = gtk.NewSpinButtonWithRange(0, float64(max(len(data)-1, 0)), 1)
+	wz.offset.SetWidthChars(8)
+	wz.offset.ConnectOutput(func() bool {
+		wz.offset.SetText(fmt.Sprintf("0x%04X", wz.offset.ValueAsInt()))
+		return true
+	})
+	location.Append(newRow(i18n.T("gui.wizard.offset"), wz.offset))
+	hexLabel := gtk.NewLabel("")
+	hexLabel.AddCSSClass("current-value")
+	hexLabel.SetXAlign(0)
+	hexLabel.SetSelectable(true)
+	location.Append(hexLabel)
+	stack.AddNamed(location, wizardPages[0])
+
+	// Step 2: shape and data type
+	shape := newPage()
+	wz.rows = gtk.NewSpinButtonWithRange(1, 32, 1)
+	wz.rows.SetValue(8)
+	wz.cols = gtk.NewSpinButtonWithRange(1, 32, 1)
+	wz.cols.SetValue(16)
+	shape.Append(newRow(i18n.T("gui.wizard.rows"), wz.rows))
+	shape.Append(newRow(i18n.T("gui.wizard.cols"), wz.cols))
+	wz.dataType = gtk.NewDropDownFromStrings(models.DataTypes)
+	shape.Append(newRow(i18n.T("gui.wizard.data_type"), wz.dataType))
+	wz.byteOrder = gtk.NewDropDownFromStrings([]string{i18n.T("gui.wizard.little_endian"), i18n.T("gui.wizard.big_endian")})
+	shape.Append(newRow(i18n.T("gui.wizard.byte_order"), wz.byteOrder))
+	endianLabel := gtk.NewLabel(i18n.T("gui.wizard.endianness"))
+	endianLabel.AddCSSClass("param-description")
+	endianLabel.SetXAlign(0)
+	shape.Append(endianLabel)
+	preview := gtk.NewDrawingArea()
+	preview.SetSizeRequest(320, 160)
+	preview.SetDrawFunc(func(_ *gtk.DrawingArea, cr *cairo.Context, w, h int) {
+		drawRawHeatmap(cr, float64(w), float64(h), wz.raws())
+	})
+	shape.Append(preview)
+	stack.AddNamed(shape, wizardPages[1])
+
+	// Step 3: scaling, with two known cells to calibrate from
+	scaling := newPage()
+	wz.scale = gtk.NewSpinButtonWithRange(-1e6, 1e6, 0.001)
+	wz.scale.SetDigits(6)
+	wz.scale.SetValue(1)
+	wz.valueOffset = gtk.NewSpinButtonWithRange(-1e6, 1e6, 0.1)
+	wz.valueOffset.SetDigits(3)
+	wz.unit = gtk.NewEntry()
+	scaling.Append(newRow(i18n.T("gui.wizard.scale"), wz.scale))
+	scaling.Append(newRow(i18n.T("gui.wizard.value_offset"), wz.valueOffset))
+	scaling.Append(newRow(i18n.T("gui.wizard.unit"), wz.unit))
+	scalePreview := gtk.NewLabel("")
+	scalePreview.SetXAlign(0)
+	scaling.Append(scalePreview)
+
+	calibration := gtk.NewExpander(i18n.T("gui.wizard.two_point"))
+	calibrationGrid := gtk.NewGrid()
+	calibrationGrid.SetColumnSpacing(10)
+	calibrationGrid.SetRowSpacing(5)
+	calibrationGrid.SetMarginTop(5)
+	var rawSpins, valueSpins [2]*gtk.SpinButton
+	for i := range rawSpins {
+		rawSpins[i] = gtk.NewSpinButtonWithRange(-32768, 65535, 1)
+		valueSpins[i] = gtk.NewSpinButtonWithRange(-1e6, 1e6, 0.1)
+		valueSpins[i].SetDigits(3)
+		calibrationGrid.Attach(gtk.NewLabel(i18n.T("gui.wizard.raw")), 0, i, 1, 1)
+		calibrationGrid.Attach(rawSpins[i], 1, i, 1, 1)
+		calibrationGrid.Attach(gtk.NewLabel(i18n.T("gui.wizard.reads_as")), 2, i, 1, 1)
+		calibrationGrid.Attach(valueSpins[i], 3, i, 1, 1)
+	}
+	rawSpins[1].SetValue(255)
+	valueSpins[1].SetValue(255)
+	calibrateButton := gtk.NewButtonWithLabel(i18n.T("gui.wizard.calibrate"))
+	calibrateButton.ConnectClicked(func() {
+		scale, offset, err := models.TwoPointScale(
+			int64(rawSpins[0].ValueAsInt()), valueSpins[0].Value(),
+			int64(rawSpins[1].ValueAsInt()), valueSpins[1].Value())
+		if err != nil {
+			statusLabel.SetText(i18n.T("gui.wizard.calibrate_failed", err))
+			statusLabel.AddCSSClass("warning-text")
+			return
+		}
+		wz.scale.SetValue(scale)
+		wz.valueOffset.SetValue(offset)
+	})
+	calibrationGrid.Attach(calibrateButton, 3, 2, 1, 1)
+	calibration.SetChild(calibrationGrid)
+	scaling.Append(calibration)
+	if prefill != nil && (prefill.XAxis != nil || prefill.YAxis != nil) {
+		wz.useAxes = gtk.NewCheckButtonWithLabel(i18n.T("gui.wizard.use_axes"))
+		wz.useAxes.SetActive(true)
+		scaling.Append(wz.useAxes)
+		axesLabel := gtk.NewLabel(axisNote)
+		axesLabel.AddCSSClass("param-description")
+		axesLabel.SetXAlign(0)
+		axesLabel.SetWrap(true)
+		scaling.Append(axesLabel)
+	}
+	stack.AddNamed(scaling, wizardPages[2])
+
+	// Step 4: name
+	naming := newPage()
+	wz.name = gtk.NewEntry()
+	wz.name.SetHExpand(true)
+	wz.desc = gtk.NewEntry()
+	wz.desc.SetHExpand(true)
+	naming.Append(newRow(i18n.T("gui.wizard.name"), wz.name))
+	naming.Append(newRow(i18n.T("gui.wizard.description"), wz.desc))
+	wz.confirmOverlap = gtk.NewCheckButtonWithLabel(i18n.T("gui.wizard.confirm_overlap"))
+	naming.Append(wz.confirmOverlap)
+	stack.AddNamed(naming, wizardPages[3])
+
+	if prefill != nil {
+		wz.offset.SetValue(float64(prefill.Offset))
+		wz.rows.SetValue(float64(prefill.Rows))
+		wz.cols.SetValue(float64(prefill.Cols))
+		for i, t := range models.DataTypes {
+			if t == prefill.DataType {
+				wz.dataType.SetSelected(uint(i))
+			}
+		}
+		if prefill.Endianness == models.BigEndian {
+			wz.byteOrder.SetSelected(1)
+		}
+		wz.scale.SetValue(prefill.Scale)
+		wz.valueOffset.SetValue(prefill.Offset2)
+		wz.unit.SetText(prefill.Unit)
+		wz.name.SetText(prefill.Name)
+		wz.desc.SetText(prefill.Description)
+	}
+
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	backButton := gtk.BaseWidget(dialog.AddButton(i18n.T("gui.wizard.back"), int(gtk.ResponseReject)))
+	nextButton := gtk.BaseWidget(dialog.AddButton(i18n.T("gui.wizard.next"), int(gtk.ResponseOK)))
+	createButton := gtk.BaseWidget(dialog.AddButton(i18n.T("gui.wizard.create"), int(gtk.ResponseAccept)))
+
+	page := 0
+	update := func() {
+		stack.SetVisibleChildName(wizardPages[page])
+		stepLabel.SetText(i18n.T("gui.wizard.step", page+1, len(wizardPages), i18n.T("gui.wizard.page."+wizardPages[page])))
+		backButton.SetSensitive(page > 0)
+		nextButton.SetVisible(page < len(wizardPages)-1)
+		createButton.SetVisible(page == len(wizardPages)-1)
+
+		hexLabel.SetText(wz.hexPreview())
+		preview.QueueDraw()
+		cfg := wz.candidate()
+		if raws := wz.raws(); len(raws) > 0 && len(raws[0]) > 0 {
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, row := range raws {
+				for _, raw := range row {
+					value := cfg.ToReal(raw)
+					lo, hi = math.Min(lo, value), math.Max(hi, value)
+				}
+			}
+			scalePreview.SetText(i18n.T("gui.wizard.scale_preview", raws[0][0], cfg.ToReal(raws[0][0]), cfg.Unit, lo, hi, cfg.Unit))
+		}
+
+		// Every step shows what the shared validator thinks so far; the
+		// name is only asked for on the last one
+		if cfg.Name == "" && page < len(wizardPages)-1 {
+			cfg.Name = i18n.T("gui.wizard.untitled")
+		}
+		check := models.CheckNewMap(cfg, models.MapConfigs, models.ConfigParams, int64(len(data)))
+		var problems []string
+		for _, err := range check.Errors {
+			problems = append(problems, err.Error())
+		}
+		for _, o := range check.Overlaps {
+			problems = append(problems, o.String())
+		}
+		statusLabel.SetText(strings.Join(problems, "\n"))
+		if check.OK() {
+			statusLabel.RemoveCSSClass("warning-text")
+		} else {
+			statusLabel.AddCSSClass("warning-text")
+		}
+		// Exact duplicates fail the check; partial overlaps need the box
+		partial := check.OK() && len(check.Overlaps) > 0
+		wz.confirmOverlap.SetVisible(partial)
+		createButton.SetSensitive(check.OK() && (!partial || wz.confirmOverlap.Active()))
+	}
+	for _, spin := range []*gtk.SpinButton{wz.offset, wz.rows, wz.cols, wz.scale, wz.valueOffset} {
+		spin.ConnectValueChanged(update)
+	}
+	for _, entry := range []*gtk.Entry{wz.unit, wz.name} {
+		entry.ConnectChanged(update)
+	}
+	wz.dataType.NotifyProperty("selected", update)
+	wz.byteOrder.NotifyProperty("selected", update)
+	wz.confirmOverlap.ConnectToggled(update)
+
+	dialog.ConnectResponse(func(responseID int) {
+		switch responseID {
+		case int(gtk.ResponseReject):
+			page--
+			update()
+			return
+		case int(gtk.ResponseOK):
+			page++
+			update()
+			return
+		case int(gtk.ResponseAccept):
+		default:
+			dialog.Destroy()
+			return
+		}
+
+		cfg := wz.candidate()
+		if overlaps := models.CheckNewMap(cfg, models.MapConfigs, models.ConfigParams, int64(len(data))).Overlaps; len(overlaps) > 0 {
+			cfg.OverlapNote = models.OverlapNote(overlaps)
+		}
+		check, err := editor.AddUserMap(cfg, int64(len(data)))
+		if err != nil {
+			mw.logError(i18n.T("gui.wizard.failed"), err)
+			return
+		}
+		for _, o := range check.Overlaps {
+			mw.logWarn("%s", o.String())
+		}
+		dialog.Destroy()
+
+		idx := len(models.MapConfigs) - 1
+		mw.appendMapRow(idx, cfg)
+		mw.selectMapRow(idx)
+		mw.logInfo(i18n.T("gui.wizard.created"), cfg.Name)
+	})
+
+	update()
+	dialog.Show()
+}
+
+// drawRawHeatmap fills the area with one cell per raw value, colored
+// between the smallest and largest value
+func drawRawHeatmap(cr *cairo.Context, w, h float64, raws [][]int64) {
+	if len(raws) == 0 || len(raws[0]) == 0 {
+		return
+	}
+	lo, hi := raws[0][0], raws[0][0]
+	for _, row := range raws {
+		for _, raw := range row {
+			lo, hi = min(lo, raw), max(hi, raw)
+		}
+	}
+
+	cellWidth, cellHeight := w/float64(len(raws[0])), h/float64(len(raws))
+	for i, row := range raws {
+		for j, raw := range row {
+			normalized := 0.5
+			if hi > lo {
+				normalized = float64(raw-lo) / float64(hi-lo)
+			}
+			r, g, b := maplayout.HeatColor(normalized)
+			cr.Rectangle(float64(j)*cellWidth, float64(i)*cellHeight, cellWidth, cellHeight)
+			cr.SetSourceRGB(r, g, b)
+			cr.Fill()
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/mapview"
+)
+
+// attachNudgeControllers lets +/- nudge the hovered cell of the map view
+// by the map's nudge step, and Tab/Shift+Tab cycle the compared data. The
+// map area takes keyboard focus when the pointer enters it, and the active
+// step is shown while it has focus.
+func (mw *MainWindow) attachNudgeControllers() {
+	mw.mapDrawArea.SetFocusable(true)
+
+	motion := gtk.NewEventControllerMotion()
+	motion.ConnectEnter(func(x, y float64) {
+		mw.mapDrawArea.GrabFocus()
+		mw.updateHoveredCell(x, y)
+	})
+	motion.ConnectMotion(mw.updateHoveredCell)
+	motion.ConnectLeave(func() { mw.hoverValid = false })
+	mw.mapDrawArea.AddController(motion)
+
+	focus := gtk.NewEventControllerFocus()
+	focus.ConnectEnter(mw.showNudgeStep)
+	mw.mapDrawArea.AddController(focus)
+
+	keys := gtk.NewEventControllerKey()
+	keys.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+		switch keyval {
+		case gdk.KEY_plus, gdk.KEY_equal, gdk.KEY_KP_Add:
+			mw.nudgeHoveredCell(1)
+		case gdk.KEY_minus, gdk.KEY_KP_Subtract:
+			mw.nudgeHoveredCell(-1)
+		case gdk.KEY_Tab:
+			mw.cycleMapSource(1)
+		case gdk.KEY_ISO_Left_Tab:
+			mw.cycleMapSource(-1)
+		default:
+			return false
+		}
+		return true
+	})
+	mw.mapDrawArea.AddController(keys)
+}
+
+// updateHoveredCell records the cell under the pointer as the nudge target
+func (mw *MainWindow) updateHoveredCell(x, y float64) {
+	if mw.CurrentMap == nil {
+		mw.hoverValid = false
+		return
+	}
+	mw.hoverRow, mw.hoverCol, mw.hoverValid = mw.getCellAtPosition(x, y)
+}
+
+// showNudgeStep puts the current map's nudge step in the status bar
+func (mw *MainWindow) showNudgeStep() {
+	if mw.CurrentMap == nil {
+		return
+	}
+	mw.statusBar.SetText(fmt.Sprintf("%s — nudge step %s (+/- on the hovered cell)",
+		mw.CurrentMap.Config.Name, mw.CurrentMap.Config.StepLabel()))
+}
+
+// nudgeHoveredCell moves the hovered cell by steps nudges and writes it
+func (mw *MainWindow) nudgeHoveredCell(steps int) {
+	if mw.CurrentMap == nil || mw.CurrentFile == "" || !mw.hoverValid {
+		return
+	}
+	// Only the current file's values are edited; don't nudge blind
+	if mw.CompareMap != nil && mw.Source != mapview.Current {
+		mw.logWarn("%s", i18n.T("gui.source.nudge_current"))
+		return
+	}
+	if !mw.checkMapEditable() {
+		return
+	}
+
+	cfg := mw.CurrentMap.Config
+	row, col := mw.hoverRow, mw.hoverCol
+	data, err := mw.currentBytes()
+	if err != nil {
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return
+	}
+	changes, err := editor.PlanNudge(data, cfg, row, col, steps)
+	if err != nil {
+		mw.reportEditError(i18n.T("gui.nudge.cannot"), err)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	c := changes[0]
+	mw.confirmThen(editor.ConfirmSave,
+		i18n.T("gui.nudge.confirm",
+			cfg.Name, row, col, c.OldValue, c.NewValue, cfg.Unit),
+		i18n.T("gui.nudge.write"),
+		func() {
+			backup, err := mw.applyChanges(fmt.Sprintf("Nudge %s [%d,%d]", cfg.Name, row, col), changes)
+			if err != nil {
+				mw.reportEditError(i18n.T("gui.nudge.failed"), err)
+				return
+			}
+			mw.logger.Info("Backup created", "path", backup)
+			mw.loadCurrentMap()
+			mw.logInfo("%s [%d,%d]: %.2f → %.2f %s (step %s)", cfg.Name, row, col, c.OldValue, c.NewValue, cfg.Unit, cfg.StepLabel())
+		})
+}
@@ -0,0 +1,63 @@
+package gui
+
+import (
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/maplayout"
+)
+
+// buildOutlierToggle creates the checkbox that outlines cells deviating
+// from their neighborhood median (editor.FindOutliers) on the current map
+func (mw *MainWindow) buildOutlierToggle() *gtk.CheckButton {
+	toggle := gtk.NewCheckButtonWithLabel(i18n.T("gui.outliers.toggle"))
+	toggle.SetTooltipText(i18n.T("gui.outliers.tooltip"))
+	toggle.ConnectToggled(func() {
+		mw.refreshOutliers()
+		mw.mapDrawArea.QueueDraw()
+	})
+	mw.outlierToggle = toggle
+	return toggle
+}
+
+// refreshOutliers recomputes the outliers of the current map with the
+// default threshold while the toggle is on, and clears them otherwise
+func (mw *MainWindow) refreshOutliers() {
+	mw.outliers = nil
+	if !mw.outlierToggle.Active() || mw.CurrentMap == nil {
+		return
+	}
+	threshold := editor.OutlierThreshold(mw.CurrentMap, 0)
+	mw.outliers = editor.FindOutliers(mw.CurrentMap, threshold)
+	cfg := mw.CurrentMap.Config
+	mw.logInfo(i18n.T("gui.outliers.found"), cfg.Name, len(mw.outliers), threshold, cfg.Unit)
+}
+
+// outlierAt returns the outlier at a cell, if it is one
+func (mw *MainWindow) outlierAt(row, col int) (editor.Outlier, bool) {
+	for _, o := range mw.outliers {
+		if o.Row == row && o.Col == col {
+			return o, true
+		}
+	}
+	return editor.Outlier{}, false
+}
+
+// drawOutlierOverlay outlines the outlier cells with a dashed orange frame
+func (mw *MainWindow) drawOutlierOverlay(cr *cairo.Context, layout maplayout.Layout) {
+	if len(mw.outliers) == 0 {
+		return
+	}
+	cellWidth, cellHeight := layout.CellSize()
+	cr.Save()
+	cr.SetSourceRGBA(1, 0.55, 0, 0.95)
+	cr.SetLineWidth(3)
+	cr.SetDash([]float64{6, 3}, 0)
+	for _, o := range mw.outliers {
+		x, y := layout.CellOrigin(o.Row, o.Col)
+		cr.Rectangle(x+1.5, y+1.5, cellWidth-3, cellHeight-3)
+		cr.Stroke()
+	}
+	cr.Restore()
+}
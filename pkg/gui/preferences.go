@@ -0,0 +1,310 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/settings"
+	"github.com/tosih/motronic-m21-tool/pkg/checksum"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// confirmPolicyLabels are the catalog keys describing
+// editor.ConfirmPolicies, in the same order
+var confirmPolicyLabels = []string{
+	"gui.prefs.policy.full",
+	"gui.prefs.policy.save",
+	"gui.prefs.policy.never",
+}
+
+// checksumPolicyLabels are the catalog keys describing
+// editor.ChecksumPolicies, in the same order
+var checksumPolicyLabels = []string{
+	"gui.prefs.checksum.ask",
+	"gui.prefs.checksum.always",
+	"gui.prefs.checksum.never",
+}
+
+// loadLocale selects the language from the saved settings or $LANG. It runs
+// before the window is built because labels are translated when created.
+func loadLocale() {
+	s, _ := settings.Load()
+	i18n.SetLocale(i18n.Detect(s.Locale))
+}
+
+// loadPreferences applies saved settings to the editor
+func (mw *MainWindow) loadPreferences() {
+	s, err := settings.Load()
+	if err != nil {
+		mw.logWarn(i18n.T("gui.prefs.load_failed"), err)
+	}
+	mw.applySnapshotSettings(s)
+	mw.applyChecksumSettings(s)
+	if s.RequireBackup && !reader.NoBackup {
+		reader.RequireBackup = true
+	}
+	editor.MirrorWrites = s.MirrorWrites
+	if s.ConfirmPolicy == "" {
+		return
+	}
+	policy, err := editor.ParseConfirmPolicy(s.ConfirmPolicy)
+	if err != nil {
+		mw.logWarn(i18n.T("gui.prefs.ignored"), err)
+		return
+	}
+	editor.Confirmation = policy
+}
+
+// applyChecksumSettings takes the profile's checksum and the checksum
+// policy from the settings. Saves can't wait for a dialog, so under the ask
+// policy a save leaves a stale checksum and then offers to fix it.
+func (mw *MainWindow) applyChecksumSettings(s *settings.Settings) {
+	if s.ChecksumSpec != "" {
+		if err := checksum.UseSpec(s.ChecksumSpec); err != nil {
+			mw.logWarn(i18n.T("gui.prefs.ignored"), err)
+		}
+	}
+	if s.ChecksumOnSave != "" {
+		policy, err := editor.ParseChecksumPolicy(s.ChecksumOnSave)
+		if err != nil {
+			mw.logWarn(i18n.T("gui.prefs.ignored"), err)
+		} else {
+			editor.ChecksumOnSave = policy
+		}
+	}
+
+	editor.PlanChecksum = checksum.SessionStatus
+	editor.AskChecksum = func(filename string, status editor.ChecksumStatus) bool {
+		if !editor.NeedsConfirm(editor.ConfirmSave) {
+			return true
+		}
+		runOnMain(func() { mw.offerChecksumFix(filename, status) })
+		return false
+	}
+}
+
+// offerChecksumFix asks whether to store the computed checksum a save left
+// stale
+func (mw *MainWindow) offerChecksumFix(filename string, status editor.ChecksumStatus) {
+	mw.logWarn(i18n.T("gui.checksum.stale"), status)
+	markup := fmt.Sprintf("<b>%s</b>\n\n%s", i18n.T("gui.checksum.stale_title"), glib.MarkupEscapeText(status.String()))
+	mw.confirmThen(editor.ConfirmSave, markup, i18n.T("gui.checksum.fix"), func() {
+		report, err := editor.FixChecksum(filename)
+		if err != nil {
+			mw.logError(i18n.T("gui.checksum.fix_failed"), err)
+			return
+		}
+		if report.Written {
+			mw.logInfo(i18n.T("gui.checksum.fixed"), status.Computed, status.Fix.Offset)
+		}
+	})
+}
+
+// showPreferencesDialog lets the user choose the confirmation policy, the
+// language, whether to take automatic snapshots, whether saves store
+// the image checksum and whether writes require a verified backup
+func (mw *MainWindow) showPreferencesDialog() {
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.menu.preferences"))
+	dialog.SetDefaultSize(400, 200)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	policyBox := gtk.NewBox(gtk.OrientationHorizontal, 10)
+	policyLabel := gtk.NewLabel(i18n.T("gui.prefs.confirmations"))
+	policyLabel.SetXAlign(0)
+	policyBox.Append(policyLabel)
+
+	policyNames := make([]string, len(confirmPolicyLabels))
+	for i, key := range confirmPolicyLabels {
+		policyNames[i] = i18n.T(key)
+	}
+	policyDropdown := gtk.NewDropDownFromStrings(policyNames)
+	policyDropdown.SetHExpand(true)
+	for i, p := range editor.ConfirmPolicies {
+		if p == editor.Confirmation {
+			policyDropdown.SetSelected(uint(i))
+		}
+	}
+	policyBox.Append(policyDropdown)
+	contentArea.Append(policyBox)
+
+	hintLabel := gtk.NewLabel(i18n.T("gui.prefs.hint"))
+	hintLabel.AddCSSClass("param-description")
+	hintLabel.SetWrap(true)
+	hintLabel.SetXAlign(0)
+	contentArea.Append(hintLabel)
+
+	// The first choice follows $LANG, the others are the catalogs
+	saved, _ := settings.Load()
+	locales := append([]string{""}, i18n.Locales()...)
+	localeNames := []string{i18n.T("gui.prefs.language_system")}
+	for _, locale := range locales[1:] {
+		localeNames = append(localeNames, i18n.Name(locale))
+	}
+
+	localeBox := gtk.NewBox(gtk.OrientationHorizontal, 10)
+	localeLabel := gtk.NewLabel(i18n.T("gui.prefs.language"))
+	localeLabel.SetXAlign(0)
+	localeBox.Append(localeLabel)
+	localeDropdown := gtk.NewDropDownFromStrings(localeNames)
+	localeDropdown.SetHExpand(true)
+	for i, locale := range locales {
+		if locale == saved.Locale {
+			localeDropdown.SetSelected(uint(i))
+		}
+	}
+	localeBox.Append(localeDropdown)
+	contentArea.Append(localeBox)
+
+	snapshotCheck := gtk.NewCheckButtonWithLabel(i18n.T("gui.prefs.snapshots"))
+	snapshotCheck.SetActive(saved.Snapshots)
+	contentArea.Append(snapshotCheck)
+	snapshotHint := gtk.NewLabel(i18n.T("gui.prefs.snapshots_hint", snapshotMinutes(saved), snapshotEdits(saved), editor.DefaultSnapshotKeep))
+	snapshotHint.AddCSSClass("param-description")
+	snapshotHint.SetWrap(true)
+	snapshotHint.SetXAlign(0)
+	contentArea.Append(snapshotHint)
+
+	checksumBox := gtk.NewBox(gtk.OrientationHorizontal, 10)
+	checksumLabel := gtk.NewLabel(i18n.T("gui.prefs.checksum"))
+	checksumLabel.SetXAlign(0)
+	checksumBox.Append(checksumLabel)
+	checksumNames := make([]string, len(checksumPolicyLabels))
+	for i, key := range checksumPolicyLabels {
+		checksumNames[i] = i18n.T(key)
+	}
+	checksumDropdown := gtk.NewDropDownFromStrings(checksumNames)
+	checksumDropdown.SetHExpand(true)
+	for i, p := range editor.ChecksumPolicies {
+		if p == editor.ChecksumOnSave {
+			checksumDropdown.SetSelected(uint(i))
+		}
+	}
+	checksumBox.Append(checksumDropdown)
+	contentArea.Append(checksumBox)
+
+	backupCheck := gtk.NewCheckButtonWithLabel(i18n.T("gui.prefs.require_backup"))
+	backupCheck.SetActive(reader.RequireBackup)
+	backupCheck.SetSensitive(!reader.NoBackup)
+	contentArea.Append(backupCheck)
+	backupHint := gtk.NewLabel(i18n.T("gui.prefs.require_backup_hint"))
+	backupHint.AddCSSClass("param-description")
+	backupHint.SetWrap(true)
+	backupHint.SetXAlign(0)
+	contentArea.Append(backupHint)
+
+	mirrorCheck := gtk.NewCheckButtonWithLabel(i18n.T("gui.prefs.mirror_writes"))
+	mirrorCheck.SetActive(editor.MirrorWrites)
+	contentArea.Append(mirrorCheck)
+	mirrorHint := gtk.NewLabel(i18n.T("gui.prefs.mirror_writes_hint"))
+	mirrorHint.AddCSSClass("param-description")
+	mirrorHint.SetWrap(true)
+	mirrorHint.SetXAlign(0)
+	contentArea.Append(mirrorHint)
+
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(i18n.T("gui.button.save"), int(gtk.ResponseAccept))
+
+	dialog.ConnectResponse(func(responseID int) {
+		if responseID == int(gtk.ResponseAccept) {
+			s, _ := settings.Load()
+			if idx := int(policyDropdown.Selected()); idx >= 0 && idx < len(editor.ConfirmPolicies) {
+				editor.Confirmation = editor.ConfirmPolicies[idx]
+				s.ConfirmPolicy = string(editor.Confirmation)
+			}
+			oldLocale := s.Locale
+			if idx := int(localeDropdown.Selected()); idx >= 0 && idx < len(locales) {
+				s.Locale = locales[idx]
+			}
+			checksumChanged := false
+			if idx := int(checksumDropdown.Selected()); idx >= 0 && idx < len(editor.ChecksumPolicies) {
+				checksumChanged = editor.ChecksumPolicies[idx] != editor.ChecksumOnSave
+				editor.ChecksumOnSave = editor.ChecksumPolicies[idx]
+				s.ChecksumOnSave = string(editor.ChecksumOnSave)
+			}
+			snapshotsChanged := s.Snapshots != snapshotCheck.Active()
+			s.Snapshots = snapshotCheck.Active()
+			mirrorChanged := s.MirrorWrites != mirrorCheck.Active()
+			s.MirrorWrites = mirrorCheck.Active()
+			editor.MirrorWrites = s.MirrorWrites
+			backupChanged := !reader.NoBackup && s.RequireBackup != backupCheck.Active()
+			if backupChanged {
+				s.RequireBackup = backupCheck.Active()
+				reader.RequireBackup = s.RequireBackup
+			}
+
+			if err := s.Save(); err != nil {
+				mw.logError(i18n.T("gui.prefs.save_failed"), err)
+			} else if snapshotsChanged {
+				mw.applySnapshotSettings(s)
+				if s.Snapshots {
+					mw.logInfo(i18n.T("gui.snapshot.enabled"), snapshotMinutes(s), snapshotEdits(s))
+				} else {
+					mw.logInfo("%s", i18n.T("gui.snapshot.disabled"))
+				}
+			} else if s.Locale != oldLocale {
+				// Existing widgets keep their labels until the next start
+				mw.logInfo(i18n.T("gui.prefs.language_set"), localeNames[localeDropdown.Selected()])
+			} else if backupChanged {
+				if s.RequireBackup {
+					mw.logInfo("%s", i18n.T("gui.prefs.require_backup_on"))
+				} else {
+					mw.logInfo("%s", i18n.T("gui.prefs.require_backup_off"))
+				}
+			} else if checksumChanged {
+				mw.logInfo(i18n.T("gui.prefs.checksum_set"), editor.ChecksumOnSave)
+			} else if mirrorChanged {
+				if s.MirrorWrites {
+					mw.logInfo("%s", i18n.T("gui.prefs.mirror_writes_on"))
+				} else {
+					mw.logInfo("%s", i18n.T("gui.prefs.mirror_writes_off"))
+				}
+			} else {
+				mw.logInfo(i18n.T("gui.prefs.policy_set"), editor.Confirmation)
+			}
+		}
+		dialog.Destroy()
+	})
+
+	dialog.Show()
+}
+
+// confirmThen shows a warning dialog with the given markup and runs action
+// if the user accepts, or runs it straight away when the confirmation
+// policy skips this kind of prompt
+func (mw *MainWindow) confirmThen(kind editor.ConfirmKind, markup, acceptLabel string, action func()) {
+	if !editor.NeedsConfirm(kind) {
+		action()
+		return
+	}
+
+	confirmDialog := gtk.NewMessageDialog(
+		&mw.window.Window,
+		gtk.DialogModal,
+		gtk.MessageWarning,
+		gtk.ButtonsNone,
+	)
+	confirmDialog.SetMarkup(markup)
+	confirmDialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	confirmDialog.AddButton(acceptLabel, int(gtk.ResponseAccept))
+
+	confirmDialog.ConnectResponse(func(responseID int) {
+		if responseID == int(gtk.ResponseAccept) {
+			action()
+		}
+		confirmDialog.Destroy()
+	})
+
+	confirmDialog.Show()
+}
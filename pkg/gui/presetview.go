@@ -0,0 +1,197 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+)
+
+// showPresetDialog lets the user pick a registered preset, fill in its
+// arguments and preview the resulting changes before applying them
+func (mw *MainWindow) showPresetDialog() {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.preset.need_file"))
+		return
+	}
+	if len(editor.Presets) == 0 || !mw.checkWritable() {
+		return
+	}
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.preset.title"))
+	dialog.SetDefaultSize(450, 300)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	names := make([]string, len(editor.Presets))
+	for i, p := range editor.Presets {
+		names[i] = p.Name
+	}
+	presetDropdown := gtk.NewDropDownFromStrings(names)
+	contentArea.Append(presetDropdown)
+
+	descLabel := gtk.NewLabel("")
+	descLabel.SetXAlign(0)
+	descLabel.SetWrap(true)
+	contentArea.Append(descLabel)
+
+	argsGrid := gtk.NewGrid()
+	argsGrid.SetRowSpacing(6)
+	argsGrid.SetColumnSpacing(10)
+	contentArea.Append(argsGrid)
+
+	previewLabel := gtk.NewLabel("")
+	previewLabel.SetXAlign(0)
+	contentArea.Append(previewLabel)
+
+	warningLabel := gtk.NewLabel(i18n.T("gui.engine_warning"))
+	warningLabel.AddCSSClass("warning-text")
+	warningLabel.SetXAlign(0)
+	contentArea.Append(warningLabel)
+
+	var spins []*gtk.SpinButton
+
+	currentPreset := func() editor.Preset {
+		return editor.Presets[presetDropdown.Selected()]
+	}
+
+	currentArgs := func() map[string]float64 {
+		args := make(map[string]float64)
+		for i, param := range currentPreset().Params {
+			args[param.Name] = spins[i].Value()
+		}
+		return args
+	}
+
+	updatePreview := func() {
+		p := currentPreset()
+		changes, err := mw.planPreset(p, currentArgs())
+		if err != nil {
+			previewLabel.SetText(i18n.T("gui.preset.error", err))
+			return
+		}
+		previewLabel.SetText(fmt.Sprintf("%d cells would change", len(changes)))
+	}
+
+	buildArgs := func() {
+		for child := argsGrid.FirstChild(); child != nil; child = argsGrid.FirstChild() {
+			argsGrid.Remove(child)
+		}
+		spins = nil
+
+		p := currentPreset()
+		descLabel.SetText(p.Description)
+		for i, param := range p.Params {
+			label := gtk.NewLabel(param.Name + ":")
+			label.SetXAlign(0)
+			label.SetTooltipText(param.Description)
+
+			step := 0.01
+			digits := uint(2)
+			if param.Integer {
+				step, digits = 1, 0
+			}
+			spin := gtk.NewSpinButtonWithRange(param.Min, param.Max, step)
+			spin.SetDigits(digits)
+			spin.SetValue(param.Default)
+			spin.SetHExpand(true)
+			spin.ConnectValueChanged(updatePreview)
+			spins = append(spins, spin)
+
+			argsGrid.Attach(label, 0, i, 1, 1)
+			argsGrid.Attach(spin, 1, i, 1, 1)
+		}
+		updatePreview()
+	}
+
+	presetDropdown.Connect("notify::selected", buildArgs)
+	buildArgs()
+
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(i18n.T("gui.button.apply"), int(gtk.ResponseAccept))
+
+	dialog.ConnectResponse(func(responseID int) {
+		if responseID != int(gtk.ResponseAccept) {
+			dialog.Destroy()
+			return
+		}
+
+		p := currentPreset()
+		args := currentArgs()
+		changes, err := mw.planPreset(p, args)
+		if err != nil {
+			mw.reportEditError(i18n.T("gui.preset.cannot", p.Name), err)
+			return
+		}
+		if len(changes) == 0 {
+			dialog.Destroy()
+			mw.logInfo(i18n.T("gui.preset.no_changes"), p.Name)
+			return
+		}
+		mw.confirmPreset(p, changes, dialog)
+	})
+
+	dialog.Show()
+}
+
+// planPreset computes a preset's cell changes against the current file
+func (mw *MainWindow) planPreset(p editor.Preset, args map[string]float64) ([]editor.CellChange, error) {
+	if err := p.ValidateArgs(args); err != nil {
+		return nil, err
+	}
+	data, err := mw.currentBytes()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := p.Plan(data, args)
+	if err != nil {
+		return nil, err
+	}
+	editor.SortChanges(changes)
+	return changes, nil
+}
+
+// confirmPreset shows the dry-run diff and applies the changes on accept
+func (mw *MainWindow) confirmPreset(p editor.Preset, changes []editor.CellChange, presetDialog *gtk.Dialog) {
+	const maxLines = 20
+
+	var lines []string
+	for i, c := range changes {
+		if i == maxLines {
+			lines = append(lines, i18n.T("gui.more", len(changes)-maxLines))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s [%d,%d]: %.3f → %.3f", c.Map, c.Row, c.Col, c.OldValue, c.NewValue))
+	}
+
+	markup := i18n.T(
+		"gui.preset.confirm",
+		p.Name, len(changes), glib.MarkupEscapeText(strings.Join(lines, "\n")),
+	)
+
+	mw.confirmThen(editor.ConfirmReview, markup, i18n.T("gui.button.apply_changes"), func() {
+		backup, err := mw.applyChanges("Preset "+p.Name, changes)
+		if backup != "" {
+			mw.logger.Info("Backup created", "path", backup)
+		}
+		if err != nil {
+			mw.reportEditError(i18n.T("gui.preset.failed"), err)
+			return
+		}
+		presetDialog.Destroy()
+		mw.loadCurrentMap()
+		mw.refreshTimeline()
+		mw.logInfo(i18n.T("gui.preset.applied"), p.Name, len(changes))
+	})
+}
@@ -0,0 +1,52 @@
+package gui
+
+import (
+	"slices"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// buildProfileDropdown adds the profile selector to the header bar. It is
+// disabled while the definitions come from --xdf or replace the built-in
+// maps, which would override any profile.
+func (mw *MainWindow) buildProfileDropdown() {
+	names := editor.ProfileNames()
+	dropdown := gtk.NewDropDownFromStrings(names)
+	if i := slices.Index(names, models.ActiveProfile); i >= 0 {
+		dropdown.SetSelected(uint(i))
+	}
+	dropdown.SetTooltipText(i18n.T("gui.profile.tooltip"))
+	if XDFFile != "" || (MapsFile != "" && MapsMode == editor.MapsReplace) {
+		dropdown.SetSensitive(false)
+		dropdown.SetTooltipText(i18n.T("gui.profile.fixed"))
+	}
+	dropdown.NotifyProperty("selected", func() {
+		if idx := dropdown.Selected(); idx < uint(len(names)) {
+			mw.switchProfile(names[idx])
+		}
+	})
+	mw.headerBar.PackEnd(dropdown)
+}
+
+// switchProfile reopens the window with the definitions of another
+// profile, keeping the open file. Views address maps by position and the
+// parameter list is built once, so a new window is simpler than
+// rebuilding them in place.
+func (mw *MainWindow) switchProfile(name string) {
+	if name == models.ActiveProfile {
+		return
+	}
+	Profile = name
+	file := mw.CurrentFile
+	// The new window takes the snapshots of the file
+	mw.snapshotter = nil
+	next := newMainWindow(mw.app, nil)
+	if i := slices.Index(next.availableFiles, file); i > 0 {
+		next.fileDropdown.SetSelected(uint(i))
+	}
+	next.logInfo(i18n.T("gui.profile.switched"), models.ActiveProfile)
+	mw.window.Destroy()
+}
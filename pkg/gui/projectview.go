@@ -0,0 +1,72 @@
+package gui
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// openProjectDialog picks a project file saved from the web UI
+func (mw *MainWindow) openProjectDialog() {
+	if mw.diff != nil {
+		mw.showReadOnlyNotice()
+		return
+	}
+	filter := gtk.NewFileFilter()
+	filter.SetName(i18n.T("gui.project.filter"))
+	filter.AddPattern("*.project.json")
+
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle(i18n.T("gui.project.title"))
+	dialog.SetDefaultFilter(filter)
+	if mw.binDir != "" {
+		dialog.SetInitialFolder(gio.NewFileForPath(mw.binDir))
+	}
+
+	ctx := context.Background()
+	dialog.Open(ctx, &mw.window.Window, func(res gio.AsyncResulter) {
+		file, err := dialog.OpenFinish(res)
+		if err != nil || file == nil {
+			return // User cancelled
+		}
+		mw.openProject(file.Path())
+	})
+}
+
+// openProject opens the project's file, comparison file and focused map.
+// Color ranges and offset overrides are web UI settings and not applied.
+func (mw *MainWindow) openProject(path string) {
+	p, err := editor.LoadProject(path)
+	if err != nil {
+		mw.logError(i18n.T("gui.project.load_failed"), err)
+		return
+	}
+	if p == nil || p.File == "" {
+		mw.logWarn(i18n.T("gui.project.no_file"), filepath.Base(path))
+		return
+	}
+
+	v := mw.State
+	v.CompareFile, v.CompareMap = "", nil
+	if p.CompareFile != "" && mw.checkECUFile(p.CompareFile) {
+		v.CompareFile = p.CompareFile
+	}
+	mw.setView(v)
+	mw.loadECUFile(p.File)
+
+	for i, cfg := range models.MapConfigs {
+		if cfg.Name == p.Map {
+			mw.selectMapRow(i)
+			break
+		}
+	}
+	if len(p.Offsets) > 0 {
+		mw.logWarn("%s", i18n.T("gui.project.offsets_web_only"))
+	}
+	mw.logInfo(i18n.T("gui.project.opened"), filepath.Base(path))
+}
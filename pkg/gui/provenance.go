@@ -0,0 +1,29 @@
+package gui
+
+import (
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// provenanceTooltip describes the save recorded in the file's sidecar for
+// the subtitle tooltip, or returns "" if there is none
+func provenanceTooltip(id *models.BinaryIdentity) string {
+	p := id.Provenance
+	if p == nil {
+		return ""
+	}
+	lines := []string{
+		i18n.T("gui.provenance.saved", p.ToolVersion, p.Saved.Local().Format("2006-01-02 15:04")),
+		i18n.T("gui.provenance.modified", strings.Join(p.Modified, ", ")),
+		i18n.T("gui.provenance.definitions", p.Definitions),
+	}
+	if p.Definitions != models.DefinitionsFingerprint() {
+		lines = append(lines, i18n.T("gui.provenance.defs_changed"))
+	}
+	if p.Stale(id.SHA256) {
+		lines = append(lines, i18n.T("gui.provenance.stale"))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,123 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+)
+
+// showScaleDialog scales the current map by a factor, showing the clamp
+// analysis live as the factor changes
+func (mw *MainWindow) showScaleDialog() {
+	if mw.CurrentMap == nil || mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.scale.need_map"))
+		return
+	}
+	if !mw.checkMapEditable() {
+		return
+	}
+	cfg := mw.CurrentMap.Config
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.scale.title"))
+	dialog.SetDefaultSize(450, 250)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	infoLabel := gtk.NewLabel(i18n.T("gui.scale.info", cfg.Name))
+	infoLabel.SetXAlign(0)
+	infoLabel.SetWrap(true)
+	contentArea.Append(infoLabel)
+
+	factorScale := gtk.NewScaleWithRange(gtk.OrientationHorizontal, 0.5, 2.0, 0.01)
+	factorScale.SetDigits(2)
+	factorScale.SetDrawValue(true)
+	factorScale.SetValue(1.0)
+	factorScale.SetHExpand(true)
+	contentArea.Append(factorScale)
+
+	analysisLabel := gtk.NewLabel("")
+	analysisLabel.SetXAlign(0)
+	analysisLabel.SetWrap(true)
+	contentArea.Append(analysisLabel)
+
+	warningLabel := gtk.NewLabel(i18n.T("gui.engine_warning"))
+	warningLabel.AddCSSClass("warning-text")
+	warningLabel.SetXAlign(0)
+	contentArea.Append(warningLabel)
+
+	updateAnalysis := func() {
+		analysis := editor.AnalyzeScale(mw.CurrentMap, factorScale.Value())
+		analysisLabel.SetText(analysis.Summary())
+		if len(analysis.Clamped) > 0 {
+			analysisLabel.AddCSSClass("warning-text")
+		} else {
+			analysisLabel.RemoveCSSClass("warning-text")
+		}
+	}
+	factorScale.ConnectValueChanged(updateAnalysis)
+	updateAnalysis()
+
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(i18n.T("gui.button.apply"), int(gtk.ResponseAccept))
+
+	dialog.ConnectResponse(func(responseID int) {
+		if responseID != int(gtk.ResponseAccept) {
+			dialog.Destroy()
+			return
+		}
+
+		factor := factorScale.Value()
+		data, err := mw.currentBytes()
+		if err != nil {
+			mw.logError(i18n.T("gui.read_failed"), err)
+			return
+		}
+		changes, err := editor.PlanScale(data, cfg, factor)
+		if err != nil {
+			mw.reportEditError(i18n.T("gui.scale.cannot", cfg.Name), err)
+			return
+		}
+		if len(changes) == 0 {
+			dialog.Destroy()
+			mw.logInfo(i18n.T("gui.scale.no_changes"), cfg.Name)
+			return
+		}
+
+		// Clamping always needs an explicit acknowledgement
+		analysis := editor.AnalyzeScale(mw.CurrentMap, factor)
+		kind := editor.ConfirmReview
+		if len(analysis.Clamped) > 0 {
+			kind = editor.ConfirmSave
+		}
+		markup := i18n.T("gui.scale.confirm",
+			glib.MarkupEscapeText(cfg.Name), factor, len(changes), analysis.Summary())
+
+		mw.confirmThen(kind, markup, i18n.T("gui.button.apply_changes"), func() {
+			backup, err := mw.applyChanges(fmt.Sprintf("Scale %s by %.2f", cfg.Name, factor), changes)
+			if backup != "" {
+				mw.logger.Info("Backup created", "path", backup)
+			}
+			if err != nil {
+				mw.reportEditError(i18n.T("gui.scale.failed"), err)
+				return
+			}
+			dialog.Destroy()
+			mw.loadCurrentMap()
+			mw.refreshTimeline()
+			mw.logInfo("%s scaled by %.2f: %d cells changed", cfg.Name, factor, len(changes))
+		})
+	})
+
+	dialog.Show()
+}
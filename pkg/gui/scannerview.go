@@ -1,9 +1,11 @@
 package gui
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
 	"github.com/tosih/motronic-m21-tool/pkg/scanner"
 )
 
@@ -16,27 +18,28 @@ func (mw *MainWindow) buildScannerView() *gtk.Box {
 	box.SetMarginBottom(20)
 
 	// Header
-	headerLabel := gtk.NewLabel("Binary Scanner - Find Unknown Maps")
+	headerLabel := gtk.NewLabel(i18n.T("gui.scan.header"))
 	headerLabel.AddCSSClass("scanner-header")
 	headerLabel.SetXAlign(0)
 	box.Append(headerLabel)
 
 	// Description
-	descLabel := gtk.NewLabel("Scan the ECU binary file for potential map locations based on data patterns.")
+	descLabel := gtk.NewLabel(i18n.T("gui.scan.description"))
 	descLabel.SetXAlign(0)
 	descLabel.SetWrap(true)
 	box.Append(descLabel)
 
-	// Scan parameters
+	// Scan parameters, filled in by a selected scan profile
+	fields := &scanFields{}
 	paramsBox := gtk.NewBox(gtk.OrientationHorizontal, 15)
 
 	// Min variance
 	minVarBox := gtk.NewBox(gtk.OrientationHorizontal, 5)
-	minVarLabel := gtk.NewLabel("Min Variance:")
+	minVarLabel := gtk.NewLabel(i18n.T("gui.scan.min_variance"))
 	minVarBox.Append(minVarLabel)
 
 	minVarEntry := gtk.NewEntry()
-	minVarEntry.SetText("10")
+	minVarEntry.SetText(fmt.Sprintf("%g", float64(scanner.DefaultMinVariance)))
 	minVarEntry.SetSizeRequest(80, -1)
 	minVarEntry.SetName("min_variance")
 	minVarBox.Append(minVarEntry)
@@ -44,29 +47,77 @@ func (mw *MainWindow) buildScannerView() *gtk.Box {
 
 	// Dimension filter
 	dimBox := gtk.NewBox(gtk.OrientationHorizontal, 5)
-	dimLabel := gtk.NewLabel("Dimensions:")
+	dimLabel := gtk.NewLabel(i18n.T("gui.scan.dimensions"))
 	dimBox.Append(dimLabel)
 
 	dimCombo := gtk.NewComboBoxText()
-	dimCombo.AppendText("All (8x8, 8x16, 16x16)")
-	dimCombo.AppendText("8x8 only")
-	dimCombo.AppendText("8x16 only")
-	dimCombo.AppendText("16x16 only")
+	dimCombo.Append("all", i18n.T("gui.scan.dim_all"))
+	for _, dim := range scanner.SizeNames() {
+		dimCombo.Append(dim, i18n.T("gui.scan.dim_only", dim))
+	}
 	dimCombo.SetActive(0)
 	dimCombo.SetName("dimension_filter")
 	dimBox.Append(dimCombo)
 	paramsBox.Append(dimBox)
 
+	// Exhaustive scans try every offset; they run in the background and
+	// can be canceled, continuing from their checkpoint next time
+	exhaustiveCheck := gtk.NewCheckButtonWithLabel(i18n.T("gui.scan.exhaustive"))
+	paramsBox.Append(exhaustiveCheck)
+
 	box.Append(paramsBox)
 
-	// Scan button
-	scanButton := gtk.NewButtonWithLabel("Scan File")
+	scanRange := newScanRangeInput()
+	box.Append(scanRange.box)
+
+	fields.minVariance, fields.dimensions = minVarEntry, dimCombo
+	fields.exhaustive, fields.scanRange = exhaustiveCheck, scanRange
+	box.InsertChildAfter(mw.buildScanProfileRow(fields), descLabel)
+
+	// Scan button, which cancels a running exhaustive scan
+	scanButton := gtk.NewButtonWithLabel(i18n.T("gui.scan.button"))
 	scanButton.AddCSSClass("suggested-action")
+	var cancelScan context.CancelFunc
 	scanButton.ConnectClicked(func() {
-		mw.performScan(box, minVarEntry, dimCombo)
+		if cancelScan != nil {
+			cancelScan()
+			return
+		}
+		profile := fields.profile()
+		if profile.ScanStride() == scanner.Stride {
+			mw.performScan(box, profile)
+			return
+		}
+		var ctx context.Context
+		ctx, cancelScan = context.WithCancel(context.Background())
+		scanButton.SetLabel(i18n.T("gui.scan.cancel"))
+		mw.performExhaustiveScan(ctx, box, profile, func() {
+			cancelScan = nil
+			scanButton.SetLabel(i18n.T("gui.scan.button"))
+		})
 	})
+	scanRange.box.Append(mw.buildGapsButton(scanRange, func() { scanButton.Activate() }))
 	box.Append(scanButton)
 
+	// Defining a map from a hit opens the wizard with its candidate
+	promoteBox := gtk.NewBox(gtk.OrientationHorizontal, 10)
+	mw.scanPromote = gtk.NewDropDownFromStrings(nil)
+	mw.scanPromote.SetHExpand(true)
+	promoteBox.Append(mw.scanPromote)
+	promoteButton := gtk.NewButtonWithLabel(i18n.T("gui.scan.promote"))
+	promoteButton.ConnectClicked(func() {
+		i := int(mw.scanPromote.Selected())
+		if i >= len(mw.scanResults) {
+			mw.logWarn("%s", i18n.T("gui.scan.promote_none"))
+			return
+		}
+		hit := mw.scanResults[i]
+		cfg := hit.Candidate(fmt.Sprintf("Scan hit 0x%04X", hit.Offset))
+		mw.showMapWizard(&cfg, fmt.Sprintf("X: %s\nY: %s", axisGuessText(hit.Axes.X), axisGuessText(hit.Axes.Y)))
+	})
+	promoteBox.Append(promoteButton)
+	box.Append(promoteBox)
+
 	// Results area (initially empty)
 	resultsLabel := gtk.NewLabel("")
 	resultsLabel.SetName("scan_results")
@@ -83,55 +134,91 @@ func (mw *MainWindow) buildScannerView() *gtk.Box {
 	return box
 }
 
-// performScan executes the binary scan
-func (mw *MainWindow) performScan(containerBox *gtk.Box, minVarEntry *gtk.Entry, dimCombo *gtk.ComboBoxText) {
-	if mw.currentFile == "" {
-		mw.showErrorDialog("Please open an ECU file first")
+// performScan executes the quick binary scan with the parameters of p
+func (mw *MainWindow) performScan(containerBox *gtk.Box, p scanner.Profile) {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
 		return
 	}
 
-	// Parse min variance
-	minVarStr := minVarEntry.Text()
-	var minVariance float64
-	if _, err := fmt.Sscanf(minVarStr, "%f", &minVariance); err != nil {
-		minVariance = 10.0
+	mw.logInfo("%s", i18n.T("gui.scan.started"))
+
+	// Perform scan
+	data, err := mw.currentBytes()
+	if err == nil {
+		err = p.Range.Check(len(data))
+	}
+	if err != nil {
+		mw.logError(i18n.T("gui.scan.failed"), err)
+		return
 	}
+	results := scanner.ScanBytesWithStats(data, p.ScanMinVariance(), p.Range)
 
-	// Get dimension filter
-	dimText := dimCombo.ActiveText()
+	filteredResults := p.Filter(results)
 
-	mw.statusBar.SetText("Scanning file... This may take a moment.")
+	// Display results
+	mw.displayScanResults(containerBox, filteredResults)
 
-	// Perform scan
-	results := scanner.ScanFile(mw.currentFile, minVariance)
-
-	// Filter by dimensions if needed
-	filteredResults := []scanner.ScanResult{}
-	for _, result := range results {
-		include := true
-
-		switch dimText {
-		case "8x8 only":
-			include = (result.Rows == 8 && result.Cols == 8)
-		case "8x16 only":
-			include = (result.Rows == 8 && result.Cols == 16)
-		case "16x16 only":
-			include = (result.Rows == 16 && result.Cols == 16)
-		}
+	mw.logInfo(i18n.T("gui.scan.complete"), len(filteredResults))
+}
 
-		if include {
-			filteredResults = append(filteredResults, result)
-		}
+// performExhaustiveScan scans with the stride and range of p in the
+// background, every offset for an exhaustive scan, saving checkpoints so a
+// canceled scan continues where it stopped next time. done is called on
+// the main loop when the scan ends.
+func (mw *MainWindow) performExhaustiveScan(ctx context.Context, containerBox *gtk.Box, p scanner.Profile, done func()) {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		done()
+		return
 	}
 
-	// Display results
-	mw.displayScanResults(containerBox, filteredResults)
+	data, err := mw.currentBytes()
+	if err != nil {
+		mw.logError(i18n.T("gui.scan.failed"), err)
+		done()
+		return
+	}
+	scan, err := scanner.OpenScanBytes(data, p.ScanStride(), p.Range, true)
+	if err != nil {
+		mw.logError(i18n.T("gui.scan.failed"), err)
+		done()
+		return
+	}
+	if scan.Resumed {
+		mw.logInfo(i18n.T("gui.scan.resuming"), scan.Checkpoint.Position())
+	} else {
+		mw.logInfo("%s", i18n.T("gui.scan.exhaustive_started"))
+	}
+
+	go func() {
+		results, err := scan.Run(ctx, nil)
+		runOnMain(func() {
+			done()
+			filtered := p.Filter(results)
+			mw.displayScanResults(containerBox, filtered)
 
-	mw.statusBar.SetText(fmt.Sprintf("Scan complete. Found %d potential maps.", len(filteredResults)))
+			switch {
+			case ctx.Err() != nil:
+				mw.logWarn(i18n.T("gui.scan.canceled"), scan.Checkpoint.Position(), len(filtered))
+			case err != nil:
+				mw.logError(i18n.T("gui.scan.failed"), err)
+			default:
+				mw.logInfo(i18n.T("gui.scan.exhaustive_complete"), len(filtered))
+			}
+		})
+	}()
 }
 
 // displayScanResults shows scan results in the UI
 func (mw *MainWindow) displayScanResults(containerBox *gtk.Box, results []scanner.ScanResult) {
+	mw.scanResults = results
+	hits := make([]string, len(results))
+	for i, result := range results {
+		hits[i] = fmt.Sprintf("%d. 0x%04X (%dx%d)", i+1, result.Offset, result.Rows, result.Cols)
+	}
+	mw.scanPromote.SetModel(gtk.NewStringList(hits))
+
 	// Find the results label
 	resultsLabel := mw.findChildByName(containerBox, "scan_results")
 	if resultsLabel == nil {
@@ -144,18 +231,28 @@ func (mw *MainWindow) displayScanResults(containerBox *gtk.Box, results []scanne
 	}
 
 	if len(results) == 0 {
-		label.SetText("No potential maps found with the current criteria.")
+		label.SetText(i18n.T("gui.scan.none"))
 		return
 	}
 
 	// Build results text
-	resultsText := fmt.Sprintf("Found %d potential maps:\n\n", len(results))
+	resultsText := i18n.T("gui.scan.found", len(results))
 
 	for i, result := range results {
 		resultsText += fmt.Sprintf("%d. Offset: 0x%04X (%dx%d)\n", i+1, result.Offset, result.Rows, result.Cols)
 		resultsText += fmt.Sprintf("   Min: %.2f, Max: %.2f, Variance: %.1f\n", result.Min, result.Max, result.Variance)
-		resultsText += fmt.Sprintf("   Mean: %.2f, StdDev: %.2f\n\n", result.Mean, result.StdDev)
+		resultsText += fmt.Sprintf("   Mean: %.2f, StdDev: %.2f\n", result.Mean, result.StdDev)
+		resultsText += fmt.Sprintf("   X axis: %s\n", axisGuessText(result.Axes.X))
+		resultsText += fmt.Sprintf("   Y axis: %s\n\n", axisGuessText(result.Axes.Y))
 	}
 
 	label.SetText(resultsText)
 }
+
+// axisGuessText describes an axis suggestion with its confidence note
+func axisGuessText(g scanner.AxisGuess) string {
+	if g.At < 0 {
+		return fmt.Sprintf("%s? (%s)", g.Kind, g.Note)
+	}
+	return fmt.Sprintf("%s in %s at 0x%04X, %s confidence: %s", g.Kind, g.Unit, g.At, g.Confidence, g.Note)
+}
@@ -0,0 +1,139 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/settings"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
+)
+
+// scanFields are the scanner tab's parameter inputs. stride is not shown:
+// the exhaustive check button scans every offset, otherwise the stride of
+// the selected profile is used.
+type scanFields struct {
+	minVariance *gtk.Entry
+	dimensions  *gtk.ComboBoxText
+	exhaustive  *gtk.CheckButton
+	scanRange   *scanRangeInput
+	stride      int
+}
+
+// profile returns the parameters the fields describe
+func (f *scanFields) profile() scanner.Profile {
+	p := scanner.Profile{Stride: f.stride, Range: f.scanRange.value()}
+	if f.exhaustive.Active() {
+		p.Stride = 1
+	} else if p.Stride == 1 {
+		p.Stride = scanner.Stride
+	}
+	if _, err := fmt.Sscanf(f.minVariance.Text(), "%f", &p.MinVariance); err != nil {
+		p.MinVariance = scanner.DefaultMinVariance
+	}
+	if id := f.dimensions.ActiveID(); id != "all" {
+		p.Sizes, _ = scanner.ParseSizes(id)
+	}
+	return p
+}
+
+// show fills the fields with the parameters of p. Size sets other than
+// one size or all of them get their own dimension entry.
+func (f *scanFields) show(p scanner.Profile) {
+	f.stride = p.Stride
+	f.exhaustive.SetActive(p.ScanStride() == 1)
+	f.minVariance.SetText(fmt.Sprintf("%g", p.ScanMinVariance()))
+
+	id := "all"
+	if len(p.Sizes) > 0 {
+		id = strings.Join(p.Sizes, ",")
+	}
+	if !f.dimensions.SetActiveID(id) {
+		f.dimensions.Append(id, i18n.T("gui.scan.dim_only", strings.ReplaceAll(id, ",", ", ")))
+		f.dimensions.SetActiveID(id)
+	}
+
+	if p.Range.IsZero() {
+		f.scanRange.check.SetActive(false)
+	} else {
+		f.scanRange.check.SetActive(true)
+		f.scanRange.from.SetValue(float64(p.Range.Start))
+		f.scanRange.to.SetValue(float64(p.Range.End - 1))
+	}
+}
+
+// buildScanProfileRow creates the profile selector of the scanner tab:
+// picking a built-in or saved profile fills in the fields, and Save stores
+// the fields under the typed name
+func (mw *MainWindow) buildScanProfileRow(fields *scanFields) *gtk.Box {
+	row := gtk.NewBox(gtk.OrientationHorizontal, 5)
+	row.Append(gtk.NewLabel(i18n.T("gui.scan.profile")))
+
+	combo := gtk.NewComboBoxTextWithEntry()
+	combo.SetTooltipText(i18n.T("gui.scan.profile_tooltip"))
+	row.Append(combo)
+
+	var profiles []scanner.Profile
+	reload := func() {
+		s, err := settings.Load()
+		if err != nil {
+			mw.logError(i18n.T("gui.prefs.load_failed"), err)
+		}
+		profiles = s.AllScanProfiles()
+		combo.RemoveAll()
+		for _, p := range profiles {
+			combo.Append(p.Name, p.Name)
+		}
+	}
+	reload()
+
+	combo.ConnectChanged(func() {
+		// Typing a new name changes the text without selecting a profile
+		for _, p := range profiles {
+			if p.Name == combo.ActiveID() {
+				fields.show(p)
+				mw.logInfo(i18n.T("gui.scan.profile_selected"), p.Name, p)
+				return
+			}
+		}
+	})
+
+	saveButton := gtk.NewButtonWithLabel(i18n.T("gui.scan.profile_save"))
+	saveButton.ConnectClicked(func() {
+		p := fields.profile()
+		p.Name = strings.TrimSpace(combo.ActiveText())
+		s, _ := settings.Load()
+		err := s.SetScanProfile(p)
+		if err == nil {
+			err = s.Save()
+		}
+		if err != nil {
+			mw.logError(i18n.T("gui.scan.profile_save_failed"), err)
+			return
+		}
+		reload()
+		combo.SetActiveID(p.Name)
+		mw.logInfo(i18n.T("gui.scan.profile_saved"), p.Name, p)
+	})
+	row.Append(saveButton)
+
+	deleteButton := gtk.NewButtonWithLabel(i18n.T("gui.scan.profile_delete"))
+	deleteButton.ConnectClicked(func() {
+		name := strings.TrimSpace(combo.ActiveText())
+		s, _ := settings.Load()
+		err := s.DeleteScanProfile(name)
+		if err == nil {
+			err = s.Save()
+		}
+		if err != nil {
+			mw.logError(i18n.T("gui.scan.profile_delete_failed"), err)
+			return
+		}
+		reload()
+		mw.logInfo(i18n.T("gui.scan.profile_deleted"), name)
+	})
+	row.Append(deleteButton)
+
+	return row
+}
@@ -0,0 +1,129 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
+)
+
+// maxScanOffset bounds the range spin buttons; OpenScan checks the range
+// against the actual file size
+const maxScanOffset = 0xFFFFF
+
+// minGapLength is the smallest unknown region offered for scanning, the
+// size of the smallest map the scanner looks for
+const minGapLength = 8 * 8
+
+// scanRangeInput is the scanner tab's optional byte range: a check button
+// turning it on and from/to spin buttons shown in hex, the end inclusive
+type scanRangeInput struct {
+	box      *gtk.Box
+	check    *gtk.CheckButton
+	from, to *gtk.SpinButton
+}
+
+// newScanRangeInput creates the range inputs, off by default
+func newScanRangeInput() *scanRangeInput {
+	in := &scanRangeInput{box: gtk.NewBox(gtk.OrientationHorizontal, 5)}
+	in.check = gtk.NewCheckButtonWithLabel(i18n.T("gui.scan.range"))
+	in.box.Append(in.check)
+
+	newOffsetSpin := func(value int) *gtk.SpinButton {
+		spin := gtk.NewSpinButtonWithRange(0, maxScanOffset, 0x40)
+		spin.SetValue(float64(value))
+		spin.SetWidthChars(8)
+		spin.SetSensitive(false)
+		// Typed hex is parsed by the default input handler
+		spin.ConnectOutput(func() bool {
+			spin.SetText(fmt.Sprintf("0x%04X", spin.ValueAsInt()))
+			return true
+		})
+		return spin
+	}
+	in.from = newOffsetSpin(0x6000)
+	in.to = newOffsetSpin(0x7FFF)
+	in.box.Append(in.from)
+	in.box.Append(gtk.NewLabel("–"))
+	in.box.Append(in.to)
+
+	in.check.ConnectToggled(func() {
+		in.from.SetSensitive(in.check.Active())
+		in.to.SetSensitive(in.check.Active())
+	})
+	return in
+}
+
+// value returns the selected range, or the zero Range (the whole file)
+// when the range is off
+func (in *scanRangeInput) value() scanner.Range {
+	if !in.check.Active() {
+		return scanner.Range{}
+	}
+	return scanner.Range{Start: in.from.ValueAsInt(), End: in.to.ValueAsInt() + 1}
+}
+
+// set turns the range on and fills it with the bytes of r
+func (in *scanRangeInput) set(r models.Region) {
+	in.check.SetActive(true)
+	in.from.SetValue(float64(r.Start))
+	in.to.SetValue(float64(r.End - 1))
+}
+
+// buildGapsButton creates the button listing the current file's unknown
+// regions, the byte ranges no map or parameter definition covers. Picking
+// one fills in the range and starts the scan.
+func (mw *MainWindow) buildGapsButton(in *scanRangeInput, scan func()) *gtk.MenuButton {
+	button := gtk.NewMenuButton()
+	button.SetLabel(i18n.T("gui.scan.gaps"))
+	button.SetTooltipText(i18n.T("gui.scan.gaps_tooltip"))
+
+	popover := gtk.NewPopover()
+	list := gtk.NewBox(gtk.OrientationVertical, 4)
+	popover.SetChild(list)
+	button.SetPopover(popover)
+
+	// The regions depend on the file, so the list is rebuilt on each open
+	popover.ConnectShow(func() {
+		for child := list.FirstChild(); child != nil; child = list.FirstChild() {
+			list.Remove(child)
+		}
+		gaps, err := mw.unknownRegions()
+		if err != nil {
+			list.Append(gtk.NewLabel(i18n.T("gui.scan.gaps_unavailable", err)))
+			return
+		}
+		if len(gaps) == 0 {
+			list.Append(gtk.NewLabel(i18n.T("gui.scan.gaps_none")))
+			return
+		}
+		for _, gap := range gaps {
+			item := gtk.NewButtonWithLabel(i18n.T("gui.scan.gap", gap.Name, gap.End-gap.Start))
+			item.AddCSSClass("flat")
+			item.ConnectClicked(func() {
+				popover.Popdown()
+				in.set(gap)
+				scan()
+			})
+			list.Append(item)
+		}
+	})
+	return button
+}
+
+// unknownRegions returns the gaps between the definitions in the current
+// file that are large enough to hold a map
+func (mw *MainWindow) unknownRegions() ([]models.Region, error) {
+	if mw.CurrentFile == "" {
+		return nil, fmt.Errorf("%s", i18n.T("gui.need_file"))
+	}
+	info, err := os.Stat(mw.CurrentFile)
+	if err != nil {
+		return nil, err
+	}
+	regions := models.DefinitionRegions(models.MapConfigs, models.ConfigParams)
+	return models.Gaps(regions, info.Size(), minGapLength), nil
+}
@@ -0,0 +1,137 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// openSession starts the editing session of filename, which every read of
+// the open file and every edit goes through until another file is loaded.
+// Loading the session's own file again keeps it.
+func (mw *MainWindow) openSession(filename string) error {
+	if mw.session != nil && mw.session.Filename() == filename {
+		return nil
+	}
+	s, err := editor.NewSession(filename)
+	if err != nil {
+		return err
+	}
+	if mw.session != nil {
+		mw.session.Close()
+	}
+	mw.session = s
+	return nil
+}
+
+// currentImage returns the open file as the session holds it. It is read
+// from disk again only if something else has written it since.
+func (mw *MainWindow) currentImage() (*reader.ECUFile, error) {
+	if mw.session == nil {
+		return nil, errors.New(i18n.T("gui.need_file"))
+	}
+	if mw.session.Image().Stale() {
+		if err := mw.session.Reload(); err != nil {
+			return nil, err
+		}
+		mw.logInfo(i18n.T("gui.session.reloaded"), filepath.Base(mw.session.Filename()))
+	}
+	return mw.session.Image(), nil
+}
+
+// currentBytes returns the contents of the open file from the session. The
+// slice is shared and must not be modified.
+func (mw *MainWindow) currentBytes() ([]byte, error) {
+	img, err := mw.currentImage()
+	if err != nil {
+		return nil, err
+	}
+	return img.Bytes(), nil
+}
+
+// commitOps writes ops to the open file in one commit of the session,
+// warning when it left the copies of a mirrored dump different. The
+// report is never nil.
+func (mw *MainWindow) commitOps(ops ...editor.Operation) (*editor.Report, error) {
+	if _, err := mw.currentImage(); err != nil {
+		return &editor.Report{}, err
+	}
+	for _, op := range ops {
+		mw.session.Add(op)
+	}
+	report, err := mw.session.Commit()
+	if report == nil {
+		report = &editor.Report{}
+	}
+	if report.Written && report.MirrorStale {
+		mw.logWarn(i18n.T("gui.mirror.stale"), report.Copies)
+	}
+	return report, err
+}
+
+// previewOps plans ops against the open file without writing
+func (mw *MainWindow) previewOps(ops ...editor.Operation) (*editor.Report, error) {
+	if _, err := mw.currentImage(); err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		mw.session.Add(op)
+	}
+	return mw.session.Preview()
+}
+
+// applyChanges writes changes planned against currentBytes to the open
+// file and returns the backup path
+func (mw *MainWindow) applyChanges(name string, changes []editor.CellChange) (string, error) {
+	report, err := mw.commitOps(editor.Operation{Name: name, Plan: func([]byte) ([]editor.CellChange, error) {
+		return changes, nil
+	}})
+	return report.Backup, err
+}
+
+// saveAsDialog asks for a new file name, writes the open file there and
+// continues editing the copy
+func (mw *MainWindow) saveAsDialog() {
+	if mw.diff != nil {
+		mw.showReadOnlyNotice()
+		return
+	}
+	if mw.session == nil {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle(i18n.T("gui.save_as.title"))
+	dialog.SetDefaultFilter(binFileFilter())
+	dialog.SetInitialFolder(gio.NewFileForPath(filepath.Dir(mw.CurrentFile)))
+	dialog.SetInitialName(filepath.Base(mw.CurrentFile))
+
+	ctx := context.Background()
+	dialog.Save(ctx, &mw.window.Window, func(res gio.AsyncResulter) {
+		file, err := dialog.SaveFinish(res)
+		if err != nil || file == nil {
+			return // User cancelled
+		}
+		path := file.Path()
+		// The copy is loaded like any other image
+		if !strings.EqualFold(filepath.Ext(path), ".bin") {
+			mw.logError(i18n.T("gui.open.not_image"), filepath.Base(path))
+			return
+		}
+		original := mw.CurrentFile
+		if err := mw.session.SaveAs(path); err != nil {
+			mw.reportEditError(i18n.T("gui.save_as.failed"), err)
+			return
+		}
+		mw.logInfo(i18n.T("gui.save_as.done"), filepath.Base(original), path)
+		mw.loadECUFile(path)
+	})
+}
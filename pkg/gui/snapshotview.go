@@ -0,0 +1,198 @@
+package gui
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/settings"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+)
+
+// snapshotCheckSeconds is how often the timer asks whether a snapshot is
+// due
+const snapshotCheckSeconds = 30
+
+// snapshotMinutes returns the snapshot interval of s in minutes
+func snapshotMinutes(s *settings.Settings) int {
+	if s.SnapshotMinutes > 0 {
+		return s.SnapshotMinutes
+	}
+	return int(editor.DefaultSnapshotInterval / time.Minute)
+}
+
+// snapshotEdits returns after how many edits s takes a snapshot
+func snapshotEdits(s *settings.Settings) int {
+	if s.SnapshotEdits > 0 {
+		return s.SnapshotEdits
+	}
+	return editor.DefaultSnapshotEdits
+}
+
+// applySnapshotSettings starts or stops automatic snapshots. Every write
+// by the editor hands its new contents to the snapshotter, which takes a
+// snapshot straight away once enough edits have accumulated. Either way a
+// written file is dropped from mw.files, so the views read it again.
+func (mw *MainWindow) applySnapshotSettings(s *settings.Settings) {
+	if !s.Snapshots {
+		mw.snapshotter = nil
+		editor.AfterWrite = func(filename string, data []byte) {
+			mw.files.Forget(filename)
+		}
+		return
+	}
+
+	snapshotter := editor.NewSnapshotter()
+	snapshotter.Interval = time.Duration(snapshotMinutes(s)) * time.Minute
+	snapshotter.Edits = snapshotEdits(s)
+	snapshotter.Track(mw.CurrentFile)
+	mw.snapshotter = snapshotter
+	editor.AfterWrite = func(filename string, data []byte) {
+		mw.files.Forget(filename)
+		if snapshotter.Written(filename, data) {
+			runOnMain(mw.takeSnapshot)
+		}
+	}
+}
+
+// startSnapshotTimer checks periodically whether the interval of the
+// automatic snapshots has passed
+func (mw *MainWindow) startSnapshotTimer() {
+	glib.TimeoutSecondsAdd(snapshotCheckSeconds, func() bool {
+		if mw.snapshotter != nil && mw.snapshotter.Due(time.Now()) {
+			mw.takeSnapshot()
+		}
+		return true
+	})
+}
+
+// takeSnapshot saves a snapshot if the file changed since the last one
+func (mw *MainWindow) takeSnapshot() {
+	if mw.snapshotter == nil {
+		return
+	}
+	snapshot, taken, err := mw.snapshotter.Take()
+	if err != nil {
+		mw.logError(i18n.T("gui.snapshot.failed"), err)
+		return
+	}
+	if taken {
+		mw.logger.Info("Snapshot saved", "path", snapshot.Path, "label", snapshot.Label)
+	}
+}
+
+// showSnapshotsDialog lists the snapshots of the current file, newest
+// first, to compare against or restore
+func (mw *MainWindow) showSnapshotsDialog() {
+	if mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.need_file"))
+		return
+	}
+	snapshots, err := editor.ListSnapshots(mw.CurrentFile)
+	if err != nil {
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return
+	}
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.snapshot.title", filepath.Base(mw.CurrentFile)))
+	dialog.SetDefaultSize(550, 400)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	if mw.snapshotter == nil {
+		offLabel := gtk.NewLabel(i18n.T("gui.snapshot.off"))
+		offLabel.AddCSSClass("param-description")
+		offLabel.SetWrap(true)
+		offLabel.SetXAlign(0)
+		contentArea.Append(offLabel)
+	}
+
+	if len(snapshots) == 0 {
+		contentArea.Append(gtk.NewLabel(i18n.T("gui.snapshot.none")))
+	} else {
+		list := gtk.NewListBox()
+		list.SetSelectionMode(gtk.SelectionNone)
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			list.Append(mw.snapshotRow(snapshots[i], dialog))
+		}
+		scrolled := gtk.NewScrolledWindow()
+		scrolled.SetVExpand(true)
+		scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+		scrolled.SetChild(list)
+		contentArea.Append(scrolled)
+	}
+
+	dialog.AddButton(i18n.T("gui.button.close"), int(gtk.ResponseClose))
+	dialog.ConnectResponse(func(responseID int) {
+		dialog.Destroy()
+	})
+	dialog.Show()
+}
+
+// snapshotRow shows one snapshot with buttons to compare the current file
+// against it and to restore it
+func (mw *MainWindow) snapshotRow(snapshot editor.Snapshot, dialog *gtk.Dialog) *gtk.Box {
+	row := gtk.NewBox(gtk.OrientationHorizontal, 10)
+	row.SetMarginTop(4)
+	row.SetMarginBottom(4)
+
+	text := snapshot.Time.Format("2006-01-02 15:04:05")
+	if snapshot.Label != "" {
+		text += "  " + snapshot.Label
+	}
+	label := gtk.NewLabel(text)
+	label.SetXAlign(0)
+	label.SetHExpand(true)
+	label.SetTooltipText(snapshot.Path)
+	row.Append(label)
+
+	compareButton := gtk.NewButtonWithLabel(i18n.T("gui.snapshot.compare"))
+	compareButton.ConnectClicked(func() {
+		mw.compareWith(snapshot.Path)
+		mw.refreshCompareParams()
+		mw.logInfo(i18n.T("gui.compare.comparing"), snapshot.Path)
+		dialog.Destroy()
+	})
+	row.Append(compareButton)
+
+	restoreButton := gtk.NewButtonWithLabel(i18n.T("gui.snapshot.restore"))
+	restoreButton.ConnectClicked(func() {
+		if !mw.checkWritable() {
+			return
+		}
+		markup := i18n.T("gui.snapshot.confirm",
+			glib.MarkupEscapeText(filepath.Base(mw.CurrentFile)),
+			glib.MarkupEscapeText(snapshot.Time.Format("2006-01-02 15:04:05")))
+		mw.confirmThen(editor.ConfirmSave, markup, i18n.T("gui.snapshot.restore"), func() {
+			backup, err := editor.RestoreSnapshot(mw.CurrentFile, snapshot)
+			if backup != "" {
+				mw.logger.Info("Backup created", "path", backup)
+			}
+			if err == nil {
+				// The restore replaced the file behind the session
+				err = mw.session.Reload()
+			}
+			if err != nil {
+				mw.reportEditError(i18n.T("gui.snapshot.restore_failed"), err)
+				return
+			}
+			dialog.Destroy()
+			mw.loadCurrentMap()
+			mw.refreshConfigValues()
+			mw.refreshTimeline()
+			mw.logInfo(i18n.T("gui.snapshot.restored"), snapshot.Time.Format("15:04:05"))
+		})
+	})
+	row.Append(restoreButton)
+	return row
+}
@@ -0,0 +1,103 @@
+package gui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+)
+
+// newSparkline returns a small drawing area plotting values left to right
+func newSparkline(values []float64, width, height int) *gtk.DrawingArea {
+	area := gtk.NewDrawingArea()
+	area.SetSizeRequest(width, height)
+	area.SetDrawFunc(func(_ *gtk.DrawingArea, cr *cairo.Context, w, h int) {
+		drawSparkline(cr, 0, 0, float64(w), float64(h), values)
+	})
+	return area
+}
+
+// drawSparkline draws values as a line scaled to fill the rectangle, with
+// the latest value marked by a dot. A flat series is drawn mid-height.
+func drawSparkline(cr *cairo.Context, x, y, w, h float64, values []float64) {
+	if len(values) < 2 {
+		return
+	}
+
+	const pad = 3
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	pointAt := func(i int) (float64, float64) {
+		px := x + pad + float64(i)*(w-2*pad)/float64(len(values)-1)
+		py := y + h/2
+		if hi > lo {
+			py = y + h - pad - (values[i]-lo)/(hi-lo)*(h-2*pad)
+		}
+		return px, py
+	}
+
+	cr.SetSourceRGB(0.4, 0.5, 0.9)
+	cr.SetLineWidth(1.5)
+	for i := range values {
+		px, py := pointAt(i)
+		if i == 0 {
+			cr.MoveTo(px, py)
+		} else {
+			cr.LineTo(px, py)
+		}
+	}
+	cr.Stroke()
+
+	px, py := pointAt(len(values) - 1)
+	cr.SetSourceRGB(0.9, 0.3, 0.2)
+	cr.Arc(px, py, 2.5, 0, 2*math.Pi)
+	cr.Fill()
+}
+
+// queryCellTooltip shows the hovered cell's value, the changelog entry
+// behind it when it differs from the compared file and, when the
+// changelog or backups record earlier values, a sparkline of its history
+func (mw *MainWindow) queryCellTooltip(x, y int, keyboardMode bool, tooltip *gtk.Tooltip) bool {
+	if keyboardMode || mw.CurrentMap == nil || mw.CurrentFile == "" {
+		return false
+	}
+	row, col, valid := mw.getCellAtPosition(float64(x), float64(y))
+	if !valid {
+		return false
+	}
+
+	cfg := mw.CurrentMap.Config
+	box := gtk.NewBox(gtk.OrientationVertical, 4)
+	box.Append(gtk.NewLabel(fmt.Sprintf("[%d,%d] %.2f %s", row, col, mw.CurrentMap.Data[row][col], cfg.Unit)))
+	if o, ok := mw.outlierAt(row, col); ok {
+		box.Append(gtk.NewLabel(i18n.T("gui.outliers.cell", o.Median, o.Smoothed)))
+	}
+	if mw.CompareMap != nil {
+		if blame := mw.cellBlame(row, col); blame != "" {
+			box.Append(gtk.NewLabel(blame))
+		}
+	}
+
+	history, err := editor.CellHistory(mw.CurrentFile, cfg.Name, row, col, editor.DefaultHistoryLimit)
+	if err != nil {
+		mw.logger.Debug("Cell history unavailable", "error", err)
+	}
+	if len(history) > 1 {
+		values := make([]float64, len(history))
+		for i, p := range history {
+			values[i] = p.Value
+		}
+		box.Append(newSparkline(values, 160, 36))
+		box.Append(gtk.NewLabel(fmt.Sprintf("%d changes since %s", len(history)-1, history[0].Time.Format("2006-01-02 15:04"))))
+	}
+
+	tooltip.SetCustom(box)
+	return true
+}
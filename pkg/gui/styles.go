@@ -39,17 +39,41 @@ listboxrow:selected {
 	background-color: @theme_selected_bg_color;
 }
 
+.category-header {
+	font-weight: bold;
+	font-size: 10pt;
+	padding: 2px 4px;
+	color: alpha(@theme_fg_color, 0.8);
+}
+
 .map-name {
 	font-weight: bold;
 	font-size: 11pt;
 	color: @theme_fg_color;
 }
 
+.map-badge {
+	font-size: 8pt;
+	padding: 0 5px;
+	border-radius: 8px;
+	background-color: alpha(@theme_fg_color, 0.1);
+}
+
+.map-badge.changed {
+	background-color: alpha(@accent_color, 0.3);
+}
+
 .map-detail {
 	font-size: 9pt;
 	color: alpha(@theme_fg_color, 0.7);
 }
 
+/* Header bar subtitle */
+.subtitle {
+	font-size: 8pt;
+	color: alpha(@theme_fg_color, 0.7);
+}
+
 /* Status bar */
 .statusbar {
 	padding: 8px 12px;
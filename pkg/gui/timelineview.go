@@ -0,0 +1,79 @@
+package gui
+
+import (
+	"os"
+
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/compare"
+)
+
+// buildTimelineBar creates the backup version slider shown above the map view
+func (mw *MainWindow) buildTimelineBar() *gtk.Box {
+	mw.timelineBox = gtk.NewBox(gtk.OrientationHorizontal, 10)
+	mw.timelineBox.SetMarginStart(10)
+	mw.timelineBox.SetMarginEnd(10)
+	mw.timelineBox.SetMarginTop(5)
+	mw.timelineBox.SetMarginBottom(5)
+	mw.timelineBox.SetVisible(false)
+
+	label := gtk.NewLabel(i18n.T("gui.timeline.label"))
+	mw.timelineBox.Append(label)
+
+	mw.versionScale = gtk.NewScaleWithRange(gtk.OrientationHorizontal, 0, 1, 1)
+	mw.versionScale.SetDigits(0)
+	mw.versionScale.SetDrawValue(false)
+	mw.versionScale.SetHExpand(true)
+	mw.versionScale.ConnectValueChanged(mw.onVersionChanged)
+	mw.timelineBox.Append(mw.versionScale)
+
+	mw.versionLabel = gtk.NewLabel("current")
+	mw.versionLabel.SetSizeRequest(160, -1)
+	mw.timelineBox.Append(mw.versionLabel)
+
+	return mw.timelineBox
+}
+
+// refreshTimeline reloads the backup series of the current file
+func (mw *MainWindow) refreshTimeline() {
+	versions, err := compare.BuildTimeline(mw.CurrentFile)
+	if err != nil || len(versions) < 2 {
+		mw.timeline = nil
+		mw.timelineBox.SetVisible(false)
+		return
+	}
+
+	mw.timeline = versions
+	last := float64(len(versions) - 1)
+	mw.versionScale.SetRange(0, last)
+	mw.versionScale.SetValue(last)
+	mw.timelineBox.SetVisible(true)
+}
+
+// onVersionChanged loads the selected backup into the comparison slot
+func (mw *MainWindow) onVersionChanged() {
+	idx := int(mw.versionScale.Value() + 0.5)
+	if idx < 0 || idx >= len(mw.timeline) {
+		return
+	}
+
+	version := mw.timeline[idx]
+	mw.versionLabel.SetText(version.Label)
+
+	// The last entry is the current file itself, so there is nothing to compare
+	if idx == len(mw.timeline)-1 {
+		v := mw.State
+		v.CompareFile, v.CompareMap = "", nil
+		mw.setView(v)
+		mw.logInfo(i18n.T("gui.loaded"), mw.CurrentFile)
+		return
+	}
+
+	if _, err := os.Stat(version.Path); err != nil {
+		mw.logWarn(i18n.T("gui.timeline.missing"), version.Path)
+		return
+	}
+
+	mw.compareWith(version.Path)
+	mw.logInfo(i18n.T("gui.timeline.comparing"), version.Label)
+}
@@ -0,0 +1,183 @@
+package gui
+
+import (
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+)
+
+// transformOpLabels are the catalog keys describing editor.TransformOps,
+// in the same order
+var transformOpLabels = []string{
+	"gui.transform.op.add",
+	"gui.transform.op.mul",
+	"gui.transform.op.set",
+}
+
+// showTransformDialog adds to, multiplies or sets the cells of the current
+// map or a region of it, previewing the resulting range and clamp count as
+// the inputs change
+func (mw *MainWindow) showTransformDialog() {
+	if mw.CurrentMap == nil || mw.CurrentFile == "" {
+		mw.logWarn("%s", i18n.T("gui.transform.need_map"))
+		return
+	}
+	if !mw.checkMapEditable() {
+		return
+	}
+	cfg := mw.CurrentMap.Config
+	data, err := mw.currentBytes()
+	if err != nil {
+		mw.logError(i18n.T("gui.read_failed"), err)
+		return
+	}
+
+	dialog := gtk.NewDialog()
+	dialog.SetTransientFor(&mw.window.Window)
+	dialog.SetModal(true)
+	dialog.SetTitle(i18n.T("gui.transform.title"))
+	dialog.SetDefaultSize(450, 300)
+
+	contentArea := dialog.ContentArea()
+	contentArea.SetSpacing(10)
+	contentArea.SetMarginStart(20)
+	contentArea.SetMarginEnd(20)
+	contentArea.SetMarginTop(20)
+	contentArea.SetMarginBottom(20)
+
+	infoLabel := gtk.NewLabel(i18n.T("gui.transform.info", cfg.Name, cfg.Unit))
+	infoLabel.SetXAlign(0)
+	infoLabel.SetWrap(true)
+	contentArea.Append(infoLabel)
+
+	opNames := make([]string, len(transformOpLabels))
+	for i, key := range transformOpLabels {
+		opNames[i] = i18n.T(key)
+	}
+	opBox := gtk.NewBox(gtk.OrientationHorizontal, 10)
+	opDropdown := gtk.NewDropDownFromStrings(opNames)
+	opBox.Append(opDropdown)
+	valueSpin := gtk.NewSpinButtonWithRange(-100000, 100000, 0.01)
+	valueSpin.SetDigits(3)
+	valueSpin.SetHExpand(true)
+	opBox.Append(valueSpin)
+	contentArea.Append(opBox)
+
+	// The region defaults to the whole map
+	region := editor.WholeMap(cfg)
+	regionGrid := gtk.NewGrid()
+	regionGrid.SetColumnSpacing(10)
+	regionGrid.SetRowSpacing(5)
+	newIndexSpin := func(limit, value int) *gtk.SpinButton {
+		spin := gtk.NewSpinButtonWithRange(0, float64(limit-1), 1)
+		spin.SetValue(float64(value))
+		return spin
+	}
+	row0 := newIndexSpin(cfg.Rows, region.Row0)
+	row1 := newIndexSpin(cfg.Rows, region.Row1)
+	col0 := newIndexSpin(cfg.Cols, region.Col0)
+	col1 := newIndexSpin(cfg.Cols, region.Col1)
+	regionGrid.Attach(gtk.NewLabel(i18n.T("gui.transform.rows")), 0, 0, 1, 1)
+	regionGrid.Attach(row0, 1, 0, 1, 1)
+	regionGrid.Attach(gtk.NewLabel("–"), 2, 0, 1, 1)
+	regionGrid.Attach(row1, 3, 0, 1, 1)
+	regionGrid.Attach(gtk.NewLabel(i18n.T("gui.transform.cols")), 0, 1, 1, 1)
+	regionGrid.Attach(col0, 1, 1, 1, 1)
+	regionGrid.Attach(gtk.NewLabel("–"), 2, 1, 1, 1)
+	regionGrid.Attach(col1, 3, 1, 1, 1)
+	contentArea.Append(regionGrid)
+
+	previewLabel := gtk.NewLabel("")
+	previewLabel.SetXAlign(0)
+	previewLabel.SetWrap(true)
+	contentArea.Append(previewLabel)
+
+	warningLabel := gtk.NewLabel(i18n.T("gui.engine_warning"))
+	warningLabel.AddCSSClass("warning-text")
+	warningLabel.SetXAlign(0)
+	contentArea.Append(warningLabel)
+
+	plan := func() (editor.TransformResult, error) {
+		op := editor.TransformOps[opDropdown.Selected()]
+		region := editor.CellRegion{
+			Row0: row0.ValueAsInt(), Row1: row1.ValueAsInt(),
+			Col0: col0.ValueAsInt(), Col1: col1.ValueAsInt(),
+		}
+		return editor.TransformRegion(data, cfg, region, op, valueSpin.Value())
+	}
+	updatePreview := func() {
+		result, err := plan()
+		if err != nil {
+			previewLabel.SetText(i18n.T("gui.preset.error", err))
+			previewLabel.AddCSSClass("warning-text")
+			dialog.SetResponseSensitive(int(gtk.ResponseAccept), false)
+			return
+		}
+		previewLabel.SetText(i18n.T("gui.transform.preview",
+			len(result.Changes), result.Cells, result.Min, result.Max, cfg.Unit, len(result.Clamped)))
+		if len(result.Clamped) > 0 {
+			previewLabel.AddCSSClass("warning-text")
+		} else {
+			previewLabel.RemoveCSSClass("warning-text")
+		}
+		dialog.SetResponseSensitive(int(gtk.ResponseAccept), len(result.Changes) > 0)
+	}
+
+	// Each operation starts from the operand that changes nothing
+	opDropdown.NotifyProperty("selected", func() {
+		switch editor.TransformOps[opDropdown.Selected()] {
+		case editor.TransformAdd:
+			valueSpin.SetValue(0)
+		case editor.TransformMultiply:
+			valueSpin.SetValue(1)
+		case editor.TransformSet:
+			valueSpin.SetValue(mw.CurrentMap.Data[row0.ValueAsInt()][col0.ValueAsInt()])
+		}
+		updatePreview()
+	})
+	for _, spin := range []*gtk.SpinButton{valueSpin, row0, row1, col0, col1} {
+		spin.ConnectValueChanged(updatePreview)
+	}
+
+	dialog.AddButton(i18n.T("gui.button.cancel"), int(gtk.ResponseCancel))
+	dialog.AddButton(i18n.T("gui.button.apply"), int(gtk.ResponseAccept))
+	updatePreview()
+
+	dialog.ConnectResponse(func(responseID int) {
+		if responseID != int(gtk.ResponseAccept) {
+			dialog.Destroy()
+			return
+		}
+
+		result, err := plan()
+		if err != nil {
+			mw.reportEditError(i18n.T("gui.transform.cannot", cfg.Name), err)
+			return
+		}
+
+		// Clamping always needs an explicit acknowledgement
+		kind := editor.ConfirmReview
+		if len(result.Clamped) > 0 {
+			kind = editor.ConfirmSave
+		}
+		markup := i18n.T("gui.transform.confirm", glib.MarkupEscapeText(cfg.Name), glib.MarkupEscapeText(result.Summary()))
+
+		mw.confirmThen(kind, markup, i18n.T("gui.button.apply_changes"), func() {
+			backup, err := mw.applyChanges("Transform "+cfg.Name, result.Changes)
+			if backup != "" {
+				mw.logger.Info("Backup created", "path", backup)
+			}
+			if err != nil {
+				mw.reportEditError(i18n.T("gui.transform.failed"), err)
+				return
+			}
+			dialog.Destroy()
+			mw.loadCurrentMap()
+			mw.refreshTimeline()
+			mw.logInfo(i18n.T("gui.transform.done"), cfg.Name, len(result.Changes))
+		})
+	})
+
+	dialog.Show()
+}
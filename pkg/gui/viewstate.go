@@ -0,0 +1,100 @@
+package gui
+
+import (
+	"errors"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/mapview"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// runOnMain runs f on the GTK main loop. It is how goroutines deliver
+// their results to the window.
+func runOnMain(f func()) {
+	glib.IdleAdd(f)
+}
+
+// setView replaces the view state, relabels the source button and redraws
+// the map. Called off the main loop, it hands the update to the main loop
+// instead of racing the draw callback.
+func (mw *MainWindow) setView(v mapview.State) {
+	if !glib.MainContextDefault().IsOwner() {
+		runOnMain(func() { mw.setView(v) })
+		return
+	}
+	mw.State = v.Normalized()
+	mw.updateSourceButton()
+	mw.mapDrawArea.QueueDraw()
+}
+
+// loadView shows the selected map of file, the session's, and of
+// compareFile if it isn't empty, both at once. The window is read here on
+// the main loop; mapview.Load only gets the values it needs.
+func (mw *MainWindow) loadView(file, compareFile string) {
+	r := mapview.Request{File: file, CompareFile: compareFile, Source: mw.Source, Files: &mw.files}
+	if mw.selectedMapIdx < len(models.MapConfigs) {
+		cfg := models.MapConfigs[mw.selectedMapIdx]
+		r.Config = &cfg
+	}
+	var err error
+	if file != "" && r.Config != nil {
+		r.Image, err = mw.currentImage()
+	}
+	l := mapview.Load(r)
+	if err != nil {
+		l.ReadErr = err
+	}
+	mw.showLoaded(l)
+}
+
+// showLoaded shows the result of mapview.Load and logs why parts of it are
+// missing
+func (mw *MainWindow) showLoaded(l mapview.Loaded) {
+	defer mw.setView(l.State)
+	switch {
+	case l.ReadErr != nil:
+		mw.logError(i18n.T("gui.map.read_failed"), l.ReadErr)
+		// A definition past the end of a short dump needs explaining
+		if errors.Is(l.ReadErr, reader.ErrOutOfRange) {
+			mw.showErrorDialog(i18n.T("gui.map.read_failed", l.ReadErr))
+		}
+		return
+	case l.State.CurrentMap == nil:
+		return
+	}
+	for _, warning := range l.State.CurrentMap.AxisWarnings() {
+		mw.logWarn("%s", warning)
+	}
+	switch {
+	case l.AlignErr != nil:
+		mw.logError(i18n.T("gui.compare_identify_failed"), l.AlignErr)
+	case l.NotCompared != "":
+		mw.logWarn("%s not compared: %s", l.State.CurrentMap.Config.Name, l.NotCompared)
+	case l.CompareErr != nil:
+		mw.logError(i18n.T("gui.map.compare_read_failed"), l.CompareErr)
+	}
+}
+
+// readMap reads a map of file through mw.files. It is for the compared
+// file and workers; the open file is read from the session.
+func (mw *MainWindow) readMap(file string, cfg models.MapConfig) (*models.ECUMap, error) {
+	f, err := mw.files.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	return f.ReadMap(cfg)
+}
+
+// compareWith compares the open file with path, or stops comparing when
+// path is empty, and reloads the selected map of both. A diff window keeps
+// comparing its second file.
+func (mw *MainWindow) compareWith(path string) {
+	if mw.diff != nil && path != mw.diff.file2 {
+		mw.showReadOnlyNotice()
+		return
+	}
+	mw.loadView(mw.CurrentFile, path)
+	mw.refreshOutliers()
+}
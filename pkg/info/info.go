@@ -0,0 +1,281 @@
+// Package info assembles the one-screen overview of a binary printed by
+// the info command: identification, hashes, backups, map statistics,
+// parameter values and definition warnings, with a verdict. Everything is
+// computed by the packages that own it; info only collects and prints.
+package info
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/ci"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+	"github.com/tosih/motronic-m21-tool/pkg/stats"
+)
+
+// MapInfo is the one-line summary of a map
+type MapInfo struct {
+	Name string  `json:"name"`
+	Unit string  `json:"unit"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+	// Problem says why the map looks implausible or could not be read,
+	// "" if it looks fine
+	Problem string `json:"problem,omitempty"`
+}
+
+// ParamInfo is a configuration parameter value
+type ParamInfo struct {
+	Name  string  `json:"name"`
+	Unit  string  `json:"unit"`
+	Value float64 `json:"value"`
+	// Problem says why the value is out of range or unreadable
+	Problem string `json:"problem,omitempty"`
+}
+
+// Summary is the overview of one binary. Issues lists every problem
+// found; an empty list means the binary looks OK.
+type Summary struct {
+	File            string `json:"file"`
+	Size            int64  `json:"size"`
+	PartNumber      string `json:"part_number,omitempty"`
+	BoschNumber     string `json:"bosch_number,omitempty"`
+	SoftwareVersion string `json:"software_version,omitempty"`
+	BaseOffset      int64  `json:"base_offset"`
+	// Copies is how often a mirrored dump holds the image, 0 for other
+	// files
+	Copies      int    `json:"copies,omitempty"`
+	SHA256      string `json:"sha256"`
+	Definitions string `json:"definitions"`
+
+	// Checks are the size, identity, checksum and sidecar checks of -ci
+	Checks []ci.CheckResult `json:"checks"`
+
+	Backups      int        `json:"backups"`
+	NewestBackup *time.Time `json:"newest_backup,omitempty"`
+
+	Maps               []MapInfo   `json:"maps"`
+	Params             []ParamInfo `json:"params"`
+	DefinitionWarnings []string    `json:"definition_warnings,omitempty"`
+	Issues             []string    `json:"issues"`
+}
+
+// OK reports whether no issues were found
+func (s *Summary) OK() bool { return len(s.Issues) == 0 }
+
+// summaryChecks are the -ci checks the overview shows; maps and
+// parameters are listed one by one instead
+var summaryChecks = map[string]bool{"size": true, "identity": true, "checksum": true, "sidecar": true}
+
+// Build reads filename and assembles its overview. It only fails if the
+// file cannot be read at all.
+func Build(filename string) (*Summary, error) {
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		return nil, err
+	}
+	id := reader.IdentifyData(data, models.M21IDProfile)
+	s := &Summary{
+		File:            filename,
+		Size:            int64(len(data)),
+		PartNumber:      id.PartNumber,
+		BoschNumber:     id.BoschNumber,
+		SoftwareVersion: id.SoftwareVersion,
+		BaseOffset:      id.BaseOffset,
+		SHA256:          id.SHA256,
+		Definitions:     models.DefinitionsFingerprint(),
+		Issues:          []string{},
+	}
+
+	for _, c := range ci.CheckFile(filename).Checks {
+		if !summaryChecks[c.Name] {
+			continue
+		}
+		s.Checks = append(s.Checks, c)
+		if c.Status == ci.Fail {
+			s.Issues = append(s.Issues, fmt.Sprintf("%s: %s", c.Name, c.Message))
+		}
+	}
+
+	if backups, err := editor.ListBackups(filename); err == nil && len(backups) > 0 {
+		s.Backups = len(backups)
+		newest := backups[len(backups)-1].Time
+		s.NewestBackup = &newest
+	}
+
+	layout := reader.DetectLayout(data)
+	if layout.Mirrored() {
+		s.Copies = layout.Copies
+	}
+	for _, cfg := range models.MapConfigs {
+		located := layout.LocateMap(cfg)
+		mi := MapInfo{Name: cfg.Name, Unit: cfg.Unit}
+		if m, err := reader.ReadMapFromBytes(data, located); err != nil {
+			// Read errors already name the map
+			mi.Problem = "unreadable"
+			s.Issues = append(s.Issues, err.Error())
+		} else {
+			sum := stats.OfMap(m.Data)
+			mi.Min, mi.Max, mi.Mean = sum.Min, sum.Max, sum.Mean
+			if mi.Problem = implausible(cfg, m.Data, sum); mi.Problem != "" {
+				s.Issues = append(s.Issues, fmt.Sprintf("%s: %s", cfg.Name, mi.Problem))
+			}
+		}
+		s.Maps = append(s.Maps, mi)
+	}
+
+	config := reader.ReadParamsFromBytes(data, layout.LocateParams(models.ConfigParams))
+	for _, p := range models.ConfigParams {
+		pi := ParamInfo{Name: p.Name, Unit: p.Unit}
+		value, ok := config.Values[p.Name]
+		switch {
+		case !ok:
+			pi.Problem = "unreadable"
+		case value < p.MinValue || value > p.MaxValue:
+			pi.Problem = fmt.Sprintf("outside %.2f-%.2f", p.MinValue, p.MaxValue)
+		}
+		pi.Value = value
+		if pi.Problem != "" {
+			s.Issues = append(s.Issues, fmt.Sprintf("%s: %s", p.Name, pi.Problem))
+		}
+		s.Params = append(s.Params, pi)
+	}
+	if err := models.CheckLinks(config.Values); err != nil {
+		s.Issues = append(s.Issues, err.Error())
+	}
+
+	// Invalid definitions and exact duplicates are issues like in
+	// -check-defs; partial overlaps are only warnings
+	check := models.CheckDefinitions(models.MapConfigs, models.ConfigParams)
+	for _, err := range check.Errors {
+		s.Issues = append(s.Issues, err.Error())
+	}
+	for _, o := range check.Overlaps {
+		if o.Exact {
+			s.Issues = append(s.Issues, o.String())
+		} else {
+			s.DefinitionWarnings = append(s.DefinitionWarnings, o.String())
+		}
+	}
+	return s, nil
+}
+
+// implausible returns why a map's contents look wrong: every cell the
+// same, as in erased (0xFF) or zeroed areas, or every cell at a limit of
+// its data type. It returns "" for maps that look like calibration data.
+func implausible(cfg models.MapConfig, data [][]float64, sum stats.Summary) string {
+	if sum.Count > 1 && sum.Min == sum.Max {
+		return fmt.Sprintf("every cell is %.2f (blank or erased?)", sum.Min)
+	}
+	lo, hi := models.RawRange(cfg.DataType)
+	limits := map[float64]bool{cfg.ToReal(lo): true, cfg.ToReal(hi): true}
+	for _, row := range data {
+		for _, v := range row {
+			if !limits[v] {
+				return ""
+			}
+		}
+	}
+	return "every cell is at a limit of its data type"
+}
+
+// WriteJSON writes the summary as indented JSON
+func WriteJSON(w io.Writer, s *Summary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Print shows the summary in sections, ending with the verdict line
+func Print(s *Summary) {
+	unknown := func(v string) string {
+		if v == "" {
+			return pterm.Gray("unknown")
+		}
+		return v
+	}
+	flag := func(problem string) string {
+		if problem == "" {
+			return pterm.Green("ok")
+		}
+		return pterm.Red(problem)
+	}
+
+	image := fmt.Sprintf("0x%X", s.BaseOffset)
+	if s.Copies > 1 {
+		image += fmt.Sprintf(" (mirrored, %d copies)", s.Copies)
+	}
+
+	pterm.DefaultSection.Println("Identification")
+	backups := "none"
+	if s.NewestBackup != nil {
+		backups = fmt.Sprintf("%d, newest %s ago", s.Backups, time.Since(*s.NewestBackup).Round(time.Minute))
+	}
+	pterm.DefaultTable.WithData(pterm.TableData{
+		{"File", filepath.Base(s.File)},
+		{"Size", reader.FormatSize(s.Size)},
+		{"Part number", unknown(s.PartNumber)},
+		{"Bosch number", unknown(s.BoschNumber)},
+		{"Software", unknown(s.SoftwareVersion)},
+		{"Image at", image},
+		{"SHA-256", s.SHA256},
+		{"Definitions", s.Definitions},
+		{"Backups", backups},
+	}).Render()
+
+	pterm.DefaultSection.Println("Checks")
+	checks := pterm.TableData{{"Check", "Status", "Detail"}}
+	for _, c := range s.Checks {
+		status := c.Status
+		switch status {
+		case ci.Pass:
+			status = pterm.Green(status)
+		case ci.Fail:
+			status = pterm.Red(status)
+		default:
+			status = pterm.Gray(status)
+		}
+		checks = append(checks, []string{c.Name, status, c.Message})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(checks).Render()
+
+	pterm.DefaultSection.Println("Maps")
+	maps := pterm.TableData{{"Map", "Min", "Max", "Mean", "Unit", "Plausible"}}
+	for _, m := range s.Maps {
+		maps = append(maps, []string{m.Name,
+			fmt.Sprintf("%.2f", m.Min), fmt.Sprintf("%.2f", m.Max), fmt.Sprintf("%.2f", m.Mean),
+			m.Unit, flag(m.Problem)})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(maps).Render()
+
+	pterm.DefaultSection.Println("Parameters")
+	params := pterm.TableData{{"Parameter", "Value", "Unit", "Range"}}
+	for _, p := range s.Params {
+		params = append(params, []string{p.Name, fmt.Sprintf("%.2f", p.Value), p.Unit, flag(p.Problem)})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(params).Render()
+
+	if len(s.DefinitionWarnings) > 0 {
+		pterm.DefaultSection.Println("Definition warnings")
+		for _, w := range s.DefinitionWarnings {
+			pterm.Warning.Println(w)
+		}
+	}
+
+	pterm.Println()
+	if s.OK() {
+		pterm.Success.Printf("%s looks OK\n", filepath.Base(s.File))
+		return
+	}
+	for _, issue := range s.Issues {
+		pterm.Error.Println(issue)
+	}
+	pterm.Error.Printf("%s: %d issue(s)\n", filepath.Base(s.File), len(s.Issues))
+}
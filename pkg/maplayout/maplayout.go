@@ -0,0 +1,138 @@
+// Package maplayout is the geometry of a drawn map: where each cell, the
+// margins and the color legend are, which cell a pointer position hits,
+// and the heat colors of the cells. The GUI draws and hit-tests with it;
+// it has no GTK or cairo dependencies, so it is tested on its own.
+package maplayout
+
+import "math"
+
+// Default margins around the map grid, leaving room for the title, axis
+// labels and color legend
+const (
+	DefaultMarginLeft   = 80.0
+	DefaultMarginRight  = 100.0
+	DefaultMarginTop    = 60.0
+	DefaultMarginBottom = 80.0
+)
+
+// Layout holds the geometry of a drawn map so that drawing and
+// hit-testing always agree on where each cell is
+type Layout struct {
+	Width, Height float64
+	Rows, Cols    int
+
+	MarginLeft, MarginRight, MarginTop, MarginBottom float64
+}
+
+// New returns the layout of a rows×cols map drawn in a widget of the
+// given size
+func New(width, height, rows, cols int) Layout {
+	return Layout{
+		Width:        float64(width),
+		Height:       float64(height),
+		Rows:         rows,
+		Cols:         cols,
+		MarginLeft:   DefaultMarginLeft,
+		MarginRight:  DefaultMarginRight,
+		MarginTop:    DefaultMarginTop,
+		MarginBottom: DefaultMarginBottom,
+	}
+}
+
+// GridWidth returns the width available to the cell grid, never negative
+func (l Layout) GridWidth() float64 {
+	return math.Max(0, l.Width-l.MarginLeft-l.MarginRight)
+}
+
+// GridHeight returns the height available to the cell grid, never negative
+func (l Layout) GridHeight() float64 {
+	return math.Max(0, l.Height-l.MarginTop-l.MarginBottom)
+}
+
+// Valid reports whether the grid has a drawable, non-empty area
+func (l Layout) Valid() bool {
+	return l.Rows > 0 && l.Cols > 0 && l.GridWidth() > 0 && l.GridHeight() > 0
+}
+
+// CellSize returns the width and height of a single cell
+func (l Layout) CellSize() (float64, float64) {
+	if !l.Valid() {
+		return 0, 0
+	}
+	return l.GridWidth() / float64(l.Cols), l.GridHeight() / float64(l.Rows)
+}
+
+// CellOrigin returns the top-left corner of a cell
+func (l Layout) CellOrigin(row, col int) (float64, float64) {
+	cellWidth, cellHeight := l.CellSize()
+	return l.MarginLeft + float64(col)*cellWidth, l.MarginTop + float64(row)*cellHeight
+}
+
+// CellAt returns the cell under a widget position. The grid is treated as
+// half-open, so a point on a shared border belongs to the cell right of or
+// below it, and the outer right and bottom edges are outside the grid.
+// Borders are the exact positions CellOrigin returns for drawing, so
+// rounding in the division can't put a point in a neighboring cell.
+func (l Layout) CellAt(x, y float64) (row, col int, ok bool) {
+	if !l.Valid() {
+		return 0, 0, false
+	}
+
+	gx := x - l.MarginLeft
+	gy := y - l.MarginTop
+	if gx < 0 || gy < 0 || gx >= l.GridWidth() || gy >= l.GridHeight() {
+		return 0, 0, false
+	}
+
+	cellWidth, cellHeight := l.CellSize()
+	col = snapToBorders(int(gx/cellWidth), l.Cols, x, func(i int) float64 {
+		left, _ := l.CellOrigin(0, i)
+		return left
+	})
+	row = snapToBorders(int(gy/cellHeight), l.Rows, y, func(i int) float64 {
+		_, top := l.CellOrigin(i, 0)
+		return top
+	})
+	return row, col, true
+}
+
+// snapToBorders corrects an estimated cell index i of n so that pos lies
+// in [start(i), start(i+1)), where start returns a cell's drawn border
+func snapToBorders(i, n int, pos float64, start func(int) float64) int {
+	i = max(0, min(i, n-1))
+	for i > 0 && pos < start(i) {
+		i--
+	}
+	for i < n-1 && pos >= start(i+1) {
+		i++
+	}
+	return i
+}
+
+// LegendRect returns the position and size of the color legend bar
+func (l Layout) LegendRect() (x, y, width, height float64) {
+	return l.Width - l.MarginRight + 20, l.MarginTop, 60, l.GridHeight()
+}
+
+// HeatColor converts a gradient position from models.HeatScale.Normalize
+// to an RGB color on a blue -> cyan -> green -> yellow -> red gradient
+func HeatColor(normalized float64) (float64, float64, float64) {
+	normalized = math.Max(0, math.Min(1, normalized))
+
+	if normalized < 0.25 {
+		// Blue to Cyan
+		t := normalized / 0.25
+		return 0, t, 1
+	} else if normalized < 0.5 {
+		// Cyan to Green
+		t := (normalized - 0.25) / 0.25
+		return 0, 1, 1 - t
+	} else if normalized < 0.75 {
+		// Green to Yellow
+		t := (normalized - 0.5) / 0.25
+		return t, 1, 0
+	}
+	// Yellow to Red
+	t := (normalized - 0.75) / 0.25
+	return 1, 1 - t, 0
+}
@@ -0,0 +1,171 @@
+package maplayout
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+func TestCellAt(t *testing.T) {
+	// 16 columns over 620px and 8 rows over 300px: cell sizes that don't
+	// divide evenly, so borders fall between pixels
+	l := New(800, 440, 8, 16)
+
+	tests := []struct {
+		name     string
+		x, y     float64
+		row, col int
+		ok       bool
+	}{
+		{name: "first cell", x: DefaultMarginLeft, y: DefaultMarginTop, row: 0, col: 0, ok: true},
+		{name: "last cell", x: 800 - DefaultMarginRight - 0.5, y: 440 - DefaultMarginBottom - 0.5, row: 7, col: 15, ok: true},
+		{name: "left of the grid", x: DefaultMarginLeft - 0.5, y: 200, ok: false},
+		{name: "above the grid", x: 200, y: DefaultMarginTop - 0.5, ok: false},
+		{name: "right edge", x: 800 - DefaultMarginRight, y: 200, ok: false},
+		{name: "bottom edge", x: 200, y: 440 - DefaultMarginBottom, ok: false},
+		{name: "shared border goes right and down", x: DefaultMarginLeft + 620.0/16, y: DefaultMarginTop + 300.0/8, row: 1, col: 1, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, col, ok := l.CellAt(tt.x, tt.y)
+			if ok != tt.ok || ok && (row != tt.row || col != tt.col) {
+				t.Errorf("CellAt(%g, %g) = %d, %d, %v; want %d, %d, %v", tt.x, tt.y, row, col, ok, tt.row, tt.col, tt.ok)
+			}
+		})
+	}
+}
+
+// TestCellAtEveryPixel checks that hit-testing agrees with drawing at the
+// center of every pixel for a range of sizes and shapes
+func TestCellAtEveryPixel(t *testing.T) {
+	shapes := []struct{ rows, cols int }{{1, 1}, {1, 16}, {8, 8}, {8, 16}, {16, 16}, {3, 7}}
+	sizes := []struct{ width, height int }{{200, 160}, {557, 331}, {800, 600}, {1023, 767}}
+	for _, shape := range shapes {
+		for _, size := range sizes {
+			l := New(size.width, size.height, shape.rows, shape.cols)
+			for py := 0; py < size.height; py++ {
+				for px := 0; px < size.width; px++ {
+					x, y := float64(px)+0.5, float64(py)+0.5
+					row, col, ok := l.CellAt(x, y)
+					inside := x >= l.MarginLeft && x < l.MarginLeft+l.GridWidth() && y >= l.MarginTop && y < l.MarginTop+l.GridHeight()
+					if ok != inside {
+						t.Fatalf("%dx%d in %dx%d: CellAt(%g, %g) ok = %v, want %v", shape.rows, shape.cols, size.width, size.height, x, y, ok, inside)
+					}
+					if !ok {
+						continue
+					}
+					// The cell's far borders are where its neighbors are drawn
+					left, top := l.CellOrigin(row, col)
+					right, bottom := l.CellOrigin(row+1, col+1)
+					if x < left || x >= right || y < top || y >= bottom {
+						t.Fatalf("%dx%d in %dx%d: (%g, %g) hit cell %d,%d drawn from %g,%g to %g,%g",
+							shape.rows, shape.cols, size.width, size.height, x, y, row, col, left, top, right, bottom)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestCellAtBorders checks the exact drawn borders, where rounding in the
+// division would go wrong: a point on a border belongs to the cell after
+// it and the largest value before it to the cell before
+func TestCellAtBorders(t *testing.T) {
+	for _, size := range []struct{ width, height int }{{557, 331}, {800, 440}, {1023, 767}} {
+		for _, n := range []int{3, 7, 8, 16} {
+			l := New(size.width, size.height, n, n)
+			midX, midY := l.MarginLeft+l.GridWidth()/2, l.MarginTop+l.GridHeight()/2
+			for i := 1; i < n; i++ {
+				x, y := l.CellOrigin(i, i)
+				if _, col, ok := l.CellAt(x, midY); !ok || col != i {
+					t.Errorf("%dx%d, %d cells: x %v on the border of column %d hits column %d", size.width, size.height, n, x, i, col)
+				}
+				if _, col, ok := l.CellAt(math.Nextafter(x, 0), midY); !ok || col != i-1 {
+					t.Errorf("%dx%d, %d cells: x just before %v hits column %d, want %d", size.width, size.height, n, x, col, i-1)
+				}
+				if row, _, ok := l.CellAt(midX, y); !ok || row != i {
+					t.Errorf("%dx%d, %d cells: y %v on the border of row %d hits row %d", size.width, size.height, n, y, i, row)
+				}
+				if row, _, ok := l.CellAt(midX, math.Nextafter(y, 0)); !ok || row != i-1 {
+					t.Errorf("%dx%d, %d cells: y just before %v hits row %d, want %d", size.width, size.height, n, y, row, i-1)
+				}
+			}
+		}
+	}
+}
+
+func TestLayoutTooSmall(t *testing.T) {
+	tests := []struct {
+		name                string
+		width, height, rows int
+	}{
+		{name: "narrower than the margins", width: 150, height: 400, rows: 8},
+		{name: "lower than the margins", width: 600, height: 120, rows: 8},
+		{name: "no rows", width: 600, height: 400, rows: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.width, tt.height, tt.rows, 8)
+			if l.Valid() {
+				t.Error("Valid() = true")
+			}
+			if w, h := l.CellSize(); w != 0 || h != 0 {
+				t.Errorf("CellSize() = %g, %g; want 0, 0", w, h)
+			}
+			if _, _, ok := l.CellAt(100, 100); ok {
+				t.Error("CellAt hit a cell")
+			}
+		})
+	}
+}
+
+func TestHeatColor(t *testing.T) {
+	tests := []struct {
+		normalized float64
+		r, g, b    float64
+	}{
+		{-1, 0, 0, 1}, // clamped
+		{0, 0, 0, 1},  // blue
+		{0.25, 0, 1, 1},
+		{0.5, 0, 1, 0},  // green
+		{0.75, 1, 1, 0}, // yellow
+		{1, 1, 0, 0},    // red
+		{2, 1, 0, 0},    // clamped
+		{0.125, 0, 0.5, 1},
+		{0.875, 1, 0.5, 0},
+	}
+	for _, tt := range tests {
+		r, g, b := HeatColor(tt.normalized)
+		if math.Abs(r-tt.r) > 1e-9 || math.Abs(g-tt.g) > 1e-9 || math.Abs(b-tt.b) > 1e-9 {
+			t.Errorf("HeatColor(%g) = %g, %g, %g; want %g, %g, %g", tt.normalized, r, g, b, tt.r, tt.g, tt.b)
+		}
+	}
+}
+
+// TestHeatScaleColors runs values through the min/max scale the map view
+// colors with, so the smallest and largest cells get the ends of the
+// gradient
+func TestHeatScaleColors(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  [][]float64
+		value float64
+		want  float64
+	}{
+		{name: "minimum", data: [][]float64{{10, 20}, {30, 50}}, value: 10, want: 0},
+		{name: "maximum", data: [][]float64{{10, 20}, {30, 50}}, value: 50, want: 1},
+		{name: "middle", data: [][]float64{{10, 20}, {30, 50}}, value: 30, want: 0.5},
+		{name: "negative", data: [][]float64{{-8, 0}, {4, 8}}, value: 0, want: 0.5},
+		{name: "flat", data: [][]float64{{7, 7}, {7, 7}}, value: 7, want: 0.5},
+		{name: "empty", data: nil, value: 0, want: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scale := models.MapConfig{}.HeatScale(tt.data)
+			if got := scale.Normalize(tt.value); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Normalize(%g) = %g, want %g", tt.value, got, tt.want)
+			}
+		})
+	}
+}
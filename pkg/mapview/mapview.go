@@ -0,0 +1,191 @@
+// Package mapview is what the GUI's map view shows: the open file and its
+// selected map, the file and map compared with them, and which of the two
+// (or their difference) is drawn. The GUI keeps one State, replaces it
+// whole on the GTK main loop and draws from a copy; the transitions
+// between states are plain functions with no GTK dependencies, so they
+// are tested on their own.
+package mapview
+
+import (
+	"slices"
+
+	"github.com/tosih/motronic-m21-tool/pkg/compare"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// Source selects which data the map view draws while comparing
+type Source int
+
+const (
+	// Current draws the loaded file's map
+	Current Source = iota
+	// Compare draws the comparison file's map
+	Compare
+	// Delta draws comparison minus current, cell by cell
+	Delta
+	// NumSources is the number of sources
+	NumSources
+)
+
+// State is one view of the map: which files and maps it holds and which
+// of them is drawn. Its maps are never changed in place, so a copy stays
+// as it was taken.
+type State struct {
+	CurrentFile string
+	CurrentMap  *models.ECUMap
+	CompareFile string
+	CompareMap  *models.ECUMap
+	Source      Source
+}
+
+// Normalized returns s as it can be shown: without a comparison map only
+// the current map can be drawn
+func (s State) Normalized() State {
+	if s.CompareMap == nil {
+		s.Source = Current
+	}
+	return s
+}
+
+// Cycled returns s with the source moved by steps through current,
+// comparison and delta. Without a comparison map s is returned unchanged.
+func (s State) Cycled(steps int) State {
+	if s.CompareMap == nil {
+		return s
+	}
+	s.Source = ((s.Source+Source(steps))%NumSources + NumSources) % NumSources
+	return s
+}
+
+// WithCell returns s with one cell of the current map set to value. The
+// map is copied rather than changed in place.
+func (s State) WithCell(row, col int, value float64) State {
+	m := *s.CurrentMap
+	m.Data = make([][]float64, len(s.CurrentMap.Data))
+	for i, cells := range s.CurrentMap.Data {
+		m.Data[i] = slices.Clone(cells)
+	}
+	m.Data[row][col] = value
+	s.CurrentMap = &m
+	return s
+}
+
+// Displayed returns the map the view draws for the active source. The
+// delta map uses min/max coloring and no highlight threshold, since the
+// map's own color bands and threshold are meaningless for differences.
+func (s State) Displayed() *models.ECUMap {
+	if s.CompareMap == nil {
+		return s.CurrentMap
+	}
+	switch s.Source {
+	case Compare:
+		return s.CompareMap
+	case Delta:
+		cfg := s.CurrentMap.Config
+		cfg.Unit = "Δ " + cfg.Unit
+		cfg.ColorScale = models.ColorScale{}
+		cfg.HighlightBelow = nil
+		data := make([][]float64, len(s.CurrentMap.Data))
+		for i, row := range s.CurrentMap.Data {
+			data[i] = make([]float64, len(row))
+			for j, value := range row {
+				data[i][j] = s.CompareMap.Data[i][j] - value
+			}
+		}
+		return &models.ECUMap{Config: cfg, Data: data, XAxis: s.CurrentMap.XAxis, YAxis: s.CurrentMap.YAxis}
+	}
+	return s.CurrentMap
+}
+
+// Scale returns the color scale for m, the result of Displayed. While
+// comparing, both files are colored on one scale resolved over both, so
+// cycling between them keeps equal values the same color, and the delta
+// on a scale symmetric around zero (see compare.SharedScales).
+func (s State) Scale(m *models.ECUMap) models.HeatScale {
+	if s.CompareMap == nil {
+		return m.Config.HeatScale(m.Data)
+	}
+	var delta [][]float64
+	if s.Source == Delta {
+		delta = m.Data
+	}
+	scales := compare.SharedScales(s.CurrentMap.Config, s.CurrentMap.Data, s.CompareMap.Data, delta)
+	if delta != nil {
+		return scales.Delta
+	}
+	return scales.Shared
+}
+
+// Request is everything Load needs, taken from the window on the main
+// loop so that Load can run anywhere
+type Request struct {
+	File        string
+	CompareFile string
+	Source      Source
+	// Config is the selected map, nil if there is none
+	Config *models.MapConfig
+	// Image is the open file as the session holds it
+	Image *reader.ECUFile
+	// Files opens the compared file
+	Files *reader.ECUFiles
+}
+
+// Loaded is the result of Load: the state to show and why parts of it
+// are missing
+type Loaded struct {
+	State State
+	// ReadErr is why the current map couldn't be read; the state then
+	// holds no maps
+	ReadErr error
+	// AlignErr is why the files couldn't be identified for comparing
+	AlignErr error
+	// NotCompared is why the selected map isn't compared, such as "out
+	// of range in file2"
+	NotCompared string
+	// CompareErr is why the comparison map couldn't be read
+	CompareErr error
+}
+
+// Load reads the selected map of the open file, and of the compared file
+// if there is one. It touches nothing but r, so workers may call it and
+// hand the result to the main loop. A comparison map that can't be read is
+// left out; a current map that can't be read leaves the state without
+// maps rather than showing the previous file's map under the new name.
+func Load(r Request) Loaded {
+	l := Loaded{State: State{CurrentFile: r.File, CompareFile: r.CompareFile, Source: r.Source}}
+	if r.File == "" || r.Config == nil {
+		return l
+	}
+	cfg := *r.Config
+	if r.Image == nil {
+		l.ReadErr = reader.NewError(reader.ErrNotFound, "no image of %s", r.File)
+		return l
+	}
+	current, err := r.Image.ReadMap(cfg)
+	if err != nil {
+		l.ReadErr = err
+		return l
+	}
+	l.State.CurrentMap = current
+	if r.CompareFile == "" {
+		return l
+	}
+
+	// The comparison map is translated to the compared file's base offset
+	align, err := compare.Align(r.File, r.CompareFile)
+	if err != nil {
+		l.AlignErr = err
+		return l
+	}
+	if l.NotCompared = align.SkipReason(cfg); l.NotCompared != "" {
+		return l
+	}
+	_, cfg2 := align.Locate(cfg)
+	f, err := r.Files.Open(r.CompareFile)
+	if err == nil {
+		l.State.CompareMap, err = f.ReadMap(cfg2)
+	}
+	l.CompareErr = err
+	return l
+}
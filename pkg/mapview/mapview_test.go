@@ -0,0 +1,241 @@
+package mapview
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// writeFiles writes images to a temporary directory, each with the first
+// fuel cell raised by its index, and returns their paths
+func writeFiles(t *testing.T, n int) []string {
+	t.Helper()
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	fuel := models.MapConfigs[0]
+	var paths []string
+	for i := range n {
+		data := testbin.Image()
+		data[fuel.Offset] += byte(i)
+		path := filepath.Join(dir, fmt.Sprintf("ecu%d.bin", i))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// request returns a request for the fuel map of file, compared with
+// compareFile unless it is empty
+func request(t *testing.T, files *reader.ECUFiles, file, compareFile string) Request {
+	t.Helper()
+	img, err := files.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := models.MapConfigs[0]
+	return Request{File: file, CompareFile: compareFile, Config: &cfg, Image: img, Files: files}
+}
+
+func TestCycled(t *testing.T) {
+	m := &models.ECUMap{Data: [][]float64{{1}}}
+	tests := []struct {
+		name   string
+		state  State
+		steps  int
+		source Source
+	}{
+		{name: "forward", state: State{CurrentMap: m, CompareMap: m}, steps: 1, source: Compare},
+		{name: "wraps forward", state: State{CurrentMap: m, CompareMap: m, Source: Delta}, steps: 1, source: Current},
+		{name: "wraps backward", state: State{CurrentMap: m, CompareMap: m}, steps: -1, source: Delta},
+		{name: "several", state: State{CurrentMap: m, CompareMap: m}, steps: 5, source: Delta},
+		{name: "nothing to compare", state: State{CurrentMap: m}, steps: 1, source: Current},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.Cycled(tt.steps).Source; got != tt.source {
+				t.Errorf("source %d, want %d", got, tt.source)
+			}
+		})
+	}
+}
+
+// Without a comparison map the view falls back to the current map
+func TestNormalized(t *testing.T) {
+	m := &models.ECUMap{Data: [][]float64{{1}}}
+	if s := (State{CurrentMap: m, Source: Delta}).Normalized(); s.Source != Current {
+		t.Errorf("source %d without a comparison, want Current", s.Source)
+	}
+	if s := (State{CurrentMap: m, CompareMap: m, Source: Delta}).Normalized(); s.Source != Delta {
+		t.Errorf("source %d while comparing, want Delta", s.Source)
+	}
+}
+
+// A cell edit leaves earlier copies of the state as they were
+func TestWithCell(t *testing.T) {
+	before := State{CurrentMap: &models.ECUMap{Data: [][]float64{{1, 2}, {3, 4}}}}
+	after := before.WithCell(1, 0, 9)
+	if after.CurrentMap.Data[1][0] != 9 {
+		t.Errorf("edited cell %g, want 9", after.CurrentMap.Data[1][0])
+	}
+	if before.CurrentMap.Data[1][0] != 3 {
+		t.Errorf("the earlier state changed to %g", before.CurrentMap.Data[1][0])
+	}
+}
+
+func TestDisplayed(t *testing.T) {
+	limit := 2.0
+	current := &models.ECUMap{Config: models.MapConfig{Unit: "ms", HighlightBelow: &limit}, Data: [][]float64{{1, 2}}}
+	other := &models.ECUMap{Config: current.Config, Data: [][]float64{{1.5, 1}}}
+	s := State{CurrentMap: current, CompareMap: other}
+	if s.Displayed() != current {
+		t.Error("Current doesn't draw the current map")
+	}
+	if s.Source = Compare; s.Displayed() != other {
+		t.Error("Compare doesn't draw the comparison map")
+	}
+	s.Source = Delta
+	delta := s.Displayed()
+	if !slices.Equal(delta.Data[0], []float64{0.5, -1}) || delta.Config.Unit != "Δ ms" || delta.Config.HighlightBelow != nil {
+		t.Errorf("delta %v in %q with threshold %v", delta.Data, delta.Config.Unit, delta.Config.HighlightBelow)
+	}
+	if scale := s.Scale(delta); scale.Min() != -scale.Max() {
+		t.Errorf("delta scale %+v isn't centered on zero", scale)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	paths := writeFiles(t, 2)
+	short := filepath.Join(t.TempDir(), "short.bin")
+	if err := os.WriteFile(short, testbin.Image()[:0x4000], 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := &reader.ECUFiles{}
+
+	t.Run("no file", func(t *testing.T) {
+		l := Load(Request{Source: Delta})
+		if l.State.CurrentMap != nil || l.ReadErr != nil {
+			t.Errorf("loaded %+v without a file", l)
+		}
+	})
+	t.Run("no map selected", func(t *testing.T) {
+		r := request(t, files, paths[0], "")
+		r.Config = nil
+		if l := Load(r); l.State.CurrentMap != nil || l.ReadErr != nil {
+			t.Errorf("loaded %+v without a map", l)
+		}
+	})
+	t.Run("no image", func(t *testing.T) {
+		r := request(t, files, paths[0], paths[1])
+		r.Image = nil
+		if l := Load(r); l.State.CurrentMap != nil || l.State.CompareMap != nil || l.ReadErr == nil {
+			t.Errorf("loaded %+v without an image", l)
+		}
+	})
+	t.Run("map past the end", func(t *testing.T) {
+		l := Load(request(t, files, short, paths[0]))
+		if !errors.Is(l.ReadErr, reader.ErrOutOfRange) || l.State.CurrentMap != nil || l.State.CompareMap != nil {
+			t.Errorf("loaded %+v from a short dump, want ErrOutOfRange and no maps", l)
+		}
+	})
+	t.Run("current only", func(t *testing.T) {
+		l := Load(request(t, files, paths[0], ""))
+		if l.State.CurrentMap == nil || l.State.CompareMap != nil || l.State.CurrentFile != paths[0] {
+			t.Errorf("loaded %+v", l)
+		}
+	})
+	t.Run("compared", func(t *testing.T) {
+		r := request(t, files, paths[0], paths[1])
+		r.Source = Delta
+		l := Load(r)
+		if l.State.CurrentMap == nil || l.State.CompareMap == nil || l.State.Source != Delta {
+			t.Fatalf("loaded %+v", l)
+		}
+		if d := l.State.CompareMap.Data[0][0] - l.State.CurrentMap.Data[0][0]; d <= 0 {
+			t.Errorf("comparison map isn't the second file's: first cells differ by %g", d)
+		}
+	})
+	t.Run("compared file missing", func(t *testing.T) {
+		l := Load(request(t, files, paths[0], filepath.Join(t.TempDir(), "missing.bin")))
+		if l.AlignErr == nil || l.State.CurrentMap == nil || l.State.CompareMap != nil {
+			t.Errorf("loaded %+v, want the current map and an alignment error", l)
+		}
+	})
+	t.Run("map not in the compared file", func(t *testing.T) {
+		l := Load(request(t, files, paths[0], short))
+		if l.NotCompared == "" || l.State.CurrentMap == nil || l.State.CompareMap != nil {
+			t.Errorf("loaded %+v, want the current map and a reason", l)
+		}
+	})
+}
+
+// Workers load views of many file pairs at once and hand them to one
+// goroutine standing in for the GTK main loop, which replaces and draws
+// the state. Run with -race: the workers share only their requests, and
+// every drawn state pairs the maps of its own files.
+func TestConcurrentLoads(t *testing.T) {
+	paths := writeFiles(t, 4)
+	files := &reader.ECUFiles{}
+	var requests []Request
+	for _, a := range paths {
+		for _, b := range paths {
+			if a != b {
+				requests = append(requests, request(t, files, a, b))
+			}
+		}
+	}
+	// The first fuel cell of each file, to tell whose map a state holds
+	first := make(map[string]float64)
+	for _, p := range paths {
+		first[p] = Load(request(t, files, p, "")).State.CurrentMap.Data[0][0]
+	}
+
+	// view is only touched on the main loop
+	var view State
+	mainLoop := make(chan func())
+	var workers sync.WaitGroup
+	for i, r := range requests {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			r.Source = Source(i) % NumSources
+			l := Load(r)
+			mainLoop <- func() { view = l.State.Normalized() }
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(mainLoop)
+	}()
+
+	shown := 0
+	for f := range mainLoop {
+		f()
+		v := view
+		if v.CurrentMap == nil || v.CompareMap == nil {
+			t.Fatalf("state %+v lacks a map", v)
+		}
+		if v.CurrentMap.Data[0][0] != first[v.CurrentFile] || v.CompareMap.Data[0][0] != first[v.CompareFile] {
+			t.Errorf("state of %s and %s holds the maps of other files", v.CurrentFile, v.CompareFile)
+		}
+		if v.Displayed() == nil {
+			t.Error("nothing to draw")
+		}
+		if v = v.Cycled(1).WithCell(0, 0, -1); view.CurrentMap.Data[0][0] == -1 {
+			t.Error("an edit of a copy reached the shown state")
+		}
+		shown++
+	}
+	if shown != len(requests) {
+		t.Errorf("%d states shown, want %d", shown, len(requests))
+	}
+}
@@ -0,0 +1,249 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MaxLoad is the load, in percent, at the high-load end of a map's rows.
+// Maps without a YAxis are labelled on one evenly spaced axis from 0% to
+// MaxLoad: the first and last stored rows sit at the two ends, so an 8-row
+// map reads 0, 14, 29, 43, 57, 71, 86, 100.
+//
+// Rows are always shown in stored order, row 0 at the top, so row indexes
+// in -nudge, -scale-region and the editors match what is on screen. Row 0
+// is the low-load row unless the map's InvertY is set.
+const MaxLoad = 100.0
+
+// MaxRPM is the end of the evenly spaced RPM axis of maps without an
+// XAxis: column j is labelled j*MaxRPM/cols
+const MaxRPM = 8000.0
+
+// AxisConfig locates the breakpoints of one map axis, stored in the binary
+// as Count consecutive values of DataType. Values convert linearly like
+// map cells: raw*Scale + Offset2.
+type AxisConfig struct {
+	Offset   int64
+	Count    int
+	DataType string
+	Scale    float64
+	Offset2  float64
+	Unit     string
+	// Endianness is the byte order of 16-bit breakpoints; empty follows
+	// the map
+	Endianness Endianness `json:",omitempty"`
+	// Name captions the axis in headers, e.g. "Temp"; empty uses the Unit
+	Name string `json:",omitempty"`
+	// Labels are fixed breakpoints for an axis whose table hasn't been
+	// located in the binary. With them, Offset, DataType and Scale are
+	// unused and Count is the number of labels.
+	Labels []float64 `json:",omitempty"`
+}
+
+// ByteSize returns the number of bytes the breakpoints occupy, none for
+// fixed Labels
+func (a AxisConfig) ByteSize() int64 {
+	if a.Labels != nil {
+		return 0
+	}
+	return int64(a.Count * DataTypeSize(a.DataType))
+}
+
+// caption returns the axis name for headers: Name, Unit or fallback
+func (a *AxisConfig) caption(fallback string) string {
+	switch {
+	case a == nil:
+		return fallback
+	case a.Name != "":
+		return a.Name
+	case a.Unit != "":
+		return a.Unit
+	}
+	return fallback
+}
+
+// ToReal converts a raw breakpoint to its engineering value
+func (a AxisConfig) ToReal(raw int64) float64 {
+	return RawToReal(raw, a.Scale, a.Offset2, ConversionLinear)
+}
+
+// CheckAxis validates an axis definition for a map dimension of want
+// cells (columns for XAxis, rows for YAxis)
+func CheckAxis(a AxisConfig, want int) error {
+	if a.Count != want {
+		return fmt.Errorf("has %d breakpoints for %d cells", a.Count, want)
+	}
+	if a.Labels != nil {
+		if len(a.Labels) != a.Count {
+			return fmt.Errorf("has %d labels for %d breakpoints", len(a.Labels), a.Count)
+		}
+		return nil
+	}
+	if !KnownDataType(a.DataType) {
+		return fmt.Errorf("unknown data type %q", a.DataType)
+	}
+	if err := CheckEndianness(a.Endianness); err != nil {
+		return err
+	}
+	return CheckScale(a.Scale)
+}
+
+// CheckAxes validates the XAxis and YAxis of every map that has one
+func CheckAxes(maps []MapConfig) []error {
+	var errs []error
+	for _, m := range maps {
+		if m.XAxis != nil {
+			if err := CheckAxis(*m.XAxis, m.Cols); err != nil {
+				errs = append(errs, fmt.Errorf("map %q RPM axis: %w", m.Name, err))
+			}
+		}
+		if m.YAxis != nil && m.IsCurve() {
+			errs = append(errs, fmt.Errorf("map %q is a curve and has no load axis", m.Name))
+		} else if m.YAxis != nil {
+			if err := CheckAxis(*m.YAxis, m.Rows); err != nil {
+				errs = append(errs, fmt.Errorf("map %q load axis: %w", m.Name, err))
+			}
+		}
+	}
+	return errs
+}
+
+// Relocate returns the definition with its map and axis offsets moved by
+// base, the image's base offset from identification
+func (c MapConfig) Relocate(base int64) MapConfig {
+	c.Offset += base
+	if c.XAxis != nil {
+		x := *c.XAxis
+		x.Offset += base
+		c.XAxis = &x
+	}
+	if c.YAxis != nil {
+		y := *c.YAxis
+		y.Offset += base
+		c.YAxis = &y
+	}
+	return c
+}
+
+// NonMonotonic returns the index of the first breakpoint that breaks a
+// strictly increasing or strictly decreasing run, or -1 if there is none.
+// Such an axis is most likely a wrong offset or data type.
+func NonMonotonic(values []float64) int {
+	if len(values) < 2 {
+		return -1
+	}
+	increasing := values[1] > values[0]
+	for i := 1; i < len(values); i++ {
+		if values[i] == values[i-1] || (values[i] > values[i-1]) != increasing {
+			return i
+		}
+	}
+	return -1
+}
+
+// AxisLabel formats a breakpoint, without decimals when it is whole
+func AxisLabel(v float64) string {
+	if v == math.Trunc(v) {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// RPMLabel returns the synthetic label of a column on the evenly spaced
+// RPM axis, for maps without an XAxis
+func (c MapConfig) RPMLabel(col int) string {
+	if c.Cols < 1 {
+		return "0"
+	}
+	return strconv.Itoa(col * (int(MaxRPM) / c.Cols))
+}
+
+// ColumnLabels returns the RPM axis labels of m: its XAxis breakpoints,
+// or the synthetic RPMLabel of each column
+func (m *ECUMap) ColumnLabels() []string {
+	labels := make([]string, m.Config.Cols)
+	for j := range labels {
+		if len(m.XAxis) == m.Config.Cols {
+			labels[j] = AxisLabel(m.XAxis[j])
+		} else {
+			labels[j] = m.Config.RPMLabel(j)
+		}
+	}
+	return labels
+}
+
+// RowLabels returns the load axis labels of m: its YAxis breakpoints, or
+// the synthetic LoadLabel of each row
+func (m *ECUMap) RowLabels() []string {
+	if len(m.YAxis) != m.Config.Rows {
+		return m.Config.LoadLabels()
+	}
+	labels := make([]string, m.Config.Rows)
+	for i := range labels {
+		labels[i] = AxisLabel(m.YAxis[i])
+	}
+	return labels
+}
+
+// AxisWarnings describes the breakpoint axes of m that are not monotonic.
+// Views still show them as stored, with these warnings next to the map.
+func (m *ECUMap) AxisWarnings() []string {
+	var warnings []string
+	check := func(axis string, values []float64) {
+		if i := NonMonotonic(values); i >= 0 {
+			warnings = append(warnings, fmt.Sprintf("%s axis of %s is not monotonic: %s follows %s at index %d",
+				axis, m.Config.Name, AxisLabel(values[i]), AxisLabel(values[i-1]), i))
+		}
+	}
+	check("RPM", m.XAxis)
+	check("load", m.YAxis)
+	return warnings
+}
+
+// LoadAt returns the load, in percent, of a row of the map
+func (c MapConfig) LoadAt(row int) float64 {
+	if c.Rows < 2 {
+		return 0
+	}
+	if c.InvertY {
+		row = c.Rows - 1 - row
+	}
+	return float64(row) * MaxLoad / float64(c.Rows-1)
+}
+
+// LoadLabel returns the axis label of a row, its load rounded to a whole
+// percent
+func (c MapConfig) LoadLabel(row int) string {
+	return fmt.Sprintf("%d%%", int(math.Round(c.LoadAt(row))))
+}
+
+// LoadLabels returns the axis labels of all rows, in stored order: the
+// fixed Labels of the YAxis, or the load of each row
+func (c MapConfig) LoadLabels() []string {
+	labels := make([]string, c.Rows)
+	for i := range labels {
+		if c.YAxis != nil && len(c.YAxis.Labels) == c.Rows {
+			labels[i] = AxisLabel(c.YAxis.Labels[i])
+		} else {
+			labels[i] = c.LoadLabel(i)
+		}
+	}
+	return labels
+}
+
+// LoadRow returns the row whose load is nearest to load, in percent. It
+// reports false for loads outside 0 to MaxLoad.
+func (c MapConfig) LoadRow(load float64) (int, bool) {
+	if load < 0 || load > MaxLoad || c.Rows < 1 {
+		return 0, false
+	}
+	if c.Rows == 1 {
+		return 0, true
+	}
+	row := int(math.Round(load / MaxLoad * float64(c.Rows-1)))
+	if c.InvertY {
+		row = c.Rows - 1 - row
+	}
+	return row, true
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestLoadAxisGolden pins the load axis of every built-in map, in stored
+// order, against testdata/load_axes.golden: row 0 is 0% unless InvertY is
+// set, and the last row is 100%. Maps with fixed row labels list those. An inverted copy of the Main Fuel Map
+// pins the other orientation.
+func TestLoadAxisGolden(t *testing.T) {
+	inverted := MapConfigs[0]
+	inverted.Name += " (InvertY)"
+	inverted.InvertY = true
+
+	var buf bytes.Buffer
+	for _, cfg := range append(append([]MapConfig(nil), MapConfigs...), inverted) {
+		fmt.Fprintf(&buf, "%s: %s\n", cfg.Name, strings.Join(cfg.LoadLabels(), " "))
+	}
+	path := filepath.Join("testdata", "load_axes.golden")
+	if *update {
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("load axes differ from %s; run go test -update if that is intended\ngot:\n%s", path, buf.String())
+	}
+}
+
+// LoadRow finds the row LoadAt places a load at, in both orientations
+func TestLoadRow(t *testing.T) {
+	for _, invert := range []bool{false, true} {
+		cfg := MapConfig{Rows: 8, Cols: 16, InvertY: invert}
+		for row := range cfg.Rows {
+			if got, ok := cfg.LoadRow(cfg.LoadAt(row)); got != row || !ok {
+				t.Errorf("InvertY %v: row %d is at %g%%, which LoadRow puts in row %d", invert, row, cfg.LoadAt(row), got)
+			}
+		}
+		if _, ok := cfg.LoadRow(100.5); ok {
+			t.Errorf("InvertY %v: LoadRow accepted 100.5%%", invert)
+		}
+	}
+}
+
+// The cold start map is found by role and captions its rows with the
+// fixed temperature labels of its YAxis, which must match the row count
+func TestFixedAxisLabels(t *testing.T) {
+	cfg, ok := MapByRole(RoleColdStart)
+	if !ok {
+		t.Fatal("no built-in map has the cold start role")
+	}
+	if got := cfg.GridHeader(); got != "Temp\\RPM" {
+		t.Errorf("cold start grid header is %q, want Temp\\RPM", got)
+	}
+	if got := strings.Join(cfg.LoadLabels(), " "); got != "-30 -15 0 15 30 50 70 90" {
+		t.Errorf("cold start rows are labeled %s", got)
+	}
+	if err := CheckAxis(*cfg.YAxis, cfg.Rows); err != nil {
+		t.Errorf("cold start axis: %v", err)
+	}
+	if cfg.YAxis.ByteSize() != 0 || len(AxisRegions([]MapConfig{cfg})) != 0 {
+		t.Error("a labeled axis claims bytes in the binary")
+	}
+
+	short := AxisConfig{Count: 8, Labels: []float64{1, 2, 3}}
+	if err := CheckAxis(short, 8); err == nil {
+		t.Error("CheckAxis accepted 3 labels for 8 breakpoints")
+	}
+	if got := MapConfigs[0].GridHeader(); got != "Load\\RPM" {
+		t.Errorf("fuel map grid header is %q, want Load\\RPM", got)
+	}
+}
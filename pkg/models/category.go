@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Map categories
+const (
+	CategoryFuel         = "Fuel"
+	CategoryIgnition     = "Ignition"
+	CategoryLambda       = "Lambda"
+	CategoryCorrections  = "Corrections"
+	CategoryExperimental = "Experimental"
+	// CategoryOther holds the maps without a category
+	CategoryOther = "Other"
+)
+
+// Categories are the categories a definition can name, in listing order.
+// CategoryOther follows them.
+var Categories = []string{CategoryFuel, CategoryIgnition, CategoryLambda, CategoryCorrections, CategoryExperimental}
+
+// FindCategory looks up a category by name, ignoring case, including
+// CategoryOther
+func FindCategory(name string) (string, bool) {
+	for _, c := range append(Categories, CategoryOther) {
+		if strings.EqualFold(c, name) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// CategoryName returns the category the map is listed under:
+// CategoryOther when it has none or names an unknown one
+func (cfg MapConfig) CategoryName() string {
+	if c, ok := FindCategory(cfg.Category); ok {
+		return c
+	}
+	return CategoryOther
+}
+
+// CategoryGroup is the maps of one category, by index into the
+// definitions they were grouped from
+type CategoryGroup struct {
+	Name    string
+	Indices []int
+}
+
+// GroupByCategory groups configs in the order of Categories, then
+// CategoryOther. Maps keep their order within a group and empty groups
+// are left out.
+func GroupByCategory(configs []MapConfig) []CategoryGroup {
+	var groups []CategoryGroup
+	for _, name := range append(Categories, CategoryOther) {
+		group := CategoryGroup{Name: name}
+		for i, cfg := range configs {
+			if cfg.CategoryName() == name {
+				group.Indices = append(group.Indices, i)
+			}
+		}
+		if len(group.Indices) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// SelectMaps returns all maps for "all", otherwise the maps whose name
+// contains sel or whose category is sel, ignoring case
+func SelectMaps(sel string) []MapConfig {
+	if sel == "all" {
+		return MapConfigs
+	}
+	var selected []MapConfig
+	for _, cfg := range MapConfigs {
+		if strings.Contains(strings.ToLower(cfg.Name), strings.ToLower(sel)) || strings.EqualFold(cfg.CategoryName(), sel) {
+			selected = append(selected, cfg)
+		}
+	}
+	return selected
+}
+
+// CheckCategory returns an error if category is neither empty nor one of
+// Categories and CategoryOther
+func CheckCategory(category string) error {
+	if _, ok := FindCategory(category); category != "" && !ok {
+		return fmt.Errorf("unknown category %q (known: %s)", category, strings.Join(append(Categories, CategoryOther), ", "))
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package models
+
+// ChecksumRegion is a byte range covered by a checksum, relative to the
+// image's base offset, End exclusive
+type ChecksumRegion struct {
+	Start int64
+	End   int64
+}
+
+// ChecksumConfig says how a profile's images are checksummed. The stored
+// checksum is Width bytes at Store (relative to the base offset) in the
+// profile's byte order; the algorithm decides the width (see pkg/checksum).
+type ChecksumConfig struct {
+	// Algorithm names an entry of checksum.Algorithms
+	Algorithm string
+	Regions   []ChecksumRegion
+	Store     int64
+}
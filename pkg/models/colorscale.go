@@ -0,0 +1,173 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Heatmap color scaling modes
+const (
+	// ScaleMinMax spreads the gradient between the smallest and largest cell
+	ScaleMinMax = "minmax"
+	// ScaleRobust ignores the top and bottom RobustTrim share of cells when
+	// finding the range, so one outlier doesn't flatten the rest of the map
+	ScaleRobust = "robust"
+	// ScaleBands places the gradient's band boundaries at fixed values
+	ScaleBands = "bands"
+	// ScaleSymmetric centers the gradient on zero, for differences
+	ScaleSymmetric = "symmetric"
+)
+
+// RobustTrim is the share of cells ignored at each end by ScaleRobust
+const RobustTrim = 0.02
+
+// ColorScale configures how a map's values are colored in heatmaps. The
+// zero value is ScaleMinMax.
+type ColorScale struct {
+	Mode string
+	// Bounds are the band boundaries for ScaleBands in engineering units,
+	// lowest first. Every band gets an equal share of the gradient however
+	// wide it is, so six bounds line up with the five CLI heatmap bands.
+	Bounds []float64
+}
+
+// HeatScale is a ColorScale resolved against one map's data. Values are
+// placed on the gradient by linear interpolation between Bounds.
+type HeatScale struct {
+	Mode   string    `json:"mode"`
+	Bounds []float64 `json:"bounds"`
+}
+
+// HeatScale resolves the map's color scale for the given data
+func (cfg MapConfig) HeatScale(data [][]float64) HeatScale {
+	var values []float64
+	for _, row := range data {
+		values = append(values, row...)
+	}
+	sort.Float64s(values)
+	if len(values) == 0 {
+		return HeatScale{Mode: ScaleMinMax, Bounds: []float64{0, 0}}
+	}
+	minMax := HeatScale{Mode: ScaleMinMax, Bounds: []float64{values[0], values[len(values)-1]}}
+
+	switch cfg.ColorScale.Mode {
+	case ScaleRobust:
+		trim := int(RobustTrim * float64(len(values)))
+		lo, hi := values[trim], values[len(values)-1-trim]
+		if lo == hi {
+			// Too few distinct values to trim; an all-gray map would hide them
+			return minMax
+		}
+		return HeatScale{Mode: ScaleRobust, Bounds: []float64{lo, hi}}
+	case ScaleBands:
+		if len(cfg.ColorScale.Bounds) >= 2 {
+			return HeatScale{Mode: ScaleBands, Bounds: cfg.ColorScale.Bounds}
+		}
+	}
+	return minMax
+}
+
+// SymmetricScale returns a scale from -m to +m, where m is the largest
+// absolute value in data, so zero sits at the middle of the gradient and
+// equal increases and decreases get mirrored colors
+func SymmetricScale(data [][]float64) HeatScale {
+	m := 0.0
+	for _, row := range data {
+		for _, v := range row {
+			m = math.Max(m, math.Abs(v))
+		}
+	}
+	return HeatScale{Mode: ScaleSymmetric, Bounds: []float64{-m, m}}
+}
+
+// Min returns the value at the low end of the gradient
+func (s HeatScale) Min() float64 {
+	return s.Bounds[0]
+}
+
+// Max returns the value at the high end of the gradient
+func (s HeatScale) Max() float64 {
+	return s.Bounds[len(s.Bounds)-1]
+}
+
+// Flat reports whether the scale has no range, e.g. a map of equal cells
+func (s HeatScale) Flat() bool {
+	return s.Max() <= s.Min()
+}
+
+// Normalize returns the position of value on the gradient from 0 to 1,
+// clamped at the ends. A flat scale puts everything at the midpoint.
+func (s HeatScale) Normalize(value float64) float64 {
+	if s.Flat() {
+		return 0.5
+	}
+	if value <= s.Min() {
+		return 0
+	}
+	if value >= s.Max() {
+		return 1
+	}
+	segments := float64(len(s.Bounds) - 1)
+	for i := 1; i < len(s.Bounds); i++ {
+		lo, hi := s.Bounds[i-1], s.Bounds[i]
+		if value < hi {
+			frac := 0.0
+			if hi > lo {
+				frac = (value - lo) / (hi - lo)
+			}
+			return (float64(i-1) + frac) / segments
+		}
+	}
+	return 1
+}
+
+// Value returns the value at position t (0 to 1) of the gradient, the
+// inverse of Normalize
+func (s HeatScale) Value(t float64) float64 {
+	t = math.Max(0, math.Min(1, t))
+	segments := float64(len(s.Bounds) - 1)
+	i := int(t * segments)
+	if i >= len(s.Bounds)-1 {
+		return s.Max()
+	}
+	frac := t*segments - float64(i)
+	return s.Bounds[i] + (s.Bounds[i+1]-s.Bounds[i])*frac
+}
+
+// BandBoundaries returns the values at the edges of n equal bands of the
+// gradient, lowest first (n+1 values)
+func (s HeatScale) BandBoundaries(n int) []float64 {
+	bounds := make([]float64, n+1)
+	for i := range bounds {
+		bounds[i] = s.Value(float64(i) / float64(n))
+	}
+	return bounds
+}
+
+// Format formats a legend value with enough decimals to tell the band
+// boundaries of a narrow scale apart
+func (s HeatScale) Format(value float64) string {
+	if s.Max()-s.Min() < 1 {
+		return fmt.Sprintf("%.2f", value)
+	}
+	return fmt.Sprintf("%.1f", value)
+}
+
+// Label describes the scaling mode for legends, e.g. "robust 2-98%"
+func (s HeatScale) Label() string {
+	switch s.Mode {
+	case ScaleRobust:
+		return fmt.Sprintf("robust %.0f-%.0f%%", RobustTrim*100, 100-RobustTrim*100)
+	case ScaleBands:
+		parts := make([]string, len(s.Bounds))
+		for i, b := range s.Bounds {
+			parts[i] = fmt.Sprintf("%g", b)
+		}
+		return "bands " + strings.Join(parts, "/")
+	case ScaleSymmetric:
+		return "symmetric around 0"
+	}
+	return "min/max"
+}
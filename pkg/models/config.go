@@ -1,5 +1,12 @@
 package models
 
+import (
+	"errors"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+)
+
 // ConfigParam defines a single configuration parameter in the ECU
 type ConfigParam struct {
 	Name        string
@@ -11,12 +18,27 @@ type ConfigParam struct {
 	Description string
 	MinValue    float64
 	MaxValue    float64
+	Conversion  string // linear (default) or inverse
+	// Formula and InverseFormula replace Scale, Offset2 and Conversion as
+	// in MapConfig
+	Formula        string `json:",omitempty"`
+	InverseFormula string `json:",omitempty"`
+	// LinkedTo names a parameter this one is constrained against: its value
+	// must stay at least MinGap above LinkedTo's (e.g. a hard cut at least
+	// 100 RPM above the soft cut). Linked parameters are edited together.
+	LinkedTo string
+	MinGap   float64
+	// Endianness is the byte order of uint16/int16 values; empty inherits
+	// the profile's default (see ByteOrder)
+	Endianness Endianness
 }
 
 // ECUConfig holds all configuration parameters
 type ECUConfig struct {
 	Params []ConfigParam
 	Values map[string]float64
+	// Errors says, by name, why a parameter has no value
+	Errors map[string]error
 }
 
 // Common Motronic M2.1 configuration parameters
@@ -67,3 +89,61 @@ var ConfigParams = []ConfigParam{
 		MaxValue:    255,
 	},
 }
+
+// FindConfigParam looks up a parameter definition by name, ignoring case
+func FindConfigParam(name string) (ConfigParam, bool) {
+	for _, param := range ConfigParams {
+		if strings.EqualFold(param.Name, name) {
+			return param, true
+		}
+	}
+	return ConfigParam{}, false
+}
+
+// LinkedGroup returns the named parameter together with every parameter
+// linked to it directly or through others, in definition order
+func LinkedGroup(name string) []ConfigParam {
+	inGroup := map[string]bool{}
+	if param, ok := FindConfigParam(name); ok {
+		inGroup[param.Name] = true
+	}
+	for grew := true; grew; {
+		grew = false
+		for _, param := range ConfigParams {
+			if param.LinkedTo == "" || inGroup[param.Name] == inGroup[param.LinkedTo] {
+				continue
+			}
+			inGroup[param.Name], inGroup[param.LinkedTo] = true, true
+			grew = true
+		}
+	}
+
+	var group []ConfigParam
+	for _, param := range ConfigParams {
+		if inGroup[param.Name] {
+			group = append(group, param)
+		}
+	}
+	return group
+}
+
+// CheckLinks verifies the LinkedTo relationships between the given values.
+// Links whose parameters are missing from values are not checked.
+func CheckLinks(values map[string]float64) error {
+	for _, param := range ConfigParams {
+		if param.LinkedTo == "" {
+			continue
+		}
+		value, ok := values[param.Name]
+		base, baseOK := values[param.LinkedTo]
+		if !ok || !baseOK {
+			continue
+		}
+		// Tolerate float error in values decoded from scaled raw bytes
+		if value < base+param.MinGap-1e-6 {
+			return errors.New(i18n.T("validate.link_gap",
+				param.Name, value, param.Unit, param.MinGap, param.Unit, param.LinkedTo, base, param.Unit))
+		}
+	}
+	return nil
+}
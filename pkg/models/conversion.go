@@ -0,0 +1,271 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// Conversion modes between raw cell values and engineering values
+const (
+	// ConversionLinear is value = raw*Scale + Offset2 (the default)
+	ConversionLinear = "linear"
+	// ConversionInverse is value = Scale/raw + Offset2, used for tables that
+	// store time periods as divisors. The division is undefined at raw 0,
+	// so raw 0 reads as Offset2, the value no other raw value reaches, and
+	// only a value of Offset2 writes raw 0.
+	ConversionInverse = "inverse"
+)
+
+// RawRange returns the smallest and largest raw value of a data type
+func RawRange(dataType string) (int64, int64) {
+	switch dataType {
+	case "uint16":
+		return 0, math.MaxUint16
+	case "int8":
+		return math.MinInt8, math.MaxInt8
+	case "int16":
+		return math.MinInt16, math.MaxInt16
+	default:
+		return 0, math.MaxUint8
+	}
+}
+
+// DecodeRaw reads a little-endian raw value, sign-extending signed types
+func DecodeRaw(b []byte, dataType string) int64 {
+	return DecodeRawOrder(b, dataType, LittleEndian)
+}
+
+// EncodeRaw writes a little-endian raw value
+func EncodeRaw(b []byte, dataType string, raw int64) {
+	EncodeRawOrder(b, dataType, raw, LittleEndian)
+}
+
+// DecodeRawOrder reads a raw value in the given byte order,
+// sign-extending signed types
+func DecodeRawOrder(b []byte, dataType string, order Endianness) int64 {
+	switch dataType {
+	case "uint16":
+		return int64(order.Binary().Uint16(b))
+	case "int8":
+		return int64(int8(b[0]))
+	case "int16":
+		return int64(int16(order.Binary().Uint16(b)))
+	default:
+		return int64(b[0])
+	}
+}
+
+// EncodeRawOrder writes a raw value in the given byte order
+func EncodeRawOrder(b []byte, dataType string, raw int64, order Endianness) {
+	if DataTypeSize(dataType) == 2 {
+		order.Binary().PutUint16(b, uint16(raw))
+		return
+	}
+	b[0] = byte(raw)
+}
+
+// CheckScale reports a scale that cannot map raw values to engineering
+// values: zero (every raw value would read the same and no value could be
+// written back), NaN or infinite. Negative scales are valid; raw and
+// engineering values then simply run in opposite directions.
+func CheckScale(scale float64) error {
+	if scale == 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+		return fmt.Errorf("scale %v is invalid: it must be a finite, non-zero number", scale)
+	}
+	return nil
+}
+
+// TwoPointScale returns the linear scale and offset that read raw1 as
+// value1 and raw2 as value2, for calibrating a definition from two known
+// cells
+func TwoPointScale(raw1 int64, value1 float64, raw2 int64, value2 float64) (scale, offset float64, err error) {
+	if raw1 == raw2 {
+		return 0, 0, fmt.Errorf("the two raw values must differ")
+	}
+	scale = (value2 - value1) / float64(raw2-raw1)
+	if err := CheckScale(scale); err != nil {
+		return 0, 0, fmt.Errorf("the two values must differ: %w", err)
+	}
+	return scale, value1 - float64(raw1)*scale, nil
+}
+
+// CheckConversion reports a conversion that cannot map raw values to
+// engineering values: an invalid formula (see CheckFormula) or, without
+// one, an invalid scale
+func CheckConversion(scale float64, formula, inverse, dataType string) error {
+	if formula != "" || inverse != "" {
+		return CheckFormula(formula, inverse, dataType)
+	}
+	return CheckScale(scale)
+}
+
+// CheckConversion reports a conversion of the map that fails the
+// package-level CheckConversion
+func (c MapConfig) CheckConversion() error {
+	return CheckConversion(c.Scale, c.Formula, c.InverseFormula, c.DataType)
+}
+
+// CheckConversion reports a conversion of the parameter that fails the
+// package-level CheckConversion
+func (p ConfigParam) CheckConversion() error {
+	return CheckConversion(p.Scale, p.Formula, p.InverseFormula, p.DataType)
+}
+
+// CheckScales returns an error for every map and parameter definition
+// whose conversion fails CheckConversion
+func CheckScales(maps []MapConfig, params []ConfigParam) []error {
+	var errs []error
+	for _, m := range maps {
+		if err := m.CheckConversion(); err != nil {
+			errs = append(errs, fmt.Errorf("map %q: %w", m.Name, err))
+		}
+	}
+	for _, p := range params {
+		if err := p.CheckConversion(); err != nil {
+			errs = append(errs, fmt.Errorf("param %q: %w", p.Name, err))
+		}
+	}
+	return errs
+}
+
+// RawToReal converts a raw value to an engineering value. Raw 0 of an
+// inverse conversion reads as offset, which RealToRaw writes as raw 0.
+func RawToReal(raw int64, scale, offset float64, conversion string) float64 {
+	if conversion == ConversionInverse {
+		if raw == 0 {
+			return offset
+		}
+		return scale/float64(raw) + offset
+	}
+	return float64(raw)*scale + offset
+}
+
+// RealToRaw converts an engineering value to the nearest raw value of the
+// data type, rounding ties to even. Values that don't fit are clamped to
+// the type's range and reported with clamped set.
+//
+// This (through MapConfig.ToRaw and ConfigParam.ToRaw) is the only
+// sanctioned way to turn a value into raw bytes: a plain int conversion
+// truncates, so re-entering a displayed value could move the byte by one.
+// An invalid scale (see CheckScale) represents nothing and reports every
+// value as clamped.
+func RealToRaw(value, scale, offset float64, conversion, dataType string) (raw int64, clamped bool) {
+	lo, hi := RawRange(dataType)
+	if CheckScale(scale) != nil {
+		return 0, true
+	}
+
+	var exact float64
+	if conversion == ConversionInverse {
+		if value == offset {
+			return 0, false
+		}
+		exact = scale / (value - offset)
+	} else {
+		exact = (value - offset) / scale
+	}
+
+	if math.IsNaN(exact) {
+		return 0, true
+	}
+	rounded := math.RoundToEven(exact)
+	switch {
+	case rounded < float64(lo):
+		raw, clamped = lo, true
+	case rounded > float64(hi):
+		raw, clamped = hi, true
+	default:
+		raw = int64(rounded)
+	}
+
+	// Inverse tables use raw 0 as a marker; never produce it by rounding
+	// or clamping. The nearest raw value on the value's side is -1 or 1,
+	// but unsigned types have only 1.
+	if conversion == ConversionInverse && raw == 0 {
+		if exact < 0 && lo < 0 {
+			return -1, true
+		}
+		return 1, true
+	}
+	return raw, clamped
+}
+
+// ToReal converts a raw cell value of the map to its engineering value
+func (c MapConfig) ToReal(raw int64) float64 {
+	if c.Formula != "" {
+		return FormulaToReal(raw, c.Formula)
+	}
+	return RawToReal(raw, c.Scale, c.Offset2, c.Conversion)
+}
+
+// ToRaw converts an engineering value to the map's raw cell value
+func (c MapConfig) ToRaw(value float64) (int64, bool) {
+	if c.Formula != "" {
+		return FormulaToRaw(value, c.Formula, c.InverseFormula, c.DataType)
+	}
+	return RealToRaw(value, c.Scale, c.Offset2, c.Conversion, c.DataType)
+}
+
+// Nudge returns the raw value reached by moving raw by steps nudges of
+// NudgeStep, snapped to the nearest representable value. Without a
+// NudgeStep, or when the step is finer than the raw resolution, each
+// nudge moves one raw step in the requested direction.
+func (c MapConfig) Nudge(raw int64, steps int) (int64, bool) {
+	if steps == 0 {
+		return raw, false
+	}
+	current := c.ToReal(raw)
+	if c.NudgeStep > 0 {
+		next, clamped := c.ToRaw(current + float64(steps)*c.NudgeStep)
+		if next != raw || clamped {
+			return next, clamped
+		}
+	}
+
+	// Raw and engineering values may run in opposite directions (negative
+	// scale, inverse tables)
+	dir := int64(1)
+	if (c.ToReal(raw+1) > current) != (steps > 0) {
+		dir = -1
+	}
+	n := int64(steps)
+	if n < 0 {
+		n = -n
+	}
+	next := raw + dir*n
+	lo, hi := RawRange(c.DataType)
+	switch {
+	case next < lo:
+		return lo, true
+	case next > hi:
+		return hi, true
+	}
+	return next, false
+}
+
+// StepLabel describes the active nudge step, e.g. "0.75 deg"
+func (c MapConfig) StepLabel() string {
+	switch {
+	case c.NudgeStep > 0:
+		return fmt.Sprintf("%g %s", c.NudgeStep, c.Unit)
+	case c.Formula == "" && c.Conversion != ConversionInverse:
+		return fmt.Sprintf("%g %s", math.Abs(c.Scale), c.Unit)
+	}
+	return "1 raw step"
+}
+
+// ToReal converts a raw parameter value to its engineering value
+func (p ConfigParam) ToReal(raw int64) float64 {
+	if p.Formula != "" {
+		return FormulaToReal(raw, p.Formula)
+	}
+	return RawToReal(raw, p.Scale, p.Offset2, p.Conversion)
+}
+
+// ToRaw converts an engineering value to the parameter's raw value
+func (p ConfigParam) ToRaw(value float64) (int64, bool) {
+	if p.Formula != "" {
+		return FormulaToRaw(value, p.Formula, p.InverseFormula, p.DataType)
+	}
+	return RealToRaw(value, p.Scale, p.Offset2, p.Conversion, p.DataType)
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+	"testing/quick"
+)
+
+// TestInverseRoundTrip converts every raw value of each type to its value
+// and back. Inverse tables are most lossy at large raw values, where
+// neighbors read almost the same.
+func TestInverseRoundTrip(t *testing.T) {
+	for _, dataType := range []string{"uint8", "int8", "uint16", "int16"} {
+		for _, scale := range []float64{1, 1000, 1e6} {
+			lo, hi := RawRange(dataType)
+			for raw := lo; raw <= hi; raw++ {
+				if raw == 0 {
+					continue
+				}
+				value := RawToReal(raw, scale, 0, ConversionInverse)
+				back, clamped := RealToRaw(value, scale, 0, ConversionInverse, dataType)
+				if back != raw || clamped {
+					t.Fatalf("%s scale %g: raw %d reads %g, which writes raw %d (clamped %v)", dataType, scale, raw, value, back, clamped)
+				}
+			}
+		}
+	}
+}
+
+func TestInverseRealToRaw(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		dataType string
+		raw      int64
+		clamped  bool
+	}{
+		{name: "zero writes the marker", value: 0, dataType: "uint8", raw: 0},
+		{name: "largest value", value: 1000, dataType: "uint8", raw: 1},
+		{name: "beyond the largest value", value: 5000, dataType: "uint8", raw: 1, clamped: true},
+		{name: "smallest value", value: 1000.0 / 255, dataType: "uint8", raw: 255},
+		{name: "below the smallest value", value: 1, dataType: "uint8", raw: 255, clamped: true},
+		{name: "negative unsigned rounding to zero", value: -5000, dataType: "uint8", raw: 1, clamped: true},
+		{name: "negative unsigned", value: -10, dataType: "uint8", raw: 1, clamped: true},
+		{name: "negative uint16", value: -1e9, dataType: "uint16", raw: 1, clamped: true},
+		{name: "negative signed rounding to zero", value: -5000, dataType: "int8", raw: -1, clamped: true},
+		{name: "negative signed", value: -10, dataType: "int8", raw: -100},
+		{name: "most negative", value: -1000.0 / 128, dataType: "int8", raw: -128},
+		{name: "beyond the most negative", value: -1, dataType: "int8", raw: -128, clamped: true},
+		{name: "NaN", value: math.NaN(), dataType: "uint8", raw: 0, clamped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, clamped := RealToRaw(tt.value, 1000, 0, ConversionInverse, tt.dataType)
+			if raw != tt.raw || clamped != tt.clamped {
+				t.Errorf("RealToRaw(%g) = %d, %v; want %d, %v", tt.value, raw, clamped, tt.raw, tt.clamped)
+			}
+		})
+	}
+}
+
+func TestLinearRealToRawExtremes(t *testing.T) {
+	tests := []struct {
+		value    float64
+		dataType string
+		raw      int64
+		clamped  bool
+	}{
+		{value: 0, dataType: "uint8", raw: 0},
+		{value: 25.5, dataType: "uint8", raw: 255},
+		{value: 25.56, dataType: "uint8", raw: 255, clamped: true},
+		{value: -0.1, dataType: "uint8", raw: 0, clamped: true},
+		{value: -12.8, dataType: "int8", raw: -128},
+		{value: 12.7, dataType: "int8", raw: 127},
+		{value: 6553.5, dataType: "uint16", raw: 65535},
+		{value: -3276.9, dataType: "int16", raw: -32768, clamped: true},
+		{value: 0.05, dataType: "uint8", raw: 0}, // ties to even
+		{value: 0.25, dataType: "uint8", raw: 2},
+	}
+	for _, tt := range tests {
+		raw, clamped := RealToRaw(tt.value, 0.1, 0, ConversionLinear, tt.dataType)
+		if raw != tt.raw || clamped != tt.clamped {
+			t.Errorf("%s RealToRaw(%g) = %d, %v; want %d, %v", tt.dataType, tt.value, raw, clamped, tt.raw, tt.clamped)
+		}
+	}
+}
+
+// Re-entering any value of a built-in map or parameter as it is shown,
+// with two decimals, writes back the raw value it was read from
+func TestDisplayedValueRoundTrip(t *testing.T) {
+	check := func(name, dataType string, toReal func(int64) float64, toRaw func(float64) (int64, bool)) {
+		lo, hi := RawRange(dataType)
+		for raw := lo; raw <= hi; raw++ {
+			shown, _ := strconv.ParseFloat(fmt.Sprintf("%.2f", toReal(raw)), 64)
+			if back, clamped := toRaw(shown); back != raw || clamped {
+				t.Fatalf("%s: raw %d is shown as %.2f, which writes raw %d (clamped %v)", name, raw, shown, back, clamped)
+			}
+		}
+	}
+	for _, m := range MapConfigs {
+		check(m.Name, m.DataType, m.ToReal, m.ToRaw)
+	}
+	for _, p := range ConfigParams {
+		check(p.Name, p.DataType, p.ToReal, p.ToRaw)
+	}
+}
+
+// For any linear conversion, a raw value's exact value converts back to
+// it, and so does the value shown with two decimals once a raw step is
+// wider than their rounding
+func TestLinearRoundTripProperty(t *testing.T) {
+	dataTypes := []string{"uint8", "int8", "uint16", "int16"}
+	// Scales from ±0.0001 to ±100 and offsets up to ±1000, both with
+	// more decimals than the two shown
+	property := func(rawSeed uint16, scaleSeed, offsetSeed int32, typeSeed uint8) bool {
+		dataType := dataTypes[int(typeSeed)%len(dataTypes)]
+		lo, hi := RawRange(dataType)
+		raw := lo + int64(rawSeed)%(hi-lo+1)
+		scale := float64(scaleSeed%1000000) / 10000
+		if scale == 0 {
+			return true
+		}
+		offset := float64(offsetSeed%10000000) / 10000
+
+		value := RawToReal(raw, scale, offset, ConversionLinear)
+		if back, clamped := RealToRaw(value, scale, offset, ConversionLinear, dataType); back != raw || clamped {
+			t.Logf("%s scale %g offset %g: raw %d reads %g, which writes %d", dataType, scale, offset, raw, value, back)
+			return false
+		}
+		if math.Abs(scale) <= 0.011 {
+			return true
+		}
+		shown, _ := strconv.ParseFloat(fmt.Sprintf("%.2f", value), 64)
+		if back, _ := RealToRaw(shown, scale, offset, ConversionLinear, dataType); back != raw {
+			t.Logf("%s scale %g offset %g: raw %d is shown as %.2f, which writes %d", dataType, scale, offset, raw, shown, back)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 20000}); err != nil {
+		t.Error(err)
+	}
+}
+
+// With an offset, raw 0 and the offset are each other's conversion, every
+// other raw value still round-trips, and writing 0 stores the raw value
+// whose value is nearest to 0 rather than the marker
+func TestInverseOffsetRoundTrip(t *testing.T) {
+	const scale, offset = 1000.0, -40.0
+	for _, dataType := range []string{"uint8", "int8", "uint16", "int16"} {
+		if v := RawToReal(0, scale, offset, ConversionInverse); v != offset {
+			t.Errorf("%s: raw 0 reads %g, want the offset %g", dataType, v, offset)
+		}
+		if raw, clamped := RealToRaw(offset, scale, offset, ConversionInverse, dataType); raw != 0 || clamped {
+			t.Errorf("%s: the offset writes raw %d (clamped %v), want raw 0", dataType, raw, clamped)
+		}
+		lo, hi := RawRange(dataType)
+		for raw := lo; raw <= hi; raw++ {
+			value := RawToReal(raw, scale, offset, ConversionInverse)
+			if back, clamped := RealToRaw(value, scale, offset, ConversionInverse, dataType); back != raw || clamped {
+				t.Fatalf("%s: raw %d reads %g, which writes raw %d (clamped %v)", dataType, raw, value, back, clamped)
+			}
+		}
+	}
+	// 1000/25 - 40 = 0
+	if raw, clamped := RealToRaw(0, scale, offset, ConversionInverse, "uint8"); raw != 25 || clamped {
+		t.Errorf("0 writes raw %d (clamped %v), want raw 25", raw, clamped)
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Endianness is the byte order of multi-byte values in an image. The
+// empty value means "inherit": a parameter without one uses its profile's
+// default (see ConfigParam.ByteOrder).
+type Endianness string
+
+const (
+	LittleEndian Endianness = "little"
+	BigEndian    Endianness = "big"
+)
+
+// Binary returns the encoding/binary order, little-endian for the empty
+// value
+func (e Endianness) Binary() binary.ByteOrder {
+	if e == BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// CheckEndianness reports a byte order other than "", "little" and "big"
+func CheckEndianness(e Endianness) error {
+	switch e {
+	case "", LittleEndian, BigEndian:
+		return nil
+	}
+	return fmt.Errorf("endianness %q is invalid: expected little or big", e)
+}
+
+// ByteOrder returns the parameter's byte order: its own Endianness, or
+// the default of the profile it belongs to
+func (p ConfigParam) ByteOrder() Endianness {
+	if p.Endianness != "" {
+		return p.Endianness
+	}
+	if M21IDProfile.Endianness != "" {
+		return M21IDProfile.Endianness
+	}
+	return LittleEndian
+}
+
+// DecodeRaw reads the parameter's raw value from the start of b
+func (p ConfigParam) DecodeRaw(b []byte) int64 {
+	return DecodeRawOrder(b, p.DataType, p.ByteOrder())
+}
+
+// EncodeRaw writes the parameter's raw value to the start of b
+func (p ConfigParam) EncodeRaw(b []byte, raw int64) {
+	EncodeRawOrder(b, p.DataType, raw, p.ByteOrder())
+}
+
+// ByteOrder returns the map's byte order, little-endian unless its
+// Endianness says otherwise. Unlike parameters, maps don't follow the
+// profile default, which -byte-order has only ever set for parameters.
+func (c MapConfig) ByteOrder() Endianness {
+	if c.Endianness == "" {
+		return LittleEndian
+	}
+	return c.Endianness
+}
+
+// DecodeRaw reads the raw value of one cell from the start of b
+func (c MapConfig) DecodeRaw(b []byte) int64 {
+	return DecodeRawOrder(b, c.DataType, c.ByteOrder())
+}
+
+// EncodeRaw writes the raw value of one cell to the start of b
+func (c MapConfig) EncodeRaw(b []byte, raw int64) {
+	EncodeRawOrder(b, c.DataType, raw, c.ByteOrder())
+}
+
+// ByteOrder returns the axis's byte order, little-endian unless its
+// Endianness says otherwise. Axes read with their map inherit the map's
+// order first (see InheritOrder).
+func (a AxisConfig) ByteOrder() Endianness {
+	if a.Endianness == "" {
+		return LittleEndian
+	}
+	return a.Endianness
+}
+
+// InheritOrder returns the axis with an empty Endianness set to the byte
+// order of its map
+func (a AxisConfig) InheritOrder(cfg MapConfig) AxisConfig {
+	if a.Endianness == "" {
+		a.Endianness = cfg.ByteOrder()
+	}
+	return a
+}
+
+// DecodeRaw reads the raw value of one breakpoint from the start of b
+func (a AxisConfig) DecodeRaw(b []byte) int64 {
+	return DecodeRawOrder(b, a.DataType, a.ByteOrder())
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+)
+
+// DefinitionsFingerprint returns a short hash of the active map and
+// parameter definitions. It changes whenever any definition changes, so
+// it can be used to invalidate cached results and to record provenance.
+// Offsets are hashed as they are in the image, so moving the definitions
+// with UseBaseOffset keeps it.
+func DefinitionsFingerprint() string {
+	h := sha256.New()
+	for _, cfg := range ImageMapConfigs() {
+		writeDefinition(h, cfg)
+	}
+	for _, param := range ImageConfigParams() {
+		writeDefinition(h, param)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Fingerprint returns a hash identifying this exact map definition
+func (c MapConfig) Fingerprint() string {
+	h := sha256.New()
+	writeDefinition(h, c)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// writeDefinition hashes a definition by value. JSON follows pointers such
+// as HighlightBelow, where %+v would hash their addresses and give a
+// different fingerprint on every run.
+func writeDefinition(h hash.Hash, def any) {
+	data, _ := json.Marshal(def)
+	h.Write(data)
+	h.Write([]byte{'\n'})
+}
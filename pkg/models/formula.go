@@ -0,0 +1,352 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Formula is a conversion expression in the raw value x, for tables the
+// linear raw*Scale + Offset2 can't describe, such as time constants stored
+// as 256/x. Formulas are built from numbers, x, + - * / ^ (power) and
+// parentheses, e.g. "256/x", "0.002*x*x" or "(x-64)^2/100".
+type Formula struct {
+	text string
+	eval func(x float64) float64
+}
+
+// formulas caches parsed formulas by their text, since definitions carry
+// them as strings and every cell conversion looks one up
+var formulas sync.Map
+
+// formulaEntry is a cached parse result
+type formulaEntry struct {
+	formula *Formula
+	err     error
+}
+
+// ParseFormula parses a formula in x (or X, as in TunerPro equations)
+func ParseFormula(text string) (*Formula, error) {
+	if e, ok := formulas.Load(text); ok {
+		return e.(formulaEntry).formula, e.(formulaEntry).err
+	}
+	f, err := parseFormula(text)
+	formulas.Store(text, formulaEntry{f, err})
+	return f, err
+}
+
+// parseFormula does the work of ParseFormula
+func parseFormula(text string) (*Formula, error) {
+	p := &formulaParser{s: strings.ReplaceAll(strings.TrimSpace(text), " ", "")}
+	if p.s == "" {
+		return nil, errors.New("formula is empty")
+	}
+	eval, err := p.expr()
+	if err == nil && p.pos < len(p.s) {
+		err = fmt.Errorf("unexpected %q", p.s[p.pos:])
+	}
+	if err == nil && !p.usesX {
+		err = errors.New("does not depend on x")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("formula %q: %v", text, err)
+	}
+	return &Formula{text: text, eval: eval}, nil
+}
+
+// Eval returns the formula's value at x
+func (f *Formula) Eval(x float64) float64 {
+	return f.eval(x)
+}
+
+// String returns the formula as written
+func (f *Formula) String() string {
+	return f.text
+}
+
+// formulaParser is a recursive descent parser compiling a formula to a
+// function of x
+type formulaParser struct {
+	s     string
+	pos   int
+	usesX bool
+}
+
+// op reports whether the next character is c, consuming it if so
+func (p *formulaParser) op(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expr := term {(+|-) term}
+func (p *formulaParser) expr() (func(float64) float64, error) {
+	v, err := p.term()
+	for err == nil {
+		var t func(float64) float64
+		switch {
+		case p.op('+'):
+			if t, err = p.term(); err == nil {
+				l := v
+				v = func(x float64) float64 { return l(x) + t(x) }
+			}
+		case p.op('-'):
+			if t, err = p.term(); err == nil {
+				l := v
+				v = func(x float64) float64 { return l(x) - t(x) }
+			}
+		default:
+			return v, nil
+		}
+	}
+	return v, err
+}
+
+// term := unary {(*|/) unary}
+func (p *formulaParser) term() (func(float64) float64, error) {
+	v, err := p.unary()
+	for err == nil {
+		var f func(float64) float64
+		switch {
+		case p.op('*'):
+			if f, err = p.unary(); err == nil {
+				l := v
+				v = func(x float64) float64 { return l(x) * f(x) }
+			}
+		case p.op('/'):
+			if f, err = p.unary(); err == nil {
+				l := v
+				v = func(x float64) float64 { return l(x) / f(x) }
+			}
+		default:
+			return v, nil
+		}
+	}
+	return v, err
+}
+
+// unary := (+|-) unary | power
+func (p *formulaParser) unary() (func(float64) float64, error) {
+	switch {
+	case p.op('+'):
+		return p.unary()
+	case p.op('-'):
+		v, err := p.unary()
+		return func(x float64) float64 { return -v(x) }, err
+	}
+	return p.power()
+}
+
+// power := primary [^ unary], so 2^-x and x^2^2 (= x^4) read as expected
+// and -x^2 is -(x^2)
+func (p *formulaParser) power() (func(float64) float64, error) {
+	v, err := p.primary()
+	if err != nil || !p.op('^') {
+		return v, err
+	}
+	e, err := p.unary()
+	return func(x float64) float64 { return math.Pow(v(x), e(x)) }, err
+}
+
+// primary := number | x | ( expr )
+func (p *formulaParser) primary() (func(float64) float64, error) {
+	if p.pos >= len(p.s) {
+		return nil, errors.New("unexpected end")
+	}
+	switch c := p.s[p.pos]; {
+	case c == 'x' || c == 'X':
+		p.pos++
+		p.usesX = true
+		return func(x float64) float64 { return x }, nil
+	case c == '(':
+		p.pos++
+		v, err := p.expr()
+		if err != nil {
+			return v, err
+		}
+		if !p.op(')') {
+			return v, errors.New("missing )")
+		}
+		return v, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+		p.pos++
+	}
+	// Exponent, as in 1e-3
+	if p.pos > start && p.pos < len(p.s) && (p.s[p.pos] == 'e' || p.s[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.s) && (p.s[p.pos] == '+' || p.s[p.pos] == '-') {
+			p.pos++
+		}
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+	}
+	if p.pos == start {
+		return nil, fmt.Errorf("unexpected %q", p.s[p.pos:])
+	}
+	n, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", p.s[start:p.pos])
+	}
+	return func(float64) float64 { return n }, nil
+}
+
+// CheckFormula reports a formula that doesn't parse, and an inverse
+// formula that doesn't parse or doesn't undo the formula: converting the
+// value of a raw value back must land within one raw step of it. An
+// inverse without a formula is an error too. Raw values the formula
+// can't convert (such as 0 in 256/x) are not checked.
+func CheckFormula(formula, inverse, dataType string) error {
+	if formula == "" {
+		if inverse != "" {
+			return errors.New("an inverse formula needs a formula")
+		}
+		return nil
+	}
+	f, err := ParseFormula(formula)
+	if err != nil {
+		return err
+	}
+	if inverse == "" {
+		return nil
+	}
+	inv, err := ParseFormula(inverse)
+	if err != nil {
+		return fmt.Errorf("inverse %w", err)
+	}
+
+	lo, hi := RawRange(dataType)
+	// Every value of 8-bit types, about 256 spread over 16-bit ones
+	step := max((hi-lo)/256, 1)
+	for raw := lo; raw <= hi; raw += step {
+		value := f.Eval(float64(raw))
+		if !finite(value) {
+			continue
+		}
+		if back := inv.Eval(value); !finite(back) || math.Abs(back-float64(raw)) > 1 {
+			return fmt.Errorf("inverse formula %q does not undo %q: raw %d reads %g, which converts back to raw %g", inverse, formula, raw, value, back)
+		}
+	}
+	return nil
+}
+
+// FormulaToReal converts a raw value with a formula. Raw values the
+// formula can't convert, such as 0 in 256/x, read as 0 like in inverse
+// conversions, and an invalid formula (see CheckFormula) reads NaN.
+func FormulaToReal(raw int64, formula string) float64 {
+	f, err := ParseFormula(formula)
+	if err != nil {
+		return math.NaN()
+	}
+	if v := f.Eval(float64(raw)); finite(v) {
+		return v
+	}
+	return 0
+}
+
+// FormulaToRaw converts an engineering value to the raw value of the data
+// type whose formula value comes closest, so real→raw→real stays within
+// one raw step. With an inverse formula only its rounded result and the
+// raw values next to it are tried; without one, or where it can't
+// convert the value, every raw value of the type is. Values no raw value
+// reaches are clamped to the nearest one and reported with clamped set,
+// like RealToRaw; an invalid formula reports every value as clamped.
+func FormulaToRaw(value float64, formula, inverse, dataType string) (raw int64, clamped bool) {
+	f, err := ParseFormula(formula)
+	if err != nil || math.IsNaN(value) {
+		return 0, true
+	}
+	// Raw values the formula can't convert read as 0, but only a value of
+	// exactly 0 writes them, like the marker of inverse conversions
+	distance := func(raw int64) float64 {
+		if v := f.Eval(float64(raw)); finite(v) {
+			return math.Abs(v - value)
+		}
+		if value == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	lo, hi := RawRange(dataType)
+
+	if exact := inverseRaw(value, inverse); finite(exact) {
+		rounded := math.RoundToEven(exact)
+		switch {
+		case rounded < float64(lo):
+			return lo, true
+		case rounded > float64(hi):
+			return hi, true
+		}
+		raw = int64(rounded)
+		best := distance(raw)
+		for _, r := range []int64{raw - 1, raw + 1} {
+			if r >= lo && r <= hi && distance(r) < best {
+				raw, best = r, distance(r)
+			}
+		}
+		// Within reach, the closest raw value is at most one step off
+		step := 0.0
+		for _, r := range []int64{raw - 1, raw + 1} {
+			if r >= lo && r <= hi {
+				step = max(step, math.Abs(FormulaToReal(r, formula)-FormulaToReal(raw, formula)))
+			}
+		}
+		return raw, best > step
+	}
+
+	// Search the whole range; the value is clamped if it is beyond the
+	// smallest or largest value of any raw value
+	best, lowest, highest := math.Inf(1), math.Inf(1), math.Inf(-1)
+	for r := lo; r <= hi; r++ {
+		d := distance(r)
+		if v := f.Eval(float64(r)); finite(v) {
+			lowest, highest = min(lowest, v), max(highest, v)
+		}
+		if d < best {
+			raw, best = r, d
+		}
+	}
+	if math.IsInf(best, 1) {
+		return 0, true
+	}
+	return raw, best > 0 && (value < lowest || value > highest)
+}
+
+// FormulaRange returns the smallest and largest value a formula reads
+// from any raw value of the data type, which for formulas need not be at
+// the ends of the raw range
+func FormulaRange(formula, dataType string) (lowest, highest float64) {
+	lo, hi := RawRange(dataType)
+	lowest, highest = math.Inf(1), math.Inf(-1)
+	for raw := lo; raw <= hi; raw++ {
+		v := FormulaToReal(raw, formula)
+		lowest, highest = min(lowest, v), max(highest, v)
+	}
+	return lowest, highest
+}
+
+// inverseRaw returns the unrounded raw value of an inverse formula, NaN
+// without a valid one
+func inverseRaw(value float64, inverse string) float64 {
+	if inverse == "" {
+		return math.NaN()
+	}
+	inv, err := ParseFormula(inverse)
+	if err != nil {
+		return math.NaN()
+	}
+	return inv.Eval(value)
+}
+
+// finite reports whether v is neither NaN nor infinite
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseFormula(t *testing.T) {
+	tests := []struct {
+		text string
+		x    float64
+		want float64
+	}{
+		{"256/x", 64, 4},
+		{"256 / X", 64, 4},
+		{"0.002*x*x", 100, 20},
+		{"(x-64)^2/100", 74, 1},
+		{"-x^2", 3, -9},
+		{"2^-x", 1, 0.5},
+		{"x^2^2", 2, 16},
+		{"1e3/x+5", 100, 15},
+		{"x-1-1", 5, 3},
+		{"x/2/2", 8, 2},
+	}
+	for _, tt := range tests {
+		f, err := ParseFormula(tt.text)
+		if err != nil {
+			t.Errorf("ParseFormula(%q): %v", tt.text, err)
+			continue
+		}
+		if got := f.Eval(tt.x); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s at x=%g = %g, want %g", tt.text, tt.x, got, tt.want)
+		}
+	}
+	for _, text := range []string{"", "256/", "(x+1", "x+1)", "2*y", "42", "x**2", "1..2*x"} {
+		if _, err := ParseFormula(text); err == nil {
+			t.Errorf("ParseFormula(%q) accepted it", text)
+		}
+	}
+}
+
+// formulaCases are the conversions the round-trip tests run over, with
+// and without an inverse, on 8-bit and 16-bit types
+var formulaCases = []struct {
+	formula, inverse, dataType string
+}{
+	{"256/x", "256/x", "uint8"},
+	{"256/x", "", "uint8"},
+	{"0.002*x*x", "", "uint8"},
+	{"0.002*x*x", "(x/0.002)^0.5", "uint16"},
+	{"1000/x+5", "1000/(x-5)", "uint16"},
+	{"2^(x/32)", "", "int8"},
+	{"10-x/4", "(10-x)*4", "int16"},
+}
+
+// lsb returns the value step of a formula at raw: the larger difference
+// to the values of the raw values either side
+func lsb(raw int64, formula, dataType string) float64 {
+	lo, hi := RawRange(dataType)
+	v := FormulaToReal(raw, formula)
+	step := 0.0
+	for _, r := range []int64{raw - 1, raw + 1} {
+		if r >= lo && r <= hi {
+			step = max(step, math.Abs(FormulaToReal(r, formula)-v))
+		}
+	}
+	return step
+}
+
+// TestFormulaRoundTrip converts the value of every raw value back, and
+// values between two raw values' values: real→raw→real stays within one
+// raw step (LSB) of the value written, and never reports it clamped.
+// 16-bit types without an inverse search every raw value per write, so
+// those are sampled.
+func TestFormulaRoundTrip(t *testing.T) {
+	for _, tc := range formulaCases {
+		if err := CheckFormula(tc.formula, tc.inverse, tc.dataType); err != nil {
+			t.Fatalf("CheckFormula: %v", err)
+		}
+		lo, hi := RawRange(tc.dataType)
+		step := int64(1)
+		if tc.inverse == "" && hi-lo > math.MaxUint8 {
+			step = 257
+		}
+		for raw := lo; raw <= hi; raw += step {
+			f, _ := ParseFormula(tc.formula)
+			if !finite(f.Eval(float64(raw))) {
+				continue
+			}
+			value := FormulaToReal(raw, tc.formula)
+			values := []float64{value}
+			if raw < hi && finite(f.Eval(float64(raw+1))) {
+				next := FormulaToReal(raw+1, tc.formula)
+				values = append(values, value+(next-value)*0.3, value+(next-value)*0.7)
+			}
+			for _, v := range values {
+				back, clamped := FormulaToRaw(v, tc.formula, tc.inverse, tc.dataType)
+				got := FormulaToReal(back, tc.formula)
+				if clamped || math.Abs(got-v) > lsb(raw, tc.formula, tc.dataType) {
+					t.Fatalf("%s (inverse %q, %s): %g near raw %d writes raw %d, which reads %g (clamped %v), more than one LSB (%g) off",
+						tc.formula, tc.inverse, tc.dataType, v, raw, back, got, clamped, lsb(raw, tc.formula, tc.dataType))
+				}
+			}
+		}
+	}
+}
+
+// Values no raw value reaches are clamped to the nearest one, and 0 is
+// written as a raw value the formula can't convert
+func TestFormulaToRawLimits(t *testing.T) {
+	for _, inverse := range []string{"256/x", ""} {
+		if raw, clamped := FormulaToRaw(1000, "256/x", inverse, "uint8"); raw != 1 || !clamped {
+			t.Errorf("inverse %q: 1000 writes raw %d (clamped %v), want raw 1 clamped", inverse, raw, clamped)
+		}
+		if raw, clamped := FormulaToRaw(0, "256/x", inverse, "uint8"); raw != 0 || clamped {
+			t.Errorf("inverse %q: 0 writes raw %d (clamped %v), want raw 0", inverse, raw, clamped)
+		}
+		if raw, clamped := FormulaToRaw(math.NaN(), "256/x", inverse, "uint8"); !clamped {
+			t.Errorf("inverse %q: NaN writes raw %d unclamped", inverse, raw)
+		}
+	}
+	if raw, clamped := FormulaToRaw(-5, "0.002*x*x", "", "uint8"); raw != 0 || !clamped {
+		t.Errorf("-5 below the smallest value writes raw %d (clamped %v), want raw 0 clamped", raw, clamped)
+	}
+	if v := FormulaToReal(0, "256/x"); v != 0 {
+		t.Errorf("raw 0 of 256/x reads %g, want 0", v)
+	}
+	if v := FormulaToReal(1, "256/"); !math.IsNaN(v) {
+		t.Errorf("an invalid formula reads %g, want NaN", v)
+	}
+}
+
+func TestCheckFormula(t *testing.T) {
+	tests := []struct {
+		formula, inverse string
+		ok               bool
+	}{
+		{"256/x", "256/x", true},
+		{"0.5*x", "", true},
+		{"", "", true},
+		{"", "2*x", false},
+		{"2*y", "", false},
+		{"0.5*x", "x/", false},
+		// Off by a factor of two, and by more than one raw step
+		{"0.5*x", "x", false},
+		{"0.5*x", "2*x+2", false},
+	}
+	for _, tt := range tests {
+		if err := CheckFormula(tt.formula, tt.inverse, "uint8"); (err == nil) != tt.ok {
+			t.Errorf("CheckFormula(%q, %q) = %v, want ok %v", tt.formula, tt.inverse, err, tt.ok)
+		}
+	}
+}
+
+// MapConfig and ConfigParam convert with their formula when there is one,
+// and with the linear scale otherwise
+func TestFormulaConversion(t *testing.T) {
+	cfg := MapConfig{DataType: "uint8", Scale: 2, Offset2: 1, Formula: "256/x", InverseFormula: "256/x"}
+	param := ConfigParam{DataType: "uint8", Scale: 2, Offset2: 1, Formula: "256/x", InverseFormula: "256/x"}
+	if cfg.ToReal(64) != 4 || param.ToReal(64) != 4 {
+		t.Errorf("raw 64 reads %g and %g, want 4", cfg.ToReal(64), param.ToReal(64))
+	}
+	if raw, _ := cfg.ToRaw(4); raw != 64 {
+		t.Errorf("map writes 4 as raw %d, want 64", raw)
+	}
+	if raw, _ := param.ToRaw(4); raw != 64 {
+		t.Errorf("parameter writes 4 as raw %d, want 64", raw)
+	}
+	cfg.Formula, cfg.InverseFormula = "", ""
+	if cfg.ToReal(64) != 129 {
+		t.Errorf("without a formula raw 64 reads %g, want 129", cfg.ToReal(64))
+	}
+}
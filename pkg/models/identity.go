@@ -0,0 +1,154 @@
+package models
+
+import "time"
+
+// Identification fields extracted from a binary
+const (
+	IDFieldBoschNumber     = "bosch_number"
+	IDFieldPartNumber      = "part_number"
+	IDFieldSoftwareVersion = "software_version"
+)
+
+// IDRegion is a byte range [Start, End) searched for identification strings
+type IDRegion struct {
+	Name  string
+	Start int64
+	End   int64
+}
+
+// IDPattern maps printable ASCII runs matching Pattern (a regular
+// expression matched against the whole run) to an identification field
+type IDPattern struct {
+	Field   string
+	Pattern string
+}
+
+// IDProfile describes where an ECU family stores its identification
+// strings and what they look like. An empty Regions list searches the
+// whole file. Files larger than ImageSize are searched for the image at
+// every multiple of it, so dumps of bigger EPROMs are recognized.
+type IDProfile struct {
+	Name      string
+	Regions   []IDRegion
+	Patterns  []IDPattern
+	MinRun    int
+	ImageSize int64
+	// Endianness is the default byte order of multi-byte parameters that
+	// don't set their own, and of the stored checksum
+	Endianness Endianness
+	// Checksum is nil when the profile's checksum scheme is unknown
+	Checksum *ChecksumConfig
+}
+
+// IDString is an identification string found in a binary
+type IDString struct {
+	Field  string
+	Value  string
+	Offset int64
+}
+
+// BinaryIdentity summarizes what a binary is
+type BinaryIdentity struct {
+	Size            int64
+	SHA256          string
+	BoschNumber     string
+	PartNumber      string
+	SoftwareVersion string
+	Strings         []IDString
+	BaseOffset      int64 // file offset of the image the map definitions refer to
+	// Provenance is the record left by the last save with this tool, or
+	// nil if the file has no sidecar
+	Provenance *Provenance
+}
+
+// Provenance records which tool build and definitions last saved a
+// modified ECU file. It is kept in the file's sidecar.
+type Provenance struct {
+	ToolVersion string `json:"tool_version"`
+	// Definitions is DefinitionsFingerprint at the time of the save
+	Definitions string `json:"definitions"`
+	Profile     string `json:"profile"`
+	// Modified lists the maps and parameters the save changed
+	Modified     []string  `json:"modified"`
+	ParentSHA256 string    `json:"parent_sha256"`
+	SHA256       string    `json:"sha256"`
+	Saved        time.Time `json:"saved"`
+}
+
+// Stale reports whether the file was changed by something else since the
+// save that recorded the provenance
+func (p *Provenance) Stale(hash string) bool {
+	return p.SHA256 != hash
+}
+
+// Label returns a short human-readable identification, or "" if nothing
+// was recognized
+func (id *BinaryIdentity) Label() string {
+	label := ""
+	for _, s := range []string{id.PartNumber, id.BoschNumber, id.SoftwareVersion} {
+		if s == "" {
+			continue
+		}
+		if label != "" {
+			label += " · "
+		}
+		label += s
+	}
+	return label
+}
+
+// UnknownVersion stands for an identification string not found in a
+// binary
+const UnknownVersion = "unknown"
+
+// ECUVersion is the part number and software version telling apart
+// binaries of the same ECU. Fields not found in the binary are empty.
+type ECUVersion struct {
+	PartNumber      string `json:"part_number"`
+	SoftwareVersion string `json:"software_version"`
+}
+
+// Known reports whether either field was found
+func (v ECUVersion) Known() bool {
+	return v.PartNumber != "" || v.SoftwareVersion != ""
+}
+
+// String returns "part number / software version", with UnknownVersion
+// for a missing field, or just UnknownVersion if neither was found
+func (v ECUVersion) String() string {
+	if !v.Known() {
+		return UnknownVersion
+	}
+	known := func(s string) string {
+		if s == "" {
+			return UnknownVersion
+		}
+		return s
+	}
+	return known(v.PartNumber) + " / " + known(v.SoftwareVersion)
+}
+
+// M21IDProfile locates BMW/Porsche part numbers, Bosch hardware numbers and
+// software numbers near the end of Motronic M2.1 EPROMs. No M2.1 checksum
+// is documented yet, so Checksum is only set by -checksum-spec.
+var M21IDProfile = IDProfile{
+	Name:       "Motronic M2.1",
+	ImageSize:  0x8000,
+	Endianness: LittleEndian,
+	Regions: []IDRegion{
+		{Name: "ID block", Start: 0x7800, End: 0x8000},
+	},
+	Patterns: []IDPattern{
+		// Bosch hardware number, e.g. "0 261 200 173"
+		{Field: IDFieldBoschNumber, Pattern: `^0 ?261 ?\d{3} ?\d{3}$`},
+		// Bosch software number, e.g. "1 267 357 006"
+		{Field: IDFieldSoftwareVersion, Pattern: `^1 ?267 ?\d{3} ?\d{3}$`},
+		// Software version tag, e.g. "SW 2.1" or "V1.03"
+		{Field: IDFieldSoftwareVersion, Pattern: `^(?i:sw|v)\s?\d+(\.\d+)+$`},
+		// Porsche part number, e.g. "964.618.124.03"
+		{Field: IDFieldPartNumber, Pattern: `^\d{3}\.\d{3}\.\d{3}\.\d{2}$`},
+		// BMW part number, e.g. "1 247 497"
+		{Field: IDFieldPartNumber, Pattern: `^1 ?\d{3} ?\d{3}$`},
+	},
+	MinRun: 6,
+}
@@ -0,0 +1,48 @@
+package models
+
+// BaseOffset is the file offset of the image the active definitions
+// address, set with UseBaseOffset. Definitions are written for an image
+// starting the file; dumps with a reader header or the image in an upper
+// bank move them.
+var BaseOffset int64
+
+// UseBaseOffset moves the active map and parameter definitions to an image
+// starting at base in its file. Definitions loaded later must be moved
+// with Located.
+func UseBaseOffset(base int64) {
+	shift := base - BaseOffset
+	for i := range MapConfigs {
+		MapConfigs[i] = MapConfigs[i].Relocate(shift)
+	}
+	for i := range ConfigParams {
+		ConfigParams[i].Offset += shift
+	}
+	BaseOffset = base
+}
+
+// Located returns a map definition written for an image starting the file
+// moved to BaseOffset, like the active ones
+func Located(cfg MapConfig) MapConfig {
+	return cfg.Relocate(BaseOffset)
+}
+
+// ImageMapConfigs returns the active map definitions with offsets into the
+// image rather than the file, as they are stored
+func ImageMapConfigs() []MapConfig {
+	maps := make([]MapConfig, len(MapConfigs))
+	for i, cfg := range MapConfigs {
+		maps[i] = cfg.Relocate(-BaseOffset)
+	}
+	return maps
+}
+
+// ImageConfigParams returns the active parameter definitions with offsets
+// into the image rather than the file
+func ImageConfigParams() []ConfigParam {
+	params := make([]ConfigParam, len(ConfigParams))
+	for i, p := range ConfigParams {
+		p.Offset -= BaseOffset
+		params[i] = p
+	}
+	return params
+}
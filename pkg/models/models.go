@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
 // MapConfig defines the structure of a map in the ECU file
 type MapConfig struct {
 	Name        string
@@ -11,14 +16,171 @@ type MapConfig struct {
 	Offset2     float64
 	Unit        string
 	Description string
+	Conversion  string // linear (default) or inverse
+
+	// Formula converts raw cell values with an expression in x instead of
+	// Scale, Offset2 and Conversion, e.g. "256/x" (see Formula), and
+	// InverseFormula turns values back into raw ones for writes; without
+	// it writes search for the closest raw value. omitempty keeps the
+	// fingerprint of definitions without a formula unchanged.
+	Formula        string `json:",omitempty"`
+	InverseFormula string `json:",omitempty"`
+
+	// Endianness is the byte order of uint16/int16 cells; empty is
+	// little-endian (see ByteOrder). omitempty keeps the fingerprint of
+	// definitions without one unchanged.
+	Endianness Endianness `json:",omitempty"`
+
+	// HighlightBelow marks cells whose value is under this threshold in
+	// every map view, e.g. retarded ignition timing. Nil disables it.
+	HighlightBelow *float64
+
+	// NudgeStep is how far one +/- nudge moves a cell, in engineering
+	// units. Zero means one raw step.
+	NudgeStep float64
+
+	// ColorScale sets how heatmaps color the map; the zero value scales
+	// between the smallest and largest cell
+	ColorScale ColorScale
+
+	// Role designates the map for features that work on a kind of map
+	// rather than a named one, e.g. RoleBoost for the boost preset
+	Role string
+
+	// Category groups the map in listings and the GUI sidebar, one of
+	// Categories; empty lists it under CategoryOther (see CategoryName).
+	// Like Source it is not part of the fingerprint.
+	Category string `json:"-"`
+
+	// MinValue and MaxValue are the plausible cell values in engineering
+	// units. Edits outside them are refused unless forced; equal values
+	// (the zero value) leave the map unlimited. omitempty keeps the
+	// fingerprint of definitions without limits unchanged.
+	MinValue float64 `json:",omitempty"`
+	MaxValue float64 `json:",omitempty"`
+
+	// Unconfirmed marks a candidate whose location or meaning hasn't been
+	// verified against real binaries. Presets that find their map by Role
+	// refuse to write to it.
+	Unconfirmed bool
+
+	// InvertY marks a map stored with its high-load row first. Views still
+	// show row 0 at the top; only the load axis labels run the other way
+	// (see LoadAt).
+	InvertY bool
+
+	// XAxis and YAxis locate the RPM (one breakpoint per column) and load
+	// (one per row) breakpoints stored in the binary. Nil falls back to
+	// the evenly spaced labels of RPMLabel and LoadLabel. omitempty keeps
+	// the fingerprint of definitions without axes unchanged.
+	XAxis *AxisConfig `json:",omitempty"`
+	YAxis *AxisConfig `json:",omitempty"`
+
+	// OverlapNote records the partial overlaps with other definitions that
+	// were confirmed when the map was added (see OverlapNote); -check-defs
+	// still lists them. omitempty keeps the fingerprint of definitions
+	// without one unchanged.
+	OverlapNote string `json:",omitempty"`
+
+	// Source names the file the definition was loaded from, empty for a
+	// built-in one. It is not part of the fingerprint.
+	Source string `json:"-"`
+}
+
+// HasLimits reports whether the map restricts its cell values to
+// MinValue-MaxValue
+func (cfg MapConfig) HasLimits() bool {
+	return cfg.MaxValue > cfg.MinValue
+}
+
+// InRange reports whether value is allowed in the map's cells. The raw
+// steps rarely land on a limit exactly, so values within rounding of it
+// are accepted.
+func (cfg MapConfig) InRange(value float64) bool {
+	const epsilon = 1e-9
+	return !cfg.HasLimits() || value >= cfg.MinValue-epsilon && value <= cfg.MaxValue+epsilon
+}
+
+// RangeLabel describes the allowed values, e.g. "-10.00 to 45.00 deg"
+func (cfg MapConfig) RangeLabel() string {
+	return fmt.Sprintf("%.2f to %.2f %s", cfg.MinValue, cfg.MaxValue, cfg.Unit)
+}
+
+// IsCurve reports whether the map is a one-row curve, shown as a line
+// against its column axis instead of a grid
+func (cfg MapConfig) IsCurve() bool {
+	return cfg.Rows == 1 && cfg.Cols > 1
+}
+
+// XAxisName returns the caption of the column axis: the name or unit of
+// the XAxis, or RPM
+func (cfg MapConfig) XAxisName() string {
+	return cfg.XAxis.caption("RPM")
+}
+
+// YAxisName returns the caption of the row axis: the name or unit of the
+// YAxis, or Load
+func (cfg MapConfig) YAxisName() string {
+	return cfg.YAxis.caption("Load")
+}
+
+// RowCaption returns the row axis caption of the text views: YAxisName,
+// or "Load%" for the synthetic load axis
+func (cfg MapConfig) RowCaption() string {
+	if cfg.YAxis == nil {
+		return "Load%"
+	}
+	return cfg.YAxisName()
+}
+
+// GridHeader returns the corner cell of a value grid naming both axes,
+// e.g. "Load\RPM" or "Temp\RPM"
+func (cfg MapConfig) GridHeader() string {
+	return cfg.YAxisName() + "\\" + cfg.XAxisName()
+}
+
+// Map roles
+const (
+	RoleBoost     = "boost"
+	RoleColdStart = "coldstart"
+)
+
+// MapByRole returns the map designated for role in the active definitions
+func MapByRole(role string) (MapConfig, bool) {
+	for _, cfg := range MapConfigs {
+		if cfg.Role == role {
+			return cfg, true
+		}
+	}
+	return MapConfig{}, false
+}
+
+// Threshold returns a pointer for MapConfig.HighlightBelow
+func Threshold(value float64) *float64 {
+	return &value
+}
+
+// BelowThreshold reports whether a value should carry the highlight marker
+func (cfg MapConfig) BelowThreshold(value float64) bool {
+	return cfg.HighlightBelow != nil && value < *cfg.HighlightBelow
 }
 
 // ECUMap represents a 2D map from the ECU
 type ECUMap struct {
 	Config MapConfig
 	Data   [][]float64
+	// XAxis and YAxis hold the breakpoints read from the binary, nil when
+	// the definition has no axis
+	XAxis []float64
+	YAxis []float64
 }
 
+// FixedMaps is how many definitions at the start of MapConfigs are used by
+// position: fuel, ignition and lambda. Definitions that replace the
+// built-in ones must keep that order. Boost and cold start are found by
+// Role instead.
+const FixedMaps = 3
+
 // Predefined map configurations for Motronic M2.1
 // Maps 0-2 are CONFIRMED via binary scan analysis
 // Maps 3+ are high-confidence candidates from scan results
@@ -26,6 +188,7 @@ var MapConfigs = []MapConfig{
 	// CONFIRMED MAPS (validated in both binary files)
 	{
 		Name:        "Main Fuel Map",
+		Category:    CategoryFuel,
 		Offset:      0x6700,
 		Rows:        8,
 		Cols:        16,
@@ -33,10 +196,17 @@ var MapConfigs = []MapConfig{
 		Scale:       0.04,
 		Offset2:     0,
 		Unit:        "ms",
+		NudgeStep:   0.2,
 		Description: "Primary fuel injection duration map (CONFIRMED)",
+		// 0 ms is a fuel cut cell
+		MinValue: 0,
+		MaxValue: 10,
+		// A single long cranking/full-load cell shouldn't flatten the rest
+		ColorScale: ColorScale{Mode: ScaleRobust},
 	},
 	{
 		Name:        "Ignition Timing Map",
+		Category:    CategoryIgnition,
 		Offset:      0x6780,
 		Rows:        8,
 		Cols:        16,
@@ -45,9 +215,14 @@ var MapConfigs = []MapConfig{
 		Offset2:     -24.0,
 		Unit:        "deg",
 		Description: "Spark advance timing map (CONFIRMED)",
+		MinValue:    -10,
+		MaxValue:    45,
+		// Negative values are retarded timing
+		HighlightBelow: Threshold(0),
 	},
 	{
 		Name:        "Lambda Target Map",
+		Category:    CategoryLambda,
 		Offset:      0x6800,
 		Rows:        8,
 		Cols:        16,
@@ -56,11 +231,17 @@ var MapConfigs = []MapConfig{
 		Offset2:     0.5,
 		Unit:        "λ",
 		Description: "Target air-fuel ratio map (CONFIRMED)",
+		MinValue:    0.7,
+		MaxValue:    1.3,
+		// Fixed bands around stoichiometric so a map varying 0.95-1.05
+		// doesn't span the whole gradient
+		ColorScale: ColorScale{Mode: ScaleBands, Bounds: []float64{0.8, 0.9, 0.97, 1.03, 1.1, 1.2}},
 	},
 
 	// HIGH-CONFIDENCE CANDIDATES (from scan analysis)
 	{
 		Name:        "Correction Table 1",
+		Category:    CategoryCorrections,
 		Offset:      0x60C0,
 		Rows:        8,
 		Cols:        8,
@@ -69,9 +250,14 @@ var MapConfigs = []MapConfig{
 		Offset2:     0,
 		Unit:        "%",
 		Description: "Limits/correction table (variance: 100.3)",
+		Unconfirmed: true,
+		// -map boost has always shown this table; boost presets stay
+		// disabled until its role is confirmed
+		Role: RoleBoost,
 	},
 	{
 		Name:        "Fuel/Timing Trim 1",
+		Category:    CategoryExperimental,
 		Offset:      0x6CC0,
 		Rows:        8,
 		Cols:        16,
@@ -80,9 +266,11 @@ var MapConfigs = []MapConfig{
 		Offset2:     0,
 		Unit:        "%",
 		Description: "Fuel or timing trim table (variance: 260.9)",
+		Unconfirmed: true,
 	},
 	{
 		Name:        "Correction Table 2",
+		Category:    CategoryCorrections,
 		Offset:      0x6D00,
 		Rows:        8,
 		Cols:        8,
@@ -91,9 +279,11 @@ var MapConfigs = []MapConfig{
 		Offset2:     0,
 		Unit:        "%",
 		Description: "Correction table (variance: 125.1)",
+		Unconfirmed: true,
 	},
 	{
 		Name:        "Fuel/Timing Trim 2",
+		Category:    CategoryExperimental,
 		Offset:      0x6EC0,
 		Rows:        8,
 		Cols:        16,
@@ -102,9 +292,11 @@ var MapConfigs = []MapConfig{
 		Offset2:     0,
 		Unit:        "%",
 		Description: "Fuel or timing trim table (variance: 385.8)",
+		Unconfirmed: true,
 	},
 	{
 		Name:        "Correction Table 3",
+		Category:    CategoryCorrections,
 		Offset:      0x6F80,
 		Rows:        8,
 		Cols:        8,
@@ -113,9 +305,11 @@ var MapConfigs = []MapConfig{
 		Offset2:     0,
 		Unit:        "%",
 		Description: "Correction table (variance: 136.3)",
+		Unconfirmed: true,
 	},
 	{
 		Name:        "Trim Table 1",
+		Category:    CategoryExperimental,
 		Offset:      0x7140,
 		Rows:        8,
 		Cols:        16,
@@ -124,9 +318,11 @@ var MapConfigs = []MapConfig{
 		Offset2:     0,
 		Unit:        "%",
 		Description: "Trim table (variance: 196.6)",
+		Unconfirmed: true,
 	},
 	{
 		Name:        "Trim Table 2",
+		Category:    CategoryExperimental,
 		Offset:      0x7200,
 		Rows:        8,
 		Cols:        16,
@@ -135,5 +331,50 @@ var MapConfigs = []MapConfig{
 		Offset2:     0,
 		Unit:        "%",
 		Description: "Trim table (variance: 237.1)",
+		Unconfirmed: true,
+	},
+
+	// CURVE CANDIDATES (one row against a breakpoint axis)
+	{
+		Name:        "Temperature Correction Curve",
+		Category:    CategoryCorrections,
+		Offset:      0x6E00,
+		Rows:        1,
+		Cols:        16,
+		DataType:    "uint8",
+		Scale:       0.01,
+		Offset2:     0,
+		Unit:        "factor",
+		Description: "Correction factor against coolant temperature (smooth 16-byte run and rising axis from the scan; role unverified)",
+		Unconfirmed: true,
+		XAxis:       &AxisConfig{Offset: 0x6580, Count: 16, DataType: "uint8", Scale: 0.75, Offset2: -40, Unit: "°C"},
+	},
+
+	// Indexed by coolant temperature rather than load. The breakpoint
+	// table hasn't been located, so the rows carry nominal labels.
+	{
+		Name:        "Cold Start Enrichment",
+		Category:    CategoryFuel,
+		Offset:      0x7A00,
+		Rows:        8,
+		Cols:        8,
+		DataType:    "uint8",
+		Scale:       0.01,
+		Offset2:     0,
+		Unit:        "%",
+		Description: "Cold start enrichment by coolant temperature (nominal -30 to +90 °C rows; offset from the original map list)",
+		Unconfirmed: true,
+		Role:        RoleColdStart,
+		YAxis:       &AxisConfig{Count: 8, Unit: "°C", Name: "Temp", Labels: []float64{-30, -15, 0, 15, 30, 50, 70, 90}},
 	},
 }
+
+// FindMapConfig looks up a map definition by name, ignoring case
+func FindMapConfig(name string) (MapConfig, bool) {
+	for _, cfg := range MapConfigs {
+		if strings.EqualFold(cfg.Name, name) {
+			return cfg, true
+		}
+	}
+	return MapConfig{}, false
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Region is a named byte range occupied by a definition in the ECU binary.
+// End is exclusive.
+type Region struct {
+	Name  string
+	Kind  string // map, param, axis
+	Start int64
+	End   int64
+}
+
+// Overlap describes two definitions whose byte ranges intersect
+type Overlap struct {
+	A     Region
+	B     Region
+	Start int64
+	End   int64
+	Exact bool // both regions cover exactly the same bytes
+}
+
+// String returns a human-readable description of the overlap
+func (o Overlap) String() string {
+	if o.Exact {
+		return fmt.Sprintf("%s %q duplicates %s %q (0x%04X-0x%04X)",
+			o.A.Kind, o.A.Name, o.B.Kind, o.B.Name, o.Start, o.End)
+	}
+	return fmt.Sprintf("%s %q overlaps %s %q at 0x%04X-0x%04X (%d bytes)",
+		o.A.Kind, o.A.Name, o.B.Kind, o.B.Name, o.Start, o.End, o.End-o.Start)
+}
+
+// DataTypeSize returns the width in bytes of a map or parameter data type
+func DataTypeSize(dataType string) int {
+	switch dataType {
+	case "uint16", "int16":
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ByteSize returns the number of bytes the map occupies in the binary
+func (c MapConfig) ByteSize() int64 {
+	return int64(c.Rows * c.Cols * DataTypeSize(c.DataType))
+}
+
+// Region returns the byte range occupied by the map
+func (c MapConfig) Region() Region {
+	return Region{Name: c.Name, Kind: "map", Start: c.Offset, End: c.Offset + c.ByteSize()}
+}
+
+// Region returns the byte range occupied by the parameter
+func (p ConfigParam) Region() Region {
+	return Region{Name: p.Name, Kind: "param", Start: p.Offset, End: p.Offset + int64(DataTypeSize(p.DataType))}
+}
+
+// Region returns the byte range of the breakpoints, named after the map
+// and axis, e.g. "Ignition X axis"
+func (a AxisConfig) Region(name string) Region {
+	return Region{Name: name, Kind: "axis", Start: a.Offset, End: a.Offset + a.ByteSize()}
+}
+
+// AxisRegions returns the byte ranges of the axes stored for the given
+// maps; axes with fixed Labels store nothing
+func AxisRegions(maps []MapConfig) []Region {
+	var regions []Region
+	for _, cfg := range maps {
+		if cfg.XAxis != nil && cfg.XAxis.Labels == nil {
+			regions = append(regions, cfg.XAxis.Region(cfg.Name+" X axis"))
+		}
+		if cfg.YAxis != nil && cfg.YAxis.Labels == nil {
+			regions = append(regions, cfg.YAxis.Region(cfg.Name+" Y axis"))
+		}
+	}
+	return regions
+}
+
+// DefinitionRegions returns the byte ranges of all given maps and parameters
+func DefinitionRegions(maps []MapConfig, params []ConfigParam) []Region {
+	regions := make([]Region, 0, len(maps)+len(params))
+	for _, cfg := range maps {
+		regions = append(regions, cfg.Region())
+	}
+	for _, param := range params {
+		regions = append(regions, param.Region())
+	}
+	return regions
+}
+
+// CheckOverlaps returns every existing region that intersects the
+// candidate. Two axes on exactly the same bytes are one breakpoint table
+// shared by several maps, which is not an overlap.
+func CheckOverlaps(candidate Region, existing []Region) []Overlap {
+	var overlaps []Overlap
+	for _, r := range existing {
+		start := max(candidate.Start, r.Start)
+		end := min(candidate.End, r.End)
+		if start >= end {
+			continue
+		}
+		if candidate.Kind == "axis" && r.Kind == "axis" && candidate.Start == r.Start && candidate.End == r.End {
+			continue
+		}
+		overlaps = append(overlaps, Overlap{
+			A:     candidate,
+			B:     r,
+			Start: start,
+			End:   end,
+			Exact: candidate.Start == r.Start && candidate.End == r.End,
+		})
+	}
+	return overlaps
+}
+
+// FindOverlaps returns all pairwise overlaps between the given regions,
+// ordered by start offset
+func FindOverlaps(regions []Region) []Overlap {
+	var overlaps []Overlap
+	for i := range regions {
+		overlaps = append(overlaps, CheckOverlaps(regions[i], regions[i+1:])...)
+	}
+
+	sort.Slice(overlaps, func(i, j int) bool {
+		return overlaps[i].Start < overlaps[j].Start
+	})
+
+	return overlaps
+}
+
+// OverlapNote describes confirmed partial overlaps for a definition's
+// OverlapNote, e.g. `overlaps map "Ignition" at 0x6A00-0x6A10 (16 bytes)`.
+// Overlaps of the map's own axes keep the axis they start from.
+func OverlapNote(overlaps []Overlap) string {
+	notes := make([]string, len(overlaps))
+	for i, o := range overlaps {
+		notes[i] = o.String()
+		if o.A.Kind == "map" {
+			notes[i] = strings.TrimPrefix(notes[i], fmt.Sprintf("%s %q ", o.A.Kind, o.A.Name))
+		}
+	}
+	return strings.Join(notes, "; ")
+}
+
+// Gaps returns the byte ranges of an image of size bytes that none of the
+// regions cover and that are at least minLength bytes long, in order. They
+// are named by their offsets and have kind "gap".
+func Gaps(regions []Region, size, minLength int64) []Region {
+	sorted := append([]Region(nil), regions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	var gaps []Region
+	add := func(start, end int64) {
+		if end-start >= minLength {
+			gaps = append(gaps, Region{Name: fmt.Sprintf("0x%04X-0x%04X", start, end-1), Kind: "gap", Start: start, End: end})
+		}
+	}
+	var covered int64
+	for _, r := range sorted {
+		if r.Start > covered {
+			add(covered, min(r.Start, size))
+		}
+		covered = max(covered, r.End)
+	}
+	if covered < size {
+		add(covered, size)
+	}
+	return gaps
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckNewMapAxisOverlaps(t *testing.T) {
+	existing := []MapConfig{{
+		Name: "Ignition", Offset: 0x100, Rows: 2, Cols: 4, DataType: "uint8", Scale: 1,
+		XAxis: &AxisConfig{Offset: 0x80, Count: 4, DataType: "uint8", Scale: 1},
+		YAxis: &AxisConfig{Offset: 0x90, Count: 2, DataType: "uint8", Scale: 1},
+	}}
+
+	tests := []struct {
+		name     string
+		cfg      MapConfig
+		overlaps []string // names of the regions overlapped, in order
+		exact    bool
+	}{
+		{
+			name: "clear",
+			cfg:  MapConfig{Name: "New", Offset: 0x200, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1},
+		},
+		{
+			name:     "map over an axis",
+			cfg:      MapConfig{Name: "New", Offset: 0x82, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1},
+			overlaps: []string{"Ignition X axis"},
+		},
+		{
+			name: "axis over a map",
+			cfg: MapConfig{Name: "New", Offset: 0x200, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1,
+				XAxis: &AxisConfig{Offset: 0x106, Count: 4, DataType: "uint8", Scale: 1}},
+			overlaps: []string{"Ignition"},
+		},
+		{
+			name: "axis over an axis",
+			cfg: MapConfig{Name: "New", Offset: 0x200, Rows: 2, Cols: 1, DataType: "uint8", Scale: 1,
+				YAxis: &AxisConfig{Offset: 0x91, Count: 2, DataType: "uint8", Scale: 1}},
+			overlaps: []string{"Ignition Y axis"},
+		},
+		{
+			name: "shared breakpoints",
+			cfg: MapConfig{Name: "New", Offset: 0x200, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1,
+				XAxis: &AxisConfig{Offset: 0x80, Count: 4, DataType: "uint8", Scale: 1}},
+		},
+		{
+			name:     "duplicate map",
+			cfg:      MapConfig{Name: "New", Offset: 0x100, Rows: 2, Cols: 4, DataType: "uint8", Scale: 1},
+			overlaps: []string{"Ignition"},
+			exact:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := CheckNewMap(tt.cfg, existing, nil, 0x1000)
+			if len(check.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", check.Errors)
+			}
+			var got []string
+			for _, o := range check.Overlaps {
+				got = append(got, o.B.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.overlaps, ",") {
+				t.Errorf("overlaps %q, want %q", got, tt.overlaps)
+			}
+			if dup := check.Duplicates() > 0; dup != tt.exact {
+				t.Errorf("duplicates %v, want %v", dup, tt.exact)
+			}
+		})
+	}
+}
+
+func TestCheckDefinitionsIncludesAxes(t *testing.T) {
+	maps := []MapConfig{
+		{Name: "A", Offset: 0x100, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1,
+			XAxis: &AxisConfig{Offset: 0x200, Count: 4, DataType: "uint8", Scale: 1}},
+		{Name: "B", Offset: 0x202, Rows: 1, Cols: 4, DataType: "uint8", Scale: 1},
+	}
+	check := CheckDefinitions(maps, nil)
+	if len(check.Overlaps) != 1 {
+		t.Fatalf("got %d overlaps, want 1: %v", len(check.Overlaps), check.Overlaps)
+	}
+	if o := check.Overlaps[0]; o.Start != 0x202 || o.End != 0x204 {
+		t.Errorf("overlap at 0x%X-0x%X, want 0x202-0x204", o.Start, o.End)
+	}
+}
+
+func TestOverlapNote(t *testing.T) {
+	overlaps := []Overlap{
+		{A: Region{Name: "New", Kind: "map"}, B: Region{Name: "Ignition", Kind: "map"}, Start: 0x104, End: 0x106},
+		{A: Region{Name: "New X axis", Kind: "axis"}, B: Region{Name: "Fuel Y axis", Kind: "axis"}, Start: 0x90, End: 0x91},
+	}
+	want := `overlaps map "Ignition" at 0x0104-0x0106 (2 bytes); axis "New X axis" overlaps axis "Fuel Y axis" at 0x0090-0x0091 (1 bytes)`
+	if got := OverlapNote(overlaps); got != want {
+		t.Errorf("OverlapNote = %q\nwant %q", got, want)
+	}
+}
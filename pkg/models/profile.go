@@ -0,0 +1,106 @@
+package models
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+)
+
+// Profile is the map and parameter definitions of one firmware variant.
+// ExpectedSizes and Signatures recognize its binaries for DetectProfile:
+// an empty ExpectedSizes accepts any size, and every signature must match.
+type Profile struct {
+	Name          string
+	Description   string
+	ExpectedSizes []int64
+	Signatures    []Signature
+	MapConfigs    []MapConfig
+	ConfigParams  []ConfigParam
+	// Source names the file the profile was loaded from, empty for a
+	// built-in one
+	Source string
+}
+
+// Signature is a run of bytes a firmware has at a fixed file offset
+type Signature struct {
+	Offset int64
+	Bytes  []byte
+}
+
+// DefaultProfile names the built-in definitions of MapConfigs and
+// ConfigParams
+const DefaultProfile = "964"
+
+// Profiles are the selectable profiles, the built-in one first. Profile
+// files in the config directory are added to it at startup.
+var Profiles = []Profile{
+	{
+		Name:          DefaultProfile,
+		Description:   "Porsche 964 (964.618.124.03, 1 267 357 006)",
+		ExpectedSizes: []int64{0x8000},
+		MapConfigs:    slices.Clone(MapConfigs),
+		ConfigParams:  slices.Clone(ConfigParams),
+	},
+}
+
+// ActiveProfile is the name of the profile last selected with UseProfile
+var ActiveProfile = DefaultProfile
+
+// FindProfile looks up a profile by name, ignoring case
+func FindProfile(name string) (Profile, bool) {
+	for _, p := range Profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// UseProfile makes the profile's definitions the active ones, replacing
+// MapConfigs and ConfigParams. They address an image starting the file
+// until UseBaseOffset moves them.
+func UseProfile(p Profile) {
+	MapConfigs = slices.Clone(p.MapConfigs)
+	ConfigParams = slices.Clone(p.ConfigParams)
+	ActiveProfile = p.Name
+	BaseOffset = 0
+}
+
+// Matches reports whether data has one of the profile's expected sizes
+// and all of its signatures
+func (p Profile) Matches(data []byte) bool {
+	if len(p.ExpectedSizes) > 0 && !slices.Contains(p.ExpectedSizes, int64(len(data))) {
+		return false
+	}
+	for _, s := range p.Signatures {
+		end := s.Offset + int64(len(s.Bytes))
+		if s.Offset < 0 || end > int64(len(data)) || !bytes.Equal(data[s.Offset:end], s.Bytes) {
+			return false
+		}
+	}
+	return true
+}
+
+// DetectProfile picks the profile of a binary. A profile whose signatures
+// match wins over those matching on size alone; the result must be the
+// only one of its kind, so ok is false when the binary is ambiguous or
+// matches nothing.
+func DetectProfile(data []byte) (p Profile, ok bool) {
+	var signed, sized []Profile
+	for _, candidate := range Profiles {
+		switch {
+		case !candidate.Matches(data):
+		case len(candidate.Signatures) > 0:
+			signed = append(signed, candidate)
+		default:
+			sized = append(sized, candidate)
+		}
+	}
+	switch {
+	case len(signed) == 1:
+		return signed[0], true
+	case len(signed) == 0 && len(sized) == 1:
+		return sized[0], true
+	}
+	return Profile{}, false
+}
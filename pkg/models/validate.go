@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefinitionCheck is the result of validating definitions. Errors and
+// duplicate byte ranges make definitions unusable; overlaps of different
+// byte ranges are only warnings, since tables sometimes share bytes.
+type DefinitionCheck struct {
+	Errors   []error
+	Overlaps []Overlap
+}
+
+// Duplicates returns how many overlaps cover exactly the same bytes
+func (c DefinitionCheck) Duplicates() int {
+	n := 0
+	for _, o := range c.Overlaps {
+		if o.Exact {
+			n++
+		}
+	}
+	return n
+}
+
+// OK reports whether the definitions pass: no errors and no duplicates
+func (c DefinitionCheck) OK() bool {
+	return len(c.Errors) == 0 && c.Duplicates() == 0
+}
+
+// CheckDefinitions validates map and parameter definitions the way
+// -check-defs does: invalid scales, axes and value limits and overlapping
+// byte ranges of maps, parameters and axes.
+func CheckDefinitions(maps []MapConfig, params []ConfigParam) DefinitionCheck {
+	errs := append(CheckScales(maps, params), CheckAxes(maps)...)
+	for _, m := range maps {
+		if err := CheckEndianness(m.Endianness); err != nil {
+			errs = append(errs, fmt.Errorf("map %q: %w", m.Name, err))
+		}
+		if (m.MinValue != 0 || m.MaxValue != 0) && !m.HasLimits() {
+			errs = append(errs, fmt.Errorf("map %q: max value %.2f must be above min value %.2f", m.Name, m.MaxValue, m.MinValue))
+		}
+		if err := CheckCategory(m.Category); err != nil {
+			errs = append(errs, fmt.Errorf("map %q: %w", m.Name, err))
+		}
+	}
+	for _, p := range params {
+		if err := CheckEndianness(p.Endianness); err != nil {
+			errs = append(errs, fmt.Errorf("parameter %q: %w", p.Name, err))
+		}
+	}
+	return DefinitionCheck{
+		Errors:   errs,
+		Overlaps: FindOverlaps(append(DefinitionRegions(maps, params), AxisRegions(maps)...)),
+	}
+}
+
+// CheckNewMap validates a map about to be added to the given definitions,
+// for an image of size bytes. Besides the checks of CheckDefinitions, the
+// name must be new and the map must have cells, a known data type and lie
+// inside the image, and so must its axes. Only overlaps of the new map and
+// its axes are reported.
+func CheckNewMap(cfg MapConfig, maps []MapConfig, params []ConfigParam, size int64) DefinitionCheck {
+	var check DefinitionCheck
+	fail := func(format string, args ...interface{}) {
+		check.Errors = append(check.Errors, fmt.Errorf(format, args...))
+	}
+
+	if strings.TrimSpace(cfg.Name) == "" {
+		fail("the map needs a name")
+	}
+	for _, m := range maps {
+		if strings.EqualFold(m.Name, cfg.Name) {
+			fail("a map named %q already exists", m.Name)
+		}
+	}
+	if cfg.Rows < 1 || cfg.Cols < 1 {
+		fail("a map needs at least one row and one column")
+	}
+	if !KnownDataType(cfg.DataType) {
+		fail("unknown data type %q", cfg.DataType)
+	}
+	if err := cfg.CheckConversion(); err != nil {
+		fail("map %q: %w", cfg.Name, err)
+	}
+	if err := CheckEndianness(cfg.Endianness); err != nil {
+		fail("map %q: %w", cfg.Name, err)
+	}
+	if err := CheckCategory(cfg.Category); err != nil {
+		fail("map %q: %w", cfg.Name, err)
+	}
+	if cfg.Offset < 0 || cfg.Offset+cfg.ByteSize() > size {
+		fail("%s at 0x%04X (%d bytes) does not fit in the file (%d bytes)", cfg.Name, cfg.Offset, cfg.ByteSize(), size)
+	}
+	for _, err := range CheckAxes([]MapConfig{cfg}) {
+		fail("%w", err)
+	}
+	for _, axis := range []*AxisConfig{cfg.XAxis, cfg.YAxis} {
+		if axis != nil && (axis.Offset < 0 || axis.Offset+axis.ByteSize() > size) {
+			fail("%s axis at 0x%04X (%d bytes) does not fit in the file (%d bytes)", cfg.Name, axis.Offset, axis.ByteSize(), size)
+		}
+	}
+
+	existing := append(DefinitionRegions(maps, params), AxisRegions(maps)...)
+	for _, r := range append([]Region{cfg.Region()}, AxisRegions([]MapConfig{cfg})...) {
+		check.Overlaps = append(check.Overlaps, CheckOverlaps(r, existing)...)
+	}
+	return check
+}
+
+// DataTypes are the raw data types definitions can use. The byte order of
+// 16-bit types comes from the definition's Endianness.
+var DataTypes = []string{"uint8", "int8", "uint16", "int16"}
+
+// KnownDataType reports whether dataType is one of DataTypes
+func KnownDataType(dataType string) bool {
+	for _, t := range DataTypes {
+		if t == dataType {
+			return true
+		}
+	}
+	return false
+}
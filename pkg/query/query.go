@@ -0,0 +1,202 @@
+// Package query parses and evaluates cell predicates such as
+// "ignition > 35" or "any.raw >= 90%". The parser is meant to be shared by
+// every feature that selects cells by a condition, so the syntax stays the
+// same across the CLI, GUI and any future validation rules.
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// Field is the quantity a predicate compares
+type Field string
+
+const (
+	// FieldValue compares the scaled value in the map's unit
+	FieldValue Field = "value"
+	// FieldRaw compares the stored raw value
+	FieldRaw Field = "raw"
+)
+
+// operators in match order, so ">=" is found before ">"
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// epsilon absorbs floating point noise from scaling
+const epsilon = 1e-6
+
+// Predicate selects cells of one or all maps by comparing their value or
+// raw value against a threshold
+type Predicate struct {
+	// Maps are the maps searched, all of them for "any"
+	Maps      []models.MapConfig
+	Field     Field
+	Op        string
+	Threshold float64
+	// Percent makes Threshold a percentage of the data type ceiling (raw)
+	// or of the largest representable value (value)
+	Percent bool
+
+	expr string
+}
+
+// Parse parses "<map>[.value|.raw] <op> <number>[%]". map is "any" (or
+// "all") for every map, otherwise a name or unique part of one; op is one
+// of > >= < <= == !=.
+func Parse(expr string) (*Predicate, error) {
+	expr = strings.TrimSpace(expr)
+	p := &Predicate{Field: FieldValue, expr: expr}
+
+	idx := -1
+	for _, op := range operators {
+		if i := strings.Index(expr, op); i >= 0 {
+			idx, p.Op = i, op
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("no comparison in %q (expected one of > >= < <= == !=)", expr)
+	}
+	if p.Op == "=" {
+		p.Op = "=="
+	}
+
+	left := strings.TrimSpace(expr[:idx])
+	right := strings.TrimSpace(expr[idx+len(p.Op):])
+	if strings.HasPrefix(right, "=") {
+		return nil, fmt.Errorf("invalid operator in %q", expr)
+	}
+
+	if i := strings.LastIndex(left, "."); i >= 0 {
+		switch field := Field(strings.ToLower(left[i+1:])); field {
+		case FieldValue, FieldRaw:
+			p.Field, left = field, strings.TrimSpace(left[:i])
+		}
+	}
+	if left == "" {
+		return nil, fmt.Errorf("missing map in %q (use \"any\" for all maps)", expr)
+	}
+
+	if strings.HasSuffix(right, "%") {
+		p.Percent = true
+		right = strings.TrimSpace(strings.TrimSuffix(right, "%"))
+	}
+	threshold, err := strconv.ParseFloat(right, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", right)
+	}
+	p.Threshold = threshold
+
+	switch strings.ToLower(left) {
+	case "any", "all", "*":
+		p.Maps = models.MapConfigs
+	default:
+		cfg, err := editor.MatchMap(left)
+		if err != nil {
+			return nil, err
+		}
+		p.Maps = []models.MapConfig{cfg}
+	}
+	return p, nil
+}
+
+// String returns the expression the predicate was parsed from
+func (p *Predicate) String() string {
+	return p.expr
+}
+
+// Covers reports whether the predicate searches the named map
+func (p *Predicate) Covers(name string) bool {
+	for _, cfg := range p.Maps {
+		if cfg.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether a cell of cfg with this value and raw value
+// satisfies the predicate
+func (p *Predicate) Match(cfg models.MapConfig, value float64, raw int64) bool {
+	x, threshold := value, p.Threshold
+	if p.Field == FieldRaw {
+		x = float64(raw)
+	}
+	if p.Percent {
+		_, hi := models.RawRange(cfg.DataType)
+		limit := float64(hi)
+		if p.Field == FieldValue {
+			limit = math.Max(cfg.ToReal(hi), cfg.ToReal(0))
+		}
+		threshold = p.Threshold / 100 * limit
+	}
+
+	d := x - threshold
+	switch p.Op {
+	case ">":
+		return d > epsilon
+	case ">=":
+		return d > -epsilon
+	case "<":
+		return d < -epsilon
+	case "<=":
+		return d < epsilon
+	case "==":
+		return math.Abs(d) <= epsilon
+	case "!=":
+		return math.Abs(d) > epsilon
+	}
+	return false
+}
+
+// Match is a cell that satisfies a predicate
+type Match struct {
+	Map    string
+	Unit   string
+	Row    int
+	Col    int
+	Value  float64
+	Raw    int64
+	Offset int64
+}
+
+// Find returns every matching cell of the predicate's maps in the image,
+// in map, row, column order. Maps that lie outside the data are skipped.
+func Find(data []byte, p *Predicate) []Match {
+	var matches []Match
+	for _, cfg := range p.Maps {
+		if cfg.Offset+cfg.ByteSize() > int64(len(data)) {
+			continue
+		}
+		size := models.DataTypeSize(cfg.DataType)
+		for i := 0; i < cfg.Rows; i++ {
+			for j := 0; j < cfg.Cols; j++ {
+				offset := cfg.Offset + int64((i*cfg.Cols+j)*size)
+				raw := cfg.DecodeRaw(data[offset:])
+				value := cfg.ToReal(raw)
+				if !p.Match(cfg, value, raw) {
+					continue
+				}
+				matches = append(matches, Match{
+					Map: cfg.Name, Unit: cfg.Unit, Row: i, Col: j,
+					Value: value, Raw: raw, Offset: offset,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// Describe summarizes a result for status lines
+func Describe(p *Predicate, matches []Match) string {
+	maps := map[string]bool{}
+	for _, m := range matches {
+		maps[m.Map] = true
+	}
+	return fmt.Sprintf("%d cells in %d map(s) match %s", len(matches), len(maps), p)
+}
@@ -0,0 +1,175 @@
+package query
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr      string
+		maps      []string
+		field     Field
+		op        string
+		threshold float64
+		percent   bool
+	}{
+		{expr: "ignition > 35", maps: []string{"Ignition Timing Map"}, field: FieldValue, op: ">", threshold: 35},
+		{expr: "fuel>=2.5", maps: []string{"Main Fuel Map"}, field: FieldValue, op: ">=", threshold: 2.5},
+		{expr: "Lambda Target Map.value <= 0.9", maps: []string{"Lambda Target Map"}, field: FieldValue, op: "<=", threshold: 0.9},
+		{expr: " ignition.RAW < 10 ", maps: []string{"Ignition Timing Map"}, field: FieldRaw, op: "<", threshold: 10},
+		{expr: "fuel == 0", maps: []string{"Main Fuel Map"}, field: FieldValue, op: "==", threshold: 0},
+		{expr: "fuel = 0", maps: []string{"Main Fuel Map"}, field: FieldValue, op: "==", threshold: 0},
+		{expr: "fuel != -1.5", maps: []string{"Main Fuel Map"}, field: FieldValue, op: "!=", threshold: -1.5},
+		{expr: "ignition.raw > 50 %", maps: []string{"Ignition Timing Map"}, field: FieldRaw, op: ">", threshold: 50, percent: true},
+		{expr: "any.raw >= 90%", field: FieldRaw, op: ">=", threshold: 90, percent: true},
+		{expr: "all < 0", field: FieldValue, op: "<", threshold: 0},
+		{expr: "* > 1", field: FieldValue, op: ">", threshold: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Field != tt.field || p.Op != tt.op || p.Threshold != tt.threshold || p.Percent != tt.percent {
+				t.Errorf("Parse = %s %s %g (percent %v), want %s %s %g (percent %v)",
+					p.Field, p.Op, p.Threshold, p.Percent, tt.field, tt.op, tt.threshold, tt.percent)
+			}
+			if tt.maps == nil {
+				if len(p.Maps) != len(models.MapConfigs) {
+					t.Errorf("%d maps searched, want all %d", len(p.Maps), len(models.MapConfigs))
+				}
+			} else if len(p.Maps) != 1 || p.Maps[0].Name != tt.maps[0] || !p.Covers(tt.maps[0]) {
+				t.Errorf("maps %v, want %v", p.Maps, tt.maps)
+			}
+			if p.String() != strings.TrimSpace(tt.expr) {
+				t.Errorf("String = %q", p.String())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{expr: "ignition 35", err: "no comparison"},
+		{expr: "", err: "no comparison"},
+		{expr: "ignition >== 35", err: "invalid operator"},
+		{expr: "> 35", err: "missing map"},
+		{expr: ".raw > 35", err: "missing map"},
+		{expr: "ignition > hot", err: `invalid number "hot"`},
+		{expr: "ignition >", err: `invalid number ""`},
+		{expr: "ignition > 35%%", err: `invalid number "35%"`},
+		{expr: "boost > 1", err: "unknown map"},
+		{expr: "table > 1", err: "Correction Table 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Parse(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Parse = %v, %v; want an error containing %q", p, err, tt.err)
+			}
+		})
+	}
+	if _, err := Parse("boost > 1"); !errors.Is(err, reader.ErrNotFound) {
+		t.Errorf("unknown map: %v, want ErrNotFound", err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	fuel, ignition := models.MapConfigs[0], models.MapConfigs[1]
+	tests := []struct {
+		expr  string
+		cfg   models.MapConfig
+		raw   int64
+		match bool
+	}{
+		// Ignition is raw*0.75-24: raw 79 is 35.25°, raw 78 is 34.5°
+		{expr: "ignition > 35", cfg: ignition, raw: 79, match: true},
+		{expr: "ignition > 35", cfg: ignition, raw: 78},
+		{expr: "ignition > 34.5", cfg: ignition, raw: 78},
+		{expr: "ignition >= 34.5", cfg: ignition, raw: 78, match: true},
+		{expr: "ignition < 34.5", cfg: ignition, raw: 78},
+		{expr: "ignition <= 34.5", cfg: ignition, raw: 78, match: true},
+		{expr: "ignition == 34.5", cfg: ignition, raw: 78, match: true},
+		{expr: "ignition != 34.5", cfg: ignition, raw: 78},
+		{expr: "ignition != 34.5", cfg: ignition, raw: 79, match: true},
+		{expr: "ignition < 0", cfg: ignition, raw: 31, match: true},
+		// Fuel is raw*0.04; scaling noise doesn't break equality
+		{expr: "fuel == 0.12", cfg: fuel, raw: 3, match: true},
+		{expr: "fuel.raw == 3", cfg: fuel, raw: 3, match: true},
+		{expr: "fuel.raw > 3", cfg: fuel, raw: 4, match: true},
+		// raw percentages are of the type's ceiling: 90% of 255 is 229.5
+		{expr: "any.raw >= 90%", cfg: fuel, raw: 230, match: true},
+		{expr: "any.raw >= 90%", cfg: fuel, raw: 229},
+		{expr: "any.raw >= 90%", cfg: ignition, raw: 230, match: true},
+		// value percentages are of the largest value: fuel tops out at 10.2 ms
+		{expr: "fuel >= 50%", cfg: fuel, raw: 128, match: true},
+		{expr: "fuel >= 50%", cfg: fuel, raw: 127},
+		{expr: "fuel == 100%", cfg: fuel, raw: 255, match: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Match(tt.cfg, tt.cfg.ToReal(tt.raw), tt.raw); got != tt.match {
+				t.Errorf("Match(raw %d, %g %s) = %v, want %v", tt.raw, tt.cfg.ToReal(tt.raw), tt.cfg.Unit, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	fuel := models.MapConfigs[0]
+	data := make([]byte, models.M21IDProfile.ImageSize)
+	data[fuel.Offset+17] = 250
+	data[fuel.Offset+fuel.ByteSize()-1] = 250
+
+	p, err := Parse("fuel.raw == 250")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := Find(data, p)
+	if len(matches) != 2 {
+		t.Fatalf("%d matches, want 2", len(matches))
+	}
+	if m := matches[0]; m.Map != fuel.Name || m.Row != 1 || m.Col != 1 || m.Raw != 250 || m.Offset != fuel.Offset+17 || m.Value != fuel.ToReal(250) {
+		t.Errorf("first match %+v", m)
+	}
+	if m := matches[1]; m.Row != fuel.Rows-1 || m.Col != fuel.Cols-1 {
+		t.Errorf("second match at [%d,%d], want the last cell", m.Row, m.Col)
+	}
+	if got := Describe(p, matches); got != "2 cells in 1 map(s) match fuel.raw == 250" {
+		t.Errorf("Describe = %q", got)
+	}
+
+	// Maps past the end of a short dump are left out
+	p, err = Parse("any.raw >= 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := data[:fuel.Offset+fuel.ByteSize()]
+	want := map[string]int{}
+	for _, cfg := range models.MapConfigs {
+		if cfg.Offset+cfg.ByteSize() <= int64(len(short)) {
+			want[cfg.Name] = cfg.Rows * cfg.Cols
+		}
+	}
+	got := map[string]int{}
+	for _, m := range Find(short, p) {
+		got[m.Map]++
+	}
+	if !maps.Equal(got, want) || got[fuel.Name] == 0 || got[models.MapConfigs[1].Name] != 0 {
+		t.Errorf("cells matched per map %v in a dump ending after the fuel map, want %v", got, want)
+	}
+}
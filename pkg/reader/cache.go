@@ -0,0 +1,244 @@
+package reader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/internal/paths"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// NoCache disables the on-disk cache of parsed map data
+var NoCache bool
+
+// cacheFlushDelay is how long changed cache entries are collected before
+// they are written together
+const cacheFlushDelay = 2 * time.Second
+
+// cacheEntry holds the parsed maps of one version of a binary, keyed by
+// map fingerprint, and the map content hashes of MapHashCached. Axes holds
+// the XAxis and YAxis breakpoints of maps whose definition has axes.
+type cacheEntry struct {
+	Fingerprint string
+	Maps        map[string][][]float64
+	Axes        map[string][2][]float64
+	Hashes      map[string]string
+}
+
+// mapCache holds the entries used by this process by cache key. Changed
+// entries are marked dirty and written by FlushCache, at the latest
+// cacheFlushDelay after the first change.
+var mapCache struct {
+	sync.Mutex
+	entries map[string]*cacheEntry
+	dirty   map[string]bool
+	timer   *time.Timer
+}
+
+// cacheKey identifies a version of a file by the SHA-256 of its contents,
+// so any write is noticed, even one within the filesystem's timestamp
+// resolution that keeps the size. Identical images share an entry.
+func cacheKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// fileKeys holds the contents each file was last read with and their
+// cache key, by path
+var fileKeys struct {
+	sync.Mutex
+	files map[string]fileKey
+}
+
+// fileKey is a file's last contents and their cache key
+type fileKey struct {
+	data []byte
+	key  string
+}
+
+// fileCacheKey returns cacheKey(data) for contents just read from path.
+// Comparing them with the contents read last time costs much less than
+// hashing them, so reading every map of an unchanged file hashes it once.
+func fileCacheKey(path string, data []byte) string {
+	fileKeys.Lock()
+	defer fileKeys.Unlock()
+	if k, ok := fileKeys.files[path]; ok && bytes.Equal(k.data, data) {
+		return k.key
+	}
+	key := cacheKey(data)
+	if fileKeys.files == nil {
+		fileKeys.files = make(map[string]fileKey)
+	}
+	fileKeys.files[path] = fileKey{slices.Clone(data), key}
+	return key
+}
+
+// cachedEntry returns the entry of key, loading it from disk the first
+// time. An entry saved with other definitions is replaced by an empty
+// one. Once loaded, entries stay valid when definitions change, since maps
+// and hashes are keyed by their own definition. The caller holds mapCache.
+func cachedEntry(key string) *cacheEntry {
+	if entry, ok := mapCache.entries[key]; ok {
+		return entry
+	}
+	fingerprint := models.DefinitionsFingerprint()
+	entry := loadCacheEntry(key)
+	if entry == nil || entry.Fingerprint != fingerprint {
+		entry = &cacheEntry{Fingerprint: fingerprint}
+	}
+	if mapCache.entries == nil {
+		mapCache.entries = make(map[string]*cacheEntry)
+	}
+	mapCache.entries[key] = entry
+	return entry
+}
+
+// markDirty schedules the entry of key to be written with the next flush.
+// The caller holds mapCache.
+func markDirty(key string) {
+	if mapCache.dirty == nil {
+		mapCache.dirty = make(map[string]bool)
+	}
+	mapCache.dirty[key] = true
+	if mapCache.timer == nil {
+		mapCache.timer = time.AfterFunc(cacheFlushDelay, FlushCache)
+	}
+}
+
+// FlushCache writes the cache entries changed since the last flush. The
+// CLI calls it before it exits; long-running frontends rely on the flush
+// scheduled with each change.
+func FlushCache() {
+	mapCache.Lock()
+	defer mapCache.Unlock()
+	if mapCache.timer != nil {
+		mapCache.timer.Stop()
+		mapCache.timer = nil
+	}
+	for key := range mapCache.dirty {
+		saveCacheEntry(key, mapCache.entries[key])
+	}
+	clear(mapCache.dirty)
+}
+
+// cachedMap returns a copy of the cached decoding of cfg in the file
+// version of key
+func cachedMap(key string, cfg models.MapConfig) (*models.ECUMap, bool) {
+	mapCache.Lock()
+	defer mapCache.Unlock()
+
+	entry := cachedEntry(key)
+	fp := cfg.Fingerprint()
+	data, ok := entry.Maps[fp]
+	if !ok {
+		return nil, false
+	}
+	axes, ok := entry.Axes[fp]
+	if !ok && (cfg.XAxis != nil || cfg.YAxis != nil) {
+		return nil, false
+	}
+	// Callers own the maps they read, so they get copies
+	m := &models.ECUMap{Config: cfg, Data: make([][]float64, len(data)), XAxis: slices.Clone(axes[0]), YAxis: slices.Clone(axes[1])}
+	for i, row := range data {
+		m.Data[i] = slices.Clone(row)
+	}
+	return m, true
+}
+
+// storeMap caches a copy of the decoded map m in the file version of key
+func storeMap(key string, m *models.ECUMap) {
+	mapCache.Lock()
+	defer mapCache.Unlock()
+
+	entry := cachedEntry(key)
+	fp := m.Config.Fingerprint()
+	data := make([][]float64, len(m.Data))
+	for i, row := range m.Data {
+		data[i] = slices.Clone(row)
+	}
+	if entry.Maps == nil {
+		entry.Maps = make(map[string][][]float64)
+	}
+	entry.Maps[fp] = data
+	if m.Config.XAxis != nil || m.Config.YAxis != nil {
+		if entry.Axes == nil {
+			entry.Axes = make(map[string][2][]float64)
+		}
+		entry.Axes[fp] = [2][]float64{slices.Clone(m.XAxis), slices.Clone(m.YAxis)}
+	}
+	markDirty(key)
+}
+
+// ReadMapCached reads a map like ReadMap, but consults the cache of parsed
+// maps first, keyed by the contents read. Entries saved with other
+// definitions are discarded.
+func ReadMapCached(filename string, cfg models.MapConfig) (*models.ECUMap, error) {
+	if NoCache {
+		return ReadMap(filename, cfg)
+	}
+	data, err := ReadBinary(filename)
+	if err != nil {
+		return nil, err
+	}
+	key := fileCacheKey(filename, data)
+	if m, ok := cachedMap(key, cfg); ok {
+		return m, nil
+	}
+
+	ecuMap, err := ReadMapFromBytes(data, cfg)
+	if err != nil {
+		return nil, err
+	}
+	storeMap(key, ecuMap)
+	return ecuMap, nil
+}
+
+func loadCacheEntry(key string) *cacheEntry {
+	dir, err := paths.CacheSubdir("maps")
+	if err != nil {
+		return nil
+	}
+
+	f, err := os.Open(filepath.Join(dir, key+".gob"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var entry cacheEntry
+	if err := gob.NewDecoder(f).Decode(&entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// saveCacheEntry writes the entry atomically; failures are ignored since
+// the cache is only an optimization
+func saveCacheEntry(key string, entry *cacheEntry) {
+	dir, err := paths.CacheSubdir("maps")
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	if err := gob.NewEncoder(tmp).Encode(entry); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, key+".gob")); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
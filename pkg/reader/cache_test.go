@@ -0,0 +1,215 @@
+package reader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// withCache points the cache at a temporary directory and starts with no
+// entries in memory, as a new process would
+func withCache(tb testing.TB) {
+	tb.Helper()
+	tb.Setenv("MOTRONIC_CONFIG_DIR", tb.TempDir())
+	forgetCache()
+	tb.Cleanup(forgetCache)
+}
+
+// forgetCache drops the in-memory entries without writing them, and the
+// contents remembered for fileCacheKey
+func forgetCache() {
+	mapCache.Lock()
+	defer mapCache.Unlock()
+	if mapCache.timer != nil {
+		mapCache.timer.Stop()
+		mapCache.timer = nil
+	}
+	mapCache.entries, mapCache.dirty = nil, nil
+	fileKeys.Lock()
+	fileKeys.files = nil
+	fileKeys.Unlock()
+}
+
+// writeImages writes n synthetic images to dir, each with a different
+// first fuel cell, and returns their paths
+func writeImages(tb testing.TB, dir string, n int) []string {
+	tb.Helper()
+	base := testbin.Image()
+	fuel := models.MapConfigs[0]
+	files := make([]string, n)
+	for i := range files {
+		data := slices.Clone(base)
+		data[fuel.Offset] = byte(i)
+		files[i] = filepath.Join(dir, fmt.Sprintf("ecu%02d.bin", i))
+		if err := os.WriteFile(files[i], data, 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return files
+}
+
+func TestReadMapCached(t *testing.T) {
+	withCache(t)
+	file := writeImages(t, t.TempDir(), 1)[0]
+	cfg := models.MapConfigs[0]
+
+	want, err := ReadMap(file, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := ReadMapCached(file, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Callers own what they read; changing it must not reach the cache
+	first.Data[0][0] = -1
+
+	FlushCache()
+	forgetCache()
+	cached, err := ReadMapCached(file, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Data[0][0] != want.Data[0][0] {
+		t.Errorf("cached cell %g, want %g", cached.Data[0][0], want.Data[0][0])
+	}
+	if mapCache.entries[cacheKeyOf(t, file)].Maps == nil {
+		t.Error("the flushed entry was not loaded from disk")
+	}
+
+	// A new version of the file has its own entry, even with the size and
+	// modification time of the old one
+	sameSizeWrite(t, file, func(data []byte) { data[cfg.Offset]++ })
+	changed, err := ReadMapCached(file, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Data[0][0] == want.Data[0][0] {
+		t.Error("a changed file read the cached map of its old version")
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	withCache(t)
+	NoCache = true
+	t.Cleanup(func() { NoCache = false })
+	file := writeImages(t, t.TempDir(), 1)[0]
+
+	f, err := OpenECUFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.ReadAllMaps(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadMapCached(file, models.MapConfigs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MapHashCached(file, models.MapConfigs[0], 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(mapCache.entries) > 0 {
+		t.Errorf("-no-cache still cached %d file(s)", len(mapCache.entries))
+	}
+}
+
+// cacheKeyOf returns the cache key of a file as it is now
+func cacheKeyOf(tb testing.TB, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return cacheKey(data)
+}
+
+// sameSizeWrite changes the file's contents with change and puts its
+// modification time back, as a write within the filesystem's timestamp
+// resolution leaves it
+func sameSizeWrite(tb testing.TB, path string, change func([]byte)) {
+	tb.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	change(data)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// benchmarkFolder reads every map of a 50-file folder per iteration, as
+// the web server's multi-file mode and compare do. With the cache, an
+// earlier run has written the entries; fresh starts each iteration with
+// none in memory, as a new process would. A nil read opens an ECUFile per
+// file and reads all its maps.
+func benchmarkFolder(b *testing.B, read func(string, models.MapConfig) (*models.ECUMap, error), fresh bool) {
+	withCache(b)
+	files := writeImages(b, b.TempDir(), 50)
+	readAll := func() {
+		for _, file := range files {
+			if read == nil {
+				f, err := OpenECUFile(file)
+				if err == nil {
+					_, err = f.ReadAllMaps()
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+				continue
+			}
+			for _, cfg := range models.MapConfigs {
+				if _, err := read(file, cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	readAll()
+	FlushCache()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if fresh {
+			b.StopTimer()
+			forgetCache()
+			b.StartTimer()
+		}
+		readAll()
+	}
+}
+
+// BenchmarkFolderReadMap reads the folder without the cache
+func BenchmarkFolderReadMap(b *testing.B) {
+	benchmarkFolder(b, ReadMap, false)
+}
+
+// BenchmarkFolderCachedFromDisk reads the folder from the entries an
+// earlier process wrote
+func BenchmarkFolderCachedFromDisk(b *testing.B) {
+	benchmarkFolder(b, ReadMapCached, true)
+}
+
+// BenchmarkFolderCachedInMemory reads the folder again in the same
+// process, as the web server does on each page load
+func BenchmarkFolderCachedInMemory(b *testing.B) {
+	benchmarkFolder(b, ReadMapCached, false)
+}
+
+// BenchmarkFolderECUFile opens each file of the folder once and reads all
+// its maps from the cache, as the web server and GUI do: the contents are
+// hashed once per file rather than once per map
+func BenchmarkFolderECUFile(b *testing.B) {
+	benchmarkFolder(b, nil, false)
+}
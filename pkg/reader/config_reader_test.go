@@ -0,0 +1,61 @@
+package reader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// Negative int8 and int16 parameters of a fixture image read as negative
+// values from the file, the image and an ECUFile, and a parameter past the
+// end gets an error instead of a value
+func TestSignedConfigParams(t *testing.T) {
+	data := testbin.Image()
+	saved := models.ConfigParams
+	t.Cleanup(func() { models.ConfigParams = saved })
+	models.ConfigParams = []models.ConfigParam{
+		{Name: "Idle Retard", Offset: 0x5000, DataType: "int8", Scale: 0.5, Unit: "deg", MinValue: -64, MaxValue: 63.5},
+		{Name: "Min Retard", Offset: 0x5001, DataType: "int8", Scale: 1, Unit: "deg", MinValue: -128, MaxValue: 127},
+		{Name: "Trim", Offset: 0x5002, DataType: "int16", Scale: 0.1, Unit: "%", MinValue: -3276.8, MaxValue: 3276.7},
+		{Name: "Trim BE", Offset: 0x5004, DataType: "int16", Scale: 1, Offset2: 100, Unit: "rpm", MinValue: -32668, MaxValue: 32867, Endianness: models.BigEndian},
+		{Name: "Beyond", Offset: 0x8000, DataType: "int16", Scale: 1},
+	}
+	want := map[string]float64{"Idle Retard": -5, "Min Retard": -128, "Trim": -30, "Trim BE": 98}
+
+	copy(data[0x5000:], []byte{
+		0xF6,       // int8 -10
+		0x80,       // int8 -128
+		0xD4, 0xFE, // int16 LE -300
+		0xFF, 0xFE, // int16 BE -2
+	})
+	file := filepath.Join(t.TempDir(), "signed.bin")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fromFile, err := ReadConfigParams(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromECUFile := NewECUFile(file, data, time.Now()).ReadConfigParams()
+	for source, config := range map[string]*models.ECUConfig{
+		"file": fromFile, "image": ReadConfigParamsFromBytes(data), "ECUFile": fromECUFile,
+	} {
+		for name, value := range want {
+			if got, ok := config.Values[name]; !ok || got != value {
+				t.Errorf("%s: %s reads %g (present %v), want %g", source, name, got, ok, value)
+			}
+		}
+		if _, ok := config.Values["Beyond"]; ok || !errors.Is(config.Errors["Beyond"], ErrOutOfRange) {
+			t.Errorf("%s: the parameter past the end has error %v", source, config.Errors["Beyond"])
+		}
+		if len(config.Errors) != 1 {
+			t.Errorf("%s: errors %v", source, config.Errors)
+		}
+	}
+}
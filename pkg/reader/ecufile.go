@@ -0,0 +1,182 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// ECUFile is an ECU image read into memory once. Maps and parameters are
+// decoded from the loaded bytes, so reading all of them opens the file a
+// single time. An ECUFile never changes after OpenECUFile, which makes it
+// safe for concurrent readers; a file changed on disk is picked up by
+// opening it again (see ECUFiles). Maps of a file opened from disk go
+// through the cache of parsed maps (see ReadMapCached).
+type ECUFile struct {
+	path    string
+	data    []byte
+	modTime time.Time
+	layout  Layout
+	// cacheKey is the key of the contents in the cache of parsed maps,
+	// empty for a session's buffers or with NoCache
+	cacheKey string
+}
+
+// OpenECUFile reads the ECU image at path after checking its size
+func OpenECUFile(path string) (*ECUFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ReadBinary(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &ECUFile{path: path, data: data, modTime: info.ModTime(), layout: DetectLayout(data)}
+	if !NoCache {
+		f.cacheKey = cacheKey(data)
+	}
+	return f, nil
+}
+
+// NewECUFile wraps contents of the image at path that are already in
+// memory, such as an editing session's, read or written at modTime. data
+// must not be modified afterwards.
+func NewECUFile(path string, data []byte, modTime time.Time) *ECUFile {
+	return &ECUFile{path: path, data: data, modTime: modTime, layout: DetectLayout(data)}
+}
+
+// Path returns the path the file was opened from
+func (f *ECUFile) Path() string { return f.path }
+
+// Size returns the size of the image in bytes
+func (f *ECUFile) Size() int64 { return int64(len(f.data)) }
+
+// Layout returns where the calibration image sits in the file. ReadMap
+// and ReadConfigParam take definitions as given, moved with LocateMap and
+// LocateParam of the layout; ReadAllMaps and ReadConfigParams move the
+// active ones themselves.
+func (f *ECUFile) Layout() Layout { return f.layout }
+
+// Bytes returns the contents of the image. The slice is shared by all
+// readers of the file and must not be modified.
+func (f *ECUFile) Bytes() []byte { return f.data }
+
+// Stale reports whether the file on disk has changed, or is gone, since it
+// was opened
+func (f *ECUFile) Stale() bool {
+	info, err := os.Stat(f.path)
+	return err != nil || !info.ModTime().Equal(f.modTime) || info.Size() != f.Size()
+}
+
+// CheckMap returns an ErrOutOfRange error if the map, or one of its axes,
+// does not lie entirely inside the image
+func (f *ECUFile) CheckMap(cfg models.MapConfig) error {
+	if err := CheckBounds(fmt.Sprintf("map %q", cfg.Name), cfg.Offset, cfg.ByteSize(), f.Size()); err != nil {
+		return err
+	}
+	axes := []struct {
+		label string
+		axis  *models.AxisConfig
+	}{{"RPM axis", cfg.XAxis}, {"load axis", cfg.YAxis}}
+	for _, a := range axes {
+		if a.axis == nil {
+			continue
+		}
+		if err := CheckBounds(fmt.Sprintf("map %q %s", cfg.Name, a.label), a.axis.Offset, a.axis.ByteSize(), f.Size()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadMap decodes a map from the image, or takes it from the cache of
+// parsed maps
+func (f *ECUFile) ReadMap(cfg models.MapConfig) (*models.ECUMap, error) {
+	if err := f.CheckMap(cfg); err != nil {
+		return nil, err
+	}
+	if f.cacheKey == "" {
+		return ReadMapFromBytes(f.data, cfg)
+	}
+	if m, ok := cachedMap(f.cacheKey, cfg); ok {
+		return m, nil
+	}
+	m, err := ReadMapFromBytes(f.data, cfg)
+	if err != nil {
+		return nil, err
+	}
+	storeMap(f.cacheKey, m)
+	return m, nil
+}
+
+// ReadAllMaps decodes every map of models.MapConfigs, moved to the image,
+// in that order. A map
+// that can't be read is nil in the result and its error is joined into the
+// returned error, so one bad definition doesn't hide the other maps.
+func (f *ECUFile) ReadAllMaps() ([]*models.ECUMap, error) {
+	maps := make([]*models.ECUMap, len(models.MapConfigs))
+	var errs []error
+	for i, cfg := range models.MapConfigs {
+		m, err := f.ReadMap(f.layout.LocateMap(cfg))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		maps[i] = m
+	}
+	return maps, errors.Join(errs...)
+}
+
+// ReadConfigParam decodes one configuration parameter from the image
+func (f *ECUFile) ReadConfigParam(param models.ConfigParam) (float64, error) {
+	return ReadConfigParamFromBytes(f.data, param)
+}
+
+// ReadConfigParams decodes all configuration parameters from the image.
+// Parameters that can't be read are listed in Errors.
+func (f *ECUFile) ReadConfigParams() *models.ECUConfig {
+	return ReadParamsFromBytes(f.data, f.layout.LocateParams(models.ConfigParams))
+}
+
+// ECUFiles keeps open ECUFiles by path for long-running frontends, so
+// that a page load or redraw reads each image once. The zero value is
+// ready to use and safe for concurrent use.
+type ECUFiles struct {
+	mu    sync.Mutex
+	files map[string]*ECUFile
+}
+
+// Open returns the cached ECUFile of path, opening it again if the file's
+// modification time or size has changed since it was read
+func (c *ECUFiles) Open(path string) (*ECUFile, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, ok := c.files[path]; ok && !f.Stale() {
+		return f, nil
+	}
+	f, err := OpenECUFile(path)
+	if err != nil {
+		delete(c.files, path)
+		return nil, err
+	}
+	if c.files == nil {
+		c.files = make(map[string]*ECUFile)
+	}
+	c.files[path] = f
+	return f, nil
+}
+
+// Forget drops the cached copy of path. Writers call it after changing a
+// file, since a write within the filesystem's timestamp resolution leaves
+// the modification time as it was.
+func (c *ECUFiles) Forget(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.files, path)
+}
@@ -0,0 +1,67 @@
+package reader
+
+import (
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+func TestECUFileUsesCache(t *testing.T) {
+	withCache(t)
+	file := writeImages(t, t.TempDir(), 1)[0]
+
+	f, err := OpenECUFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.ReadAllMaps(); err != nil {
+		t.Fatal(err)
+	}
+	entry := mapCache.entries[f.cacheKey]
+	if entry == nil || len(entry.Maps) != len(models.MapConfigs) {
+		t.Fatalf("ReadAllMaps cached %v, want every map", entry)
+	}
+	if !mapCache.dirty[f.cacheKey] {
+		t.Error("the new entry is not waiting to be written")
+	}
+
+	// ReadMapCached on the path finds what the ECUFile parsed
+	delete(entry.Maps, models.MapConfigs[1].Fingerprint())
+	entry.Maps[models.MapConfigs[0].Fingerprint()][0][0] = 42
+	m, err := ReadMapCached(file, models.MapConfigs[0])
+	if err != nil || m.Data[0][0] != 42 {
+		t.Errorf("ReadMapCached = %v, %v; want the ECUFile's cached map", m, err)
+	}
+}
+
+// A file rewritten with the same size and modification time reads its new
+// contents once ECUFiles forgets it, not the maps cached for the old ones
+func TestECUFilesForgetSameSizeWrite(t *testing.T) {
+	withCache(t)
+	file := writeImages(t, t.TempDir(), 1)[0]
+	fuel := models.MapConfigs[0]
+
+	var files ECUFiles
+	f, err := files.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := f.ReadMap(fuel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sameSizeWrite(t, file, func(data []byte) { data[fuel.Offset] = 4 })
+	files.Forget(file)
+	if f, err = files.Open(file); err != nil {
+		t.Fatal(err)
+	}
+	after, err := f.ReadMap(fuel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := ReadMap(file, fuel)
+	if after.Data[0][0] != want.Data[0][0] || after.Data[0][0] == before.Data[0][0] {
+		t.Errorf("after the write the first cell reads %g, want %g (was %g)", after.Data[0][0], want.Data[0][0], before.Data[0][0])
+	}
+}
@@ -0,0 +1,79 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds returned by reader and editor. Test for them with errors.Is;
+// the messages carry the details.
+var (
+	// ErrNotFound reports an unknown map or parameter name
+	ErrNotFound = errors.New("not found")
+	// ErrOutOfRange reports an offset, cell or region outside the image
+	ErrOutOfRange = errors.New("offset out of range")
+	// ErrValueOutOfBounds reports a value outside its allowed range, one
+	// that cannot be stored in the data type, or one that breaks a link
+	// between parameters
+	ErrValueOutOfBounds = errors.New("value out of bounds")
+	// ErrUnsupportedDataType reports a definition with an unknown data type
+	ErrUnsupportedDataType = errors.New("unsupported data type")
+	// ErrInvalidDefinition reports a definition that cannot be decoded,
+	// such as one with a zero scale
+	ErrInvalidDefinition = errors.New("invalid definition")
+	// ErrMapLocked reports an image that another program holds open
+	ErrMapLocked = errors.New("file is locked by another program")
+	// ErrReadOnly reports an image that cannot be written
+	ErrReadOnly = errors.New("file is read-only")
+	// ErrLinkedMismatch reports a lock-step edit of cells that already
+	// differ between the two linked files
+	ErrLinkedMismatch = errors.New("linked files differ")
+	// ErrLikelyCode reports an edit of an unconfirmed map whose bytes look
+	// like program code, made without the user's typed agreement
+	ErrLikelyCode = errors.New("map looks like program code")
+	// ErrBackupUnverified reports a write stopped by RequireBackup because
+	// the backup of the file could not be read back intact
+	ErrBackupUnverified = errors.New("backup not verified")
+	// ErrOverlap reports a new definition on bytes another one uses: an
+	// exact duplicate, or a partial overlap the user hasn't confirmed
+	ErrOverlap = errors.New("overlapping definition")
+)
+
+// kindError is a descriptive message classified by one of the error kinds
+type kindError struct {
+	kind error
+	msg  string
+}
+
+// Error implements error
+func (e *kindError) Error() string { return e.msg }
+
+// Unwrap lets errors.Is match the kind
+func (e *kindError) Unwrap() error { return e.kind }
+
+// NewError formats a message classified as kind, so the message reads as
+// before while callers can test errors.Is(err, kind)
+func NewError(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// CheckBounds returns an ErrOutOfRange error naming the bytes what needs
+// and the size of the image if length bytes at offset don't lie inside an
+// image of size bytes, e.g. `map "Main Fuel Map" needs bytes
+// 0x6700-0x6780 but file is only 0x4000 bytes`. The end is exclusive.
+func CheckBounds(what string, offset, length, size int64) error {
+	if offset < 0 {
+		return NewError(ErrOutOfRange, "%s has a negative offset (-0x%X)", what, -offset)
+	}
+	if offset+length > size {
+		return NewError(ErrOutOfRange, "%s needs bytes 0x%04X-0x%04X but file is only 0x%X bytes", what, offset, offset+length, size)
+	}
+	return nil
+}
+
+// IsValidation reports whether err was caused by the requested edit
+// rather than by the file, so it can be shown next to the input instead
+// of as a failure
+func IsValidation(err error) bool {
+	return errors.Is(err, ErrValueOutOfBounds) || errors.Is(err, ErrOutOfRange)
+}
@@ -0,0 +1,82 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+var errorKinds = []error{ErrNotFound, ErrOutOfRange, ErrValueOutOfBounds, ErrUnsupportedDataType, ErrInvalidDefinition,
+	ErrMapLocked, ErrReadOnly, ErrLinkedMismatch, ErrLikelyCode, ErrBackupUnverified, ErrOverlap}
+
+// TestErrorKinds checks the kind of the errors the reader returns and that
+// each matches its own kind only
+func TestErrorKinds(t *testing.T) {
+	image := make([]byte, 0x4000)
+	fuel := models.MapConfig{Name: "Main Fuel Map", Offset: 0x3F80, Rows: 8, Cols: 16, DataType: "uint8", Scale: 0.04}
+	param := models.ConfigParam{Name: "Rev Limiter", Offset: 0x3FFF, DataType: "uint16", Scale: 1}
+	kind := func(cfg *models.MapConfig, set func()) error {
+		saved := *cfg
+		set()
+		defer func() { *cfg = saved }()
+		_, err := ReadMapFromBytes(image, *cfg)
+		return err
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		kind error
+		msg  string
+	}{
+		{"map past the end", kind(&fuel, func() { fuel.Offset = 0x3FC0 }), ErrOutOfRange,
+			`map "Main Fuel Map" needs bytes 0x3FC0-0x4040 but file is only 0x4000 bytes`},
+		{"negative offset", kind(&fuel, func() { fuel.Offset = -0x10 }), ErrOutOfRange, ""},
+		{"axis past the end", kind(&fuel, func() {
+			fuel.XAxis = &models.AxisConfig{Offset: 0x3FFC, Count: 16, DataType: "uint8", Scale: 1}
+		}), ErrOutOfRange, ""},
+		{"unknown data type", kind(&fuel, func() { fuel.DataType = "float32" }), ErrUnsupportedDataType, ""},
+		{"zero scale", kind(&fuel, func() { fuel.Scale = 0 }), ErrInvalidDefinition, ""},
+		{"parameter past the end", func() error { _, err := ReadConfigParamFromBytes(image, param); return err }(), ErrOutOfRange,
+			`parameter "Rev Limiter" needs bytes 0x3FFF-0x4001 but file is only 0x4000 bytes`},
+		{"parameter data type", func() error {
+			p := param
+			p.DataType = "float32"
+			_, err := ReadConfigParamFromBytes(image, p)
+			return err
+		}(), ErrUnsupportedDataType, "unsupported data type: float32"},
+		{"wrapped", fmt.Errorf("reading: %w", NewError(ErrNotFound, "parameter not found: Boost")), ErrNotFound,
+			"reading: parameter not found: Boost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("no error")
+			}
+			for _, k := range errorKinds {
+				if errors.Is(tt.err, k) != (k == tt.kind) {
+					t.Errorf("errors.Is(%q, %v) = %v", tt.err, k, k != tt.kind)
+				}
+			}
+			if tt.msg != "" && tt.err.Error() != tt.msg {
+				t.Errorf("message %q, want %q", tt.err, tt.msg)
+			}
+		})
+	}
+}
+
+// Edits that failed on their own values are validation errors; problems
+// with the file are not
+func TestIsValidation(t *testing.T) {
+	for _, k := range errorKinds {
+		want := k == ErrValueOutOfBounds || k == ErrOutOfRange
+		if got := IsValidation(NewError(k, "x")); got != want {
+			t.Errorf("IsValidation(%v) = %v, want %v", k, got, want)
+		}
+	}
+	if IsValidation(&WriteError{Path: "ecu.bin", Err: errors.New("busy")}) {
+		t.Error("a write error is a validation error")
+	}
+}
@@ -0,0 +1,201 @@
+package reader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// IdentifyBinary hashes the file and extracts its identification strings
+// using the Motronic M2.1 profile. The file is streamed for hashing and
+// only the profile's ID regions are read, so oversized files are hashed
+// without being loaded; their strings are not extracted.
+func IdentifyBinary(filename string) (*models.BinaryIdentity, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := HashFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxFileSize {
+		return &models.BinaryIdentity{Size: info.Size(), SHA256: hash}, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	id, err := identify(f, info.Size(), hash, models.M21IDProfile)
+	if id != nil {
+		id.Provenance = LoadProvenance(filename)
+	}
+	return id, err
+}
+
+// IdentifyData identifies binary contents using the given profile. The
+// first string found for each field fills the structured field; all
+// matches are listed in Strings.
+func IdentifyData(data []byte, profile models.IDProfile) *models.BinaryIdentity {
+	sum := sha256.Sum256(data)
+	id, _ := identify(bytes.NewReader(data), int64(len(data)), hex.EncodeToString(sum[:]), profile)
+	return id
+}
+
+// identify searches the profile's ID regions of each image the file may
+// hold. Dumps larger than the profile's image size are tried bank by bank
+// and the first bank with recognized strings sets BaseOffset; if none has
+// any, the first bank is reported.
+func identify(r io.ReaderAt, size int64, hash string, profile models.IDProfile) (*models.BinaryIdentity, error) {
+	var first *models.BinaryIdentity
+	for _, base := range imageBases(profile, size) {
+		id := &models.BinaryIdentity{Size: size, SHA256: hash, BaseOffset: base}
+
+		regions := profile.Regions
+		if len(regions) == 0 {
+			regions = []models.IDRegion{{Name: "file", Start: 0, End: size}}
+		}
+		for _, region := range regions {
+			start := base + region.Start
+			end := min(base+region.End, size)
+			if start >= end {
+				continue
+			}
+			buf := make([]byte, end-start)
+			if _, err := r.ReadAt(buf, start); err != nil {
+				return nil, err
+			}
+			extractIDStrings(id, buf, start, profile)
+		}
+
+		if id.Label() != "" {
+			return id, nil
+		}
+		if first == nil {
+			first = id
+		}
+	}
+	return first, nil
+}
+
+// imageBases returns the offsets at which a calibration image may start:
+// after the reader header if there is one (see headerSize), then every
+// image size further that still leaves a whole image in the file. With
+// BaseOffsetOverride there is only that one.
+func imageBases(profile models.IDProfile, size int64) []int64 {
+	if BaseOffsetOverride >= 0 {
+		return []int64{BaseOffsetOverride}
+	}
+	start := headerSize(profile, size)
+	bases := []int64{start}
+	if profile.ImageSize <= 0 || len(profile.Regions) == 0 {
+		return bases
+	}
+	for base := start + profile.ImageSize; base+profile.ImageSize <= size; base += profile.ImageSize {
+		bases = append(bases, base)
+	}
+	return bases
+}
+
+// extractIDStrings matches the printable runs in buf, which starts at
+// file offset base, against the profile's patterns
+func extractIDStrings(id *models.BinaryIdentity, buf []byte, base int64, profile models.IDProfile) {
+	patterns := make([]*regexp.Regexp, len(profile.Patterns))
+	for i, p := range profile.Patterns {
+		patterns[i] = regexp.MustCompile(p.Pattern)
+	}
+
+	for _, run := range printableRuns(buf, profile.MinRun) {
+		value := strings.TrimSpace(string(run.text))
+		for i, re := range patterns {
+			if !re.MatchString(value) {
+				continue
+			}
+			field := profile.Patterns[i].Field
+			id.Strings = append(id.Strings, models.IDString{
+				Field:  field,
+				Value:  value,
+				Offset: base + run.offset,
+			})
+			setIDField(id, field, value)
+			break
+		}
+	}
+}
+
+// setIDField fills a structured field unless it is already set
+func setIDField(id *models.BinaryIdentity, field, value string) {
+	var target *string
+	switch field {
+	case models.IDFieldBoschNumber:
+		target = &id.BoschNumber
+	case models.IDFieldPartNumber:
+		target = &id.PartNumber
+	case models.IDFieldSoftwareVersion:
+		target = &id.SoftwareVersion
+	default:
+		return
+	}
+	if *target == "" {
+		*target = value
+	}
+}
+
+type asciiRun struct {
+	offset int64
+	text   []byte
+}
+
+// printableRuns returns runs of printable ASCII at least minLen long
+func printableRuns(data []byte, minLen int) []asciiRun {
+	var runs []asciiRun
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] >= 0x20 && data[i] < 0x7F {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minLen {
+			runs = append(runs, asciiRun{offset: int64(start), text: data[start:i]})
+		}
+		start = -1
+	}
+	return runs
+}
+
+// IdentifyECU returns the part number and software version of a binary.
+// The profile's ID regions are searched first; when they hold neither,
+// the whole file is scanned for strings of the same shape, since some
+// variants keep their ID block elsewhere. Fields that are still not found
+// are left empty rather than guessed.
+func IdentifyECU(filename string) (models.ECUVersion, error) {
+	id, err := IdentifyBinary(filename)
+	if err != nil {
+		return models.ECUVersion{}, err
+	}
+	v := models.ECUVersion{PartNumber: id.PartNumber, SoftwareVersion: id.SoftwareVersion}
+	if v.Known() || id.Size > MaxFileSize {
+		return v, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return v, err
+	}
+	profile := models.M21IDProfile
+	profile.Regions = nil
+	id = IdentifyData(data, profile)
+	return models.ECUVersion{PartNumber: id.PartNumber, SoftwareVersion: id.SoftwareVersion}, nil
+}
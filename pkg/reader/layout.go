@@ -0,0 +1,163 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"slices"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// HeaderSizes are the lengths of the headers some EPROM readers write
+// before the image
+var HeaderSizes = []int64{16, 512}
+
+// BaseOffsetOverride is the base offset of every binary in place of the
+// detected one (-base-offset). Negative detects it.
+var BaseOffsetOverride int64 = -1
+
+// Layout says where the calibration image sits in a dump
+type Layout struct {
+	// Header is the length of a reader header before the first image
+	Header int64
+	// BaseOffset is the file offset of the image the map definitions
+	// address: the header plus the bank the image was found in
+	BaseOffset int64
+	// Copies is how often the image is repeated after the header: more
+	// than 1 for a mirrored dump, such as a 27C256 image read as a 27C512
+	Copies int
+	// CopySize is the length of each copy of a mirrored dump
+	CopySize int64
+	// Reason explains a layout other than a plain image, "" for one
+	Reason string
+}
+
+// DetectLayout finds the image in the contents of a dump. A size that
+// is a known header length past a multiple of the image size has a
+// header; a dump holding more than one image uses the bank whose
+// identification strings are recognized (see IdentifyData), and one
+// whose images are all equal is mirrored. BaseOffsetOverride replaces the
+// detection of the base offset.
+func DetectLayout(data []byte) Layout {
+	profile := models.M21IDProfile
+	size := int64(len(data))
+	l := Layout{BaseOffset: IdentifyData(data, profile).BaseOffset, Copies: 1}
+	l.Header = headerSize(profile, size)
+	if n := copies(data[l.Header:], profile.ImageSize); n > 1 {
+		l.Copies, l.CopySize = n, profile.ImageSize
+	}
+
+	var reasons []string
+	switch {
+	case BaseOffsetOverride >= 0:
+		reasons = append(reasons, "set with -base-offset")
+	case l.Header > 0:
+		reasons = append(reasons, fmt.Sprintf("%d-byte header before the image", l.Header))
+	case !isPowerOfTwo(size):
+		reasons = append(reasons, fmt.Sprintf("%d bytes is not a power of two and matches no known header; offsets are used as they are", size))
+	}
+	if bank := l.BaseOffset - l.Header; bank > 0 && BaseOffsetOverride < 0 {
+		reasons = append(reasons, fmt.Sprintf("identification found in the image at +0x%X", bank))
+	}
+	if l.Mirrored() {
+		reasons = append(reasons, fmt.Sprintf("mirrored, the image repeated %d times", l.Copies))
+	}
+	l.Reason = strings.Join(reasons, ", ")
+	return l
+}
+
+// copies returns how many equal images of imageSize bytes data holds, 1
+// unless it is two or more of them and nothing else
+func copies(data []byte, imageSize int64) int {
+	size := int64(len(data))
+	if imageSize <= 0 || size < 2*imageSize || size%imageSize != 0 {
+		return 1
+	}
+	first := data[:imageSize]
+	for off := imageSize; off < size; off += imageSize {
+		if !bytes.Equal(first, data[off:off+imageSize]) {
+			return 1
+		}
+	}
+	return int(size / imageSize)
+}
+
+// Mirrored reports whether the dump holds the image more than once
+func (l Layout) Mirrored() bool { return l.Copies > 1 }
+
+// Extent returns how many of the size bytes of the dump hold distinct
+// contents: all of them, or the header and first copy of a mirrored dump.
+// Comparing files up to their extent treats a mirrored dump like the image
+// it repeats.
+func (l Layout) Extent(size int64) int64 {
+	if !l.Mirrored() {
+		return size
+	}
+	return l.Header + l.CopySize
+}
+
+// MirrorOffsets returns where the size bytes at offset are repeated in the
+// other copies of a mirrored dump: none unless they lie in the first copy
+func (l Layout) MirrorOffsets(offset, size int64) []int64 {
+	rel := offset - l.Header
+	if !l.Mirrored() || rel < 0 || rel+size > l.CopySize {
+		return nil
+	}
+	offsets := make([]int64, 0, l.Copies-1)
+	for i := 1; i < l.Copies; i++ {
+		offsets = append(offsets, offset+int64(i)*l.CopySize)
+	}
+	return offsets
+}
+
+// FileLayout reads filename and detects its layout with DetectLayout
+func FileLayout(filename string) (Layout, error) {
+	data, err := ReadBinary(filename)
+	if err != nil {
+		return Layout{}, err
+	}
+	return DetectLayout(data), nil
+}
+
+// headerSize returns the length of the header before the first image:
+// the remainder of size over the image size when it is one of
+// HeaderSizes, otherwise 0
+func headerSize(profile models.IDProfile, size int64) int64 {
+	if profile.ImageSize <= 0 || isPowerOfTwo(size) {
+		return 0
+	}
+	if rem := size % profile.ImageSize; rem < size && slices.Contains(HeaderSizes, rem) {
+		return rem
+	}
+	return 0
+}
+
+// isPowerOfTwo reports whether n is a power of two
+func isPowerOfTwo(n int64) bool {
+	return n > 0 && bits.OnesCount64(uint64(n)) == 1
+}
+
+// LocateMap returns cfg, one of the active definitions, moved to the image
+// of a file with layout l
+func (l Layout) LocateMap(cfg models.MapConfig) models.MapConfig {
+	return cfg.Relocate(l.BaseOffset - models.BaseOffset)
+}
+
+// LocateParam returns p, one of the active definitions, moved to the image
+// of a file with layout l
+func (l Layout) LocateParam(p models.ConfigParam) models.ConfigParam {
+	p.Offset += l.BaseOffset - models.BaseOffset
+	return p
+}
+
+// LocateParams returns a copy of params, active definitions, moved to the
+// image of a file with layout l
+func (l Layout) LocateParams(params []models.ConfigParam) []models.ConfigParam {
+	located := make([]models.ConfigParam, len(params))
+	for i, p := range params {
+		located[i] = l.LocateParam(p)
+	}
+	return located
+}
@@ -0,0 +1,148 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// dump joins parts into the contents of a dump
+func dump(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestDetectLayout(t *testing.T) {
+	image := testbin.Image()
+	size := int64(len(image))
+	erased := bytes.Repeat([]byte{0xFF}, len(image))
+	blank := make([]byte, len(image))
+	// Another tune of the same ECU, so the banks aren't mirrored
+	other := slices.Clone(image)
+	other[models.MapConfigs[0].Offset]++
+
+	tests := []struct {
+		name   string
+		data   []byte
+		header int64
+		base   int64
+		reason string
+	}{
+		{name: "plain image", data: image},
+		{name: "16-byte header", data: dump(make([]byte, 16), image), header: 16, base: 16, reason: "16-byte header before the image"},
+		{name: "512-byte header", data: dump(make([]byte, 512), image), header: 512, base: 512, reason: "512-byte header before the image"},
+		{name: "image at 0x8000 of 64 KB", data: dump(erased, image), base: size, reason: "identification found in the image at +0x8000"},
+		{name: "header and upper bank", data: dump(make([]byte, 512), erased, image), header: 512, base: 512 + size,
+			reason: "512-byte header before the image, identification found in the image at +0x8000"},
+		// Both banks identify: the first one is used
+		{name: "two tunes", data: dump(image, other)},
+		// Nothing identifies: offsets are used as they are
+		{name: "no identification", data: dump(erased, blank)},
+		{name: "unknown header", data: dump(make([]byte, 100), image),
+			reason: "32868 bytes is not a power of two and matches no known header; offsets are used as they are"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := DetectLayout(tt.data)
+			want := Layout{Header: tt.header, BaseOffset: tt.base, Copies: 1, Reason: tt.reason}
+			if !reflect.DeepEqual(l, want) {
+				t.Errorf("DetectLayout = %+v, want %+v", l, want)
+			}
+		})
+	}
+}
+
+// The definitions read the same map from a plain image and from a dump
+// holding it behind a header in its upper bank
+func TestLocateMap(t *testing.T) {
+	image := testbin.Image()
+	padded := dump(make([]byte, 16), bytes.Repeat([]byte{0xFF}, len(image)), image)
+	l := DetectLayout(padded)
+	for _, cfg := range models.MapConfigs {
+		want, err := ReadMapFromBytes(image, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadMapFromBytes(padded, l.LocateMap(cfg))
+		if err != nil {
+			t.Fatalf("%s: %v", cfg.Name, err)
+		}
+		if !reflect.DeepEqual(got.Data, want.Data) {
+			t.Errorf("%s reads differently from the padded dump", cfg.Name)
+		}
+	}
+	params := l.LocateParams(models.ConfigParams)
+	if want, got := ReadParamsFromBytes(image, models.ConfigParams), ReadParamsFromBytes(padded, params); !reflect.DeepEqual(got.Values, want.Values) {
+		t.Errorf("parameters %v from the padded dump, want %v", got.Values, want.Values)
+	}
+}
+
+// -base-offset replaces the detected base offset, in the layout and the
+// identification alike
+func TestBaseOffsetOverride(t *testing.T) {
+	t.Cleanup(func() { BaseOffsetOverride = -1 })
+	BaseOffsetOverride = 0x8000
+	data := dump(testbin.Image(), testbin.Image()[:0x7000], make([]byte, 0x1000))
+	l := DetectLayout(data)
+	if l.BaseOffset != 0x8000 || !strings.HasPrefix(l.Reason, "set with -base-offset") {
+		t.Errorf("layout %+v, want base 0x8000 set with -base-offset", l)
+	}
+}
+
+func TestMirroredLayout(t *testing.T) {
+	image := testbin.Image()
+	size := int64(len(image))
+	tests := []struct {
+		name   string
+		data   []byte
+		header int64
+		copies int
+	}{
+		{name: "27C256 read as 27C512", data: bytes.Repeat(image, 2), copies: 2},
+		{name: "27C256 read as 27C010", data: bytes.Repeat(image, 4), copies: 4},
+		{name: "behind a header", data: dump(make([]byte, 512), image, image), header: 512, copies: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := DetectLayout(tt.data)
+			if l.Copies != tt.copies || l.CopySize != size || l.Header != tt.header || l.BaseOffset != tt.header {
+				t.Fatalf("DetectLayout = %+v, want %d copies of 0x%X bytes after %d", l, tt.copies, size, tt.header)
+			}
+			if reason := fmt.Sprintf("mirrored, the image repeated %d times", tt.copies); !strings.HasSuffix(l.Reason, reason) {
+				t.Errorf("reason %q, want %q", l.Reason, reason)
+			}
+			if got := l.Extent(int64(len(tt.data))); got != tt.header+size {
+				t.Errorf("extent 0x%X, want 0x%X", got, tt.header+size)
+			}
+			offset := tt.header + 0x100
+			offsets := l.MirrorOffsets(offset, 2)
+			if len(offsets) != tt.copies-1 {
+				t.Fatalf("mirror offsets %X, want %d", offsets, tt.copies-1)
+			}
+			for i, o := range offsets {
+				if o != offset+int64(i+1)*size {
+					t.Errorf("copy %d at 0x%X, want 0x%X", i+1, o, offset+int64(i+1)*size)
+				}
+			}
+			// Only bytes wholly inside the first copy are mirrored
+			if got := l.MirrorOffsets(tt.header+size-1, 2); got != nil {
+				t.Errorf("bytes crossing the copy are mirrored at %X", got)
+			}
+			if got := l.MirrorOffsets(tt.header+size, 1); got != nil {
+				t.Errorf("the second copy is mirrored at %X", got)
+			}
+		})
+	}
+
+	// One changed byte and the banks are two images
+	changed := bytes.Repeat(image, 2)
+	changed[len(changed)-1]++
+	if l := DetectLayout(changed); l.Mirrored() || l.MirrorOffsets(0x100, 1) != nil || l.Extent(2*size) != 2*size {
+		t.Errorf("different banks detected as %+v", l)
+	}
+}
@@ -0,0 +1,108 @@
+package reader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxFileSize is the largest file accepted as an ECU image. M2.1
+// EPROMs are 32KB; the limit leaves room for other families and padded
+// dumps while rejecting disk images and archives.
+const DefaultMaxFileSize int64 = 4 << 20
+
+// MaxFileSize is the active size limit, configurable with -max-file-size
+var MaxFileSize = DefaultMaxFileSize
+
+// FileTooLargeError reports a file that exceeds MaxFileSize
+type FileTooLargeError struct {
+	Size  int64
+	Limit int64
+}
+
+// Error implements error
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("file too large to be an ECU image (size %s, limit %s)", FormatSize(e.Size), FormatSize(e.Limit))
+}
+
+// CheckFileSize returns a FileTooLargeError if the file exceeds MaxFileSize
+func CheckFileSize(filename string) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", filename)
+	}
+	if info.Size() > MaxFileSize {
+		return &FileTooLargeError{Size: info.Size(), Limit: MaxFileSize}
+	}
+	return nil
+}
+
+// ReadBinary reads a whole ECU image after checking its size
+func ReadBinary(filename string) ([]byte, error) {
+	if err := CheckFileSize(filename); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filename)
+}
+
+// HashFile returns the hex SHA-256 of a file, streaming its contents so
+// that even oversized files are never loaded into memory
+func HashFile(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ParseSize parses a byte count with an optional K, M or G suffix
+// (e.g. "512K", "4MB", "1MiB")
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(strings.TrimSuffix(str, "IB"), "B")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(str, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(str, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(str, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		str = str[:len(str)-1]
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// FormatSize renders a byte count in human-readable binary units
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,113 @@
+package reader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// sparseFile creates a file of size bytes that takes no space on disk
+func sparseFile(tb testing.TB, size int64) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "disk.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(size); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func TestOversizedFile(t *testing.T) {
+	const size = 2 << 30
+	path := sparseFile(t, size)
+
+	checks := map[string]func() error{
+		"CheckFileSize": func() error { return CheckFileSize(path) },
+		"ReadBinary":    func() error { _, err := ReadBinary(path); return err },
+		"OpenECUFile":   func() error { _, err := OpenECUFile(path); return err },
+		"ReadMap":       func() error { _, err := ReadMap(path, models.MapConfigs[0]); return err },
+		"ReadMapCached": func() error { _, err := ReadMapCached(path, models.MapConfigs[0]); return err },
+	}
+	for name, check := range checks {
+		err := check()
+		var tooLarge *FileTooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("%s: %v, want a FileTooLargeError", name, err)
+			continue
+		}
+		if tooLarge.Size != size || tooLarge.Limit != MaxFileSize {
+			t.Errorf("%s: size %d, limit %d; want %d, %d", name, tooLarge.Size, tooLarge.Limit, int64(size), MaxFileSize)
+		}
+	}
+
+	want := "file too large to be an ECU image (size 2.0 GiB, limit 4.0 MiB)"
+	if err := CheckFileSize(path); err == nil || err.Error() != want {
+		t.Errorf("message %q, want %q", err, want)
+	}
+}
+
+// Identification of a file over the limit hashes it as a stream
+func TestIdentifyOversizedFile(t *testing.T) {
+	size := MaxFileSize + 1
+	path := sparseFile(t, size)
+
+	id, err := IdentifyBinary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(make([]byte, size))
+	if id.Size != size || id.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("identified as %d bytes, %s; want %d bytes, %x", id.Size, id.SHA256, size, sum)
+	}
+	if id.PartNumber != "" || len(id.Strings) != 0 {
+		t.Errorf("strings were extracted from an oversized file: %+v", id)
+	}
+}
+
+func TestMaxFileSizeSetting(t *testing.T) {
+	saved := MaxFileSize
+	t.Cleanup(func() { MaxFileSize = saved })
+
+	path := sparseFile(t, 64<<10)
+	MaxFileSize = 32 << 10
+	if err := CheckFileSize(path); err == nil || !strings.Contains(err.Error(), "limit 32.0 KiB") {
+		t.Errorf("64 KiB file under a 32 KiB limit: %v", err)
+	}
+	MaxFileSize = 64 << 10
+	if err := CheckFileSize(path); err != nil {
+		t.Errorf("a file of exactly the limit was refused: %v", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"512", 512},
+		{"512K", 512 << 10},
+		{"4MB", 4 << 20},
+		{"1MiB", 1 << 20},
+		{" 2g ", 2 << 30},
+	}
+	for _, tt := range tests {
+		if got, err := ParseSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "0", "-1K", "4X", "M"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) accepted", in)
+		}
+	}
+}
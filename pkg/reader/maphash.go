@@ -0,0 +1,69 @@
+package reader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// MapHashFromBytes hashes the raw bytes of a map in an image whose maps
+// start at base. The definition's offset, dimensions and data type are
+// part of the hash input, so hashes change when a definition moves or
+// resizes a map even if the bytes there happen to be the same. The base is
+// not, so the same tune in a bigger dump hashes the same.
+func MapHashFromBytes(data []byte, cfg models.MapConfig, base int64) (string, error) {
+	start := base + cfg.Offset
+	end := start + cfg.ByteSize()
+	if err := CheckBounds(fmt.Sprintf("map %q", cfg.Name), start, cfg.ByteSize(), int64(len(data))); err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%d:%d:%s\n", cfg.Offset, cfg.Rows, cfg.Cols, cfg.DataType)
+	h.Write(data[start:end])
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MapHashCached returns MapHashFromBytes of the file, keeping the hash in
+// the parsed-binary cache next to the decoded maps of the same contents
+func MapHashCached(filename string, cfg models.MapConfig, base int64) (string, error) {
+	data, err := ReadBinary(filename)
+	if err != nil {
+		return "", err
+	}
+	if NoCache {
+		return MapHashFromBytes(data, cfg, base)
+	}
+
+	key := fileCacheKey(filename, data)
+	hashKey := mapHashKey(cfg, base)
+
+	mapCache.Lock()
+	mapHash, ok := cachedEntry(key).Hashes[hashKey]
+	mapCache.Unlock()
+	if ok {
+		return mapHash, nil
+	}
+
+	mapHash, err = MapHashFromBytes(data, cfg, base)
+	if err != nil {
+		return "", err
+	}
+
+	mapCache.Lock()
+	defer mapCache.Unlock()
+	entry := cachedEntry(key)
+	if entry.Hashes == nil {
+		entry.Hashes = make(map[string]string)
+	}
+	entry.Hashes[hashKey] = mapHash
+	markDirty(key)
+	return mapHash, nil
+}
+
+// mapHashKey keys a map hash by definition and base offset
+func mapHashKey(cfg models.MapConfig, base int64) string {
+	return fmt.Sprintf("%s@%x", cfg.Fingerprint(), base)
+}
@@ -0,0 +1,30 @@
+package reader
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// SidecarPath returns the metadata file kept next to an ECU file
+func SidecarPath(filename string) string {
+	return filename + ".meta.json"
+}
+
+// LoadProvenance returns the provenance recorded in the file's sidecar, or
+// nil if there is no sidecar or it holds none. A missing or unreadable
+// sidecar is never an error: provenance is informational only.
+func LoadProvenance(filename string) *models.Provenance {
+	data, err := os.ReadFile(SidecarPath(filename))
+	if err != nil {
+		return nil
+	}
+	var sidecar struct {
+		Provenance *models.Provenance `json:"provenance"`
+	}
+	if json.Unmarshal(data, &sidecar) != nil {
+		return nil
+	}
+	return sidecar.Provenance
+}
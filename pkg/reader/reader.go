@@ -1,56 +1,126 @@
 package reader
 
 import (
-	"encoding/binary"
-	"io"
-	"os"
+	"fmt"
+	"slices"
 
 	"github.com/tosih/motronic-m21-tool/pkg/models"
 )
 
 // ReadMap reads a map from the binary file at the specified configuration
 func ReadMap(filename string, cfg models.MapConfig) (*models.ECUMap, error) {
-	f, err := os.Open(filename)
+	data, err := ReadBinary(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	return ReadMapFromBytes(data, cfg)
+}
+
+// ReadMapFromBytes decodes a map from the contents of an ECU image. It
+// does no file I/O, so it also works in the browser build.
+func ReadMapFromBytes(data []byte, cfg models.MapConfig) (*models.ECUMap, error) {
+	if err := cfg.CheckConversion(); err != nil {
+		return nil, NewError(ErrInvalidDefinition, "%s: %v", cfg.Name, err)
+	}
+	raw, err := ReadRawMapFromBytes(data, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]float64, cfg.Rows)
+	for i := range raw {
+		values[i] = make([]float64, cfg.Cols)
+		for j, r := range raw[i] {
+			values[i][j] = cfg.ToReal(r)
+		}
+	}
+
+	m := &models.ECUMap{
+		Config: cfg,
+		Data:   values,
+	}
+	if cfg.XAxis != nil {
+		if m.XAxis, err = readAxis(data, cfg.XAxis.InheritOrder(cfg), fmt.Sprintf("map %q RPM axis", cfg.Name)); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.YAxis != nil {
+		if m.YAxis, err = readAxis(data, cfg.YAxis.InheritOrder(cfg), fmt.Sprintf("map %q load axis", cfg.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ReadAxis reads the breakpoints of a map axis from a binary file
+func ReadAxis(filename string, axis models.AxisConfig) ([]float64, error) {
+	data, err := ReadBinary(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ReadAxisFromBytes(data, axis)
+}
+
+// ReadAxisFromBytes decodes the breakpoints of a map axis from the
+// contents of an ECU image, in stored order
+func ReadAxisFromBytes(data []byte, axis models.AxisConfig) ([]float64, error) {
+	return readAxis(data, axis, "axis")
+}
+
+// readAxis decodes an axis, naming it as label in errors
+func readAxis(data []byte, axis models.AxisConfig, label string) ([]float64, error) {
+	if axis.Labels != nil {
+		return slices.Clone(axis.Labels), nil
+	}
+	if !models.KnownDataType(axis.DataType) {
+		return nil, NewError(ErrUnsupportedDataType, "%s: unknown data type %q", label, axis.DataType)
+	}
+	if err := models.CheckScale(axis.Scale); err != nil {
+		return nil, NewError(ErrInvalidDefinition, "%s: %v", label, err)
+	}
+	if err := CheckBounds(label, axis.Offset, axis.ByteSize(), int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	size := models.DataTypeSize(axis.DataType)
+	values := make([]float64, axis.Count)
+	for i := range values {
+		values[i] = axis.ToReal(axis.DecodeRaw(data[axis.Offset+int64(i*size):]))
+	}
+	return values, nil
+}
 
-	_, err = f.Seek(cfg.Offset, io.SeekStart)
+// ReadRawMap reads the unconverted cell values of a map, as integers of
+// the map's data type in its byte order
+func ReadRawMap(filename string, cfg models.MapConfig) ([][]int64, error) {
+	data, err := ReadBinary(filename)
 	if err != nil {
 		return nil, err
 	}
+	return ReadRawMapFromBytes(data, cfg)
+}
 
-	data := make([][]float64, cfg.Rows)
+// ReadRawMapFromBytes decodes the unconverted cell values of a map from
+// the contents of an ECU image
+func ReadRawMapFromBytes(data []byte, cfg models.MapConfig) ([][]int64, error) {
+	if !models.KnownDataType(cfg.DataType) {
+		return nil, NewError(ErrUnsupportedDataType, "%s: unknown data type %q", cfg.Name, cfg.DataType)
+	}
+	if err := CheckBounds(fmt.Sprintf("map %q", cfg.Name), cfg.Offset, cfg.ByteSize(), int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	size := models.DataTypeSize(cfg.DataType)
+	raw := make([][]int64, cfg.Rows)
 	for i := 0; i < cfg.Rows; i++ {
-		data[i] = make([]float64, cfg.Cols)
+		raw[i] = make([]int64, cfg.Cols)
 		for j := 0; j < cfg.Cols; j++ {
-			var value float64
-
-			if cfg.DataType == "uint8" {
-				var rawValue uint8
-				err := binary.Read(f, binary.LittleEndian, &rawValue)
-				if err != nil {
-					return nil, err
-				}
-				value = float64(rawValue)*cfg.Scale + cfg.Offset2
-			} else {
-				var rawValue uint16
-				err := binary.Read(f, binary.LittleEndian, &rawValue)
-				if err != nil {
-					return nil, err
-				}
-				value = float64(rawValue)*cfg.Scale + cfg.Offset2
-			}
-
-			data[i][j] = value
+			pos := cfg.Offset + int64((i*cfg.Cols+j)*size)
+			raw[i][j] = cfg.DecodeRaw(data[pos:])
 		}
 	}
 
-	return &models.ECUMap{
-		Config: cfg,
-		Data:   data,
-	}, nil
+	return raw, nil
 }
 
 // FindMinMax finds the minimum and maximum values in map data
@@ -74,54 +144,27 @@ func FindMinMax(data [][]float64) (float64, float64) {
 
 // ReadConfigParam reads a configuration parameter value from the ECU file
 func ReadConfigParam(filename string, param models.ConfigParam) (float64, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return 0, err
-	}
-	defer f.Close()
-
-	_, err = f.Seek(param.Offset, io.SeekStart)
+	data, err := ReadBinary(filename)
 	if err != nil {
 		return 0, err
 	}
+	return ReadConfigParamFromBytes(data, param)
+}
 
-	var value float64
+// ReadConfigParamFromBytes decodes a configuration parameter value from
+// the contents of an ECU image
+func ReadConfigParamFromBytes(data []byte, param models.ConfigParam) (float64, error) {
 	switch param.DataType {
-	case "uint8":
-		var rawValue uint8
-		err = binary.Read(f, binary.LittleEndian, &rawValue)
-		if err != nil {
-			return 0, err
-		}
-		value = float64(rawValue)*param.Scale + param.Offset2
-
-	case "uint16":
-		var rawValue uint16
-		err = binary.Read(f, binary.LittleEndian, &rawValue)
-		if err != nil {
-			return 0, err
-		}
-		value = float64(rawValue)*param.Scale + param.Offset2
-
-	case "int8":
-		var rawValue int8
-		err = binary.Read(f, binary.LittleEndian, &rawValue)
-		if err != nil {
-			return 0, err
-		}
-		value = float64(rawValue)*param.Scale + param.Offset2
-
-	case "int16":
-		var rawValue int16
-		err = binary.Read(f, binary.LittleEndian, &rawValue)
-		if err != nil {
-			return 0, err
-		}
-		value = float64(rawValue)*param.Scale + param.Offset2
-
+	case "uint8", "uint16", "int8", "int16":
 	default:
-		return 0, nil
+		return 0, NewError(ErrUnsupportedDataType, "unsupported data type: %s", param.DataType)
 	}
-
-	return value, nil
+	if err := param.CheckConversion(); err != nil {
+		return 0, NewError(ErrInvalidDefinition, "%s: %v", param.Name, err)
+	}
+	size := int64(models.DataTypeSize(param.DataType))
+	if err := CheckBounds(fmt.Sprintf("parameter %q", param.Name), param.Offset, size, int64(len(data))); err != nil {
+		return 0, err
+	}
+	return param.ToReal(param.DecodeRaw(data[param.Offset:])), nil
 }
@@ -0,0 +1,50 @@
+package reader
+
+import (
+	"errors"
+	"io"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// ReadMapAt decodes a map from an image of size bytes read through r, for
+// callers that hold the image somewhere other than a file, such as a
+// network stream buffered behind an io.ReaderAt
+func ReadMapAt(r io.ReaderAt, size int64, cfg models.MapConfig) (*models.ECUMap, error) {
+	data, err := readImageAt(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return ReadMapFromBytes(data, cfg)
+}
+
+// ReadConfigParamAt decodes a configuration parameter value from an image
+// of size bytes read through r
+func ReadConfigParamAt(r io.ReaderAt, size int64, param models.ConfigParam) (float64, error) {
+	data, err := readImageAt(r, size)
+	if err != nil {
+		return 0, err
+	}
+	return ReadConfigParamFromBytes(data, param)
+}
+
+// readImageAt reads the whole image behind r after checking size against
+// MaxFileSize. An image shorter than size is an ErrOutOfRange error
+// rather than a bare io.EOF.
+func readImageAt(r io.ReaderAt, size int64) ([]byte, error) {
+	if size < 0 {
+		return nil, NewError(ErrOutOfRange, "invalid image size %d", size)
+	}
+	if size > MaxFileSize {
+		return nil, &FileTooLargeError{Size: size, Limit: MaxFileSize}
+	}
+	data := make([]byte, size)
+	n, err := r.ReadAt(data, 0)
+	if n == len(data) {
+		return data, nil // io.EOF is allowed with the last byte
+	}
+	if errors.Is(err, io.EOF) || err == nil {
+		return nil, NewError(ErrOutOfRange, "image is only %d bytes, expected %d", n, size)
+	}
+	return nil, err
+}
@@ -0,0 +1,81 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// WriteError reports an ECU file that cannot be modified, naming the path
+// and the reason the operating system gave
+type WriteError struct {
+	Path string
+	Err  error
+}
+
+// Error implements error
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("cannot write %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying OS error
+func (e *WriteError) Unwrap() error { return e.Err }
+
+// ReadOnly reports whether the file or its mount refuses writes
+func (e *WriteError) ReadOnly() bool {
+	return errors.Is(e.Err, fs.ErrPermission) || isReadOnlyFS(e.Err)
+}
+
+// Locked reports whether another program holds the file open exclusively
+func (e *WriteError) Locked() bool { return isLocked(e.Err) }
+
+// Is matches ErrReadOnly and ErrMapLocked by the failure's cause
+func (e *WriteError) Is(target error) bool {
+	switch target {
+	case ErrReadOnly:
+		return e.ReadOnly()
+	case ErrMapLocked:
+		return e.Locked()
+	}
+	return false
+}
+
+// Hint returns an actionable suggestion for the failure, or "" if there is
+// nothing the user can do beyond the OS message
+func (e *WriteError) Hint() string {
+	switch {
+	case e.ReadOnly():
+		return "file is read-only — copy it first or run with -safe-copy"
+	case e.Locked():
+		return "file is open in another program — close it and try again"
+	}
+	return ""
+}
+
+// CheckWritable opens the file for reading and writing without changing it,
+// so that read-only files, read-only mounts and files locked by another
+// program are rejected before any backup or prompt
+func CheckWritable(filename string) error {
+	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return &WriteError{Path: filename, Err: err}
+	}
+	return f.Close()
+}
+
+// DescribeWriteError renders err with the hint of a WriteError it wraps, for
+// display in the CLI, GUI and web interface
+func DescribeWriteError(err error) string {
+	var we *WriteError
+	if errors.As(err, &we) {
+		if hint := we.Hint(); hint != "" {
+			return fmt.Sprintf("%v (%s)", err, hint)
+		}
+	}
+	return err.Error()
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package reader
+
+// Platforms without file permissions or locks, such as the browser build,
+// only report the generic OS error
+
+func isLocked(err error) bool { return false }
+
+func isReadOnlyFS(err error) bool { return false }
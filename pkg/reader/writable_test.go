@@ -0,0 +1,83 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// readOnly makes path read-only, skipping the test where file modes don't
+// stop writes: on Windows, and for root
+func readOnly(t *testing.T, path string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("read-only files are tested with chmod on Unix")
+	}
+	if err := os.Chmod(path, 0444); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(path, 0644) })
+	if f, err := os.OpenFile(path, os.O_RDWR, 0); err == nil {
+		f.Close()
+		t.Skip("file modes are not enforced for this user")
+	}
+}
+
+func TestCheckWritableReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ecu.bin")
+	if err := os.WriteFile(path, []byte{1, 2, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckWritable(path); err != nil {
+		t.Fatalf("writable file: %v", err)
+	}
+	readOnly(t, path)
+
+	err := CheckWritable(path)
+	var we *WriteError
+	if !errors.As(err, &we) || we.Path != path {
+		t.Fatalf("CheckWritable = %v, want a WriteError for %s", err, path)
+	}
+	if !errors.Is(err, ErrReadOnly) || errors.Is(err, ErrMapLocked) {
+		t.Errorf("%v is not reported as read-only", err)
+	}
+	want := fmt.Sprintf("cannot write %s: permission denied (file is read-only — copy it first or run with -safe-copy)", path)
+	if got := DescribeWriteError(err); got != want {
+		t.Errorf("DescribeWriteError = %q, want %q", got, want)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 3 {
+		t.Error("CheckWritable changed the file")
+	}
+}
+
+func TestWriteErrorKinds(t *testing.T) {
+	tests := []struct {
+		err      error
+		readOnly bool
+		hint     string
+	}{
+		{fs.ErrPermission, true, "-safe-copy"},
+		{fmt.Errorf("open: %w", fs.ErrPermission), true, "-safe-copy"},
+		{errors.New("disk on fire"), false, ""},
+	}
+	for _, tt := range tests {
+		we := &WriteError{Path: "ecu.bin", Err: tt.err}
+		if errors.Is(we, ErrReadOnly) != tt.readOnly {
+			t.Errorf("%v: read-only %v, want %v", tt.err, !tt.readOnly, tt.readOnly)
+		}
+		if hint := we.Hint(); !strings.Contains(hint, tt.hint) || (tt.hint == "") != (hint == "") {
+			t.Errorf("%v: hint %q, want one containing %q", tt.err, hint, tt.hint)
+		}
+		if !errors.Is(we, tt.err) {
+			t.Errorf("%v is not unwrapped", tt.err)
+		}
+	}
+	if got := DescribeWriteError(errors.New("plain")); got != "plain" {
+		t.Errorf("DescribeWriteError of a plain error = %q", got)
+	}
+}
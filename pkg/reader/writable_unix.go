@@ -0,0 +1,16 @@
+//go:build unix
+
+package reader
+
+import (
+	"errors"
+	"syscall"
+)
+
+func isLocked(err error) bool {
+	return errors.Is(err, syscall.ETXTBSY)
+}
+
+func isReadOnlyFS(err error) bool {
+	return errors.Is(err, syscall.EROFS)
+}
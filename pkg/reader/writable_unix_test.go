@@ -0,0 +1,22 @@
+//go:build unix
+
+package reader
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestWriteErrorErrnos(t *testing.T) {
+	if we := (&WriteError{Path: "ecu.bin", Err: syscall.EROFS}); !errors.Is(we, ErrReadOnly) {
+		t.Error("EROFS is not read-only")
+	}
+	we := &WriteError{Path: "ecu.bin", Err: syscall.ETXTBSY}
+	if !errors.Is(we, ErrMapLocked) || errors.Is(we, ErrReadOnly) {
+		t.Error("ETXTBSY is not reported as locked")
+	}
+	if hint := we.Hint(); hint != "file is open in another program — close it and try again" {
+		t.Errorf("hint %q", hint)
+	}
+}
@@ -0,0 +1,22 @@
+package reader
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Windows error codes for write-protected media and files opened by
+// another process
+const (
+	errWriteProtect     syscall.Errno = 19
+	errSharingViolation syscall.Errno = 32
+	errLockViolation    syscall.Errno = 33
+)
+
+func isLocked(err error) bool {
+	return errors.Is(err, errSharingViolation) || errors.Is(err, errLockViolation)
+}
+
+func isReadOnlyFS(err error) bool {
+	return errors.Is(err, errWriteProtect)
+}
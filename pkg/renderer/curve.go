@@ -0,0 +1,55 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// sparkBlocks are the bar heights of a curve's sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// curveColumn is the width of one column of a rendered curve
+const curveColumn = 7
+
+// buildCurveString renders a one-row map as its column axis, the values
+// and a sparkline of bars rising with the value, colored by the map's
+// color scale. Every display mode shows the same view, since a single
+// row of symbols or heatmap blocks says little about the curve's shape.
+func buildCurveString(m *models.ECUMap) string {
+	var result strings.Builder
+	scale := m.Config.HeatScale(m.Data)
+	values := m.Data[0]
+	lo, hi := findMinMax(m.Data)
+
+	result.WriteString(fmt.Sprintf("%8s → |", m.Config.XAxisName()))
+	for _, label := range m.ColumnLabels() {
+		result.WriteString(fmt.Sprintf("%*s", curveColumn, label))
+	}
+	result.WriteString("\n" + strings.Repeat("-", 11) + "|" + strings.Repeat("-", len(values)*curveColumn) + "\n")
+
+	result.WriteString(fmt.Sprintf("%8s   |", m.Config.Unit))
+	for _, value := range values {
+		text := fmt.Sprintf("%*.2f", curveColumn, value)
+		if m.Config.BelowThreshold(value) {
+			text = fmt.Sprintf("%*.2f", curveColumn-1, value) + HighlightMarker
+		}
+		result.WriteString(getColorStyle(value, scale).Sprint(text))
+	}
+	result.WriteString("\n" + strings.Repeat(" ", 11) + "|")
+	for _, value := range values {
+		level := len(sparkBlocks) / 2
+		if hi > lo {
+			level = int((value - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		bar := strings.Repeat(string(sparkBlocks[level]), 4)
+		result.WriteString(strings.Repeat(" ", curveColumn-4) + getColorStyle(value, scale).Sprint(bar))
+	}
+
+	result.WriteString(fmt.Sprintf("\n\nCurve: %.2f to %.2f %s  (%s)", lo, hi, m.Config.Unit, scale.Label()))
+	if m.Config.HighlightBelow != nil {
+		result.WriteString(fmt.Sprintf("\n%s below %.1f %s", HighlightMarker, *m.Config.HighlightBelow, m.Config.Unit))
+	}
+	return result.String()
+}
@@ -0,0 +1,51 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// HeatmapBands is the number of color bands in the CLI heatmap. The GUI and
+// web legends label the same boundaries on their continuous gradients.
+const HeatmapBands = 5
+
+// HighlightMarker flags cells below a map's HighlightBelow threshold
+const HighlightMarker = "•"
+
+// heatmapStyles are the CLI heatmap band colors, lowest first
+var heatmapStyles = []*pterm.Style{
+	pterm.NewStyle(pterm.BgBlue, pterm.FgWhite),
+	pterm.NewStyle(pterm.BgCyan, pterm.FgBlack),
+	pterm.NewStyle(pterm.BgGreen, pterm.FgBlack),
+	pterm.NewStyle(pterm.BgYellow, pterm.FgBlack),
+	pterm.NewStyle(pterm.BgRed, pterm.FgWhite),
+}
+
+// getHeatmapLegend labels each heatmap band with its value range and the
+// scaling mode, plus the highlight marker if the map defines a threshold
+func getHeatmapLegend(cfg models.MapConfig, scale models.HeatScale) string {
+	var result strings.Builder
+	result.WriteString("Heatmap: ")
+
+	if scale.Flat() {
+		result.WriteString(pterm.BgGray.Sprint("  ") + fmt.Sprintf(" %.1f %s", scale.Min(), cfg.Unit))
+	} else {
+		bounds := scale.BandBoundaries(HeatmapBands)
+		for i, style := range heatmapStyles {
+			if i > 0 {
+				result.WriteString("  ")
+			}
+			result.WriteString(style.Sprint("▄▄") + fmt.Sprintf(" %s…%s", scale.Format(bounds[i]), scale.Format(bounds[i+1])))
+		}
+		result.WriteString(" " + cfg.Unit)
+	}
+	result.WriteString(fmt.Sprintf("  (%s)", scale.Label()))
+
+	if cfg.HighlightBelow != nil {
+		result.WriteString(fmt.Sprintf("\n         %s below %.1f %s", HighlightMarker, *cfg.HighlightBelow, cfg.Unit))
+	}
+	return result.String()
+}
@@ -2,10 +2,16 @@ package renderer
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/tabular"
+	"github.com/tosih/motronic-m21-tool/pkg/datalog"
 	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
 )
 
 // RenderMap displays a map with optional verbose output and display mode
@@ -14,24 +20,31 @@ func RenderMap(m *models.ECUMap, verbose bool, displayMode string, min, max floa
 		m.Config.Name, m.Config.Offset, m.Config.Rows, m.Config.Cols, min, max, m.Config.Unit)
 
 	pterm.Info.Println(m.Config.Description)
-	pterm.DefaultBox.WithTitle(title).WithTitleTopLeft().Println(BuildMapString(m, displayMode, min, max))
+	for _, warning := range m.AxisWarnings() {
+		pterm.Warning.Println(warning)
+	}
+	pterm.DefaultBox.WithTitle(title).WithTitleTopLeft().Println(BuildMapString(m, displayMode))
 }
 
-// BuildMapString creates a formatted string representation of the map
-func BuildMapString(m *models.ECUMap, displayMode string, min, max float64) string {
+// BuildMapString creates a formatted string representation of the map,
+// colored by the map's color scale. The axes show the map's breakpoints,
+// or the synthetic RPM and load labels when it has none. A curve is shown
+// as values over a sparkline (see buildCurveString).
+func BuildMapString(m *models.ECUMap, displayMode string) string {
+	if m.Config.IsCurve() {
+		return buildCurveString(m)
+	}
 	var result strings.Builder
-
-	rpmStep := 8000 / m.Config.Cols
-	loadStep := 100 / m.Config.Rows
+	scale := m.Config.HeatScale(m.Data)
+	rowLabels := m.RowLabels()
 
 	// Header
-	result.WriteString("    RPM → |")
-	for j := 0; j < m.Config.Cols; j++ {
-		rpm := j * rpmStep
+	result.WriteString(fmt.Sprintf("%7s → |", m.Config.XAxisName()))
+	for _, rpm := range m.ColumnLabels() {
 		if displayMode == "values" {
-			result.WriteString(fmt.Sprintf("%6d", rpm))
+			result.WriteString(fmt.Sprintf("%6s", rpm))
 		} else {
-			result.WriteString(fmt.Sprintf("%-4d", rpm))
+			result.WriteString(fmt.Sprintf("%-4s", rpm))
 		}
 	}
 	result.WriteString("\n")
@@ -41,22 +54,30 @@ func BuildMapString(m *models.ECUMap, displayMode string, min, max float64) stri
 	if displayMode != "values" {
 		sep = 4
 	}
-	result.WriteString("  Load%  |" + strings.Repeat("-", m.Config.Cols*sep) + "\n")
+	result.WriteString(fmt.Sprintf("  %-7s|", m.Config.RowCaption()) + strings.Repeat("-", m.Config.Cols*sep) + "\n")
 
 	// Data rows
 	for i := 0; i < m.Config.Rows; i++ {
-		loadPct := i * loadStep
-		result.WriteString(fmt.Sprintf("   %3d ↓ |", loadPct))
+		result.WriteString(fmt.Sprintf("  %4s ↓ |", rowLabels[i]))
 		for j := 0; j < m.Config.Cols; j++ {
 			value := m.Data[i][j]
+			marked := m.Config.BelowThreshold(value)
 			if displayMode == "values" {
-				color := getColorStyle(value, min, max)
-				result.WriteString(color.Sprintf("%6.2f", value))
+				color := getColorStyle(value, scale)
+				if marked {
+					result.WriteString(color.Sprintf("%5.1f", value) + HighlightMarker)
+				} else {
+					result.WriteString(color.Sprintf("%6.2f", value))
+				}
 			} else if displayMode == "heatmap" {
-				result.WriteString(getHeatmapBlock(value, min, max))
+				result.WriteString(getHeatmapBlock(value, scale, marked))
 			} else {
-				symbol := getSymbolForValue(value, min, max)
-				result.WriteString(symbol + symbol + symbol + symbol)
+				symbol := getSymbolForValue(value, scale)
+				if marked {
+					result.WriteString(symbol + symbol + symbol + HighlightMarker)
+				} else {
+					result.WriteString(symbol + symbol + symbol + symbol)
+				}
 			}
 		}
 		result.WriteString("\n")
@@ -64,56 +85,50 @@ func BuildMapString(m *models.ECUMap, displayMode string, min, max float64) stri
 
 	// Legend
 	if displayMode == "heatmap" {
-		result.WriteString("\n" + getHeatmapLegend())
-	} else if displayMode == "symbols" {
-		result.WriteString("\nLegend: ")
-		result.WriteString(pterm.FgCyan.Sprint("░") + " Low  ")
-		result.WriteString(pterm.FgGreen.Sprint("▒") + " Med  ")
-		result.WriteString(pterm.FgYellow.Sprint("▓") + " High  ")
-		result.WriteString(pterm.FgRed.Sprint("█") + " Max")
+		result.WriteString("\n" + getHeatmapLegend(m.Config, scale))
+	} else {
+		if displayMode == "symbols" {
+			result.WriteString("\nLegend: ")
+			result.WriteString(pterm.FgCyan.Sprint("░") + " Low  ")
+			result.WriteString(pterm.FgGreen.Sprint("▒") + " Med  ")
+			result.WriteString(pterm.FgYellow.Sprint("▓") + " High  ")
+			result.WriteString(pterm.FgRed.Sprint("█") + " Max")
+			result.WriteString("  (" + scale.Label() + ")")
+		}
+		if m.Config.HighlightBelow != nil {
+			result.WriteString(fmt.Sprintf("\n%s below %.1f %s", HighlightMarker, *m.Config.HighlightBelow, m.Config.Unit))
+		}
 	}
 
 	return result.String()
 }
 
-func getHeatmapBlock(value, min, max float64) string {
-	if max == min {
-		return pterm.BgGray.Sprint("  ")
+func getHeatmapBlock(value float64, scale models.HeatScale, marked bool) string {
+	block := "▄▄"
+	if marked {
+		block = "▄" + HighlightMarker
 	}
 
-	normalized := (value - min) / (max - min)
-
-	switch {
-	case normalized < 0.2:
-		return pterm.NewStyle(pterm.BgBlue, pterm.FgWhite).Sprint("▄▄")
-	case normalized < 0.4:
-		return pterm.NewStyle(pterm.BgCyan, pterm.FgBlack).Sprint("▄▄")
-	case normalized < 0.6:
-		return pterm.NewStyle(pterm.BgGreen, pterm.FgBlack).Sprint("▄▄")
-	case normalized < 0.8:
-		return pterm.NewStyle(pterm.BgYellow, pterm.FgBlack).Sprint("▄▄")
-	default:
-		return pterm.NewStyle(pterm.BgRed, pterm.FgWhite).Sprint("▄▄")
+	if scale.Flat() {
+		return pterm.BgGray.Sprint(block)
 	}
-}
 
-func getHeatmapLegend() string {
-	var result strings.Builder
-	result.WriteString("Heatmap: ")
-	result.WriteString(pterm.NewStyle(pterm.BgBlue, pterm.FgWhite).Sprint("▄▄") + " Very Low  ")
-	result.WriteString(pterm.NewStyle(pterm.BgCyan, pterm.FgBlack).Sprint("▄▄") + " Low  ")
-	result.WriteString(pterm.NewStyle(pterm.BgGreen, pterm.FgBlack).Sprint("▄▄") + " Medium  ")
-	result.WriteString(pterm.NewStyle(pterm.BgYellow, pterm.FgBlack).Sprint("▄▄") + " High  ")
-	result.WriteString(pterm.NewStyle(pterm.BgRed, pterm.FgWhite).Sprint("▄▄") + " Very High")
-	return result.String()
+	band := int(scale.Normalize(value) * HeatmapBands)
+	if band >= HeatmapBands {
+		band = HeatmapBands - 1
+	}
+	if band < 0 {
+		band = 0
+	}
+	return heatmapStyles[band].Sprint(block)
 }
 
-func getSymbolForValue(value, min, max float64) string {
-	if max == min {
+func getSymbolForValue(value float64, scale models.HeatScale) string {
+	if scale.Flat() {
 		return pterm.FgGray.Sprint("·")
 	}
 
-	normalized := (value - min) / (max - min)
+	normalized := scale.Normalize(value)
 
 	switch {
 	case normalized < 0.25:
@@ -127,12 +142,12 @@ func getSymbolForValue(value, min, max float64) string {
 	}
 }
 
-func getColorStyle(value, min, max float64) *pterm.Style {
-	if max == min {
+func getColorStyle(value float64, scale models.HeatScale) *pterm.Style {
+	if scale.Flat() {
 		return pterm.NewStyle(pterm.FgGray)
 	}
 
-	normalized := (value - min) / (max - min)
+	normalized := scale.Normalize(value)
 
 	switch {
 	case normalized < 0.25:
@@ -146,57 +161,115 @@ func getColorStyle(value, min, max float64) *pterm.Style {
 	}
 }
 
-// ListAvailableMaps displays all available ECU maps in a table
-func ListAvailableMaps() {
-	pterm.DefaultHeader.WithFullWidth().Println("Available ECU Maps")
-
-	data := [][]string{
-		{"Name", "Offset", "Size", "Unit", "Description"},
+// MapListTable returns one row per defined map in definition order, for
+// -list with -format
+func MapListTable() *tabular.Table {
+	t := tabular.New("Name", "Category", "Offset", "Size", "Unit", "Source", "Description")
+	for _, cfg := range models.MapConfigs {
+		t.Add(append([]string{cfg.Name, cfg.CategoryName()}, mapListCells(cfg)...)...)
 	}
+	return t
+}
 
-	for _, cfg := range models.MapConfigs {
-		data = append(data, []string{
-			cfg.Name,
-			fmt.Sprintf("0x%04X", cfg.Offset),
-			fmt.Sprintf("%dx%d", cfg.Rows, cfg.Cols),
-			cfg.Unit,
-			cfg.Description,
-		})
+// mapListCells returns the cells of a map's row after its name and
+// category
+func mapListCells(cfg models.MapConfig) []string {
+	source := cfg.Source
+	if source == "" {
+		source = "built-in"
+	}
+	return []string{
+		fmt.Sprintf("0x%04X", cfg.Offset),
+		fmt.Sprintf("%dx%d", cfg.Rows, cfg.Cols),
+		cfg.Unit,
+		source,
+		cfg.Description,
 	}
+}
 
-	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+// ListAvailableMaps displays all available ECU maps, one table per
+// category
+func ListAvailableMaps() {
+	pterm.DefaultHeader.WithFullWidth().Println(i18n.T("cli.maps.list_header"))
+	for _, group := range models.GroupByCategory(models.MapConfigs) {
+		pterm.DefaultSection.Println(i18n.T("cli.maps.category", group.Name, len(group.Indices)))
+		t := tabular.New("Name", "Offset", "Size", "Unit", "Source", "Description")
+		for _, i := range group.Indices {
+			cfg := models.MapConfigs[i]
+			t.Add(append([]string{cfg.Name}, mapListCells(cfg)...)...)
+		}
+		t.Render()
+	}
 }
 
 // DisplayMaps reads and displays the selected maps
-func DisplayMaps(filename, mapType string, verbose bool, displayMode string, readMap func(string, models.MapConfig) (*models.ECUMap, error)) {
+func DisplayMaps(filename, mapType string, verbose bool, displayMode string, id *models.BinaryIdentity, readMap func(string, models.MapConfig) (*models.ECUMap, error)) {
 	// Select which maps to display
 	var selectedConfigs []models.MapConfig
-	switch mapType {
+	switch strings.ToLower(mapType) {
 	case "fuel":
-		selectedConfigs = []models.MapConfig{models.MapConfigs[0]}
+		selectedConfigs = categoryMaps(0, models.CategoryFuel)
 	case "spark", "ignition":
-		selectedConfigs = []models.MapConfig{models.MapConfigs[1]}
+		selectedConfigs = categoryMaps(1, models.CategoryIgnition)
 	case "lambda":
-		selectedConfigs = []models.MapConfig{models.MapConfigs[2]}
+		selectedConfigs = categoryMaps(2, models.CategoryLambda)
 	case "boost":
-		selectedConfigs = []models.MapConfig{models.MapConfigs[3]}
+		cfg, ok := models.MapByRole(models.RoleBoost)
+		if !ok {
+			pterm.Error.Println("No boost map is designated in the map definitions")
+			return
+		}
+		selectedConfigs = []models.MapConfig{cfg}
 	case "coldstart":
-		selectedConfigs = []models.MapConfig{models.MapConfigs[4]}
+		cfg, ok := models.MapByRole(models.RoleColdStart)
+		if !ok {
+			pterm.Error.Println("No cold start map is designated in the map definitions")
+			return
+		}
+		selectedConfigs = []models.MapConfig{cfg}
+	case "curves":
+		for _, cfg := range models.MapConfigs {
+			if cfg.IsCurve() {
+				selectedConfigs = append(selectedConfigs, cfg)
+			}
+		}
+		if len(selectedConfigs) == 0 {
+			pterm.Error.Println("No curves are defined in the map definitions")
+			return
+		}
 	case "all":
 		selectedConfigs = models.MapConfigs
 	default:
-		pterm.Error.Printf("Unknown map type: %s\n", mapType)
-		return
+		category, ok := models.FindCategory(mapType)
+		if !ok {
+			pterm.Error.Printf("Unknown map type: %s\n", mapType)
+			return
+		}
+		selectedConfigs = categoryMaps(-1, category)
+		if len(selectedConfigs) == 0 {
+			pterm.Error.Printf("No maps are in the %s category\n", category)
+			return
+		}
 	}
 
+	// The part number and software version tell near-identical bins apart
+	header := i18n.T("cli.maps.header")
+	if version, err := reader.IdentifyECU(filename); err == nil {
+		header = i18n.T("cli.maps.header_ecu", header, version)
+	}
 	pterm.DefaultHeader.WithFullWidth().
 		WithBackgroundStyle(pterm.NewStyle(pterm.BgDarkGray)).
 		WithTextStyle(pterm.NewStyle(pterm.FgLightWhite)).
-		Println("ECU Map Reader - Motronic M2.1")
+		Println(header)
+
+	if id != nil {
+		PrintIdentity(filename, id)
+	}
 
 	pterm.Println()
 
 	// Read and display maps
+	var data []byte
 	for i, cfg := range selectedConfigs {
 		if i > 0 {
 			pterm.Println()
@@ -209,9 +282,81 @@ func DisplayMaps(filename, mapType string, verbose bool, displayMode string, rea
 
 		min, max := findMinMax(ecuMap.Data)
 		RenderMap(ecuMap, verbose, displayMode, min, max)
+		if cfg.Unconfirmed {
+			if data == nil {
+				data, _ = reader.ReadBinary(filename)
+			}
+			printCodeCheck(data, cfg)
+		}
 	}
 }
 
+// categoryMaps returns the maps of category in definition order, along
+// with the map at index fixed, which fuel, ignition and lambda are
+// addressed by whatever category it has; -1 for none
+func categoryMaps(fixed int, category string) []models.MapConfig {
+	var configs []models.MapConfig
+	for i, cfg := range models.MapConfigs {
+		if i == fixed || cfg.CategoryName() == category {
+			configs = append(configs, cfg)
+		}
+	}
+	return configs
+}
+
+// printCodeCheck tells whether the bytes of an unconfirmed map look like
+// program code, the warning editing it would give
+func printCodeCheck(data []byte, cfg models.MapConfig) {
+	check, err := scanner.CheckCode(data, cfg)
+	switch {
+	case err != nil:
+		pterm.Warning.Printf("Unconfirmed definition; code check failed: %v\n", err)
+	case check.LikelyCode:
+		pterm.Warning.Printf("Unconfirmed definition; %s\n", check)
+	default:
+		pterm.Info.Printf("Unconfirmed definition; %s\n", check)
+	}
+}
+
+// PrintIdentity prints the identification strings found in a binary
+func PrintIdentity(filename string, id *models.BinaryIdentity) {
+	unknown := func(s string) string {
+		if s == "" {
+			return pterm.Gray("unknown")
+		}
+		return s
+	}
+
+	data := pterm.TableData{
+		{"File", filepath.Base(filename)},
+		{"Part number", unknown(id.PartNumber)},
+		{"Bosch number", unknown(id.BoschNumber)},
+		{"Software", unknown(id.SoftwareVersion)},
+		{"SHA-256", id.SHA256[:16]},
+	}
+	if p := id.Provenance; p != nil {
+		defs := p.Definitions
+		if defs != models.DefinitionsFingerprint() {
+			defs += pterm.Yellow(" (differs from current definitions)")
+		}
+		saved := p.ToolVersion + ", " + p.Saved.Local().Format("2006-01-02 15:04")
+		if p.Stale(id.SHA256) {
+			saved += pterm.Yellow(" (file changed since)")
+		}
+		parent := p.ParentSHA256
+		if len(parent) > 16 {
+			parent = parent[:16]
+		}
+		data = append(data,
+			[]string{"Saved by", saved},
+			[]string{"Definitions", defs},
+			[]string{"Modified", strings.Join(p.Modified, ", ")},
+			[]string{"Parent SHA-256", unknown(parent)},
+		)
+	}
+	pterm.DefaultTable.WithData(data).Render()
+}
+
 func findMinMax(data [][]float64) (float64, float64) {
 	min := data[0][0]
 	max := data[0][0]
@@ -229,3 +374,42 @@ func findMinMax(data [][]float64) (float64, float64) {
 
 	return min, max
 }
+
+// RenderLogOverlay prints the measured mean lambda and sample count of each
+// cell. Low-confidence cells are greyed and prefixed with ~.
+func RenderLogOverlay(target *models.ECUMap, o *datalog.Overlay) {
+	var result strings.Builder
+
+	result.WriteString("    RPM → |")
+	for j := 0; j < o.Cols; j++ {
+		result.WriteString(fmt.Sprintf("%11d", int(float64(j)*datalog.MaxRPM/float64(o.Cols))))
+	}
+	result.WriteString("\n  Load%  |" + strings.Repeat("-", o.Cols*11) + "\n")
+
+	for i := 0; i < o.Rows; i++ {
+		result.WriteString(fmt.Sprintf("  %4s ↓ |", target.Config.LoadLabel(i)))
+		for j := 0; j < o.Cols; j++ {
+			switch {
+			case o.Count[i][j] == 0:
+				result.WriteString(pterm.FgGray.Sprintf("%11s", "·"))
+			case o.LowConfidence(i, j):
+				result.WriteString(pterm.FgGray.Sprintf("%11s", fmt.Sprintf("~%.2f(%d)", o.Mean[i][j], o.Count[i][j])))
+			default:
+				style := pterm.NewStyle(pterm.FgGreen)
+				if o.Mean[i][j] < target.Data[i][j] {
+					style = pterm.NewStyle(pterm.FgCyan)
+				} else if o.Mean[i][j] > target.Data[i][j] {
+					style = pterm.NewStyle(pterm.FgRed)
+				}
+				result.WriteString(style.Sprintf("%11s", fmt.Sprintf("%.2f(%d)", o.Mean[i][j], o.Count[i][j])))
+			}
+		}
+		result.WriteString("\n")
+	}
+
+	result.WriteString(fmt.Sprintf("\nmeasured(samples): %s richer than target  %s leaner  %s within target  ~ fewer than %d samples",
+		pterm.FgCyan.Sprint("■"), pterm.FgRed.Sprint("■"), pterm.FgGreen.Sprint("■"), o.MinSamples))
+
+	title := fmt.Sprintf("%s | Measured lambda", target.Config.Name)
+	pterm.DefaultBox.WithTitle(title).WithTitleTopLeft().Println(result.String())
+}
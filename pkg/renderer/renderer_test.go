@@ -0,0 +1,60 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// The -list CSV reads back with one row per map and the λ unit intact
+func TestMapListCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := MapListTable().WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(models.MapConfigs)+1 {
+		t.Fatalf("%d rows, want a header and %d maps", len(records), len(models.MapConfigs))
+	}
+	var lambda bool
+	for i, cfg := range models.MapConfigs {
+		row := records[i+1]
+		if row[0] != cfg.Name || row[4] != cfg.Unit || row[6] != cfg.Description {
+			t.Errorf("row %d reads back as %q", i, row)
+		}
+		lambda = lambda || cfg.Unit == "λ"
+	}
+	if !lambda {
+		t.Error("no built-in map has the λ unit any more; the test needs another")
+	}
+}
+
+// The cold start map is shown with its temperature rows under a Temp
+// caption rather than the synthetic load axis
+func TestColdStartRows(t *testing.T) {
+	cfg, ok := models.MapByRole(models.RoleColdStart)
+	if !ok {
+		t.Fatal("no built-in cold start map")
+	}
+	m, err := reader.ReadMapFromBytes(testbin.Image(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := BuildMapString(m, "values")
+	for _, want := range []string{"  Temp   |", "   -30 ↓ |", "    90 ↓ |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Load%") || strings.Contains(out, "100%") {
+		t.Errorf("output shows the load axis:\n%s", out)
+	}
+}
@@ -0,0 +1,206 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/stats"
+)
+
+// Axis kinds suggested for a detected axis vector
+const (
+	AxisRPM     = "RPM"
+	AxisLoad    = "Load"
+	AxisCoolant = "Coolant temperature"
+	AxisUnknown = "Unknown"
+)
+
+// Confidence levels of an axis suggestion
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+	// ConfidenceNone marks the RPM/Load default used when no axis vector
+	// was found next to the map
+	ConfidenceNone = "none"
+)
+
+// AxisGuess is a suggested name and scaling for one map axis. Values are
+// Scale*raw + Offset2 in Unit. The user is expected to accept or override
+// it; nothing here is confirmed against a real M2.1 definition.
+type AxisGuess struct {
+	Kind       string
+	Unit       string
+	Scale      float64
+	Offset2    float64
+	Confidence string
+	Note       string
+	// At is the file offset of the axis vector, or -1 for a default
+	At int
+}
+
+// String formats the guess as "RPM (high)"
+func (g AxisGuess) String() string {
+	return fmt.Sprintf("%s (%s)", g.Kind, g.Confidence)
+}
+
+// AxisSuggestion holds the guesses for both axes of a scan hit
+type AxisSuggestion struct {
+	X AxisGuess // columns
+	Y AxisGuess // rows
+}
+
+// String formats both guesses on one line
+func (s AxisSuggestion) String() string {
+	return fmt.Sprintf("X: %s, Y: %s", s.X, s.Y)
+}
+
+// Thresholds for InferAxis. Coolant axes cover a narrow byte range with
+// breakpoints packed unevenly; RPM axes span a wide range in roughly equal
+// steps.
+const (
+	coolantMaxSpan   = 120
+	rpmMinSpan       = 100
+	linearStepCV     = 0.35
+	veryLinearStepCV = 0.15
+)
+
+// InferAxis guesses what a candidate axis vector measures from its byte
+// values alone. Vectors that are not strictly monotonic are not axes and
+// get AxisUnknown with ConfidenceNone.
+func InferAxis(values []byte) AxisGuess {
+	guess := AxisGuess{Kind: AxisUnknown, Unit: "raw", Scale: 1, Confidence: ConfidenceNone, At: -1}
+	if len(values) < 4 {
+		guess.Note = "too short for an axis"
+		return guess
+	}
+
+	increasing, decreasing := true, true
+	steps := make([]float64, len(values)-1)
+	for i := 1; i < len(values); i++ {
+		d := float64(values[i]) - float64(values[i-1])
+		increasing = increasing && d > 0
+		decreasing = decreasing && d < 0
+		steps[i-1] = math.Abs(d)
+	}
+	if !increasing && !decreasing {
+		guess.Note = "not monotonic"
+		return guess
+	}
+
+	span := math.Abs(float64(values[len(values)-1]) - float64(values[0]))
+	s := stats.Of(steps)
+	cv := 0.0
+	if s.Mean > 0 {
+		cv = s.StdDev / s.Mean
+	}
+
+	switch {
+	case increasing && span >= rpmMinSpan && cv < linearStepCV:
+		guess.Kind, guess.Unit, guess.Scale = AxisRPM, "RPM", 40
+		guess.Confidence = ConfidenceMedium
+		if cv < veryLinearStepCV {
+			guess.Confidence = ConfidenceHigh
+		}
+		guess.Note = fmt.Sprintf("wide span (%.0f) in roughly equal steps", span)
+	case span <= coolantMaxSpan && cv >= linearStepCV:
+		guess.Kind, guess.Unit, guess.Scale, guess.Offset2 = AxisCoolant, "°C", 1, -40
+		guess.Confidence = ConfidenceMedium
+		guess.Note = fmt.Sprintf("narrow span (%.0f) with uneven breakpoints", span)
+	case span <= coolantMaxSpan:
+		guess.Kind, guess.Unit, guess.Scale, guess.Offset2 = AxisCoolant, "°C", 1, -40
+		guess.Confidence = ConfidenceLow
+		guess.Note = fmt.Sprintf("narrow span (%.0f), but evenly spaced", span)
+	default:
+		guess.Kind, guess.Unit, guess.Scale = AxisLoad, "%", 100.0/255
+		guess.Confidence = ConfidenceLow
+		guess.Note = fmt.Sprintf("wide span (%.0f) in uneven steps", span)
+	}
+	return guess
+}
+
+// SuggestAxes looks for axis vectors stored directly before a uint8 map,
+// in either X-then-Y or Y-then-X order, and infers what they measure.
+// Axes that can't be found default to RPM (X) and Load (Y) with
+// ConfidenceNone, which is what callers showed before.
+func SuggestAxes(data []byte, hit ScanResult) AxisSuggestion {
+	best := AxisSuggestion{X: defaultAxis(AxisRPM), Y: defaultAxis(AxisLoad)}
+	if hit.DataType != "uint8" {
+		return best
+	}
+
+	bestScore := 0
+	for _, xFirst := range []bool{true, false} {
+		var xAt, yAt int
+		if xFirst {
+			xAt = hit.Offset - hit.Rows - hit.Cols
+			yAt = hit.Offset - hit.Rows
+		} else {
+			yAt = hit.Offset - hit.Rows - hit.Cols
+			xAt = hit.Offset - hit.Cols
+		}
+		if xAt < 0 || yAt < 0 {
+			continue
+		}
+
+		s := AxisSuggestion{
+			X: inferAt(data, xAt, hit.Cols, AxisRPM),
+			Y: inferAt(data, yAt, hit.Rows, AxisLoad),
+		}
+		if score := s.X.score() + s.Y.score(); score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	return best
+}
+
+// inferAt infers the axis stored at offset, falling back to the default
+// kind when the bytes there are not an axis
+func inferAt(data []byte, offset, length int, fallback string) AxisGuess {
+	guess := InferAxis(data[offset : offset+length])
+	if guess.Confidence == ConfidenceNone {
+		return defaultAxis(fallback)
+	}
+	guess.At = offset
+	return guess
+}
+
+// defaultAxis is the unconfirmed RPM or Load axis shown without evidence
+func defaultAxis(kind string) AxisGuess {
+	g := AxisGuess{Kind: kind, Unit: "%", Scale: 100.0 / 255, Confidence: ConfidenceNone, At: -1,
+		Note: "no adjacent axis vector found"}
+	if kind == AxisRPM {
+		g.Unit, g.Scale = "RPM", 40
+	}
+	return g
+}
+
+// Config returns the axis definition of a guess found in the file, with
+// count breakpoints, or nil for a default or missing guess
+func (g AxisGuess) Config(count int) *models.AxisConfig {
+	if g.At < 0 || g.Confidence == ConfidenceNone || g.Confidence == "" {
+		return nil
+	}
+	return &models.AxisConfig{
+		Offset:   int64(g.At),
+		Count:    count,
+		DataType: "uint8",
+		Scale:    g.Scale,
+		Offset2:  g.Offset2,
+		Unit:     g.Unit,
+	}
+}
+
+// score ranks guesses so the more convincing axis layout wins
+func (g AxisGuess) score() int {
+	switch g.Confidence {
+	case ConfidenceHigh:
+		return 3
+	case ConfidenceMedium:
+		return 2
+	case ConfidenceLow:
+		return 1
+	}
+	return 0
+}
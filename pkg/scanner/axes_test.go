@@ -0,0 +1,168 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+)
+
+// rpmAxis is 16 breakpoints in equal steps over a wide span
+var rpmAxis = []byte{20, 32, 44, 56, 68, 80, 92, 104, 116, 128, 140, 152, 164, 176, 188, 200}
+
+// coolantAxis is 8 unevenly packed breakpoints over a narrow span
+var coolantAxis = []byte{20, 25, 30, 40, 60, 90, 110, 120}
+
+func TestInferAxis(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []byte
+		kind       string
+		confidence string
+	}{
+		{"rpm", rpmAxis, AxisRPM, ConfidenceHigh},
+		{"rpm with uneven steps", []byte{20, 35, 60, 75, 100, 115, 140, 155}, AxisRPM, ConfidenceMedium},
+		{"coolant", coolantAxis, AxisCoolant, ConfidenceMedium},
+		{"narrow and even", []byte{10, 20, 30, 40, 50, 60, 70, 80}, AxisCoolant, ConfidenceLow},
+		{"wide and uneven", []byte{0, 5, 10, 20, 60, 120, 200, 250}, AxisLoad, ConfidenceLow},
+		{"decreasing narrow", []byte{120, 110, 90, 60, 40, 30, 25, 20}, AxisCoolant, ConfidenceMedium},
+		{"not monotonic", []byte{10, 30, 20, 40, 50, 60}, AxisUnknown, ConfidenceNone},
+		{"flat", []byte{7, 7, 7, 7, 7}, AxisUnknown, ConfidenceNone},
+		{"too short", []byte{10, 100, 200}, AxisUnknown, ConfidenceNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := InferAxis(tt.values)
+			if g.Kind != tt.kind || g.Confidence != tt.confidence {
+				t.Errorf("InferAxis = %s, want %s (%s)", g, tt.kind, tt.confidence)
+			}
+			if g.At != -1 {
+				t.Errorf("InferAxis At = %d, want -1", g.At)
+			}
+			if g.Note == "" {
+				t.Error("InferAxis gave no note")
+			}
+		})
+	}
+}
+
+// imageWithAxes returns an image with a noisy 8x16 uint8 map at mapAt,
+// preceded by the given bytes
+func imageWithAxes(mapAt int, before ...[]byte) []byte {
+	data := make([]byte, 0x400)
+	at := mapAt
+	for _, b := range before {
+		at -= len(b)
+	}
+	for _, b := range before {
+		at += copy(data[at:], b)
+	}
+	for i := 0; i < 8*16; i++ {
+		data[mapAt+i] = byte(i*37 + 11)
+	}
+	return data
+}
+
+func TestSuggestAxes(t *testing.T) {
+	hit := ScanResult{Offset: 0x118, Rows: 8, Cols: 16, DataType: "uint8"}
+
+	t.Run("x then y", func(t *testing.T) {
+		s := SuggestAxes(imageWithAxes(hit.Offset, rpmAxis, coolantAxis), hit)
+		if s.X.Kind != AxisRPM || s.X.At != 0x100 {
+			t.Errorf("X = %s at %#x, want RPM at 0x100", s.X, s.X.At)
+		}
+		if s.Y.Kind != AxisCoolant || s.Y.At != 0x110 {
+			t.Errorf("Y = %s at %#x, want coolant at 0x110", s.Y, s.Y.At)
+		}
+	})
+
+	t.Run("y then x", func(t *testing.T) {
+		s := SuggestAxes(imageWithAxes(hit.Offset, coolantAxis, rpmAxis), hit)
+		if s.X.Kind != AxisRPM || s.X.At != 0x108 {
+			t.Errorf("X = %s at %#x, want RPM at 0x108", s.X, s.X.At)
+		}
+		if s.Y.Kind != AxisCoolant || s.Y.At != 0x100 {
+			t.Errorf("Y = %s at %#x, want coolant at 0x100", s.Y, s.Y.At)
+		}
+	})
+
+	defaults := map[string]struct {
+		data []byte
+		hit  ScanResult
+	}{
+		"no axes":       {imageWithAxes(hit.Offset), hit},
+		"16-bit map":    {imageWithAxes(hit.Offset, rpmAxis, coolantAxis), ScanResult{Offset: 0x118, Rows: 8, Cols: 16, DataType: "uint16"}},
+		"start of file": {imageWithAxes(4), ScanResult{Offset: 4, Rows: 8, Cols: 16, DataType: "uint8"}},
+	}
+	for name, tt := range defaults {
+		t.Run(name, func(t *testing.T) {
+			s := SuggestAxes(tt.data, tt.hit)
+			if s.X.Kind != AxisRPM || s.Y.Kind != AxisLoad || s.X.Confidence != ConfidenceNone || s.Y.Confidence != ConfidenceNone {
+				t.Errorf("SuggestAxes = %s, want the unconfirmed RPM/Load default", s)
+			}
+			if s.X.Config(tt.hit.Cols) != nil || s.Y.Config(tt.hit.Rows) != nil {
+				t.Error("a default guess has an axis definition")
+			}
+		})
+	}
+}
+
+func TestCandidate(t *testing.T) {
+	hit := ScanResult{Offset: 0x118, Rows: 8, Cols: 16, DataType: "uint8", Endianness: "N/A"}
+	hit.Axes = SuggestAxes(imageWithAxes(hit.Offset, rpmAxis, coolantAxis), hit)
+
+	cfg := hit.Candidate("Hit")
+	if cfg.XAxis == nil || cfg.YAxis == nil {
+		t.Fatalf("candidate axes X %v Y %v, want both", cfg.XAxis, cfg.YAxis)
+	}
+	if cfg.XAxis.Offset != 0x100 || cfg.XAxis.Count != 16 || cfg.XAxis.Scale != 40 || cfg.XAxis.Unit != "RPM" {
+		t.Errorf("X axis %+v, want 16 RPM breakpoints at 0x100 scaled by 40", *cfg.XAxis)
+	}
+	if cfg.YAxis.Offset != 0x110 || cfg.YAxis.Count != 8 || cfg.YAxis.Offset2 != -40 || cfg.YAxis.Unit != "°C" {
+		t.Errorf("Y axis %+v, want 8 °C breakpoints at 0x110 offset by -40", *cfg.YAxis)
+	}
+	if !strings.Contains(cfg.Description, "RPM (high)") {
+		t.Errorf("description %q does not name the suggestion", cfg.Description)
+	}
+	if !cfg.Unconfirmed || cfg.Scale != 1 || cfg.Unit != "raw" {
+		t.Errorf("candidate %+v is not an unconfirmed raw map", cfg)
+	}
+
+	// A hit without suggestions, such as one loaded from a checkpoint
+	// written before axes were inferred, gets no axes
+	bare := ScanResult{Offset: 0x118, Rows: 8, Cols: 16, DataType: "uint8"}.Candidate("Bare")
+	if bare.XAxis != nil || bare.YAxis != nil {
+		t.Errorf("bare hit got axes X %v Y %v", bare.XAxis, bare.YAxis)
+	}
+}
+
+func TestFindResult(t *testing.T) {
+	results := []ScanResult{
+		{Offset: 0x6800, Rows: 8, Cols: 16},
+		{Offset: 0x6800, Rows: 16, Cols: 8},
+		{Offset: 0x7000, Rows: 4, Cols: 4},
+	}
+	tests := []struct {
+		spec       string
+		rows, cols int
+		err        bool
+	}{
+		{"0x6800", 8, 16, false},
+		{"0x6800:16x8", 16, 8, false},
+		{" 0x7000 ", 4, 4, false},
+		{"28672", 4, 4, false},
+		{"0x6800:4x4", 0, 0, true},
+		{"0x6900", 0, 0, true},
+		{"zz", 0, 0, true},
+	}
+	for _, tt := range tests {
+		r, err := FindResult(results, tt.spec)
+		if tt.err {
+			if err == nil {
+				t.Errorf("FindResult(%q) = %+v, want an error", tt.spec, r)
+			}
+			continue
+		}
+		if err != nil || r.Rows != tt.rows || r.Cols != tt.cols {
+			t.Errorf("FindResult(%q) = %dx%d, %v, want %dx%d", tt.spec, r.Rows, r.Cols, err, tt.rows, tt.cols)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/internal/paths"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// CheckpointInterval is how often a running scan saves its checkpoint
+const CheckpointInterval = 2 * time.Second
+
+// Checkpoint is the progress of a scan: the pass and offset it continues
+// from and the results found before them. Checkpoints are keyed by the
+// image hash, stride and range, so a checkpoint is never applied to a
+// different file or scan.
+type Checkpoint struct {
+	SHA256  string       `json:"sha256"`
+	Stride  int          `json:"stride"`
+	Range   Range        `json:"range"`
+	Pass    int          `json:"pass"`
+	Offset  int          `json:"offset"`
+	Results []ScanResult `json:"results"`
+}
+
+// CheckpointPath returns where checkpoints of scans of the image with this
+// hash, stride and range are kept, in the cache directory
+func CheckpointPath(hash string, stride int, r Range) (string, error) {
+	dir, err := paths.CacheSubdir("scans")
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%d.json", hash, stride)
+	if !r.IsZero() {
+		name = fmt.Sprintf("%s-%d-%x-%x.json", hash, stride, r.Start, r.End)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// LoadCheckpoint reads a checkpoint file. A missing file returns nil
+// without error.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cp.Pass < 0 || cp.Pass > PassCount() || cp.Offset < 0 {
+		return nil, fmt.Errorf("%s: invalid scan position", path)
+	}
+	return cp, nil
+}
+
+// SaveCheckpoint writes the checkpoint atomically, so an interrupted save
+// leaves the previous checkpoint intact
+func SaveCheckpoint(path string, cp *Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// ResumableScan is a checkpointed scan of one file
+type ResumableScan struct {
+	// Checkpoint is where the scan starts; it advances as the scan runs
+	Checkpoint *Checkpoint
+	// Resumed reports whether Checkpoint was saved by an earlier run
+	Resumed bool
+	// Path is the checkpoint file
+	Path string
+
+	data []byte
+}
+
+// OpenScan prepares a scan of a file with the given stride, restricted to
+// r. With resume set it continues from a saved checkpoint of the same
+// file, stride and range if there is one, otherwise it starts from the
+// beginning.
+func OpenScan(filename string, stride int, r Range, resume bool) (*ResumableScan, error) {
+	data, err := reader.ReadBinary(filename)
+	if err != nil {
+		return nil, err
+	}
+	return OpenScanBytes(data, stride, r, resume)
+}
+
+// OpenScanBytes is OpenScan for contents already in memory, which must not
+// be modified while the scan runs. Checkpoints are keyed by the contents,
+// so either form resumes the other's scan.
+func OpenScanBytes(data []byte, stride int, r Range, resume bool) (*ResumableScan, error) {
+	if err := r.Check(len(data)); err != nil {
+		return nil, err
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+	path, err := CheckpointPath(hash, stride, r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ResumableScan{Checkpoint: &Checkpoint{SHA256: hash, Stride: stride, Range: r}, Path: path, data: data}
+	if resume {
+		saved, err := LoadCheckpoint(path)
+		if err != nil {
+			return nil, err
+		}
+		if saved != nil && saved.SHA256 == hash && saved.Stride == stride && saved.Range == r {
+			s.Checkpoint, s.Resumed = saved, true
+		}
+	}
+	return s, nil
+}
+
+// Run scans to the end, saving the checkpoint every CheckpointInterval. A
+// completed scan removes its checkpoint; a canceled one keeps it and
+// returns the partial results with ctx's error.
+func (s *ResumableScan) Run(ctx context.Context, step func(pass string)) ([]ScanResult, error) {
+	save := func(cp *Checkpoint) error { return SaveCheckpoint(s.Path, cp) }
+	results, err := ScanBytesFrom(ctx, s.data, s.Checkpoint, save, step)
+	if err != nil {
+		return results, err
+	}
+	os.Remove(s.Path)
+	return results, nil
+}
+
+// Position describes where the scan is, e.g. "8x16 uint8 at 0x1A40"
+func (cp *Checkpoint) Position() string {
+	passes := scanPasses()
+	if cp.Pass >= len(passes) {
+		return "end"
+	}
+	return fmt.Sprintf("%s at 0x%04X", passes[cp.Pass], cp.Offset)
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// Thresholds of CheckCode. Uniformly random cells have a roughness of
+// about 0.33 and an opcode share of about 0.06; maps are far smoother,
+// and 80C32 code is both rough and full of the opcodes below.
+const (
+	codeRoughness   = 0.25
+	codeOpcodeShare = 0.15
+)
+
+// codeOpcodes are the most frequent 80C32 opcodes in Motronic firmware:
+// LJMP, LCALL, RET, SJMP, JZ, JNZ, MOV DPTR/A/direct, MOVX, INC DPTR and
+// CLR C
+var codeOpcodes = [256]bool{
+	0x02: true, 0x12: true, 0x22: true, 0x60: true, 0x70: true, 0x74: true,
+	0x75: true, 0x80: true, 0x90: true, 0xA3: true, 0xC3: true, 0xE0: true,
+	0xE5: true, 0xF0: true, 0xF5: true,
+}
+
+// CodeCheck is the verdict of the code-vs-map heuristic on a map's bytes
+type CodeCheck struct {
+	LikelyCode bool `json:"likely_code"`
+	// Roughness is the mean difference between neighboring cells, across
+	// and down, relative to the value range; 0 for a flat map
+	Roughness float64 `json:"roughness"`
+	// OpcodeShare is the fraction of the bytes that are common opcodes
+	OpcodeShare float64 `json:"opcode_share"`
+}
+
+// String describes the verdict with the measurements behind it
+func (c CodeCheck) String() string {
+	verdict := "looks like map data"
+	if c.LikelyCode {
+		verdict = "looks like program code"
+	}
+	return fmt.Sprintf("%s (roughness %.2f, opcode bytes %.0f%%)", verdict, c.Roughness, c.OpcodeShare*100)
+}
+
+// CheckCode tells whether the bytes of a map look like program code
+// rather than calibration data: neighboring cells of a real map change
+// smoothly, while code jumps around and is dense in common opcodes. It is
+// a heuristic meant for unconfirmed definitions, which may point into
+// code by mistake.
+func CheckCode(data []byte, cfg models.MapConfig) (CodeCheck, error) {
+	if !models.KnownDataType(cfg.DataType) {
+		return CodeCheck{}, reader.NewError(reader.ErrUnsupportedDataType, "%s: unknown data type %q", cfg.Name, cfg.DataType)
+	}
+	if err := reader.CheckBounds(fmt.Sprintf("map %q", cfg.Name), cfg.Offset, cfg.ByteSize(), int64(len(data))); err != nil {
+		return CodeCheck{}, err
+	}
+	raw, err := reader.ReadRawMapFromBytes(data, cfg)
+	if err != nil {
+		return CodeCheck{}, err
+	}
+
+	var c CodeCheck
+	minRaw, maxRaw := raw[0][0], raw[0][0]
+	var sum float64
+	pairs := 0
+	for i, row := range raw {
+		for j, v := range row {
+			minRaw, maxRaw = min(minRaw, v), max(maxRaw, v)
+			if j > 0 {
+				sum += math.Abs(float64(v - row[j-1]))
+				pairs++
+			}
+			if i > 0 {
+				sum += math.Abs(float64(v - raw[i-1][j]))
+				pairs++
+			}
+		}
+	}
+	if pairs > 0 && maxRaw > minRaw {
+		c.Roughness = sum / float64(pairs) / float64(maxRaw-minRaw)
+	}
+
+	region := data[cfg.Offset : cfg.Offset+cfg.ByteSize()]
+	opcodes := 0
+	for _, b := range region {
+		if codeOpcodes[b] {
+			opcodes++
+		}
+	}
+	c.OpcodeShare = float64(opcodes) / float64(len(region))
+	c.LikelyCode = c.Roughness >= codeRoughness && c.OpcodeShare >= codeOpcodeShare
+	return c, nil
+}
@@ -0,0 +1,141 @@
+package scanner
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// DefaultMinVariance is the smallest value range (max - min) of a hit
+// when a profile doesn't set one, the threshold of the uint8 passes
+const DefaultMinVariance = 10
+
+// Profile is a named set of scanner parameters, saved in the settings
+// file so a tuning for, say, calibration areas needn't be retyped. Zero
+// fields mean the default: a stride of Stride, DefaultMinVariance, every
+// size and the whole file.
+type Profile struct {
+	Name string `json:"name"`
+	// Stride is the offset step; 1 tries every offset
+	Stride int `json:"stride,omitempty"`
+	// MinVariance drops hits whose value range is smaller
+	MinVariance float64 `json:"min_variance,omitempty"`
+	// Sizes keeps only hits of these shapes, e.g. "8x16"
+	Sizes []string `json:"sizes,omitempty"`
+	Range Range    `json:"range,omitzero"`
+}
+
+// BuiltinProfiles ship with the tool and can't be replaced or deleted
+var BuiltinProfiles = []Profile{
+	{Name: "quick", Stride: Stride},
+	{Name: "exhaustive", Stride: 1},
+}
+
+// IsBuiltinProfile reports whether name is one of BuiltinProfiles
+func IsBuiltinProfile(name string) bool {
+	return slices.ContainsFunc(BuiltinProfiles, func(p Profile) bool { return p.Name == name })
+}
+
+// FindProfile returns the built-in or saved profile called name
+func FindProfile(saved []Profile, name string) (Profile, error) {
+	for _, p := range slices.Concat(BuiltinProfiles, saved) {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, reader.NewError(reader.ErrNotFound, "no scan profile %q", name)
+}
+
+// Check validates a profile before it is saved
+func (p Profile) Check() error {
+	if strings.TrimSpace(p.Name) == "" || strings.ContainsAny(p.Name, " \t\n") {
+		return fmt.Errorf("invalid scan profile name %q: expected one word", p.Name)
+	}
+	if p.Stride < 0 {
+		return fmt.Errorf("scan profile %s: stride %d is negative", p.Name, p.Stride)
+	}
+	if p.MinVariance < 0 {
+		return fmt.Errorf("scan profile %s: min variance %g is negative", p.Name, p.MinVariance)
+	}
+	if _, err := ParseSizes(strings.Join(p.Sizes, ",")); err != nil {
+		return fmt.Errorf("scan profile %s: %w", p.Name, err)
+	}
+	if !p.Range.IsZero() && (p.Range.Start < 0 || p.Range.End <= p.Range.Start) {
+		return fmt.Errorf("scan profile %s: invalid range %s", p.Name, p.Range)
+	}
+	return nil
+}
+
+// ScanStride returns the stride to scan with
+func (p Profile) ScanStride() int {
+	if p.Stride <= 0 {
+		return Stride
+	}
+	return p.Stride
+}
+
+// ScanMinVariance returns the smallest value range of a hit
+func (p Profile) ScanMinVariance() float64 {
+	if p.MinVariance <= 0 {
+		return DefaultMinVariance
+	}
+	return p.MinVariance
+}
+
+// Filter keeps the results of the profile's sizes whose value range is at
+// least its min variance
+func (p Profile) Filter(results []ScanResult) []ScanResult {
+	minVariance := p.ScanMinVariance()
+	var kept []ScanResult
+	for _, result := range results {
+		size := fmt.Sprintf("%dx%d", result.Rows, result.Cols)
+		if len(p.Sizes) > 0 && !slices.Contains(p.Sizes, size) {
+			continue
+		}
+		if result.Max-result.Min >= minVariance {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// String summarizes the parameters, e.g. "stride 0x40, min variance 10,
+// sizes 8x16, range all"
+func (p Profile) String() string {
+	sizes := "all"
+	if len(p.Sizes) > 0 {
+		sizes = strings.Join(p.Sizes, ",")
+	}
+	return fmt.Sprintf("stride 0x%X, min variance %g, sizes %s, range %s", p.ScanStride(), p.ScanMinVariance(), sizes, p.Range)
+}
+
+// SizeNames are the map shapes the scanner looks for, as Sizes names them
+func SizeNames() []string {
+	names := make([]string, len(scanSizes))
+	for i, size := range scanSizes {
+		names[i] = fmt.Sprintf("%dx%d", size.rows, size.cols)
+	}
+	return names
+}
+
+// ParseSizes parses a comma-separated list of map shapes such as
+// "8x16,16x16". The empty string means every size and returns nil.
+func ParseSizes(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	known := SizeNames()
+	var sizes []string
+	for _, size := range strings.Split(s, ",") {
+		size = strings.ToLower(strings.TrimSpace(size))
+		if !slices.Contains(known, size) {
+			return nil, fmt.Errorf("unknown scan size %q: expected %s", size, strings.Join(known, ", "))
+		}
+		if !slices.Contains(sizes, size) {
+			sizes = append(sizes, size)
+		}
+	}
+	return sizes, nil
+}
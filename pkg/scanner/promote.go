@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// Candidate returns the map definition promoting the hit adds, named name:
+// its location, shape, data type and byte order, read raw (scale 1) until
+// it is calibrated. Axis vectors SuggestAxes found next to the map become
+// its axes with the suggested scaling; the description names the guesses
+// and their confidence. It is marked Unconfirmed, so presets leave it
+// alone and edits check it for program code.
+func (r ScanResult) Candidate(name string) models.MapConfig {
+	cfg := models.MapConfig{
+		Name:        name,
+		Offset:      int64(r.Offset),
+		Rows:        r.Rows,
+		Cols:        r.Cols,
+		DataType:    r.DataType,
+		Endianness:  r.ByteOrder(),
+		Scale:       1,
+		Unit:        "raw",
+		Description: fmt.Sprintf("Scan hit at 0x%04X", r.Offset),
+		Category:    models.CategoryExperimental,
+		Unconfirmed: true,
+		XAxis:       r.Axes.X.Config(r.Cols),
+		YAxis:       r.Axes.Y.Config(r.Rows),
+	}
+	if cfg.XAxis != nil || cfg.YAxis != nil {
+		cfg.Description += "; suggested axes " + r.Axes.String()
+	}
+	return cfg
+}
+
+// FindResult returns the scan hit at spec, an offset such as 0x6800 or an
+// offset and shape such as 0x6800:8x16 when several shapes were found
+// there. Without a shape the first (best ranked) hit at the offset wins.
+func FindResult(results []ScanResult, spec string) (ScanResult, error) {
+	at, shape, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	offset, err := strconv.ParseInt(at, 0, 64)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("invalid scan hit %q: want an offset such as 0x6800, optionally with a shape such as :8x16", spec)
+	}
+	for _, r := range results {
+		if int64(r.Offset) == offset && (shape == "" || strings.EqualFold(shape, fmt.Sprintf("%dx%d", r.Rows, r.Cols))) {
+			return r, nil
+		}
+	}
+	return ScanResult{}, fmt.Errorf("no scan hit at 0x%04X%s", offset, strings.TrimSuffix(" ("+shape+")", " ()"))
+}
@@ -1,20 +1,23 @@
 package scanner
 
 import (
-	"encoding/binary"
+	"context"
 	"fmt"
-	"io"
-	"os"
+	"time"
 
-	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+	"github.com/tosih/motronic-m21-tool/pkg/stats"
 )
 
 // ScanResult holds information about a potential map location
 type ScanResult struct {
-	Offset     int
-	Rows       int
-	Cols       int
-	DataType   string
+	Offset   int
+	Rows     int
+	Cols     int
+	DataType string
+	// Endianness is "LE" or "BE" for 16-bit hits and "N/A" for 8-bit ones
+	// (see ByteOrder)
 	Endianness string
 	Min        float64
 	Max        float64
@@ -22,68 +25,160 @@ type ScanResult struct {
 	StdDev     float64
 	Variance   float64
 	Preview    string
+	// Axes suggests what the map's axes measure, from vectors stored just
+	// before it
+	Axes AxisSuggestion
 }
 
-// ScanForMaps scans a binary file for potential map locations
-func ScanForMaps(filename string) {
-	spinner, _ := pterm.DefaultSpinner.Start("Scanning file for map locations...")
+// endiannessLabels are the ScanResult.Endianness of 16-bit hits
+var endiannessLabels = map[models.Endianness]string{
+	models.LittleEndian: "LE",
+	models.BigEndian:    "BE",
+}
 
-	f, err := os.Open(filename)
-	if err != nil {
-		spinner.Fail("Error opening file")
-		pterm.Error.Printf("Error: %v\n", err)
-		return
+// ByteOrder returns the byte order a map definition of the hit needs, or
+// "" for 8-bit hits, which have none
+func (r ScanResult) ByteOrder() models.Endianness {
+	for order, label := range endiannessLabels {
+		if r.Endianness == label {
+			return order
+		}
 	}
-	defer f.Close()
+	return ""
+}
 
-	data, err := io.ReadAll(f)
-	if err != nil {
-		spinner.Fail("Error reading file")
-		pterm.Error.Printf("Error: %v\n", err)
-		return
-	}
+// Stride is the offset step of a normal scan. An exhaustive scan uses a
+// stride of 1 and tries every offset.
+const Stride = 0x40
 
-	spinner.Success(fmt.Sprintf("File loaded: %d bytes (0x%X)", len(data), len(data)))
+// ScanBytes scans the contents of an ECU image for 8x8, 8x16 and 16x16
+// blocks of uint8 and uint16 (both byte orders) values with enough spread
+// to be maps. step, if not nil, is called after each size and type pass.
+func ScanBytes(data []byte, step func(pass string)) []ScanResult {
+	results, _ := ScanBytesFrom(context.Background(), data, &Checkpoint{Stride: Stride}, nil, step)
+	return results
+}
 
-	pterm.Println()
-	pterm.DefaultSection.Println("Potential Map Locations")
+// scanPass is one size and data type combination; uint16 passes try both
+// byte orders at each offset
+type scanPass struct {
+	rows, cols int
+	wide       bool
+}
 
-	var results []ScanResult
+func (p scanPass) String() string {
+	if p.wide {
+		return fmt.Sprintf("%dx%d uint16", p.rows, p.cols)
+	}
+	return fmt.Sprintf("%dx%d uint8", p.rows, p.cols)
+}
 
-	// Scan for 8x8, 8x16, and 16x16 patterns
-	sizes := []struct{ rows, cols int }{
-		{8, 8},
-		{8, 16},
-		{16, 16},
+// scanPasses returns the passes in the order they are scanned
+func scanPasses() []scanPass {
+	var passes []scanPass
+	for _, size := range scanSizes {
+		passes = append(passes, scanPass{size.rows, size.cols, false}, scanPass{size.rows, size.cols, true})
 	}
+	return passes
+}
 
-	// Try uint8 and uint16 with both endiannesses
-	for _, size := range sizes {
-		cellCount := size.rows * size.cols
+// PassCount is the number of passes a scan makes, for progress reporting
+func PassCount() int {
+	return len(scanSizes) * 2
+}
 
-		// Scan for uint8 values
-		for offset := 0; offset < len(data)-cellCount; offset += 0x40 {
-			if result := scanUint8(data, offset, size.rows, size.cols); result != nil {
-				results = append(results, *result)
-			}
+// checkpointEvery is how often, in offsets, a scan checks for cancellation
+// and whether a checkpoint is due
+const checkpointEvery = 1024
+
+// ScanBytesFrom runs the scan ScanBytes does with cp's stride and range,
+// continuing
+// from cp's pass and offset with the results found before them. cp is
+// updated as the scan proceeds and save, if not nil, is called with it
+// every CheckpointInterval. When ctx is canceled the scan stops at the
+// current offset, saves the checkpoint and returns the results so far with
+// ctx's error; continuing from that checkpoint gives the same results as an
+// uninterrupted scan.
+func ScanBytesFrom(ctx context.Context, data []byte, cp *Checkpoint, save func(*Checkpoint) error, step func(pass string)) ([]ScanResult, error) {
+	stride := cp.Stride
+	if stride <= 0 {
+		stride = Stride
+	}
+	start, end := cp.Range.bounds(data)
+	lastSave := time.Now()
+	passes := scanPasses()
+	found := func() []ScanResult {
+		results := append([]ScanResult(nil), cp.Results...)
+		suggestAxes(data, results)
+		return results
+	}
+
+	for ; cp.Pass < len(passes); cp.Pass, cp.Offset = cp.Pass+1, 0 {
+		p := passes[cp.Pass]
+		byteCount := p.rows * p.cols
+		if p.wide {
+			byteCount *= 2
 		}
 
-		// Scan for uint16 values (need 2 bytes per cell)
-		byteCount := cellCount * 2
-		for offset := 0; offset < len(data)-byteCount; offset += 0x40 {
-			// Try little-endian
-			if result := scanUint16(data, offset, size.rows, size.cols, binary.LittleEndian, "LE"); result != nil {
-				results = append(results, *result)
+		cp.Offset = max(cp.Offset, start)
+		for n := 0; cp.Offset < end-byteCount; cp.Offset, n = cp.Offset+stride, n+1 {
+			if n%checkpointEvery == 0 && n > 0 {
+				if err := ctx.Err(); err != nil {
+					return found(), saveCheckpoint(save, cp, err)
+				}
+				if save != nil && time.Since(lastSave) >= CheckpointInterval {
+					if err := save(cp); err != nil {
+						return found(), err
+					}
+					lastSave = time.Now()
+				}
 			}
-			// Try big-endian
-			if result := scanUint16(data, offset, size.rows, size.cols, binary.BigEndian, "BE"); result != nil {
-				results = append(results, *result)
+
+			if !p.wide {
+				if result := scanUint8(data, cp.Offset, p.rows, p.cols); result != nil {
+					cp.Results = append(cp.Results, *result)
+				}
+				continue
+			}
+			if result := scanUint16(data, cp.Offset, p.rows, p.cols, models.LittleEndian); result != nil {
+				cp.Results = append(cp.Results, *result)
+			}
+			if result := scanUint16(data, cp.Offset, p.rows, p.cols, models.BigEndian); result != nil {
+				cp.Results = append(cp.Results, *result)
 			}
 		}
+		if step != nil {
+			step(p.String())
+		}
+	}
+
+	return found(), nil
+}
+
+// saveCheckpoint saves an interrupted scan and returns the interruption
+// error, or the save error if saving failed
+func saveCheckpoint(save func(*Checkpoint) error, cp *Checkpoint, cause error) error {
+	if save == nil {
+		return cause
+	}
+	if err := save(cp); err != nil {
+		return fmt.Errorf("%w (checkpoint not saved: %v)", cause, err)
 	}
+	return cause
+}
 
-	// Display results in table
-	displayResults(results)
+// suggestAxes fills in the axis suggestions of each result
+func suggestAxes(data []byte, results []ScanResult) {
+	for i := range results {
+		results[i].Axes = SuggestAxes(data, results[i])
+	}
+}
+
+// scanSizes are the map shapes looked for, in rows x cols
+var scanSizes = []struct{ rows, cols int }{
+	{8, 8},
+	{8, 16},
+	{16, 16},
 }
 
 func scanUint8(data []byte, offset int, rows int, cols int) *ScanResult {
@@ -97,10 +192,10 @@ func scanUint8(data []byte, offset int, rows int, cols int) *ScanResult {
 		values[i] = float64(data[offset+i])
 	}
 
-	min, max, variance := calculateStats(values)
+	s := stats.Of(values)
 
 	// Check if variance is good enough
-	if (max-min) < 10 || max == 0 {
+	if (s.Max-s.Min) < 10 || s.Max == 0 {
 		return nil
 	}
 
@@ -116,14 +211,15 @@ func scanUint8(data []byte, offset int, rows int, cols int) *ScanResult {
 		Cols:       cols,
 		DataType:   "uint8",
 		Endianness: "N/A",
-		Min:        min,
-		Max:        max,
-		Variance:   variance,
+		Min:        s.Min,
+		Max:        s.Max,
+		Variance:   s.Variance,
 		Preview:    preview + "...",
 	}
 }
 
-func scanUint16(data []byte, offset int, rows int, cols int, byteOrder binary.ByteOrder, endianness string) *ScanResult {
+func scanUint16(data []byte, offset int, rows int, cols int, order models.Endianness) *ScanResult {
+	byteOrder := order.Binary()
 	cellCount := rows * cols
 	byteCount := cellCount * 2
 	if offset+byteCount > len(data) {
@@ -136,10 +232,10 @@ func scanUint16(data []byte, offset int, rows int, cols int, byteOrder binary.By
 		values[i] = float64(val)
 	}
 
-	min, max, variance := calculateStats(values)
+	s := stats.Of(values)
 
 	// Check if variance is good enough (higher threshold for uint16)
-	if (max-min) < 100 || max == 0 {
+	if (s.Max-s.Min) < 100 || s.Max == 0 {
 		return nil
 	}
 
@@ -155,106 +251,46 @@ func scanUint16(data []byte, offset int, rows int, cols int, byteOrder binary.By
 		Rows:       rows,
 		Cols:       cols,
 		DataType:   "uint16",
-		Endianness: endianness,
-		Min:        min,
-		Max:        max,
-		Variance:   variance,
+		Endianness: endiannessLabels[order],
+		Min:        s.Min,
+		Max:        s.Max,
+		Variance:   s.Variance,
 		Preview:    preview + "...",
 	}
 }
 
-func calculateStats(values []float64) (float64, float64, float64) {
-	if len(values) == 0 {
-		return 0, 0, 0
-	}
-
-	min := values[0]
-	max := values[0]
-	sum := 0.0
-
-	for _, v := range values {
-		if v < min {
-			min = v
-		}
-		if v > max {
-			max = v
-		}
-		sum += v
-	}
-
-	avg := sum / float64(len(values))
-
-	// Calculate variance
-	variance := 0.0
-	for _, v := range values {
-		diff := v - avg
-		variance += diff * diff
-	}
-	variance /= float64(len(values))
-
-	return min, max, variance
-}
-
-func displayResults(results []ScanResult) {
-	if len(results) == 0 {
-		pterm.Info.Println("No potential maps found")
-		return
-	}
-
-	tableData := pterm.TableData{
-		{"Offset", "Size", "Type", "Endian", "Min", "Max", "Variance", "Preview"},
-	}
-
-	for _, result := range results {
-		tableData = append(tableData, []string{
-			fmt.Sprintf("0x%04X", result.Offset),
-			fmt.Sprintf("%dx%d", result.Rows, result.Cols),
-			result.DataType,
-			result.Endianness,
-			fmt.Sprintf("%.0f", result.Min),
-			fmt.Sprintf("%.0f", result.Max),
-			fmt.Sprintf("%.1f", result.Variance),
-			result.Preview,
-		})
-	}
-
-	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
-	pterm.Info.Printf("\nFound %d potential map(s)\n", len(results))
-}
-
-// ScanFile scans a binary file and returns scan results (for GUI use)
-func ScanFile(filename string, minVariance float64) []ScanResult {
-	f, err := os.Open(filename)
+// ScanFile scans a binary file, or the range r of it, and returns scan
+// results (for GUI use)
+func ScanFile(filename string, minVariance float64, r Range) ([]ScanResult, error) {
+	data, err := reader.ReadBinary(filename)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	defer f.Close()
-
-	data, err := io.ReadAll(f)
-	if err != nil {
-		return nil
+	if err := r.Check(len(data)); err != nil {
+		return nil, err
 	}
+	return ScanBytesWithStats(data, minVariance, r), nil
+}
 
+// ScanBytesWithStats scans the contents of an ECU image, restricted to r,
+// for uint8 maps whose value range is at least minVariance, including mean
+// and spread
+func ScanBytesWithStats(data []byte, minVariance float64, r Range) []ScanResult {
 	var results []ScanResult
+	start, end := r.bounds(data)
 
-	// Scan for 8x8, 8x16, and 16x16 patterns
-	sizes := []struct{ rows, cols int }{
-		{8, 8},
-		{8, 16},
-		{16, 16},
-	}
-
-	for _, size := range sizes {
+	for _, size := range scanSizes {
 		cellCount := size.rows * size.cols
 
 		// Scan for uint8 values
-		for offset := 0; offset < len(data)-cellCount; offset += 0x40 {
+		for offset := start; offset < end-cellCount; offset += 0x40 {
 			if result := scanUint8WithStats(data, offset, size.rows, size.cols, minVariance); result != nil {
 				results = append(results, *result)
 			}
 		}
 	}
 
+	suggestAxes(data, results)
 	return results
 }
 
@@ -270,39 +306,24 @@ func scanUint8WithStats(data []byte, offset int, rows int, cols int, minVariance
 		values[i] = float64(data[offset+i])
 	}
 
-	min, max, variance := calculateStats(values)
+	s := stats.Of(values)
 
 	// Check if variance is good enough
-	if (max-min) < minVariance || max == 0 {
+	if (s.Max-s.Min) < minVariance || s.Max == 0 {
 		return nil
 	}
 
-	// Calculate mean and std dev
-	sum := 0.0
-	for _, v := range values {
-		sum += v
-	}
-	mean := sum / float64(len(values))
-
-	stddev := 0.0
-	for _, v := range values {
-		diff := v - mean
-		stddev += diff * diff
-	}
-	stddev = stddev / float64(len(values))
-	stddev = float64(int(stddev*10)) / 10 // Round to 1 decimal
-
 	return &ScanResult{
 		Offset:     offset,
 		Rows:       rows,
 		Cols:       cols,
 		DataType:   "uint8",
 		Endianness: "N/A",
-		Min:        min,
-		Max:        max,
-		Mean:       mean,
-		StdDev:     stddev,
-		Variance:   variance,
+		Min:        s.Min,
+		Max:        s.Max,
+		Mean:       s.Mean,
+		StdDev:     float64(int(s.Variance*10)) / 10, // Round to 1 decimal
+		Variance:   s.Variance,
 		Preview:    "",
 	}
 }
@@ -0,0 +1,119 @@
+//go:build !js
+
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/internal/progress"
+	"github.com/tosih/motronic-m21-tool/internal/tabular"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// ScanForMaps scans a binary file for potential map locations with the
+// stride and range of profile p, keeping the hits p's filter passes. The
+// scan saves checkpoints as it goes; when ctx is canceled (Ctrl+C) it
+// shows the results found so far and a rerun with resume set continues
+// where it stopped. It returns false if the scan failed or was
+// interrupted, along with the results found.
+func ScanForMaps(ctx context.Context, filename string, p Profile, resume bool) ([]ScanResult, bool) {
+	r := p.Range
+	spinner, _ := pterm.DefaultSpinner.Start("Scanning file for map locations...")
+
+	scan, err := OpenScan(filename, p.ScanStride(), r, resume)
+	if errors.Is(err, reader.ErrOutOfRange) {
+		spinner.Fail("Invalid scan range")
+		pterm.Error.Printf("Error: %v\n", err)
+		return nil, false
+	}
+	if err != nil {
+		spinner.Fail("Error reading file")
+		pterm.Error.Printf("Error: %v\n", err)
+		return nil, false
+	}
+
+	size := len(scan.data)
+	spinner.Success(fmt.Sprintf("File loaded: %d bytes (0x%X)", size, size))
+	if p.Name != "" {
+		pterm.Info.Printf("Scan profile %s: %s\n", p.Name, p)
+	}
+	if !r.IsZero() {
+		pterm.Info.Printf("Scanning %s only (%d bytes)\n", r, r.End-r.Start)
+	} else if layout := reader.DetectLayout(scan.data); layout.Mirrored() {
+		pterm.Info.Printf("Mirrored dump: scanning the first of %d copies of the image\n", layout.Copies)
+	}
+	if scan.Resumed {
+		pterm.Info.Printf("Resuming from checkpoint at %s with %d result(s)\n",
+			scan.Checkpoint.Position(), len(scan.Checkpoint.Results))
+	} else if resume {
+		pterm.Info.Println("No checkpoint for this file, stride and range, starting from the beginning")
+	}
+
+	pterm.Println()
+	pterm.DefaultSection.Println(i18n.T("cli.scan.section"))
+
+	bar := progress.Start("Scanning", PassCount()-scan.Checkpoint.Pass)
+	results, err := scan.Run(ctx, bar.Step)
+	bar.Stop()
+	results = p.Filter(results)
+
+	// Display results in table
+	displayResults(results, p)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			pterm.Warning.Printf("Scan interrupted at %s; checkpoint saved to %s\n", scan.Checkpoint.Position(), scan.Path)
+			pterm.Warning.Println("Rerun with -resume to continue")
+		} else {
+			pterm.Error.Printf("Scan failed: %v\n", err)
+		}
+		return results, false
+	}
+	return results, true
+}
+
+// ResultsTable returns one row per scan result of profile p, for display
+// or -format. The Range and Profile columns record the scanned range and
+// the profile's name, so output files of different scans can be told
+// apart; Profile is empty when no profile was selected.
+func ResultsTable(results []ScanResult, p Profile) *tabular.Table {
+	t := tabular.New("Offset", "Size", "Type", "Endian", "Min", "Max", "Variance", "Axes", "Preview", "Range", "Profile")
+	for _, result := range results {
+		t.Add(
+			fmt.Sprintf("0x%04X", result.Offset),
+			fmt.Sprintf("%dx%d", result.Rows, result.Cols),
+			result.DataType,
+			result.Endianness,
+			fmt.Sprintf("%.0f", result.Min),
+			fmt.Sprintf("%.0f", result.Max),
+			fmt.Sprintf("%.1f", result.Variance),
+			result.Axes.String(),
+			result.Preview,
+			p.Range.String(),
+			p.Name,
+		)
+	}
+	return t
+}
+
+func displayResults(results []ScanResult, p Profile) {
+	if len(results) == 0 {
+		pterm.Info.Println("No potential maps found")
+		return
+	}
+
+	ResultsTable(results, p).Render()
+	pterm.Info.Printf("\nFound %d potential map(s)\n", len(results))
+	pterm.Info.Println("Axes are guesses from adjacent byte vectors; \"none\" means the RPM/Load default")
+	for _, result := range results {
+		if result.ByteOrder() == models.BigEndian {
+			pterm.Info.Println("BE hits need \"endianness\": \"big\" in their map definition")
+			break
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// A disk image picked by mistake is refused before it is read
+func TestScanFileOversized(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.bin")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, 2<<30); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ScanFile(path, 0, Range{})
+	var tooLarge *reader.FileTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Errorf("ScanFile of a 2 GiB file: %v, want a FileTooLargeError", err)
+	}
+}
+
+// A stride-1 scan of the map area canceled in every pass and resumed from
+// its checkpoint each time finds exactly what an uninterrupted scan does
+func TestResumedScan(t *testing.T) {
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	data := testbin.Image()
+	r, err := ParseRange("0x6000:0x7FFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ScanBytesFrom(context.Background(), data, &Checkpoint{Stride: 1, Range: r}, nil, nil)
+	if err != nil || len(want) == 0 {
+		t.Fatalf("uninterrupted scan found %d results, %v", len(want), err)
+	}
+
+	var got []ScanResult
+	runs := 0
+	for resume := false; ; resume = true {
+		s, err := OpenScanBytes(data, 1, r, resume)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Resumed != resume {
+			t.Fatalf("run %d resumed %v", runs, s.Resumed)
+		}
+		// Cancel once a pass is done, so the scan stops partway into the next
+		ctx, cancel := context.WithCancel(context.Background())
+		got, err = s.Run(ctx, func(string) { cancel() })
+		cancel()
+		runs++
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal(err)
+		}
+		saved, loadErr := LoadCheckpoint(s.Path)
+		if loadErr != nil || saved == nil || saved.Offset == 0 {
+			t.Fatalf("checkpoint after run %d: %+v, %v", runs, saved, loadErr)
+		}
+		if runs > PassCount()+1 {
+			t.Fatal("the scan doesn't advance between runs")
+		}
+	}
+
+	if runs < 3 {
+		t.Errorf("the scan finished in %d runs, want it canceled at least twice", runs)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resumed scan found %d results, uninterrupted %d, or they differ", len(got), len(want))
+	}
+	if s, _ := OpenScanBytes(data, 1, r, true); s.Resumed {
+		t.Error("a completed scan left its checkpoint")
+	}
+}
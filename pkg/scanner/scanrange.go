@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// Range restricts a scan to the maps lying entirely inside a byte range.
+// End is exclusive. The zero Range scans the whole file.
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ParseRange parses a -scan-range value "from:to" with an inclusive end,
+// e.g. "0x6000:0x7FFF". Offsets may be hex (0x) or decimal.
+func ParseRange(s string) (Range, error) {
+	from, to, ok := strings.Cut(s, ":")
+	if !ok {
+		return Range{}, fmt.Errorf("invalid scan range %q: expected from:to, e.g. 0x6000:0x7FFF", s)
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(from), 0, 64)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid scan range start %q", from)
+	}
+	last, err := strconv.ParseInt(strings.TrimSpace(to), 0, 64)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid scan range end %q", to)
+	}
+	if start < 0 || last < start {
+		return Range{}, fmt.Errorf("invalid scan range %q: the end lies before the start", s)
+	}
+	return Range{Start: int(start), End: int(last) + 1}, nil
+}
+
+// IsZero reports whether r is the whole file
+func (r Range) IsZero() bool { return r == Range{} }
+
+// Check validates r against a file of size bytes
+func (r Range) Check(size int) error {
+	if r.IsZero() {
+		return nil
+	}
+	if r.Start < 0 || r.End <= r.Start || r.End > size {
+		return reader.NewError(reader.ErrOutOfRange, "scan range %s lies outside the file (0x0000-0x%04X)", r, size-1)
+	}
+	return nil
+}
+
+// bounds returns the first offset and the exclusive end of r in data.
+// The whole of a mirrored dump ends with its first copy, so each map is
+// found once.
+func (r Range) bounds(data []byte) (int, int) {
+	if r.IsZero() {
+		return 0, int(reader.DetectLayout(data).Extent(int64(len(data))))
+	}
+	return r.Start, min(r.End, len(data))
+}
+
+// String formats r with an inclusive end like ParseRange takes it, or
+// "all" for the whole file
+func (r Range) String() string {
+	if r.IsZero() {
+		return "all"
+	}
+	return fmt.Sprintf("0x%04X-0x%04X", r.Start, r.End-1)
+}
@@ -0,0 +1,48 @@
+// Package stats computes summary statistics of map and scan data. It has
+// no I/O or terminal dependencies so it also builds for the browser.
+package stats
+
+import "math"
+
+// Summary describes a set of values
+type Summary struct {
+	Count    int
+	Min      float64
+	Max      float64
+	Mean     float64
+	Variance float64 // population variance
+	StdDev   float64
+}
+
+// Of summarizes values. An empty slice gives a zero Summary.
+func Of(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+
+	s := Summary{Count: len(values), Min: values[0], Max: values[0]}
+	sum := 0.0
+	for _, v := range values {
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+		sum += v
+	}
+	s.Mean = sum / float64(len(values))
+
+	for _, v := range values {
+		d := v - s.Mean
+		s.Variance += d * d
+	}
+	s.Variance /= float64(len(values))
+	s.StdDev = math.Sqrt(s.Variance)
+	return s
+}
+
+// OfMap summarizes every cell of a map grid
+func OfMap(data [][]float64) Summary {
+	var values []float64
+	for _, row := range data {
+		values = append(values, row...)
+	}
+	return Of(values)
+}
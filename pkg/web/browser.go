@@ -5,6 +5,9 @@ import (
 	"runtime"
 )
 
+// OpenBrowser is called by Start with the server's URL; nil opens nothing
+var OpenBrowser = openBrowser
+
 // openBrowser tries to open the default browser with the given URL
 func openBrowser(url string) {
 	var err error
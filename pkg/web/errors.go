@@ -0,0 +1,67 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+// absPath matches an absolute Unix or Windows path at the start of a
+// message or after a space, quote, parenthesis or equals sign, so unit
+// names such as "km/h" are left alone. Directory names may contain
+// spaces, since a separator follows them; the last element ends at the
+// first space. Words followed by a colon, as in "file.bin: permission
+// denied", are never taken for directories.
+var absPath = regexp.MustCompile(`(^|[\s"'(=])((?:/|[A-Za-z]:\\)(?:[^\s"',;():/\\]+(?: [^\s"',;():/\\]+)*[/\\])*[^\s"',;()]*)`)
+
+// redactPaths replaces every absolute path in msg with its base name, so
+// error responses don't reveal the server's directory layout
+func redactPaths(msg string) string {
+	return absPath.ReplaceAllStringFunc(msg, func(match string) string {
+		parts := absPath.FindStringSubmatch(match)
+		// Windows paths are cut on any platform
+		path := strings.TrimRight(parts[2], `/\`)
+		return parts[1] + path[strings.LastIndexAny(path, `/\`)+1:]
+	})
+}
+
+// newErrorRef returns a short random reference tying an error response to
+// its server log line
+func newErrorRef() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writeError writes an error response of the given status. what says
+// what failed and err, if not nil, why. Client errors (4xx) show both,
+// with file paths reduced to base names. Server errors show only what
+// and a reference; the full error is logged on the server under the same
+// reference.
+func writeError(w http.ResponseWriter, r *http.Request, status int, what string, err error) {
+	msg := what
+	if err != nil {
+		detail := reader.DescribeWriteError(err)
+		if msg == "" {
+			msg = detail
+		} else {
+			msg += ": " + detail
+		}
+	}
+
+	if status < http.StatusInternalServerError {
+		http.Error(w, redactPaths(msg), status)
+		return
+	}
+	ref := newErrorRef()
+	pterm.Error.Printf("%s %s failed (ref %s): %s\n", r.Method, r.URL.Path, ref, msg)
+	if what == "" {
+		what = "Internal error"
+	}
+	http.Error(w, what+" (internal error, see the server log for ref "+ref+")", status)
+}
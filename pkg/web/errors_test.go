@@ -0,0 +1,59 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+)
+
+func TestRedactPaths(t *testing.T) {
+	tests := []struct {
+		msg, want string
+	}{
+		{"open /home/me/ecu.bin: permission denied", "open ecu.bin: permission denied"},
+		{"open /home/me/My ECU Files/ecu.bin: permission denied", "open ecu.bin: permission denied"},
+		{`Error reading "/srv/bins/a b/ecu.bin"`, `Error reading "ecu.bin"`},
+		{`open C:\Users\Jo Doe\ecu.bin: access denied`, "open ecu.bin: access denied"},
+		{"/var/tmp/x.bin is too large (40000 bytes)", "x.bin is too large (40000 bytes)"},
+		{"file=/data/ecu.bin", "file=ecu.bin"},
+		{"speed above 250 km/h", "speed above 250 km/h"},
+		{"Not an ECU image: notes.txt (expected a .bin file)", "Not an ECU image: notes.txt (expected a .bin file)"},
+		{"rename /a/ecu.bin.tmp /a/ecu.bin: busy", "rename ecu.bin.tmp ecu.bin: busy"},
+	}
+	for _, tt := range tests {
+		if got := redactPaths(tt.msg); got != tt.want {
+			t.Errorf("redactPaths(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fs.ErrNotExist, http.StatusNotFound},
+		{&fs.PathError{Op: "open", Path: "/a/ecu.bin", Err: syscall.ENOENT}, http.StatusNotFound},
+		{reader.NewError(reader.ErrNotFound, "parameter not found: Boost"), http.StatusNotFound},
+		{reader.NewError(reader.ErrOutOfRange, "map needs bytes"), http.StatusBadRequest},
+		{reader.NewError(reader.ErrValueOutOfBounds, "outside 0-10"), http.StatusBadRequest},
+		{reader.NewError(reader.ErrUnsupportedDataType, "unknown data type"), http.StatusBadRequest},
+		{fmt.Errorf("nudge: %w", reader.NewError(reader.ErrValueOutOfBounds, "outside 0-10")), http.StatusBadRequest},
+		{reader.NewError(reader.ErrMapLocked, "busy"), http.StatusConflict},
+		{&reader.WriteError{Path: "ecu.bin", Err: syscall.ETXTBSY}, http.StatusConflict},
+		{reader.NewError(reader.ErrLikelyCode, "looks like code"), http.StatusConflict},
+		{&reader.WriteError{Path: "ecu.bin", Err: fs.ErrPermission}, http.StatusForbidden},
+		{reader.NewError(reader.ErrInvalidDefinition, "zero scale"), http.StatusInternalServerError},
+		{errors.New("disk on fire"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := errorStatus(tt.err); got != tt.want {
+			t.Errorf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -1,17 +1,28 @@
 package web
 
 import (
+	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pterm/pterm"
+	"github.com/tosih/motronic-m21-tool/internal/i18n"
+	"github.com/tosih/motronic-m21-tool/pkg/compare"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
 	"github.com/tosih/motronic-m21-tool/pkg/models"
 	"github.com/tosih/motronic-m21-tool/pkg/reader"
 )
@@ -27,12 +38,72 @@ type MapResponse struct {
 	Unit     string      `json:"unit"`
 	Data     [][]float64 `json:"data"`
 	Filename string      `json:"filename"`
+	LoadAxis []string    `json:"loadAxis"`
+	// XAxis and YAxis are the breakpoints read from the binary, which
+	// MapCanvas shows instead of the synthetic RPM and load labels
+	XAxis        []float64 `json:"xAxis,omitempty"`
+	YAxis        []float64 `json:"yAxis,omitempty"`
+	AxisWarnings []string  `json:"axisWarnings,omitempty"`
+	// XLabel and YLabel caption the map's own axes, e.g. "Temp"; empty
+	// for the synthetic RPM and load axes
+	XLabel string `json:"xLabel,omitempty"`
+	YLabel string `json:"yLabel,omitempty"`
+
+	HighlightBelow *float64         `json:"highlightBelow,omitempty"`
+	NudgeStep      string           `json:"nudgeStep"`
+	Scale          models.HeatScale `json:"scale"`
+	ScaleLabel     string           `json:"scaleLabel"`
+}
+
+// NudgeRequest moves one cell of map index Map by Steps nudge steps
+type NudgeRequest struct {
+	File  string `json:"file"`
+	Map   int    `json:"map"`
+	Row   int    `json:"row"`
+	Col   int    `json:"col"`
+	Steps int    `json:"steps"`
+}
+
+// TransformRequest adds to, multiplies or sets the cells of Region (the
+// whole map if nil) of map index Map. With DryRun set only the preview is
+// returned.
+type TransformRequest struct {
+	File   string             `json:"file"`
+	Map    int                `json:"map"`
+	Op     string             `json:"op"`
+	Value  float64            `json:"value"`
+	Region *editor.CellRegion `json:"region,omitempty"`
+	DryRun bool               `json:"dryRun"`
+}
+
+// TransformResponse is the preview of a transform and, once written, the
+// map's new values
+type TransformResponse struct {
+	Changes int         `json:"changes"`
+	Cells   int         `json:"cells"`
+	Min     float64     `json:"min"`
+	Max     float64     `json:"max"`
+	Clamped int         `json:"clamped"`
+	Written bool        `json:"written"`
+	Data    [][]float64 `json:"data,omitempty"`
+	// Checksum describes a checksum the write left stale
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// ChecksumFixRequest asks to store the computed image checksum of File
+type ChecksumFixRequest struct {
+	File string `json:"file"`
 }
 
 type Server struct {
 	binFolder string
 	binFiles  []string
 	port      int
+
+	// stateMu serializes project file saves and loads
+	stateMu sync.Mutex
+	// files holds the images read by handlers, reloaded when they change
+	files reader.ECUFiles
 }
 
 func NewServer(filename string, port int) *Server {
@@ -72,9 +143,12 @@ func NewServer(filename string, port int) *Server {
 func NewCompareServer(filename1, filename2 string, port int) *Server {
 	// For compare mode, use the directory of the first file
 	binFolder := filepath.Dir(filename1)
-	binFiles, err := findBinFiles(binFolder)
-	if err != nil {
-		binFiles = []string{filename1, filename2}
+	binFiles, _ := findBinFiles(binFolder)
+	// The second file may live elsewhere; it is served too
+	for _, file := range []string{filename1, filename2} {
+		if !slices.ContainsFunc(binFiles, func(f string) bool { return filepath.Clean(f) == filepath.Clean(file) }) {
+			binFiles = append(binFiles, file)
+		}
 	}
 
 	return &Server{
@@ -101,14 +175,36 @@ func findBinFiles(dir string) ([]string, error) {
 	return binFiles, nil
 }
 
-func (s *Server) Start() error {
-	http.HandleFunc("/", s.handleIndex)
-	http.HandleFunc("/api/files", s.handleFileList)
-	http.HandleFunc("/api/config", s.handleConfigData)
-	http.HandleFunc("/api/config/update", s.handleConfigUpdate)
-	http.HandleFunc("/api/map/", s.handleMapData)
-	http.HandleFunc("/api/compare/", s.handleCompareData)
-	http.HandleFunc("/api/mode", s.handleMode)
+// ShutdownTimeout bounds how long Start waits for in-flight requests, such
+// as an edit being written, after it is told to stop
+var ShutdownTimeout = 10 * time.Second
+
+// Start serves the web interface until ctx is canceled, then stops
+// accepting connections, waits up to ShutdownTimeout for running handlers
+// to finish their writes and removes temporary files a killed write may
+// have left next to the binaries. If the wait times out the temporary
+// files are kept, since an abandoned handler may still be writing. It
+// returns nil after a clean or timed-out shutdown.
+func (s *Server) Start(ctx context.Context) error {
+	static, err := fs.Sub(templates, "templates/static")
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+	mux.HandleFunc("/api/files", s.handleFileList)
+	mux.HandleFunc("/api/maps", s.handleMapList)
+	mux.HandleFunc("/api/config", s.handleConfigData)
+	mux.HandleFunc("/api/config/update", s.handleConfigUpdate)
+	mux.HandleFunc("/api/map/", s.handleMapData)
+	mux.HandleFunc("/api/map/nudge", s.handleMapNudge)
+	mux.HandleFunc("/api/map/transform", s.handleMapTransform)
+	mux.HandleFunc("/api/checksum/fix", s.handleChecksumFix)
+	mux.HandleFunc("/api/compare/", s.handleCompareData)
+	mux.HandleFunc("/api/mode", s.handleMode)
+	mux.HandleFunc("/api/state", s.handleState)
 
 	addr := fmt.Sprintf(":%d", s.port)
 	url := fmt.Sprintf("http://localhost%s", addr)
@@ -116,22 +212,51 @@ func (s *Server) Start() error {
 	pterm.DefaultHeader.WithFullWidth().
 		WithBackgroundStyle(pterm.NewStyle(pterm.BgCyan)).
 		WithTextStyle(pterm.NewStyle(pterm.FgBlack)).
-		Println("🌐 ECU Web Viewer Started")
+		Println(i18n.T("cli.web.header"))
 
-	pterm.Info.Printf("Opening web interface at %s\n", url)
-	pterm.Info.Println("Press Ctrl+C to stop the server")
+	pterm.Info.Print(i18n.T("cli.web.opening", url))
+	pterm.Info.Println(i18n.T("cli.web.stop"))
 	pterm.Println()
 
 	// Try to open browser
-	openBrowser(url)
+	if OpenBrowser != nil {
+		OpenBrowser(url)
+	}
 
 	server := &http.Server{
 		Addr:         addr,
+		Handler:      mux,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	return server.ListenAndServe()
+	served := make(chan error, 1)
+	go func() { served <- server.ListenAndServe() }()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	pterm.Info.Println(i18n.T("cli.web.stopping"))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Handlers still running are abandoned and may be inside a session
+		// write, whose temp file can't be told from a stale one, so the
+		// temp files are left for a later clean shutdown
+		pterm.Warning.Println(i18n.T("cli.web.shutdown_timeout", ShutdownTimeout))
+		pterm.Success.Println(i18n.T("cli.web.stopped"))
+		return nil
+	}
+	for _, file := range s.binFiles {
+		for _, tmp := range editor.RemoveStaleTemps(file) {
+			pterm.Info.Println(i18n.T("cli.web.removed_temp", filepath.Base(tmp)))
+		}
+	}
+	pterm.Success.Println(i18n.T("cli.web.stopped"))
+	return err
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -142,7 +267,7 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 	content, err := templates.ReadFile("templates/index.html")
 	if err != nil {
-		http.Error(w, "Template not found", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Template not found", nil)
 		return
 	}
 
@@ -154,15 +279,178 @@ func (s *Server) handleFileList(w http.ResponseWriter, r *http.Request) {
 	fileList := make([]map[string]string, len(s.binFiles))
 	for i, fullPath := range s.binFiles {
 		fileList[i] = map[string]string{
-			"path": fullPath,
+			"id":   fileID(fullPath),
 			"name": filepath.Base(fullPath),
 		}
+		if id, err := reader.IdentifyBinary(fullPath); err == nil {
+			fileList[i]["part_number"] = id.PartNumber
+			fileList[i]["bosch_number"] = id.BoschNumber
+			fileList[i]["software_version"] = id.SoftwareVersion
+			fileList[i]["label"] = id.Label()
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(fileList)
 }
 
+// handleMapList lists the active map definitions, built-in and loaded with
+// -maps, in the order /api/map/{idx} addresses them. A profile parameter
+// lists the maps of that profile instead.
+func (s *Server) handleMapList(w http.ResponseWriter, r *http.Request) {
+	configs := models.MapConfigs
+	if name := r.URL.Query().Get("profile"); name != "" {
+		p, ok := models.FindProfile(name)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, fmt.Sprintf("Unknown profile %q", name), nil)
+			return
+		}
+		configs = p.MapConfigs
+	}
+	maps := make([]map[string]interface{}, len(configs))
+	for i, cfg := range configs {
+		maps[i] = map[string]interface{}{
+			"index":    i,
+			"name":     cfg.Name,
+			"offset":   cfg.Offset,
+			"rows":     cfg.Rows,
+			"cols":     cfg.Cols,
+			"unit":     cfg.Unit,
+			"source":   cfg.Source,
+			"category": cfg.CategoryName(),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maps)
+}
+
+// fileID is how pages refer to a served binary: a hash of its cleaned
+// absolute path, so responses don't reveal where the server keeps it and
+// files with the same name in different folders get different IDs
+func fileID(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(path)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// servedFile returns the served binary that name refers to, by its ID
+// or its path. Requests may only touch the binaries the server
+// lists, so anything else is refused with 403 Forbidden.
+func (s *Server) servedFile(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	if name == "" {
+		writeError(w, r, http.StatusBadRequest, "File parameter required", nil)
+		return "", false
+	}
+	for _, file := range s.binFiles {
+		if name == fileID(file) || filepath.Clean(name) == filepath.Clean(file) {
+			return file, true
+		}
+	}
+	writeError(w, r, http.StatusForbidden, fmt.Sprintf("Not a served file: %s", filepath.Base(name)), nil)
+	return "", false
+}
+
+// checkFile rejects requested files that are not .bin images or exceed the
+// size limit, writing the HTTP error itself. It reports whether the file
+// may be read.
+func checkFile(w http.ResponseWriter, r *http.Request, filename string) bool {
+	if !strings.EqualFold(filepath.Ext(filename), ".bin") {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Not an ECU image: %s (expected a .bin file)", filepath.Base(filename)), nil)
+		return false
+	}
+
+	err := reader.CheckFileSize(filename)
+	var tooLarge *reader.FileTooLargeError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, r, http.StatusRequestEntityTooLarge, "", err)
+		return false
+	case err != nil:
+		writeError(w, r, errorStatus(err), "Error reading file", err)
+		return false
+	}
+	return true
+}
+
+// profileDefinitions returns the maps and parameters of the profile named
+// by the request's profile parameter, "auto" to detect it from f, or the
+// active definitions without one, moved to the image of f. It writes the
+// HTTP error itself if the profile is unknown or can't be detected.
+func profileDefinitions(w http.ResponseWriter, r *http.Request, f *reader.ECUFile) ([]models.MapConfig, []models.ConfigParam, bool) {
+	name := r.URL.Query().Get("profile")
+	var p models.Profile
+	var ok bool
+	switch {
+	case name == "":
+		maps, params := locatedDefinitions(f, models.MapConfigs, models.ConfigParams, models.BaseOffset)
+		return maps, params, true
+	case strings.EqualFold(name, "auto"):
+		if p, ok = models.DetectProfile(f.Bytes()); !ok {
+			writeError(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("No single profile matches %s", filepath.Base(f.Path())), nil)
+			return nil, nil, false
+		}
+	default:
+		if p, ok = models.FindProfile(name); !ok {
+			writeError(w, r, http.StatusNotFound, fmt.Sprintf("Unknown profile %q", name), nil)
+			return nil, nil, false
+		}
+	}
+	maps, params := locatedDefinitions(f, p.MapConfigs, p.ConfigParams, 0)
+	return maps, params, true
+}
+
+// locatedDefinitions returns copies of maps and params, written for an
+// image starting at base, moved to the image of f
+func locatedDefinitions(f *reader.ECUFile, maps []models.MapConfig, params []models.ConfigParam, base int64) ([]models.MapConfig, []models.ConfigParam) {
+	shift := f.Layout().BaseOffset - base
+	located := make([]models.MapConfig, len(maps))
+	for i, cfg := range maps {
+		located[i] = cfg.Relocate(shift)
+	}
+	locatedParams := make([]models.ConfigParam, len(params))
+	for i, p := range params {
+		p.Offset += shift
+		locatedParams[i] = p
+	}
+	return located, locatedParams
+}
+
+// errorStatus maps an error from reader or editor to an HTTP status:
+// bad requests for invalid offsets and values, not found for missing
+// files and names, conflict for locked files and forbidden for read-only
+// ones. Anything else is a server error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, reader.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, reader.ErrOutOfRange), errors.Is(err, reader.ErrValueOutOfBounds),
+		errors.Is(err, reader.ErrUnsupportedDataType):
+		return http.StatusBadRequest
+	case errors.Is(err, reader.ErrMapLocked), errors.Is(err, reader.ErrLikelyCode):
+		return http.StatusConflict
+	case errors.Is(err, reader.ErrReadOnly):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// openFile returns the loaded image of filename, writing the HTTP error
+// itself if it can't be read
+func (s *Server) openFile(w http.ResponseWriter, r *http.Request, filename string) (*reader.ECUFile, bool) {
+	f, err := s.files.Open(filename)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Error reading file", err)
+		return nil, false
+	}
+	return f, true
+}
+
 func (s *Server) handleConfigData(w http.ResponseWriter, r *http.Request) {
 	// Get filename from query parameter
 	filename := r.URL.Query().Get("file")
@@ -170,22 +458,30 @@ func (s *Server) handleConfigData(w http.ResponseWriter, r *http.Request) {
 		if len(s.binFiles) > 0 {
 			filename = s.binFiles[0]
 		} else {
-			http.Error(w, "No bin files available", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "No bin files available", nil)
 			return
 		}
 	}
+	filename, ok := s.servedFile(w, r, filename)
+	if !ok || !checkFile(w, r, filename) {
+		return
+	}
 
-	// Read config parameters
-	config, err := reader.ReadConfigParams(filename)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error reading config: %v", err), http.StatusInternalServerError)
+	f, ok := s.openFile(w, r, filename)
+	if !ok {
 		return
 	}
+	_, params, ok := profileDefinitions(w, r, f)
+	if !ok {
+		return
+	}
+	config := reader.ReadParamsFromBytes(f.Bytes(), params)
 
 	// Build response with params and values
 	response := map[string]interface{}{
 		"params":   config.Params,
 		"values":   config.Values,
+		"errors":   paramErrors(config),
 		"filename": filepath.Base(filename),
 	}
 
@@ -193,40 +489,73 @@ func (s *Server) handleConfigData(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// paramErrors returns the messages of the parameters that couldn't be
+// read, by name, for the errors field of the config responses
+func paramErrors(config *models.ECUConfig) map[string]string {
+	errs := make(map[string]string, len(config.Errors))
+	for name, err := range config.Errors {
+		errs[name] = err.Error()
+	}
+	return errs
+}
+
 func (s *Server) handleMapData(w http.ResponseWriter, r *http.Request) {
 	// Extract map index from URL path
 	idxStr := r.URL.Path[len("/api/map/"):]
 	idx, err := strconv.Atoi(idxStr)
-	if err != nil || idx < 0 || idx >= len(models.MapConfigs) {
-		http.Error(w, "Invalid map index", http.StatusBadRequest)
+	if err != nil || idx < 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid map index", nil)
 		return
 	}
 
-	cfg := models.MapConfigs[idx]
-
-	// Check for custom offset in query parameters
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		var customOffset int64
-		if _, err := fmt.Sscanf(offsetStr, "0x%x", &customOffset); err == nil {
-			cfg.Offset = customOffset
-		}
-	}
-
 	// Get filename from query parameter, or use first file
 	filename := r.URL.Query().Get("file")
 	if filename == "" {
 		if len(s.binFiles) > 0 {
 			filename = s.binFiles[0]
 		} else {
-			http.Error(w, "No bin files available", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "No bin files available", nil)
 			return
 		}
 	}
+	filename, ok := s.servedFile(w, r, filename)
+	if !ok || !checkFile(w, r, filename) {
+		return
+	}
+
+	f, ok := s.openFile(w, r, filename)
+	if !ok {
+		return
+	}
+	maps, _, ok := profileDefinitions(w, r, f)
+	if !ok {
+		return
+	}
+	if idx >= len(maps) {
+		writeError(w, r, http.StatusBadRequest, "Invalid map index", nil)
+		return
+	}
+	cfg := maps[idx]
+
+	// Custom shapes and offsets must keep the whole map inside the file
+	q := r.URL.Query()
+	cfg, rangeErr := checkShape(q, cfg, f.Size())
+	switch {
+	case rangeErr != nil:
+	case q.Get("offset") != "":
+		cfg.Offset, rangeErr = checkOffset(q.Get("offset"), cfg, f.Size())
+	case hasShapeOverride(q):
+		rangeErr = checkOffsetValue(cfg.Offset, cfg, f.Size())
+	}
+	if rangeErr != nil {
+		writeRangeError(w, rangeErr)
+		return
+	}
 
 	// Read the map
-	ecuMap, err := reader.ReadMap(filename, cfg)
+	ecuMap, err := f.ReadMap(cfg)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error reading map: %v", err), http.StatusInternalServerError)
+		writeError(w, r, errorStatus(err), "Error reading map", err)
 		return
 	}
 
@@ -238,40 +567,243 @@ func (s *Server) handleMapData(w http.ResponseWriter, r *http.Request) {
 		Unit:     cfg.Unit,
 		Data:     ecuMap.Data,
 		Filename: filepath.Base(filename),
+		LoadAxis: cfg.LoadLabels(),
+		XAxis:    ecuMap.XAxis,
+		YAxis:    ecuMap.YAxis,
+
+		AxisWarnings:   ecuMap.AxisWarnings(),
+		HighlightBelow: cfg.HighlightBelow,
+		NudgeStep:      cfg.StepLabel(),
 	}
+	response.XLabel, response.YLabel = axisLabels(cfg)
+	scale := cfg.HeatScale(ecuMap.Data)
+	response.Scale, response.ScaleLabel = scale, scale.Label()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// axisLabels returns the captions of the axes cfg defines, empty for the
+// synthetic RPM and load axes
+func axisLabels(cfg models.MapConfig) (x, y string) {
+	if cfg.XAxis != nil {
+		x = cfg.XAxisName()
+	}
+	if cfg.YAxis != nil {
+		y = cfg.YAxisName()
+	}
+	return x, y
+}
+
+// RangeError is the JSON body returned with 422 when a map override
+// parameter is malformed or would read outside the file. Min and Max are
+// the valid range of the parameter; dtype lists its Allowed values
+// instead.
+type RangeError struct {
+	Error   string   `json:"error"`
+	Param   string   `json:"param"`
+	Min     int64    `json:"min"`
+	Max     int64    `json:"max"`
+	Allowed []string `json:"allowed,omitempty"`
+}
+
+// maxOverrideDim is the largest rows or cols override, the limit of the
+// map wizard
+const maxOverrideDim = 32
+
+// hasShapeOverride reports whether q overrides the rows, cols or dtype
+// of a map
+func hasShapeOverride(q url.Values) bool {
+	return q.Get("rows") != "" || q.Get("cols") != "" || q.Get("dtype") != ""
+}
+
+// checkShape applies the rows, cols and dtype override parameters to cfg.
+// Each must be valid on its own, and an overridden shape that can't fit
+// in a file of size bytes is refused whatever the offset. Axes no longer
+// match a changed dimension, so they are dropped with it.
+func checkShape(q url.Values, cfg models.MapConfig, size int64) (models.MapConfig, *RangeError) {
+	if !hasShapeOverride(q) {
+		return cfg, nil
+	}
+	if dtype := q.Get("dtype"); dtype != "" {
+		if !models.KnownDataType(dtype) {
+			return cfg, &RangeError{
+				Error:   fmt.Sprintf("dtype %q is not one of %s", dtype, strings.Join(models.DataTypes, ", ")),
+				Param:   "dtype",
+				Allowed: models.DataTypes,
+			}
+		}
+		cfg.DataType = dtype
+	}
+	for _, dim := range []struct {
+		param string
+		value *int
+		axis  **models.AxisConfig
+	}{{"rows", &cfg.Rows, &cfg.YAxis}, {"cols", &cfg.Cols, &cfg.XAxis}} {
+		str := q.Get(dim.param)
+		if str == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(str))
+		if err != nil || n < 1 || n > maxOverrideDim {
+			return cfg, &RangeError{
+				Error: fmt.Sprintf("%s %q is not a whole number from 1 to %d", dim.param, str, maxOverrideDim),
+				Param: dim.param,
+				Min:   1,
+				Max:   maxOverrideDim,
+			}
+		}
+		*dim.value, *dim.axis = n, nil
+	}
+	if cfg.ByteSize() > size {
+		return cfg, &RangeError{
+			Error: fmt.Sprintf("%dx%d %s (%d bytes) does not fit in the file (%d bytes)", cfg.Rows, cfg.Cols, cfg.DataType, cfg.ByteSize(), size),
+			Param: "rows",
+			Min:   1,
+			Max:   min(maxOverrideDim, size/int64(cfg.Cols*models.DataTypeSize(cfg.DataType))),
+		}
+	}
+	return cfg, nil
+}
+
+// checkOffset parses a custom map offset, given in hex with or without a
+// 0x prefix, and checks that the map fits in a file of size bytes there
+func checkOffset(value string, cfg models.MapConfig, size int64) (int64, *RangeError) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(value), "0x"), "0X")
+	if strings.HasPrefix(digits, "-") {
+		return 0, offsetRangeError(cfg, size, "offset %q is negative", value)
+	}
+	offset, err := strconv.ParseInt(digits, 16, 64)
+	if err != nil {
+		return 0, offsetRangeError(cfg, size, "offset %q is not a hex number", value)
+	}
+	return offset, checkOffsetValue(offset, cfg, size)
+}
+
+// checkOffsetValue checks that cfg fits in a file of size bytes at offset
+func checkOffsetValue(offset int64, cfg models.MapConfig, size int64) *RangeError {
+	if offset+cfg.ByteSize() <= size {
+		return nil
+	}
+	return offsetRangeError(cfg, size, "%s at offset 0x%04X needs bytes 0x%04X-0x%04X but file is only 0x%X bytes", cfg.Name, offset, offset, offset+cfg.ByteSize(), size)
+}
+
+// offsetRangeError returns the offset RangeError for cfg in a file of size
+// bytes, its message followed by the valid offsets
+func offsetRangeError(cfg models.MapConfig, size int64, format string, args ...interface{}) *RangeError {
+	maxOffset := size - cfg.ByteSize()
+	msg := fmt.Sprintf(format, args...)
+	if maxOffset < 0 {
+		msg += fmt.Sprintf("; %s (%d bytes) does not fit in the file (%d bytes)", cfg.Name, cfg.ByteSize(), size)
+	} else {
+		msg += fmt.Sprintf("; valid offsets for %s are 0x0000-0x%04X", cfg.Name, maxOffset)
+	}
+	return &RangeError{Error: msg, Param: "offset", Min: 0, Max: maxOffset}
+}
+
+// writeRangeError writes a RangeError as a 422 JSON response
+func writeRangeError(w http.ResponseWriter, e *RangeError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(e)
+}
+
+// handleMode describes the server. version is the part number and
+// software version of the file parameter, or of the first file, and
+// "unknown" if it has neither.
 func (s *Server) handleMode(w http.ResponseWriter, r *http.Request) {
+	version := models.ECUVersion{}
+	filename := r.URL.Query().Get("file")
+	if filename == "" && len(s.binFiles) > 0 {
+		filename = s.binFiles[0]
+	}
+	if filename != "" {
+		var ok bool
+		if filename, ok = s.servedFile(w, r, filename); !ok || !checkFile(w, r, filename) {
+			return
+		}
+		var err error
+		if version, err = reader.IdentifyECU(filename); err != nil {
+			writeError(w, r, errorStatus(err), "Error reading file", err)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"mode":      "multi",
-		"binFolder": s.binFolder,
+		"binFolder": filepath.Base(s.binFolder),
 		"fileCount": len(s.binFiles),
+		"version":   version.String(),
 	})
 }
 
+// CompareResponse is one map of a comparison. Status is "ok", or
+// "skipped" with Reason set and no data when the map lies outside one of
+// the files.
 type CompareResponse struct {
 	Name      string      `json:"name"`
 	Offset    int64       `json:"offset"`
 	Rows      int         `json:"rows"`
 	Cols      int         `json:"cols"`
 	Unit      string      `json:"unit"`
-	Data1     [][]float64 `json:"data1"`
-	Data2     [][]float64 `json:"data2"`
-	Diff      [][]float64 `json:"diff"`
+	Status    string      `json:"status"`
+	Reason    string      `json:"reason,omitempty"`
+	Data1     [][]float64 `json:"data1,omitempty"`
+	Data2     [][]float64 `json:"data2,omitempty"`
+	Diff      [][]float64 `json:"diff,omitempty"`
+	LoadAxis  []string    `json:"loadAxis"`
+	XAxis     []float64   `json:"xAxis,omitempty"`
+	YAxis     []float64   `json:"yAxis,omitempty"`
+	XLabel    string      `json:"xLabel,omitempty"`
+	YLabel    string      `json:"yLabel,omitempty"`
+	Tolerance float64     `json:"tolerance"`
 	Filename1 string      `json:"filename1"`
 	Filename2 string      `json:"filename2"`
+	Size1     int64       `json:"size1"`
+	Size2     int64       `json:"size2"`
+	Base1     int64       `json:"base1"`
+	Base2     int64       `json:"base2"`
+
+	// Raw is set for raw=true: values are unscaled raw values (see
+	// compare.RawConfig)
+	Raw bool `json:"raw"`
+	// DefinitionsDiffer is set when the files were saved with different
+	// map definitions
+	DefinitionsDiffer bool `json:"definitionsDiffer"`
+
+	// Scale colors both files alike and DeltaScale the difference map
+	// (see compare.Scales)
+	Scale      models.HeatScale `json:"scale"`
+	ScaleLabel string           `json:"scaleLabel"`
+	DeltaScale models.HeatScale `json:"deltaScale"`
+}
+
+// CompareParamResponse is one configuration parameter that differs
+// between the compared files
+type CompareParamResponse struct {
+	Name         string  `json:"name"`
+	Offset       int64   `json:"offset"`
+	Unit         string  `json:"unit"`
+	Value1       float64 `json:"value1"`
+	Value2       float64 `json:"value2"`
+	Raw1         int64   `json:"raw1"`
+	Raw2         int64   `json:"raw2"`
+	Implausible1 bool    `json:"implausible1"`
+	Implausible2 bool    `json:"implausible2"`
+	Error        string  `json:"error,omitempty"`
 }
 
 func (s *Server) handleCompareData(w http.ResponseWriter, r *http.Request) {
 	// Extract map index from URL path
 	idxStr := r.URL.Path[len("/api/compare/"):]
+	if idxStr == "params" {
+		s.handleCompareParams(w, r)
+		return
+	}
 	idx, err := strconv.Atoi(idxStr)
 	if err != nil || idx < 0 || idx >= len(models.MapConfigs) {
-		http.Error(w, "Invalid map index", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid map index", nil)
 		return
 	}
 
@@ -280,42 +812,101 @@ func (s *Server) handleCompareData(w http.ResponseWriter, r *http.Request) {
 	file2 := r.URL.Query().Get("file2")
 
 	if file1 == "" || file2 == "" {
-		http.Error(w, "Both file1 and file2 parameters required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Both file1 and file2 parameters required", nil)
 		return
 	}
 
-	cfg := models.MapConfigs[idx]
-
-	// Read both maps
-	ecuMap1, err1 := reader.ReadMap(file1, cfg)
-	ecuMap2, err2 := reader.ReadMap(file2, cfg)
-
-	if err1 != nil || err2 != nil {
-		http.Error(w, fmt.Sprintf("Error reading maps: %v, %v", err1, err2), http.StatusInternalServerError)
+	file1, ok := s.servedFile(w, r, file1)
+	if !ok {
+		return
+	}
+	if file2, ok = s.servedFile(w, r, file2); !ok {
+		return
+	}
+	if !checkFile(w, r, file1) || !checkFile(w, r, file2) {
 		return
 	}
 
-	// Calculate differences
-	diff := make([][]float64, cfg.Rows)
-	for i := 0; i < cfg.Rows; i++ {
-		diff[i] = make([]float64, cfg.Cols)
-		for j := 0; j < cfg.Cols; j++ {
-			diff[i][j] = ecuMap2.Data[i][j] - ecuMap1.Data[i][j]
-		}
+	cfg := models.MapConfigs[idx]
+	raw := r.URL.Query().Get("raw") == "true"
+	if raw {
+		cfg = compare.RawConfig(cfg)
 	}
 
+	align, err := compare.Align(file1, file2)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Error identifying files", err)
+		return
+	}
 	response := CompareResponse{
 		Name:      cfg.Name,
 		Offset:    cfg.Offset,
 		Rows:      cfg.Rows,
 		Cols:      cfg.Cols,
 		Unit:      cfg.Unit,
-		Data1:     ecuMap1.Data,
-		Data2:     ecuMap2.Data,
-		Diff:      diff,
+		Status:    "ok",
+		LoadAxis:  cfg.LoadLabels(),
 		Filename1: filepath.Base(file1),
 		Filename2: filepath.Base(file2),
+		Size1:     align.Size1,
+		Size2:     align.Size2,
+		Base1:     align.Base1,
+		Base2:     align.Base2,
+
+		Raw:               raw,
+		DefinitionsDiffer: align.DefinitionsDiffer(),
+	}
+
+	// Maps outside the shorter file are reported rather than diffed
+	if reason := align.SkipReason(cfg); reason != "" {
+		response.Status = "skipped"
+		response.Reason = reason
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Read both maps
+	f1, ok := s.openFile(w, r, file1)
+	if !ok {
+		return
+	}
+	f2, ok := s.openFile(w, r, file2)
+	if !ok {
+		return
 	}
+	cfg1, cfg2 := align.Locate(cfg)
+	ecuMap1, err1 := f1.ReadMap(cfg1)
+	ecuMap2, err2 := f2.ReadMap(cfg2)
+
+	if err1 != nil || err2 != nil {
+		err := errors.Join(err1, err2)
+		writeError(w, r, errorStatus(err), "Error reading maps", err)
+		return
+	}
+
+	// Calculate differences, ignoring changes within tolerance unless strict
+	tolerance := compare.DefaultTolerance(cfg)
+	if r.URL.Query().Get("strict") == "true" {
+		tolerance = 0
+	} else if tolStr := r.URL.Query().Get("tolerance"); tolStr != "" {
+		tol, err := strconv.ParseFloat(tolStr, 64)
+		if err != nil || tol < 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid tolerance", nil)
+			return
+		}
+		tolerance = tol
+	}
+	response.Data1 = ecuMap1.Data
+	response.XAxis, response.YAxis = ecuMap1.XAxis, ecuMap1.YAxis
+	response.XLabel, response.YLabel = axisLabels(cfg)
+	response.Data2 = ecuMap2.Data
+	response.Diff = compare.DiffMaps(ecuMap1.Data, ecuMap2.Data, tolerance)
+	response.Tolerance = tolerance
+	scales := compare.SharedScales(cfg, ecuMap1.Data, ecuMap2.Data, response.Diff)
+	response.Scale = scales.Shared
+	response.ScaleLabel = "shared " + scales.Shared.Label()
+	response.DeltaScale = scales.Delta
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -327,43 +918,277 @@ type ConfigUpdateRequest struct {
 	Value float64 `json:"value"`
 }
 
+// handleMapNudge moves one cell by whole nudge steps, snapped to a
+// representable value, and returns the updated map data
+func (s *Server) handleMapNudge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req NudgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+	if req.Map < 0 || req.Map >= len(models.MapConfigs) {
+		writeError(w, r, http.StatusBadRequest, "Invalid map index", nil)
+		return
+	}
+	file, ok := s.servedFile(w, r, req.File)
+	if !ok || !checkFile(w, r, file) {
+		return
+	}
+	f, ok := s.openFile(w, r, file)
+	if !ok {
+		return
+	}
+	cfg := f.Layout().LocateMap(models.MapConfigs[req.Map])
+	changes, err := editor.PlanNudge(f.Bytes(), cfg, req.Row, req.Col, req.Steps)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Cannot nudge", err)
+		return
+	}
+	if len(changes) > 0 {
+		_, err := editor.ApplyChanges(file, changes)
+		s.files.Forget(file)
+		if err != nil {
+			writeError(w, r, errorStatus(err), "Error writing nudge", err)
+			return
+		}
+		if f, ok = s.openFile(w, r, file); !ok {
+			return
+		}
+	}
+
+	ecuMap, err := f.ReadMap(cfg)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Error reading map", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":     ecuMap.Data,
+		"value":    ecuMap.Data[req.Row][req.Col],
+		"step":     cfg.StepLabel(),
+		"checksum": staleChecksum(file),
+	})
+}
+
+// handleMapTransform plans a transform with editor.TransformRegion and
+// writes it unless the request is a dry run
+func (s *Server) handleMapTransform(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req TransformRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+	if req.Map < 0 || req.Map >= len(models.MapConfigs) {
+		writeError(w, r, http.StatusBadRequest, "Invalid map index", nil)
+		return
+	}
+	file, ok := s.servedFile(w, r, req.File)
+	if !ok {
+		return
+	}
+	op, err := editor.ParseTransformOp(req.Op)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "", err)
+		return
+	}
+	if !checkFile(w, r, file) {
+		return
+	}
+	f, ok := s.openFile(w, r, file)
+	if !ok {
+		return
+	}
+	cfg := f.Layout().LocateMap(models.MapConfigs[req.Map])
+	region := editor.WholeMap(cfg)
+	if req.Region != nil {
+		region = *req.Region
+	}
+	result, err := editor.TransformRegion(f.Bytes(), cfg, region, op, req.Value)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Cannot transform", err)
+		return
+	}
+
+	response := TransformResponse{
+		Changes: len(result.Changes),
+		Cells:   result.Cells,
+		Min:     result.Min,
+		Max:     result.Max,
+		Clamped: len(result.Clamped),
+	}
+	if !req.DryRun && len(result.Changes) > 0 {
+		_, err := editor.ApplyChanges(file, result.Changes)
+		s.files.Forget(file)
+		if err != nil {
+			writeError(w, r, errorStatus(err), "Error writing transform", err)
+			return
+		}
+		response.Written = true
+
+		if f, ok = s.openFile(w, r, file); !ok {
+			return
+		}
+		ecuMap, err := f.ReadMap(cfg)
+		if err != nil {
+			writeError(w, r, errorStatus(err), "Error reading map", err)
+			return
+		}
+		response.Data = ecuMap.Data
+		response.Checksum = staleChecksum(file)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// staleChecksum describes the image checksum of filename if it no longer
+// matches and the checksum policy is ask, so the page can offer to fix it.
+// The server can't prompt on the terminal, so writes leave it stale.
+func staleChecksum(filename string) string {
+	if editor.ChecksumOnSave != editor.ChecksumAsk {
+		return ""
+	}
+	status, err := editor.CheckChecksum(filename)
+	if err != nil || status == nil || status.OK() {
+		return ""
+	}
+	return status.String()
+}
+
+// handleChecksumFix stores the computed image checksum of a file, after
+// the page confirmed the stale checksum a write reported
+func (s *Server) handleChecksumFix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	var req ChecksumFixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+	file, ok := s.servedFile(w, r, req.File)
+	if !ok || !checkFile(w, r, file) {
+		return
+	}
+
+	report, err := editor.FixChecksum(file)
+	s.files.Forget(file)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Error fixing checksum", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"fixed":    report.Written,
+		"checksum": staleChecksum(file),
+	})
+}
+
+// handleCompareParams lists the configuration parameters whose values
+// differ between file1 and file2
+func (s *Server) handleCompareParams(w http.ResponseWriter, r *http.Request) {
+	file1 := r.URL.Query().Get("file1")
+	file2 := r.URL.Query().Get("file2")
+	if file1 == "" || file2 == "" {
+		writeError(w, r, http.StatusBadRequest, "Both file1 and file2 parameters required", nil)
+		return
+	}
+	file1, ok := s.servedFile(w, r, file1)
+	if !ok {
+		return
+	}
+	if file2, ok = s.servedFile(w, r, file2); !ok {
+		return
+	}
+	if !checkFile(w, r, file1) || !checkFile(w, r, file2) {
+		return
+	}
+
+	align, err := compare.Align(file1, file2)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Error identifying files", err)
+		return
+	}
+	diffs, err := compare.CompareParams(file1, file2, align)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Error comparing parameters", err)
+		return
+	}
+
+	response := make([]CompareParamResponse, 0, len(diffs))
+	for _, d := range diffs {
+		response = append(response, CompareParamResponse{
+			Name:         d.Param.Name,
+			Offset:       d.Param.Offset,
+			Unit:         d.Param.Unit,
+			Value1:       d.Value1,
+			Value2:       d.Value2,
+			Raw1:         d.Raw1,
+			Raw2:         d.Raw2,
+			Implausible1: d.Implausible1,
+			Implausible2: d.Implausible2,
+			Error:        d.Err,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func (s *Server) handleConfigUpdate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
 		return
 	}
 
 	var req ConfigUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid request", err)
 		return
 	}
 
-	// Validate file exists
-	if req.File == "" {
-		http.Error(w, "File parameter required", http.StatusBadRequest)
+	file, ok := s.servedFile(w, r, req.File)
+	if !ok || !checkFile(w, r, file) {
 		return
 	}
 
-	// Write the config parameter
-	if err := reader.WriteConfigParam(req.File, req.Param, req.Value); err != nil {
-		http.Error(w, fmt.Sprintf("Error updating config: %v", err), http.StatusInternalServerError)
+	// Write the config parameter in a session, like map edits
+	_, err := editor.SetConfigParam(file, req.Param, req.Value)
+	s.files.Forget(file)
+	if err != nil {
+		writeError(w, r, errorStatus(err), "Error updating config", err)
 		return
 	}
 
 	// Return updated config
-	config, err := reader.ReadConfigParams(req.File)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error reading updated config: %v", err), http.StatusInternalServerError)
+	f, ok := s.openFile(w, r, file)
+	if !ok {
 		return
 	}
+	config := f.ReadConfigParams()
 
 	response := map[string]interface{}{
 		"success":  true,
 		"message":  fmt.Sprintf("Updated %s to %.2f", req.Param, req.Value),
 		"params":   config.Params,
 		"values":   config.Values,
-		"filename": filepath.Base(req.File),
+		"errors":   paramErrors(config),
+		"filename": filepath.Base(file),
+		"checksum": staleChecksum(file),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,344 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/checksum"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// newTestServer serves a folder with one synthetic image and returns the
+// server, the served image and an image in another folder
+func newTestServer(t *testing.T) (*Server, string, string) {
+	t.Helper()
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	served := filepath.Join(t.TempDir(), "ecu.bin")
+	outside := filepath.Join(t.TempDir(), "other.bin")
+	for _, path := range []string{served, outside} {
+		if err := os.WriteFile(path, testbin.Image(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewServer(served, 0), served, outside
+}
+
+// post sends body as JSON to handler and returns the recorded response
+func post(t *testing.T, handler http.HandlerFunc, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	return rec
+}
+
+// assertUnchanged fails if the image at path is no longer the synthetic one
+func assertUnchanged(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, testbin.Image()) {
+		t.Errorf("%s was written", filepath.Base(path))
+	}
+}
+
+func TestNudgeServedFilesOnly(t *testing.T) {
+	s, served, outside := newTestServer(t)
+
+	for _, file := range []string{outside, "../" + filepath.Base(outside), "", "ecu"} {
+		rec := post(t, s.handleMapNudge, "/api/map/nudge", NudgeRequest{File: file, Steps: 1})
+		if rec.Code != http.StatusForbidden && rec.Code != http.StatusBadRequest {
+			t.Errorf("nudge of %q: status %d, want it refused", file, rec.Code)
+		}
+	}
+	assertUnchanged(t, outside)
+
+	for _, file := range []string{served, fileID(served)} {
+		rec := post(t, s.handleMapNudge, "/api/map/nudge", NudgeRequest{File: file, Steps: 1})
+		if rec.Code != http.StatusOK {
+			t.Errorf("nudge of %q: status %d (%s), want 200", file, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestTransformServedFilesOnly(t *testing.T) {
+	s, served, outside := newTestServer(t)
+
+	rec := post(t, s.handleMapTransform, "/api/map/transform", TransformRequest{File: outside, Op: "add", Value: 1})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("transform of an unlisted file: status %d, want 403", rec.Code)
+	}
+	assertUnchanged(t, outside)
+
+	rec = post(t, s.handleMapTransform, "/api/map/transform", TransformRequest{File: fileID(served), Op: "add", Value: 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("transform of the served file: status %d (%s), want 200", rec.Code, rec.Body)
+	}
+	var resp TransformResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Written {
+		t.Error("the served file was not written")
+	}
+}
+
+func TestChecksumFixServedFilesOnly(t *testing.T) {
+	s, served, outside := newTestServer(t)
+	profile, plan := models.M21IDProfile.Checksum, editor.PlanChecksum
+	t.Cleanup(func() { models.M21IDProfile.Checksum, editor.PlanChecksum = profile, plan })
+	if err := checksum.UseSpec("sum16:0x0000-0x7FFD@0x7FFE"); err != nil {
+		t.Fatal(err)
+	}
+	editor.PlanChecksum = checksum.SessionStatus
+
+	rec := post(t, s.handleChecksumFix, "/api/checksum/fix", ChecksumFixRequest{File: outside})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("checksum fix of an unlisted file: status %d, want 403", rec.Code)
+	}
+	assertUnchanged(t, outside)
+
+	rec = post(t, s.handleChecksumFix, "/api/checksum/fix", ChecksumFixRequest{File: served})
+	if rec.Code != http.StatusOK {
+		t.Errorf("checksum fix of the served file: status %d (%s), want 200", rec.Code, rec.Body)
+	}
+}
+
+// TestNoAbsolutePaths requests every endpoint with files the server
+// can't read, isn't serving or can't save to, and checks that neither
+// the error bodies nor the regular responses reveal where the files are
+func TestNoAbsolutePaths(t *testing.T) {
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "My ECU Files")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ecu.bin", "gone.bin"} {
+		if err := os.WriteFile(filepath.Join(dir, name), testbin.Image(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewServer(dir, 0)
+	gone := filepath.Join(dir, "gone.bin")
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "other.bin")
+	ecuID, goneID := fileID(filepath.Join(dir, "ecu.bin")), fileID(gone)
+
+	requests := []struct {
+		method  string
+		handler http.HandlerFunc
+		target  string
+		body    string
+		status  int
+	}{
+		{http.MethodGet, s.handleConfigData, "/api/config?file=" + url.QueryEscape(gone), "", http.StatusNotFound},
+		{http.MethodGet, s.handleConfigData, "/api/config?file=" + url.QueryEscape(outside), "", http.StatusForbidden},
+		{http.MethodGet, s.handleMapData, "/api/map/0?file=" + goneID, "", http.StatusNotFound},
+		{http.MethodGet, s.handleMode, "/api/mode?file=" + goneID, "", http.StatusNotFound},
+		{http.MethodGet, s.handleCompareData, "/api/compare/0?file1=" + ecuID + "&file2=" + url.QueryEscape(outside), "", http.StatusForbidden},
+		{http.MethodGet, s.handleCompareData, "/api/compare/params?file1=" + ecuID + "&file2=" + goneID, "", http.StatusNotFound},
+		{http.MethodPost, s.handleConfigUpdate, "/api/config/update", `{"file":"` + goneID + `","param":"x","value":1}`, http.StatusNotFound},
+		{http.MethodPost, s.handleMapNudge, "/api/map/nudge", `{"file":"` + gone + `","steps":1}`, http.StatusNotFound},
+		{http.MethodPost, s.handleMapTransform, "/api/map/transform", `{"file":"` + outside + `","op":"add"}`, http.StatusForbidden},
+		{http.MethodPost, s.handleChecksumFix, "/api/checksum/fix", `{"file":"` + goneID + `"}`, http.StatusNotFound},
+		{http.MethodPost, s.handleState, "/api/state", `{"file":"` + outside + `"}`, http.StatusForbidden},
+		{http.MethodPost, s.handleState, "/api/state", `{"file":`, http.StatusBadRequest},
+		{http.MethodPost, s.handleState, "/api/state", `{"file":"` + filepath.Join(dir, "ecu.bin") + `","compare_file":"` + ecuID + `"}`, http.StatusOK},
+		{http.MethodGet, s.handleState, "/api/state", "", http.StatusOK},
+		{http.MethodGet, s.handleFileList, "/api/files", "", http.StatusOK},
+		{http.MethodGet, s.handleMode, "/api/mode", "", http.StatusOK},
+	}
+	for _, req := range requests {
+		rec := httptest.NewRecorder()
+		req.handler(rec, httptest.NewRequest(req.method, req.target, strings.NewReader(req.body)))
+		body := rec.Body.String()
+		if rec.Code != req.status {
+			t.Errorf("%s %s: status %d (%s), want %d", req.method, req.target, rec.Code, body, req.status)
+		}
+		for _, leak := range []string{dir, filepath.Dir(outside), os.TempDir()} {
+			if strings.Contains(body, leak) {
+				t.Errorf("%s %s: response reveals %s: %s", req.method, req.target, leak, body)
+			}
+		}
+	}
+
+	// A state file the server can't back up fails with a reference only
+	stateFile := filepath.Join(dir, "ecu-reader.project.json")
+	if err := os.Remove(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(stateFile, 0755); err != nil {
+		t.Fatal(err)
+	}
+	rec := post(t, s.handleState, "/api/state", editor.Project{File: ecuID})
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "ref ") {
+		t.Errorf("state save into a directory: status %d (%s), want 500 with a reference", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), dir) || strings.Contains(rec.Body.String(), "project.json") {
+		t.Errorf("state save error reveals the state file: %s", rec.Body)
+	}
+}
+
+func TestMapDataOverrides(t *testing.T) {
+	s, served, _ := newTestServer(t)
+	target := "/api/map/0?file=" + fileID(served) + "&"
+	size := int64(len(testbin.Image()))
+	fuel := models.MapConfigs[0]
+
+	rejected := []struct {
+		query string
+		param string
+		max   int64
+	}{
+		{"offset=-10", "offset", size - fuel.ByteSize()},
+		{"offset=0x-10", "offset", size - fuel.ByteSize()},
+		{"offset=zz", "offset", size - fuel.ByteSize()},
+		{"offset=0x7FF0", "offset", size - fuel.ByteSize()},
+		{"offset=7F81", "offset", size - fuel.ByteSize()},
+		{"rows=0", "rows", maxOverrideDim},
+		{"rows=33", "rows", maxOverrideDim},
+		{"rows=four", "rows", maxOverrideDim},
+		{"cols=-1", "cols", maxOverrideDim},
+		{"cols=1.5", "cols", maxOverrideDim},
+		{"dtype=float32", "dtype", 0},
+		{"rows=32&cols=32&dtype=uint16&offset=0x7C00", "offset", size - 32*32*2},
+	}
+	for _, tt := range rejected {
+		rec := httptest.NewRecorder()
+		s.handleMapData(rec, httptest.NewRequest(http.MethodGet, target+tt.query, nil))
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d (%s), want 422", tt.query, rec.Code, rec.Body)
+			continue
+		}
+		var e RangeError
+		if err := json.NewDecoder(rec.Body).Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Param != tt.param || e.Max != tt.max || e.Error == "" {
+			t.Errorf("%s: %+v, want param %s with max %d", tt.query, e, tt.param, tt.max)
+		}
+		if tt.param == "dtype" && len(e.Allowed) != len(models.DataTypes) {
+			t.Errorf("%s: allowed %v, want %v", tt.query, e.Allowed, models.DataTypes)
+		}
+	}
+
+	accepted := []struct {
+		query      string
+		offset     int64
+		rows, cols int
+	}{
+		{"", fuel.Offset, fuel.Rows, fuel.Cols},
+		{"offset=7F80", size - fuel.ByteSize(), fuel.Rows, fuel.Cols},
+		{"offset=0x0", 0, fuel.Rows, fuel.Cols},
+		{"rows=4&cols=2&dtype=int16", fuel.Offset, 4, 2},
+		{"rows=32&cols=32&dtype=uint16&offset=0x7800", 0x7800, 32, 32},
+	}
+	for _, tt := range accepted {
+		rec := httptest.NewRecorder()
+		s.handleMapData(rec, httptest.NewRequest(http.MethodGet, target+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%q: status %d (%s), want 200", tt.query, rec.Code, rec.Body)
+			continue
+		}
+		var m MapResponse
+		if err := json.NewDecoder(rec.Body).Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if m.Offset != tt.offset || m.Rows != tt.rows || m.Cols != tt.cols || len(m.Data) != tt.rows || len(m.Data[0]) != tt.cols {
+			t.Errorf("%q: %dx%d at 0x%X, want %dx%d at 0x%X", tt.query, m.Rows, m.Cols, m.Offset, tt.rows, tt.cols, tt.offset)
+		}
+	}
+}
+
+// An oversized .bin in the served folder is refused with 413 and the
+// reason, without being read
+func TestOversizedFile(t *testing.T) {
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	big := filepath.Join(dir, "disk.bin")
+	if err := os.WriteFile(big, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(big, 2<<30); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(dir, 0)
+
+	handlers := map[string]http.HandlerFunc{
+		"/api/map/0?file=" + fileID(big):  s.handleMapData,
+		"/api/config?file=" + fileID(big): s.handleConfigData,
+	}
+	for path, handler := range handlers {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: status %d, want 413", path, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "file too large to be an ECU image (size 2.0 GiB, limit 4.0 MiB)") {
+			t.Errorf("%s: %s doesn't give the reason", path, rec.Body)
+		}
+	}
+}
+
+// In compare mode the second file may have the same name as the first in
+// another folder; the two get different IDs, and each ID reads its own file
+func TestCompareSameNamedFiles(t *testing.T) {
+	t.Setenv("MOTRONIC_CONFIG_DIR", t.TempDir())
+	file1 := filepath.Join(t.TempDir(), "ecu.bin")
+	file2 := filepath.Join(t.TempDir(), "ecu.bin")
+	data := testbin.Image()
+	if err := os.WriteFile(file1, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fuel := models.MapConfigs[0]
+	data[fuel.Offset]++
+	if err := os.WriteFile(file2, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewCompareServer(file1, file2, 0)
+
+	rec := httptest.NewRecorder()
+	s.handleFileList(rec, httptest.NewRequest(http.MethodGet, "/api/files", nil))
+	var files []map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0]["id"] == files[1]["id"] || files[0]["name"] != files[1]["name"] {
+		t.Fatalf("file list %v, want two same-named files with different IDs", files)
+	}
+	for _, f := range files {
+		if strings.Contains(f["id"], "ecu") {
+			t.Errorf("ID %q reveals the file name", f["id"])
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	target := "/api/compare/0?strict=true&file1=" + files[0]["id"] + "&file2=" + files[1]["id"]
+	s.handleCompareData(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("compare: status %d (%s)", rec.Code, rec.Body)
+	}
+	var resp CompareResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Diff) == 0 || resp.Diff[0][0] == 0 {
+		t.Errorf("the first fuel cell differs between the files, but the diff is %v", resp.Diff)
+	}
+}
@@ -0,0 +1,170 @@
+//go:build unix
+
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tosih/motronic-m21-tool/internal/testbin"
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// A SIGINT arriving while a nudge is being written lets the write finish
+// and answer before the server stops, and the shutdown removes the temp
+// file an earlier killed write left behind
+func TestShutdownDuringWrite(t *testing.T) {
+	s, served, _ := newTestServer(t)
+	s.port = freePort(t)
+	saved := OpenBrowser
+	OpenBrowser = nil
+	t.Cleanup(func() { OpenBrowser = saved })
+	stale := served + ".tmp123456"
+	if err := os.WriteFile(stale, []byte("half"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// The write is slowed down after the new contents are in place, and
+	// the signal arrives in the middle of it
+	var once sync.Once
+	editor.AfterWrite = func(string, []byte) {
+		once.Do(func() {
+			syscall.Kill(os.Getpid(), syscall.SIGINT)
+			<-ctx.Done()
+			time.Sleep(300 * time.Millisecond)
+		})
+	}
+	t.Cleanup(func() { editor.AfterWrite = nil })
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start(ctx) }()
+	base := fmt.Sprintf("http://127.0.0.1:%d", s.port)
+	waitForServer(t, base)
+
+	body := strings.NewReader(fmt.Sprintf(`{"file":%q,"map":0,"row":1,"col":2,"steps":1}`, fileID(served)))
+	resp, err := http.Post(base+"/api/map/nudge", "application/json", body)
+	if err != nil {
+		t.Fatalf("the nudge got no answer: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("nudge status %d, want 200", resp.StatusCode)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start = %v, want a clean shutdown", err)
+		}
+	case <-time.After(ShutdownTimeout):
+		t.Fatal("the server didn't stop")
+	}
+
+	want := testbin.Image()
+	changes, err := editor.PlanNudge(want, models.MapConfigs[0], 1, 2, 1)
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("PlanNudge = %v, %v", changes, err)
+	}
+	changes[0].Apply(want)
+	if got, _ := os.ReadFile(served); string(got) != string(want) {
+		t.Error("the file doesn't hold exactly the nudged image")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(served))
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp") {
+			t.Errorf("%s was left after the shutdown", e.Name())
+		}
+	}
+	if _, err := http.Get(base + "/api/files"); err == nil {
+		t.Error("the server still answers after the shutdown")
+	}
+}
+
+// freePort returns a TCP port nothing is listening on
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// waitForServer waits until the server at base answers
+func waitForServer(t *testing.T, base string) {
+	t.Helper()
+	for range 100 {
+		if resp, err := http.Get(base + "/api/files"); err == nil {
+			resp.Body.Close()
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("the server didn't start")
+}
+
+// When a write outlasts ShutdownTimeout, Start returns without removing
+// temp files, since the abandoned handler may still be writing
+func TestShutdownTimeoutKeepsTemps(t *testing.T) {
+	s, served, _ := newTestServer(t)
+	s.port = freePort(t)
+	savedBrowser, savedTimeout := OpenBrowser, ShutdownTimeout
+	OpenBrowser, ShutdownTimeout = nil, 100*time.Millisecond
+	t.Cleanup(func() { OpenBrowser, ShutdownTimeout = savedBrowser, savedTimeout })
+	stale := served + ".tmp123456"
+	if err := os.WriteFile(stale, []byte("half"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writing, release := make(chan struct{}), make(chan struct{})
+	editor.AfterWrite = func(string, []byte) {
+		close(writing)
+		<-release
+	}
+	t.Cleanup(func() { editor.AfterWrite = nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Start(ctx) }()
+	base := fmt.Sprintf("http://127.0.0.1:%d", s.port)
+	waitForServer(t, base)
+
+	answered := make(chan struct{})
+	go func() {
+		defer close(answered)
+		body := strings.NewReader(fmt.Sprintf(`{"file":%q,"map":0,"row":1,"col":2,"steps":1}`, fileID(served)))
+		if resp, err := http.Post(base+"/api/map/nudge", "application/json", body); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-writing
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start = %v, want nil after a timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server didn't stop")
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Errorf("a temp file was removed while a write was still running: %v", err)
+	}
+	close(release)
+	<-answered
+}
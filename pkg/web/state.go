@@ -0,0 +1,81 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+
+	"github.com/tosih/motronic-m21-tool/pkg/editor"
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+)
+
+// StateResponse is the saved view state of the bin folder, with the map
+// names the state's per-map settings are keyed by. Files are given by
+// their IDs and Path and Backup by file name, so the page never sees
+// where the server keeps them.
+type StateResponse struct {
+	Project *editor.Project `json:"project"`
+	Path    string          `json:"path"`
+	Maps    []string        `json:"maps"`
+	Backup  string          `json:"backup,omitempty"`
+}
+
+// handleState loads (GET) or saves (POST) the project file of the bin
+// folder, or of the state slot named by ?slot=. Saves are last-write-wins;
+// the previous state is backed up first.
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	path, err := editor.ProjectPath(s.binFolder, r.URL.Query().Get("slot"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "", err)
+		return
+	}
+	response := StateResponse{Path: filepath.Base(path)}
+	for _, cfg := range models.MapConfigs {
+		response.Maps = append(response.Maps, cfg.Name)
+	}
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		if response.Project, err = editor.LoadProject(path); err != nil {
+			writeError(w, r, errorStatus(err), "Error reading state", err)
+			return
+		}
+		if p := response.Project; p != nil {
+			p.File, p.CompareFile = fileID(p.File), fileID(p.CompareFile)
+		}
+	case http.MethodPost:
+		var p editor.Project
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid state", err)
+			return
+		}
+		for _, file := range []*string{&p.File, &p.CompareFile} {
+			if *file == "" {
+				continue
+			}
+			var ok bool
+			if *file, ok = s.servedFile(w, r, *file); !ok {
+				return
+			}
+		}
+		backup, err := editor.SaveProject(path, &p)
+		if err != nil {
+			writeError(w, r, errorStatus(err), "Error saving state", err)
+			return
+		}
+		if backup != "" {
+			response.Backup = filepath.Base(backup)
+		}
+		p.File, p.CompareFile = fileID(p.File), fileID(p.CompareFile)
+		response.Project = &p
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,339 @@
+// MapCanvas renders ECU maps as heatmaps on a <canvas> element with axis
+// labels, a color legend and hover tooltips. It has no external
+// dependencies so the web viewer works offline.
+//
+// Usage:
+//   MapCanvas.render(canvas, map, options)
+//
+// map:     { name, unit, rows, cols, data, xAxis?, yAxis?, loadAxis?,
+//            xLabel?, yLabel?, highlightBelow?, scale?, scaleLabel? }
+//
+// xAxis and yAxis are breakpoints read from the binary (models.AxisConfig)
+// and win when present. Otherwise loadAxis holds the server's row labels
+// (models.MapConfig.LoadLabels), so the load axis reads the same as in the
+// CLI and GUI.
+// options: { min?, max?, diverging?, showValues?, title?, onCellClick? }
+//
+// onCellClick(row, col, x, y) is called with the clicked cell and the
+// click position relative to the canvas.
+//
+// Cell colors use the same blue -> cyan -> green -> yellow -> red gradient
+// as the GTK GUI. In diverging mode (used for difference maps) the scale is
+// symmetric around zero: blue for decreases, gray for no change, red for
+// increases.
+//
+// map.scale is the map's resolved color scale (models.HeatScale): values
+// are placed on the gradient by interpolating between scale.bounds, so
+// robust and fixed-band scales color the same as the CLI and GUI. An
+// explicit options.min/max overrides it. The legend is labelled at the same
+// band boundaries as the CLI heatmap (renderer.HeatmapBands) and captioned
+// with map.scaleLabel. Cells under map.highlightBelow get a corner dot and
+// the threshold is marked on the legend.
+//
+// When comparing, both files' maps carry the same map.scale (resolved over
+// both files) so equal values get equal colors, and the difference map's
+// map.scale is symmetric around zero (compare.Scales).
+const MapCanvas = (() => {
+    const margin = { left: 60, right: 90, top: 30, bottom: 50 };
+    const textColor = '#e0e0e0';
+    const borderColor = '#2a2a2a';
+    const legendBands = 5;
+
+    // normalize mirrors models.HeatScale.Normalize: the position of value
+    // on the gradient, interpolated between bounds (lowest first)
+    function normalize(value, bounds) {
+        const lo = bounds[0], hi = bounds[bounds.length - 1];
+        if (!(hi > lo)) return 0.5;
+        if (value <= lo) return 0;
+        if (value >= hi) return 1;
+        const segments = bounds.length - 1;
+        for (let i = 1; i < bounds.length; i++) {
+            if (value < bounds[i]) {
+                const span = bounds[i] - bounds[i - 1];
+                const frac = span > 0 ? (value - bounds[i - 1]) / span : 0;
+                return (i - 1 + frac) / segments;
+            }
+        }
+        return 1;
+    }
+
+    // scaleValue is the inverse of normalize (models.HeatScale.Value)
+    function scaleValue(t, bounds) {
+        const segments = bounds.length - 1;
+        const i = Math.min(Math.floor(t * segments), segments - 1);
+        const frac = t * segments - i;
+        return bounds[i] + (bounds[i + 1] - bounds[i]) * frac;
+    }
+
+    // valueToColor colors value on a linear min..max scale
+    function valueToColor(value, min, max) {
+        return gradientColor(normalize(value, [min, max]));
+    }
+
+    // gradientColor mirrors heatColor in pkg/gui/maplayout.go
+    function gradientColor(t) {
+        t = Math.max(0, Math.min(1, t));
+
+        let r, g, b;
+        if (t < 0.25) {
+            const s = t / 0.25;
+            [r, g, b] = [0, s, 1];
+        } else if (t < 0.5) {
+            const s = (t - 0.25) / 0.25;
+            [r, g, b] = [0, 1, 1 - s];
+        } else if (t < 0.75) {
+            const s = (t - 0.5) / 0.25;
+            [r, g, b] = [s, 1, 0];
+        } else {
+            const s = (t - 0.75) / 0.25;
+            [r, g, b] = [1, 1 - s, 0];
+        }
+        return [r, g, b];
+    }
+
+    function divergingColor(value, maxAbs) {
+        if (maxAbs === 0 || value === 0) return [0.53, 0.53, 0.53];
+        const t = Math.max(-1, Math.min(1, value / maxAbs));
+        if (t < 0) {
+            const s = -t;
+            return [0.53 * (1 - s), 0.53 * (1 - s) + 0.28 * s, 0.53 + 0.47 * s];
+        }
+        return [0.53 + 0.47 * t, 0.53 * (1 - t), 0.53 * (1 - t)];
+    }
+
+    function css([r, g, b]) {
+        return `rgb(${Math.round(r * 255)}, ${Math.round(g * 255)}, ${Math.round(b * 255)})`;
+    }
+
+    // axisLabels returns breakpoint labels, falling back to synthetic
+    // RPM/load headers when the map has no axis data
+    function axisLabels(map) {
+        const x = map.xAxis && map.xAxis.length === map.cols
+            ? map.xAxis.map(v => formatNumber(v))
+            : Array.from({ length: map.cols }, (_, j) => String(j * Math.floor(8000 / map.cols)));
+        const y = map.yAxis && map.yAxis.length === map.rows
+            ? map.yAxis.map(v => formatNumber(v))
+            : map.loadAxis || Array.from({ length: map.rows }, (_, i) => String(i));
+        return { x, y };
+    }
+
+    function formatNumber(v) {
+        return Number.isInteger(v) ? String(v) : v.toFixed(2);
+    }
+
+    function dataRange(data) {
+        const flat = data.flat();
+        return { min: Math.min(...flat), max: Math.max(...flat) };
+    }
+
+    function render(canvas, map, options = {}) {
+        const showValues = options.showValues ?? true;
+        const diverging = options.diverging ?? false;
+        const range = dataRange(map.data);
+        const min = options.min ?? range.min;
+        const max = options.max ?? range.max;
+        const maxAbs = diverging && map.scale
+            ? map.scale.bounds[map.scale.bounds.length - 1]
+            : Math.max(Math.abs(range.min), Math.abs(range.max));
+
+        // A manual range from the page overrides the map's color scale
+        const manual = options.min != null || options.max != null;
+        let bounds = [min, max];
+        let scaleLabel = manual ? 'manual range' : 'min/max';
+        if (diverging) {
+            bounds = [-maxAbs, maxAbs];
+            scaleLabel = '';
+        } else if (!manual && map.scale) {
+            bounds = map.scale.bounds;
+            scaleLabel = map.scaleLabel;
+        }
+
+        const threshold = !diverging && map.highlightBelow != null ? map.highlightBelow : null;
+        const colorFor = diverging
+            ? v => divergingColor(v, maxAbs)
+            : v => gradientColor(normalize(v, bounds));
+
+        // Match the canvas resolution to its displayed size
+        const ratio = window.devicePixelRatio || 1;
+        const width = canvas.clientWidth;
+        const height = canvas.clientHeight;
+        canvas.width = width * ratio;
+        canvas.height = height * ratio;
+
+        const ctx = canvas.getContext('2d');
+        ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+        ctx.clearRect(0, 0, width, height);
+
+        const plotWidth = width - margin.left - margin.right;
+        const plotHeight = height - margin.top - margin.bottom;
+        const cellWidth = plotWidth / map.cols;
+        const cellHeight = plotHeight / map.rows;
+
+        // Title
+        if (options.title) {
+            ctx.fillStyle = textColor;
+            ctx.font = 'bold 13px sans-serif';
+            ctx.textAlign = 'left';
+            ctx.textBaseline = 'alphabetic';
+            ctx.fillText(options.title, margin.left, 18);
+        }
+
+        // Cells
+        const fontSize = Math.max(8, Math.min(12, cellWidth / 4));
+        ctx.font = `${fontSize}px sans-serif`;
+        ctx.textAlign = 'center';
+        ctx.textBaseline = 'middle';
+        for (let i = 0; i < map.rows; i++) {
+            for (let j = 0; j < map.cols; j++) {
+                const x = margin.left + j * cellWidth;
+                const y = margin.top + i * cellHeight;
+                const value = map.data[i][j];
+                const rgb = colorFor(value);
+
+                ctx.fillStyle = css(rgb);
+                ctx.fillRect(x, y, cellWidth, cellHeight);
+                ctx.strokeStyle = borderColor;
+                ctx.strokeRect(x, y, cellWidth, cellHeight);
+
+                if (showValues && cellWidth > 24) {
+                    const luminance = 0.299 * rgb[0] + 0.587 * rgb[1] + 0.114 * rgb[2];
+                    ctx.fillStyle = luminance < 0.5 ? '#ffffff' : '#000000';
+                    const text = diverging && value > 0 ? `+${value.toFixed(2)}` : value.toFixed(2);
+                    ctx.fillText(text, x + cellWidth / 2, y + cellHeight / 2);
+                }
+
+                if (threshold !== null && value < threshold) {
+                    const luminance = 0.299 * rgb[0] + 0.587 * rgb[1] + 0.114 * rgb[2];
+                    ctx.fillStyle = luminance < 0.5 ? '#ffffff' : '#000000';
+                    ctx.beginPath();
+                    ctx.arc(x + cellWidth - 6, y + 6, 3, 0, 2 * Math.PI);
+                    ctx.fill();
+                }
+            }
+        }
+
+        // Axes
+        const labels = axisLabels(map);
+        ctx.fillStyle = textColor;
+        ctx.font = '11px sans-serif';
+        ctx.textBaseline = 'top';
+        ctx.textAlign = 'center';
+        const xStep = Math.max(1, Math.ceil(40 / cellWidth));
+        for (let j = 0; j < map.cols; j += xStep) {
+            ctx.fillText(labels.x[j], margin.left + (j + 0.5) * cellWidth, margin.top + plotHeight + 5);
+        }
+        ctx.textAlign = 'right';
+        ctx.textBaseline = 'middle';
+        for (let i = 0; i < map.rows; i++) {
+            ctx.fillText(labels.y[i], margin.left - 6, margin.top + (i + 0.5) * cellHeight);
+        }
+
+        ctx.font = 'bold 12px sans-serif';
+        ctx.textAlign = 'center';
+        ctx.textBaseline = 'bottom';
+        ctx.fillText(map.xLabel || 'RPM', margin.left + plotWidth / 2, height - 8);
+        ctx.save();
+        ctx.translate(14, margin.top + plotHeight / 2);
+        ctx.rotate(-Math.PI / 2);
+        ctx.textBaseline = 'middle';
+        ctx.fillText(map.yLabel || 'Load %', 0, 0);
+        ctx.restore();
+
+        // Legend
+        drawLegend(ctx, width - margin.right + 15, margin.top, 20, plotHeight,
+            bounds, colorFor, diverging ? `Δ ${map.unit}` : map.unit, threshold, scaleLabel);
+
+        attachTooltip(canvas, map, cellWidth, cellHeight, options.onCellClick);
+    }
+
+    function drawLegend(ctx, x, y, w, h, bounds, colorFor, unit, threshold, scaleLabel) {
+        const min = bounds[0], max = bounds[bounds.length - 1];
+        const digits = max - min < 1 ? 2 : 1;
+        const steps = 100;
+        const stepHeight = h / steps;
+        for (let i = 0; i < steps; i++) {
+            const value = scaleValue(1 - i / steps, bounds);
+            ctx.fillStyle = css(colorFor(value));
+            ctx.fillRect(x, y + i * stepHeight, w, stepHeight + 1);
+        }
+        ctx.strokeStyle = textColor;
+        ctx.strokeRect(x, y, w, h);
+
+        ctx.fillStyle = textColor;
+        ctx.font = '10px sans-serif';
+        ctx.textAlign = 'left';
+        ctx.textBaseline = 'middle';
+        for (let i = 0; i <= legendBands; i++) {
+            const value = scaleValue(1 - i / legendBands, bounds);
+            ctx.fillText(value.toFixed(digits), x + w + 4, y + i * h / legendBands);
+        }
+        ctx.textBaseline = 'bottom';
+        ctx.fillText(unit, x, y - 4);
+
+        // Scaling mode below the bar, so screenshots show how colors were
+        // assigned
+        if (scaleLabel) {
+            ctx.textAlign = 'right';
+            ctx.textBaseline = 'top';
+            ctx.fillText(scaleLabel, ctx.canvas.clientWidth - 4, y + h + 20);
+            ctx.textAlign = 'left';
+        }
+
+        if (threshold !== null && threshold > min && threshold <= max) {
+            const ty = y + (1 - normalize(threshold, bounds)) * h;
+            ctx.strokeStyle = textColor;
+            ctx.lineWidth = 2;
+            ctx.beginPath();
+            ctx.moveTo(x, ty);
+            ctx.lineTo(x + w, ty);
+            ctx.stroke();
+            ctx.lineWidth = 1;
+            ctx.beginPath();
+            ctx.arc(x - 6, ty, 3, 0, 2 * Math.PI);
+            ctx.fill();
+        }
+    }
+
+    function attachTooltip(canvas, map, cellWidth, cellHeight, onCellClick) {
+        let tooltip = canvas.parentElement.querySelector('.map-tooltip');
+        if (!tooltip) {
+            tooltip = document.createElement('div');
+            tooltip.className = 'map-tooltip';
+            canvas.parentElement.style.position = 'relative';
+            canvas.parentElement.appendChild(tooltip);
+        }
+        const labels = axisLabels(map);
+
+        canvas.onmousemove = (e) => {
+            const rect = canvas.getBoundingClientRect();
+            const px = e.clientX - rect.left;
+            const py = e.clientY - rect.top;
+            const col = Math.floor((px - margin.left) / cellWidth);
+            const row = Math.floor((py - margin.top) / cellHeight);
+            if (row < 0 || row >= map.rows || col < 0 || col >= map.cols) {
+                tooltip.style.display = 'none';
+                return;
+            }
+            const value = map.data[row][col];
+            tooltip.textContent = `Row ${row}, Column ${col} (${labels.y[row]} / ${labels.x[col]}): ${value.toFixed(2)} ${map.unit}`;
+            tooltip.style.left = `${px + 12}px`;
+            tooltip.style.top = `${py + 12}px`;
+            tooltip.style.display = 'block';
+        };
+        canvas.onmouseleave = () => {
+            tooltip.style.display = 'none';
+        };
+        canvas.onclick = !onCellClick ? null : (e) => {
+            const rect = canvas.getBoundingClientRect();
+            const px = e.clientX - rect.left;
+            const py = e.clientY - rect.top;
+            const col = Math.floor((px - margin.left) / cellWidth);
+            const row = Math.floor((py - margin.top) / cellHeight);
+            if (row >= 0 && row < map.rows && col >= 0 && col < map.cols) {
+                onCellClick(row, col, px, py);
+            }
+        };
+    }
+
+    return { render, valueToColor };
+})();
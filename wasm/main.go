@@ -0,0 +1,203 @@
+//go:build js && wasm
+
+// Command wasm exposes the reader, scanner and statistics packages to
+// JavaScript for the browser-only analyzer in web/static. Build with
+//
+//	GOOS=js GOARCH=wasm go build -o web/static/analyzer.wasm ./wasm
+//
+// and copy $(go env GOROOT)/lib/wasm/wasm_exec.js next to it. All functions
+// take the image as a Uint8Array and return plain objects; failures are
+// returned as {error: "..."}.
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"github.com/tosih/motronic-m21-tool/pkg/models"
+	"github.com/tosih/motronic-m21-tool/pkg/reader"
+	"github.com/tosih/motronic-m21-tool/pkg/scanner"
+	"github.com/tosih/motronic-m21-tool/pkg/stats"
+)
+
+func main() {
+	js.Global().Set("motronic", js.ValueOf(map[string]interface{}{
+		"maps":     js.FuncOf(listMaps),
+		"identify": js.FuncOf(identify),
+		"readMap":  js.FuncOf(readMap),
+		"scan":     js.FuncOf(scan),
+		"stats":    js.FuncOf(mapStats),
+	}))
+
+	// Keep the exported functions alive
+	select {}
+}
+
+// listMaps returns the name, offset and shape of every map definition
+func listMaps(this js.Value, args []js.Value) interface{} {
+	maps := make([]interface{}, len(models.MapConfigs))
+	for i, cfg := range models.MapConfigs {
+		maps[i] = map[string]interface{}{
+			"index":  i,
+			"name":   cfg.Name,
+			"offset": cfg.Offset,
+			"rows":   cfg.Rows,
+			"cols":   cfg.Cols,
+			"unit":   cfg.Unit,
+		}
+	}
+	return maps
+}
+
+// identify(bytes) returns the identification strings of an image
+func identify(this js.Value, args []js.Value) interface{} {
+	data, err := bytesArg(args, 0)
+	if err != nil {
+		return errorResult(err)
+	}
+	id := reader.IdentifyData(data, models.M21IDProfile)
+	return map[string]interface{}{
+		"size":            id.Size,
+		"sha256":          id.SHA256,
+		"label":           id.Label(),
+		"boschNumber":     id.BoschNumber,
+		"partNumber":      id.PartNumber,
+		"softwareVersion": id.SoftwareVersion,
+		"baseOffset":      id.BaseOffset,
+	}
+}
+
+// readMap(bytes, index) returns a map's definition and converted values
+func readMap(this js.Value, args []js.Value) interface{} {
+	data, cfg, err := mapArgs(args)
+	if err != nil {
+		return errorResult(err)
+	}
+	m, err := reader.ReadMapFromBytes(data, cfg)
+	if err != nil {
+		return errorResult(err)
+	}
+	// Gradient positions (0..1) from the map's color scale, so the page
+	// colors cells the same way as the CLI and GUI
+	scale := cfg.HeatScale(m.Data)
+	positions := make([][]float64, len(m.Data))
+	for i, row := range m.Data {
+		positions[i] = make([]float64, len(row))
+		for j, v := range row {
+			positions[i][j] = scale.Normalize(v)
+		}
+	}
+	return map[string]interface{}{
+		"name":   cfg.Name,
+		"offset": cfg.Offset,
+		"rows":   cfg.Rows,
+		"cols":   cfg.Cols,
+		"unit":   cfg.Unit,
+		"data":   grid(m.Data),
+		"colors": grid(positions),
+		"scale":  scale.Label(),
+		"header": cfg.GridHeader(),
+		"rpm":    labels(m.ColumnLabels()),
+		"load":   labels(m.RowLabels()),
+		// Axes that are not monotonic, shown above the table
+		"axisWarnings": labels(m.AxisWarnings()),
+	}
+}
+
+// scan(bytes) returns potential map locations found by the scanner
+func scan(this js.Value, args []js.Value) interface{} {
+	data, err := bytesArg(args, 0)
+	if err != nil {
+		return errorResult(err)
+	}
+	results := scanner.ScanBytes(data, nil)
+	out := make([]interface{}, len(results))
+	for i, r := range results {
+		out[i] = map[string]interface{}{
+			"offset":     r.Offset,
+			"rows":       r.Rows,
+			"cols":       r.Cols,
+			"dataType":   r.DataType,
+			"endianness": r.Endianness,
+			"min":        r.Min,
+			"max":        r.Max,
+			"variance":   r.Variance,
+			"preview":    r.Preview,
+			"axes":       r.Axes.String(),
+		}
+	}
+	return out
+}
+
+// mapStats(bytes, index) returns summary statistics of a map
+func mapStats(this js.Value, args []js.Value) interface{} {
+	data, cfg, err := mapArgs(args)
+	if err != nil {
+		return errorResult(err)
+	}
+	m, err := reader.ReadMapFromBytes(data, cfg)
+	if err != nil {
+		return errorResult(err)
+	}
+	s := stats.OfMap(m.Data)
+	return map[string]interface{}{
+		"count":    s.Count,
+		"min":      s.Min,
+		"max":      s.Max,
+		"mean":     s.Mean,
+		"variance": s.Variance,
+		"stdDev":   s.StdDev,
+	}
+}
+
+// bytesArg copies the Uint8Array argument at i into a Go slice
+func bytesArg(args []js.Value, i int) ([]byte, error) {
+	if len(args) <= i || args[i].Type() != js.TypeObject || args[i].Get("length").Type() != js.TypeNumber {
+		return nil, fmt.Errorf("argument %d must be a Uint8Array", i+1)
+	}
+	data := make([]byte, args[i].Get("length").Int())
+	js.CopyBytesToGo(data, args[i])
+	return data, nil
+}
+
+// mapArgs decodes the (bytes, index) arguments shared by readMap and stats
+func mapArgs(args []js.Value) ([]byte, models.MapConfig, error) {
+	data, err := bytesArg(args, 0)
+	if err != nil {
+		return nil, models.MapConfig{}, err
+	}
+	if len(args) < 2 || args[1].Type() != js.TypeNumber {
+		return nil, models.MapConfig{}, fmt.Errorf("argument 2 must be a map index")
+	}
+	idx := args[1].Int()
+	if idx < 0 || idx >= len(models.MapConfigs) {
+		return nil, models.MapConfig{}, fmt.Errorf("map index %d out of range (0-%d)", idx, len(models.MapConfigs)-1)
+	}
+	return data, models.MapConfigs[idx], nil
+}
+
+// grid converts map data to nested JS arrays
+func grid(data [][]float64) []interface{} {
+	rows := make([]interface{}, len(data))
+	for i, row := range data {
+		cells := make([]interface{}, len(row))
+		for j, v := range row {
+			cells[j] = v
+		}
+		rows[i] = cells
+	}
+	return rows
+}
+
+// labels converts axis labels to a JS array
+func labels(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func errorResult(err error) interface{} {
+	return map[string]interface{}{"error": err.Error()}
+}
@@ -0,0 +1,116 @@
+// Browser-only analyzer: loads analyzer.wasm (built from ./wasm) and runs
+// the Go reader, scanner and stats on a file chosen via the File API.
+
+let image = null; // Uint8Array of the selected file
+
+const $ = id => document.getElementById(id);
+
+function setStatus(text, isError = false) {
+    $('status').textContent = text;
+    $('status').className = isError ? 'error' : 'muted';
+}
+
+// escapeHTML guards values taken from the binary, such as ID strings
+function escapeHTML(s) {
+    return String(s).replace(/[&<>"']/g, c => ({
+        '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
+    }[c]));
+}
+
+// cellColor maps a gradient position (0..1, from the map's color scale)
+// to a blue-to-red heatmap color
+function cellColor(t) {
+    return `hsl(${(1 - t) * 240}, 70%, 75%)`;
+}
+
+function renderIdentity() {
+    const id = motronic.identify(image);
+    if (id.error) {
+        $('identity').innerHTML = `<span class="error">${escapeHTML(id.error)}</span>`;
+        return;
+    }
+    $('identity').innerHTML = `
+        <strong>${escapeHTML(id.label || 'Unidentified image')}</strong><br>
+        Size: ${id.size} bytes · Base offset: 0x${id.baseOffset.toString(16).toUpperCase()}<br>
+        <span class="muted">SHA-256 ${id.sha256}</span>`;
+}
+
+function renderMap() {
+    const idx = Number($('mapSelect').value);
+    const map = motronic.readMap(image, idx);
+    if (map.error) {
+        $('map').innerHTML = `<span class="error">${escapeHTML(map.error)}</span>`;
+        $('stats').innerHTML = '';
+        return;
+    }
+
+    const s = motronic.stats(image, idx);
+    $('stats').innerHTML = `
+        <strong>${escapeHTML(map.name)}</strong> (0x${map.offset.toString(16).toUpperCase()}, ${map.rows}x${map.cols})<br>
+        Min ${s.min.toFixed(2)} · Max ${s.max.toFixed(2)} · Mean ${s.mean.toFixed(2)} · Std dev ${s.stdDev.toFixed(2)} ${escapeHTML(map.unit)}<br>
+        <span class="muted">Color scale: ${escapeHTML(map.scale)}</span>` +
+        map.axisWarnings.map(w => `<br><span class="error">${escapeHTML(w)}</span>`).join('');
+
+    let html = `<table><tr><th>${escapeHTML(map.header)}</th>`;
+    for (let c = 0; c < map.cols; c++) {
+        html += `<th>${map.rpm[c]}</th>`;
+    }
+    html += '</tr>';
+    map.data.forEach((row, r) => {
+        html += `<tr><th>${map.load[r]}</th>`;
+        row.forEach((v, c) => {
+            html += `<td style="background:${cellColor(map.colors[r][c])}">${v.toFixed(2)}</td>`;
+        });
+        html += '</tr>';
+    });
+    $('map').innerHTML = html + '</table>';
+}
+
+function renderScan() {
+    const results = motronic.scan(image);
+    if (results.error) {
+        $('scan').innerHTML = `<span class="error">${escapeHTML(results.error)}</span>`;
+        return;
+    }
+    let html = `<strong>${results.length} potential map(s)</strong><table>
+        <tr><th>Offset</th><th>Size</th><th>Type</th><th>Endian</th><th>Min</th><th>Max</th><th>Variance</th></tr>`;
+    results.forEach(r => {
+        html += `<tr><td>0x${r.offset.toString(16).toUpperCase()}</td><td>${r.rows}x${r.cols}</td>
+            <td>${r.dataType}</td><td>${r.endianness}</td><td>${r.min}</td><td>${r.max}</td>
+            <td>${r.variance.toFixed(1)}</td></tr>`;
+    });
+    $('scan').innerHTML = html + '</table>';
+}
+
+$('fileInput').addEventListener('change', async event => {
+    const file = event.target.files[0];
+    if (!file) return;
+    image = new Uint8Array(await file.arrayBuffer());
+    setStatus(`${file.name}: ${image.length} bytes`);
+    $('mapSelect').disabled = false;
+    $('scanButton').disabled = false;
+    $('scan').innerHTML = '';
+    renderIdentity();
+    renderMap();
+});
+
+$('mapSelect').addEventListener('change', () => image && renderMap());
+$('scanButton').addEventListener('click', () => image && renderScan());
+
+// Start the Go runtime, which registers the global motronic object
+(async () => {
+    const go = new Go();
+    try {
+        const result = await WebAssembly.instantiateStreaming(fetch('analyzer.wasm'), go.importObject);
+        go.run(result.instance);
+    } catch (err) {
+        setStatus(`Failed to load analyzer.wasm: ${err}`, true);
+        return;
+    }
+
+    motronic.maps().forEach(m => {
+        $('mapSelect').add(new Option(`${m.name} (0x${m.offset.toString(16).toUpperCase()})`, m.index));
+    });
+    $('fileInput').disabled = false;
+    setStatus('Select an ECU .bin file');
+})();